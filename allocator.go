@@ -0,0 +1,71 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// Allocator supplies the memory that tensor data is decoded into, so that
+// embedders can place it in arenas, huge pages, or memory managed outside the
+// Go heap. Alloc returns a slice of at least n bytes whose start is aligned to
+// 16 bytes; its contents need not be zeroed. Free receives slices previously
+// returned by Alloc, truncated to the size that was requested.
+type Allocator interface {
+	Alloc(n int) []byte
+	Free(b []byte)
+}
+
+// WithAllocator decodes the data of numeric and structured tensors into memory
+// obtained from a instead of the Go heap. Other dtypes, and all dtypes on
+// big-endian platforms, are still allocated by Go. Tensors read this way must
+// be released with Release once they are no longer used.
+func WithAllocator(a Allocator) Option {
+	return func(o *options) {
+		o.allocator = a
+	}
+}
+
+// Release returns the data of a tensor read with WithAllocator to a, and clears
+// the tensor's data. Slices obtained from the tensor must not be used afterwards.
+func (t *Tensor) Release(a Allocator) {
+	if b, ok := rawBytes(t.Data); ok && len(b) > 0 {
+		a.Free(b)
+	}
+	t.Data = nil
+}
+
+// readDataAlloc reads data as readData does, placing it in memory from a when
+// the dtype allows it.
+func readDataAlloc(shape Shape, dtype DType, r io.Reader, a Allocator, zeroCopy bool) (interface{}, error) {
+	size, ok := dataSize(dtype, shape)
+	if _, viewable := viewData(dtype, nil); !viewable || !ok || size == 0 {
+		if zeroCopy {
+			return readDataZeroCopy(shape, dtype, r)
+		}
+		return readData(shape, dtype, r)
+	}
+
+	b := a.Alloc(int(size))
+	if len(b) < int(size) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("allocator returned %d bytes, requested %d", len(b), size)}
+	}
+	b = b[:size]
+	if uintptr(unsafe.Pointer(&b[0]))%16 != 0 {
+		a.Free(b)
+		return nil, ErrorNpy{Msg: "allocator returned memory that is not 16-byte aligned"}
+	}
+
+	data, _ := viewData(dtype, b)
+	var err error
+	if zeroCopy {
+		_, err = io.ReadFull(r, b)
+	} else {
+		err = decodeInto(r, dtype, data)
+	}
+	if err != nil {
+		a.Free(b)
+		return nil, err
+	}
+	return data, nil
+}
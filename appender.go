@@ -0,0 +1,108 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+)
+
+// NpyAppender writes an NPY file whose leading dimension grows as rows are
+// appended. The header is written up front with room for any row count and is
+// rewritten with the final count by Close. If the writer is interrupted before
+// Close, RecoverNPY can still read the rows that were written.
+type NpyAppender struct {
+	f          *os.File
+	dtype      DType
+	rowShape   Shape
+	rowElems   int
+	headerSize int
+	rows       int
+	maxRows    int // rows the placeholder header leaves room for
+}
+
+// NewNpyAppender creates the NPY file at path for rows of the given dtype and shape.
+func NewNpyAppender(path string, dtype DType, rowShape Shape) (*NpyAppender, error) {
+	if dtype.isPacked() || dtype == DTypeObject {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot append rows of dtype %s", dtype)}
+	}
+
+	// Reserve room for the largest leading dimension that readers accept, so
+	// the header of an interrupted file still parses
+	rowBytes, ok := dataSize(dtype, rowShape)
+	if !ok || rowBytes > math.MaxInt {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("data size of row shape %v overflows", rowShape)}
+	}
+	maxRows := math.MaxInt
+	if rowBytes > 0 {
+		maxRows = int(math.MaxInt / rowBytes)
+	}
+	placeholder := append(Shape{maxRows}, rowShape...)
+	prefix, err := encodeHeader(&Header{Descr: dtype, Shape: placeholder}, defaultHeaderAlign, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(prefix); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &NpyAppender{
+		f:          f,
+		dtype:      dtype,
+		rowShape:   append(Shape(nil), rowShape...),
+		rowElems:   rowShape.ElemCount(),
+		headerSize: len(prefix),
+		maxRows:    maxRows,
+	}, nil
+}
+
+// AppendRows writes one or more rows. data is a slice of the dtype's element
+// type whose length is a multiple of the number of elements in a row.
+func (a *NpyAppender) AppendRows(data interface{}) error {
+	want, err := makeData(a.dtype, 0)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(data)
+	if reflect.TypeOf(data) != reflect.TypeOf(want) {
+		return ErrorNpy{Msg: fmt.Sprintf("%s rows must be %T, got %T", a.dtype, want, data)}
+	}
+	n := v.Len()
+	if a.dtype.isStructured() {
+		n /= a.dtype.Size() // Records are raw bytes
+	}
+	if a.rowElems == 0 || n%a.rowElems != 0 {
+		return ErrorNpy{Msg: fmt.Sprintf("%d elements do not form whole rows of shape %v", n, a.rowShape)}
+	}
+	if n/a.rowElems > a.maxRows-a.rows {
+		return ErrorNpy{Msg: fmt.Sprintf("appending %d rows exceeds the maximum of %d", n/a.rowElems, a.maxRows), Err: ErrTooLarge}
+	}
+	if err := writeData(a.f, a.dtype, data); err != nil {
+		return err
+	}
+	a.rows += n / a.rowElems
+	return nil
+}
+
+// Rows returns the number of rows appended so far.
+func (a *NpyAppender) Rows() int {
+	return a.rows
+}
+
+// Close records the final row count in the header and closes the file.
+func (a *NpyAppender) Close() error {
+	shape := append(Shape{a.rows}, a.rowShape...)
+	prefix, err := encodeHeader(&Header{Descr: a.dtype, Shape: shape}, defaultHeaderAlign, a.headerSize)
+	if err == nil {
+		_, err = a.f.WriteAt(prefix, 0)
+	}
+	if cerr := a.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,52 @@
+package gonpy
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNpyAppenderRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.npy")
+	a, err := NewNpyAppender(path, DTypeF32, Shape{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := a.AppendRows([]float32{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadNPY(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape.Equal(Shape{2, 3}) {
+		t.Errorf("shape %v, want [2 3]", out.Shape)
+	}
+}
+
+func TestRecoverInterruptedNpyAppender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rows.npy")
+	a, err := NewNpyAppender(path, DTypeF64, Shape{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AppendRows([]float64{1, 2, 3, 4, 5, 6}); err != nil {
+		t.Fatal(err)
+	}
+	a.f.Close() // interrupted before Close rewrites the header
+
+	out, err := RecoverNPY(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape.Equal(Shape{2, 3}) {
+		t.Errorf("shape %v, want [2 3]", out.Shape)
+	}
+	if got := out.Data.([]float64); got[5] != 6 {
+		t.Errorf("data %v", got)
+	}
+}
@@ -0,0 +1,213 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// arenaAlign is the byte alignment of each tensor region inside an arena.
+const arenaAlign = 64
+
+// Arena owns a single contiguous allocation backing all tensors returned by ReadNPZArena.
+type Arena struct {
+	buf     []uint64 // uint64 elements guarantee 8-byte alignment of the base address
+	tensors []*Tensor
+}
+
+// Len returns the size of the arena in bytes.
+func (a *Arena) Len() int {
+	return len(a.buf) * 8
+}
+
+// Free releases the arena's memory by detaching it from every tensor it backs.
+// The Data field of those tensors is set to nil; the memory is reclaimed by the
+// garbage collector once no other references to the data slices remain.
+func (a *Arena) Free() {
+	for _, t := range a.tensors {
+		t.Data = nil
+	}
+	a.tensors = nil
+	a.buf = nil
+}
+
+// bytes returns the arena storage viewed as a byte slice.
+func (a *Arena) bytes() []byte {
+	if len(a.buf) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&a.buf[0])), len(a.buf)*8)
+}
+
+// arenaSlice returns a typed slice of n elements of dtype viewing mem.
+func arenaSlice(dtype DType, mem []byte, n int) (interface{}, error) {
+	if n == 0 {
+		return makeData(dtype, 0)
+	}
+	p := unsafe.Pointer(&mem[0])
+	switch dtype {
+	case DTypeBF16, DTypeF16:
+		return unsafe.Slice((*uint16)(p), n), nil
+	case DTypeF32:
+		return unsafe.Slice((*float32)(p), n), nil
+	case DTypeF64:
+		return unsafe.Slice((*float64)(p), n), nil
+	case DTypeC64:
+		return unsafe.Slice((*complex64)(p), n), nil
+	case DTypeC128:
+		return unsafe.Slice((*complex128)(p), n), nil
+	case DTypeI32:
+		return unsafe.Slice((*int32)(p), n), nil
+	case DTypeI64:
+		return unsafe.Slice((*int64)(p), n), nil
+	case DTypeU64:
+		return unsafe.Slice((*uint64)(p), n), nil
+	case DTypeU32:
+		return unsafe.Slice((*uint32)(p), n), nil
+	case DTypeU8:
+		return mem[:n:n], nil
+	case DTypeBool:
+		return unsafe.Slice((*bool)(p), n), nil
+	case DTypeI8, DTypeF8E4M3, DTypeF8E5M2:
+		return unsafe.Slice((*int8)(p), n), nil
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", dtype), Err: ErrUnsupportedDType}
+	}
+}
+
+// ReadNPZArena reads the named tensors from an NPZ file into a single contiguous
+// arena allocation. Each returned tensor's Data is a slice into the arena, so the
+// whole set can be released at once with Arena.Free. Tensors are returned in the
+// order of names.
+func ReadNPZArena(path string, names []string, opts ...Option) ([]*Tensor, *Arena, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer r.Close()
+
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.loadChecksums(r.File); err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string]*zip.File, len(entries))
+	for _, file := range entries {
+		files[o.nameCodec.Decode(file.Name)] = file
+	}
+
+	// First pass: read headers to size the arena.
+	headers := make([]*Header, len(names))
+	offsets := make([]int, len(names))
+	total := 0
+	for i, name := range names {
+		file, ok := files[name]
+		if !ok {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, path), Err: ErrEntryNotFound}
+		}
+		header, err := readEntryHeader(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Descr.Size() == 0 {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", header.Descr), Err: ErrUnsupportedDType}
+		}
+		if err := o.checkSize(header.Descr, header.Shape); err != nil {
+			return nil, nil, err
+		}
+		size, _ := dataSize(header.Descr, header.Shape) // bounded by parseHeader
+		region := (size + arenaAlign - 1) / arenaAlign * arenaAlign
+		if region > int64(math.MaxInt-total) {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("arena for %d tensors in %s overflows", len(names), path), Err: ErrTooLarge}
+		}
+		headers[i] = header
+		offsets[i] = total
+		total += int(region)
+	}
+
+	arena := &Arena{buf: make([]uint64, total/8)}
+	mem := arena.bytes()
+
+	// Second pass: decode each payload into its arena region.
+	for i, name := range names {
+		header := headers[i]
+		n := header.Shape.ElemCount()
+		data, err := arenaSlice(header.Descr, mem[offsets[i]:], n)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := readEntryInto(files[name], name, data, o); err != nil {
+			return nil, nil, err
+		}
+		tensor := &Tensor{
+			Data:   data,
+			Shape:  header.Shape,
+			DType:  header.Descr,
+			Device: "cpu",
+		}
+		if info, ok := packing[name]; ok {
+			if tensor, err = info.unpackStorage(name, tensor); err != nil {
+				return nil, nil, err
+			}
+		}
+		arena.tensors = append(arena.tensors, tensor)
+	}
+	return arena.tensors, arena, nil
+}
+
+// readEntryHeader parses the NPY header of a zip entry.
+func readEntryHeader(file *zip.File) (*Header, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	headerStr, err := readHeader(rc)
+	if err != nil {
+		return nil, err
+	}
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		return nil, err
+	}
+	if header.FortranOrder {
+		return nil, ErrFortranOrder
+	}
+	return header, nil
+}
+
+// readEntryInto decodes the payload of the zip entry for tensor name into the
+// typed slice data.
+func readEntryInto(file *zip.File, name string, data interface{}, o *options) error {
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r, verify := o.verifyingReader(name, rc)
+	headerStr, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		return err
+	}
+	if err := decodeInto(r, header.Descr, data); err != nil {
+		return err
+	}
+	return verify()
+}
@@ -0,0 +1,65 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip writes a zip file at path holding the given entries.
+func writeZip(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, b := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadNPZArena(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	err := WriteNPZ(path, map[string]*Tensor{
+		"x": {Data: []float32{1, 2, 3}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"},
+		"y": {Data: []int64{4, 5}, Shape: Shape{2}, DType: DTypeI64, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tensors, arena, err := ReadNPZArena(path, []string{"y", "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arena.Free()
+	if got := tensors[0].Data.([]int64); got[1] != 5 {
+		t.Errorf("y = %v", got)
+	}
+	if got := tensors[1].Data.([]float32); got[2] != 3 {
+		t.Errorf("x = %v", got)
+	}
+}
+
+func TestReadNPZArenaBudget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.npz")
+	header := "{'descr': '<f8', 'fortran_order': False, 'shape': (1099511627776,), }"
+	writeZip(t, path, map[string][]byte{"x.npy": npyBytes(header, nil)})
+
+	_, _, err := ReadNPZArena(path, []string{"x"}, WithMaxBytes(1<<20))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
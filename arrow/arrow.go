@@ -0,0 +1,329 @@
+// Package arrow converts between gonpy tensors and Apache Arrow arrays, and
+// reads and writes arrays as Arrow IPC streams and files (Feather version 2)
+// that pyarrow, pandas, DuckDB, Polars, and other Arrow libraries can open.
+//
+// The package works with the Arrow columnar format directly and has no
+// dependency on the Arrow Go module. A 1-D tensor becomes a primitive array.
+// A tensor with more dimensions becomes an arrow.fixed_shape_tensor extension
+// array, whose storage is a FixedSizeList holding one row of the tensor per
+// slot. Arrays have no nulls.
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Type is the type of the values of an Array.
+type Type int
+
+const (
+	Bool Type = iota + 1
+	Int8
+	Uint8
+	Int16
+	Uint16
+	Int32
+	Uint32
+	Int64
+	Uint64
+	Float16
+	Float32
+	Float64
+)
+
+// String returns the Arrow name of the type.
+func (t Type) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Int8:
+		return "int8"
+	case Uint8:
+		return "uint8"
+	case Int16:
+		return "int16"
+	case Uint16:
+		return "uint16"
+	case Int32:
+		return "int32"
+	case Uint32:
+		return "uint32"
+	case Int64:
+		return "int64"
+	case Uint64:
+		return "uint64"
+	case Float16:
+		return "halffloat"
+	case Float32:
+		return "float"
+	case Float64:
+		return "double"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// bitWidth returns the number of bits of one value.
+func (t Type) bitWidth() int {
+	switch t {
+	case Bool:
+		return 1
+	case Int8, Uint8:
+		return 8
+	case Int16, Uint16, Float16:
+		return 16
+	case Int32, Uint32, Float32:
+		return 32
+	case Int64, Uint64, Float64:
+		return 64
+	}
+	return 0
+}
+
+// signed reports whether the type is a signed integer.
+func (t Type) signed() bool {
+	return t == Int8 || t == Int16 || t == Int32 || t == Int64
+}
+
+// types maps dtypes to the Arrow types that hold them.
+var types = map[gonpy.DType]Type{
+	gonpy.DTypeBool: Bool,
+	gonpy.DTypeI8:   Int8,
+	gonpy.DTypeU8:   Uint8,
+	gonpy.DTypeI32:  Int32,
+	gonpy.DTypeU32:  Uint32,
+	gonpy.DTypeI64:  Int64,
+	gonpy.DTypeU64:  Uint64,
+	gonpy.DTypeF16:  Float16,
+	gonpy.DTypeF32:  Float32,
+	gonpy.DTypeF64:  Float64,
+}
+
+// Array is an Arrow array of Len slots without nulls. If Shape is nil, it is
+// a primitive array with one value per slot. Otherwise it is a
+// fixed_shape_tensor array whose slots each hold a tensor of Shape.
+type Array struct {
+	Type   Type
+	Len    int
+	Shape  gonpy.Shape
+	Values []byte // Values buffer: little-endian, or bit-packed for Bool
+}
+
+// valuesBytes returns the size of the values buffer, or false if the length
+// or shape is negative or the size overflows an int.
+func (a *Array) valuesBytes() (int, bool) {
+	n := a.Len
+	if a.Shape != nil {
+		perRow, err := a.Shape.CheckedElemCount()
+		if err != nil || perRow > 0 && n > math.MaxInt/perRow {
+			return 0, false
+		}
+		n *= perRow
+	}
+	bits := max(a.Type.bitWidth(), 1)
+	if n < 0 || n > (math.MaxInt-7)/bits {
+		return 0, false
+	}
+	return (n*a.Type.bitWidth() + 7) / 8, true
+}
+
+// ToArrow converts a tensor to an Array. The first dimension becomes the
+// slots and any others the shape of each slot; a scalar becomes one slot.
+// On little-endian platforms the values buffer shares the tensor's memory,
+// except for booleans, which Arrow stores as bits.
+func ToArrow(t *gonpy.Tensor) (*Array, error) {
+	typ, ok := types[t.DType]
+	if !ok {
+		return nil, fmt.Errorf("arrow: no Arrow type for %s: %w", t.DType, gonpy.ErrUnsupportedDType)
+	}
+	n, err := t.Shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	a := &Array{Type: typ, Len: 1}
+	if len(t.Shape) > 0 {
+		a.Len = t.Shape[0]
+	}
+	if len(t.Shape) > 1 {
+		a.Shape = append(gonpy.Shape(nil), t.Shape[1:]...)
+	}
+
+	if typ == Bool {
+		data, ok := t.Data.([]bool)
+		if !ok || len(data) != n {
+			return nil, fmt.Errorf("arrow: bool tensor of shape %v has data %T", t.Shape, t.Data)
+		}
+		a.Values = make([]byte, (n+7)/8)
+		for i, v := range data {
+			if v {
+				a.Values[i/8] |= 1 << (i % 8)
+			}
+		}
+		return a, nil
+	}
+	if binary.Size(t.Data) != n*t.DType.Size() {
+		return nil, fmt.Errorf("arrow: %s tensor of shape %v has data %T", t.DType, t.Shape, t.Data)
+	}
+	if a.Values = rawBytes(t.Data); a.Values == nil {
+		var buf bytes.Buffer
+		if err := binary.Write(&buf, binary.LittleEndian, t.Data); err != nil {
+			return nil, err
+		}
+		a.Values = buf.Bytes()
+	}
+	return a, nil
+}
+
+// FromArrow converts an Array to a tensor of shape [Len, Shape...]. On
+// little-endian platforms the tensor shares the values buffer if it is
+// suitably aligned. Int16 and Uint16 values are widened to 32 bits, since
+// gonpy has no 16-bit integer dtypes.
+func FromArrow(a *Array) (*gonpy.Tensor, error) {
+	shape := append(gonpy.Shape{a.Len}, a.Shape...)
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	if a.Type.bitWidth() == 0 {
+		return nil, fmt.Errorf("arrow: unknown type %v", a.Type)
+	}
+	need, ok := a.valuesBytes()
+	if !ok {
+		return nil, fmt.Errorf("arrow: array of %d slots of shape %v is too large: %w", a.Len, a.Shape, gonpy.ErrTooLarge)
+	}
+	if len(a.Values) < need {
+		return nil, fmt.Errorf("arrow: values buffer of %d bytes, %d needed", len(a.Values), need)
+	}
+
+	var dtype gonpy.DType
+	for d, typ := range types {
+		if typ == a.Type {
+			dtype = d
+		}
+	}
+	switch a.Type {
+	case Bool:
+		data := make([]bool, n)
+		for i := range data {
+			data[i] = a.Values[i/8]&(1<<(i%8)) != 0
+		}
+		return &gonpy.Tensor{Data: data, Shape: shape, DType: gonpy.DTypeBool}, nil
+	case Int16:
+		data := make([]int32, n)
+		for i := range data {
+			data[i] = int32(int16(binary.LittleEndian.Uint16(a.Values[2*i:])))
+		}
+		return &gonpy.Tensor{Data: data, Shape: shape, DType: gonpy.DTypeI32}, nil
+	case Uint16:
+		data := make([]uint32, n)
+		for i := range data {
+			data[i] = uint32(binary.LittleEndian.Uint16(a.Values[2*i:]))
+		}
+		return &gonpy.Tensor{Data: data, Shape: shape, DType: gonpy.DTypeU32}, nil
+	}
+
+	values := a.Values[:n*dtype.Size()]
+	if data := viewData(dtype, values); data != nil {
+		return &gonpy.Tensor{Data: data, Shape: shape, DType: dtype}, nil
+	}
+	t, err := gonpy.Zeros(dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	if err := binary.Read(bytes.NewReader(values), binary.LittleEndian, t.Data); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// nativeLittleEndian reports whether the platform's byte order matches Arrow's.
+var nativeLittleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// rawBytes views the memory of a numeric data slice as bytes, or returns nil
+// if it cannot.
+func rawBytes(data interface{}) []byte {
+	if !nativeLittleEndian {
+		return nil
+	}
+	switch d := data.(type) {
+	case []byte:
+		return d
+	case []int8:
+		return asBytes(d)
+	case []uint16:
+		return asBytes(d)
+	case []int32:
+		return asBytes(d)
+	case []uint32:
+		return asBytes(d)
+	case []int64:
+		return asBytes(d)
+	case []uint64:
+		return asBytes(d)
+	case []float32:
+		return asBytes(d)
+	case []float64:
+		return asBytes(d)
+	}
+	return nil
+}
+
+// viewData views b as the data slice of dtype, or returns nil if it cannot
+// because of the platform's byte order or b's alignment.
+func viewData(dtype gonpy.DType, b []byte) interface{} {
+	if !nativeLittleEndian {
+		return nil
+	}
+	if len(b) > 0 && uintptr(unsafe.Pointer(&b[0]))%uintptr(dtype.Size()) != 0 {
+		return nil
+	}
+	switch dtype {
+	case gonpy.DTypeU8:
+		return b
+	case gonpy.DTypeI8:
+		return fromBytes[int8](b)
+	case gonpy.DTypeF16:
+		return fromBytes[uint16](b)
+	case gonpy.DTypeI32:
+		return fromBytes[int32](b)
+	case gonpy.DTypeU32:
+		return fromBytes[uint32](b)
+	case gonpy.DTypeI64:
+		return fromBytes[int64](b)
+	case gonpy.DTypeU64:
+		return fromBytes[uint64](b)
+	case gonpy.DTypeF32:
+		return fromBytes[float32](b)
+	case gonpy.DTypeF64:
+		return fromBytes[float64](b)
+	}
+	return nil
+}
+
+type numeric interface {
+	int8 | uint16 | int32 | uint32 | int64 | uint64 | float32 | float64
+}
+
+// asBytes views the memory of s as a byte slice.
+func asBytes[T numeric](s []T) []byte {
+	if len(s) == 0 {
+		return []byte{}
+	}
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(zero)))
+}
+
+// fromBytes views b as a slice of T. The start of b must be aligned for T.
+func fromBytes[T numeric](b []byte) []T {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if len(b) < size {
+		return []T{}
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&b[0])), len(b)/size)
+}
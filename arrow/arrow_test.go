@@ -0,0 +1,81 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	w := &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"}
+	m := &gonpy.Tensor{Data: []bool{true, false}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeBool, Device: "cpu"}
+	var columns []Column
+	for name, x := range map[string]*gonpy.Tensor{"w": w, "m": m} {
+		a, err := ToArrow(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns = append(columns, Column{Name: name, Array: a})
+	}
+	var b bytes.Buffer
+	if err := WriteStream(&b, columns); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadStream(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range got {
+		x, err := FromArrow(c.Array)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch c.Name {
+		case "w":
+			if d := x.Data.([]float32); !x.Shape.Equal(w.Shape) || d[5] != 6 {
+				t.Errorf("w = %v %v", x.Shape, d)
+			}
+		case "m":
+			if d := x.Data.([]bool); !d[0] || d[1] {
+				t.Errorf("m = %v", d)
+			}
+		}
+	}
+}
+
+func TestHostileListSize(t *testing.T) {
+	x := &gonpy.Tensor{Data: make([]int8, 12345), Shape: gonpy.Shape{1, 12345}, DType: gonpy.DTypeI8, Device: "cpu"}
+	a, err := ToArrow(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteStream(&b, []Column{{Name: "x", Array: a}}); err != nil {
+		t.Fatal(err)
+	}
+	size := binary.LittleEndian.AppendUint32(nil, 12345)
+	stream := b.Bytes()
+	if !bytes.Contains(stream, size) {
+		t.Fatal("list size not found")
+	}
+	for _, bad := range []int32{-1, 0, -2147483648} {
+		// The schema, holding the list size, precedes the record batch
+		patched := bytes.Replace(stream, size, binary.LittleEndian.AppendUint32(nil, uint32(bad)), 1)
+		if _, err := ReadStream(bytes.NewReader(patched)); err == nil {
+			t.Errorf("list size %d accepted", bad)
+		}
+	}
+}
+
+func TestValuesBytesOverflow(t *testing.T) {
+	a := &Array{Type: Float64, Len: math.MaxInt / 2, Shape: gonpy.Shape{4}}
+	if _, ok := a.valuesBytes(); ok {
+		t.Error("overflowing size accepted")
+	}
+	if _, err := FromArrow(a); err == nil {
+		t.Error("FromArrow accepted an overflowing array")
+	}
+}
@@ -0,0 +1,172 @@
+package arrow
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gocnn/gonpy"
+)
+
+// An Arrow IPC file, also known as Feather version 2, is the magic string
+// padded to 8 bytes, an IPC stream, and a Footer flatbuffer holding the
+// schema and the location of every record batch, followed by the footer's
+// length and the magic string again.
+
+// fileMagic starts and ends every Arrow IPC file.
+const fileMagic = "ARROW1"
+
+// blockSize is the size of a Block struct: the offset of a message, the size
+// of its metadata, and the size of its body.
+const blockSize = 24
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteFile writes columns to an Arrow IPC file with one uncompressed record
+// batch, which pandas.read_feather, polars.read_ipc, and pyarrow.feather can
+// read. All arrays must have the same length.
+func WriteFile(path string, columns []Column) error {
+	schema, batch, body, bodyLength, err := encodeColumns(columns)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = writeFile(f, schema, batch, body, bodyLength)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// writeFile writes the messages and footer of an IPC file to f.
+func writeFile(f *os.File, schema, batch fbTable, body [][]byte, bodyLength int64) error {
+	bw := bufio.NewWriter(f)
+	w := &countingWriter{w: bw}
+	if _, err := w.Write([]byte(fileMagic + "\x00\x00")); err != nil {
+		return err
+	}
+	if _, err := writeMessage(w, headerSchema, schema, nil, 0); err != nil {
+		return err
+	}
+	offset := w.n
+	metaLength, err := writeMessage(w, headerRecordBatch, batch, body, bodyLength)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, []uint32{continuation, 0}); err != nil {
+		return err
+	}
+
+	block := appendPair(nil, offset, int64(metaLength))
+	block = binary.LittleEndian.AppendUint64(block, uint64(bodyLength))
+	// Footer fields are version, schema, dictionaries, and record batches
+	footer := buildFlatbuffer(fbTable{int16(metadataV5), schema, nil, fbStructs{1, block}})
+	if _, err := w.Write(footer); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(footer))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(fileMagic)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadFile reads the columns of an Arrow IPC file, such as a Feather file
+// written by pandas or polars. Record batches are concatenated. Columns are
+// subject to the same limits as in ReadStream, and buffers may be compressed
+// with LZ4, the default of pandas.to_feather, but not ZSTD.
+func ReadFile(path string) ([]Column, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < 2*int64(len(fileMagic))+6 {
+		return nil, fmt.Errorf("arrow: %s is not an Arrow file: %w", path, gonpy.ErrBadMagic)
+	}
+	head := make([]byte, len(fileMagic))
+	tail := make([]byte, 4+len(fileMagic))
+	if _, err := f.ReadAt(head, 0); err != nil {
+		return nil, err
+	}
+	if _, err := f.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return nil, err
+	}
+	if string(head) != fileMagic || string(tail[4:]) != fileMagic {
+		return nil, fmt.Errorf("arrow: %s is not an Arrow file: %w", path, gonpy.ErrBadMagic)
+	}
+	footerLength := int64(binary.LittleEndian.Uint32(tail))
+	if footerLength > maxMetadataSize || footerLength > size-int64(len(tail))-8 {
+		return nil, fmt.Errorf("arrow: footer of %d bytes", footerLength)
+	}
+	footer := make([]byte, footerLength)
+	if _, err := f.ReadAt(footer, size-int64(len(tail))-footerLength); err != nil {
+		return nil, err
+	}
+
+	fr := &fbReader{buf: footer}
+	root := fr.root()
+	schema, ok := root.table(1)
+	if !ok {
+		return nil, fmt.Errorf("arrow: file footer has no schema")
+	}
+	columns, err := readSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	if _, n := root.vector(2, blockSize); n > 0 {
+		return nil, fmt.Errorf("arrow: dictionary-encoded columns are not supported")
+	}
+	start, count := root.vector(3, blockSize)
+	if fr.err != nil {
+		return nil, fmt.Errorf("arrow: decoding footer: %w", fr.err)
+	}
+	for i := 0; i < count; i++ {
+		p := fr.bytes(start+blockSize*i, blockSize)
+		offset := int64(binary.LittleEndian.Uint64(p))
+		length := int64(int32(binary.LittleEndian.Uint32(p[8:]))) + int64(binary.LittleEndian.Uint64(p[16:]))
+		if offset < 0 || length < 0 || offset > size || length > size-offset {
+			return nil, fmt.Errorf("arrow: record batch %d of %d bytes at %d is outside the file", i, length, offset)
+		}
+		msg, body, err := readMessage(io.NewSectionReader(f, offset, length))
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil || msg.u8(1) != headerRecordBatch {
+			return nil, fmt.Errorf("arrow: block %d is not a record batch", i)
+		}
+		header, _ := msg.table(2)
+		if err := readRecordBatch(header, body, columns); err != nil {
+			return nil, err
+		}
+		if msg.r.err != nil {
+			return nil, fmt.Errorf("arrow: decoding record batch: %w", msg.r.err)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,99 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	x := &gonpy.Tensor{Data: []int64{1, -2, 3, -4, 5, -6}, Shape: gonpy.Shape{3, 2}, DType: gonpy.DTypeI64, Device: "cpu"}
+	y := &gonpy.Tensor{Data: []float64{0.5, 1.5, 2.5}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeF64, Device: "cpu"}
+	var columns []Column
+	for _, c := range []struct {
+		name string
+		t    *gonpy.Tensor
+	}{{"x", x}, {"y", y}} {
+		a, err := ToArrow(c.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns = append(columns, Column{Name: c.name, Array: a})
+	}
+	path := filepath.Join(t.TempDir(), "a.arrow")
+	if err := WriteFile(path, columns); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "x" || got[1].Name != "y" {
+		t.Fatalf("read columns %v", got)
+	}
+	gx, err := FromArrow(got[0].Array)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := gx.Data.([]int64); !gx.Shape.Equal(x.Shape) || d[5] != -6 {
+		t.Errorf("x = %v %v", gx.Shape, d)
+	}
+	gy, err := FromArrow(got[1].Array)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := gy.Data.([]float64); !gy.Shape.Equal(y.Shape) || d[2] != 2.5 {
+		t.Errorf("y = %v %v", gy.Shape, d)
+	}
+}
+
+func TestWriteFileInvalid(t *testing.T) {
+	a := &Array{Type: Int8, Len: 2, Values: []byte{1, 2}}
+	b := &Array{Type: Int8, Len: 3, Values: []byte{1, 2, 3}}
+	path := filepath.Join(t.TempDir(), "a.arrow")
+	if err := WriteFile(path, []Column{{Name: "a", Array: a}, {Name: "b", Array: b}}); err == nil {
+		t.Error("columns of different lengths written")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("failed write left a file")
+	}
+}
+
+func TestHostileFile(t *testing.T) {
+	a := &Array{Type: Int32, Len: 2, Values: make([]byte, 8)}
+	path := filepath.Join(t.TempDir(), "a.arrow")
+	if err := WriteFile(path, []Column{{Name: "a", Array: a}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	footerLength := int(binary.LittleEndian.Uint32(b[len(b)-10:]))
+	footer := b[len(b)-10-footerLength:]
+	huge := append(append([]byte{}, b[:len(b)-10]...), "\xff\xff\xff\x7fARROW1"...)
+	tests := map[string][]byte{
+		"short":         b[:10],
+		"head magic":    append([]byte("ARROW2"), b[6:]...),
+		"tail magic":    append(append([]byte{}, b[:len(b)-1]...), '2'),
+		"footer length": huge,
+		// The footer's record batch now lies beyond the end of the file
+		"no messages": append(append([]byte{}, b[:8]...), footer...),
+	}
+	for name, bad := range tests {
+		if err := os.WriteFile(path, bad, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadFile(path)
+		if err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+		if (name == "short" || name == "head magic" || name == "tail magic") && !errors.Is(err, gonpy.ErrBadMagic) {
+			t.Errorf("%s: got %v, want ErrBadMagic", name, err)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The IPC metadata is encoded as flatbuffers. This file holds a minimal
+// builder and reader for the subset the package uses: tables of scalars,
+// strings, tables, and vectors of tables or structs.
+
+// fbTable is a table to encode. Element i is field i: an int8, uint8, bool,
+// int16, int32, or int64 scalar, a string, an fbTable, an fbTables vector, an
+// fbStructs vector, or nil if the field is absent.
+type fbTable []interface{}
+
+// fbTables is a vector of tables.
+type fbTables []fbTable
+
+// fbStructs is a vector of n structs of size bytes each, aligned to 8 bytes.
+type fbStructs struct {
+	n    int
+	data []byte
+}
+
+// fbBuilder writes a flatbuffer front to back: each table is preceded by its
+// vtable and followed by the objects it refers to, so that every offset
+// points forward as the format requires.
+type fbBuilder struct {
+	buf []byte
+}
+
+// buildFlatbuffer encodes root as a complete flatbuffer.
+func buildFlatbuffer(root fbTable) []byte {
+	b := &fbBuilder{buf: make([]byte, 4)}
+	pos := b.table(root)
+	binary.LittleEndian.PutUint32(b.buf, uint32(pos))
+	b.align(8, 0)
+	return b.buf
+}
+
+// align pads the buffer until its length plus extra is a multiple of n.
+func (b *fbBuilder) align(n, extra int) {
+	for (len(b.buf)+extra)%n != 0 {
+		b.buf = append(b.buf, 0)
+	}
+}
+
+// scalarSize returns the inline size of a field value, which is also its
+// alignment.
+func scalarSize(v interface{}) int {
+	switch v.(type) {
+	case int8, uint8, bool:
+		return 1
+	case int16:
+		return 2
+	case int64:
+		return 8
+	default:
+		return 4 // int32 and offsets
+	}
+}
+
+// table writes t and the objects it refers to, and returns its position.
+func (b *fbBuilder) table(t fbTable) int {
+	// Lay out the fields after the 4-byte vtable offset, each aligned to its
+	// size, assuming the table starts 8-byte aligned
+	offsets := make([]int, len(t))
+	size := 4
+	for i, v := range t {
+		if v == nil {
+			continue
+		}
+		n := scalarSize(v)
+		size = (size + n - 1) / n * n
+		offsets[i] = size
+		size += n
+	}
+
+	b.align(2, 0)
+	vtable := len(b.buf)
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(4+2*len(t)))
+	b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(size))
+	for _, off := range offsets {
+		b.buf = binary.LittleEndian.AppendUint16(b.buf, uint16(off))
+	}
+	b.align(8, 0)
+	pos := len(b.buf)
+	b.buf = append(b.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(pos-vtable))
+
+	var refs []int
+	for i, v := range t {
+		p := b.buf[pos+offsets[i]:]
+		switch v := v.(type) {
+		case nil:
+		case int8:
+			p[0] = byte(v)
+		case uint8:
+			p[0] = v
+		case bool:
+			if v {
+				p[0] = 1
+			}
+		case int16:
+			binary.LittleEndian.PutUint16(p, uint16(v))
+		case int32:
+			binary.LittleEndian.PutUint32(p, uint32(v))
+		case int64:
+			binary.LittleEndian.PutUint64(p, uint64(v))
+		default:
+			refs = append(refs, i)
+		}
+	}
+	for _, i := range refs {
+		b.patch(pos+offsets[i], b.object(t[i]))
+	}
+	return pos
+}
+
+// patch stores at field the offset from field to target.
+func (b *fbBuilder) patch(field, target int) {
+	binary.LittleEndian.PutUint32(b.buf[field:], uint32(target-field))
+}
+
+// object writes a string, table, or vector and returns its position.
+func (b *fbBuilder) object(v interface{}) int {
+	switch v := v.(type) {
+	case string:
+		b.align(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, v...)
+		b.buf = append(b.buf, 0)
+		return pos
+	case fbTable:
+		return b.table(v)
+	case fbTables:
+		b.align(4, 0)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(len(v)))
+		b.buf = append(b.buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			b.patch(pos+4+4*i, b.table(t))
+		}
+		return pos
+	case fbStructs:
+		b.align(8, 4)
+		pos := len(b.buf)
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, uint32(v.n))
+		b.buf = append(b.buf, v.data...)
+		return pos
+	}
+	panic(fmt.Sprintf("arrow: cannot encode %T", v))
+}
+
+// fbReader decodes a flatbuffer, remembering the first error so that callers
+// can check once after a run of reads.
+type fbReader struct {
+	buf []byte
+	err error
+}
+
+// fbRef is a table in the buffer of r.
+type fbRef struct {
+	r   *fbReader
+	pos int
+}
+
+func (r *fbReader) fail(format string, args ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, args...)
+	}
+}
+
+// bytes returns n bytes at pos, or zeros if they are outside the buffer.
+func (r *fbReader) bytes(pos, n int) []byte {
+	if pos < 0 || n < 0 || pos > len(r.buf)-n {
+		r.fail("flatbuffer offset %d outside %d bytes", pos, len(r.buf))
+		return make([]byte, max(n, 0))
+	}
+	return r.buf[pos : pos+n]
+}
+
+func (r *fbReader) u32(pos int) uint32 { return binary.LittleEndian.Uint32(r.bytes(pos, 4)) }
+
+// root returns the root table.
+func (r *fbReader) root() fbRef {
+	return r.deref(0)
+}
+
+// deref follows the offset stored at pos.
+func (r *fbReader) deref(pos int) fbRef {
+	off := r.u32(pos)
+	if uint64(pos)+uint64(off) > uint64(len(r.buf)) {
+		r.fail("flatbuffer offset %d outside %d bytes", off, len(r.buf))
+		return fbRef{r, 0}
+	}
+	return fbRef{r, pos + int(off)}
+}
+
+// field returns the position of field i, or 0 if it is absent.
+func (t fbRef) field(i int) int {
+	vtable := int64(t.pos) - int64(int32(t.r.u32(t.pos)))
+	if vtable < 0 || vtable > int64(len(t.r.buf)) {
+		t.r.fail("flatbuffer vtable outside the buffer")
+		return 0
+	}
+	size := int(binary.LittleEndian.Uint16(t.r.bytes(int(vtable), 2)))
+	if 4+2*i+2 > size {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(t.r.bytes(int(vtable)+4+2*i, 2)))
+	if off == 0 {
+		return 0
+	}
+	return t.pos + off
+}
+
+// scalar returns the n little-endian bytes of field i, or zeros if it is
+// absent.
+func (t fbRef) scalar(i, n int) []byte {
+	if pos := t.field(i); pos != 0 {
+		return t.r.bytes(pos, n)
+	}
+	return make([]byte, n)
+}
+
+func (t fbRef) u8(i int) uint8  { return t.scalar(i, 1)[0] }
+func (t fbRef) i16(i int) int16 { return int16(binary.LittleEndian.Uint16(t.scalar(i, 2))) }
+func (t fbRef) i32(i int) int32 { return int32(binary.LittleEndian.Uint32(t.scalar(i, 4))) }
+func (t fbRef) i64(i int) int64 { return int64(binary.LittleEndian.Uint64(t.scalar(i, 8))) }
+
+// table returns the table in field i, or false if it is absent.
+func (t fbRef) table(i int) (fbRef, bool) {
+	pos := t.field(i)
+	if pos == 0 {
+		return fbRef{}, false
+	}
+	return t.r.deref(pos), true
+}
+
+// vector returns the position of the first element and the length of the
+// vector in field i, which has elements of size bytes.
+func (t fbRef) vector(i, size int) (int, int) {
+	pos := t.field(i)
+	if pos == 0 {
+		return 0, 0
+	}
+	v := t.r.deref(pos).pos
+	n := int(t.r.u32(v))
+	if n > (len(t.r.buf)-v-4)/size {
+		t.r.fail("flatbuffer vector of %d elements overruns the buffer", n)
+		return 0, 0
+	}
+	return v + 4, n
+}
+
+// str returns the string in field i.
+func (t fbRef) str(i int) string {
+	start, n := t.vector(i, 1)
+	return string(t.r.bytes(start, n))
+}
+
+// tables returns the tables in the vector of field i.
+func (t fbRef) tables(i int) []fbRef {
+	start, n := t.vector(i, 4)
+	refs := make([]fbRef, n)
+	for k := range refs {
+		refs[k] = t.r.deref(start + 4*k)
+	}
+	return refs
+}
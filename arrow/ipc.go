@@ -0,0 +1,513 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Column is a named array, one field of a record batch.
+type Column struct {
+	Name  string
+	Array *Array
+}
+
+// Flatbuffer union and enum values from the Arrow format's Schema.fbs and
+// Message.fbs.
+const (
+	metadataV5 = 4
+
+	headerSchema          = 1
+	headerDictionaryBatch = 2
+	headerRecordBatch     = 3
+
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeBool          = 6
+	typeFixedSizeList = 16
+
+	precisionHalf   = 0
+	precisionSingle = 1
+	precisionDouble = 2
+
+	codecLZ4Frame = 0
+	codecZSTD     = 1
+)
+
+// Extension type metadata keys and the name of the tensor extension.
+const (
+	extensionNameKey     = "ARROW:extension:name"
+	extensionMetadataKey = "ARROW:extension:metadata"
+	tensorExtension      = "arrow.fixed_shape_tensor"
+)
+
+// continuation starts every encapsulated message of an IPC stream.
+const continuation = 0xffffffff
+
+// maxMetadataSize bounds the flatbuffer metadata of one message.
+const maxMetadataSize = 64 << 20
+
+// tensorMetadata is the JSON metadata of the tensor extension type.
+type tensorMetadata struct {
+	Shape       []int `json:"shape"`
+	Permutation []int `json:"permutation,omitempty"`
+}
+
+// WriteStream writes columns to w as an Arrow IPC stream holding a schema and
+// one record batch. All arrays must have the same length.
+func WriteStream(w io.Writer, columns []Column) error {
+	schema, batch, body, bodyLength, err := encodeColumns(columns)
+	if err != nil {
+		return err
+	}
+	if _, err := writeMessage(w, headerSchema, schema, nil, 0); err != nil {
+		return err
+	}
+	if _, err := writeMessage(w, headerRecordBatch, batch, body, bodyLength); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, []uint32{continuation, 0})
+}
+
+// encodeColumns returns the Schema and RecordBatch tables of columns and the
+// body of the record batch, padded to 8 bytes per buffer, with its length.
+func encodeColumns(columns []Column) (fbTable, fbTable, [][]byte, int64, error) {
+	fields := make(fbTables, len(columns))
+	var nodes, buffers []byte
+	var body [][]byte
+	var offset int64
+	for i, c := range columns {
+		a := c.Array
+		if a.Len != columns[0].Array.Len {
+			return nil, nil, nil, 0, fmt.Errorf("arrow: column %s has %d rows, %s has %d", c.Name, a.Len, columns[0].Name, columns[0].Array.Len)
+		}
+		if a.Type.bitWidth() == 0 {
+			return nil, nil, nil, 0, fmt.Errorf("arrow: column %s has unknown type %v", c.Name, a.Type)
+		}
+		size, ok := a.valuesBytes()
+		if !ok {
+			return nil, nil, nil, 0, fmt.Errorf("arrow: column %s of %d slots of shape %v is too large", c.Name, a.Len, a.Shape)
+		}
+		if len(a.Values) < size {
+			return nil, nil, nil, 0, fmt.Errorf("arrow: column %s has %d bytes of values, %d needed", c.Name, len(a.Values), size)
+		}
+		field, err := schemaField(c)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		fields[i] = field
+
+		// Every array has an empty validity buffer; lists add their child
+		nodes = appendPair(nodes, int64(a.Len), 0)
+		buffers = appendPair(buffers, offset, 0)
+		if a.Shape != nil {
+			nodes = appendPair(nodes, int64(a.Len*a.Shape.ElemCount()), 0)
+			buffers = appendPair(buffers, offset, 0)
+		}
+		buffers = appendPair(buffers, offset, int64(size))
+		body = append(body, a.Values[:size])
+		if pad := (8 - size%8) % 8; pad > 0 {
+			body = append(body, make([]byte, pad))
+		}
+		offset += int64(size + (8-size%8)%8)
+	}
+
+	length := 0
+	if len(columns) > 0 {
+		length = columns[0].Array.Len
+	}
+	schema := fbTable{int16(0), fields}
+	batch := fbTable{
+		int64(length),
+		fbStructs{len(nodes) / 16, nodes},
+		fbStructs{len(buffers) / 16, buffers},
+	}
+	return schema, batch, body, offset, nil
+}
+
+// appendPair appends two little-endian int64 values, as in the FieldNode and
+// Buffer structs.
+func appendPair(b []byte, x, y int64) []byte {
+	b = binary.LittleEndian.AppendUint64(b, uint64(x))
+	return binary.LittleEndian.AppendUint64(b, uint64(y))
+}
+
+// writeMessage writes an encapsulated message: the continuation marker, the
+// metadata length, the Message flatbuffer, and the body. It returns the size
+// of the message before the body.
+func writeMessage(w io.Writer, headerType uint8, header fbTable, body [][]byte, bodyLength int64) (int, error) {
+	meta := buildFlatbuffer(fbTable{int16(metadataV5), headerType, header, bodyLength})
+	if err := binary.Write(w, binary.LittleEndian, []uint32{continuation, uint32(len(meta))}); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(meta); err != nil {
+		return 0, err
+	}
+	for _, b := range body {
+		if _, err := w.Write(b); err != nil {
+			return 0, err
+		}
+	}
+	return 8 + len(meta), nil
+}
+
+// schemaField returns the Field table of a column.
+func schemaField(c Column) (fbTable, error) {
+	a := c.Array
+	var typeID uint8
+	var typ fbTable
+	switch a.Type {
+	case Bool:
+		typeID, typ = typeBool, fbTable{}
+	case Float16:
+		typeID, typ = typeFloatingPoint, fbTable{int16(precisionHalf)}
+	case Float32:
+		typeID, typ = typeFloatingPoint, fbTable{int16(precisionSingle)}
+	case Float64:
+		typeID, typ = typeFloatingPoint, fbTable{int16(precisionDouble)}
+	default:
+		typeID, typ = typeInt, fbTable{int32(a.Type.bitWidth()), a.Type.signed()}
+	}
+	// Fields are name, nullable, type_type, type, dictionary, children, and
+	// custom_metadata
+	if a.Shape == nil {
+		return fbTable{c.Name, false, typeID, typ, nil, fbTables{}}, nil
+	}
+	meta, err := json.Marshal(tensorMetadata{Shape: a.Shape})
+	if err != nil {
+		return nil, err
+	}
+	item := fbTable{"item", false, typeID, typ, nil, fbTables{}}
+	return fbTable{
+		c.Name, false, uint8(typeFixedSizeList), fbTable{int32(a.Shape.ElemCount())}, nil,
+		fbTables{item},
+		fbTables{{extensionNameKey, tensorExtension}, {extensionMetadataKey, string(meta)}},
+	}, nil
+}
+
+// ReadStream reads the columns of an Arrow IPC stream. Record batches are
+// concatenated. Columns must be primitive arrays of the types of this package
+// or fixed-size lists of them, such as fixed_shape_tensor arrays, without
+// nulls. A fixed-size list without the extension type gets a 1-D Shape.
+func ReadStream(r io.Reader) ([]Column, error) {
+	var columns []Column
+	schema := false
+	for {
+		msg, body, err := readMessage(r)
+		if err != nil {
+			return nil, err
+		}
+		if msg == nil {
+			break
+		}
+		header, ok := msg.table(2)
+		switch typ := msg.u8(1); {
+		case !ok:
+			return nil, fmt.Errorf("arrow: message without a header")
+		case typ == headerSchema && !schema:
+			columns, err = readSchema(header)
+			schema = true
+		case typ == headerRecordBatch && schema:
+			err = readRecordBatch(header, body, columns)
+		case typ == headerDictionaryBatch:
+			err = fmt.Errorf("arrow: dictionary-encoded columns are not supported")
+		default:
+			err = fmt.Errorf("arrow: unexpected message type %d", typ)
+		}
+		if err == nil {
+			err = msg.r.err
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !schema {
+		return nil, fmt.Errorf("arrow: stream has no schema")
+	}
+	return columns, nil
+}
+
+// readMessage reads one encapsulated message and its body, or returns a nil
+// message at the end of the stream.
+func readMessage(r io.Reader) (*fbRef, []byte, error) {
+	var word [4]byte
+	if _, err := io.ReadFull(r, word[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	// Streams written before Arrow 0.15 have no continuation marker
+	size := binary.LittleEndian.Uint32(word[:])
+	if size == continuation {
+		if _, err := io.ReadFull(r, word[:]); err != nil {
+			return nil, nil, err
+		}
+		size = binary.LittleEndian.Uint32(word[:])
+	}
+	if size == 0 {
+		return nil, nil, nil
+	}
+	if size > maxMetadataSize {
+		return nil, nil, fmt.Errorf("arrow: message metadata of %d bytes", size)
+	}
+	meta := make([]byte, size)
+	if _, err := io.ReadFull(r, meta); err != nil {
+		return nil, nil, err
+	}
+	fr := &fbReader{buf: meta}
+	msg := fr.root()
+	bodyLength := msg.i64(3)
+	if fr.err != nil {
+		return nil, nil, fmt.Errorf("arrow: decoding message: %w", fr.err)
+	}
+	if bodyLength < 0 {
+		return nil, nil, fmt.Errorf("arrow: message body of %d bytes", bodyLength)
+	}
+	body, err := io.ReadAll(io.LimitReader(r, bodyLength))
+	if err != nil {
+		return nil, nil, err
+	}
+	if int64(len(body)) != bodyLength {
+		return nil, nil, fmt.Errorf("arrow: message body truncated: %w", io.ErrUnexpectedEOF)
+	}
+	return &msg, body, nil
+}
+
+// readSchema returns empty columns for the fields of a Schema table.
+func readSchema(schema fbRef) ([]Column, error) {
+	if endianness := schema.i16(0); endianness != 0 {
+		return nil, fmt.Errorf("arrow: big-endian streams are not supported")
+	}
+	fields := schema.tables(1)
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		name := f.str(0)
+		a, err := readField(f)
+		if err != nil {
+			return nil, fmt.Errorf("arrow: column %s: %w", name, err)
+		}
+		columns[i] = Column{Name: name, Array: a}
+	}
+	return columns, nil
+}
+
+// readField returns an empty array of the type of a Field table.
+func readField(f fbRef) (*Array, error) {
+	if _, ok := f.table(4); ok {
+		return nil, fmt.Errorf("dictionary-encoded columns are not supported")
+	}
+	if f.u8(2) != typeFixedSizeList {
+		typ, err := readType(f)
+		return &Array{Type: typ}, err
+	}
+
+	list, _ := f.table(3)
+	size := int(list.i32(0))
+	if size <= 0 {
+		return nil, fmt.Errorf("fixed-size list of size %d", size)
+	}
+	children := f.tables(5)
+	if len(children) != 1 {
+		return nil, fmt.Errorf("fixed-size list with %d children", len(children))
+	}
+	typ, err := readType(children[0])
+	if err != nil {
+		return nil, err
+	}
+	a := &Array{Type: typ, Shape: gonpy.Shape{size}}
+
+	var extension, meta string
+	for _, kv := range f.tables(6) {
+		switch kv.str(0) {
+		case extensionNameKey:
+			extension = kv.str(1)
+		case extensionMetadataKey:
+			meta = kv.str(1)
+		}
+	}
+	if extension == tensorExtension {
+		var m tensorMetadata
+		if err := json.Unmarshal([]byte(meta), &m); err != nil {
+			return nil, fmt.Errorf("tensor extension metadata: %w", err)
+		}
+		for i, p := range m.Permutation {
+			if p != i {
+				return nil, fmt.Errorf("permuted tensor dimensions are not supported")
+			}
+		}
+		a.Shape = gonpy.Shape(m.Shape)
+		if n, err := a.Shape.CheckedElemCount(); err != nil || n != size {
+			return nil, fmt.Errorf("tensor shape %v does not match list size %d", m.Shape, size)
+		}
+	}
+	return a, nil
+}
+
+// readType returns the primitive type of a Field table.
+func readType(f fbRef) (Type, error) {
+	typ, ok := f.table(3)
+	if !ok && f.u8(2) != typeBool {
+		return 0, fmt.Errorf("field without a type")
+	}
+	switch f.u8(2) {
+	case typeBool:
+		return Bool, nil
+	case typeFloatingPoint:
+		switch typ.i16(0) {
+		case precisionHalf:
+			return Float16, nil
+		case precisionSingle:
+			return Float32, nil
+		case precisionDouble:
+			return Float64, nil
+		}
+	case typeInt:
+		bits, signed := int(typ.i32(0)), typ.u8(1) != 0
+		for _, t := range []Type{Int8, Uint8, Int16, Uint16, Int32, Uint32, Int64, Uint64} {
+			if t.bitWidth() == bits && t.signed() == signed {
+				return t, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("Arrow type %d is not supported: %w", f.u8(2), gonpy.ErrUnsupportedDType)
+}
+
+// readRecordBatch appends the values of a RecordBatch table to columns.
+// Buffers compressed with LZ4 are decompressed.
+func readRecordBatch(batch fbRef, body []byte, columns []Column) error {
+	compressed := false
+	if c, ok := batch.table(3); ok {
+		switch {
+		case c.u8(1) != 0:
+			return fmt.Errorf("arrow: body compression method %d is not supported", c.u8(1))
+		case c.u8(0) == codecZSTD:
+			return fmt.Errorf("arrow: ZSTD-compressed record batches are not supported")
+		case c.u8(0) != codecLZ4Frame:
+			return fmt.Errorf("arrow: compression codec %d is not supported", c.u8(0))
+		}
+		compressed = true
+	}
+	length := batch.i64(0)
+	nodeStart, nodeCount := batch.vector(1, 16)
+	bufStart, bufCount := batch.vector(2, 16)
+	fr := batch.r
+	node, buf := 0, 0
+	nextNode := func() (int64, int64) {
+		if node >= nodeCount {
+			fr.fail("record batch has too few field nodes")
+			return 0, 0
+		}
+		p := fr.bytes(nodeStart+16*node, 16)
+		node++
+		return int64(binary.LittleEndian.Uint64(p)), int64(binary.LittleEndian.Uint64(p[8:]))
+	}
+	nextBuffer := func() []byte {
+		if buf >= bufCount {
+			fr.fail("record batch has too few buffers")
+			return nil
+		}
+		p := fr.bytes(bufStart+16*buf, 16)
+		buf++
+		off, n := binary.LittleEndian.Uint64(p), binary.LittleEndian.Uint64(p[8:])
+		if off > uint64(len(body)) || n > uint64(len(body))-off {
+			fr.fail("buffer of %d bytes at %d outside a body of %d bytes", n, off, len(body))
+			return nil
+		}
+		b := body[off : off+n]
+		if !compressed || len(b) == 0 {
+			return b
+		}
+		// A compressed buffer starts with its uncompressed length, or -1 if
+		// it was left uncompressed
+		if len(b) < 8 {
+			fr.fail("compressed buffer of %d bytes", len(b))
+			return nil
+		}
+		size := int64(binary.LittleEndian.Uint64(b))
+		if size == -1 {
+			return b[8:]
+		}
+		b, err := lz4FrameDecode(b[8:], size)
+		if err != nil {
+			fr.fail("decompressing buffer: %v", err)
+		}
+		return b
+	}
+
+	for _, c := range columns {
+		a := c.Array
+		rows, nulls := nextNode()
+		nextBuffer() // Validity
+		if a.Shape != nil {
+			_, childNulls := nextNode()
+			nulls += childNulls
+			nextBuffer()
+		}
+		values := nextBuffer()
+		if fr.err != nil {
+			return fmt.Errorf("arrow: decoding record batch: %w", fr.err)
+		}
+		if rows != length || rows > math.MaxInt32 {
+			return fmt.Errorf("arrow: column %s has %d rows in a batch of %d", c.Name, rows, length)
+		}
+		if nulls != 0 {
+			return fmt.Errorf("arrow: column %s has %d nulls", c.Name, nulls)
+		}
+		if err := a.appendValues(int(rows), values); err != nil {
+			return fmt.Errorf("arrow: column %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// appendValues appends rows slots from a values buffer to a. The first batch
+// is used in place.
+func (a *Array) appendValues(rows int, values []byte) error {
+	perRow := 1
+	if a.Shape != nil {
+		perRow = a.Shape.ElemCount()
+	}
+	next := &Array{Type: a.Type, Len: rows, Shape: a.Shape}
+	need, ok := next.valuesBytes()
+	if !ok {
+		return fmt.Errorf("%d rows of shape %v are too large: %w", rows, a.Shape, gonpy.ErrTooLarge)
+	}
+	if len(values) < need {
+		return fmt.Errorf("values buffer of %d bytes, %d needed", len(values), need)
+	}
+	if rows > math.MaxInt-a.Len {
+		return fmt.Errorf("%d rows after %d overflow: %w", rows, a.Len, gonpy.ErrTooLarge)
+	}
+	combined := &Array{Type: a.Type, Len: a.Len + rows, Shape: a.Shape}
+	if _, ok := combined.valuesBytes(); !ok {
+		return fmt.Errorf("%d rows of shape %v are too large: %w", combined.Len, a.Shape, gonpy.ErrTooLarge)
+	}
+	have, _ := a.valuesBytes()
+	switch {
+	case a.Len == 0:
+		a.Values = values[:need]
+	case a.Type == Bool:
+		start, total := a.Len*perRow, (a.Len+rows)*perRow
+		bits := make([]byte, (total+7)/8)
+		copy(bits, a.Values[:have])
+		if start%8 != 0 {
+			bits[start/8] &= 1<<(start%8) - 1 // Padding bits are unspecified
+		}
+		for i := 0; i < rows*perRow; i++ {
+			if values[i/8]&(1<<(i%8)) != 0 {
+				bits[(start+i)/8] |= 1 << ((start + i) % 8)
+			}
+		}
+		a.Values = bits
+	default:
+		a.Values = append(a.Values[:have:have], values[:need]...)
+	}
+	a.Len += rows
+	return nil
+}
@@ -0,0 +1,121 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// lz4FrameMagic starts every LZ4 frame.
+const lz4FrameMagic = 0x184d2204
+
+var errCorruptLZ4 = errors.New("corrupt LZ4 data")
+
+// lz4FrameDecode decompresses an LZ4 frame holding size bytes, as Arrow
+// writes compressed buffers. Checksums are not verified.
+func lz4FrameDecode(src []byte, size int64) ([]byte, error) {
+	if size < 0 || size/255 > int64(len(src)) {
+		return nil, fmt.Errorf("LZ4 frame of %d bytes cannot hold %d bytes", len(src), size)
+	}
+	if len(src) < 7 || binary.LittleEndian.Uint32(src) != lz4FrameMagic {
+		return nil, fmt.Errorf("not an LZ4 frame")
+	}
+	flags := src[4]
+	if flags>>6 != 1 {
+		return nil, fmt.Errorf("LZ4 frame version %d", flags>>6)
+	}
+	pos := 6 // Magic, flags, and block descriptor
+	if flags&0x08 != 0 {
+		pos += 8 // Content size
+	}
+	if flags&0x01 != 0 {
+		return nil, fmt.Errorf("LZ4 frames with dictionaries are not supported")
+	}
+	pos++ // Header checksum
+	blockChecksum := flags&0x10 != 0
+
+	dst := make([]byte, 0, size)
+	for {
+		if pos+4 > len(src) {
+			return nil, errCorruptLZ4
+		}
+		n := binary.LittleEndian.Uint32(src[pos:])
+		pos += 4
+		if n == 0 {
+			break // End mark, possibly followed by a content checksum
+		}
+		raw := n&0x80000000 != 0
+		n &^= 0x80000000
+		if uint64(n) > uint64(len(src)-pos) {
+			return nil, errCorruptLZ4
+		}
+		block := src[pos : pos+int(n)]
+		pos += int(n)
+		if blockChecksum {
+			pos += 4
+		}
+		var err error
+		if raw {
+			if int64(len(dst)+len(block)) > size {
+				return nil, errCorruptLZ4
+			}
+			dst = append(dst, block...)
+		} else if dst, err = lz4BlockDecode(dst, block, size); err != nil {
+			return nil, err
+		}
+	}
+	if int64(len(dst)) != size {
+		return nil, fmt.Errorf("LZ4 frame holds %d bytes, %d expected", len(dst), size)
+	}
+	return dst, nil
+}
+
+// lz4BlockDecode appends the decompressed block to dst, which also holds
+// the data of earlier blocks that matches may refer to. Each sequence is a
+// token holding the literal and match lengths, extra length bytes, the
+// literals, and the match's 2-byte offset; the last has only literals.
+func lz4BlockDecode(dst, block []byte, size int64) ([]byte, error) {
+	length := func(n int) (int, bool) {
+		if n != 15 {
+			return n, true
+		}
+		for {
+			if len(block) == 0 {
+				return 0, false
+			}
+			b := block[0]
+			block = block[1:]
+			n += int(b)
+			if b != 255 {
+				return n, true
+			}
+		}
+	}
+	for len(block) > 0 {
+		token := block[0]
+		block = block[1:]
+		lit, ok := length(int(token >> 4))
+		if !ok || lit > len(block) || int64(len(dst)+lit) > size {
+			return nil, errCorruptLZ4
+		}
+		dst = append(dst, block[:lit]...)
+		block = block[lit:]
+		if len(block) == 0 {
+			break
+		}
+		if len(block) < 2 {
+			return nil, errCorruptLZ4
+		}
+		offset := int(binary.LittleEndian.Uint16(block))
+		block = block[2:]
+		match, ok := length(int(token & 15))
+		match += 4
+		if !ok || offset == 0 || offset > len(dst) || int64(len(dst)+match) > size {
+			return nil, errCorruptLZ4
+		}
+		for i := 0; i < match; i++ { // Matches may overlap their output
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	return dst, nil
+}
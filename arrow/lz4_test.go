@@ -0,0 +1,77 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// lz4Frame returns an LZ4 frame of the given blocks without checksums. Raw
+// blocks are stored uncompressed.
+func lz4Frame(raw bool, blocks ...string) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, lz4FrameMagic)
+	b = append(b, 0x40, 0x40, 0) // Version 1, 64 KiB blocks, header checksum
+	for _, block := range blocks {
+		n := uint32(len(block))
+		if raw {
+			n |= 0x80000000
+		}
+		b = binary.LittleEndian.AppendUint32(b, n)
+		b = append(b, block...)
+	}
+	return binary.LittleEndian.AppendUint32(b, 0)
+}
+
+func TestLZ4FrameDecode(t *testing.T) {
+	tests := []struct {
+		frame []byte
+		want  string
+	}{
+		// Three literals, then an overlapping match of 9 bytes at offset 3
+		{lz4Frame(false, "\x35abc\x03\x00"), "abcabcabcabc"},
+		// A match referring to the previous block, then trailing literals
+		{lz4Frame(false, "\x20xy", "\x10z\x02\x00"), "xyzyzyz"},
+		// A literal run of 15 or more bytes has extra length bytes
+		{lz4Frame(false, "\xf0\x01abcdefghijklmnop"), "abcdefghijklmnop"},
+		{lz4Frame(true, "raw"), "raw"},
+		{lz4Frame(false), ""},
+	}
+	for _, tt := range tests {
+		got, err := lz4FrameDecode(tt.frame, int64(len(tt.want)))
+		if err != nil {
+			t.Errorf("%q: %v", tt.want, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("decoded %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestHostileLZ4(t *testing.T) {
+	valid := lz4Frame(false, "\x35abc\x03\x00")
+	tests := map[string]struct {
+		frame []byte
+		size  int64
+	}{
+		"magic":          {append([]byte{0}, valid[1:]...), 12},
+		"version":        {append(append([]byte{}, valid[:4]...), append([]byte{0x80}, valid[5:]...)...), 12},
+		"dictionary":     {append(append([]byte{}, valid[:4]...), append([]byte{0x41}, valid[5:]...)...), 12},
+		"short size":     {valid, 11},
+		"long size":      {valid, 13},
+		"huge size":      {valid, 1 << 40},
+		"negative size":  {valid, -1},
+		"no end mark":    {valid[:len(valid)-4], 12},
+		"block overrun":  {lz4Frame(false, "\x35abc\x03\x00")[:12], 12},
+		"zero offset":    {lz4Frame(false, "\x35abc\x00\x00"), 12},
+		"far offset":     {lz4Frame(false, "\x35abc\x04\x00"), 12},
+		"literal length": {lz4Frame(false, "\xf0"), 15},
+		"literals":       {lz4Frame(false, "\x50abc"), 5},
+		"short offset":   {lz4Frame(false, "\x35abc\x03"), 12},
+		"raw overrun":    {lz4Frame(true, "raw"), 2},
+	}
+	for name, tt := range tests {
+		if _, err := lz4FrameDecode(tt.frame, tt.size); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package gonpy
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WithAtomicWrite makes WriteNPY, WriteNPZ, and NpzWriter write to a temporary
+// file in the destination's directory and rename it into place only once it is
+// complete, so that the destination never holds a partially written file. The
+// temporary file is removed if writing fails.
+func WithAtomicWrite() Option {
+	return func(o *options) {
+		o.atomic = true
+	}
+}
+
+// WithSync flushes written files to stable storage before they are closed and,
+// for atomic writes, before they are renamed into place.
+func WithSync() Option {
+	return func(o *options) {
+		o.sync = true
+	}
+}
+
+// outputFile is a file being written for a destination path. When the file is
+// temporary, its name differs from path and commit renames it over path.
+type outputFile struct {
+	*os.File
+	path string
+	sync bool
+}
+
+// createOutput creates the file for writing to path, as a temporary file if
+// atomic writes were requested.
+func createOutput(path string, o *options) (*outputFile, error) {
+	if o.atomic {
+		return createTemp(path, o)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &outputFile{File: f, path: path, sync: o.sync}, nil
+}
+
+// createTemp creates a temporary file in the directory of path that commit
+// renames over it. The file takes the mode of any existing file at path.
+func createTemp(path string, o *options) (*outputFile, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &outputFile{File: f, path: path, sync: o.sync}, nil
+}
+
+// temporary reports whether the file is written under a temporary name.
+func (f *outputFile) temporary() bool {
+	return f.Name() != f.path
+}
+
+// commit syncs the file if requested, closes it, and renames it into place.
+// On failure a temporary file is removed.
+func (f *outputFile) commit() error {
+	if f.sync {
+		if err := f.Sync(); err != nil {
+			f.discard()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		f.discard()
+		return err
+	}
+	if !f.temporary() {
+		return nil
+	}
+	if err := os.Rename(f.Name(), f.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if f.sync {
+		syncDir(filepath.Dir(f.path))
+	}
+	return nil
+}
+
+// discard closes the file after a failed write, removing it if it is temporary.
+// A file written in place is left as it is.
+func (f *outputFile) discard() {
+	f.Close()
+	if f.temporary() {
+		os.Remove(f.Name())
+	}
+}
+
+// syncDir flushes a directory so that a rename within it is durable. Errors
+// are ignored, since not every platform can sync a directory.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
@@ -0,0 +1,78 @@
+package gonpy
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteFailure(t *testing.T) {
+	bad := &Tensor{Data: []float32{1}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"}
+	writes := map[string]func(path string, opts ...Option) error{
+		"WriteNPY": func(path string, opts ...Option) error {
+			return bad.WriteNPY(path, opts...)
+		},
+		"WriteNPZ": func(path string, opts ...Option) error {
+			return WriteNPZ(path, map[string]*Tensor{"x": bad}, opts...)
+		},
+		"AppendNPZ": func(path string, opts ...Option) error {
+			return AppendNPZ(path, map[string]*Tensor{"x": bad}, opts...)
+		},
+	}
+	for name, write := range writes {
+		for _, opts := range [][]Option{{WithAtomicWrite()}, {WithAtomicWrite(), WithSync()}} {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.npz")
+			if err := WriteNPZ(path, map[string]*Tensor{"y": {Data: []int64{1}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"}}); err != nil {
+				t.Fatal(err)
+			}
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := write(path, opts...); err == nil {
+				t.Fatalf("%s: short tensor written", name)
+			}
+			after, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(before, after) {
+				t.Errorf("%s: original file changed: %v", name, err)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range entries {
+				if e.Name() != "a.npz" {
+					t.Errorf("%s: %s left behind", name, e.Name())
+				}
+			}
+		}
+	}
+}
+
+func TestAtomicWriteKeepsMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.npy")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	x := &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"}
+	if err := x.WriteNPY(path, WithAtomicWrite()); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+	if out, err := ReadNPY(path); err != nil || !out.Shape.Equal(Shape{2}) {
+		t.Errorf("ReadNPY = %v, %v", out, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d files, want 1", len(entries))
+	}
+}
@@ -0,0 +1,247 @@
+// Package bench measures gonpy read and write throughput over parameterized workloads.
+//
+// A Config describes the cartesian product of element counts, dtypes, storage
+// formats, and parallelism levels to exercise. Run executes every combination
+// in a scratch directory and reports one Result per combination, which can be
+// emitted as JSON for comparison across machines and storage configurations.
+package bench
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Format selects how a workload tensor is stored on disk.
+type Format string
+
+const (
+	FormatNPY        Format = "npy"         // Plain NPY file
+	FormatNPZStore   Format = "npz-store"   // NPZ archive without compression
+	FormatNPZDeflate Format = "npz-deflate" // NPZ archive with deflate compression
+)
+
+// Config describes the workloads to run.
+type Config struct {
+	Sizes       []int         // Number of elements per tensor
+	DTypes      []gonpy.DType // Element types to exercise
+	Formats     []Format      // Storage formats to exercise
+	Parallelism []int         // Number of concurrent workers, each with its own file
+	Iterations  int           // Write/read rounds per workload; defaults to 1
+	Dir         string        // Scratch directory; a temporary one is used if empty
+	Seed        uint64        // Seed for the generated tensor contents
+}
+
+// DefaultConfig returns a small configuration suitable for a quick survey.
+func DefaultConfig() Config {
+	return Config{
+		Sizes:       []int{1 << 10, 1 << 20},
+		DTypes:      []gonpy.DType{gonpy.DTypeF32, gonpy.DTypeF64, gonpy.DTypeI64, gonpy.DTypeU8},
+		Formats:     []Format{FormatNPY, FormatNPZStore, FormatNPZDeflate},
+		Parallelism: []int{1, 4},
+		Iterations:  3,
+	}
+}
+
+// Result reports the measured throughput of a single workload.
+type Result struct {
+	Format      Format      `json:"format"`
+	DType       gonpy.DType `json:"dtype"`
+	Elements    int         `json:"elements"`
+	Parallelism int         `json:"parallelism"`
+	Iterations  int         `json:"iterations"`
+	Bytes       int64       `json:"bytes"`      // Payload bytes moved per direction, across all workers and iterations
+	FileBytes   int64       `json:"file_bytes"` // On-disk size of a single worker's file
+	WriteNanos  int64       `json:"write_ns"`
+	ReadNanos   int64       `json:"read_ns"`
+	WriteMBps   float64     `json:"write_mbps"`
+	ReadMBps    float64     `json:"read_mbps"`
+}
+
+// Run executes every workload described by cfg and returns their results.
+func Run(cfg Config) ([]Result, error) {
+	if cfg.Iterations <= 0 {
+		cfg.Iterations = 1
+	}
+	dir := cfg.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "gonpy-bench-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+	var results []Result
+	for _, dtype := range cfg.DTypes {
+		for _, size := range cfg.Sizes {
+			tensor, err := Generate(rng, dtype, size)
+			if err != nil {
+				return nil, err
+			}
+			for _, format := range cfg.Formats {
+				for _, workers := range cfg.Parallelism {
+					if workers <= 0 {
+						workers = 1
+					}
+					res, err := runWorkload(dir, tensor, format, workers, cfg.Iterations)
+					if err != nil {
+						return nil, fmt.Errorf("%s/%s/%d/%d: %w", format, dtype, size, workers, err)
+					}
+					results = append(results, res)
+				}
+			}
+		}
+	}
+	return results, nil
+}
+
+// WriteJSON writes the results as an indented JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// runWorkload times writing and reading a tensor with the given format and worker count.
+func runWorkload(dir string, t *gonpy.Tensor, format Format, workers, iterations int) (Result, error) {
+	paths := make([]string, workers)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("w%d.%s", i, extension(format)))
+	}
+	defer func() {
+		for _, p := range paths {
+			os.Remove(p)
+		}
+	}()
+
+	var writeDur, readDur time.Duration
+	for range iterations {
+		d, err := parallel(paths, func(p string) error { return write(p, t, format) })
+		if err != nil {
+			return Result{}, err
+		}
+		writeDur += d
+
+		d, err = parallel(paths, func(p string) error { return read(p, format) })
+		if err != nil {
+			return Result{}, err
+		}
+		readDur += d
+	}
+
+	info, err := os.Stat(paths[0])
+	if err != nil {
+		return Result{}, err
+	}
+
+	payload := int64(t.Shape.ElemCount() * t.DType.Size())
+	moved := payload * int64(workers) * int64(iterations)
+	return Result{
+		Format:      format,
+		DType:       t.DType,
+		Elements:    t.Shape.ElemCount(),
+		Parallelism: workers,
+		Iterations:  iterations,
+		Bytes:       moved,
+		FileBytes:   info.Size(),
+		WriteNanos:  writeDur.Nanoseconds(),
+		ReadNanos:   readDur.Nanoseconds(),
+		WriteMBps:   throughput(moved, writeDur),
+		ReadMBps:    throughput(moved, readDur),
+	}, nil
+}
+
+// parallel runs fn once per path concurrently and returns the wall-clock duration.
+func parallel(paths []string, fn func(string) error) (time.Duration, error) {
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i, p := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(p)
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	for _, err := range errs {
+		if err != nil {
+			return 0, err
+		}
+	}
+	return elapsed, nil
+}
+
+// write stores the tensor at path using the given format.
+func write(path string, t *gonpy.Tensor, format Format) error {
+	switch format {
+	case FormatNPY:
+		return t.WriteNPY(path)
+	case FormatNPZStore, FormatNPZDeflate:
+		method := zip.Store
+		if format == FormatNPZDeflate {
+			method = zip.Deflate
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		zw := zip.NewWriter(f)
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: "x.npy", Method: method})
+		if err != nil {
+			return err
+		}
+		if err := t.Write(w); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		return f.Close()
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// read loads the tensor stored at path using the given format.
+func read(path string, format Format) error {
+	switch format {
+	case FormatNPY:
+		_, err := gonpy.ReadNPY(path)
+		return err
+	case FormatNPZStore, FormatNPZDeflate:
+		_, err := gonpy.ReadNPZ(path)
+		return err
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// extension returns the file extension used for a format.
+func extension(format Format) string {
+	if format == FormatNPY {
+		return "npy"
+	}
+	return "npz"
+}
+
+// throughput converts bytes over a duration into megabytes per second.
+func throughput(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) / d.Seconds() / 1e6
+}
@@ -0,0 +1,86 @@
+// Command npybench runs gonpy throughput workloads and prints the results as JSON.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gocnn/gonpy"
+	"github.com/gocnn/gonpy/bench"
+)
+
+func main() {
+	cfg := bench.DefaultConfig()
+
+	sizes := flag.String("sizes", joinInts(cfg.Sizes), "comma-separated element counts")
+	dtypes := flag.String("dtypes", joinDTypes(cfg.DTypes), "comma-separated dtypes")
+	formats := flag.String("formats", joinFormats(cfg.Formats), "comma-separated formats (npy, npz-store, npz-deflate)")
+	parallelism := flag.String("parallelism", joinInts(cfg.Parallelism), "comma-separated worker counts")
+	flag.IntVar(&cfg.Iterations, "iterations", cfg.Iterations, "write/read rounds per workload")
+	flag.StringVar(&cfg.Dir, "dir", "", "scratch directory (defaults to a temporary directory)")
+	flag.Uint64Var(&cfg.Seed, "seed", 0, "seed for generated data")
+	flag.Parse()
+
+	var err error
+	if cfg.Sizes, err = parseInts(*sizes); err != nil {
+		log.Fatalf("invalid -sizes: %v", err)
+	}
+	if cfg.Parallelism, err = parseInts(*parallelism); err != nil {
+		log.Fatalf("invalid -parallelism: %v", err)
+	}
+	cfg.DTypes = nil
+	for _, s := range strings.Split(*dtypes, ",") {
+		cfg.DTypes = append(cfg.DTypes, gonpy.DType(strings.TrimSpace(s)))
+	}
+	cfg.Formats = nil
+	for _, s := range strings.Split(*formats, ",") {
+		cfg.Formats = append(cfg.Formats, bench.Format(strings.TrimSpace(s)))
+	}
+
+	results, err := bench.Run(cfg)
+	if err != nil {
+		log.Fatalf("benchmark failed: %v", err)
+	}
+	if err := bench.WriteJSON(os.Stdout, results); err != nil {
+		log.Fatalf("failed to write results: %v", err)
+	}
+}
+
+func parseInts(s string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func joinInts(xs []int) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = strconv.Itoa(x)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinDTypes(xs []gonpy.DType) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = string(x)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinFormats(xs []bench.Format) string {
+	parts := make([]string, len(xs))
+	for i, x := range xs {
+		parts[i] = string(x)
+	}
+	return strings.Join(parts, ",")
+}
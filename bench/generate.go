@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Generate returns a 1-D tensor of n pseudo-random elements of the given dtype.
+func Generate(rng *rand.Rand, dtype gonpy.DType, n int) (*gonpy.Tensor, error) {
+	var data interface{}
+	switch dtype {
+	case gonpy.DTypeBF16, gonpy.DTypeF16:
+		d := make([]uint16, n)
+		for i := range d {
+			d[i] = uint16(rng.Uint32())
+		}
+		data = d
+	case gonpy.DTypeF32:
+		d := make([]float32, n)
+		for i := range d {
+			d[i] = rng.Float32()
+		}
+		data = d
+	case gonpy.DTypeF64:
+		d := make([]float64, n)
+		for i := range d {
+			d[i] = rng.Float64()
+		}
+		data = d
+	case gonpy.DTypeC64:
+		d := make([]complex64, n)
+		for i := range d {
+			d[i] = complex(rng.Float32(), rng.Float32())
+		}
+		data = d
+	case gonpy.DTypeC128:
+		d := make([]complex128, n)
+		for i := range d {
+			d[i] = complex(rng.Float64(), rng.Float64())
+		}
+		data = d
+	case gonpy.DTypeI32:
+		d := make([]int32, n)
+		for i := range d {
+			d[i] = rng.Int32()
+		}
+		data = d
+	case gonpy.DTypeI64:
+		d := make([]int64, n)
+		for i := range d {
+			d[i] = rng.Int64()
+		}
+		data = d
+	case gonpy.DTypeU64:
+		d := make([]uint64, n)
+		for i := range d {
+			d[i] = rng.Uint64()
+		}
+		data = d
+	case gonpy.DTypeU32:
+		d := make([]uint32, n)
+		for i := range d {
+			d[i] = rng.Uint32()
+		}
+		data = d
+	case gonpy.DTypeU8:
+		d := make([]byte, n)
+		for i := range d {
+			d[i] = byte(rng.Uint32())
+		}
+		data = d
+	case gonpy.DTypeBool:
+		d := make([]bool, n)
+		for i := range d {
+			d[i] = rng.IntN(2) == 1
+		}
+		data = d
+	default:
+		return nil, fmt.Errorf("cannot generate data for dtype %s", dtype)
+	}
+	return &gonpy.Tensor{
+		Data:   data,
+		Shape:  gonpy.Shape{n},
+		DType:  dtype,
+		Device: "cpu",
+	}, nil
+}
@@ -0,0 +1,49 @@
+package gonpy
+
+import "math"
+
+// bf16BitsToF32 converts a bfloat16 bit pattern to float32. bfloat16 is the
+// upper half of a float32, so the conversion is exact.
+func bf16BitsToF32(h uint16) float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// f32ToBF16Bits converts a float32 to a bfloat16 bit pattern, rounding to
+// nearest with ties to even. NaNs stay NaN.
+func f32ToBF16Bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	if b&0x7fffffff > 0x7f800000 { // NaN
+		return uint16(b>>16) | 0x0040
+	}
+	b += 0x7fff + (b>>16)&1
+	return uint16(b >> 16)
+}
+
+// BF16ToF32 converts bfloat16 bit patterns to float32 values.
+func BF16ToF32(src []uint16) []float32 {
+	dst := make([]float32, len(src))
+	bf16ToF32s(dst, src)
+	return dst
+}
+
+// F32ToBF16 converts float32 values to bfloat16 bit patterns, rounding to
+// nearest with ties to even.
+func F32ToBF16(src []float32) []uint16 {
+	dst := make([]uint16, len(src))
+	f32ToBF16s(dst, src)
+	return dst
+}
+
+// bf16ToF32sGeneric converts src into dst one element at a time.
+func bf16ToF32sGeneric(dst []float32, src []uint16) {
+	for i, h := range src {
+		dst[i] = bf16BitsToF32(h)
+	}
+}
+
+// f32ToBF16sGeneric converts src into dst one element at a time.
+func f32ToBF16sGeneric(dst []uint16, src []float32) {
+	for i, f := range src {
+		dst[i] = f32ToBF16Bits(f)
+	}
+}
@@ -0,0 +1,35 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Tensors are registered with gob so that they can be sent as values of
+// interface types, such as in a map[string]interface{}.
+func init() {
+	gob.Register(&Tensor{})
+}
+
+// MarshalBinary encodes the tensor in NPY format, implementing
+// encoding.BinaryMarshaler. Tensors therefore encode with gob, and fit caches
+// and RPC layers that store binary values.
+func (t *Tensor) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.write(&buf, newOptions(nil)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a tensor encoded by MarshalBinary, or the content
+// of any NPY file, implementing encoding.BinaryUnmarshaler. Object arrays are
+// rejected.
+func (t *Tensor) UnmarshalBinary(b []byte) error {
+	result, err := readTensor(bytes.NewReader(b), int64(len(b)), newOptions(nil))
+	if err != nil {
+		return err
+	}
+	*t = *result
+	return nil
+}
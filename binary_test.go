@@ -0,0 +1,60 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"testing"
+)
+
+func TestBinaryRoundTrip(t *testing.T) {
+	in := &Tensor{Data: []int32{1, -2, 3, 4, 5, -6}, Shape: Shape{2, 3}, DType: DTypeI32, Device: "cpu"}
+	b, err := in.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out Tensor
+	if err := out.UnmarshalBinary(b); err != nil {
+		t.Fatal(err)
+	}
+	if out.DType != in.DType || !out.Shape.Equal(in.Shape) || fmt.Sprint(out.Data) != fmt.Sprint(in.Data) {
+		t.Errorf("decoded %s %v %v", out.DType, out.Shape, out.Data)
+	}
+}
+
+func TestGob(t *testing.T) {
+	in := map[string]interface{}{
+		"w": &Tensor{Data: []float32{1.5, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"},
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	w, ok := out["w"].(*Tensor)
+	if !ok {
+		t.Fatalf("decoded %T", out["w"])
+	}
+	if got := fmt.Sprint(w.Data); !w.Shape.Equal(Shape{2}) || got != "[1.5 2]" {
+		t.Errorf("w = %v %s", w.Shape, got)
+	}
+}
+
+func TestHostileUnmarshalBinary(t *testing.T) {
+	tests := map[string][]byte{
+		"empty":     nil,
+		"magic":     []byte("\x93NUMPX\x01\x00"),
+		"truncated": npyBytes("{'descr': '<f8', 'fortran_order': False, 'shape': (4,), }", make([]byte, 16)),
+		"huge":      npyBytes("{'descr': '<f8', 'fortran_order': False, 'shape': (1099511627776, 1099511627776), }", make([]byte, 8)),
+		"object":    npyBytes("{'descr': '|O', 'fortran_order': False, 'shape': (1,), }", make([]byte, 8)),
+	}
+	for name, b := range tests {
+		var out Tensor
+		if err := out.UnmarshalBinary(b); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
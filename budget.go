@@ -0,0 +1,86 @@
+package gonpy
+
+import "math"
+
+// ErrTooLarge is returned when the header of an array declares more data than
+// the limit set with WithMaxBytes, before any of it is allocated.
+var ErrTooLarge = ErrorNpy{Msg: "array exceeds the maximum size; raise the limit with WithMaxBytes"}
+
+// defaultMaxBytes is the largest array that is read unless WithMaxBytes says otherwise.
+const defaultMaxBytes = 16 << 30
+
+// WithMaxBytes limits the size of the data of each array read to n bytes, so
+// that a corrupt or malicious header cannot trigger an enormous allocation.
+// Larger arrays fail with ErrTooLarge. The default is 16 GiB; n <= 0 removes
+// the limit.
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// checkSize returns ErrTooLarge if an array of dtype and shape is larger than
+// the limit in o.
+func (o *options) checkSize(dtype DType, shape Shape) error {
+	if o.maxBytes <= 0 {
+		return nil
+	}
+	if size, ok := dataSize(dtype, shape); !ok || size > o.maxBytes {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// dataSize returns the number of bytes of data of an array of dtype and shape,
+// or false if the size does not fit in an int64.
+func dataSize(dtype DType, shape Shape) (int64, bool) {
+	for _, dim := range shape {
+		if dim == 0 {
+			return 0, true
+		}
+	}
+	size := int64(dtype.Size())
+	if dtype.isPacked() {
+		size = 1
+	}
+	for _, dim := range shape {
+		if dim < 0 || size > math.MaxInt64/int64(dim) {
+			return 0, false
+		}
+		size *= int64(dim)
+	}
+	return size, true
+}
+
+// ReadLimits holds the file limiter and size limit selected by a list of
+// options, so that readers of other formats, such as those in the gguf, onnx,
+// hdf5, and zarr packages, can apply them as gonpy's own readers do.
+type ReadLimits struct {
+	Limiter  *FileLimiter // Bounds files held open; nil imposes no limit
+	MaxBytes int64        // Largest array data read; 0 or less removes the limit
+}
+
+// Limits returns the file limiter and size limit that opts select, defaulting
+// as every gonpy reader does.
+func Limits(opts ...Option) ReadLimits {
+	o := newOptions(opts)
+	return ReadLimits{Limiter: o.limiter, MaxBytes: o.maxBytes}
+}
+
+// CheckBytes returns ErrTooLarge if n bytes of array data exceed the limit.
+func (l ReadLimits) CheckBytes(n int64) error {
+	if l.MaxBytes > 0 && n > l.MaxBytes {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// CheckSize returns ErrTooLarge if an array of dtype and shape exceeds the
+// limit or its size overflows an int64.
+func (l ReadLimits) CheckSize(dtype DType, shape Shape) error {
+	size, ok := dataSize(dtype, shape)
+	if !ok {
+		return ErrTooLarge
+	}
+	return l.CheckBytes(size)
+}
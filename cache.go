@@ -0,0 +1,122 @@
+package gonpy
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithCacheBytes enables a cache of decoded tensors in NpzTensors, holding up
+// to n bytes of tensor data. The least recently used tensors are evicted first.
+// Cached tensors are shared between calls to Get and must not be modified.
+func WithCacheBytes(n int64) Option {
+	return func(o *options) {
+		o.cacheBytes = n
+	}
+}
+
+// tensorCache is a least-recently-used cache of decoded tensors bounded by the
+// total size of their data.
+type tensorCache struct {
+	mu      sync.Mutex
+	budget  int64
+	used    int64
+	order   *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+// cacheEntry is a cached tensor and its data size.
+type cacheEntry struct {
+	name   string
+	tensor *Tensor
+	size   int64
+}
+
+// newTensorCache returns a cache holding up to budget bytes, or nil if budget is not positive.
+func newTensorCache(budget int64) *tensorCache {
+	if budget <= 0 {
+		return nil
+	}
+	return &tensorCache{budget: budget, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the cached tensor for name, if any. A nil cache holds nothing.
+func (c *tensorCache) get(name string) (*Tensor, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).tensor, true
+}
+
+// has reports whether name is cached without affecting its recency.
+func (c *tensorCache) has(name string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[name]
+	return ok
+}
+
+// put caches the tensor for name, evicting older tensors to stay within budget.
+// Tensors larger than the whole budget are not cached.
+func (c *tensorCache) put(name string, t *Tensor) {
+	if c == nil {
+		return
+	}
+	size := tensorBytes(t)
+	if size > c.budget {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[name]; ok {
+		c.used -= e.Value.(*cacheEntry).size
+		c.order.Remove(e)
+	}
+	for c.used+size > c.budget {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.name)
+		c.used -= entry.size
+	}
+	c.entries[name] = c.order.PushFront(&cacheEntry{name: name, tensor: t, size: size})
+	c.used += size
+}
+
+// tensorBytes returns the size of a tensor's data as stored.
+func tensorBytes(t *Tensor) int64 {
+	n := int64(t.Shape.ElemCount())
+	if t.DType.isPacked() {
+		return (n + 1) / 2
+	}
+	return n * int64(t.DType.Size())
+}
+
+// Preload decodes the named tensors into the cache in the background, so that
+// later calls to Get return them without decoding. It has no effect unless the
+// cache is enabled with WithCacheBytes. Errors are not reported; they surface
+// when the tensor is requested with Get.
+func (n *NpzTensors) Preload(names ...string) {
+	if n.cache == nil {
+		return
+	}
+	n.preloads.Add(1)
+	go func() {
+		defer n.preloads.Done()
+		for _, name := range names {
+			if n.cache.has(name) {
+				continue
+			}
+			n.Get(name)
+		}
+	}()
+}
@@ -0,0 +1,409 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+)
+
+// OverflowPolicy selects how Cast handles values the target dtype cannot represent.
+type OverflowPolicy int
+
+const (
+	// OverflowSaturate clamps out-of-range values to the nearest representable
+	// integer, maps NaN to zero, lets floats overflow to infinity, and drops the
+	// imaginary part of complex values converted to real dtypes.
+	OverflowSaturate OverflowPolicy = iota
+	// OverflowError fails the cast on any out-of-range value, NaN converted to an
+	// integer, finite value overflowing to infinity, or non-zero imaginary part
+	// converted to a real dtype.
+	OverflowError
+)
+
+// WithOverflow sets the overflow policy used by Cast. The default is OverflowSaturate.
+func WithOverflow(p OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = p
+	}
+}
+
+// castValues holds tensor data widened to one of four intermediate kinds.
+// Exactly one of the slices is non-nil.
+type castValues struct {
+	f []float64
+	i []int64
+	u []uint64
+	c []complex128
+}
+
+// integer is the set of element types of the integer dtypes.
+type integer interface {
+	~int8 | ~int32 | ~int64 | ~uint8 | ~uint32 | ~uint64
+}
+
+// Cast returns a new tensor holding the values of t converted to dtype.
+// Floating-point values are truncated toward zero when cast to integers, and
+// any non-zero value becomes true when cast to bool. Out-of-range values are
+// handled according to the WithOverflow option.
+func (t *Tensor) Cast(dtype DType, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	var v castValues
+	var err error
+	if t.DType.isPacked() {
+		v, err = widenPacked(t.DType, t.Data, t.Shape.ElemCount())
+	} else {
+		v, err = widen(t.DType, t.Data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	switch dtype {
+	case DTypeF64:
+		data, err = castFloats(v, o.overflow, func(x float64) (float64, bool) { return x, false })
+	case DTypeF32:
+		data, err = castFloats(v, o.overflow, func(x float64) (float32, bool) {
+			f := float32(x)
+			return f, isOverflow(x, float64(f))
+		})
+	case DTypeF16:
+		data, err = castFloats(v, o.overflow, func(x float64) (uint16, bool) {
+			h := f32ToF16Bits(float32(x))
+			return h, isOverflow(x, float64(f16BitsToF32(h)))
+		})
+	case DTypeBF16:
+		data, err = castFloats(v, o.overflow, func(x float64) (uint16, bool) {
+			h := f32ToBF16Bits(float32(x))
+			return h, isOverflow(x, float64(bf16BitsToF32(h)))
+		})
+	case DTypeC64:
+		c := toComplex(v)
+		out := make([]complex64, len(c))
+		for i, x := range c {
+			out[i] = complex64(x)
+			if o.overflow == OverflowError && (isOverflow(real(x), float64(real(out[i]))) || isOverflow(imag(x), float64(imag(out[i])))) {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("value %v overflows %s", x, dtype)}
+			}
+		}
+		data = out
+	case DTypeC128:
+		data = toComplex(v)
+	case DTypeI8:
+		data, err = castInts[int8](v, math.MinInt8, math.MaxInt8, o.overflow)
+	case DTypeI32:
+		data, err = castInts[int32](v, math.MinInt32, math.MaxInt32, o.overflow)
+	case DTypeI64:
+		data, err = castInts[int64](v, math.MinInt64, math.MaxInt64, o.overflow)
+	case DTypeU8:
+		data, err = castInts[uint8](v, 0, math.MaxUint8, o.overflow)
+	case DTypeU32:
+		data, err = castInts[uint32](v, 0, math.MaxUint32, o.overflow)
+	case DTypeU64:
+		data, err = castInts[uint64](v, 0, math.MaxUint64, o.overflow)
+	case DTypeBool:
+		data = castBools(v)
+	case DTypeI4:
+		var values []int8
+		if values, err = castInts[int8](v, -8, 7, o.overflow); err == nil {
+			data, err = PackInt4(values)
+		}
+	case DTypeU4:
+		var values []uint8
+		if values, err = castInts[uint8](v, 0, 15, o.overflow); err == nil {
+			data, err = PackUint4(values)
+		}
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot cast to %s", dtype)}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tensor{
+		Data:   data,
+		Shape:  append(Shape(nil), t.Shape...),
+		DType:  dtype,
+		Device: t.Device,
+	}, nil
+}
+
+// widen converts tensor data to its intermediate kind.
+func widen(dtype DType, data interface{}) (castValues, error) {
+	var v castValues
+	switch d := data.(type) {
+	case []uint16:
+		v.f = make([]float64, len(d))
+		switch dtype {
+		case DTypeF16:
+			for i, h := range d {
+				v.f[i] = float64(f16BitsToF32(h))
+			}
+		case DTypeBF16:
+			for i, h := range d {
+				v.f[i] = float64(bf16BitsToF32(h))
+			}
+		default:
+			return v, ErrorNpy{Msg: fmt.Sprintf("cannot cast from %s", dtype)}
+		}
+	case []float32:
+		v.f = widenSlice[float32, float64](d)
+	case []float64:
+		v.f = d
+	case []complex64:
+		v.c = make([]complex128, len(d))
+		for i, x := range d {
+			v.c[i] = complex128(x)
+		}
+	case []complex128:
+		v.c = d
+	case []int8:
+		if dtype != DTypeI8 {
+			return v, ErrorNpy{Msg: fmt.Sprintf("cannot cast from %s", dtype)}
+		}
+		v.i = widenSlice[int8, int64](d)
+	case []int32:
+		v.i = widenSlice[int32, int64](d)
+	case []int64:
+		v.i = d
+	case []uint64:
+		v.u = d
+	case []uint32:
+		v.u = widenSlice[uint32, uint64](d)
+	case []byte:
+		switch dtype {
+		case DTypeU8:
+			v.u = widenSlice[uint8, uint64](d)
+		default:
+			return v, ErrorNpy{Msg: fmt.Sprintf("cannot cast from %s", dtype)}
+		}
+	case []bool:
+		v.u = make([]uint64, len(d))
+		for i, b := range d {
+			if b {
+				v.u[i] = 1
+			}
+		}
+	default:
+		return v, ErrorNpy{Msg: fmt.Sprintf("cannot cast from %s", dtype)}
+	}
+	return v, nil
+}
+
+// widenPacked unpacks n 4-bit values to their intermediate kind.
+func widenPacked(dtype DType, data interface{}, n int) (castValues, error) {
+	var v castValues
+	packed, ok := data.([]byte)
+	if !ok || len(packed) != (n+1)/2 {
+		return v, ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not hold %d packed values", dtype, n)}
+	}
+	if dtype == DTypeI4 {
+		v.i = widenSlice[int8, int64](UnpackInt4(packed, n))
+	} else {
+		v.u = widenSlice[uint8, uint64](UnpackUint4(packed, n))
+	}
+	return v, nil
+}
+
+// widenSlice converts each element of src to the wider type W.
+func widenSlice[N, W int8 | int32 | int64 | uint8 | uint32 | uint64 | float32 | float64](src []N) []W {
+	dst := make([]W, len(src))
+	for i, x := range src {
+		dst[i] = W(x)
+	}
+	return dst
+}
+
+// isOverflow reports whether a finite source value became infinite.
+func isOverflow(src, dst float64) bool {
+	return !math.IsInf(src, 0) && !math.IsNaN(src) && math.IsInf(dst, 0)
+}
+
+// castFloats converts values to a floating-point element type with conv, which
+// also reports whether the conversion overflowed.
+func castFloats[T any](v castValues, p OverflowPolicy, conv func(float64) (T, bool)) ([]T, error) {
+	var src []float64
+	switch {
+	case v.f != nil:
+		src = v.f
+	case v.i != nil:
+		src = widenSlice[int64, float64](v.i)
+	case v.u != nil:
+		src = widenSlice[uint64, float64](v.u)
+	default:
+		src = make([]float64, len(v.c))
+		for i, x := range v.c {
+			if p == OverflowError && imag(x) != 0 {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("complex value %v has a non-zero imaginary part", x)}
+			}
+			src[i] = real(x)
+		}
+	}
+
+	out := make([]T, len(src))
+	for i, x := range src {
+		y, overflow := conv(x)
+		if overflow && p == OverflowError {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("value %v overflows target dtype", x)}
+		}
+		out[i] = y
+	}
+	return out, nil
+}
+
+// castInts converts values to the integer type T with range [lo, hi].
+func castInts[T integer](v castValues, lo int64, hi uint64, p OverflowPolicy) ([]T, error) {
+	outOfRange := func(x interface{}) error {
+		return ErrorNpy{Msg: fmt.Sprintf("value %v out of range [%d, %d]", x, lo, hi)}
+	}
+
+	var out []T
+	switch {
+	case v.i != nil:
+		out = make([]T, len(v.i))
+		for i, x := range v.i {
+			switch {
+			case x < lo:
+				if p == OverflowError {
+					return nil, outOfRange(x)
+				}
+				out[i] = T(lo)
+			case x > 0 && uint64(x) > hi:
+				if p == OverflowError {
+					return nil, outOfRange(x)
+				}
+				out[i] = T(hi)
+			default:
+				out[i] = T(x)
+			}
+		}
+	case v.u != nil:
+		out = make([]T, len(v.u))
+		for i, x := range v.u {
+			if x > hi {
+				if p == OverflowError {
+					return nil, outOfRange(x)
+				}
+				x = hi
+			}
+			out[i] = T(x)
+		}
+	default:
+		src := v.f
+		if src == nil {
+			src = make([]float64, len(v.c))
+			for i, x := range v.c {
+				if p == OverflowError && imag(x) != 0 {
+					return nil, ErrorNpy{Msg: fmt.Sprintf("complex value %v has a non-zero imaginary part", x)}
+				}
+				src[i] = real(x)
+			}
+		}
+		out = make([]T, len(src))
+		for i, x := range src {
+			x = math.Trunc(x)
+			switch {
+			case math.IsNaN(x):
+				if p == OverflowError {
+					return nil, ErrorNpy{Msg: "cannot cast NaN to an integer dtype"}
+				}
+				out[i] = 0
+			case x < float64(lo):
+				if p == OverflowError {
+					return nil, outOfRange(x)
+				}
+				out[i] = T(lo)
+			case x >= float64(hi)+1:
+				if p == OverflowError {
+					return nil, outOfRange(x)
+				}
+				out[i] = T(hi)
+			case x < 0:
+				out[i] = T(int64(x))
+			default:
+				out[i] = T(uint64(x))
+			}
+		}
+	}
+	return out, nil
+}
+
+// castBools converts values to booleans, mapping non-zero values to true.
+func castBools(v castValues) []bool {
+	var out []bool
+	switch {
+	case v.f != nil:
+		out = make([]bool, len(v.f))
+		for i, x := range v.f {
+			out[i] = x != 0
+		}
+	case v.i != nil:
+		out = make([]bool, len(v.i))
+		for i, x := range v.i {
+			out[i] = x != 0
+		}
+	case v.u != nil:
+		out = make([]bool, len(v.u))
+		for i, x := range v.u {
+			out[i] = x != 0
+		}
+	default:
+		out = make([]bool, len(v.c))
+		for i, x := range v.c {
+			out[i] = x != 0
+		}
+	}
+	return out
+}
+
+// toComplex converts values to complex128.
+func toComplex(v castValues) []complex128 {
+	switch {
+	case v.c != nil:
+		return append([]complex128(nil), v.c...)
+	case v.f != nil:
+		out := make([]complex128, len(v.f))
+		for i, x := range v.f {
+			out[i] = complex(x, 0)
+		}
+		return out
+	case v.i != nil:
+		out := make([]complex128, len(v.i))
+		for i, x := range v.i {
+			out[i] = complex(float64(x), 0)
+		}
+		return out
+	default:
+		out := make([]complex128, len(v.u))
+		for i, x := range v.u {
+			out[i] = complex(float64(x), 0)
+		}
+		return out
+	}
+}
+
+// isFloat reports whether the dtype is a real floating-point dtype that Cast supports.
+func (d DType) isFloat() bool {
+	switch d {
+	case DTypeF16, DTypeBF16, DTypeF32, DTypeF64:
+		return true
+	default:
+		return false
+	}
+}
+
+// storedAs returns the tensor in host memory, converted to the dtype requested
+// by WithStoreAs, or t itself when it is on the host and no conversion applies.
+// Writers call it for every tensor before encoding its data.
+func (t *Tensor) storedAs(o *options) (*Tensor, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	if o.storeAs == "" || o.storeAs == t.DType || !t.DType.isFloat() {
+		return t, nil
+	}
+	if !o.storeAs.isFloat() {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot store tensors as non-float dtype %s", o.storeAs)}
+	}
+	return t.Cast(o.storeAs, WithOverflow(o.overflow))
+}
@@ -0,0 +1,90 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCastOverflow(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+	tests := []struct {
+		name     string
+		in       *Tensor
+		dtype    DType
+		saturate string // Data under OverflowSaturate
+		fails    bool   // Whether OverflowError fails
+	}{
+		{"nan to int", &Tensor{Data: []float64{nan, 1}, DType: DTypeF64}, DTypeI32, "[0 1]", true},
+		{"inf to int", &Tensor{Data: []float64{inf, 1}, DType: DTypeF64}, DTypeI64, "[9223372036854775807 1]", true},
+		{"-inf to int", &Tensor{Data: []float64{-inf}, DType: DTypeF64}, DTypeI8, "[-128]", true},
+		{"inf to uint", &Tensor{Data: []float32{float32(inf)}, DType: DTypeF32}, DTypeU8, "[255]", true},
+		{"f64 above i8", &Tensor{Data: []float64{127.9, 128, 1e300}, DType: DTypeF64}, DTypeI8, "[127 127 127]", true},
+		{"f64 below i8", &Tensor{Data: []float64{-128.9, -129, -1e300}, DType: DTypeF64}, DTypeI8, "[-128 -128 -128]", true},
+		{"f64 in i8", &Tensor{Data: []float64{127.9, -128.9, -0.5}, DType: DTypeF64}, DTypeI8, "[127 -128 0]", false},
+		{"f64 to u64", &Tensor{Data: []float64{1 << 63, 1 << 64}, DType: DTypeF64}, DTypeU64, "[9223372036854775808 18446744073709551615]", true},
+		{"f64 to i64", &Tensor{Data: []float64{-1 << 63, 1 << 63}, DType: DTypeF64}, DTypeI64, "[-9223372036854775808 9223372036854775807]", true},
+		{"negative to uint", &Tensor{Data: []float64{-1}, DType: DTypeF64}, DTypeU32, "[0]", true},
+		{"u64 to i64", &Tensor{Data: []uint64{math.MaxInt64, math.MaxInt64 + 1, math.MaxUint64}, DType: DTypeU64}, DTypeI64, "[9223372036854775807 9223372036854775807 9223372036854775807]", true},
+		{"u64 in i64", &Tensor{Data: []uint64{math.MaxInt64}, DType: DTypeU64}, DTypeI64, "[9223372036854775807]", false},
+		{"u64 to i8", &Tensor{Data: []uint64{math.MaxUint64}, DType: DTypeU64}, DTypeI8, "[127]", true},
+		{"i64 to u8", &Tensor{Data: []int64{-1, 256}, DType: DTypeI64}, DTypeU8, "[0 255]", true},
+		{"i32 to i4", &Tensor{Data: []int32{-9, 8}, DType: DTypeI32}, DTypeI4, "[120]", true}, // -8 and 7, packed
+		{"f64 to f32", &Tensor{Data: []float64{1e39, -1e39, inf}, DType: DTypeF64}, DTypeF32, "[+Inf -Inf +Inf]", true},
+		{"inf to f32", &Tensor{Data: []float64{inf, nan}, DType: DTypeF64}, DTypeF32, "[+Inf NaN]", false},
+		{"complex to real", &Tensor{Data: []complex128{1 + 2i}, DType: DTypeC128}, DTypeF64, "[1]", true},
+		{"complex to int", &Tensor{Data: []complex64{-3 + 1i}, DType: DTypeC64}, DTypeI32, "[-3]", true},
+		{"real complex", &Tensor{Data: []complex64{-3}, DType: DTypeC64}, DTypeI32, "[-3]", false},
+		{"c128 to c64", &Tensor{Data: []complex128{complex(1e39, 0)}, DType: DTypeC128}, DTypeC64, "[(+Inf+0i)]", true},
+	}
+	for _, tt := range tests {
+		tt.in.Shape = Shape{reflect.ValueOf(tt.in.Data).Len()}
+		tt.in.Device = "cpu"
+		out, err := tt.in.Cast(tt.dtype)
+		if err != nil {
+			t.Errorf("%s: saturating cast: %v", tt.name, err)
+		} else if got := fmt.Sprint(out.Data); got != tt.saturate {
+			t.Errorf("%s: saturating cast = %s, want %s", tt.name, got, tt.saturate)
+		}
+		out, err = tt.in.Cast(tt.dtype, WithOverflow(OverflowError))
+		if tt.fails && err == nil {
+			t.Errorf("%s: cast succeeded with %v, want an error", tt.name, out.Data)
+		}
+		if !tt.fails && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestCastHalfRounding(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    []float64
+		dtype DType
+		want  []uint16
+		fails bool // Whether OverflowError fails
+	}{
+		// Ties round to even
+		{"f16 ties", []float64{1 + 1.0/2048, 1 + 3.0/2048, 2049}, DTypeF16, []uint16{0x3c00, 0x3c02, 0x6800}, false},
+		{"f16 subnormal", []float64{1.0 / (1 << 24), 1.0 / (1 << 25), 3.0 / (1 << 25)}, DTypeF16, []uint16{0x0001, 0x0000, 0x0002}, false},
+		{"f16 max", []float64{65504, 65519, -65519}, DTypeF16, []uint16{0x7bff, 0x7bff, 0xfbff}, false},
+		{"f16 overflow", []float64{65520, -1e6}, DTypeF16, []uint16{0x7c00, 0xfc00}, true},
+		{"bf16 ties", []float64{1 + 1.0/256, 1 + 3.0/256}, DTypeBF16, []uint16{0x3f80, 0x3f82}, false},
+		{"bf16 overflow", []float64{1e39}, DTypeBF16, []uint16{0x7f80}, true},
+		{"bf16 inf", []float64{math.Inf(-1)}, DTypeBF16, []uint16{0xff80}, false},
+	}
+	for _, tt := range tests {
+		in := &Tensor{Data: tt.in, Shape: Shape{len(tt.in)}, DType: DTypeF64, Device: "cpu"}
+		out, err := in.Cast(tt.dtype)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := out.Data.([]uint16); fmt.Sprintf("%#04x", got) != fmt.Sprintf("%#04x", tt.want) {
+			t.Errorf("%s: got %#04x, want %#04x", tt.name, got, tt.want)
+		}
+		if _, err := in.Cast(tt.dtype, WithOverflow(OverflowError)); (err != nil) != tt.fails {
+			t.Errorf("%s: OverflowError cast returned %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+)
+
+// NPZ archives written with WithChecksums carry a JSON entry named
+// checksumEntry holding the CRC-32C of each tensor's NPY content, keyed by
+// tensor name. Unlike the CRC-32 in the zip format, which is only checked when
+// an entry is read to its end, it is verified by WithVerifyChecksums after
+// every tensor is decoded.
+
+// checksumEntry is the NPZ entry recording the checksums of tensors.
+const checksumEntry = "__checksums__.json"
+
+// checksumAlgorithm names the checksum recorded in the manifest.
+const checksumAlgorithm = "crc32c"
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksumMismatch is returned when a tensor does not match its recorded checksum.
+var ErrChecksumMismatch = ErrorNpy{Msg: "checksum mismatch"}
+
+// checksumManifest is the content of the checksum entry.
+type checksumManifest struct {
+	Algorithm string            `json:"algorithm"`
+	Checksums map[string]string `json:"checksums"` // Eight hex digits per tensor
+}
+
+// WithChecksums records a CRC-32C checksum of every tensor written to an NPZ
+// archive, for verification with WithVerifyChecksums.
+func WithChecksums() Option {
+	return func(o *options) {
+		o.writeChecksums = true
+	}
+}
+
+// WithVerifyChecksums verifies each tensor read from an NPZ archive against the
+// checksum recorded by WithChecksums. Reading fails if the archive has no
+// checksum for a tensor or the tensor's content does not match it.
+func WithVerifyChecksums() Option {
+	return func(o *options) {
+		o.verifyChecksums = true
+	}
+}
+
+// isMetadataEntry reports whether an NPZ entry holds metadata rather than a tensor.
+func isMetadataEntry(name string) bool {
+	return name == packingEntry || name == checksumEntry
+}
+
+// readChecksums loads the checksums of an archive, if present.
+func readChecksums(files []*zip.File) (map[string]uint32, error) {
+	for _, file := range files {
+		if file.Name != checksumEntry {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+
+		var m checksumManifest
+		if err := json.NewDecoder(rc).Decode(&m); err != nil {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("invalid checksum metadata: %v", err)}
+		}
+		if m.Algorithm != checksumAlgorithm {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported checksum algorithm %s", m.Algorithm)}
+		}
+		sums := make(map[string]uint32, len(m.Checksums))
+		for name, hex := range m.Checksums {
+			sum, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("invalid checksum %q for %s", hex, name)}
+			}
+			sums[name] = uint32(sum)
+		}
+		return sums, nil
+	}
+	return nil, nil
+}
+
+// writeChecksums writes the checksum entry, if there are any checksums.
+func writeChecksums(zw *zip.Writer, sums map[string]uint32) error {
+	if len(sums) == 0 {
+		return nil
+	}
+	m := checksumManifest{Algorithm: checksumAlgorithm, Checksums: make(map[string]string, len(sums))}
+	for name, sum := range sums {
+		m.Checksums[name] = fmt.Sprintf("%08x", sum)
+	}
+	w, err := zw.Create(checksumEntry)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(m)
+}
+
+// loadChecksums reads the checksums of an archive into o if verification was requested.
+func (o *options) loadChecksums(files []*zip.File) error {
+	if !o.verifyChecksums {
+		return nil
+	}
+	sums, err := readChecksums(files)
+	if err != nil {
+		return err
+	}
+	o.checksums = sums
+	return nil
+}
+
+// verifyingReader returns a reader over the content of the entry for tensor
+// name that checksums what is read through it, and a function that consumes
+// the rest of the content and compares the checksum with the one recorded.
+// Without WithVerifyChecksums, it returns r and a function that does nothing.
+func (o *options) verifyingReader(name string, r io.Reader) (io.Reader, func() error) {
+	if !o.verifyChecksums {
+		return r, func() error { return nil }
+	}
+	h := crc32.New(castagnoli)
+	return io.TeeReader(r, h), func() error {
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		want, ok := o.checksums[name]
+		if !ok {
+			return ErrorNpy{Msg: fmt.Sprintf("no checksum recorded for %s", name)}
+		}
+		if got := h.Sum32(); got != want {
+			return ErrorNpy{Msg: fmt.Sprintf("checksum mismatch for %s: got %08x, want %08x", name, got, want), Err: ErrChecksumMismatch}
+		}
+		return nil
+	}
+}
+
+// readEntryTensor decodes the tensor stored in a zip entry under name, verifying
+// its checksum if requested and restoring packed storage.
+func readEntryTensor(file *zip.File, name string, packing map[string]packingInfo, o *options) (*Tensor, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	r, verify := o.verifyingReader(name, rc)
+	tensor, err := readTensor(r, int64(file.UncompressedSize64), o)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(); err != nil {
+		return nil, err
+	}
+	if info, ok := packing[name]; ok {
+		if tensor, err = info.unpackStorage(name, tensor); err != nil {
+			return nil, err
+		}
+	}
+	return tensor, nil
+}
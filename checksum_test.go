@@ -0,0 +1,121 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeChecksummedNPZ writes tensors x and y to an NPZ file with checksums.
+func writeChecksummedNPZ(t *testing.T, path string, opts ...Option) {
+	t.Helper()
+	err := WriteNPZ(path, map[string]*Tensor{
+		"x": {Data: []float32{1, 2, 3}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"},
+		"y": {Data: []int64{4}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"},
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flipLastByte rewrites the zip file at path with the last byte of entry
+// flipped. The zip CRC-32 is recomputed, so only the manifest can catch it.
+func flipLastByte(t *testing.T, path, entry string) {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, file := range r.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if file.Name == entry {
+			b[len(b)-1] ^= 0x01
+		}
+		entries = append(entries, file.Name, string(b))
+	}
+	r.Close()
+	writeZipEntries(t, path, entries...)
+}
+
+func TestChecksumsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	writeChecksummedNPZ(t, path, WithChecksums())
+
+	tensors, err := ReadNPZ(path, WithVerifyChecksums())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tensors) != 2 || tensors[0].Name != "x" || tensors[1].Name != "y" {
+		t.Errorf("ReadNPZ = %v", tensors)
+	}
+
+	n, err := NewNpzTensors(path, WithVerifyChecksums())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	names := n.Names()
+	sort.Strings(names)
+	if strings.Join(names, ",") != "x,y" {
+		t.Errorf("Names = %v, want the manifest hidden", names)
+	}
+	if _, err := n.Get("y"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChecksumsDetectCorruption(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	writeChecksummedNPZ(t, path, WithChecksums())
+	flipLastByte(t, path, "x.npy")
+
+	if _, err := ReadNPZ(path, WithVerifyChecksums()); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("ReadNPZ: got %v, want ErrChecksumMismatch", err)
+	}
+	n, err := NewNpzTensors(path, WithVerifyChecksums())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if _, err := n.Get("x"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Get x: got %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := n.Get("y"); err != nil {
+		t.Errorf("Get y: %v", err)
+	}
+
+	// Without verification the corrupted value is returned
+	tensors, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := tensors[0].Tensor.Data.([]float32); x[2] == 3 {
+		t.Errorf("x = %v, want the last element corrupted", x)
+	}
+}
+
+func TestChecksumsMissingManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	writeChecksummedNPZ(t, path)
+
+	_, err := ReadNPZ(path, WithVerifyChecksums())
+	if err == nil || errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "no checksum recorded for x") {
+		t.Errorf("got %v, want no checksum recorded", err)
+	}
+	if _, err := ReadNPZ(path); err != nil {
+		t.Errorf("without verification: %v", err)
+	}
+}
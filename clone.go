@@ -0,0 +1,26 @@
+package gonpy
+
+import "reflect"
+
+// Clone returns a deep copy of the tensor: its data and shape are copied, so
+// the clone can be modified without affecting t or anything sharing t's data.
+// A tensor on a device is copied through the host into a new buffer on the
+// same device, which must be released with the device's Free.
+func (t *Tensor) Clone() (*Tensor, error) {
+	if t.OnDevice() {
+		return t.ToDevice(t.Device)
+	}
+	c := &Tensor{DType: t.DType, Device: t.Device}
+	if t.Shape != nil {
+		c.Shape = append(make(Shape, 0, len(t.Shape)), t.Shape...)
+	}
+	data := reflect.ValueOf(t.Data)
+	if data.Kind() != reflect.Slice || data.IsNil() {
+		c.Data = t.Data
+		return c, nil
+	}
+	copied := reflect.MakeSlice(data.Type(), data.Len(), data.Len())
+	reflect.Copy(copied, data)
+	c.Data = copied.Interface()
+	return c, nil
+}
@@ -0,0 +1,43 @@
+package gonpy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClone(t *testing.T) {
+	x := &Tensor{Data: []float32{1, 2, 3, 4}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"}
+	c, err := x.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Data.([]float32)[0] = 10
+	c.Shape[0] = 4
+	if fmt.Sprint(x.Data) != "[1 2 3 4]" || !x.Shape.Equal(Shape{2, 2}) {
+		t.Errorf("changing the clone changed the tensor to %v %v", x.Shape, x.Data)
+	}
+}
+
+func TestCloneDevice(t *testing.T) {
+	x := &Tensor{Data: []int32{1, -2, 3}, Shape: Shape{3}, DType: DTypeI32, Device: "cpu"}
+	d, err := x.ToDevice(testDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Device != testDevice || c.Data == d.Data {
+		t.Fatalf("clone on %s shares the buffer", c.Device)
+	}
+	// Changing the original buffer leaves the clone's alone
+	d.Data.(*memBuffer).b[0] = 9
+	h, err := c.ToHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(h.Data) != "[1 -2 3]" {
+		t.Errorf("clone holds %v", h.Data)
+	}
+}
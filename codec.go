@@ -0,0 +1,290 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// codecChunkBytes is the size of the buffer through which fixed-size elements
+// are decoded and encoded, bounding the memory used beyond the data itself.
+const codecChunkBytes = 1 << 16
+
+// Arrays of at least parallelDecodeBytes are decoded concurrently in chunks of
+// parallelChunkBytes when more than one CPU is available.
+const (
+	parallelDecodeBytes = 8 << 20
+	parallelChunkBytes  = 1 << 20
+)
+
+// writeBufferSize is the size of the buffer placed in front of files written
+// by WriteNPY and WriteNPZ, so that small writes are coalesced into few syscalls.
+const writeBufferSize = 1 << 16
+
+// decodeInto decodes the payload of an array of dtype into the typed slice data,
+// whose length determines the number of elements read.
+func decodeInto(r io.Reader, dtype DType, data interface{}) error {
+	le := binary.LittleEndian
+	switch d := data.(type) {
+	case []byte: // U8, packed, or structured records
+		_, err := io.ReadFull(r, d)
+		return err
+	case []string:
+		decoded, err := readStrings(r, dtype, len(d))
+		if err != nil {
+			return err
+		}
+		copy(d, decoded)
+		return nil
+	case []uint16: // BF16 or F16 bits
+		return readChunks(r, len(d), 2, func(b []byte, first int) {
+			for i := range b[:len(b)/2] {
+				d[first+i] = le.Uint16(b[2*i:])
+			}
+		})
+	case []float32:
+		return readChunks(r, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				d[first+i] = math.Float32frombits(le.Uint32(b[4*i:]))
+			}
+		})
+	case []float64:
+		return readChunks(r, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				d[first+i] = math.Float64frombits(le.Uint64(b[8*i:]))
+			}
+		})
+	case []complex64: // Interleaved real/imag float32 pairs
+		return readChunks(r, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				re := math.Float32frombits(le.Uint32(b[8*i:]))
+				im := math.Float32frombits(le.Uint32(b[8*i+4:]))
+				d[first+i] = complex(re, im)
+			}
+		})
+	case []complex128: // Interleaved real/imag float64 pairs
+		return readChunks(r, len(d), 16, func(b []byte, first int) {
+			for i := range b[:len(b)/16] {
+				re := math.Float64frombits(le.Uint64(b[16*i:]))
+				im := math.Float64frombits(le.Uint64(b[16*i+8:]))
+				d[first+i] = complex(re, im)
+			}
+		})
+	case []int32:
+		return readChunks(r, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				d[first+i] = int32(le.Uint32(b[4*i:]))
+			}
+		})
+	case []int64:
+		return readChunks(r, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				d[first+i] = int64(le.Uint64(b[8*i:]))
+			}
+		})
+	case []uint32:
+		return readChunks(r, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				d[first+i] = le.Uint32(b[4*i:])
+			}
+		})
+	case []uint64:
+		return readChunks(r, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				d[first+i] = le.Uint64(b[8*i:])
+			}
+		})
+	case []bool: // Any non-zero byte decodes as true
+		return readChunks(r, len(d), 1, func(b []byte, first int) {
+			for i, x := range b {
+				d[first+i] = x != 0
+			}
+		})
+	case []int8: // I8 values or FP8 bits
+		return readChunks(r, len(d), 1, func(b []byte, first int) {
+			for i, x := range b {
+				d[first+i] = int8(x)
+			}
+		})
+	default:
+		return ErrorNpy{Msg: "unsupported data type for reading"}
+	}
+}
+
+// readChunks reads count elements of size bytes from r through a bounded
+// buffer, passing each chunk of whole elements and the index of its first
+// element to decode.
+func readChunks(r io.Reader, count, size int, decode func(b []byte, first int)) error {
+	if count == 0 {
+		return nil
+	}
+	if workers := runtime.GOMAXPROCS(0); workers > 1 && count*size >= parallelDecodeBytes {
+		return readChunksParallel(r, count, size, decode, workers)
+	}
+	per := max(1, codecChunkBytes/size)
+	buf := chunkPool.get()
+	defer chunkPool.put(buf)
+	for first := 0; first < count; first += per {
+		b := (*buf)[:min(per, count-first)*size]
+		if _, err := io.ReadFull(r, b); err != nil {
+			if err == io.EOF && first > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		decode(b, first)
+	}
+	return nil
+}
+
+// readChunksParallel is readChunks for large arrays: chunks are read from r in
+// order and decoded concurrently by up to workers goroutines. Each chunk covers
+// a disjoint range of elements, so decode calls never overlap.
+func readChunksParallel(r io.Reader, count, size int, decode func(b []byte, first int), workers int) error {
+	type job struct {
+		buf   *[]byte
+		n     int
+		first int
+	}
+	per := max(1, parallelChunkBytes/size)
+	jobs := make(chan job)
+	free := make(chan *[]byte, 2*workers)
+	for i := 0; i < cap(free); i++ {
+		free <- parallelChunkPool.get()
+	}
+	defer func() {
+		for i := 0; i < cap(free); i++ {
+			parallelChunkPool.put(<-free)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				decode((*j.buf)[:j.n], j.first)
+				free <- j.buf
+			}
+		}()
+	}
+
+	var err error
+	for first := 0; first < count; first += per {
+		buf := <-free
+		n := min(per, count-first) * size
+		if _, err = io.ReadFull(r, (*buf)[:n]); err != nil {
+			free <- buf
+			if err == io.EOF && first > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			break
+		}
+		jobs <- job{buf: buf, n: n, first: first}
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
+
+// encodeFrom writes the elements of the typed slice data in little-endian order.
+func encodeFrom(w io.Writer, data interface{}) error {
+	le := binary.LittleEndian
+	switch d := data.(type) {
+	case []uint16: // BF16 or F16 bits
+		return writeChunks(w, len(d), 2, func(b []byte, first int) {
+			for i := range b[:len(b)/2] {
+				le.PutUint16(b[2*i:], d[first+i])
+			}
+		})
+	case []float32:
+		return writeChunks(w, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				le.PutUint32(b[4*i:], math.Float32bits(d[first+i]))
+			}
+		})
+	case []float64:
+		return writeChunks(w, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				le.PutUint64(b[8*i:], math.Float64bits(d[first+i]))
+			}
+		})
+	case []complex64:
+		return writeChunks(w, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				le.PutUint32(b[8*i:], math.Float32bits(real(d[first+i])))
+				le.PutUint32(b[8*i+4:], math.Float32bits(imag(d[first+i])))
+			}
+		})
+	case []complex128:
+		return writeChunks(w, len(d), 16, func(b []byte, first int) {
+			for i := range b[:len(b)/16] {
+				le.PutUint64(b[16*i:], math.Float64bits(real(d[first+i])))
+				le.PutUint64(b[16*i+8:], math.Float64bits(imag(d[first+i])))
+			}
+		})
+	case []int32:
+		return writeChunks(w, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				le.PutUint32(b[4*i:], uint32(d[first+i]))
+			}
+		})
+	case []int64:
+		return writeChunks(w, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				le.PutUint64(b[8*i:], uint64(d[first+i]))
+			}
+		})
+	case []uint32:
+		return writeChunks(w, len(d), 4, func(b []byte, first int) {
+			for i := range b[:len(b)/4] {
+				le.PutUint32(b[4*i:], d[first+i])
+			}
+		})
+	case []uint64:
+		return writeChunks(w, len(d), 8, func(b []byte, first int) {
+			for i := range b[:len(b)/8] {
+				le.PutUint64(b[8*i:], d[first+i])
+			}
+		})
+	case []bool:
+		return writeChunks(w, len(d), 1, func(b []byte, first int) {
+			for i := range b {
+				b[i] = 0
+				if d[first+i] {
+					b[i] = 1
+				}
+			}
+		})
+	case []int8: // I8 values or FP8 bits
+		return writeChunks(w, len(d), 1, func(b []byte, first int) {
+			for i := range b {
+				b[i] = byte(d[first+i])
+			}
+		})
+	default:
+		return ErrorNpy{Msg: "unsupported data type for writing"}
+	}
+}
+
+// writeChunks writes count elements of size bytes to w through a bounded
+// buffer, which encode fills one chunk of whole elements at a time.
+func writeChunks(w io.Writer, count, size int, encode func(b []byte, first int)) error {
+	if count == 0 {
+		return nil
+	}
+	per := max(1, codecChunkBytes/size)
+	buf := chunkPool.get()
+	defer chunkPool.put(buf)
+	for first := 0; first < count; first += per {
+		b := (*buf)[:min(per, count-first)*size]
+		encode(b, first)
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package gonpy
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"testing"
+	"testing/iotest"
+)
+
+// codecDTypes are the fixed-size dtypes with a typed fast path in the codec.
+var codecDTypes = []DType{
+	DTypeU8, DTypeI8, DTypeBool, DTypeF16, DTypeBF16, DTypeF32, DTypeF64,
+	DTypeC64, DTypeC128, DTypeI32, DTypeI64, DTypeU32, DTypeU64,
+}
+
+// codecPayload returns the encoding of n elements of dtype with varied bytes.
+// Bool elements are 0 or 1 so that they round-trip exactly.
+func codecPayload(dtype DType, n int) []byte {
+	b := make([]byte, n*dtype.Size())
+	for i := range b {
+		if dtype == DTypeBool {
+			b[i] = byte(i & 1)
+		} else {
+			b[i] = byte(i*131 + i>>8)
+		}
+	}
+	return b
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	// Large enough to cross codec chunks and the parallel decode threshold
+	for _, n := range []int{0, 1, 1000, parallelDecodeBytes + 3} {
+		for _, dtype := range codecDTypes {
+			in := codecPayload(dtype, n/dtype.Size())
+			data, err := makeData(dtype, n/dtype.Size())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := decodeInto(bytes.NewReader(in), dtype, data); err != nil {
+				t.Fatalf("%s: decode %d bytes: %v", dtype, len(in), err)
+			}
+			var out bytes.Buffer
+			if err := writeData(&out, dtype, data); err != nil {
+				t.Fatalf("%s: encode: %v", dtype, err)
+			}
+			if !bytes.Equal(out.Bytes(), in) {
+				t.Errorf("%s: %d bytes did not round-trip", dtype, len(in))
+			}
+		}
+	}
+}
+
+func TestCodecShortInput(t *testing.T) {
+	for _, dtype := range codecDTypes {
+		data, err := makeData(dtype, 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := codecPayload(dtype, 999)
+		if err := decodeInto(bytes.NewReader(in), dtype, data); err == nil {
+			t.Errorf("%s: decoded 1000 elements from %d bytes", dtype, len(in))
+		}
+	}
+}
+
+// decodeWith decodes in with GOMAXPROCS set to procs, which selects the
+// serial or the parallel path for large arrays.
+func decodeWith(procs int, in []byte, dtype DType, n int) (interface{}, error) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+	data, err := makeData(dtype, n)
+	if err != nil {
+		return nil, err
+	}
+	return data, decodeInto(iotest.HalfReader(bytes.NewReader(in)), dtype, data)
+}
+
+func TestParallelDecodeMatchesSerial(t *testing.T) {
+	for _, dtype := range codecDTypes {
+		// An odd count, so that the last parallel chunk is partial
+		n := parallelDecodeBytes/dtype.Size() + 7
+		in := codecPayload(dtype, n)
+		parallel, err := decodeWith(2, in, dtype, n)
+		if err != nil {
+			t.Fatalf("%s: parallel decode: %v", dtype, err)
+		}
+		serial, err := decodeWith(1, in, dtype, n)
+		if err != nil {
+			t.Fatalf("%s: serial decode: %v", dtype, err)
+		}
+		// Compare encodings, as NaNs are never equal
+		var p, s bytes.Buffer
+		if err := writeData(&p, dtype, parallel); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeData(&s, dtype, serial); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(p.Bytes(), s.Bytes()) || !bytes.Equal(p.Bytes(), in) {
+			t.Errorf("%s: parallel and serial decodes of %d elements differ", dtype, n)
+		}
+
+		// Input cut short in the last chunk, at a chunk boundary and before the first
+		for _, cut := range []int{len(in) - 1, 2 * parallelChunkBytes, 0} {
+			_, perr := decodeWith(2, in[:cut], dtype, n)
+			_, serr := decodeWith(1, in[:cut], dtype, n)
+			if perr == nil || perr != serr {
+				t.Errorf("%s: %d of %d bytes: parallel decode got %v, serial %v", dtype, cut, len(in), perr, serr)
+			}
+		}
+	}
+}
+
+func BenchmarkDecodeInto(b *testing.B) {
+	const n = 1 << 20
+	for _, dtype := range codecDTypes {
+		b.Run(string(dtype), func(b *testing.B) {
+			in := codecPayload(dtype, n)
+			data, err := makeData(dtype, n)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(in)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := decodeInto(bytes.NewReader(in), dtype, data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEncodeFrom(b *testing.B) {
+	const n = 1 << 20
+	for _, dtype := range codecDTypes {
+		b.Run(string(dtype), func(b *testing.B) {
+			data, err := makeData(dtype, n)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := decodeInto(bytes.NewReader(codecPayload(dtype, n)), dtype, data); err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(n * dtype.Size()))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := writeData(io.Discard, dtype, data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package gonpy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Concat joins tensors along an existing axis, which may be negative to count
+// from the last dimension. The tensors must share a dtype and have the same
+// shape in every other dimension. The result holds a copy of the data.
+func Concat(axis int, tensors ...*Tensor) (*Tensor, error) {
+	if len(tensors) == 0 {
+		return nil, ErrorNpy{Msg: "no tensors to concatenate"}
+	}
+	first := tensors[0]
+	a, err := normalizeAxis(axis, len(first.Shape))
+	if err != nil {
+		return nil, err
+	}
+	shape := append(Shape(nil), first.Shape...)
+	shape[a] = 0
+	for i, t := range tensors {
+		if t.DType != first.DType {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("tensor %d has dtype %s, expected %s", i, t.DType, first.DType)}
+		}
+		if len(t.Shape) != len(shape) {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("tensor %d has shape %v, expected %d dimensions", i, t.Shape, len(shape))}
+		}
+		for d, dim := range t.Shape {
+			if d != a && dim != shape[d] {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("tensor %d has shape %v, incompatible with %v along axis %d", i, t.Shape, first.Shape, axis)}
+			}
+		}
+		if err := t.checkLength(); err != nil {
+			return nil, err
+		}
+		shape[a] += t.Shape[a]
+	}
+
+	// Packed tensors are joined one value per byte, then packed again
+	parts := make([]reflect.Value, len(tensors))
+	for i, t := range tensors {
+		switch t.DType {
+		case DTypeI4:
+			parts[i] = reflect.ValueOf(UnpackInt4(t.Data.([]byte), t.Shape.ElemCount()))
+		case DTypeU4:
+			parts[i] = reflect.ValueOf(UnpackUint4(t.Data.([]byte), t.Shape.ElemCount()))
+		default:
+			parts[i] = reflect.ValueOf(t.Data)
+		}
+	}
+	size := 1
+	if first.DType.isStructured() {
+		size = first.DType.Size() // Records are raw bytes
+	}
+	outer := shape[:a].ElemCount()
+	inner := shape[a+1:].ElemCount() * size
+
+	joined := reflect.MakeSlice(parts[0].Type(), shape.ElemCount()*size, shape.ElemCount()*size)
+	pos := 0
+	for o := 0; o < outer; o++ {
+		for i, t := range tensors {
+			chunk := t.Shape[a] * inner
+			reflect.Copy(joined.Slice(pos, pos+chunk), parts[i].Slice(o*chunk, (o+1)*chunk))
+			pos += chunk
+		}
+	}
+
+	var data interface{} = joined.Interface()
+	switch first.DType {
+	case DTypeI4:
+		data, err = PackInt4(data.([]int8))
+	case DTypeU4:
+		data, err = PackUint4(data.([]uint8))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{Data: data, Shape: shape, DType: first.DType, Device: first.Device}, nil
+}
+
+// Stack joins tensors of the same dtype and shape along a new axis, which may
+// be negative to count from the end of the result's shape. The result holds a
+// copy of the data.
+func Stack(axis int, tensors ...*Tensor) (*Tensor, error) {
+	if len(tensors) == 0 {
+		return nil, ErrorNpy{Msg: "no tensors to stack"}
+	}
+	expanded := make([]*Tensor, len(tensors))
+	for i, t := range tensors {
+		if !t.Shape.Equal(tensors[0].Shape) {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("tensor %d has shape %v, expected %v", i, t.Shape, tensors[0].Shape)}
+		}
+		u, err := t.Unsqueeze(axis)
+		if err != nil {
+			return nil, err
+		}
+		expanded[i] = u
+	}
+	return Concat(axis, expanded...)
+}
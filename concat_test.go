@@ -0,0 +1,120 @@
+package gonpy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConcat(t *testing.T) {
+	a := &Tensor{Data: []int32{1, 2, 3, 4}, Shape: Shape{2, 2}, DType: DTypeI32, Device: "cpu"}
+	b := &Tensor{Data: []int32{5, 6}, Shape: Shape{1, 2}, DType: DTypeI32, Device: "cpu"}
+	c := &Tensor{Data: []int32{7, 8}, Shape: Shape{2, 1}, DType: DTypeI32, Device: "cpu"}
+	sd, err := NewStructuredDType(Field{Name: "a", DType: DTypeU8}, Field{Name: "b", DType: DTypeI8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		axis    int
+		tensors []*Tensor
+		shape   Shape
+		want    string
+	}{
+		{"rows", 0, []*Tensor{a, b}, Shape{3, 2}, "[1 2 3 4 5 6]"},
+		{"columns", 1, []*Tensor{a, c}, Shape{2, 3}, "[1 2 7 3 4 8]"},
+		{"negative axis", -1, []*Tensor{c, a, c}, Shape{2, 4}, "[7 1 2 7 8 3 4 8]"},
+		{"single", 0, []*Tensor{b}, Shape{1, 2}, "[5 6]"},
+		{"empty part", 0, []*Tensor{{Data: []int32{}, Shape: Shape{0, 2}, DType: DTypeI32}, b}, Shape{1, 2}, "[5 6]"},
+		{"strings", 0, []*Tensor{
+			{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1)},
+			{Data: []string{"b", "c"}, Shape: Shape{2}, DType: UnicodeDType(1)},
+		}, Shape{3}, "[a b c]"},
+		{"i4", 0, []*Tensor{
+			{Data: []byte{0x8f, 0x07}, Shape: Shape{3}, DType: DTypeI4},
+			{Data: []byte{0x01}, Shape: Shape{1}, DType: DTypeI4},
+		}, Shape{4}, "[143 23]"},
+		{"structured", 1, []*Tensor{
+			{Data: []byte{1, 0, 2, 0}, Shape: Shape{2, 1}, DType: sd.DType()},
+			{Data: []byte{3, 0, 4, 0}, Shape: Shape{2, 1}, DType: sd.DType()},
+		}, Shape{2, 2}, "[1 0 3 0 2 0 4 0]"},
+	}
+	for _, tt := range tests {
+		before := fmt.Sprint(a.Data)
+		got, err := Concat(tt.axis, tt.tensors...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !got.Shape.Equal(tt.shape) || fmt.Sprint(got.Data) != tt.want || got.DType != tt.tensors[0].DType {
+			t.Errorf("%s: got %s %v %v, want %v %s", tt.name, got.DType, got.Shape, got.Data, tt.shape, tt.want)
+		}
+		// The result holds a copy
+		if d, ok := got.Data.([]int32); ok && len(d) > 0 {
+			d[0] = -1
+			if fmt.Sprint(a.Data) != before || fmt.Sprint(b.Data) != "[5 6]" || fmt.Sprint(c.Data) != "[7 8]" {
+				t.Errorf("%s: result shares data with its inputs", tt.name)
+			}
+		}
+	}
+}
+
+func TestStack(t *testing.T) {
+	a := &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"}
+	b := &Tensor{Data: []float32{3, 4}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"}
+	tests := []struct {
+		axis  int
+		shape Shape
+		want  string
+	}{
+		{0, Shape{3, 2}, "[1 2 3 4 1 2]"},
+		{1, Shape{2, 3}, "[1 3 1 2 4 2]"},
+		{-1, Shape{2, 3}, "[1 3 1 2 4 2]"},
+		{-2, Shape{3, 2}, "[1 2 3 4 1 2]"},
+	}
+	for _, tt := range tests {
+		got, err := Stack(tt.axis, a, b, a)
+		if err != nil {
+			t.Errorf("axis %d: %v", tt.axis, err)
+			continue
+		}
+		if !got.Shape.Equal(tt.shape) || fmt.Sprint(got.Data) != tt.want {
+			t.Errorf("axis %d: got %v %v, want %v %s", tt.axis, got.Shape, got.Data, tt.shape, tt.want)
+		}
+	}
+	s, err := Stack(0, &Tensor{Data: []int64{9}, Shape: Shape{}, DType: DTypeI64})
+	if err != nil || !s.Shape.Equal(Shape{1}) || fmt.Sprint(s.Data) != "[9]" {
+		t.Errorf("stacked scalar: %v, %v", s, err)
+	}
+}
+
+func TestConcatStackInvalid(t *testing.T) {
+	f32 := func(shape ...int) *Tensor {
+		return &Tensor{Data: make([]float32, Shape(shape).ElemCount()), Shape: shape, DType: DTypeF32}
+	}
+	tests := []struct {
+		name    string
+		axis    int
+		tensors []*Tensor
+		stack   bool // Stack fails too
+	}{
+		{"no tensors", 0, nil, true},
+		{"dtype mismatch", 0, []*Tensor{f32(2), {Data: []float64{1, 2}, Shape: Shape{2}, DType: DTypeF64}}, true},
+		{"shape mismatch", 0, []*Tensor{f32(2, 2), f32(2, 3)}, true},
+		{"rank mismatch", 0, []*Tensor{f32(2, 2), f32(4)}, true},
+		{"axis out of range", 2, []*Tensor{f32(2, 2), f32(2, 2)}, false},
+		{"negative axis out of range", -3, []*Tensor{f32(2, 2), f32(2, 2)}, false},
+		{"scalar", 0, []*Tensor{{Data: []float32{1}, Shape: Shape{}, DType: DTypeF32}}, false},
+		{"short data", 0, []*Tensor{f32(2), {Data: []float32{1}, Shape: Shape{2}, DType: DTypeF32}}, true},
+	}
+	for _, tt := range tests {
+		if got, err := Concat(tt.axis, tt.tensors...); err == nil {
+			t.Errorf("%s: Concat = %v", tt.name, got)
+		}
+		if got, err := Stack(tt.axis, tt.tensors...); tt.stack && err == nil {
+			t.Errorf("%s: Stack = %v", tt.name, got)
+		}
+	}
+	if _, err := Stack(3, f32(2, 2), f32(2, 2)); err == nil {
+		t.Error("Stack along axis 3 of 2-dimensional tensors accepted")
+	}
+}
@@ -0,0 +1,105 @@
+package gonpy
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// withContext makes reads and writes fail with ctx.Err() once ctx is done.
+func withContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// ReadNPZContext is like ReadNPZ but stops with ctx.Err() when ctx is done.
+// Cancellation is checked between chunks of at most 1 MiB.
+func ReadNPZContext(ctx context.Context, path string, opts ...Option) ([]NamedTensor, error) {
+	tensors, err := ReadNPZ(path, append(opts, withContext(ctx))...)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return tensors, err
+}
+
+// WriteNPZContext is like WriteNPZ but stops with ctx.Err() when ctx is done,
+// removing the incomplete file unless the write was atomic.
+func WriteNPZContext(ctx context.Context, path string, tensors map[string]*Tensor, opts ...Option) error {
+	err := WriteNPZ(path, tensors, append(opts, withContext(ctx))...)
+	if err != nil && ctx.Err() != nil {
+		if !newOptions(opts).atomic {
+			os.Remove(path)
+		}
+		return ctx.Err()
+	}
+	return err
+}
+
+// GetContext is like Get but stops with ctx.Err() when ctx is done.
+func (n *NpzTensors) GetContext(ctx context.Context, name string) (*Tensor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	o := *n.opts
+	o.ctx = ctx
+	t, err := n.get(name, &o)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return t, err
+}
+
+// cancelableReader wraps r to check the operation's context between chunks.
+func (o *options) cancelableReader(r io.Reader) io.Reader {
+	if o.ctx == nil {
+		return r
+	}
+	return &ctxReader{ctx: o.ctx, r: r}
+}
+
+// cancelableWriter wraps w to check the operation's context between chunks.
+func (o *options) cancelableWriter(w io.Writer) io.Writer {
+	if o.ctx == nil {
+		return w
+	}
+	return &ctxWriter{ctx: o.ctx, w: w}
+}
+
+// ctxReader fails reads once its context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr *ctxReader) Read(b []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(b) > ioChunk {
+		b = b[:ioChunk]
+	}
+	return cr.r.Read(b)
+}
+
+// ctxWriter fails writes once its context is done.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw *ctxWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		if err := cw.ctx.Err(); err != nil {
+			return written, err
+		}
+		n, err := cw.w.Write(b[:min(len(b), ioChunk)])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,82 @@
+package gonpy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContextCanceled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.npz")
+	tensors := map[string]*Tensor{
+		"x": {Data: []float32{1, 2, 3}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"},
+	}
+	if err := WriteNPZ(path, tensors); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ReadNPZContext(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadNPZContext: got %v, want context.Canceled", err)
+	}
+	n, err := NewNpzTensors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if _, err := n.GetContext(ctx, "x"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetContext: got %v, want context.Canceled", err)
+	}
+	for name, opts := range map[string][]Option{"plain": nil, "atomic": {WithAtomicWrite()}} {
+		out := filepath.Join(dir, name+".npz")
+		if err := WriteNPZContext(ctx, out, tensors, opts...); !errors.Is(err, context.Canceled) {
+			t.Errorf("WriteNPZContext %s: got %v, want context.Canceled", name, err)
+		}
+		if _, err := os.Stat(out); !os.IsNotExist(err) {
+			t.Errorf("WriteNPZContext %s left %s behind", name, out)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("canceled writes left %d extra files", len(entries)-1)
+	}
+}
+
+func TestContextCanceledMidway(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.npz")
+	// Several chunks, so that cancellation is seen between them
+	big := &Tensor{Data: make([]float64, 1<<19), Shape: Shape{1 << 19}, DType: DTypeF64, Device: "cpu"}
+	tensors := map[string]*Tensor{"big": big}
+	if err := WriteNPZ(path, tensors); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls int
+	stop := WithProgress(func(done, total int64) {
+		calls++
+		cancel()
+	})
+
+	if _, err := ReadNPZContext(ctx, path, stop); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadNPZContext: got %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("read went on for %d chunks after cancellation", calls-1)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	calls = 0
+	out := filepath.Join(dir, "out.npz")
+	if err := WriteNPZContext(ctx, out, tensors, stop); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteNPZContext: got %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("WriteNPZContext left %s behind", out)
+	}
+}
@@ -0,0 +1,111 @@
+//go:build amd64 && !purego
+
+package gonpy
+
+// CPU features used by the vectorized conversions, detected at startup.
+var hasF16C, hasAVX2 = detectCPU()
+
+// detectCPU reports whether the CPU and operating system support the F16C and
+// AVX2 instructions, both of which need the OS to save the AVX registers.
+func detectCPU() (f16c, avx2 bool) {
+	maxID, _, _, _ := cpuid(0, 0)
+	if maxID < 1 {
+		return false, false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	osxsave := ecx1&(1<<27) != 0
+	avx := ecx1&(1<<28) != 0
+	if !osxsave || !avx {
+		return false, false
+	}
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 { // XMM and YMM state
+		return false, false
+	}
+	f16c = ecx1&(1<<29) != 0
+	if maxID >= 7 {
+		_, ebx7, _, _ := cpuid(7, 0)
+		avx2 = ebx7&(1<<5) != 0
+	}
+	return f16c, avx2
+}
+
+// Implemented in convert_amd64.s. Each converts n elements, where n is a
+// positive multiple of 8. f16ToF32F16C stops early at a block of 8 containing
+// a signaling NaN, which the hardware would quiet, and returns the number of
+// elements converted.
+
+//go:noescape
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+//go:noescape
+func xgetbv() (eax, edx uint32)
+
+//go:noescape
+func f16ToF32F16C(dst *float32, src *uint16, n int) int
+
+//go:noescape
+func f32ToF16F16C(dst *uint16, src *float32, n int)
+
+//go:noescape
+func bf16ToF32AVX2(dst *float32, src *uint16, n int)
+
+//go:noescape
+func f32ToBF16AVX2(dst *uint16, src *float32, n int)
+
+// f16ToF32s converts the half-precision bit patterns in src into dst, which
+// must be at least as long.
+func f16ToF32s(dst []float32, src []uint16) {
+	n := len(src) &^ 7
+	if !hasF16C {
+		n = 0
+	}
+	if n > 0 {
+		_ = dst[len(src)-1]
+	}
+	for i := 0; i < n; i += 8 {
+		i += f16ToF32F16C(&dst[i], &src[i], n-i)
+		if i < n {
+			f16ToF32sGeneric(dst[i:i+8], src[i:i+8])
+		}
+	}
+	f16ToF32sGeneric(dst[n:], src[n:])
+}
+
+// f32ToF16s converts the float32 values in src into dst, which must be at
+// least as long.
+func f32ToF16s(dst []uint16, src []float32) {
+	n := len(src) &^ 7
+	if hasF16C && n > 0 {
+		_ = dst[len(src)-1]
+		f32ToF16F16C(&dst[0], &src[0], n)
+	} else {
+		n = 0
+	}
+	f32ToF16sGeneric(dst[n:], src[n:])
+}
+
+// bf16ToF32s converts the bfloat16 bit patterns in src into dst, which must be
+// at least as long.
+func bf16ToF32s(dst []float32, src []uint16) {
+	n := len(src) &^ 7
+	if hasAVX2 && n > 0 {
+		_ = dst[len(src)-1]
+		bf16ToF32AVX2(&dst[0], &src[0], n)
+	} else {
+		n = 0
+	}
+	bf16ToF32sGeneric(dst[n:], src[n:])
+}
+
+// f32ToBF16s converts the float32 values in src into dst, which must be at
+// least as long.
+func f32ToBF16s(dst []uint16, src []float32) {
+	n := len(src) &^ 7
+	if hasAVX2 && n > 0 {
+		_ = dst[len(src)-1]
+		f32ToBF16AVX2(&dst[0], &src[0], n)
+	} else {
+		n = 0
+	}
+	f32ToBF16sGeneric(dst[n:], src[n:])
+}
@@ -0,0 +1,140 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
+
+// func f16ToF32F16C(dst *float32, src *uint16, n int) int
+TEXT ·f16ToF32F16C(SB), NOSPLIT, $0-32
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+	XORQ DX, DX
+
+	// The masks are broadcast with VPSHUFD rather than VPBROADCASTW, which
+	// needs AVX2.
+	MOVL    $0x7e007e00, AX
+	VMOVD   AX, X8
+	VPSHUFD $0, X8, X8 // Exponent and quiet bit
+	MOVL    $0x7c007c00, AX
+	VMOVD   AX, X9
+	VPSHUFD $0, X9, X9 // Exponent of Inf and NaN
+	MOVL    $0x03ff03ff, AX
+	VMOVD   AX, X10
+	VPSHUFD $0, X10, X10 // Mantissa
+	VPXOR   X11, X11, X11
+
+f16loop:
+	VMOVDQU   (SI), X1
+	VPAND     X8, X1, X2
+	VPCMPEQW  X9, X2, X2 // Inf or signaling NaN
+	VPAND     X10, X1, X3
+	VPCMPEQW  X11, X3, X3 // Zero mantissa
+	VPANDN    X2, X3, X2  // Signaling NaN
+	VPTEST    X2, X2
+	JNZ       f16done
+	VCVTPH2PS X1, Y0
+	VMOVUPS   Y0, (DI)
+	ADDQ      $16, SI
+	ADDQ      $32, DI
+	ADDQ      $8, DX
+	CMPQ      DX, CX
+	JNE       f16loop
+
+f16done:
+	VZEROUPPER
+	MOVQ DX, ret+24(FP)
+	RET
+
+// func f32ToF16F16C(dst *uint16, src *float32, n int)
+TEXT ·f32ToF16F16C(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+
+f32loop:
+	VMOVUPS   (SI), Y0
+	VCVTPS2PH $0, Y0, (DI) // Round to nearest even
+	ADDQ      $32, SI
+	ADDQ      $16, DI
+	SUBQ      $8, CX
+	JNZ       f32loop
+	VZEROUPPER
+	RET
+
+// func bf16ToF32AVX2(dst *float32, src *uint16, n int)
+TEXT ·bf16ToF32AVX2(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+
+bf16loop:
+	VPMOVZXWD (SI), Y0
+	VPSLLD    $16, Y0, Y0
+	VMOVDQU   Y0, (DI)
+	ADDQ      $16, SI
+	ADDQ      $32, DI
+	SUBQ      $8, CX
+	JNZ       bf16loop
+	VZEROUPPER
+	RET
+
+// func f32ToBF16AVX2(dst *uint16, src *float32, n int)
+TEXT ·f32ToBF16AVX2(SB), NOSPLIT, $0-24
+	MOVQ dst+0(FP), DI
+	MOVQ src+8(FP), SI
+	MOVQ n+16(FP), CX
+
+	MOVL         $1, AX
+	VMOVD        AX, X8
+	VPBROADCASTD X8, Y8  // 1
+	MOVL         $0x7fff, AX
+	VMOVD        AX, X9
+	VPBROADCASTD X9, Y9  // Rounding bias
+	MOVL         $0x7fffffff, AX
+	VMOVD        AX, X10
+	VPBROADCASTD X10, Y10 // Magnitude mask
+	MOVL         $0x7f800000, AX
+	VMOVD        AX, X11
+	VPBROADCASTD X11, Y11 // Infinity
+	MOVL         $0x40, AX
+	VMOVD        AX, X12
+	VPBROADCASTD X12, Y12 // Quiet bit of a bfloat16 NaN
+
+bf16outloop:
+	VMOVDQU      (SI), Y0
+	VPSRLD       $16, Y0, Y1
+	VPAND        Y8, Y1, Y2
+	VPADDD       Y9, Y0, Y3
+	VPADDD       Y2, Y3, Y3
+	VPSRLD       $16, Y3, Y3  // Rounded to nearest even
+	VPAND        Y10, Y0, Y4
+	VPCMPGTD     Y11, Y4, Y4  // NaN lanes
+	VPOR         Y12, Y1, Y1  // Truncated quiet NaN
+	VPBLENDVB    Y4, Y1, Y3, Y3
+	VEXTRACTI128 $1, Y3, X5
+	VPACKUSDW    X5, X3, X3
+	VMOVDQU      X3, (DI)
+	ADDQ         $32, SI
+	ADDQ         $16, DI
+	SUBQ         $8, CX
+	JNZ          bf16outloop
+	VZEROUPPER
+	RET
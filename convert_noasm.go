@@ -0,0 +1,33 @@
+//go:build !amd64 || purego
+
+package gonpy
+
+// Only amd64 has vectorized conversions. Other architectures, arm64 included,
+// use the scalar loops: a NEON path (FCVTL/FCVTN) would need hand-encoded
+// instructions that the Go assembler does not name, and is left out until it
+// can be run against the exhaustive tests in convert_test.go on arm64
+// hardware.
+
+// f16ToF32s converts the half-precision bit patterns in src into dst, which
+// must be at least as long.
+func f16ToF32s(dst []float32, src []uint16) {
+	f16ToF32sGeneric(dst, src)
+}
+
+// f32ToF16s converts the float32 values in src into dst, which must be at
+// least as long.
+func f32ToF16s(dst []uint16, src []float32) {
+	f32ToF16sGeneric(dst, src)
+}
+
+// bf16ToF32s converts the bfloat16 bit patterns in src into dst, which must be
+// at least as long.
+func bf16ToF32s(dst []float32, src []uint16) {
+	bf16ToF32sGeneric(dst, src)
+}
+
+// f32ToBF16s converts the float32 values in src into dst, which must be at
+// least as long.
+func f32ToBF16s(dst []uint16, src []float32) {
+	f32ToBF16sGeneric(dst, src)
+}
@@ -0,0 +1,128 @@
+package gonpy
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// allHalves returns every 16-bit pattern in order, so that each block of 8
+// the vectorized conversions see holds neighbouring values: normals,
+// subnormals, infinities, and signaling and quiet NaNs.
+func allHalves() []uint16 {
+	h := make([]uint16, 1<<16)
+	for i := range h {
+		h[i] = uint16(i)
+	}
+	return h
+}
+
+// halfProbes returns float32 values around every half-precision and
+// bfloat16 value: the value itself and the neighbours that test rounding,
+// ties to even, overflow, underflow, and NaN payloads.
+func halfProbes() []float32 {
+	var f []float32
+	for _, h := range allHalves() {
+		for _, base := range []uint32{math.Float32bits(f16BitsToF32(h)), uint32(h) << 16} {
+			for _, low := range []uint32{0, 1, 0x0fff, 0x1000, 0x1001, 0x1fff, 0x7fff, 0x8000, 0x8001, 0xffff} {
+				f = append(f, math.Float32frombits(base|low))
+			}
+		}
+	}
+	return f
+}
+
+func TestF16ToF32sExhaustive(t *testing.T) {
+	src := allHalves()
+	got, want := make([]float32, len(src)), make([]float32, len(src))
+	f16ToF32s(got, src)
+	f16ToF32sGeneric(want, src)
+	for i := range src {
+		if math.Float32bits(got[i]) != math.Float32bits(want[i]) {
+			t.Errorf("f16 %#04x: got %#08x, want %#08x", src[i], math.Float32bits(got[i]), math.Float32bits(want[i]))
+		}
+	}
+}
+
+func TestBF16ToF32sExhaustive(t *testing.T) {
+	src := allHalves()
+	got, want := make([]float32, len(src)), make([]float32, len(src))
+	bf16ToF32s(got, src)
+	bf16ToF32sGeneric(want, src)
+	for i := range src {
+		if math.Float32bits(got[i]) != math.Float32bits(want[i]) {
+			t.Errorf("bf16 %#04x: got %#08x, want %#08x", src[i], math.Float32bits(got[i]), math.Float32bits(want[i]))
+		}
+	}
+}
+
+func TestF32ToHalvesProbes(t *testing.T) {
+	src := halfProbes()
+	got, want := make([]uint16, len(src)), make([]uint16, len(src))
+	f32ToF16s(got, src)
+	f32ToF16sGeneric(want, src)
+	for i := range src {
+		if got[i] != want[i] {
+			t.Errorf("f32 %#08x to f16: got %#04x, want %#04x", math.Float32bits(src[i]), got[i], want[i])
+		}
+	}
+	f32ToBF16s(got, src)
+	f32ToBF16sGeneric(want, src)
+	for i := range src {
+		if got[i] != want[i] {
+			t.Errorf("f32 %#08x to bf16: got %#04x, want %#04x", math.Float32bits(src[i]), got[i], want[i])
+		}
+	}
+}
+
+func TestConvertLengths(t *testing.T) {
+	// Slices of every length up to 40 at every alignment, drawn at random
+	// so that signaling NaNs land in mixed blocks and in the tail
+	r := rand.New(rand.NewSource(1))
+	halves := make([]uint16, 48)
+	floats := make([]float32, 48)
+	for trial := 0; trial < 200; trial++ {
+		for i := range halves {
+			halves[i] = uint16(r.Intn(1 << 16))
+			if r.Intn(8) == 0 {
+				halves[i] = 0x7c01 | uint16(r.Intn(0x1ff)) // Signaling NaN
+			}
+			floats[i] = math.Float32frombits(r.Uint32())
+		}
+		for off := 0; off < 8; off++ {
+			for n := 0; n <= 40; n++ {
+				h, f := halves[off:off+n], floats[off:off+n]
+				got32, want32 := make([]float32, n), make([]float32, n)
+				f16ToF32s(got32, h)
+				f16ToF32sGeneric(want32, h)
+				for i := range got32 {
+					if math.Float32bits(got32[i]) != math.Float32bits(want32[i]) {
+						t.Fatalf("f16 %#04x at %d of %d: got %#08x, want %#08x", h[i], i, n, math.Float32bits(got32[i]), math.Float32bits(want32[i]))
+					}
+				}
+				bf16ToF32s(got32, h)
+				bf16ToF32sGeneric(want32, h)
+				for i := range got32 {
+					if math.Float32bits(got32[i]) != math.Float32bits(want32[i]) {
+						t.Fatalf("bf16 %#04x at %d of %d: got %#08x, want %#08x", h[i], i, n, math.Float32bits(got32[i]), math.Float32bits(want32[i]))
+					}
+				}
+				got16, want16 := make([]uint16, n), make([]uint16, n)
+				f32ToF16s(got16, f)
+				f32ToF16sGeneric(want16, f)
+				for i := range got16 {
+					if got16[i] != want16[i] {
+						t.Fatalf("f32 %#08x to f16 at %d of %d: got %#04x, want %#04x", math.Float32bits(f[i]), i, n, got16[i], want16[i])
+					}
+				}
+				f32ToBF16s(got16, f)
+				f32ToBF16sGeneric(want16, f)
+				for i := range got16 {
+					if got16[i] != want16[i] {
+						t.Fatalf("f32 %#08x to bf16 at %d of %d: got %#04x, want %#04x", math.Float32bits(f[i]), i, n, got16[i], want16[i])
+					}
+				}
+			}
+		}
+	}
+}
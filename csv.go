@@ -0,0 +1,276 @@
+package gonpy
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WithCSVDelimiter sets the field delimiter of ReadCSV and WriteCSV. The
+// default is a tab for paths ending in .tsv and a comma otherwise.
+func WithCSVDelimiter(r rune) Option {
+	return func(o *options) {
+		o.csvDelimiter = r
+	}
+}
+
+// WithCSVHeader makes WriteCSV write a header row of names, or of column
+// numbers if no names are given, and makes ReadCSV skip the first row of the
+// file as a header.
+func WithCSVHeader(names ...string) Option {
+	return func(o *options) {
+		o.csvHeader = names
+		o.csvHasHeader = true
+	}
+}
+
+// csvComma returns the field delimiter for path.
+func (o *options) csvComma(path string) rune {
+	if o.csvDelimiter != 0 {
+		return o.csvDelimiter
+	}
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		return '\t'
+	}
+	return ','
+}
+
+// ReadCSV reads a CSV or TSV file into a 2-D tensor of dtype with one row per
+// record. Every record must have the same number of fields. Fields are parsed
+// as numbers, booleans, or strings, as dtype requires, ignoring surrounding
+// spaces except for strings.
+func ReadCSV(path string, dtype DType, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	if !csvDType(dtype) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot read CSV as %s", dtype), Err: ErrUnsupportedDType}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(bufio.NewReader(o.cancelableReader(f)))
+	r.Comma = o.csvComma(path)
+	r.ReuseRecord = true
+	if o.csvHasHeader {
+		if _, err := r.Read(); err != nil && !errors.Is(err, io.EOF) {
+			return nil, locate(err, path, "")
+		}
+	}
+
+	var fields []string
+	rows, cols := 0, 0
+	for {
+		record, err := r.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, locate(err, path, "")
+		}
+		if rows == 0 {
+			cols = len(record)
+		}
+		fields = append(fields, record...)
+		rows++
+		if err := o.checkSize(dtype, Shape{len(fields)}); err != nil {
+			return nil, err
+		}
+	}
+
+	t, err := Zeros(dtype, Shape{rows, cols})
+	if err != nil {
+		return nil, err
+	}
+	for i, field := range fields {
+		if err := t.parseElement(i, field); err != nil {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("%s: record %d field %d: %v", path, i/cols+1, i%cols+1, err)}
+		}
+	}
+	return t, nil
+}
+
+// csvDType reports whether CSV fields can be read into or written from dtype.
+func csvDType(dtype DType) bool {
+	switch dtype {
+	case DTypeF8E4M3, DTypeF8E5M2, DTypeObject:
+		return false
+	}
+	_, err := makeData(dtype, 0)
+	return err == nil && !dtype.isStructured()
+}
+
+// parseElement parses the text s into element i of t.
+func (t *Tensor) parseElement(i int, s string) error {
+	if data, ok := t.Data.([]string); ok {
+		data[i] = s
+		return nil
+	}
+	s = strings.TrimSpace(s)
+	var err error
+	switch data := t.Data.(type) {
+	case []bool:
+		data[i], err = strconv.ParseBool(s)
+	case []int8:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 8)
+		data[i] = int8(v)
+	case []int32:
+		var v int64
+		v, err = strconv.ParseInt(s, 10, 32)
+		data[i] = int32(v)
+	case []int64:
+		data[i], err = strconv.ParseInt(s, 10, 64)
+	case []uint8:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, 8)
+		data[i] = uint8(v)
+	case []uint32:
+		var v uint64
+		v, err = strconv.ParseUint(s, 10, 32)
+		data[i] = uint32(v)
+	case []uint64:
+		data[i], err = strconv.ParseUint(s, 10, 64)
+	case []uint16:
+		var v float64
+		v, err = strconv.ParseFloat(s, 32)
+		if t.DType == DTypeBF16 {
+			data[i] = f32ToBF16Bits(float32(v))
+		} else {
+			data[i] = f32ToF16Bits(float32(v))
+		}
+	case []float32:
+		var v float64
+		v, err = strconv.ParseFloat(s, 32)
+		data[i] = float32(v)
+	case []float64:
+		data[i], err = strconv.ParseFloat(s, 64)
+	case []complex64:
+		var v complex128
+		v, err = strconv.ParseComplex(s, 64)
+		data[i] = complex64(v)
+	case []complex128:
+		data[i], err = strconv.ParseComplex(s, 128)
+	default:
+		return ErrorNpy{Msg: fmt.Sprintf("cannot parse CSV as %s", t.DType), Err: ErrUnsupportedDType}
+	}
+	return err
+}
+
+// WriteCSV writes a tensor of at most two dimensions to a CSV or TSV file,
+// one row per line. A 1-D tensor is written as a single column. Floats use
+// the shortest representation that reads back to the same value.
+func (t *Tensor) WriteCSV(path string, opts ...Option) error {
+	o := newOptions(opts)
+	t, err := t.ToHost()
+	if err != nil {
+		return err
+	}
+	if !csvDType(t.DType) {
+		return ErrorNpy{Msg: fmt.Sprintf("cannot write %s tensor as CSV", t.DType), Err: ErrUnsupportedDType}
+	}
+	if err := t.checkData(); err != nil {
+		return err
+	}
+	rows, cols := 1, 1
+	switch len(t.Shape) {
+	case 0:
+	case 1:
+		rows = t.Shape[0]
+	case 2:
+		rows, cols = t.Shape[0], t.Shape[1]
+	default:
+		return ErrorNpy{Msg: fmt.Sprintf("cannot write a tensor of shape %v as CSV", t.Shape)}
+	}
+	if o.csvHasHeader && len(o.csvHeader) != 0 && len(o.csvHeader) != cols {
+		return ErrorNpy{Msg: fmt.Sprintf("CSV header has %d names for %d columns", len(o.csvHeader), cols)}
+	}
+
+	f, err := createOutput(path, o)
+	if err != nil {
+		return err
+	}
+	if err := t.writeCSV(f, o.csvComma(path), rows, cols, o); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
+}
+
+// writeCSV writes the header, if any, and the rows of t to f.
+func (t *Tensor) writeCSV(f *outputFile, delimiter rune, rows, cols int, o *options) error {
+	bw := bufio.NewWriterSize(o.cancelableWriter(f), writeBufferSize)
+	w := csv.NewWriter(bw)
+	w.Comma = delimiter
+	record := make([]string, cols)
+	if o.csvHasHeader {
+		header := o.csvHeader
+		if len(header) == 0 {
+			for c := range record {
+				record[c] = strconv.Itoa(c)
+			}
+			header = record
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+	for r := 0; r < rows; r++ {
+		for c := range record {
+			record[c] = t.formatElement(r*cols + c)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// formatElement formats element i of t as text. The dtype of t must pass
+// csvDType.
+func (t *Tensor) formatElement(i int) string {
+	switch data := t.Data.(type) {
+	case []string:
+		return data[i]
+	case []bool:
+		return strconv.FormatBool(data[i])
+	case []int8:
+		return strconv.FormatInt(int64(data[i]), 10)
+	case []int32:
+		return strconv.FormatInt(int64(data[i]), 10)
+	case []int64:
+		return strconv.FormatInt(data[i], 10)
+	case []uint8:
+		return strconv.FormatUint(uint64(data[i]), 10)
+	case []uint32:
+		return strconv.FormatUint(uint64(data[i]), 10)
+	case []uint64:
+		return strconv.FormatUint(data[i], 10)
+	case []uint16:
+		v := f16BitsToF32(data[i])
+		if t.DType == DTypeBF16 {
+			v = bf16BitsToF32(data[i])
+		}
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case []float32:
+		return strconv.FormatFloat(float64(data[i]), 'g', -1, 32)
+	case []float64:
+		return strconv.FormatFloat(data[i], 'g', -1, 64)
+	case []complex64:
+		return strconv.FormatComplex(complex128(data[i]), 'g', -1, 64)
+	case []complex128:
+		return strconv.FormatComplex(data[i], 'g', -1, 128)
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package gonpy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	tests := []*Tensor{
+		{Data: []float32{1.5, -2, 3e-8, 4, 5, 6}, Shape: Shape{2, 3}, DType: DTypeF32, Device: "cpu"},
+		{Data: []int64{-1, 1 << 40}, Shape: Shape{2}, DType: DTypeI64, Device: "cpu"},
+		{Data: []string{"a,b", "c\"d"}, Shape: Shape{1, 2}, DType: UnicodeDType(3), Device: "cpu"},
+		{Data: []bool{true, false}, Shape: Shape{2, 1}, DType: DTypeBool, Device: "cpu"},
+	}
+	for _, path := range []string{filepath.Join(dir, "t.csv"), filepath.Join(dir, "t.tsv")} {
+		for _, in := range tests {
+			if err := in.WriteCSV(path, WithCSVHeader()); err != nil {
+				t.Fatalf("%s: %v", in.DType, err)
+			}
+			out, err := ReadCSV(path, in.DType, WithCSVHeader())
+			if err != nil {
+				t.Fatalf("%s: %v", in.DType, err)
+			}
+			rows, cols := in.Shape[0], 1
+			if len(in.Shape) == 2 {
+				cols = in.Shape[1]
+			}
+			if !out.Shape.Equal(Shape{rows, cols}) {
+				t.Errorf("%s: shape %v, want [%d %d]", in.DType, out.Shape, rows, cols)
+			}
+			for i := 0; i < rows*cols; i++ {
+				if got, want := out.formatElement(i), in.formatElement(i); got != want {
+					t.Errorf("%s: element %d is %s, want %s", in.DType, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestHostileCSV(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]struct {
+		text  string
+		dtype DType
+	}{
+		"ragged":   {"1,2\n3\n", DTypeI32},
+		"text":     {"1,x\n", DTypeF64},
+		"range":    {"300\n", DTypeI8},
+		"negative": {"-1\n", DTypeU32},
+		"quote":    {"\"1\n", DTypeI32},
+		"dtype":    {"1\n", DTypeF8E4M3},
+	}
+	for name, tt := range tests {
+		path := filepath.Join(dir, name+".csv")
+		if err := os.WriteFile(path, []byte(tt.text), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadCSV(path, tt.dtype); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+
+	path := filepath.Join(dir, "big.csv")
+	if err := os.WriteFile(path, []byte("1,2,3\n4,5,6\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCSV(path, DTypeF64, WithMaxBytes(40)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
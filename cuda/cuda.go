@@ -0,0 +1,195 @@
+//go:build cuda
+
+// Package cuda places tensors read by gonpy directly in CUDA device memory,
+// so that model weights stream from NPY, NPZ, and safetensors files to the GPU
+// without first being decoded into host tensors.
+//
+// The package does not link against CUDA itself. The application supplies
+// the runtime functions through a Runtime, typically thin cgo wrappers of
+// cudaMalloc, cudaFree, cudaMallocHost, cudaFreeHost, and cudaMemcpy, and
+// registers the resulting device with gonpy:
+//
+//	dev, err := cuda.NewDevice(runtime)
+//	...
+//	gonpy.RegisterDevice("cuda:0", dev)
+//	t, err := gonpy.ReadNPY("weights.npy", gonpy.WithDevice("cuda:0"))
+//
+// The data of the tensor is then a *Buffer. Tensors needed on the host as
+// well can instead be read with gonpy.WithAllocator and the device's
+// HostAllocator, into page-locked memory that copies to the GPU at full
+// speed. The package is only built with the cuda build tag.
+package cuda
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// MemcpyKind is the direction of a copy, with the values of cudaMemcpyKind.
+type MemcpyKind int
+
+const (
+	HostToDevice MemcpyKind = 1
+	DeviceToHost MemcpyKind = 2
+)
+
+// DefaultChunkSize is the size of the pinned buffer through which data is
+// copied when Runtime.ChunkSize is zero.
+const DefaultChunkSize = 8 << 20
+
+// Runtime holds the CUDA runtime functions a Device calls. Malloc, Free, and
+// Memcpy are required. MallocHost and FreeHost allocate the page-locked
+// staging buffer that data is copied through, which lets the driver copy by
+// DMA; without them, copies are made from Go memory.
+type Runtime struct {
+	Malloc     func(size int) (unsafe.Pointer, error)
+	Free       func(ptr unsafe.Pointer) error
+	MallocHost func(size int) (unsafe.Pointer, error)
+	FreeHost   func(ptr unsafe.Pointer) error
+	Memcpy     func(dst, src unsafe.Pointer, count int, kind MemcpyKind) error
+
+	// ChunkSize is the size of the staging buffer in bytes.
+	ChunkSize int
+}
+
+// Buffer is an allocation in device memory, the data of tensors on a Device.
+type Buffer struct {
+	Ptr  unsafe.Pointer
+	Size int
+}
+
+// Device is a gonpy.Device backed by CUDA memory. It is safe for concurrent
+// use; copies through the staging buffer are serialized.
+type Device struct {
+	rt Runtime
+
+	mu      sync.Mutex
+	staging []byte // Page-locked, allocated on first use
+
+	pinnedMu sync.Mutex
+	pinned   map[uintptr]int // Sizes of HostAllocator allocations by address
+}
+
+// NewDevice returns a device that calls the functions of rt.
+func NewDevice(rt Runtime) (*Device, error) {
+	if rt.Malloc == nil || rt.Free == nil || rt.Memcpy == nil {
+		return nil, fmt.Errorf("cuda: Malloc, Free, and Memcpy are required")
+	}
+	if (rt.MallocHost == nil) != (rt.FreeHost == nil) {
+		return nil, fmt.Errorf("cuda: MallocHost and FreeHost must be given together")
+	}
+	if rt.ChunkSize < 0 {
+		return nil, fmt.Errorf("cuda: chunk size %d", rt.ChunkSize)
+	}
+	if rt.ChunkSize == 0 {
+		rt.ChunkSize = DefaultChunkSize
+	}
+	return &Device{rt: rt, pinned: make(map[uintptr]int)}, nil
+}
+
+// Allocate returns a *Buffer of n bytes of device memory.
+func (d *Device) Allocate(n int) (interface{}, error) {
+	ptr, err := d.rt.Malloc(n)
+	if err != nil {
+		return nil, fmt.Errorf("cuda: allocating %d bytes: %w", n, err)
+	}
+	return &Buffer{Ptr: ptr, Size: n}, nil
+}
+
+// Free releases a *Buffer returned by Allocate. Errors from the runtime are
+// ignored.
+func (d *Device) Free(buf interface{}) {
+	if b, ok := buf.(*Buffer); ok && b.Ptr != nil {
+		d.rt.Free(b.Ptr)
+		b.Ptr = nil
+	}
+}
+
+// CopyFrom copies src into buf at offset.
+func (d *Device) CopyFrom(buf interface{}, offset int, src []byte) error {
+	b, err := checkBuffer(buf, offset, len(src))
+	if err != nil {
+		return err
+	}
+	return d.copy(src, func(host []byte, at int) error {
+		return d.rt.Memcpy(unsafe.Add(b.Ptr, offset+at), unsafe.Pointer(unsafe.SliceData(host)), len(host), HostToDevice)
+	}, true)
+}
+
+// CopyTo copies len(dst) bytes of buf at offset into dst.
+func (d *Device) CopyTo(buf interface{}, offset int, dst []byte) error {
+	b, err := checkBuffer(buf, offset, len(dst))
+	if err != nil {
+		return err
+	}
+	return d.copy(dst, func(host []byte, at int) error {
+		return d.rt.Memcpy(unsafe.Pointer(unsafe.SliceData(host)), unsafe.Add(b.Ptr, offset+at), len(host), DeviceToHost)
+	}, false)
+}
+
+// copy runs memcpy over data in chunks, passing each chunk through the
+// staging buffer unless data is already page-locked or there is no staging
+// buffer. toDevice tells whether data is the source of the copy.
+func (d *Device) copy(data []byte, memcpy func(host []byte, at int) error, toDevice bool) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if d.rt.MallocHost == nil || d.isPinned(data) {
+		if err := memcpy(data, 0); err != nil {
+			return fmt.Errorf("cuda: memcpy: %w", err)
+		}
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.staging == nil {
+		ptr, err := d.rt.MallocHost(d.rt.ChunkSize)
+		if err != nil {
+			return fmt.Errorf("cuda: allocating pinned memory: %w", err)
+		}
+		d.staging = unsafe.Slice((*byte)(ptr), d.rt.ChunkSize)
+	}
+	for at := 0; at < len(data); at += len(d.staging) {
+		chunk := data[at:min(at+len(d.staging), len(data))]
+		host := d.staging[:len(chunk)]
+		if toDevice {
+			copy(host, chunk)
+		}
+		if err := memcpy(host, at); err != nil {
+			return fmt.Errorf("cuda: memcpy: %w", err)
+		}
+		if !toDevice {
+			copy(chunk, host)
+		}
+	}
+	return nil
+}
+
+// Close releases the staging buffer. Buffers allocated on the device are not
+// affected, and the device may still be used; a new staging buffer is then
+// allocated.
+func (d *Device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.staging == nil {
+		return nil
+	}
+	err := d.rt.FreeHost(unsafe.Pointer(unsafe.SliceData(d.staging)))
+	d.staging = nil
+	return err
+}
+
+// checkBuffer returns buf as a *Buffer, checking that n bytes at offset lie
+// within it.
+func checkBuffer(buf interface{}, offset, n int) (*Buffer, error) {
+	b, ok := buf.(*Buffer)
+	if !ok || (b.Ptr == nil && b.Size > 0) {
+		return nil, fmt.Errorf("cuda: %T is not a live device buffer", buf)
+	}
+	if offset < 0 || n > b.Size-offset {
+		return nil, fmt.Errorf("cuda: copy of %d bytes at offset %d overruns %d-byte buffer", n, offset, b.Size)
+	}
+	return b, nil
+}
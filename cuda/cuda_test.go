@@ -0,0 +1,202 @@
+//go:build cuda
+
+package cuda
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+)
+
+// hostRuntime is a Runtime whose device and page-locked memory are Go byte
+// slices, so that the package can be tested without a GPU.
+type hostRuntime struct {
+	mu        sync.Mutex
+	mem       map[uintptr][]byte // Live allocations, kept reachable
+	host      map[uintptr]bool   // Which of them came from MallocHost
+	hostFails bool               // MallocHost returns an error
+	copies    []uintptr          // Host address of each memcpy
+}
+
+// runtime returns the functions of r, with or without MallocHost and FreeHost.
+func (r *hostRuntime) runtime(pinned bool, chunk int) Runtime {
+	r.mem = make(map[uintptr][]byte)
+	r.host = make(map[uintptr]bool)
+	rt := Runtime{
+		Malloc:    func(n int) (unsafe.Pointer, error) { return r.alloc(n, false), nil },
+		Free:      r.free,
+		Memcpy:    r.memcpy,
+		ChunkSize: chunk,
+	}
+	if pinned {
+		rt.MallocHost = func(n int) (unsafe.Pointer, error) {
+			if r.hostFails {
+				return nil, errors.New("out of pinned memory")
+			}
+			return r.alloc(n, true), nil
+		}
+		rt.FreeHost = r.free
+	}
+	return rt
+}
+
+// alloc returns n bytes aligned to 16 bytes, as the CUDA allocators are.
+func (r *hostRuntime) alloc(n int, host bool) unsafe.Pointer {
+	b := make([]byte, n+16)
+	ptr := unsafe.Pointer(unsafe.SliceData(b))
+	ptr = unsafe.Add(ptr, (16-uintptr(ptr)%16)%16)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mem[uintptr(ptr)] = b
+	r.host[uintptr(ptr)] = host
+	return ptr
+}
+
+func (r *hostRuntime) free(ptr unsafe.Pointer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.mem[uintptr(ptr)]; !ok {
+		return fmt.Errorf("free of unknown pointer %p", ptr)
+	}
+	delete(r.mem, uintptr(ptr))
+	delete(r.host, uintptr(ptr))
+	return nil
+}
+
+func (r *hostRuntime) memcpy(dst, src unsafe.Pointer, count int, kind MemcpyKind) error {
+	copy(unsafe.Slice((*byte)(dst), count), unsafe.Slice((*byte)(src), count))
+	host := src
+	if kind == DeviceToHost {
+		host = dst
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.copies = append(r.copies, uintptr(host))
+	return nil
+}
+
+// pinnedCopies returns how many copies were made from page-locked memory, and how many in all.
+func (r *hostRuntime) pinnedCopies() (pinned, all int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, addr := range r.copies {
+		for base, b := range r.mem {
+			if r.host[base] && addr >= base && addr < base+uintptr(len(b)) {
+				pinned++
+				break
+			}
+		}
+	}
+	return pinned, len(r.copies)
+}
+
+// live returns the number of allocations not yet freed.
+func (r *hostRuntime) live() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.mem)
+}
+
+func TestDeviceRoundTrip(t *testing.T) {
+	x := &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, Shape: gonpy.Shape{2, 5}, DType: gonpy.DTypeF32, Device: "cpu"}
+	tests := []struct {
+		name   string
+		pinned bool
+		copies int // Memcpy calls for the round trip
+	}{
+		{"staged", true, 6}, // 40 bytes in 16-byte chunks each way
+		{"direct", false, 2},
+	}
+	for _, tt := range tests {
+		var r hostRuntime
+		d, err := NewDevice(r.runtime(tt.pinned, 16))
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := "cuda:" + tt.name
+		gonpy.RegisterDevice(name, d)
+		on, err := x.ToDevice(name)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if b, ok := on.Data.(*Buffer); !ok || b.Size != 40 {
+			t.Errorf("%s: device data is %#v", tt.name, on.Data)
+		}
+		back, err := on.ToHost()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !gonpy.Equal(back, x) {
+			t.Errorf("%s: read back %v", tt.name, back.Data)
+		}
+		pinned, all := r.pinnedCopies()
+		if all != tt.copies || (tt.pinned && pinned != all) || (!tt.pinned && pinned != 0) {
+			t.Errorf("%s: %d memcpy calls, %d from pinned memory", tt.name, all, pinned)
+		}
+		d.Free(on.Data)
+		if err := d.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.live(); n != 0 {
+			t.Errorf("%s: %d allocations left", tt.name, n)
+		}
+	}
+}
+
+func TestNewDeviceInvalid(t *testing.T) {
+	var r hostRuntime
+	full := r.runtime(true, 0)
+	tests := map[string]func(rt *Runtime){
+		"no Malloc":        func(rt *Runtime) { rt.Malloc = nil },
+		"no Memcpy":        func(rt *Runtime) { rt.Memcpy = nil },
+		"MallocHost alone": func(rt *Runtime) { rt.FreeHost = nil },
+		"FreeHost alone":   func(rt *Runtime) { rt.MallocHost = nil },
+		"negative chunk":   func(rt *Runtime) { rt.ChunkSize = -1 },
+	}
+	for name, edit := range tests {
+		rt := full
+		edit(&rt)
+		if _, err := NewDevice(rt); err == nil {
+			t.Errorf("%s: device created", name)
+		}
+	}
+}
+
+func TestCopyBounds(t *testing.T) {
+	var r hostRuntime
+	d, err := NewDevice(r.runtime(false, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := d.Allocate(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		buf    interface{}
+		offset int
+		n      int
+	}{
+		{buf, 4, 5},
+		{buf, -1, 1},
+		{buf, 9, 0},
+		{&Buffer{Size: 4}, 0, 1},
+		{[]byte{1}, 0, 1},
+	}
+	for _, tt := range tests {
+		if err := d.CopyFrom(tt.buf, tt.offset, make([]byte, tt.n)); err == nil {
+			t.Errorf("CopyFrom %d bytes at %d into %#v", tt.n, tt.offset, tt.buf)
+		}
+		if err := d.CopyTo(tt.buf, tt.offset, make([]byte, tt.n)); err == nil {
+			t.Errorf("CopyTo %d bytes at %d from %#v", tt.n, tt.offset, tt.buf)
+		}
+	}
+	d.Free(buf)
+	if err := d.CopyFrom(buf, 0, []byte{1}); err == nil {
+		t.Error("copied into a freed buffer")
+	}
+}
@@ -0,0 +1,68 @@
+//go:build cuda
+
+package cuda
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+)
+
+// pinnedAllocator allocates page-locked host memory for a device.
+type pinnedAllocator struct {
+	d *Device
+}
+
+// HostAllocator returns an allocator of page-locked host memory, for use with
+// gonpy.WithAllocator, so that tensors decoded on the host are ready for
+// copying to the GPU. Copies between the device and memory from the
+// allocator bypass the staging buffer. The runtime must have MallocHost and
+// FreeHost.
+func (d *Device) HostAllocator() (gonpy.Allocator, error) {
+	if d.rt.MallocHost == nil {
+		return nil, fmt.Errorf("cuda: runtime has no MallocHost")
+	}
+	return pinnedAllocator{d}, nil
+}
+
+// Alloc returns n bytes of page-locked memory, or nil if the runtime fails to
+// allocate them, which the reader reports as an error.
+func (a pinnedAllocator) Alloc(n int) []byte {
+	ptr, err := a.d.rt.MallocHost(n)
+	if err != nil || ptr == nil {
+		return nil
+	}
+	a.d.pinnedMu.Lock()
+	a.d.pinned[uintptr(ptr)] = n
+	a.d.pinnedMu.Unlock()
+	return unsafe.Slice((*byte)(ptr), n)
+}
+
+// Free releases memory returned by Alloc.
+func (a pinnedAllocator) Free(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	ptr := unsafe.Pointer(unsafe.SliceData(b))
+	a.d.pinnedMu.Lock()
+	_, ok := a.d.pinned[uintptr(ptr)]
+	delete(a.d.pinned, uintptr(ptr))
+	a.d.pinnedMu.Unlock()
+	if ok {
+		a.d.rt.FreeHost(ptr)
+	}
+}
+
+// isPinned reports whether b lies within memory from the host allocator.
+func (d *Device) isPinned(b []byte) bool {
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(b)))
+	d.pinnedMu.Lock()
+	defer d.pinnedMu.Unlock()
+	for base, size := range d.pinned {
+		if start >= base && start+uintptr(len(b)) <= base+uintptr(size) {
+			return true
+		}
+	}
+	return false
+}
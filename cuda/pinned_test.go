@@ -0,0 +1,115 @@
+//go:build cuda
+
+package cuda
+
+import (
+	"path/filepath"
+	"testing"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestHostAllocator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npy")
+	x := &gonpy.Tensor{Data: []float64{1, 2, 3, 4}, Shape: gonpy.Shape{4}, DType: gonpy.DTypeF64, Device: "cpu"}
+	if err := x.WriteNPY(path); err != nil {
+		t.Fatal(err)
+	}
+	var r hostRuntime
+	d, err := NewDevice(r.runtime(true, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gonpy.RegisterDevice("cuda:pinned", d)
+	a, err := d.HostAllocator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := gonpy.ReadNPY(path, gonpy.WithAllocator(a))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gonpy.Equal(x, y) {
+		t.Fatalf("read %v", y.Data)
+	}
+	if !d.isPinned(unsafe.Slice((*byte)(unsafe.Pointer(&y.Data.([]float64)[0])), 32)) {
+		t.Error("tensor data is not in page-locked memory")
+	}
+
+	// Pinned data is copied in one call, without the staging buffer
+	on, err := y.ToDevice("cuda:pinned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pinned, all := r.pinnedCopies(); pinned != 1 || all != 1 {
+		t.Errorf("%d memcpy calls, %d from pinned memory", all, pinned)
+	}
+	if n := r.live(); n != 2 {
+		t.Errorf("%d allocations, want the tensor and its device copy", n)
+	}
+	d.Free(on.Data)
+	y.Release(a)
+	if n := r.live(); n != 0 {
+		t.Errorf("%d allocations left after Release", n)
+	}
+	if len(d.pinned) != 0 {
+		t.Errorf("%d pinned ranges left after Release", len(d.pinned))
+	}
+	a.Free(make([]byte, 8)) // Memory from elsewhere is ignored
+}
+
+func TestHostAllocatorFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npy")
+	x := &gonpy.Tensor{Data: []int32{1, 2, 3, 4}, Shape: gonpy.Shape{2, 2}, DType: gonpy.DTypeI32, Device: "cpu"}
+	if err := x.WriteNPY(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without MallocHost there is no host allocator, and copies are direct
+	var direct hostRuntime
+	d, err := NewDevice(direct.runtime(false, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.HostAllocator(); err == nil {
+		t.Error("host allocator without MallocHost")
+	}
+
+	// When MallocHost fails, reads into pinned memory and staged copies fail
+	var r hostRuntime
+	rt := r.runtime(true, 0)
+	r.hostFails = true
+	d, err = NewDevice(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := d.HostAllocator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := a.Alloc(16); b != nil {
+		t.Errorf("failed allocation returned %d bytes", len(b))
+	}
+	if y, err := gonpy.ReadNPY(path, gonpy.WithAllocator(a)); err == nil {
+		t.Errorf("read %v without pinned memory", y.Data)
+	}
+	gonpy.RegisterDevice("cuda:nopinned", d)
+	if _, err := x.ToDevice("cuda:nopinned"); err == nil {
+		t.Error("copied without a staging buffer")
+	}
+	if n := r.live(); n != 0 {
+		t.Errorf("%d allocations left after failures", n)
+	}
+
+	// Once MallocHost recovers, the staging buffer is allocated
+	r.hostFails = false
+	on, err := x.ToDevice("cuda:nopinned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := on.ToHost()
+	if err != nil || !gonpy.Equal(back, x) {
+		t.Errorf("read back %v, %v", back, err)
+	}
+}
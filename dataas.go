@@ -0,0 +1,83 @@
+package gonpy
+
+import "fmt"
+
+// Numeric is the set of element types accepted by DataAs.
+type Numeric interface {
+	int8 | int32 | int64 | uint8 | uint32 | uint64 | float32 | float64 | complex64 | complex128
+}
+
+// losslessCasts lists, for each dtype, the numeric dtypes that represent all of its values exactly.
+var losslessCasts = map[DType][]DType{
+	DTypeBool: {DTypeI8, DTypeU8, DTypeI32, DTypeU32, DTypeI64, DTypeU64, DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeI4:   {DTypeI8, DTypeI32, DTypeI64, DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeU4:   {DTypeI8, DTypeU8, DTypeI32, DTypeU32, DTypeI64, DTypeU64, DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeI8:   {DTypeI32, DTypeI64, DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeU8:   {DTypeI32, DTypeU32, DTypeI64, DTypeU64, DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeI32:  {DTypeI64, DTypeF64, DTypeC128},
+	DTypeU32:  {DTypeI64, DTypeU64, DTypeF64, DTypeC128},
+	DTypeF16:  {DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeBF16: {DTypeF32, DTypeF64, DTypeC64, DTypeC128},
+	DTypeF32:  {DTypeF64, DTypeC64, DTypeC128},
+	DTypeF64:  {DTypeC128},
+	DTypeC64:  {DTypeC128},
+}
+
+// dtypeOf returns the dtype whose element type is T.
+func dtypeOf[T Numeric]() DType {
+	var zero T
+	switch interface{}(zero).(type) {
+	case int8:
+		return DTypeI8
+	case int32:
+		return DTypeI32
+	case int64:
+		return DTypeI64
+	case uint8:
+		return DTypeU8
+	case uint32:
+		return DTypeU32
+	case uint64:
+		return DTypeU64
+	case float32:
+		return DTypeF32
+	case float64:
+		return DTypeF64
+	case complex64:
+		return DTypeC64
+	default:
+		return DTypeC128
+	}
+}
+
+// DataAs returns the tensor data as a []T. If T is the element type of the
+// tensor's dtype, the data is returned without copying. If every value of the
+// dtype is exactly representable as T, the data is converted into a new slice.
+// Any other combination is an error; use Cast for lossy conversions. The data
+// of a tensor on a device is copied to the host first.
+func DataAs[T Numeric](t *Tensor) ([]T, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	dtype := dtypeOf[T]()
+	if t.DType == dtype {
+		data, ok := t.Data.([]T)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor data is %T, expected %T", t.DType, t.Data, []T(nil))}
+		}
+		return data, nil
+	}
+
+	for _, d := range losslessCasts[t.DType] {
+		if d != dtype {
+			continue
+		}
+		c, err := t.Cast(dtype)
+		if err != nil {
+			return nil, err
+		}
+		return c.Data.([]T), nil
+	}
+	return nil, ErrorNpy{Msg: fmt.Sprintf("cannot convert %s tensor to %s without loss", t.DType, dtype)}
+}
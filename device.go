@@ -0,0 +1,208 @@
+package gonpy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Device is memory outside the host, such as an accelerator's, that tensors
+// can be read into and written from. Devices are registered by name with
+// RegisterDevice. A tensor whose Device field names a registered device
+// holds, as its Data, the buffer handle that the device's Allocate returned.
+// Device buffers hold elements in the layout of NPY data: little-endian, in
+// C order, with int4 and uint4 values packed two per byte.
+type Device interface {
+	// Allocate returns a handle to a buffer of n bytes on the device.
+	Allocate(n int) (interface{}, error)
+	// Free releases a buffer returned by Allocate.
+	Free(buf interface{})
+	// CopyFrom copies src from the host into buf, starting offset bytes in.
+	CopyFrom(buf interface{}, offset int, src []byte) error
+	// CopyTo copies len(dst) bytes of buf, starting offset bytes in, to the
+	// host.
+	CopyTo(buf interface{}, offset int, dst []byte) error
+}
+
+// deviceChunkSize is the size of the host buffer through which NPY data is
+// copied to a device as it is read.
+const deviceChunkSize = 4 << 20
+
+var (
+	devicesMu sync.RWMutex
+	devices   = make(map[string]Device)
+)
+
+// RegisterDevice makes a device available under name, such as "cuda:0". It
+// panics if name is empty or "cpu", d is nil, or name is already registered.
+func RegisterDevice(name string, d Device) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+	if name == "" || name == "cpu" || d == nil {
+		panic(fmt.Sprintf("gonpy: invalid device registration %q", name))
+	}
+	if _, ok := devices[name]; ok {
+		panic(fmt.Sprintf("gonpy: device %q registered twice", name))
+	}
+	devices[name] = d
+}
+
+// lookupDevice returns the device registered under name, or nil.
+func lookupDevice(name string) Device {
+	devicesMu.RLock()
+	defer devicesMu.RUnlock()
+	return devices[name]
+}
+
+// WithDevice makes NPY, NPZ, and safetensors readers copy tensor data to the
+// registered device named name as it is read, rather than decoding it into
+// host memory. Object arrays cannot be placed on a device. WithAllocator and
+// WithZeroCopy have no effect on data read to a device.
+func WithDevice(name string) Option {
+	return func(o *options) {
+		o.device = name
+	}
+}
+
+// deviceName returns the Device of tensors read with o.
+func (o *options) deviceName() string {
+	if o.device == "" {
+		return "cpu"
+	}
+	return o.device
+}
+
+// deviceSize returns the number of bytes a tensor of dtype and shape occupies
+// on a device.
+func deviceSize(dtype DType, shape Shape) (int, error) {
+	if dtype == DTypeObject {
+		return 0, ErrorNpy{Msg: "object arrays cannot be placed on a device", Err: ErrUnsupportedDType}
+	}
+	if dtype.isPacked() {
+		return (shape.ElemCount() + 1) / 2, nil
+	}
+	size, ok := dataSize(dtype, shape)
+	if !ok || size == 0 && dtype.Size() == 0 {
+		return 0, ErrorNpy{Msg: fmt.Sprintf("%s tensor of shape %v has no fixed size", dtype, shape), Err: ErrUnsupportedDType}
+	}
+	return int(size), nil
+}
+
+// readDataDevice reads data of the given shape and dtype into a buffer on the
+// device named name.
+func readDataDevice(shape Shape, dtype DType, r io.Reader, name string) (interface{}, error) {
+	d := lookupDevice(name)
+	if d == nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("device %q is not registered", name)}
+	}
+	size, err := deviceSize(dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := d.Allocate(size)
+	if err != nil {
+		return nil, err
+	}
+	chunk := make([]byte, min(size, deviceChunkSize))
+	for offset := 0; offset < size; {
+		n := min(len(chunk), size-offset)
+		if _, err := io.ReadFull(r, chunk[:n]); err != nil {
+			d.Free(buf)
+			return nil, err
+		}
+		if err := d.CopyFrom(buf, offset, chunk[:n]); err != nil {
+			d.Free(buf)
+			return nil, err
+		}
+		offset += n
+	}
+	return buf, nil
+}
+
+// OnDevice reports whether the tensor's data is on a registered device rather
+// than in host memory.
+func (t *Tensor) OnDevice() bool {
+	return lookupDevice(t.Device) != nil
+}
+
+// ToDevice returns a copy of the tensor on the registered device named name.
+// A tensor already on a device is copied through the host. The copy's buffer
+// must be released with the device's Free.
+func (t *Tensor) ToDevice(name string) (*Tensor, error) {
+	d := lookupDevice(name)
+	if d == nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("device %q is not registered", name)}
+	}
+	h, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkData(); err != nil {
+		return nil, err
+	}
+	b, ok := rawBytes(h.Data)
+	if !ok {
+		var raw bytes.Buffer
+		if err := writeData(&raw, h.DType, h.Data); err != nil {
+			return nil, err
+		}
+		b = raw.Bytes()
+	}
+	size, err := deviceSize(h.DType, h.Shape)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != size {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor of shape %v holds %d bytes, expected %d", h.DType, h.Shape, len(b), size)}
+	}
+	buf, err := d.Allocate(size)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.CopyFrom(buf, 0, b); err != nil {
+		d.Free(buf)
+		return nil, err
+	}
+	return &Tensor{Data: buf, Shape: h.Shape, DType: h.DType, Device: name}, nil
+}
+
+// ToHost returns a copy of a tensor on a device in host memory, or the tensor
+// itself if it is not on a device. Writers call it for every tensor they
+// write, so device tensors can be saved like any other.
+func (t *Tensor) ToHost() (*Tensor, error) {
+	d := lookupDevice(t.Device)
+	if d == nil {
+		return t, nil
+	}
+	size, err := deviceSize(t.DType, t.Shape)
+	if err != nil {
+		return nil, err
+	}
+	h := &Tensor{Shape: t.Shape, DType: t.DType, Device: "cpu"}
+	if t.DType.isPacked() {
+		data := make([]byte, size)
+		if err := d.CopyTo(t.Data, 0, data); err != nil {
+			return nil, err
+		}
+		h.Data = data
+		return h, nil
+	}
+	if h.Data, err = makeData(t.DType, t.Shape.ElemCount()); err != nil {
+		return nil, err
+	}
+	if b, ok := rawBytes(h.Data); ok {
+		if err := d.CopyTo(t.Data, 0, b); err != nil {
+			return nil, err
+		}
+		return h, nil
+	}
+	raw := make([]byte, size)
+	if err := d.CopyTo(t.Data, 0, raw); err != nil {
+		return nil, err
+	}
+	if h.Data, err = readData(t.Shape, t.DType, bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
@@ -0,0 +1,93 @@
+package gonpy
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memDevice is a Device whose buffers are host byte slices. The handles are
+// opaque, like those of real devices, so they cannot pass for host data.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+// testDevice is the name memDevice is registered under.
+const testDevice = "mem:0"
+
+func init() {
+	RegisterDevice(testDevice, memDevice{})
+}
+
+func TestWritersMoveDeviceTensorsToHost(t *testing.T) {
+	h := &Tensor{Data: []float32{1, -2, 3.5, 4}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"}
+	d, err := h.ToDevice(testDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each writer returns its encoding of a tensor, from a file if it writes one
+	path := filepath.Join(t.TempDir(), "out")
+	inFile := func(write func(x *Tensor) error) func(x *Tensor) ([]byte, error) {
+		return func(x *Tensor) ([]byte, error) {
+			if err := write(x); err != nil {
+				return nil, err
+			}
+			return os.ReadFile(path)
+		}
+	}
+	writers := map[string]func(x *Tensor) ([]byte, error){
+		"WriteNPY": inFile(func(x *Tensor) error { return x.WriteNPY(path) }),
+		"WriteNPZ": inFile(func(x *Tensor) error { return WriteNPZ(path, map[string]*Tensor{"x": x}) }),
+		"WriteNPZ f16": inFile(func(x *Tensor) error {
+			return WriteNPZ(path, map[string]*Tensor{"x": x}, WithStoreAs(DTypeF16))
+		}),
+		"WriteSafetensors": inFile(func(x *Tensor) error { return WriteSafetensors(path, map[string]*Tensor{"x": x}) }),
+		"WriteParquet":     inFile(func(x *Tensor) error { return WriteParquet(path, map[string]*Tensor{"x": x}) }),
+		"WriteTFRecord":    inFile(func(x *Tensor) error { return WriteTFRecord(path, map[string]*Tensor{"x": x}) }),
+		"WriteWAV":         inFile(func(x *Tensor) error { return WriteWAV(path, x, 8000) }),
+		"WriteCSV":         inFile(func(x *Tensor) error { return x.WriteCSV(path) }),
+		"MarshalBinary":    func(x *Tensor) ([]byte, error) { return x.MarshalBinary() },
+		"MarshalProto":     func(x *Tensor) ([]byte, error) { return x.MarshalProto() },
+		"EncodeJSON":       func(x *Tensor) ([]byte, error) { return x.EncodeJSON(WithJSONValues()) },
+		"ToNested": func(x *Tensor) ([]byte, error) {
+			v, err := x.ToNested()
+			return []byte(fmt.Sprint(v)), err
+		},
+		"DataAs": func(x *Tensor) ([]byte, error) {
+			v, err := DataAs[float64](x)
+			return []byte(fmt.Sprint(v)), err
+		},
+	}
+	for name, write := range writers {
+		want, err := write(h)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := write(d)
+		if err != nil {
+			t.Errorf("%s of a device tensor: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s of a device tensor differs from the host tensor's", name)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+)
+
+// NpzEditor records renames and deletions of the entries of an NPZ archive and
+// applies them when Save rewrites the archive. Entry data is copied without
+// being decoded, and is only recompressed if SetCompression was called.
+type NpzEditor struct {
+	path       string
+	o          *options
+	names      []string               // current names in archive order
+	sources    map[string]string      // current name to entry name in the archive
+	packing    map[string]packingInfo // keyed by current name
+	checksums  map[string]uint32      // keyed by current name
+	method     uint16
+	recompress bool
+}
+
+// OpenNpzEditor reads the entry list of the NPZ file at path for editing.
+func OpenNpzEditor(path string, opts ...Option) (*NpzEditor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if packing == nil {
+		packing = make(map[string]packingInfo)
+	}
+	checksums, err := readChecksums(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if checksums == nil {
+		checksums = make(map[string]uint32)
+	}
+
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, err
+	}
+	e := &NpzEditor{path: path, o: o, sources: make(map[string]string), packing: packing, checksums: checksums}
+	for _, file := range entries {
+		name := o.nameCodec.Decode(file.Name)
+		e.names = append(e.names, name)
+		e.sources[name] = file.Name
+	}
+	return e, nil
+}
+
+// Names returns the entry names as they will be written by Save.
+func (e *NpzEditor) Names() []string {
+	return append([]string(nil), e.names...)
+}
+
+// Rename changes the name of an entry. Names whose entry would not be a local
+// relative path, such as "../x", are rejected.
+func (e *NpzEditor) Rename(oldName, newName string) error {
+	source, ok := e.sources[oldName]
+	if !ok {
+		return ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", oldName, e.path), Err: ErrEntryNotFound}
+	}
+	if oldName == newName {
+		return nil
+	}
+	if _, err := sanitizeEntryName(e.o.nameCodec.Encode(newName)); err != nil {
+		return err
+	}
+	if _, ok := e.sources[newName]; ok {
+		return ErrorNpy{Msg: fmt.Sprintf("array %s already exists in %s", newName, e.path)}
+	}
+	for i, name := range e.names {
+		if name == oldName {
+			e.names[i] = newName
+		}
+	}
+	delete(e.sources, oldName)
+	e.sources[newName] = source
+	if info, ok := e.packing[oldName]; ok {
+		delete(e.packing, oldName)
+		e.packing[newName] = info
+	}
+	if sum, ok := e.checksums[oldName]; ok {
+		delete(e.checksums, oldName)
+		e.checksums[newName] = sum
+	}
+	return nil
+}
+
+// Delete removes an entry.
+func (e *NpzEditor) Delete(name string) error {
+	if _, ok := e.sources[name]; !ok {
+		return ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, e.path), Err: ErrEntryNotFound}
+	}
+	for i, n := range e.names {
+		if n == name {
+			e.names = append(e.names[:i], e.names[i+1:]...)
+			break
+		}
+	}
+	delete(e.sources, name)
+	delete(e.packing, name)
+	delete(e.checksums, name)
+	return nil
+}
+
+// SetCompression makes Save repack every entry with the given zip method,
+// such as zip.Store or zip.Deflate.
+func (e *NpzEditor) SetCompression(method uint16) {
+	e.method = method
+	e.recompress = true
+}
+
+// Save rewrites the archive with the recorded edits. The new archive is written
+// alongside the original, which it then replaces atomically.
+func (e *NpzEditor) Save() error {
+	if err := e.save(); err != nil {
+		return err
+	}
+	saved, err := OpenNpzEditor(e.path, func(o *options) { *o = *e.o })
+	if err != nil {
+		return err
+	}
+	*e = *saved
+	return nil
+}
+
+// save writes the edited archive and replaces the original with it.
+func (e *NpzEditor) save() error {
+	e.o.limiter.Acquire()
+	defer e.o.limiter.Release()
+	r, err := zip.OpenReader(e.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	files := make(map[string]*zip.File, len(r.File))
+	for _, file := range r.File {
+		files[file.Name] = file
+	}
+
+	tmp, err := createTemp(e.path, e.o)
+	if err != nil {
+		return err
+	}
+	if err := e.write(tmp, files); err != nil {
+		tmp.discard()
+		return err
+	}
+	r.Close()
+	return tmp.commit()
+}
+
+// write writes the edited archive to f, copying entries from files.
+func (e *NpzEditor) write(f *outputFile, files map[string]*zip.File) error {
+	zw := zip.NewWriter(f)
+	for _, name := range e.names {
+		file, ok := files[e.sources[name]]
+		if !ok {
+			return ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, e.path), Err: ErrEntryNotFound}
+		}
+		if err := e.copyEntry(zw, file, e.o.nameCodec.Encode(name)); err != nil {
+			return err
+		}
+	}
+	if err := writePacking(zw, e.packing); err != nil {
+		return err
+	}
+	if err := writeChecksums(zw, e.checksums); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// copyEntry copies file into zw under entryName, recompressing it if requested.
+func (e *NpzEditor) copyEntry(zw *zip.Writer, file *zip.File, entryName string) error {
+	if !e.recompress {
+		return copyRawEntry(zw, file, entryName)
+	}
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := zw.CreateHeader(&zip.FileHeader{Name: entryName, Method: e.method, Modified: file.Modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+// copyRawEntry copies the compressed data of file into zw under entryName.
+func copyRawEntry(zw *zip.Writer, file *zip.File, entryName string) error {
+	header := file.FileHeader
+	header.Name = entryName
+	src, err := file.OpenRaw()
+	if err != nil {
+		return err
+	}
+	dst, err := zw.CreateRaw(&header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
@@ -0,0 +1,59 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+)
+
+// DuplicatePolicy selects how reads handle several entries of an NPZ archive
+// whose names decode to the same tensor name.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError fails the read.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateFirstWins keeps the first such entry in archive order.
+	DuplicateFirstWins
+	// DuplicateLastWins keeps the last such entry in archive order.
+	DuplicateLastWins
+)
+
+// WithDuplicatePolicy sets how NPZ reads handle duplicate tensor names. The
+// default is DuplicateError.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(o *options) {
+		o.duplicates = p
+	}
+}
+
+// tensorEntries returns the entries of an archive that hold tensors, in archive
+// order. Entry names that are not local relative paths, such as "../x.npy" or
+// absolute paths, are rejected so that tensor names are safe to use as file
+// paths. Duplicate tensor names are resolved according to o.duplicates.
+func tensorEntries(files []*zip.File, o *options) ([]*zip.File, error) {
+	entries := make([]*zip.File, 0, len(files))
+	index := make(map[string]int, len(files))
+	for _, file := range files {
+		if isMetadataEntry(file.Name) {
+			continue
+		}
+		if _, err := sanitizeEntryName(file.Name); err != nil {
+			return nil, err
+		}
+		name := o.nameCodec.Decode(file.Name)
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(entries)
+			entries = append(entries, file)
+			continue
+		}
+		switch o.duplicates {
+		case DuplicateFirstWins:
+		case DuplicateLastWins:
+			entries[i] = file
+		default:
+			return nil, ErrorNpy{Msg: fmt.Sprintf("duplicate array name %s", name)}
+		}
+	}
+	return entries, nil
+}
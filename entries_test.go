@@ -0,0 +1,77 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEntryNamesRejected(t *testing.T) {
+	dir := t.TempDir()
+	header := "{'descr': '<f4', 'fortran_order': False, 'shape': (1,), }"
+	evil := filepath.Join(dir, "evil.npz")
+	writeZip(t, evil, map[string][]byte{"../x.npy": npyBytes(header, make([]byte, 4))})
+
+	if _, _, err := ReadNPZArena(evil, []string{"../x"}); err == nil {
+		t.Error("ReadNPZArena accepted an escaping entry name")
+	}
+	if err := MergeNPZ(filepath.Join(dir, "merged.npz"), evil); err == nil {
+		t.Error("MergeNPZ accepted an escaping entry name")
+	}
+
+	good := filepath.Join(dir, "good.npz")
+	writeZip(t, good, map[string][]byte{"x.npy": npyBytes(header, make([]byte, 4))})
+	e, err := OpenNpzEditor(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Rename("x", "../x"); err == nil {
+		t.Error("Rename accepted an escaping name")
+	}
+	if err := e.Rename("x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Save(); err != nil {
+		t.Fatal(err)
+	}
+	tensors, arena, err := ReadNPZArena(good, []string{"y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arena.Free()
+	if len(tensors[0].Data.([]float32)) != 1 {
+		t.Errorf("y = %v", tensors[0].Data)
+	}
+}
+
+func TestDuplicateEntriesInArena(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dup.npz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, v := range []byte{1, 2} {
+		w, err := zw.Create("x.npy")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(npyBytes("{'descr': '|u1', 'fortran_order': False, 'shape': (1,), }", []byte{v}))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, _, err := ReadNPZArena(path, []string{"x"}); err == nil {
+		t.Error("ReadNPZArena accepted duplicate entries")
+	}
+	tensors, _, err := ReadNPZArena(path, []string{"x"}, WithDuplicatePolicy(DuplicateLastWins))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tensors[0].Data.([]uint8); got[0] != 2 {
+		t.Errorf("x = %v, want the last entry", got)
+	}
+}
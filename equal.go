@@ -0,0 +1,130 @@
+package gonpy
+
+import (
+	"math"
+	"math/cmplx"
+	"reflect"
+)
+
+// WithEqualNaN makes Equal and AllClose treat NaN values as equal to each other,
+// as numpy's equal_nan argument does.
+func WithEqualNaN() Option {
+	return func(o *options) {
+		o.equalNaN = true
+	}
+}
+
+// Equal reports whether a and b have the same dtype, shape, and values.
+// Floating-point values are compared numerically, including F16 and BF16,
+// so that 0 equals -0 and NaN equals nothing unless WithEqualNaN is given.
+// Other values, including strings and structured records, must be identical.
+func Equal(a, b *Tensor, opts ...Option) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.DType != b.DType || !a.Shape.Equal(b.Shape) {
+		return false
+	}
+	if a.checkLength() != nil || b.checkLength() != nil {
+		return false
+	}
+	if a.DType.isPacked() {
+		n := a.Shape.ElemCount()
+		va, _ := widenPacked(a.DType, a.Data, n)
+		vb, _ := widenPacked(b.DType, b.Data, n)
+		return reflect.DeepEqual(va, vb)
+	}
+	if !a.DType.isFloat() && a.DType != DTypeC64 && a.DType != DTypeC128 {
+		return reflect.DeepEqual(a.Data, b.Data)
+	}
+	return AllClose(a, b, 0, 0, opts...)
+}
+
+// AllClose reports whether a and b have the same shape and every pair of
+// values satisfies |a - b| <= atol + rtol*|b|, as numpy.allclose does without
+// broadcasting. The tensors may have different numeric dtypes, including F16,
+// BF16, packed, and complex dtypes. Infinities are close only to the same
+// infinity, and NaN only to NaN with WithEqualNaN. Tensors of non-numeric
+// dtypes are compared with Equal.
+func AllClose(a, b *Tensor, rtol, atol float64, opts ...Option) bool {
+	o := newOptions(opts)
+	if a == nil || b == nil {
+		return a == b
+	}
+	if !a.Shape.Equal(b.Shape) {
+		return false
+	}
+	va, errA := a.widen()
+	vb, errB := b.widen()
+	if errA != nil || errB != nil {
+		return a.DType == b.DType && Equal(a, b, opts...)
+	}
+
+	if va.c != nil || vb.c != nil {
+		ca, cb := toComplex(va), toComplex(vb)
+		if len(ca) != len(cb) {
+			return false
+		}
+		for i := range ca {
+			if !isCloseComplex(ca[i], cb[i], rtol, atol, o.equalNaN) {
+				return false
+			}
+		}
+		return true
+	}
+	fa, fb := toFloat64(va), toFloat64(vb)
+	if len(fa) != len(fb) {
+		return false
+	}
+	for i := range fa {
+		if !isClose(fa[i], fb[i], rtol, atol, o.equalNaN) {
+			return false
+		}
+	}
+	return true
+}
+
+// widen converts the tensor's data to its intermediate kind after checking its length.
+func (t *Tensor) widen() (castValues, error) {
+	if err := t.checkLength(); err != nil {
+		return castValues{}, err
+	}
+	if t.DType.isPacked() {
+		return widenPacked(t.DType, t.Data, t.Shape.ElemCount())
+	}
+	return widen(t.DType, t.Data)
+}
+
+// toFloat64 converts real values to float64.
+func toFloat64(v castValues) []float64 {
+	switch {
+	case v.f != nil:
+		return v.f
+	case v.i != nil:
+		return widenSlice[int64, float64](v.i)
+	default:
+		return widenSlice[uint64, float64](v.u)
+	}
+}
+
+// isClose reports whether x is within atol + rtol*|y| of y.
+func isClose(x, y, rtol, atol float64, equalNaN bool) bool {
+	switch {
+	case math.IsNaN(x) || math.IsNaN(y):
+		return equalNaN && math.IsNaN(x) && math.IsNaN(y)
+	case math.IsInf(x, 0) || math.IsInf(y, 0):
+		return x == y
+	}
+	return math.Abs(x-y) <= atol+rtol*math.Abs(y)
+}
+
+// isCloseComplex is isClose for complex values, comparing their magnitudes.
+func isCloseComplex(x, y complex128, rtol, atol float64, equalNaN bool) bool {
+	switch {
+	case cmplx.IsNaN(x) || cmplx.IsNaN(y):
+		return equalNaN && cmplx.IsNaN(x) && cmplx.IsNaN(y)
+	case cmplx.IsInf(x) || cmplx.IsInf(y):
+		return x == y
+	}
+	return cmplx.Abs(x-y) <= atol+rtol*cmplx.Abs(y)
+}
@@ -0,0 +1,86 @@
+package gonpy
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	nan := math.NaN()
+	f64 := func(v ...float64) *Tensor { return &Tensor{Data: v, Shape: Shape{len(v)}, DType: DTypeF64} }
+	tests := []struct {
+		name    string
+		a, b    *Tensor
+		want    bool
+		wantNaN bool // With WithEqualNaN
+	}{
+		{"equal", f64(1, 2), f64(1, 2), true, true},
+		{"different value", f64(1, 2), f64(1, 3), false, false},
+		{"signed zeros", f64(0), f64(math.Copysign(0, -1)), true, true},
+		{"NaN", f64(nan, 1), f64(nan, 1), false, true},
+		{"NaN and number", f64(nan), f64(1), false, false},
+		{"infinities", f64(math.Inf(1)), f64(math.Inf(1)), true, true},
+		{"opposite infinities", f64(math.Inf(1)), f64(math.Inf(-1)), false, false},
+		{"shape mismatch", f64(1, 2), &Tensor{Data: []float64{1, 2}, Shape: Shape{1, 2}, DType: DTypeF64}, false, false},
+		{"dtype mismatch", f64(1, 2), &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32}, false, false},
+		{"short data", f64(1, 2), &Tensor{Data: []float64{1}, Shape: Shape{2}, DType: DTypeF64}, false, false},
+		{"nil", f64(1), nil, false, false},
+		{"both nil", nil, nil, true, true},
+		{"f16 signed zeros", &Tensor{Data: []uint16{0x0000}, Shape: Shape{1}, DType: DTypeF16}, &Tensor{Data: []uint16{0x8000}, Shape: Shape{1}, DType: DTypeF16}, true, true},
+		{"f16 NaN payloads", &Tensor{Data: []uint16{0x7e00}, Shape: Shape{1}, DType: DTypeF16}, &Tensor{Data: []uint16{0x7e01}, Shape: Shape{1}, DType: DTypeF16}, false, true},
+		{"complex NaN", &Tensor{Data: []complex128{complex(nan, 0)}, Shape: Shape{1}, DType: DTypeC128}, &Tensor{Data: []complex128{complex(0, nan)}, Shape: Shape{1}, DType: DTypeC128}, false, true},
+		{"ints", &Tensor{Data: []int64{1 << 62}, Shape: Shape{1}, DType: DTypeI64}, &Tensor{Data: []int64{1<<62 + 1}, Shape: Shape{1}, DType: DTypeI64}, false, false},
+		{"strings", &Tensor{Data: []string{"a", "b"}, Shape: Shape{2}, DType: UnicodeDType(1)}, &Tensor{Data: []string{"a", "b"}, Shape: Shape{2}, DType: UnicodeDType(1)}, true, true},
+		{"different strings", &Tensor{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1)}, &Tensor{Data: []string{"b"}, Shape: Shape{1}, DType: UnicodeDType(1)}, false, false},
+		{"packed ignores padding", &Tensor{Data: []byte{0x21, 0x03}, Shape: Shape{3}, DType: DTypeI4}, &Tensor{Data: []byte{0x21, 0xf3}, Shape: Shape{3}, DType: DTypeI4}, true, true},
+		{"packed", &Tensor{Data: []byte{0x21, 0x03}, Shape: Shape{3}, DType: DTypeU4}, &Tensor{Data: []byte{0x21, 0x04}, Shape: Shape{3}, DType: DTypeU4}, false, false},
+	}
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: Equal = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Equal(tt.b, tt.a); got != tt.want {
+			t.Errorf("%s: reversed Equal = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := Equal(tt.a, tt.b, WithEqualNaN()); got != tt.wantNaN {
+			t.Errorf("%s: Equal with WithEqualNaN = %v, want %v", tt.name, got, tt.wantNaN)
+		}
+	}
+}
+
+func TestAllClose(t *testing.T) {
+	f64 := func(v ...float64) *Tensor { return &Tensor{Data: v, Shape: Shape{len(v)}, DType: DTypeF64} }
+	tests := []struct {
+		name       string
+		a, b       *Tensor
+		rtol, atol float64
+		want       bool
+	}{
+		{"within atol", f64(1, 2), f64(1.05, 2), 0, 0.1, true},
+		{"outside atol", f64(1, 2), f64(1.2, 2), 0, 0.1, false},
+		{"within rtol of b", f64(99), f64(100), 0.01, 0, true},
+		{"outside rtol of b", f64(100), f64(99), 0.01, 0, false},
+		{"exact", f64(3), f64(3), 0, 0, true},
+		{"infinity", f64(math.Inf(1)), f64(math.Inf(1)), 1, 1, true},
+		{"infinity and number", f64(math.Inf(1)), f64(1e308), 1, 1, false},
+		{"NaN", f64(math.NaN()), f64(math.NaN()), 1, 1, false},
+		{"mixed dtypes", &Tensor{Data: []int32{1, 2}, Shape: Shape{2}, DType: DTypeI32}, &Tensor{Data: []float32{1, 2.001}, Shape: Shape{2}, DType: DTypeF32}, 0, 0.01, true},
+		{"f16 and f64", &Tensor{Data: []uint16{0x3c00, 0xc000}, Shape: Shape{2}, DType: DTypeF16}, f64(1, -2), 0, 0, true},
+		{"bf16 and f32", &Tensor{Data: []uint16{0x3f80}, Shape: Shape{1}, DType: DTypeBF16}, &Tensor{Data: []float32{1.01}, Shape: Shape{1}, DType: DTypeF32}, 0, 0.001, false},
+		{"packed and int", &Tensor{Data: []byte{0x8f}, Shape: Shape{2}, DType: DTypeI4}, &Tensor{Data: []int8{-1, -8}, Shape: Shape{2}, DType: DTypeI8}, 0, 0, true},
+		{"complex", &Tensor{Data: []complex64{3 + 4i}, Shape: Shape{1}, DType: DTypeC64}, &Tensor{Data: []complex128{3 + 4.05i}, Shape: Shape{1}, DType: DTypeC128}, 0, 0.1, true},
+		{"complex and real", &Tensor{Data: []complex128{2 + 1i}, Shape: Shape{1}, DType: DTypeC128}, f64(2), 0, 0.5, false},
+		{"shape mismatch", f64(1, 2), &Tensor{Data: []float64{1, 2}, Shape: Shape{2, 1}, DType: DTypeF64}, 1, 1, false},
+		{"short data", f64(1, 2), &Tensor{Data: []float64{1}, Shape: Shape{2}, DType: DTypeF64}, 1, 1, false},
+		{"strings", &Tensor{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1)}, &Tensor{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1)}, 0, 0, true},
+		{"string and number", &Tensor{Data: []string{"1"}, Shape: Shape{1}, DType: UnicodeDType(1)}, f64(1), 1, 1, false},
+	}
+	for _, tt := range tests {
+		if got := AllClose(tt.a, tt.b, tt.rtol, tt.atol); got != tt.want {
+			t.Errorf("%s: AllClose = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !AllClose(f64(math.NaN()), f64(math.NaN()), 0, 0, WithEqualNaN()) {
+		t.Error("NaNs not close with WithEqualNaN")
+	}
+}
@@ -83,6 +83,7 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create NpzTensors: %v", err)
 	}
+	defer npzTensors.Close()
 
 	// List available tensor names
 	names = npzTensors.Names()
@@ -0,0 +1,180 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractLimits bounds the resources consumed by ExtractNPZ.
+// A zero value for any field means that dimension is unlimited.
+type ExtractLimits struct {
+	MaxEntries    int   // Maximum number of entries in the archive.
+	MaxEntryBytes int64 // Maximum uncompressed size of a single entry.
+	MaxTotalBytes int64 // Maximum uncompressed size of all entries combined.
+}
+
+// sanitizeEntryName validates a zip entry name and returns it as a local relative path.
+func sanitizeEntryName(name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, 0) {
+		return "", ErrorNpy{Msg: fmt.Sprintf("invalid entry name %q", name)}
+	}
+	if strings.Contains(name, "\\") {
+		return "", ErrorNpy{Msg: fmt.Sprintf("entry name %q contains a backslash", name)}
+	}
+	if len(name) >= 2 && name[1] == ':' {
+		// A drive letter, which filepath.IsLocal only recognizes on Windows
+		return "", ErrorNpy{Msg: fmt.Sprintf("entry name %q has a volume name", name)}
+	}
+	cleaned := filepath.FromSlash(name)
+	if !filepath.IsLocal(cleaned) {
+		return "", ErrorNpy{Msg: fmt.Sprintf("entry name %q escapes the destination directory", name)}
+	}
+	return filepath.Clean(cleaned), nil
+}
+
+// ExtractNPZ unpacks the entries of an NPZ file into dir, enforcing the given limits.
+// Entry names are sanitized so that nothing is written outside dir, and sizes are
+// checked against the bytes actually decompressed rather than the sizes claimed by
+// the archive. Only regular files are extracted; directory entries are created and
+// all other entry types are rejected. The paths of the extracted files are returned.
+// Names and entry types are checked before anything is written. If extraction
+// fails later, such as on an entry larger than its claimed size, the files and
+// directories created so far are removed.
+func ExtractNPZ(path, dir string, limits ExtractLimits, opts ...Option) ([]string, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if limits.MaxEntries > 0 && len(r.File) > limits.MaxEntries {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("archive has %d entries, limit is %d", len(r.File), limits.MaxEntries)}
+	}
+	names := make([]string, len(r.File))
+	for i, file := range r.File {
+		if names[i], err = sanitizeEntryName(file.Name); err != nil {
+			return nil, err
+		}
+		if mode := file.Mode(); !mode.IsDir() && !mode.IsRegular() {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("entry %s is not a regular file", file.Name)}
+		}
+	}
+
+	var created []string
+	paths, err := extractEntries(r.File, names, dir, limits, &created)
+	if err != nil {
+		for i := len(created) - 1; i >= 0; i-- {
+			os.Remove(created[i])
+		}
+		return nil, err
+	}
+	return paths, nil
+}
+
+// extractEntries extracts files, whose sanitized names are names, into dir,
+// appending each file and directory it creates to created.
+func extractEntries(files []*zip.File, names []string, dir string, limits ExtractLimits, created *[]string) ([]string, error) {
+	if err := mkdirAll(dir, created); err != nil {
+		return nil, err
+	}
+	var paths []string
+	var total int64
+	for i, file := range files {
+		dest := filepath.Join(dir, names[i])
+		if file.Mode().IsDir() {
+			if err := mkdirAll(dest, created); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		budget := int64(-1)
+		if limits.MaxEntryBytes > 0 {
+			budget = limits.MaxEntryBytes
+		}
+		if limits.MaxTotalBytes > 0 {
+			if remaining := limits.MaxTotalBytes - total; budget < 0 || remaining < budget {
+				budget = remaining
+			}
+		}
+
+		n, err := extractEntry(file, dest, budget, created)
+		if err != nil {
+			return nil, err
+		}
+		total += n
+		paths = append(paths, dest)
+	}
+	return paths, nil
+}
+
+// extractEntry copies a single zip entry to dest, failing if more than budget bytes
+// are produced. A negative budget disables the check. If dest is a new file, it is
+// appended to created.
+func extractEntry(file *zip.File, dest string, budget int64, created *[]string) (int64, error) {
+	if budget >= 0 && file.UncompressedSize64 > uint64(budget) {
+		return 0, ErrorNpy{Msg: fmt.Sprintf("entry %s exceeds size limit", file.Name)}
+	}
+
+	if err := mkdirAll(filepath.Dir(dest), created); err != nil {
+		return 0, err
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	_, statErr := os.Lstat(dest)
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if os.IsNotExist(statErr) {
+		*created = append(*created, dest)
+	}
+
+	var src io.Reader = rc
+	if budget >= 0 {
+		src = io.LimitReader(rc, budget+1)
+	}
+	n, err := io.Copy(f, src)
+	if err != nil {
+		return n, err
+	}
+	if budget >= 0 && n > budget {
+		return n, ErrorNpy{Msg: fmt.Sprintf("entry %s exceeds size limit", file.Name)}
+	}
+	return n, f.Close()
+}
+
+// mkdirAll creates dir and any missing parents, as os.MkdirAll does, appending
+// the directories it creates to created, parents first.
+func mkdirAll(dir string, created *[]string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Lstat(d); !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		*created = append(*created, missing[i])
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZipEntries writes a zip file holding entries, alternately names and
+// contents, in the order given.
+func writeZipEntries(t *testing.T, path string, entries ...string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for i := 0; i < len(entries); i += 2 {
+		w, err := zw.Create(entries[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entries[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertEmptyDir fails unless dir is missing or has no entries.
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	names, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	for _, n := range names {
+		t.Errorf("%s left in %s", n.Name(), dir)
+	}
+}
+
+func TestExtractNPZ(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.npz")
+	writeZipEntries(t, path, "x.npy", "xx", "sub/", "", "sub/y.npy", "yyy")
+	dir := filepath.Join(tmp, "out")
+	paths, err := ExtractNPZ(path, dir, ExtractLimits{MaxEntries: 3, MaxEntryBytes: 3, MaxTotalBytes: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("paths = %v", paths)
+	}
+	for name, want := range map[string]string{"x.npy": "xx", "sub/y.npy": "yyy"} {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestExtractNPZUnsafeNames(t *testing.T) {
+	for _, name := range []string{"../x.npy", "a/../../x.npy", "/abs.npy", `a\b.npy`, `..\x.npy`, "C:/x.npy", "c:x.npy", ""} {
+		tmp := t.TempDir()
+		path := filepath.Join(tmp, "a.npz")
+		// The good entry comes first, so it would be written before the bad one
+		writeZipEntries(t, path, "a.npy", "a", name, "evil")
+		dir := filepath.Join(tmp, "out")
+		if _, err := ExtractNPZ(path, dir, ExtractLimits{}); err == nil {
+			t.Errorf("%q extracted", name)
+		}
+		assertEmptyDir(t, dir)
+		if _, err := os.Stat(filepath.Join(tmp, "x.npy")); err == nil {
+			t.Errorf("%q written outside the destination", name)
+		}
+	}
+}
+
+func TestExtractNPZLimits(t *testing.T) {
+	tests := map[string]ExtractLimits{
+		"entries":     {MaxEntries: 2},
+		"entry bytes": {MaxEntryBytes: 3},
+		"total bytes": {MaxTotalBytes: 9},
+	}
+	// The byte limits are only exceeded by the last entry, after a new
+	// subdirectory and two files have been written
+	for name, limits := range tests {
+		tmp := t.TempDir()
+		path := filepath.Join(tmp, "a.npz")
+		writeZipEntries(t, path, "a.npy", "aaa", "b/c.npy", "bbb", "d.npy", "dddd")
+		dir := filepath.Join(tmp, "out")
+		if _, err := ExtractNPZ(path, dir, limits); err == nil {
+			t.Errorf("%s: limit not enforced", name)
+		}
+		assertEmptyDir(t, dir)
+	}
+}
+
+func TestExtractNPZKeepsExisting(t *testing.T) {
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.npz")
+	writeZipEntries(t, path, "a.npy", "a", "b.npy", "bbbb")
+	dir := filepath.Join(tmp, "out")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	keep := filepath.Join(dir, "keep")
+	if err := os.WriteFile(keep, []byte("k"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExtractNPZ(path, dir, ExtractLimits{MaxEntryBytes: 2}); err == nil {
+		t.Fatal("limit not enforced")
+	}
+	names, _ := os.ReadDir(dir)
+	if len(names) != 1 || names[0].Name() != "keep" {
+		t.Errorf("destination holds %v, want only the existing file", names)
+	}
+}
+
+func TestExtractNPZLyingSize(t *testing.T) {
+	// A stored entry that claims 4 bytes but holds far more
+	payload := bytes.Repeat([]byte{'z'}, 1<<16)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("a.npy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("a"))
+	w, err = zw.CreateRaw(&zip.FileHeader{
+		Name:               "bomb.npy",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE(payload),
+		CompressedSize64:   uint64(len(payload)),
+		UncompressedSize64: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(payload)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tmp := t.TempDir()
+	path := filepath.Join(tmp, "a.npz")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(tmp, "out")
+	if _, err := ExtractNPZ(path, dir, ExtractLimits{MaxEntryBytes: 1 << 10}); err == nil {
+		t.Error("entry larger than its declared size extracted")
+	}
+	assertEmptyDir(t, dir)
+}
@@ -0,0 +1,152 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"reflect"
+)
+
+// Zeros returns a tensor of the given dtype and shape with every element zero.
+func Zeros(dtype DType, shape Shape) (*Tensor, error) {
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if dtype.isPacked() {
+		data = make([]byte, (n+1)/2)
+	} else if data, err = makeData(dtype, n); err != nil {
+		return nil, err
+	}
+	return &Tensor{Data: data, Shape: append(Shape{}, shape...), DType: dtype, Device: "cpu"}, nil
+}
+
+// Ones returns a tensor of the given numeric dtype and shape with every element one.
+func Ones(dtype DType, shape Shape) (*Tensor, error) {
+	return Full(dtype, shape, 1)
+}
+
+// Full returns a tensor of the given numeric dtype and shape with every element
+// set to value, which must be representable in dtype.
+func Full(dtype DType, shape Shape, value float64) (*Tensor, error) {
+	t, err := Zeros(dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	one, err := (&Tensor{Data: []float64{value}, Shape: Shape{1}, DType: DTypeF64}).Cast(dtype, WithOverflow(OverflowError))
+	if err != nil {
+		return nil, err
+	}
+	n := t.Shape.ElemCount()
+	if dtype.isPacked() {
+		// Repeat the byte holding the value in both nibbles
+		b := one.Data.([]byte)[0]
+		fill(reflect.ValueOf(t.Data), reflect.ValueOf(b|b<<4))
+		if n%2 == 1 {
+			t.Data.([]byte)[n/2] = b
+		}
+		return t, nil
+	}
+	if n > 0 {
+		fill(reflect.ValueOf(t.Data), reflect.ValueOf(one.Data).Index(0))
+	}
+	return t, nil
+}
+
+// fill sets every element of the slice s to v, copying in doubling runs.
+func fill(s, v reflect.Value) {
+	if s.Len() == 0 {
+		return
+	}
+	s.Index(0).Set(v)
+	for k := 1; k < s.Len(); k *= 2 {
+		reflect.Copy(s.Slice(k, s.Len()), s.Slice(0, k))
+	}
+}
+
+// Arange returns a 1-D tensor of the given numeric dtype holding start,
+// start+step, and so on up to but excluding stop, as numpy.arange does.
+func Arange(dtype DType, start, stop, step float64) (*Tensor, error) {
+	if step == 0 || math.IsNaN(step) || math.IsInf(step, 0) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid step %v", step)}
+	}
+	count := math.Ceil((stop - start) / step)
+	if math.IsNaN(count) || count > math.MaxInt32 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid range from %v to %v by %v", start, stop, step)}
+	}
+	values := make([]float64, max(0, int(count)))
+	for i := range values {
+		values[i] = start + float64(i)*step
+	}
+	return fromFloat64(dtype, values)
+}
+
+// Linspace returns a 1-D tensor of the given numeric dtype holding num evenly
+// spaced values from start to stop inclusive, as numpy.linspace does.
+func Linspace(dtype DType, start, stop float64, num int) (*Tensor, error) {
+	if num < 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("negative number of samples %d", num)}
+	}
+	values := make([]float64, num)
+	step := 0.0
+	if num > 1 {
+		step = (stop - start) / float64(num-1)
+	}
+	for i := range values {
+		values[i] = start + float64(i)*step
+	}
+	if num > 1 {
+		values[num-1] = stop
+	}
+	return fromFloat64(dtype, values)
+}
+
+// Rand returns a tensor of the given floating-point or complex dtype and shape
+// holding values drawn uniformly from [0, 1) using rng, or the package-level
+// generator of math/rand/v2 if rng is nil. Complex values have a zero
+// imaginary part.
+func Rand(rng *rand.Rand, dtype DType, shape Shape) (*Tensor, error) {
+	if !dtype.isFloat() && dtype != DTypeC64 && dtype != DTypeC128 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("random values need a floating-point dtype, not %s", dtype)}
+	}
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	// Draw multiples of 2^-bits so that values are exact in dtype and below 1
+	bits := 53
+	switch dtype {
+	case DTypeF32, DTypeC64:
+		bits = 24
+	case DTypeF16:
+		bits = 11
+	case DTypeBF16:
+		bits = 8
+	}
+	values := make([]float64, n)
+	for i := range values {
+		var u uint64
+		if rng != nil {
+			u = rng.Uint64()
+		} else {
+			u = rand.Uint64()
+		}
+		values[i] = math.Ldexp(float64(u>>(64-bits)), -bits)
+	}
+	t, err := fromFloat64(dtype, values)
+	if err != nil {
+		return nil, err
+	}
+	t.Shape = append(Shape{}, shape...)
+	return t, nil
+}
+
+// fromFloat64 returns a 1-D tensor of values converted to dtype.
+func fromFloat64(dtype DType, values []float64) (*Tensor, error) {
+	t := &Tensor{Data: values, Shape: Shape{len(values)}, DType: DTypeF64, Device: "cpu"}
+	if dtype == DTypeF64 {
+		return t, nil
+	}
+	return t.Cast(dtype, WithOverflow(OverflowError))
+}
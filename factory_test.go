@@ -0,0 +1,134 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestZerosFull(t *testing.T) {
+	tests := []struct {
+		name  string
+		make  func() (*Tensor, error)
+		shape Shape
+		want  string
+	}{
+		{"zeros f32", func() (*Tensor, error) { return Zeros(DTypeF32, Shape{2, 2}) }, Shape{2, 2}, "[0 0 0 0]"},
+		{"zeros scalar", func() (*Tensor, error) { return Zeros(DTypeI64, Shape{}) }, Shape{}, "[0]"},
+		{"zeros empty", func() (*Tensor, error) { return Zeros(DTypeF64, Shape{3, 0}) }, Shape{3, 0}, "[]"},
+		{"zeros strings", func() (*Tensor, error) { return Zeros(UnicodeDType(2), Shape{2}) }, Shape{2}, "[ ]"},
+		{"zeros i4", func() (*Tensor, error) { return Zeros(DTypeI4, Shape{3}) }, Shape{3}, "[0 0]"},
+		{"ones bool", func() (*Tensor, error) { return Ones(DTypeBool, Shape{2}) }, Shape{2}, "[true true]"},
+		{"ones f16", func() (*Tensor, error) { return Ones(DTypeF16, Shape{1}) }, Shape{1}, "[15360]"},
+		{"full i32", func() (*Tensor, error) { return Full(DTypeI32, Shape{3}, -7) }, Shape{3}, "[-7 -7 -7]"},
+		{"full c64", func() (*Tensor, error) { return Full(DTypeC64, Shape{1}, 2.5) }, Shape{1}, "[(2.5+0i)]"},
+		{"full i4 odd", func() (*Tensor, error) { return Full(DTypeI4, Shape{3}, -1) }, Shape{3}, "[255 15]"},
+		{"full u4 even", func() (*Tensor, error) { return Full(DTypeU4, Shape{2, 2}, 9) }, Shape{2, 2}, "[153 153]"},
+		{"full empty", func() (*Tensor, error) { return Full(DTypeU8, Shape{0}, 3) }, Shape{0}, "[]"},
+	}
+	for _, tt := range tests {
+		got, err := tt.make()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !got.Shape.Equal(tt.shape) || fmt.Sprint(got.Data) != tt.want || got.Device != "cpu" {
+			t.Errorf("%s: got %v %v on %q, want %v %s", tt.name, got.Shape, got.Data, got.Device, tt.shape, tt.want)
+		}
+		if err := got.checkLength(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	// The result does not share the caller's shape
+	shape := Shape{2}
+	z, err := Zeros(DTypeF32, shape)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape[0] = 5
+	if !z.Shape.Equal(Shape{2}) {
+		t.Errorf("shape changed to %v with the caller's", z.Shape)
+	}
+}
+
+func TestArangeLinspace(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() (*Tensor, error)
+		want string
+	}{
+		{"arange", func() (*Tensor, error) { return Arange(DTypeI64, 0, 5, 1) }, "[0 1 2 3 4]"},
+		{"arange fractional stop", func() (*Tensor, error) { return Arange(DTypeF64, 1, 2.1, 0.5) }, "[1 1.5 2]"},
+		{"arange descending", func() (*Tensor, error) { return Arange(DTypeI32, 3, 0, -1) }, "[3 2 1]"},
+		{"arange empty", func() (*Tensor, error) { return Arange(DTypeF32, 5, 0, 1) }, "[]"},
+		{"linspace", func() (*Tensor, error) { return Linspace(DTypeF64, 0, 1, 5) }, "[0 0.25 0.5 0.75 1]"},
+		{"linspace one", func() (*Tensor, error) { return Linspace(DTypeF32, 2, 3, 1) }, "[2]"},
+		{"linspace none", func() (*Tensor, error) { return Linspace(DTypeF64, 2, 3, 0) }, "[]"},
+		{"linspace exact stop", func() (*Tensor, error) { return Linspace(DTypeF64, 0, 0.3, 4) }, "[0 0.09999999999999999 0.19999999999999998 0.3]"},
+		{"linspace ints", func() (*Tensor, error) { return Linspace(DTypeU8, 0, 10, 3) }, "[0 5 10]"},
+	}
+	for _, tt := range tests {
+		got, err := tt.make()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(got.Shape) != 1 || fmt.Sprint(got.Data) != tt.want {
+			t.Errorf("%s: got %v %v, want %s", tt.name, got.Shape, got.Data, tt.want)
+		}
+	}
+}
+
+func TestRand(t *testing.T) {
+	for _, dtype := range []DType{DTypeF16, DTypeBF16, DTypeF32, DTypeF64, DTypeC64, DTypeC128} {
+		x, err := Rand(rand.New(rand.NewPCG(1, 2)), dtype, Shape{10, 10})
+		if err != nil {
+			t.Fatalf("%s: %v", dtype, err)
+		}
+		y, err := Rand(rand.New(rand.NewPCG(1, 2)), dtype, Shape{10, 10})
+		if err != nil {
+			t.Fatalf("%s: %v", dtype, err)
+		}
+		if x.DType != dtype || !x.Shape.Equal(Shape{10, 10}) || !Equal(x, y) {
+			t.Errorf("%s: same seed gave different tensors", dtype)
+		}
+		s, err := x.Cast(DTypeC128)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range s.Data.([]complex128) {
+			if real(v) < 0 || real(v) >= 1 || imag(v) != 0 {
+				t.Errorf("%s: value %v out of [0, 1)", dtype, v)
+				break
+			}
+		}
+	}
+	if _, err := Rand(nil, DTypeF32, Shape{3}); err != nil {
+		t.Errorf("package-level generator: %v", err)
+	}
+}
+
+func TestFactoryInvalid(t *testing.T) {
+	tests := map[string]func() (*Tensor, error){
+		"negative dimension":   func() (*Tensor, error) { return Zeros(DTypeF32, Shape{2, -1}) },
+		"unknown dtype":        func() (*Tensor, error) { return Zeros("<q7", Shape{1}) },
+		"full overflow":        func() (*Tensor, error) { return Full(DTypeU8, Shape{1}, 256) },
+		"full negative uint":   func() (*Tensor, error) { return Full(DTypeU32, Shape{1}, -1) },
+		"full i4 overflow":     func() (*Tensor, error) { return Full(DTypeI4, Shape{2}, 8) },
+		"full string":          func() (*Tensor, error) { return Full(UnicodeDType(1), Shape{1}, 1) },
+		"arange zero step":     func() (*Tensor, error) { return Arange(DTypeF64, 0, 1, 0) },
+		"arange NaN step":      func() (*Tensor, error) { return Arange(DTypeF64, 0, 1, math.NaN()) },
+		"arange infinite stop": func() (*Tensor, error) { return Arange(DTypeF64, 0, math.Inf(1), 1) },
+		"arange overflow":      func() (*Tensor, error) { return Arange(DTypeI8, 0, 200, 1) },
+		"linspace negative":    func() (*Tensor, error) { return Linspace(DTypeF64, 0, 1, -1) },
+		"rand int":             func() (*Tensor, error) { return Rand(nil, DTypeI32, Shape{1}) },
+		"rand bad shape":       func() (*Tensor, error) { return Rand(nil, DTypeF32, Shape{-2}) },
+	}
+	for name, f := range tests {
+		if got, err := f(); err == nil {
+			t.Errorf("%s: got %v %v", name, got.Shape, got.Data)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+)
+
+// f16BitsToF32 converts an IEEE 754 half-precision bit pattern to float32.
+// The conversion is exact, including subnormals, infinities, and NaN payloads.
+func f16BitsToF32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f: // Inf or NaN
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0: // Zero or subnormal
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		mant &= 0x3ff
+		return math.Float32frombits(sign | e<<23 | mant<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	}
+}
+
+// f32ToF16Bits converts a float32 to an IEEE 754 half-precision bit pattern,
+// rounding to nearest with ties to even. Values too large for half precision
+// become infinities and values too small become (signed) zero or subnormals.
+func f32ToF16Bits(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if exp == 0xff { // Inf or NaN
+		if mant != 0 {
+			return sign | 0x7e00 | uint16(mant>>13) // Keep NaN quiet and non-zero
+		}
+		return sign | 0x7c00
+	}
+
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+	if e <= 0 {
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	h := uint32(e)<<10 | mant>>13
+	rem := mant & 0x1fff
+	if rem > 0x1000 || (rem == 0x1000 && h&1 == 1) {
+		h++ // A carry into the exponent correctly rounds up to the next binade or Inf
+	}
+	return sign | uint16(h)
+}
+
+// F16BitsToF32 converts a single half-precision bit pattern to a float32.
+func F16BitsToF32(h uint16) float32 {
+	return f16BitsToF32(h)
+}
+
+// F16ToF32 converts half-precision bit patterns to float32 values.
+func F16ToF32(src []uint16) []float32 {
+	dst := make([]float32, len(src))
+	f16ToF32s(dst, src)
+	return dst
+}
+
+// F32ToF16 converts float32 values to half-precision bit patterns, rounding to
+// nearest with ties to even.
+func F32ToF16(src []float32) []uint16 {
+	dst := make([]uint16, len(src))
+	f32ToF16s(dst, src)
+	return dst
+}
+
+// f16ToF32sGeneric converts src into dst one element at a time.
+func f16ToF32sGeneric(dst []float32, src []uint16) {
+	for i, h := range src {
+		dst[i] = f16BitsToF32(h)
+	}
+}
+
+// f32ToF16sGeneric converts src into dst one element at a time.
+func f32ToF16sGeneric(dst []uint16, src []float32) {
+	for i, f := range src {
+		dst[i] = f32ToF16Bits(f)
+	}
+}
+
+// ToFloat32 returns a new F32 tensor holding the values of t, which must be of
+// a floating-point dtype. The original tensor is not modified.
+func (t *Tensor) ToFloat32() (*Tensor, error) {
+	var data []float32
+	switch t.DType {
+	case DTypeF16:
+		src, ok := t.Data.([]uint16)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("f16 tensor data is %T, expected []uint16", t.Data)}
+		}
+		data = F16ToF32(src)
+	case DTypeBF16:
+		src, ok := t.Data.([]uint16)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("bf16 tensor data is %T, expected []uint16", t.Data)}
+		}
+		data = BF16ToF32(src)
+	case DTypeF32:
+		src, ok := t.Data.([]float32)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("f32 tensor data is %T, expected []float32", t.Data)}
+		}
+		data = append([]float32(nil), src...)
+	case DTypeF64:
+		src, ok := t.Data.([]float64)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("f64 tensor data is %T, expected []float64", t.Data)}
+		}
+		data = make([]float32, len(src))
+		for i, v := range src {
+			data[i] = float32(v)
+		}
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot convert %s tensor to f32", t.DType)}
+	}
+
+	shape := append(Shape(nil), t.Shape...)
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  DTypeF32,
+		Device: t.Device,
+	}, nil
+}
@@ -0,0 +1,381 @@
+// Package gguf reads and writes tensors and key/value metadata in GGUF files,
+// the format used by llama.cpp and other ggml-based tools.
+//
+// A GGUF file holds a header of typed key/value pairs, a table describing each
+// tensor, and the aligned tensor data. Dimensions in the table are listed
+// fastest-varying first; this package reverses them so that shapes read the
+// same way as numpy and gonpy shapes. Quantized ggml block formats are
+// dequantized to F32 when read, and 16-bit integers are widened to I32, since
+// gonpy has no dtype for them.
+package gguf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/gocnn/gonpy"
+)
+
+// magic identifies GGUF files: "GGUF" read as a little-endian uint32.
+const magic = 0x46554747
+
+// DefaultAlignment is the alignment of tensor data when the file does not set
+// the general.alignment key.
+const DefaultAlignment = 32
+
+// alignmentKey is the metadata key overriding the data alignment.
+const alignmentKey = "general.alignment"
+
+// Limits on header values, so that a corrupt count or length cannot trigger an
+// enormous allocation.
+const (
+	maxStringLen = 1 << 30
+	maxCount     = 1 << 28
+	maxDims      = 4 // GGML_MAX_DIMS
+)
+
+// Type is a ggml tensor type.
+type Type uint32
+
+const (
+	TypeF32  Type = 0
+	TypeF16  Type = 1
+	TypeQ4_0 Type = 2
+	TypeQ4_1 Type = 3
+	TypeQ5_0 Type = 6
+	TypeQ5_1 Type = 7
+	TypeQ8_0 Type = 8
+	TypeQ8_1 Type = 9
+	TypeQ2_K Type = 10
+	TypeQ3_K Type = 11
+	TypeQ4_K Type = 12
+	TypeQ5_K Type = 13
+	TypeQ6_K Type = 14
+	TypeQ8_K Type = 15
+	TypeI8   Type = 24
+	TypeI16  Type = 25
+	TypeI32  Type = 26
+	TypeI64  Type = 27
+	TypeF64  Type = 28
+	TypeBF16 Type = 30
+)
+
+// typeNames holds the names ggml gives the types this package knows.
+var typeNames = map[Type]string{
+	TypeF32: "F32", TypeF16: "F16", TypeQ4_0: "Q4_0", TypeQ4_1: "Q4_1",
+	TypeQ5_0: "Q5_0", TypeQ5_1: "Q5_1", TypeQ8_0: "Q8_0", TypeQ8_1: "Q8_1",
+	TypeQ2_K: "Q2_K", TypeQ3_K: "Q3_K", TypeQ4_K: "Q4_K", TypeQ5_K: "Q5_K",
+	TypeQ6_K: "Q6_K", TypeQ8_K: "Q8_K", TypeI8: "I8", TypeI16: "I16",
+	TypeI32: "I32", TypeI64: "I64", TypeF64: "F64", TypeBF16: "BF16",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", uint32(t))
+}
+
+// plainTypes maps unquantized ggml types to the dtypes they are read as.
+var plainTypes = map[Type]gonpy.DType{
+	TypeF32:  gonpy.DTypeF32,
+	TypeF16:  gonpy.DTypeF16,
+	TypeBF16: gonpy.DTypeBF16,
+	TypeF64:  gonpy.DTypeF64,
+	TypeI8:   gonpy.DTypeI8,
+	TypeI16:  gonpy.DTypeI32,
+	TypeI32:  gonpy.DTypeI32,
+	TypeI64:  gonpy.DTypeI64,
+}
+
+// DType returns the dtype tensors of type t are read as, and false if t is
+// neither a plain type nor a quantized format this package can dequantize.
+func (t Type) DType() (gonpy.DType, bool) {
+	if dtype, ok := plainTypes[t]; ok {
+		return dtype, true
+	}
+	_, ok := blockFormats[t]
+	return gonpy.DTypeF32, ok
+}
+
+// dataSize returns the number of bytes of n elements of type t.
+func (t Type) dataSize(n int64) (int64, error) {
+	switch t {
+	case TypeF32, TypeI32:
+		return mulSize(n, 4)
+	case TypeF16, TypeBF16, TypeI16:
+		return mulSize(n, 2)
+	case TypeF64, TypeI64:
+		return mulSize(n, 8)
+	case TypeI8:
+		return mulSize(n, 1)
+	}
+	if b, ok := blockFormats[t]; ok {
+		if n%int64(b.elems) != 0 {
+			return 0, fmt.Errorf("gguf: %d %s elements do not fill whole blocks of %d", n, t, b.elems)
+		}
+		return mulSize(n/int64(b.elems), int64(b.bytes))
+	}
+	return 0, fmt.Errorf("gguf: unsupported tensor type %s: %w", t, gonpy.ErrUnsupportedDType)
+}
+
+// mulSize returns n*size, or an error if n is negative or the product
+// overflows an int64.
+func mulSize(n, size int64) (int64, error) {
+	if n < 0 || n > math.MaxInt64/size {
+		return 0, fmt.Errorf("gguf: data size of %d elements overflows: %w", n, gonpy.ErrTooLarge)
+	}
+	return n * size, nil
+}
+
+// TensorInfo describes a tensor in a GGUF file.
+type TensorInfo struct {
+	Name   string
+	Shape  gonpy.Shape // Slowest-varying dimension first, as in numpy
+	Type   Type
+	Offset int64 // Relative to the start of the data section
+	Size   int64 // Bytes of data
+}
+
+// File provides access to the metadata and tensors of a GGUF file. Tensors
+// are loaded on demand; the file stays open until Close is called.
+type File struct {
+	Version   uint32
+	Metadata  map[string]interface{}
+	Keys      []string // Metadata keys in file order
+	Tensors   []TensorInfo
+	Alignment int
+
+	f         *os.File
+	dataStart int64
+	index     map[string]int
+	limits    gonpy.ReadLimits
+}
+
+// Open opens a GGUF file and reads its header and tensor table. The file
+// limiter and the size limit set with gonpy.WithMaxBytes apply; the file
+// occupies a slot of the limiter until Close is called.
+func Open(path string, opts ...gonpy.Option) (*File, error) {
+	limits := gonpy.Limits(opts...)
+	limits.Limiter.Acquire()
+	f, err := os.Open(path)
+	if err != nil {
+		limits.Limiter.Release()
+		return nil, err
+	}
+	g := &File{f: f, Metadata: make(map[string]interface{}), index: make(map[string]int), limits: limits}
+	if err := g.readHeader(); err != nil {
+		f.Close()
+		limits.Limiter.Release()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// Close closes the file. Tensors already loaded remain valid.
+func (g *File) Close() error {
+	if g.f == nil {
+		return nil
+	}
+	err := g.f.Close()
+	g.f = nil
+	g.limits.Limiter.Release()
+	return err
+}
+
+// readHeader reads everything before the tensor data.
+func (g *File) readHeader() error {
+	info, err := g.f.Stat()
+	if err != nil {
+		return err
+	}
+	cr := &countingReader{r: bufio.NewReader(g.f)}
+	d := &decoder{r: cr}
+
+	if m := d.u32(); d.err == nil && m != magic {
+		return fmt.Errorf("gguf: bad magic 0x%08x: %w", m, gonpy.ErrBadMagic)
+	}
+	g.Version = d.u32()
+	if d.err == nil && (g.Version < 2 || g.Version > 3) {
+		return fmt.Errorf("gguf: version %d: %w", g.Version, gonpy.ErrUnsupportedVersion)
+	}
+	tensorCount := d.count()
+	kvCount := d.count()
+	for i := uint64(0); i < kvCount && d.err == nil; i++ {
+		key := d.str()
+		value := d.value(valueType(d.u32()))
+		if _, dup := g.Metadata[key]; dup && d.err == nil {
+			return fmt.Errorf("gguf: duplicate metadata key %s", key)
+		}
+		g.Metadata[key] = value
+		g.Keys = append(g.Keys, key)
+	}
+	for i := uint64(0); i < tensorCount && d.err == nil; i++ {
+		var t TensorInfo
+		t.Name = d.str()
+		ndim := d.u32()
+		if ndim > maxDims {
+			return fmt.Errorf("gguf: tensor %s has %d dimensions", t.Name, ndim)
+		}
+		t.Shape = make(gonpy.Shape, ndim)
+		for j := int(ndim) - 1; j >= 0; j-- {
+			dim := d.u64()
+			if dim > math.MaxInt32 {
+				return fmt.Errorf("gguf: tensor %s has dimension %d", t.Name, dim)
+			}
+			t.Shape[j] = int(dim)
+		}
+		t.Type = Type(d.u32())
+		t.Offset = int64(d.u64())
+		if d.err != nil {
+			break
+		}
+		if _, dup := g.index[t.Name]; dup {
+			return fmt.Errorf("gguf: duplicate tensor %s", t.Name)
+		}
+		n, err := t.Shape.CheckedElemCount()
+		if err != nil {
+			return err
+		}
+		if t.Size, err = t.Type.dataSize(int64(n)); err != nil {
+			return fmt.Errorf("tensor %s: %w", t.Name, err)
+		}
+		g.index[t.Name] = len(g.Tensors)
+		g.Tensors = append(g.Tensors, t)
+	}
+	if d.err != nil {
+		return fmt.Errorf("gguf: reading header: %w", d.err)
+	}
+
+	g.Alignment = DefaultAlignment
+	if a, ok := g.Metadata[alignmentKey].(uint32); ok {
+		if a == 0 || a&(a-1) != 0 {
+			return fmt.Errorf("gguf: alignment %d is not a power of two", a)
+		}
+		g.Alignment = int(a)
+	}
+	g.dataStart = align(cr.n, int64(g.Alignment))
+	avail := info.Size() - g.dataStart
+	for _, t := range g.Tensors {
+		if t.Offset < 0 || t.Offset%int64(g.Alignment) != 0 || t.Offset > avail || t.Size > avail-t.Offset {
+			return fmt.Errorf("gguf: tensor %s has invalid offset %d", t.Name, t.Offset)
+		}
+	}
+	return nil
+}
+
+// align rounds n up to a multiple of a.
+func align(n, a int64) int64 {
+	return (n + a - 1) / a * a
+}
+
+// Names returns the tensor names in table order.
+func (g *File) Names() []string {
+	names := make([]string, len(g.Tensors))
+	for i, t := range g.Tensors {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Info returns the table entry of a named tensor.
+func (g *File) Info(name string) (TensorInfo, bool) {
+	i, ok := g.index[name]
+	if !ok {
+		return TensorInfo{}, false
+	}
+	return g.Tensors[i], true
+}
+
+// Tensor loads a named tensor, dequantizing it if needed.
+func (g *File) Tensor(name string) (*gonpy.Tensor, error) {
+	info, ok := g.Info(name)
+	if !ok {
+		return nil, fmt.Errorf("gguf: no tensor %s: %w", name, gonpy.ErrEntryNotFound)
+	}
+	if g.f == nil {
+		return nil, fmt.Errorf("gguf: reading tensor %s: %w", name, os.ErrClosed)
+	}
+	dtype, _ := info.Type.DType() // checked when the table was read
+	if err := g.limits.CheckSize(dtype, info.Shape); err != nil {
+		return nil, fmt.Errorf("gguf: tensor %s: %w", name, err)
+	}
+	raw := make([]byte, info.Size)
+	if _, err := g.f.ReadAt(raw, g.dataStart+info.Offset); err != nil {
+		return nil, fmt.Errorf("gguf: reading tensor %s: %w", name, err)
+	}
+	t, err := decodeTensor(info, raw)
+	if err != nil {
+		return nil, fmt.Errorf("tensor %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// Read reads the metadata and all tensors of a GGUF file, in table order.
+func Read(path string, opts ...gonpy.Option) (map[string]interface{}, []gonpy.NamedTensor, error) {
+	g, err := Open(path, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer g.Close()
+
+	tensors := make([]gonpy.NamedTensor, len(g.Tensors))
+	for i, info := range g.Tensors {
+		t, err := g.Tensor(info.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		tensors[i] = gonpy.NamedTensor{Name: info.Name, Tensor: t}
+	}
+	return g.Metadata, tensors, nil
+}
+
+// decodeTensor converts the raw data of a tensor to a gonpy tensor.
+func decodeTensor(info TensorInfo, raw []byte) (*gonpy.Tensor, error) {
+	n := info.Shape.ElemCount()
+	t := &gonpy.Tensor{Shape: info.Shape, Device: "cpu"}
+	var err error
+	switch info.Type {
+	case TypeF32:
+		t.DType, t.Data = gonpy.DTypeF32, make([]float32, n)
+	case TypeF16, TypeBF16:
+		t.DType, t.Data = plainTypes[info.Type], make([]uint16, n)
+	case TypeF64:
+		t.DType, t.Data = gonpy.DTypeF64, make([]float64, n)
+	case TypeI8:
+		t.DType, t.Data = gonpy.DTypeI8, make([]int8, n)
+	case TypeI32:
+		t.DType, t.Data = gonpy.DTypeI32, make([]int32, n)
+	case TypeI64:
+		t.DType, t.Data = gonpy.DTypeI64, make([]int64, n)
+	case TypeI16:
+		data := make([]int32, n)
+		for i := range data {
+			data[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+		}
+		t.DType, t.Data = gonpy.DTypeI32, data
+		return t, nil
+	default:
+		t.DType = gonpy.DTypeF32
+		t.Data, err = dequantize(info.Type, raw, n)
+		return t, err
+	}
+	return t, binary.Read(bytes.NewReader(raw), binary.LittleEndian, t.Data)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
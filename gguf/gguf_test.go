@@ -0,0 +1,164 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	tensors := []gonpy.NamedTensor{
+		{Name: "w", Tensor: &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"}},
+		{Name: "h", Tensor: &gonpy.Tensor{Data: gonpy.F32ToF16([]float32{0.5, -2}), Shape: gonpy.Shape{2}, DType: gonpy.DTypeF16, Device: "cpu"}},
+	}
+	if err := Write(path, map[string]interface{}{"general.name": "test"}, tensors); err != nil {
+		t.Fatal(err)
+	}
+	metadata, got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["general.name"] != "test" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if len(got) != 2 || got[0].Name != "w" || !got[0].Tensor.Shape.Equal(gonpy.Shape{2, 3}) {
+		t.Fatalf("tensors = %v", got)
+	}
+	if w := got[0].Tensor.Data.([]float32); w[5] != 6 {
+		t.Errorf("w = %v", w)
+	}
+	if h := gonpy.F16ToF32(got[1].Tensor.Data.([]uint16)); h[0] != 0.5 || h[1] != -2 {
+		t.Errorf("h = %v", h)
+	}
+}
+
+func TestDequantizeQ8_0(t *testing.T) {
+	block := make([]byte, 34)
+	binary.LittleEndian.PutUint16(block, 0x3800) // d = 0.5
+	for i := 0; i < 32; i++ {
+		block[2+i] = byte(int8(i - 16))
+	}
+	got, err := dequantize(TypeQ8_0, block, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != -8 || got[31] != 7.5 {
+		t.Errorf("got %v", got)
+	}
+}
+
+// header returns a GGUF file declaring one tensor with the given dimensions
+// (fastest-varying first), type, and offset, followed by size bytes of data.
+func header(dims []uint64, typ Type, offset uint64, size int) []byte {
+	var b bytes.Buffer
+	le := func(v interface{}) { binary.Write(&b, binary.LittleEndian, v) }
+	le(uint32(magic))
+	le(uint32(3))
+	le(uint64(1)) // tensors
+	le(uint64(0)) // metadata
+	le(uint64(1))
+	b.WriteString("t")
+	le(uint32(len(dims)))
+	for _, d := range dims {
+		le(d)
+	}
+	le(uint32(typ))
+	le(offset)
+	for b.Len()%DefaultAlignment != 0 {
+		b.WriteByte(0)
+	}
+	b.Write(make([]byte, size))
+	return b.Bytes()
+}
+
+func TestHostileHeaders(t *testing.T) {
+	cases := map[string][]byte{
+		"overflowing size":   header([]uint64{0x7fffffff, 0x7fffffff}, TypeF64, 0, 4),
+		"overflowing offset": header([]uint64{4}, TypeF32, 1<<63-32, 16),
+		"negative offset":    header([]uint64{4}, TypeF32, 1<<63, 16),
+		"truncated data":     header([]uint64{4}, TypeF32, 0, 8),
+	}
+	for name, b := range cases {
+		path := filepath.Join(t.TempDir(), "bad.gguf")
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := Read(path); err == nil {
+			t.Errorf("%s: Read succeeded", name)
+		}
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.gguf")
+	if err := os.WriteFile(path, header([]uint64{1024}, TypeF32, 0, 4096), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Read(path, gonpy.WithMaxBytes(1024)); !errors.Is(err, gonpy.ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+	if _, _, err := Read(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteDeviceTensor(t *testing.T) {
+	x := &gonpy.Tensor{Data: []float32{1, 2, 3}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeF32, Device: "cpu"}
+	dir := t.TempDir()
+	var files [2][]byte
+	for i, in := range []*gonpy.Tensor{x, onDevice(t, x)} {
+		path := filepath.Join(dir, "model.gguf")
+		if err := Write(path, nil, []gonpy.NamedTensor{{Name: "x", Tensor: in}}); err != nil {
+			t.Fatalf("%s tensor: %v", in.Device, err)
+		}
+		var err error
+		if files[i], err = os.ReadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(files[0], files[1]) {
+		t.Error("device tensor written differently from the host tensor")
+	}
+}
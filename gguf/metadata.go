@@ -0,0 +1,310 @@
+package gguf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// valueType is the type tag of a metadata value.
+type valueType uint32
+
+const (
+	valueU8     valueType = 0
+	valueI8     valueType = 1
+	valueU16    valueType = 2
+	valueI16    valueType = 3
+	valueU32    valueType = 4
+	valueI32    valueType = 5
+	valueF32    valueType = 6
+	valueBool   valueType = 7
+	valueString valueType = 8
+	valueArray  valueType = 9
+	valueU64    valueType = 10
+	valueI64    valueType = 11
+	valueF64    valueType = 12
+)
+
+// decoder reads little-endian header fields, remembering the first error so
+// that callers can check once after a run of reads.
+type decoder struct {
+	r   io.Reader
+	err error
+	buf [8]byte
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	if _, err := io.ReadFull(d.r, d.buf[:n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+	return d.buf[:n]
+}
+
+func (d *decoder) u8() uint8   { return d.read(1)[0] }
+func (d *decoder) u16() uint16 { return binary.LittleEndian.Uint16(d.read(2)) }
+func (d *decoder) u32() uint32 { return binary.LittleEndian.Uint32(d.read(4)) }
+func (d *decoder) u64() uint64 { return binary.LittleEndian.Uint64(d.read(8)) }
+
+// count reads a 64-bit element count, rejecting implausible values.
+func (d *decoder) count() uint64 {
+	n := d.u64()
+	if d.err == nil && n > maxCount {
+		d.err = fmt.Errorf("count %d too large", n)
+	}
+	return n
+}
+
+func (d *decoder) str() string {
+	n := d.u64()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxStringLen {
+		d.err = fmt.Errorf("string length %d too large", n)
+		return ""
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.err = io.ErrUnexpectedEOF
+	}
+	return string(b)
+}
+
+// value reads a metadata value of type vt. Scalars become the matching Go
+// type, arrays of scalars become slices such as []uint32 or []string, and
+// arrays of arrays become []interface{}.
+func (d *decoder) value(vt valueType) interface{} {
+	switch vt {
+	case valueU8:
+		return d.u8()
+	case valueI8:
+		return int8(d.u8())
+	case valueU16:
+		return d.u16()
+	case valueI16:
+		return int16(d.u16())
+	case valueU32:
+		return d.u32()
+	case valueI32:
+		return int32(d.u32())
+	case valueF32:
+		return math.Float32frombits(d.u32())
+	case valueBool:
+		return d.u8() != 0
+	case valueString:
+		return d.str()
+	case valueU64:
+		return d.u64()
+	case valueI64:
+		return int64(d.u64())
+	case valueF64:
+		return math.Float64frombits(d.u64())
+	case valueArray:
+		return d.array()
+	}
+	if d.err == nil {
+		d.err = fmt.Errorf("unknown metadata value type %d", vt)
+	}
+	return nil
+}
+
+func (d *decoder) array() interface{} {
+	elem := valueType(d.u32())
+	n := int(d.count())
+	if d.err != nil {
+		return nil
+	}
+	switch elem {
+	case valueU8:
+		return readArray(d, elem, n, func(v interface{}) uint8 { return v.(uint8) })
+	case valueI8:
+		return readArray(d, elem, n, func(v interface{}) int8 { return v.(int8) })
+	case valueU16:
+		return readArray(d, elem, n, func(v interface{}) uint16 { return v.(uint16) })
+	case valueI16:
+		return readArray(d, elem, n, func(v interface{}) int16 { return v.(int16) })
+	case valueU32:
+		return readArray(d, elem, n, func(v interface{}) uint32 { return v.(uint32) })
+	case valueI32:
+		return readArray(d, elem, n, func(v interface{}) int32 { return v.(int32) })
+	case valueF32:
+		return readArray(d, elem, n, func(v interface{}) float32 { return v.(float32) })
+	case valueBool:
+		return readArray(d, elem, n, func(v interface{}) bool { return v.(bool) })
+	case valueString:
+		return readArray(d, elem, n, func(v interface{}) string { return v.(string) })
+	case valueU64:
+		return readArray(d, elem, n, func(v interface{}) uint64 { return v.(uint64) })
+	case valueI64:
+		return readArray(d, elem, n, func(v interface{}) int64 { return v.(int64) })
+	case valueF64:
+		return readArray(d, elem, n, func(v interface{}) float64 { return v.(float64) })
+	case valueArray:
+		return readArray(d, elem, n, func(v interface{}) interface{} { return v })
+	}
+	d.err = fmt.Errorf("unknown metadata array type %d", elem)
+	return nil
+}
+
+// readArray reads n values of type elem into a slice, stopping at the first error.
+func readArray[T any](d *decoder, elem valueType, n int, conv func(interface{}) T) []T {
+	values := make([]T, 0, min(n, 1<<16))
+	for i := 0; i < n; i++ {
+		v := d.value(elem)
+		if d.err != nil {
+			return nil
+		}
+		values = append(values, conv(v))
+	}
+	return values
+}
+
+// encoder writes little-endian header fields, remembering the first error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) write(v interface{}) {
+	if e.err == nil {
+		e.err = binary.Write(e.w, binary.LittleEndian, v)
+	}
+}
+
+func (e *encoder) str(s string) {
+	e.write(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+// valueTypeOf returns the metadata type of a Go value as produced by the decoder.
+func valueTypeOf(v interface{}) (valueType, bool) {
+	switch v.(type) {
+	case uint8:
+		return valueU8, true
+	case int8:
+		return valueI8, true
+	case uint16:
+		return valueU16, true
+	case int16:
+		return valueI16, true
+	case uint32:
+		return valueU32, true
+	case int32:
+		return valueI32, true
+	case float32:
+		return valueF32, true
+	case bool:
+		return valueBool, true
+	case string:
+		return valueString, true
+	case uint64:
+		return valueU64, true
+	case int64:
+		return valueI64, true
+	case float64:
+		return valueF64, true
+	case []uint8, []int8, []uint16, []int16, []uint32, []int32, []float32,
+		[]bool, []string, []uint64, []int64, []float64, []interface{}:
+		return valueArray, true
+	}
+	return 0, false
+}
+
+// value writes the type tag and contents of a metadata value.
+func (e *encoder) value(v interface{}) {
+	vt, ok := valueTypeOf(v)
+	if !ok {
+		if e.err == nil {
+			e.err = fmt.Errorf("unsupported metadata value type %T", v)
+		}
+		return
+	}
+	e.write(uint32(vt))
+	e.contents(v)
+}
+
+// contents writes a metadata value without its type tag.
+func (e *encoder) contents(v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		var b uint8
+		if v {
+			b = 1
+		}
+		e.write(b)
+	case string:
+		e.str(v)
+	case []bool:
+		writeArray(e, valueBool, v)
+	case []string:
+		writeArray(e, valueString, v)
+	case []interface{}:
+		// Nested arrays must share an element type
+		e.write(uint32(valueArray))
+		e.write(uint64(len(v)))
+		for i, item := range v {
+			if vt, _ := valueTypeOf(item); vt != valueArray && e.err == nil {
+				e.err = fmt.Errorf("array element %d is %T, not an array", i, item)
+			}
+			e.contents(item)
+		}
+	case []uint8, []int8, []uint16, []int16, []uint32, []int32, []float32, []uint64, []int64, []float64:
+		// Fixed-size scalar slices are written in one go after their header
+		elem, _ := valueTypeOf(sliceElem(v))
+		e.write(uint32(elem))
+		e.write(uint64(sliceLen(v)))
+		e.write(v)
+	default:
+		e.write(v)
+	}
+}
+
+// writeArray writes an array of values needing per-element encoding.
+func writeArray[T any](e *encoder, elem valueType, values []T) {
+	e.write(uint32(elem))
+	e.write(uint64(len(values)))
+	for _, v := range values {
+		e.contents(v)
+	}
+}
+
+// sliceElem returns the zero value of a scalar slice's element type.
+func sliceElem(v interface{}) interface{} {
+	switch v.(type) {
+	case []uint8:
+		return uint8(0)
+	case []int8:
+		return int8(0)
+	case []uint16:
+		return uint16(0)
+	case []int16:
+		return int16(0)
+	case []uint32:
+		return uint32(0)
+	case []int32:
+		return int32(0)
+	case []float32:
+		return float32(0)
+	case []uint64:
+		return uint64(0)
+	case []int64:
+		return int64(0)
+	case []float64:
+		return float64(0)
+	}
+	return nil
+}
+
+// sliceLen returns the length of a scalar slice.
+func sliceLen(v interface{}) int {
+	return binary.Size(v) / binary.Size(sliceElem(v))
+}
@@ -0,0 +1,244 @@
+package gguf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/gocnn/gonpy"
+)
+
+// blockFormat describes a quantized ggml type: each block of elems values is
+// stored in bytes bytes and decoded by decode into dst.
+type blockFormat struct {
+	elems  int
+	bytes  int
+	decode func(dst []float32, b []byte)
+}
+
+// blockFormats holds the quantized types this package can dequantize, with
+// layouts following ggml's dequantize_row functions.
+var blockFormats = map[Type]blockFormat{
+	TypeQ4_0: {32, 18, decodeQ4_0},
+	TypeQ4_1: {32, 20, decodeQ4_1},
+	TypeQ5_0: {32, 22, decodeQ5_0},
+	TypeQ5_1: {32, 24, decodeQ5_1},
+	TypeQ8_0: {32, 34, decodeQ8_0},
+	TypeQ8_1: {32, 36, decodeQ8_1},
+	TypeQ2_K: {256, 84, decodeQ2_K},
+	TypeQ3_K: {256, 110, decodeQ3_K},
+	TypeQ4_K: {256, 144, decodeQ4_K},
+	TypeQ5_K: {256, 176, decodeQ5_K},
+	TypeQ6_K: {256, 210, decodeQ6_K},
+	TypeQ8_K: {256, 292, decodeQ8_K},
+}
+
+// dequantize decodes n values of quantized type t from raw.
+func dequantize(t Type, raw []byte, n int) ([]float32, error) {
+	f, ok := blockFormats[t]
+	if !ok {
+		return nil, fmt.Errorf("gguf: cannot dequantize %s: %w", t, gonpy.ErrUnsupportedDType)
+	}
+	dst := make([]float32, n)
+	for i := 0; i < n/f.elems; i++ {
+		f.decode(dst[i*f.elems:(i+1)*f.elems], raw[i*f.bytes:(i+1)*f.bytes])
+	}
+	return dst, nil
+}
+
+// half decodes the little-endian IEEE half-precision value at the start of b.
+func half(b []byte) float32 {
+	return gonpy.F16BitsToF32(binary.LittleEndian.Uint16(b))
+}
+
+func decodeQ4_0(dst []float32, b []byte) {
+	d := half(b)
+	qs := b[2:]
+	for j := 0; j < 16; j++ {
+		dst[j] = float32(int(qs[j]&0xf)-8) * d
+		dst[j+16] = float32(int(qs[j]>>4)-8) * d
+	}
+}
+
+func decodeQ4_1(dst []float32, b []byte) {
+	d, m := half(b), half(b[2:])
+	qs := b[4:]
+	for j := 0; j < 16; j++ {
+		dst[j] = float32(qs[j]&0xf)*d + m
+		dst[j+16] = float32(qs[j]>>4)*d + m
+	}
+}
+
+func decodeQ5_0(dst []float32, b []byte) {
+	d := half(b)
+	qh := binary.LittleEndian.Uint32(b[2:])
+	qs := b[6:]
+	for j := 0; j < 16; j++ {
+		x0 := int(qs[j]&0xf) | int((qh>>j)<<4)&0x10
+		x1 := int(qs[j]>>4) | int(qh>>(j+12))&0x10
+		dst[j] = float32(x0-16) * d
+		dst[j+16] = float32(x1-16) * d
+	}
+}
+
+func decodeQ5_1(dst []float32, b []byte) {
+	d, m := half(b), half(b[2:])
+	qh := binary.LittleEndian.Uint32(b[4:])
+	qs := b[8:]
+	for j := 0; j < 16; j++ {
+		x0 := int(qs[j]&0xf) | int((qh>>j)<<4)&0x10
+		x1 := int(qs[j]>>4) | int(qh>>(j+12))&0x10
+		dst[j] = float32(x0)*d + m
+		dst[j+16] = float32(x1)*d + m
+	}
+}
+
+func decodeQ8_0(dst []float32, b []byte) {
+	d := half(b)
+	for j, q := range b[2:34] {
+		dst[j] = float32(int8(q)) * d
+	}
+}
+
+func decodeQ8_1(dst []float32, b []byte) {
+	d := half(b) // b[2:4] holds the block sum, which is not needed
+	for j, q := range b[4:36] {
+		dst[j] = float32(int8(q)) * d
+	}
+}
+
+func decodeQ2_K(dst []float32, b []byte) {
+	scales, q := b[:16], b[16:80]
+	d, dmin := half(b[80:]), half(b[82:])
+	is, y := 0, 0
+	for n := 0; n < 256; n += 128 {
+		for shift := 0; shift < 8; shift += 2 {
+			for h := 0; h < 2; h++ {
+				sc := scales[is]
+				is++
+				dl, ml := d*float32(sc&0xf), dmin*float32(sc>>4)
+				for l := 0; l < 16; l++ {
+					dst[y] = dl*float32((q[l+16*h]>>shift)&3) - ml
+					y++
+				}
+			}
+		}
+		q = q[32:]
+	}
+}
+
+func decodeQ3_K(dst []float32, b []byte) {
+	hm, q := b[:32], b[32:96]
+	d := half(b[108:])
+
+	// Unpack the twelve bytes of 6-bit scales
+	const kmask1, kmask2 = 0x03030303, 0x0f0f0f0f
+	var aux [4]uint32
+	for i := 0; i < 3; i++ {
+		aux[i] = binary.LittleEndian.Uint32(b[96+4*i:])
+	}
+	tmp := aux[2]
+	aux[2] = (aux[0]>>4)&kmask2 | ((tmp>>4)&kmask1)<<4
+	aux[3] = (aux[1]>>4)&kmask2 | ((tmp>>6)&kmask1)<<4
+	aux[0] = aux[0]&kmask2 | (tmp&kmask1)<<4
+	aux[1] = aux[1]&kmask2 | ((tmp>>2)&kmask1)<<4
+	var scales [16]int8
+	for i := range scales {
+		scales[i] = int8(aux[i/4] >> (8 * (i % 4)))
+	}
+
+	is, y := 0, 0
+	m := uint8(1)
+	for n := 0; n < 256; n += 128 {
+		for shift := 0; shift < 8; shift += 2 {
+			for h := 0; h < 2; h++ {
+				dl := d * float32(int(scales[is])-32)
+				is++
+				for l := 16 * h; l < 16*h+16; l++ {
+					v := int((q[l] >> shift) & 3)
+					if hm[l]&m == 0 {
+						v -= 4
+					}
+					dst[y] = dl * float32(v)
+					y++
+				}
+			}
+			m <<= 1
+		}
+		q = q[32:]
+	}
+}
+
+// scaleMinK4 extracts the j-th 6-bit scale and minimum of a Q4_K or Q5_K block.
+func scaleMinK4(j int, q []byte) (float32, float32) {
+	if j < 4 {
+		return float32(q[j] & 63), float32(q[j+4] & 63)
+	}
+	sc := q[j+4]&0xf | (q[j-4]>>6)<<4
+	m := q[j+4]>>4 | (q[j]>>6)<<4
+	return float32(sc), float32(m)
+}
+
+func decodeQ4_K(dst []float32, b []byte) {
+	d, dmin := half(b), half(b[2:])
+	scales, q := b[4:16], b[16:144]
+	for j, is := 0, 0; j < 256; j, is = j+64, is+2 {
+		sc1, m1 := scaleMinK4(is, scales)
+		sc2, m2 := scaleMinK4(is+1, scales)
+		for l := 0; l < 32; l++ {
+			dst[j+l] = d*sc1*float32(q[l]&0xf) - dmin*m1
+			dst[j+32+l] = d*sc2*float32(q[l]>>4) - dmin*m2
+		}
+		q = q[32:]
+	}
+}
+
+func decodeQ5_K(dst []float32, b []byte) {
+	d, dmin := half(b), half(b[2:])
+	scales, qh, ql := b[4:16], b[16:48], b[48:176]
+	u1, u2 := uint8(1), uint8(2)
+	for j, is := 0, 0; j < 256; j, is = j+64, is+2 {
+		sc1, m1 := scaleMinK4(is, scales)
+		sc2, m2 := scaleMinK4(is+1, scales)
+		for l := 0; l < 32; l++ {
+			lo, hi := float32(ql[l]&0xf), float32(ql[l]>>4)
+			if qh[l]&u1 != 0 {
+				lo += 16
+			}
+			if qh[l]&u2 != 0 {
+				hi += 16
+			}
+			dst[j+l] = d*sc1*lo - dmin*m1
+			dst[j+32+l] = d*sc2*hi - dmin*m2
+		}
+		ql = ql[32:]
+		u1 <<= 2
+		u2 <<= 2
+	}
+}
+
+func decodeQ6_K(dst []float32, b []byte) {
+	ql, qh, sc := b[:128], b[128:192], b[192:208]
+	d := half(b[208:])
+	for n := 0; n < 256; n += 128 {
+		for l := 0; l < 32; l++ {
+			is := l / 16
+			q1 := int(ql[l]&0xf|(qh[l]&3)<<4) - 32
+			q2 := int(ql[l+32]&0xf|((qh[l]>>2)&3)<<4) - 32
+			q3 := int(ql[l]>>4|((qh[l]>>4)&3)<<4) - 32
+			q4 := int(ql[l+32]>>4|((qh[l]>>6)&3)<<4) - 32
+			dst[n+l] = d * float32(int8(sc[is])) * float32(q1)
+			dst[n+l+32] = d * float32(int8(sc[is+2])) * float32(q2)
+			dst[n+l+64] = d * float32(int8(sc[is+4])) * float32(q3)
+			dst[n+l+96] = d * float32(int8(sc[is+6])) * float32(q4)
+		}
+		ql, qh, sc = ql[64:], qh[32:], sc[8:]
+	}
+}
+
+func decodeQ8_K(dst []float32, b []byte) {
+	d := math.Float32frombits(binary.LittleEndian.Uint32(b))
+	for j, q := range b[4:260] {
+		dst[j] = float32(int8(q)) * d
+	}
+}
@@ -0,0 +1,142 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/gocnn/gonpy"
+)
+
+// writeTypes maps the dtypes Write accepts to ggml types.
+var writeTypes = map[gonpy.DType]Type{
+	gonpy.DTypeF32:  TypeF32,
+	gonpy.DTypeF16:  TypeF16,
+	gonpy.DTypeBF16: TypeBF16,
+	gonpy.DTypeF64:  TypeF64,
+	gonpy.DTypeI8:   TypeI8,
+	gonpy.DTypeI32:  TypeI32,
+	gonpy.DTypeI64:  TypeI64,
+}
+
+// Write writes metadata and tensors to a GGUF version 3 file, with keys in
+// sorted order and tensors in the order given. Metadata values must have the
+// Go types File.Metadata holds, such as uint32, string, or []string. Tensors
+// are stored unquantized, so only F32, F16, BF16, F64, I8, I32, and I64
+// tensors are accepted. Data is aligned to the general.alignment key if it is
+// set, and to DefaultAlignment otherwise.
+func Write(path string, metadata map[string]interface{}, tensors []gonpy.NamedTensor) error {
+	alignment := int64(DefaultAlignment)
+	if v, ok := metadata[alignmentKey]; ok {
+		a, ok := v.(uint32)
+		if !ok || a == 0 || a&(a-1) != 0 {
+			return fmt.Errorf("gguf: %s must be a uint32 power of two, not %v", alignmentKey, v)
+		}
+		alignment = int64(a)
+	}
+
+	infos := make([]TensorInfo, len(tensors))
+	var offset int64
+	hosted := make([]gonpy.NamedTensor, len(tensors))
+	for i, nt := range tensors {
+		t, err := nt.Tensor.ToHost()
+		if err != nil {
+			return err
+		}
+		hosted[i] = gonpy.NamedTensor{Name: nt.Name, Tensor: t}
+		typ, ok := writeTypes[t.DType]
+		if !ok {
+			return fmt.Errorf("gguf: no ggml type for %s tensor %s: %w", t.DType, nt.Name, gonpy.ErrUnsupportedDType)
+		}
+		if len(t.Shape) > maxDims {
+			return fmt.Errorf("gguf: tensor %s has %d dimensions, at most %d are allowed", nt.Name, len(t.Shape), maxDims)
+		}
+		size, err := typ.dataSize(int64(t.Shape.ElemCount()))
+		if err != nil {
+			return err
+		}
+		if int64(binary.Size(t.Data)) != size {
+			return fmt.Errorf("gguf: tensor %s has %d bytes of data, shape %v needs %d", nt.Name, binary.Size(t.Data), t.Shape, size)
+		}
+		infos[i] = TensorInfo{Name: nt.Name, Shape: t.Shape, Type: typ, Offset: offset, Size: size}
+		offset = align(offset+size, alignment)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f, metadata, infos, hosted, alignment)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// write writes the header, tensor table, and data of a GGUF file to w.
+func write(w io.Writer, metadata map[string]interface{}, infos []TensorInfo, tensors []gonpy.NamedTensor, alignment int64) error {
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	e := &encoder{w: cw}
+	e.write(uint32(magic))
+	e.write(uint32(3))
+	e.write(uint64(len(infos)))
+	e.write(uint64(len(metadata)))
+
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e.str(k)
+		e.value(metadata[k])
+		if e.err != nil {
+			return fmt.Errorf("gguf: metadata %s: %w", k, e.err)
+		}
+	}
+
+	for _, info := range infos {
+		e.str(info.Name)
+		e.write(uint32(len(info.Shape)))
+		for j := len(info.Shape) - 1; j >= 0; j-- {
+			e.write(uint64(info.Shape[j]))
+		}
+		e.write(uint32(info.Type))
+		e.write(uint64(info.Offset))
+	}
+
+	dataStart := align(cw.n, alignment)
+	for i, nt := range tensors {
+		e.pad(dataStart + infos[i].Offset - cw.n)
+		e.write(nt.Tensor.Data)
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return cw.w.(*bufio.Writer).Flush()
+}
+
+// pad writes n zero bytes.
+func (e *encoder) pad(n int64) {
+	if e.err == nil && n > 0 {
+		_, e.err = e.w.Write(make([]byte, n))
+	}
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,21 @@
+module github.com/gocnn/gonpy/gomlx
+
+go 1.25
+
+require (
+	github.com/gocnn/gonpy v0.0.0-00010101000000-000000000000
+	github.com/gomlx/gomlx v0.19.0
+	github.com/gomlx/gopjrt v0.7.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/gomlx/exceptions v0.0.3 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	k8s.io/klog/v2 v2.130.1 // indirect
+)
+
+replace github.com/gocnn/gonpy => ../
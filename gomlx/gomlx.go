@@ -0,0 +1,183 @@
+// Package gomlx converts between gonpy tensors and the tensors of gomlx, so
+// that NPZ datasets can be fed to gomlx models and their outputs saved for
+// numpy.
+//
+// gonpy itself does not depend on gomlx, so this package is a module of its
+// own, github.com/gocnn/gonpy/gomlx, which requires github.com/gomlx/gomlx.
+// It uses the types/shapes and types/tensors packages, which later gomlx
+// releases have moved.
+//
+// Every gonpy dtype has a gomlx counterpart except float8, which is converted
+// to Float32, and packed int4 and uint4, which become Int8 and Uint8. In the
+// other direction, Int16 and Uint16 tensors are widened to I32 and U32.
+// String, structured, and object tensors are not supported. Data is always
+// copied, since gomlx owns the memory of its tensors.
+package gomlx
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+	"github.com/gomlx/gomlx/types/shapes"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// toGoMLX maps gonpy dtypes to the gomlx dtypes with the same memory layout.
+var toGoMLX = map[gonpy.DType]dtypes.DType{
+	gonpy.DTypeBool: dtypes.Bool,
+	gonpy.DTypeI8:   dtypes.Int8,
+	gonpy.DTypeI32:  dtypes.Int32,
+	gonpy.DTypeI64:  dtypes.Int64,
+	gonpy.DTypeU8:   dtypes.Uint8,
+	gonpy.DTypeU32:  dtypes.Uint32,
+	gonpy.DTypeU64:  dtypes.Uint64,
+	gonpy.DTypeF16:  dtypes.Float16,
+	gonpy.DTypeBF16: dtypes.BFloat16,
+	gonpy.DTypeF32:  dtypes.Float32,
+	gonpy.DTypeF64:  dtypes.Float64,
+	gonpy.DTypeC64:  dtypes.Complex64,
+	gonpy.DTypeC128: dtypes.Complex128,
+}
+
+// ToTensor returns a copy of t as a gomlx tensor. Tensors on a gonpy device
+// are copied through the host.
+func ToTensor(t *gonpy.Tensor) (*tensors.Tensor, error) {
+	result, err := toTensor(t)
+	if err != nil {
+		return nil, fmt.Errorf("gomlx: %w", err)
+	}
+	return result, nil
+}
+
+func toTensor(t *gonpy.Tensor) (*tensors.Tensor, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	switch t.DType {
+	case gonpy.DTypeF8E4M3, gonpy.DTypeF8E5M2:
+		t, err = t.Cast(gonpy.DTypeF32)
+	case gonpy.DTypeI4:
+		t, err = t.Cast(gonpy.DTypeI8)
+	case gonpy.DTypeU4:
+		t, err = t.Cast(gonpy.DTypeU8)
+	}
+	if err != nil {
+		return nil, err
+	}
+	dtype, ok := toGoMLX[t.DType]
+	if !ok {
+		return nil, fmt.Errorf("%s tensor: %w", t.DType, gonpy.ErrUnsupportedDType)
+	}
+	src := flatBytes(t.Data)
+	result := tensors.FromShape(shapes.Make(dtype, t.Shape...))
+	result.MutableFlatData(func(flat any) {
+		dst := flatBytes(flat)
+		if len(dst) != len(src) {
+			err = fmt.Errorf("%s tensor of shape %v holds %d bytes, expected %d", t.DType, t.Shape, len(src), len(dst))
+			return
+		}
+		copy(dst, src)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FromTensor returns a copy of a gomlx tensor as a gonpy tensor.
+func FromTensor(t *tensors.Tensor) (*gonpy.Tensor, error) {
+	result, err := fromTensor(t)
+	if err != nil {
+		return nil, fmt.Errorf("gomlx: %w", err)
+	}
+	return result, nil
+}
+
+func fromTensor(t *tensors.Tensor) (*gonpy.Tensor, error) {
+	shape := t.Shape()
+	dtype, ok := fromGoMLX(shape.DType)
+	if !ok {
+		return nil, fmt.Errorf("%s tensor: %w", shape.DType, gonpy.ErrUnsupportedDType)
+	}
+	result, err := gonpy.Zeros(dtype, gonpy.Shape(append([]int{}, shape.Dimensions...)))
+	if err != nil {
+		return nil, err
+	}
+	t.ConstFlatData(func(flat any) {
+		switch src := flat.(type) {
+		case []int16:
+			dst := result.Data.([]int32)
+			for i, v := range src {
+				dst[i] = int32(v)
+			}
+		case []uint16:
+			dst := result.Data.([]uint32)
+			for i, v := range src {
+				dst[i] = uint32(v)
+			}
+		default:
+			copy(flatBytes(result.Data), flatBytes(flat))
+		}
+	})
+	return result, nil
+}
+
+// fromGoMLX returns the gonpy dtype holding values of a gomlx dtype.
+func fromGoMLX(dtype dtypes.DType) (gonpy.DType, bool) {
+	switch dtype {
+	case dtypes.Int16:
+		return gonpy.DTypeI32, true
+	case dtypes.Uint16:
+		return gonpy.DTypeU32, true
+	}
+	for d, g := range toGoMLX {
+		if g == dtype {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+// flatBytes views the memory of a slice of fixed-size values as bytes.
+func flatBytes(flat any) []byte {
+	v := reflect.ValueOf(flat)
+	if v.Len() == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(v.UnsafePointer()), v.Len()*int(v.Type().Elem().Size()))
+}
+
+// ReadNPZ reads the tensors of an NPZ file as gomlx tensors, keyed by name.
+func ReadNPZ(path string, opts ...gonpy.Option) (map[string]*tensors.Tensor, error) {
+	entries, err := gonpy.ReadNPZ(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*tensors.Tensor, len(entries))
+	for _, nt := range entries {
+		t, err := toTensor(nt.Tensor)
+		if err != nil {
+			return nil, fmt.Errorf("gomlx: %s: %w", nt.Name, err)
+		}
+		result[nt.Name] = t
+	}
+	return result, nil
+}
+
+// WriteNPZ writes gomlx tensors, such as the outputs of a model, to an NPZ
+// file.
+func WriteNPZ(path string, ts map[string]*tensors.Tensor, opts ...gonpy.Option) error {
+	out := make(map[string]*gonpy.Tensor, len(ts))
+	for name, t := range ts {
+		g, err := fromTensor(t)
+		if err != nil {
+			return fmt.Errorf("gomlx: %s: %w", name, err)
+		}
+		out[name] = g
+	}
+	return gonpy.WriteNPZ(path, out, opts...)
+}
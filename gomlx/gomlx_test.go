@@ -0,0 +1,70 @@
+package gomlx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+	"github.com/gomlx/gomlx/types/tensors"
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+func TestTensorRoundTrip(t *testing.T) {
+	tests := []*gonpy.Tensor{
+		{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"},
+		{Data: []int64{-5}, Shape: gonpy.Shape{}, DType: gonpy.DTypeI64, Device: "cpu"},
+		{Data: []uint16{0x3f80, 0xc000}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeBF16, Device: "cpu"},
+		{Data: []bool{true, false}, Shape: gonpy.Shape{1, 2}, DType: gonpy.DTypeBool, Device: "cpu"},
+		{Data: []complex64{1 - 2i}, Shape: gonpy.Shape{1}, DType: gonpy.DTypeC64, Device: "cpu"},
+	}
+	for _, in := range tests {
+		g, err := ToTensor(in)
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		out, err := FromTensor(g)
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		if out.DType != in.DType || !out.Shape.Equal(in.Shape) || fmt.Sprint(out.Data) != fmt.Sprint(in.Data) {
+			t.Errorf("%s %v read back as %s %v %v", in.DType, in.Shape, out.DType, out.Shape, out.Data)
+		}
+	}
+}
+
+func TestToTensorConverts(t *testing.T) {
+	packed, err := gonpy.PackInt4([]int8{-8, 7, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := &gonpy.Tensor{Data: packed, Shape: gonpy.Shape{3}, DType: gonpy.DTypeI4, Device: "cpu"}
+	g, err := ToTensor(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dtype := g.Shape().DType; dtype != dtypes.Int8 || fmt.Sprint(g.Value()) != "[-8 7 1]" {
+		t.Errorf("int4 tensor became %s %v", dtype, g.Value())
+	}
+}
+
+func TestFromTensorWidens(t *testing.T) {
+	out, err := FromTensor(tensors.FromFlatDataAndDimensions([]int16{-1, 300}, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.DType != gonpy.DTypeI32 || fmt.Sprint(out.Data) != "[-1 300]" {
+		t.Errorf("int16 tensor read as %s %v", out.DType, out.Data)
+	}
+}
+
+func TestToTensorInvalid(t *testing.T) {
+	s := &gonpy.Tensor{Data: []string{"a"}, Shape: gonpy.Shape{1}, DType: gonpy.UnicodeDType(1), Device: "cpu"}
+	if _, err := ToTensor(s); !errors.Is(err, gonpy.ErrUnsupportedDType) {
+		t.Errorf("string tensor: got %v, want ErrUnsupportedDType", err)
+	}
+	short := &gonpy.Tensor{Data: []float64{1}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeF64, Device: "cpu"}
+	if _, err := ToTensor(short); err == nil {
+		t.Error("tensor with short data converted")
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/gocnn/gonpy/gonum
+
+go 1.25
+
+require (
+	github.com/gocnn/gonpy v0.0.0-00010101000000-000000000000
+	gonum.org/v1/gonum v0.17.0
+)
+
+replace github.com/gocnn/gonpy => ../
@@ -0,0 +1,80 @@
+// Package gonum converts between gonpy tensors and gonum matrices and vectors,
+// so that tensors read from NPY or NPZ files can be used with gonum's linear
+// algebra.
+//
+// gonpy itself does not depend on gonum, so this package is a module of its
+// own, github.com/gocnn/gonpy/gonum, which requires gonum.org/v1/gonum.
+//
+// F64 data is shared with gonum rather than copied wherever the layouts
+// allow, so writes through either value are seen by the other.
+package gonum
+
+import (
+	"fmt"
+
+	"github.com/gocnn/gonpy"
+	"gonum.org/v1/gonum/mat"
+)
+
+// ToDense returns a 2-D tensor as a matrix. The data of an F64 tensor backs
+// the matrix; F32 and integer tensors whose values are exact as float64 are
+// converted. Gonum has no empty matrices, so both dimensions must be
+// positive.
+func ToDense(t *gonpy.Tensor) (*mat.Dense, error) {
+	if len(t.Shape) != 2 || t.Shape[0] == 0 || t.Shape[1] == 0 {
+		return nil, fmt.Errorf("gonum: cannot convert tensor of shape %v to a matrix", t.Shape)
+	}
+	data, err := gonpy.DataAs[float64](t)
+	if err != nil {
+		return nil, fmt.Errorf("gonum: %w", err)
+	}
+	return mat.NewDense(t.Shape[0], t.Shape[1], data), nil
+}
+
+// ToVecDense returns a non-empty 1-D tensor as a vector, sharing or converting
+// its data as in ToDense.
+func ToVecDense(t *gonpy.Tensor) (*mat.VecDense, error) {
+	if len(t.Shape) != 1 || t.Shape[0] == 0 {
+		return nil, fmt.Errorf("gonum: cannot convert tensor of shape %v to a vector", t.Shape)
+	}
+	data, err := gonpy.DataAs[float64](t)
+	if err != nil {
+		return nil, fmt.Errorf("gonum: %w", err)
+	}
+	return mat.NewVecDense(t.Shape[0], data), nil
+}
+
+// FromDense returns a matrix as a 2-D F64 tensor. The tensor shares the data
+// of a *mat.Dense whose rows are stored without gaps, as for any matrix made
+// by mat.NewDense; other matrices, such as slices and transposes, are copied.
+func FromDense(m mat.Matrix) *gonpy.Tensor {
+	rows, cols := m.Dims()
+	var data []float64
+	if d, ok := m.(*mat.Dense); ok && d.RawMatrix().Stride == cols {
+		data = d.RawMatrix().Data[:rows*cols]
+	} else {
+		data = make([]float64, rows*cols)
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				data[i*cols+j] = m.At(i, j)
+			}
+		}
+	}
+	return &gonpy.Tensor{Data: data, Shape: gonpy.Shape{rows, cols}, DType: gonpy.DTypeF64, Device: "cpu"}
+}
+
+// FromVecDense returns a vector as a 1-D F64 tensor, sharing the data of a
+// *mat.VecDense whose elements are adjacent and copying any other vector.
+func FromVecDense(v mat.Vector) *gonpy.Tensor {
+	n := v.Len()
+	var data []float64
+	if d, ok := v.(*mat.VecDense); ok && (d.RawVector().Inc == 1 || n <= 1) {
+		data = d.RawVector().Data[:n]
+	} else {
+		data = make([]float64, n)
+		for i := range data {
+			data[i] = v.AtVec(i)
+		}
+	}
+	return &gonpy.Tensor{Data: data, Shape: gonpy.Shape{n}, DType: gonpy.DTypeF64, Device: "cpu"}
+}
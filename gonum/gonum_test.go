@@ -0,0 +1,121 @@
+package gonum
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestDenseRoundTrip(t *testing.T) {
+	in := &gonpy.Tensor{Data: []float64{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF64, Device: "cpu"}
+	m, err := ToDense(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, c := m.Dims(); r != 2 || c != 3 || m.At(0, 2) != 3 || m.At(1, 0) != 4 {
+		t.Errorf("matrix %dx%d is not row-major: %v", r, c, mat.Formatted(m))
+	}
+	m.Set(1, 2, 60) // The matrix shares the tensor's data
+	if d := in.Data.([]float64); d[5] != 60 {
+		t.Errorf("tensor data %v not shared", d)
+	}
+	out := FromDense(m)
+	if !out.Shape.Equal(in.Shape) || fmt.Sprint(out.Data) != "[1 2 3 4 5 60]" {
+		t.Errorf("FromDense = %v %v", out.Shape, out.Data)
+	}
+
+	// A transpose is copied in row-major order
+	tr := FromDense(m.T())
+	if !tr.Shape.Equal(gonpy.Shape{3, 2}) || fmt.Sprint(tr.Data) != "[1 4 2 5 3 60]" {
+		t.Errorf("FromDense of transpose = %v %v", tr.Shape, tr.Data)
+	}
+}
+
+func TestToDenseConverts(t *testing.T) {
+	in := &gonpy.Tensor{Data: []int32{-1, 2}, Shape: gonpy.Shape{1, 2}, DType: gonpy.DTypeI32, Device: "cpu"}
+	m, err := ToDense(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.At(0, 0) != -1 || m.At(0, 1) != 2 {
+		t.Errorf("matrix %v", mat.Formatted(m))
+	}
+}
+
+func TestToDenseInvalid(t *testing.T) {
+	tests := map[string]*gonpy.Tensor{
+		"vector":  {Data: []float64{1, 2}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeF64, Device: "cpu"},
+		"empty":   {Data: []float64{}, Shape: gonpy.Shape{0, 2}, DType: gonpy.DTypeF64, Device: "cpu"},
+		"int64":   {Data: []int64{1 << 60}, Shape: gonpy.Shape{1, 1}, DType: gonpy.DTypeI64, Device: "cpu"},
+		"complex": {Data: []complex128{1}, Shape: gonpy.Shape{1, 1}, DType: gonpy.DTypeC128, Device: "cpu"},
+	}
+	for name, in := range tests {
+		if _, err := ToDense(in); err == nil {
+			t.Errorf("%s: converted without error", name)
+		}
+	}
+}
+
+func TestVecDenseRoundTrip(t *testing.T) {
+	in := &gonpy.Tensor{Data: []float32{1.5, -2}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeF32, Device: "cpu"}
+	v, err := ToVecDense(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := FromVecDense(v)
+	if out.DType != gonpy.DTypeF64 || fmt.Sprint(out.Data) != "[1.5 -2]" {
+		t.Errorf("FromVecDense = %s %v", out.DType, out.Data)
+	}
+	if _, err := ToVecDense(&gonpy.Tensor{Data: []float64{1}, Shape: gonpy.Shape{1, 1}, DType: gonpy.DTypeF64, Device: "cpu"}); err == nil {
+		t.Error("matrix converted to a vector")
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestToDenseDevice(t *testing.T) {
+	x := &gonpy.Tensor{Data: []float64{1, 2, 3, 4}, Shape: gonpy.Shape{2, 2}, DType: gonpy.DTypeF64, Device: "cpu"}
+	m, err := ToDense(onDevice(t, x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.At(1, 0) != 3 {
+		t.Errorf("matrix %v", mat.Formatted(m))
+	}
+}
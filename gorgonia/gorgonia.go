@@ -0,0 +1,158 @@
+// Package gorgonia converts between gonpy tensors and the Dense tensors of
+// gorgonia.org/tensor, so that weights saved by numpy or PyTorch can be
+// loaded into a gorgonia model.
+//
+// gonpy itself does not depend on gorgonia, so this package is a module of
+// its own, github.com/gocnn/gonpy/gorgonia, which requires
+// gorgonia.org/tensor.
+//
+// Data is shared rather than copied wherever the element types match, so
+// writes through either tensor are seen by the other; Clone the result for an
+// independent copy. F16, BF16, and float8 tensors become Float32 tensors and
+// packed int4 and uint4 tensors become Int8 and Uint8 tensors, since gorgonia
+// has no such types. String, structured, and object tensors are not supported.
+package gorgonia
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gocnn/gonpy"
+	"gorgonia.org/tensor"
+)
+
+// ToDense returns t as a gorgonia tensor of the same shape.
+func ToDense(t *gonpy.Tensor) (*tensor.Dense, error) {
+	d, err := toDense(t)
+	if err != nil {
+		return nil, fmt.Errorf("gorgonia: %w", err)
+	}
+	return d, nil
+}
+
+func toDense(t *gonpy.Tensor) (*tensor.Dense, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	switch t.DType {
+	case gonpy.DTypeF16, gonpy.DTypeBF16, gonpy.DTypeF8E4M3, gonpy.DTypeF8E5M2:
+		t, err = t.Cast(gonpy.DTypeF32)
+	case gonpy.DTypeI4:
+		t, err = t.Cast(gonpy.DTypeI8)
+	case gonpy.DTypeU4:
+		t, err = t.Cast(gonpy.DTypeU8)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch t.Data.(type) {
+	case []float32, []float64, []complex64, []complex128,
+		[]int8, []int32, []int64, []uint8, []uint32, []uint64, []bool:
+	default:
+		return nil, fmt.Errorf("%s tensor: %w", t.DType, gonpy.ErrUnsupportedDType)
+	}
+	data := reflect.ValueOf(t.Data)
+	if n := t.Shape.ElemCount(); data.Len() != n {
+		return nil, fmt.Errorf("tensor of shape %v has %d elements", t.Shape, data.Len())
+	}
+	if len(t.Shape) == 0 {
+		return tensor.New(tensor.FromScalar(data.Index(0).Interface())), nil
+	}
+	return tensor.New(tensor.WithShape(t.Shape...), tensor.WithBacking(t.Data)), nil
+}
+
+// FromDense returns a gorgonia tensor as a gonpy tensor. Int and Uint tensors
+// become I64 and U64 tensors, and Int16 and Uint16 tensors are widened to I32
+// and U32, which copies their data. Views are materialized first; tensors in
+// column-major order are not supported.
+func FromDense(d *tensor.Dense) (*gonpy.Tensor, error) {
+	if d.IsMaterializable() {
+		d = d.Materialize().(*tensor.Dense)
+	}
+	if d.DataOrder().IsColMajor() {
+		return nil, fmt.Errorf("gorgonia: column-major tensors are not supported")
+	}
+	shape := gonpy.Shape(append([]int{}, d.Shape()...))
+	data := d.Data()
+	if d.IsScalar() {
+		shape = gonpy.Shape{}
+		v := reflect.ValueOf(data)
+		s := reflect.MakeSlice(reflect.SliceOf(v.Type()), 1, 1)
+		s.Index(0).Set(v)
+		data = s.Interface()
+	}
+
+	var dtype gonpy.DType
+	switch v := data.(type) {
+	case []float32:
+		dtype = gonpy.DTypeF32
+	case []float64:
+		dtype = gonpy.DTypeF64
+	case []complex64:
+		dtype = gonpy.DTypeC64
+	case []complex128:
+		dtype = gonpy.DTypeC128
+	case []int8:
+		dtype = gonpy.DTypeI8
+	case []int32:
+		dtype = gonpy.DTypeI32
+	case []int64:
+		dtype = gonpy.DTypeI64
+	case []uint8:
+		dtype = gonpy.DTypeU8
+	case []uint32:
+		dtype = gonpy.DTypeU32
+	case []uint64:
+		dtype = gonpy.DTypeU64
+	case []bool:
+		dtype = gonpy.DTypeBool
+	case []int:
+		dtype, data = gonpy.DTypeI64, widen[int, int64](v)
+	case []uint:
+		dtype, data = gonpy.DTypeU64, widen[uint, uint64](v)
+	case []int16:
+		dtype, data = gonpy.DTypeI32, widen[int16, int32](v)
+	case []uint16:
+		dtype, data = gonpy.DTypeU32, widen[uint16, uint32](v)
+	default:
+		return nil, fmt.Errorf("gorgonia: %v tensor: %w", d.Dtype(), gonpy.ErrUnsupportedDType)
+	}
+	return &gonpy.Tensor{Data: data, Shape: shape, DType: dtype, Device: "cpu"}, nil
+}
+
+// widen converts integers to a type at least as wide.
+func widen[S, D int | uint | int16 | uint16 | int32 | uint32 | int64 | uint64](src []S) []D {
+	dst := make([]D, len(src))
+	for i, v := range src {
+		dst[i] = D(v)
+	}
+	return dst
+}
+
+// ReadNPY reads an NPY file as a gorgonia tensor.
+func ReadNPY(path string, opts ...gonpy.Option) (*tensor.Dense, error) {
+	t, err := gonpy.ReadNPY(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return ToDense(t)
+}
+
+// ReadNPZ reads the tensors of an NPZ file as gorgonia tensors, keyed by
+// name.
+func ReadNPZ(path string, opts ...gonpy.Option) (map[string]*tensor.Dense, error) {
+	tensors, err := gonpy.ReadNPZ(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*tensor.Dense, len(tensors))
+	for _, nt := range tensors {
+		d, err := toDense(nt.Tensor)
+		if err != nil {
+			return nil, fmt.Errorf("gorgonia: %s: %w", nt.Name, err)
+		}
+		result[nt.Name] = d
+	}
+	return result, nil
+}
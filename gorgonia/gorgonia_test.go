@@ -0,0 +1,115 @@
+package gorgonia
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+	"gorgonia.org/tensor"
+)
+
+func TestDenseRoundTrip(t *testing.T) {
+	tests := []*gonpy.Tensor{
+		{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"},
+		{Data: []int64{-5}, Shape: gonpy.Shape{}, DType: gonpy.DTypeI64, Device: "cpu"},
+		{Data: []bool{true, false}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeBool, Device: "cpu"},
+		{Data: []complex128{1 + 2i}, Shape: gonpy.Shape{1, 1}, DType: gonpy.DTypeC128, Device: "cpu"},
+	}
+	for _, in := range tests {
+		d, err := ToDense(in)
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		out, err := FromDense(d)
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		if out.DType != in.DType || !out.Shape.Equal(in.Shape) || fmt.Sprint(out.Data) != fmt.Sprint(in.Data) {
+			t.Errorf("%s %v read back as %s %v %v", in.DType, in.Shape, out.DType, out.Shape, out.Data)
+		}
+	}
+}
+
+func TestToDenseConverts(t *testing.T) {
+	half := &gonpy.Tensor{Data: []uint16{0x3c00, 0xc000}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeF16, Device: "cpu"}
+	d, err := ToDense(half)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Dtype() != tensor.Float32 || fmt.Sprint(d.Data()) != "[1 -2]" {
+		t.Errorf("f16 tensor became %v %v", d.Dtype(), d.Data())
+	}
+}
+
+func TestFromDenseView(t *testing.T) {
+	d := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]int{1, 2, 3, 4, 5, 6}))
+	if err := d.T(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := FromDense(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.DType != gonpy.DTypeI64 || !out.Shape.Equal(gonpy.Shape{3, 2}) || fmt.Sprint(out.Data) != "[1 4 2 5 3 6]" {
+		t.Errorf("transposed view read as %s %v %v", out.DType, out.Shape, out.Data)
+	}
+}
+
+func TestToDenseInvalid(t *testing.T) {
+	s := &gonpy.Tensor{Data: []string{"a"}, Shape: gonpy.Shape{1}, DType: gonpy.UnicodeDType(1), Device: "cpu"}
+	if _, err := ToDense(s); !errors.Is(err, gonpy.ErrUnsupportedDType) {
+		t.Errorf("string tensor: got %v, want ErrUnsupportedDType", err)
+	}
+	short := &gonpy.Tensor{Data: []float64{1}, Shape: gonpy.Shape{2, 2}, DType: gonpy.DTypeF64, Device: "cpu"}
+	if _, err := ToDense(short); err == nil {
+		t.Error("tensor with short data converted")
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestToDenseDevice(t *testing.T) {
+	x := &gonpy.Tensor{Data: []float32{1, 2, 3, 4}, Shape: gonpy.Shape{2, 2}, DType: gonpy.DTypeF32, Device: "cpu"}
+	d, err := ToDense(onDevice(t, x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(d.Data()) != "[1 2 3 4]" {
+		t.Errorf("device tensor became %v", d.Data())
+	}
+}
@@ -0,0 +1,69 @@
+package hdf5
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// lookup3 returns Bob Jenkins' lookup3 hashlittle of b with an initial value
+// of zero, the checksum HDF5 stores in version 2 metadata structures.
+func lookup3(b []byte) uint32 {
+	a := 0xdeadbeef + uint32(len(b))
+	c, d := a, a
+	for len(b) > 12 {
+		a += binary.LittleEndian.Uint32(b)
+		c += binary.LittleEndian.Uint32(b[4:])
+		d += binary.LittleEndian.Uint32(b[8:])
+		a, c, d = lookup3Mix(a, c, d)
+		b = b[12:]
+	}
+	if len(b) == 0 {
+		return d
+	}
+	var tail [12]byte
+	copy(tail[:], b)
+	a += binary.LittleEndian.Uint32(tail[:])
+	c += binary.LittleEndian.Uint32(tail[4:])
+	d += binary.LittleEndian.Uint32(tail[8:])
+	return lookup3Final(a, c, d)
+}
+
+func lookup3Mix(a, b, c uint32) (uint32, uint32, uint32) {
+	a -= c
+	a ^= bits.RotateLeft32(c, 4)
+	c += b
+	b -= a
+	b ^= bits.RotateLeft32(a, 6)
+	a += c
+	c -= b
+	c ^= bits.RotateLeft32(b, 8)
+	b += a
+	a -= c
+	a ^= bits.RotateLeft32(c, 16)
+	c += b
+	b -= a
+	b ^= bits.RotateLeft32(a, 19)
+	a += c
+	c -= b
+	c ^= bits.RotateLeft32(b, 4)
+	b += a
+	return a, b, c
+}
+
+func lookup3Final(a, b, c uint32) uint32 {
+	c ^= b
+	c -= bits.RotateLeft32(b, 14)
+	a ^= c
+	a -= bits.RotateLeft32(c, 11)
+	b ^= a
+	b -= bits.RotateLeft32(a, 25)
+	c ^= b
+	c -= bits.RotateLeft32(b, 16)
+	a ^= c
+	a -= bits.RotateLeft32(c, 4)
+	b ^= a
+	b -= bits.RotateLeft32(a, 14)
+	c ^= b
+	c -= bits.RotateLeft32(b, 24)
+	return c
+}
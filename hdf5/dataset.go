@@ -0,0 +1,567 @@
+package hdf5
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Datatype classes.
+const (
+	classFixedPoint = 0
+	classFloat      = 1
+	classEnum       = 8
+)
+
+// Layout classes.
+const (
+	layoutCompact    = 0
+	layoutContiguous = 1
+	layoutChunked    = 2
+)
+
+// Filter identifiers.
+const (
+	filterDeflate    = 1
+	filterShuffle    = 2
+	filterFletcher32 = 3
+)
+
+// elemType describes how the elements of a dataset are stored.
+type elemType struct {
+	dtype     gonpy.DType
+	size      int  // Bytes per element in the file
+	bigEndian bool // Byte order in the file
+	widen     bool // 16-bit integers read into 32 bits
+	signed    bool
+}
+
+// filter is one stage of a dataset's filter pipeline.
+type filter struct {
+	id   uint16
+	data []uint32
+}
+
+// readDataset reads the dataset whose object header is at addr.
+func (h *File) readDataset(addr uint64) (*gonpy.Tensor, error) {
+	msgs, err := h.readObjectHeader(addr)
+	if err != nil {
+		return nil, err
+	}
+	var shape gonpy.Shape
+	var elem elemType
+	var filters []filter
+	for _, typ := range []uint16{msgDataspace, msgDatatype, msgFilters, msgLayout} {
+		msg, ok := msgs.first(typ)
+		if !ok {
+			if typ == msgFilters {
+				continue
+			}
+			return nil, fmt.Errorf("missing header message 0x%02x", typ)
+		}
+		if msg.flags&msgFlagShared != 0 {
+			return nil, fmt.Errorf("shared header message 0x%02x is not supported", typ)
+		}
+		switch typ {
+		case msgDataspace:
+			shape, err = h.parseDataspace(msg.data)
+		case msgDatatype:
+			elem, err = parseDatatype(h.parse(msg.data))
+		case msgFilters:
+			filters, err = parseFilters(h.parse(msg.data))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt/elem.size {
+		return nil, fmt.Errorf("dataset of shape %v is too large: %w", shape, gonpy.ErrTooLarge)
+	}
+	if err := h.limits.CheckSize(elem.dtype, shape); err != nil {
+		return nil, err
+	}
+	layout, _ := msgs.first(msgLayout)
+	raw, err := h.readLayout(layout.data, shape, elem.size, filters, n*elem.size)
+	if err != nil {
+		return nil, err
+	}
+	return decodeElements(raw, shape, elem)
+}
+
+// parseDataspace decodes a dataspace message into a shape.
+func (h *File) parseDataspace(data []byte) (gonpy.Shape, error) {
+	b := h.parse(data)
+	version := b.u8()
+	rank := int(b.u8())
+	b.u8() // Flags; maximum dimensions follow the current ones and are not needed
+	switch version {
+	case 1:
+		b.skip(5)
+	case 2:
+		switch typ := b.u8(); typ {
+		case 0:
+			rank = 0 // Scalar
+		case 2:
+			return nil, fmt.Errorf("null dataspace")
+		}
+	default:
+		return nil, fmt.Errorf("dataspace version %d", version)
+	}
+	shape := make(gonpy.Shape, rank)
+	for i := range shape {
+		dim := b.length()
+		if dim > math.MaxInt32 {
+			return nil, fmt.Errorf("dimension %d too large", dim)
+		}
+		shape[i] = int(dim)
+	}
+	return shape, b.err
+}
+
+// parseDatatype decodes a datatype message.
+func parseDatatype(b *buffer) (elemType, error) {
+	classVersion := b.u8()
+	bits := b.bytes(3)
+	e := elemType{size: int(b.u32()), bigEndian: bits[0]&1 != 0}
+	if b.err != nil {
+		return e, b.err
+	}
+	class := classVersion & 0x0f
+	switch class {
+	case classFixedPoint:
+		b.skip(2) // Bit offset
+		if precision := int(b.u16()); precision != 8*e.size {
+			return e, fmt.Errorf("%d-bit integers in %d bytes are not supported", precision, e.size)
+		}
+		e.signed = bits[0]&0x08 != 0
+		switch {
+		case e.size == 1 && e.signed:
+			e.dtype = gonpy.DTypeI8
+		case e.size == 1:
+			e.dtype = gonpy.DTypeU8
+		case e.size == 2 && e.signed, e.size == 4 && e.signed:
+			e.dtype, e.widen = gonpy.DTypeI32, e.size == 2
+		case e.size == 2, e.size == 4:
+			e.dtype, e.widen = gonpy.DTypeU32, e.size == 2
+		case e.size == 8 && e.signed:
+			e.dtype = gonpy.DTypeI64
+		case e.size == 8:
+			e.dtype = gonpy.DTypeU64
+		}
+	case classFloat:
+		if bits[0]&0x40 != 0 {
+			return e, fmt.Errorf("VAX floating-point order is not supported")
+		}
+		b.skip(5) // Bit offset, precision, and exponent location
+		expSize := b.u8()
+		switch {
+		case e.size == 8:
+			e.dtype = gonpy.DTypeF64
+		case e.size == 4:
+			e.dtype = gonpy.DTypeF32
+		case e.size == 2 && expSize == 5:
+			e.dtype = gonpy.DTypeF16
+		case e.size == 2 && expSize == 8:
+			e.dtype = gonpy.DTypeBF16
+		case e.size == 1 && expSize == 4:
+			e.dtype = gonpy.DTypeF8E4M3
+		case e.size == 1 && expSize == 5:
+			e.dtype = gonpy.DTypeF8E5M2
+		}
+	case classEnum:
+		// h5py stores booleans as an enum of FALSE and TRUE over a 1-byte integer
+		base, err := parseDatatype(b)
+		if err != nil {
+			return e, err
+		}
+		members := int(bits[0]) | int(bits[1])<<8
+		if base.size == 1 && e.size == 1 && members == 2 {
+			e.dtype = gonpy.DTypeBool
+		}
+	}
+	if b.err != nil {
+		return e, b.err
+	}
+	if e.dtype == "" {
+		return e, fmt.Errorf("unsupported datatype class %d of %d bytes: %w", class, e.size, gonpy.ErrUnsupportedDType)
+	}
+	return e, nil
+}
+
+// parseFilters decodes a filter pipeline message.
+func parseFilters(b *buffer) ([]filter, error) {
+	version := b.u8()
+	count := int(b.u8())
+	if version == 1 {
+		b.skip(6)
+	} else if version != 2 {
+		return nil, fmt.Errorf("filter pipeline version %d", version)
+	}
+	filters := make([]filter, 0, count)
+	for i := 0; i < count; i++ {
+		var f filter
+		f.id = b.u16()
+		nameLen := 0
+		if version == 1 || f.id >= 256 {
+			nameLen = int(b.u16())
+		}
+		b.u16() // Flags
+		values := int(b.u16())
+		if version == 1 {
+			nameLen = (nameLen + 7) &^ 7
+		}
+		b.skip(nameLen)
+		for j := 0; j < values; j++ {
+			f.data = append(f.data, b.u32())
+		}
+		if version == 1 && values%2 == 1 {
+			b.skip(4)
+		}
+		filters = append(filters, f)
+	}
+	return filters, b.err
+}
+
+// readLayout reads the n bytes of data described by a layout message. The
+// layout is checked against n before the data is allocated.
+func (h *File) readLayout(data []byte, shape gonpy.Shape, size int, filters []filter, n int) ([]byte, error) {
+	b := h.parse(data)
+	version := b.u8()
+	if version != 3 && version != 4 {
+		return nil, fmt.Errorf("data layout version %d: %w", version, gonpy.ErrUnsupportedVersion)
+	}
+	switch class := b.u8(); class {
+	case layoutCompact:
+		stored := b.bytes(int(b.u16()))
+		if b.err != nil {
+			return nil, b.err
+		}
+		if len(stored) < n {
+			return nil, fmt.Errorf("compact data has %d bytes, %d needed", len(stored), n)
+		}
+		return append([]byte(nil), stored[:n]...), nil
+
+	case layoutContiguous:
+		addr, length := b.offset(), b.length()
+		if b.err != nil {
+			return nil, b.err
+		}
+		if addr == undefinedAddr {
+			return make([]byte, n), nil // Never written
+		}
+		if length < uint64(n) {
+			return nil, fmt.Errorf("contiguous data has %d bytes, %d needed", length, n)
+		}
+		stored, err := h.readAt(addr, n)
+		if err != nil {
+			return nil, err
+		}
+		return stored.b, nil
+
+	case layoutChunked:
+		c := &chunkReader{h: h, shape: shape, size: size, filters: filters}
+		if version == 3 {
+			dims := int(b.u8())
+			btree := b.offset()
+			for i := 0; i < dims; i++ {
+				c.chunk = append(c.chunk, int(b.u32()))
+			}
+			if err := c.init(b.err, n); err != nil {
+				return nil, err
+			}
+			if btree == undefinedAddr {
+				return c.raw, nil
+			}
+			return c.raw, c.walkBTree(btree, 0)
+		}
+		return c.raw, c.readV4(b, n)
+	default:
+		return nil, fmt.Errorf("data layout class %d is not supported", class)
+	}
+}
+
+// chunkReader assembles a chunked dataset from its chunks.
+type chunkReader struct {
+	h       *File
+	shape   gonpy.Shape
+	size    int // Bytes per element
+	chunk   []int
+	filters []filter
+	raw     []byte
+}
+
+// init checks the chunk dimensions read from the layout message, which end
+// with the element size, and allocates the n bytes of the dataset.
+func (c *chunkReader) init(err error, n int) error {
+	if err != nil {
+		return err
+	}
+	if len(c.chunk) != len(c.shape)+1 {
+		return fmt.Errorf("%d chunk dimensions for rank %d", len(c.chunk), len(c.shape))
+	}
+	c.chunk = c.chunk[:len(c.shape)]
+	for _, d := range c.chunk {
+		if d <= 0 {
+			return fmt.Errorf("invalid chunk dimensions %v", c.chunk)
+		}
+	}
+	elems, err := gonpy.Shape(c.chunk).CheckedElemCount()
+	if err != nil || elems > math.MaxInt/c.size {
+		return fmt.Errorf("invalid chunk dimensions %v", c.chunk)
+	}
+	if err := c.h.limits.CheckBytes(int64(elems * c.size)); err != nil {
+		return err
+	}
+	c.raw = make([]byte, n)
+	return nil
+}
+
+// chunkBytes returns the unfiltered size of one chunk.
+func (c *chunkReader) chunkBytes() int {
+	return gonpy.Shape(c.chunk).ElemCount() * c.size
+}
+
+// readV4 reads the n bytes of a chunked dataset described by a version 4
+// layout message.
+func (c *chunkReader) readV4(b *buffer, n int) error {
+	flags := b.u8()
+	dims := int(b.u8())
+	width := int(b.u8())
+	for i := 0; i < dims; i++ {
+		c.chunk = append(c.chunk, int(b.uint(width)))
+	}
+	if err := c.init(b.err, n); err != nil {
+		return err
+	}
+	switch index := b.u8(); index {
+	case 1: // Single chunk
+		size, mask := uint64(c.chunkBytes()), uint32(0)
+		if flags&0x02 != 0 {
+			size, mask = b.length(), b.u32()
+		}
+		addr := b.offset()
+		if b.err != nil || addr == undefinedAddr {
+			return b.err
+		}
+		return c.readChunk(addr, size, mask, make([]int, len(c.shape)))
+	case 2: // Implicit: every chunk stored unfiltered, in row-major order
+		addr := b.offset()
+		if b.err != nil || addr == undefinedAddr {
+			return b.err
+		}
+		grid := make(gonpy.Shape, len(c.shape))
+		for i, d := range c.shape {
+			grid[i] = (d + c.chunk[i] - 1) / c.chunk[i]
+		}
+		offsets := make([]int, len(c.shape))
+		for i := 0; i < grid.ElemCount(); i++ {
+			for k, rem := len(grid)-1, i; k >= 0; k-- {
+				offsets[k] = rem % grid[k] * c.chunk[k]
+				rem /= grid[k]
+			}
+			chunkAddr := addr + uint64(i)*uint64(c.chunkBytes())
+			if err := c.readChunk(chunkAddr, uint64(c.chunkBytes()), 0, offsets); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("chunk index type %d is not supported", index)
+	}
+}
+
+// walkBTree reads the chunks under the version 1 B-tree node at addr.
+func (c *chunkReader) walkBTree(addr uint64, depth int) error {
+	if depth > 64 {
+		return fmt.Errorf("chunk B-tree too deep")
+	}
+	h := c.h
+	head, err := h.readAt(addr, 8+2*h.sizeOffsets)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(head.bytes(4), []byte("TREE")) {
+		return fmt.Errorf("bad B-tree signature")
+	}
+	if typ := head.u8(); typ != 1 {
+		return fmt.Errorf("chunk B-tree has node type %d", typ)
+	}
+	level := head.u8()
+	entries := int(head.u16())
+	keySize := 8 + 8*(len(c.shape)+1)
+	b, err := h.readAt(addr+uint64(len(head.b)), entries*(keySize+h.sizeOffsets)+keySize)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < entries; i++ {
+		size := uint64(b.u32())
+		mask := b.u32()
+		offsets := make([]int, len(c.shape))
+		for k := range offsets {
+			offsets[k] = int(b.u64())
+		}
+		b.skip(8) // Offset in the element dimension
+		child := b.offset()
+		if b.err != nil {
+			return b.err
+		}
+		if level > 0 {
+			err = c.walkBTree(child, depth+1)
+		} else {
+			err = c.readChunk(child, size, mask, offsets)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readChunk reads, unfilters, and places the chunk of size bytes at addr
+// whose first element is at offsets in the dataset. Bit i of mask is set if
+// filter i was not applied to the chunk.
+func (c *chunkReader) readChunk(addr, size uint64, mask uint32, offsets []int) error {
+	if size > uint64(c.h.size) {
+		return fmt.Errorf("chunk of %d bytes", size)
+	}
+	stored, err := c.h.readAt(addr, int(size))
+	if err != nil {
+		return err
+	}
+	data := stored.b
+	for i := len(c.filters) - 1; i >= 0; i-- {
+		if mask&(1<<i) != 0 {
+			continue
+		}
+		if data, err = c.unfilter(c.filters[i], data); err != nil {
+			return err
+		}
+	}
+	if len(data) != c.chunkBytes() {
+		return fmt.Errorf("chunk at %v has %d bytes, %d expected", offsets, len(data), c.chunkBytes())
+	}
+	c.place(data, offsets)
+	return nil
+}
+
+// unfilter reverses one filter.
+func (c *chunkReader) unfilter(f filter, data []byte) ([]byte, error) {
+	switch f.id {
+	case filterDeflate:
+		zr, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(io.LimitReader(zr, int64(c.chunkBytes())+1))
+	case filterShuffle:
+		size := c.size
+		if len(f.data) > 0 {
+			size = int(f.data[0])
+		}
+		return unshuffle(data, size), nil
+	case filterFletcher32:
+		if len(data) < 4 {
+			return nil, fmt.Errorf("fletcher32 chunk of %d bytes", len(data))
+		}
+		return data[:len(data)-4], nil
+	}
+	return nil, fmt.Errorf("filter %d is not supported", f.id)
+}
+
+// unshuffle reverses the shuffle filter, which stores the first byte of every
+// element, then the second, and so on. Trailing bytes that do not form a whole
+// element are left in place.
+func unshuffle(data []byte, size int) []byte {
+	if size <= 1 {
+		return data
+	}
+	n := len(data) / size
+	out := make([]byte, len(data))
+	for j := 0; j < size; j++ {
+		for i := 0; i < n; i++ {
+			out[i*size+j] = data[j*n+i]
+		}
+	}
+	copy(out[n*size:], data[n*size:])
+	return out
+}
+
+// place copies the parts of a chunk starting at offsets that lie inside the
+// dataset into c.raw.
+func (c *chunkReader) place(data []byte, offsets []int) {
+	rank := len(c.shape)
+	if rank == 0 {
+		copy(c.raw, data)
+		return
+	}
+	for k, off := range offsets {
+		if off >= c.shape[k] {
+			return
+		}
+	}
+	last := rank - 1
+	run := min(c.chunk[last], c.shape[last]-offsets[last]) * c.size
+	rows := gonpy.Shape(c.chunk[:last]).ElemCount()
+	idx := make([]int, last)
+	for r := 0; r < rows; r++ {
+		inside := true
+		dst := 0
+		for k := 0; k < last; k++ {
+			g := offsets[k] + idx[k]
+			inside = inside && g < c.shape[k]
+			dst = dst*c.shape[k] + g
+		}
+		if inside {
+			dst = (dst*c.shape[last] + offsets[last]) * c.size
+			copy(c.raw[dst:dst+run], data[r*c.chunk[last]*c.size:])
+		}
+		for k := last - 1; k >= 0; k-- {
+			idx[k]++
+			if idx[k] < c.chunk[k] {
+				break
+			}
+			idx[k] = 0
+		}
+	}
+}
+
+// decodeElements converts raw element bytes to a tensor.
+func decodeElements(raw []byte, shape gonpy.Shape, e elemType) (*gonpy.Tensor, error) {
+	if e.bigEndian && e.size > 1 {
+		for i := 0; i+e.size <= len(raw); i += e.size {
+			for l, r := i, i+e.size-1; l < r; l, r = l+1, r-1 {
+				raw[l], raw[r] = raw[r], raw[l]
+			}
+		}
+	}
+	t, err := gonpy.Zeros(e.dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case e.widen && e.signed:
+		data := t.Data.([]int32)
+		for i := range data {
+			data[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+		}
+	case e.widen:
+		data := t.Data.([]uint32)
+		for i := range data {
+			data[i] = uint32(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+	default:
+		err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, t.Data)
+	}
+	return t, err
+}
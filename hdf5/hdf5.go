@@ -0,0 +1,319 @@
+// Package hdf5 reads numeric datasets from HDF5 files, such as Keras weights
+// and h5py datasets, and writes tensors into new HDF5 files as datasets in
+// nested groups. It is written in pure Go and needs no HDF5 library.
+//
+// The reader supports the structures h5py and the HDF5 library write by
+// default: superblock versions 0 to 3, version 1 and 2 object headers, groups
+// stored as symbol tables or compact links, and contiguous, compact, and
+// chunked datasets. Chunks may be compressed with deflate and filtered with
+// shuffle and fletcher32. Groups with dense link storage, chunk indexes other
+// than version 1 B-trees, single chunks, and implicit indexes, and attributes
+// are not supported. Unallocated data reads as zeros whatever the fill value.
+//
+// Integer, floating-point, and boolean (h5py enum) datasets are read into the
+// matching dtypes. 16-bit integers are widened to 32 bits, since gonpy has no
+// dtype for them.
+package hdf5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gocnn/gonpy"
+)
+
+// signature starts every HDF5 superblock.
+var signature = []byte("\x89HDF\r\n\x1a\n")
+
+// undefinedAddr marks an address that has not been allocated.
+const undefinedAddr = ^uint64(0)
+
+// maxObjects bounds the number of objects visited while walking groups, so
+// that a corrupt or cyclic file cannot loop forever.
+const maxObjects = 1 << 20
+
+// File is an HDF5 file opened for reading.
+type File struct {
+	f           *os.File
+	size        int64
+	base        uint64 // Base address added to all addresses
+	sizeOffsets int
+	sizeLengths int
+	root        uint64 // Address of the root group's object header
+	datasets    map[string]uint64
+	names       []string
+	limits      gonpy.ReadLimits
+}
+
+// Open opens an HDF5 file and lists its datasets. The file limiter and the
+// size limit set with gonpy.WithMaxBytes apply; the file occupies a slot of
+// the limiter until Close is called.
+func Open(path string, opts ...gonpy.Option) (*File, error) {
+	limits := gonpy.Limits(opts...)
+	limits.Limiter.Acquire()
+	f, err := os.Open(path)
+	if err != nil {
+		limits.Limiter.Release()
+		return nil, err
+	}
+	h := &File{f: f, datasets: make(map[string]uint64), limits: limits}
+	if err := h.init(); err != nil {
+		f.Close()
+		limits.Limiter.Release()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return h, nil
+}
+
+// Close closes the file. Tensors already read remain valid.
+func (h *File) Close() error {
+	if h.f == nil {
+		return nil
+	}
+	err := h.f.Close()
+	h.f = nil
+	h.limits.Limiter.Release()
+	return err
+}
+
+// init reads the superblock and walks the group hierarchy.
+func (h *File) init() error {
+	info, err := h.f.Stat()
+	if err != nil {
+		return err
+	}
+	h.size = info.Size()
+
+	// The superblock may follow a user block of 512, 1024, 2048, ... bytes
+	var sb int64 = -1
+	sig := make([]byte, len(signature))
+	for off := int64(0); off+int64(len(signature)) <= h.size; off = max(512, 2*off) {
+		if _, err := h.f.ReadAt(sig, off); err != nil {
+			return err
+		}
+		if bytes.Equal(sig, signature) {
+			sb = off
+			break
+		}
+	}
+	if sb < 0 {
+		return fmt.Errorf("hdf5: no superblock found: %w", gonpy.ErrBadMagic)
+	}
+	if err := h.readSuperblock(sb); err != nil {
+		return err
+	}
+	visited := make(map[uint64]bool)
+	return h.walk(h.root, "", visited)
+}
+
+// readSuperblock reads the superblock at off.
+func (h *File) readSuperblock(off int64) error {
+	head := make([]byte, 16)
+	if _, err := h.f.ReadAt(head, off); err != nil {
+		return err
+	}
+	version := head[8]
+	switch version {
+	case 0, 1:
+		h.sizeOffsets, h.sizeLengths = int(head[13]), int(head[14])
+	case 2, 3:
+		h.sizeOffsets, h.sizeLengths = int(head[9]), int(head[10])
+	default:
+		return fmt.Errorf("hdf5: superblock version %d: %w", version, gonpy.ErrUnsupportedVersion)
+	}
+	if (h.sizeOffsets != 4 && h.sizeOffsets != 8) || (h.sizeLengths != 4 && h.sizeLengths != 8) {
+		return fmt.Errorf("hdf5: unsupported address sizes %d and %d", h.sizeOffsets, h.sizeLengths)
+	}
+
+	switch version {
+	case 0, 1:
+		// Skip the fixed fields, then read the base, free-space, end of file, and
+		// driver addresses, followed by the root group's symbol table entry
+		fixed := 24
+		if version == 1 {
+			fixed += 4
+		}
+		b, err := h.readAt(uint64(off)+uint64(fixed), 4*h.sizeOffsets+2*h.sizeOffsets+24)
+		if err != nil {
+			return err
+		}
+		h.base = b.offset()
+		b.skip(3*h.sizeOffsets + h.sizeOffsets) // Link name offset of the root entry
+		h.root = b.offset()
+		if b.err != nil {
+			return b.err
+		}
+	case 2, 3:
+		b, err := h.readAt(uint64(off)+12, 4*h.sizeOffsets)
+		if err != nil {
+			return err
+		}
+		h.base = b.offset()
+		b.skip(2 * h.sizeOffsets) // Superblock extension and end of file addresses
+		h.root = b.offset()
+		if b.err != nil {
+			return b.err
+		}
+	}
+	if h.base == undefinedAddr {
+		h.base = 0
+	}
+	return nil
+}
+
+// walk records the datasets reachable from the object at addr, which is named path.
+func (h *File) walk(addr uint64, path string, visited map[uint64]bool) error {
+	if visited[addr] {
+		return nil // Hard links may make the hierarchy a graph
+	}
+	if len(visited) >= maxObjects {
+		return fmt.Errorf("hdf5: more than %d objects", maxObjects)
+	}
+	visited[addr] = true
+
+	msgs, err := h.readObjectHeader(addr)
+	if err != nil {
+		return fmt.Errorf("hdf5: object %s: %w", displayName(path), err)
+	}
+	if _, ok := msgs.first(msgLayout); ok {
+		h.datasets[path] = addr
+		h.names = append(h.names, path)
+		return nil
+	}
+	links, err := h.groupLinks(msgs)
+	if err != nil {
+		return fmt.Errorf("hdf5: group %s: %w", displayName(path), err)
+	}
+	for _, l := range links {
+		child := l.name
+		if path != "" {
+			child = path + "/" + l.name
+		}
+		if err := h.walk(l.addr, child, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayName returns the absolute HDF5 name of an object path.
+func displayName(path string) string {
+	return "/" + path
+}
+
+// Datasets returns the names of the file's datasets, such as "dense/kernel",
+// in sorted order.
+func (h *File) Datasets() []string {
+	names := append([]string(nil), h.names...)
+	sort.Strings(names)
+	return names
+}
+
+// Read reads a dataset by name, with or without a leading slash.
+func (h *File) Read(name string) (*gonpy.Tensor, error) {
+	addr, ok := h.datasets[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return nil, fmt.Errorf("hdf5: no dataset %s: %w", name, gonpy.ErrEntryNotFound)
+	}
+	if h.f == nil {
+		return nil, fmt.Errorf("hdf5: dataset %s: %w", name, os.ErrClosed)
+	}
+	t, err := h.readDataset(addr)
+	if err != nil {
+		return nil, fmt.Errorf("hdf5: dataset %s: %w", displayName(strings.TrimPrefix(name, "/")), err)
+	}
+	return t, nil
+}
+
+// ReadFile reads all datasets of an HDF5 file, in sorted name order.
+func ReadFile(path string, opts ...gonpy.Option) ([]gonpy.NamedTensor, error) {
+	h, err := Open(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer h.Close()
+
+	names := h.Datasets()
+	tensors := make([]gonpy.NamedTensor, len(names))
+	for i, name := range names {
+		t, err := h.Read(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		tensors[i] = gonpy.NamedTensor{Name: name, Tensor: t}
+	}
+	return tensors, nil
+}
+
+// readAt reads n bytes at a file address.
+func (h *File) readAt(addr uint64, n int) (*buffer, error) {
+	if addr == undefinedAddr || n < 0 || addr+h.base > uint64(h.size) || uint64(n) > uint64(h.size)-addr-h.base {
+		return nil, fmt.Errorf("hdf5: %d bytes at address %d are outside the file", n, addr)
+	}
+	b := make([]byte, n)
+	if _, err := h.f.ReadAt(b, int64(addr+h.base)); err != nil {
+		return nil, err
+	}
+	return &buffer{b: b, sizeOffsets: h.sizeOffsets, sizeLengths: h.sizeLengths}, nil
+}
+
+// readRest reads up to n bytes at a file address, fewer if the file ends first.
+func (h *File) readRest(addr uint64, n int) (*buffer, error) {
+	if addr != undefinedAddr && addr+h.base < uint64(h.size) {
+		n = int(min(uint64(n), uint64(h.size)-addr-h.base))
+	}
+	return h.readAt(addr, n)
+}
+
+// buffer decodes little-endian fields from a block read from the file,
+// remembering the first error so that callers can check once after a run of
+// reads.
+type buffer struct {
+	b           []byte
+	pos         int
+	err         error
+	sizeOffsets int
+	sizeLengths int
+}
+
+func (b *buffer) next(n int) []byte {
+	if b.err != nil || n < 0 || b.pos+n > len(b.b) {
+		if b.err == nil {
+			b.err = fmt.Errorf("hdf5: truncated structure")
+		}
+		return make([]byte, max(n, 0))
+	}
+	p := b.b[b.pos : b.pos+n]
+	b.pos += n
+	return p
+}
+
+func (b *buffer) skip(n int)         { b.next(n) }
+func (b *buffer) remaining() int     { return len(b.b) - b.pos }
+func (b *buffer) u8() uint8          { return b.next(1)[0] }
+func (b *buffer) u16() uint16        { return binary.LittleEndian.Uint16(b.next(2)) }
+func (b *buffer) u32() uint32        { return binary.LittleEndian.Uint32(b.next(4)) }
+func (b *buffer) u64() uint64        { return binary.LittleEndian.Uint64(b.next(8)) }
+func (b *buffer) offset() uint64     { return b.uint(b.sizeOffsets) }
+func (b *buffer) length() uint64     { return b.uint(b.sizeLengths) }
+func (b *buffer) bytes(n int) []byte { return b.next(n) }
+
+// uint reads an unsigned integer of n bytes. Addresses of all one bits are
+// returned as undefinedAddr whatever their width.
+func (b *buffer) uint(n int) uint64 {
+	p := b.next(n)
+	var v uint64
+	allOnes := true
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(p[i])
+		allOnes = allOnes && p[i] == 0xff
+	}
+	if allOnes && n > 0 {
+		return undefinedAddr
+	}
+	return v
+}
@@ -0,0 +1,150 @@
+package hdf5
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "w.h5")
+	tensors := []gonpy.NamedTensor{
+		{Name: "dense/kernel", Tensor: &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"}},
+		{Name: "mask", Tensor: &gonpy.Tensor{Data: []bool{true, false}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeBool, Device: "cpu"}},
+	}
+	if err := WriteFile(path, tensors); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Name != "dense/kernel" || got[1].Name != "mask" {
+		t.Fatalf("read %v", got)
+	}
+	if k := got[0].Tensor.Data.([]float32); !got[0].Tensor.Shape.Equal(gonpy.Shape{2, 3}) || k[5] != 6 {
+		t.Errorf("kernel = %v %v", got[0].Tensor.Shape, k)
+	}
+	if m := got[1].Tensor.Data.([]bool); !m[0] || m[1] {
+		t.Errorf("mask = %v", m)
+	}
+}
+
+// patched writes a file holding tensor x and returns its bytes with old
+// replaced by new.
+func patched(t *testing.T, x *gonpy.Tensor, old, new []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "x.h5")
+	if err := WriteFile(path, []gonpy.NamedTensor{{Name: "x", Tensor: x}}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(b, old) != 1 {
+		t.Fatalf("pattern %x found %d times", old, bytes.Count(b, old))
+	}
+	if err := os.WriteFile(path, bytes.Replace(b, old, new, 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func dims(d ...uint64) []byte {
+	var b []byte
+	for _, v := range d {
+		b = binary.LittleEndian.AppendUint64(b, v)
+	}
+	return b
+}
+
+func TestHostileDataspace(t *testing.T) {
+	x := &gonpy.Tensor{Data: make([]float64, 15), Shape: gonpy.Shape{3, 5}, DType: gonpy.DTypeF64, Device: "cpu"}
+	path := patched(t, x, dims(3, 5), dims(2147483647, 268435456))
+	if _, err := ReadFile(path); !errors.Is(err, gonpy.ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+
+	path = patched(t, x, dims(3, 5), dims(3, 6))
+	if _, err := ReadFile(path); err == nil {
+		t.Error("dataspace larger than the stored data was accepted")
+	}
+}
+
+func TestZeroSizeEnum(t *testing.T) {
+	x := &gonpy.Tensor{Data: []bool{true}, Shape: gonpy.Shape{1}, DType: gonpy.DTypeBool, Device: "cpu"}
+	enum := []byte{0x10 | classEnum, 2, 0, 0, 1, 0, 0, 0}
+	path := patched(t, x, enum, []byte{0x10 | classEnum, 2, 0, 0, 0, 0, 0, 0})
+	if _, err := ReadFile(path); err == nil {
+		t.Error("enum of size 0 was accepted")
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "x.h5")
+	x := &gonpy.Tensor{Data: make([]float64, 1024), Shape: gonpy.Shape{1024}, DType: gonpy.DTypeF64, Device: "cpu"}
+	if err := WriteFile(path, []gonpy.NamedTensor{{Name: "x", Tensor: x}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(path, gonpy.WithMaxBytes(1024)); !errors.Is(err, gonpy.ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteDeviceTensor(t *testing.T) {
+	x := &gonpy.Tensor{Data: []int64{1, -2, 3}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeI64, Device: "cpu"}
+	path := filepath.Join(t.TempDir(), "a.h5")
+	if err := WriteFile(path, []gonpy.NamedTensor{{Name: "x", Tensor: onDevice(t, x)}}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || fmt.Sprint(out[0].Tensor.Data) != "[1 -2 3]" {
+		t.Errorf("read back %v", out)
+	}
+}
@@ -0,0 +1,332 @@
+package hdf5
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Header message types.
+const (
+	msgDataspace    = 0x01
+	msgLinkInfo     = 0x02
+	msgDatatype     = 0x03
+	msgFillValue    = 0x05
+	msgLink         = 0x06
+	msgLayout       = 0x08
+	msgGroupInfo    = 0x0a
+	msgFilters      = 0x0b
+	msgContinuation = 0x10
+	msgSymbolTable  = 0x11
+)
+
+// msgFlagShared marks a message stored elsewhere and only referenced here.
+const msgFlagShared = 0x02
+
+// maxHeaderBlocks bounds the continuation blocks of one object header.
+const maxHeaderBlocks = 1 << 10
+
+// message is one header message of an object.
+type message struct {
+	typ   uint16
+	flags uint8
+	data  []byte
+}
+
+// messages are the header messages of an object, in header order.
+type messages []message
+
+// first returns the first message of type typ.
+func (m messages) first(typ uint16) (message, bool) {
+	for _, msg := range m {
+		if msg.typ == typ {
+			return msg, true
+		}
+	}
+	return message{}, false
+}
+
+// headerBlock is a block of header messages still to be parsed.
+type headerBlock struct {
+	addr uint64
+	size uint64
+}
+
+// readObjectHeader reads the messages of the object header at addr, following
+// continuation blocks.
+func (h *File) readObjectHeader(addr uint64) (messages, error) {
+	prefix, err := h.readRest(addr, 34)
+	if err != nil {
+		return nil, err
+	}
+	v2 := bytes.HasPrefix(prefix.b, []byte("OHDR"))
+	var blocks []headerBlock
+	crtOrder := false
+	if v2 {
+		prefix.skip(4)
+		if version := prefix.u8(); version != 2 {
+			return nil, fmt.Errorf("object header version %d", version)
+		}
+		flags := prefix.u8()
+		if flags&0x20 != 0 {
+			prefix.skip(16) // Access, modification, change, and birth times
+		}
+		if flags&0x10 != 0 {
+			prefix.skip(4) // Attribute phase change values
+		}
+		size := prefix.uint(1 << (flags & 3))
+		crtOrder = flags&0x04 != 0
+		blocks = append(blocks, headerBlock{addr + uint64(prefix.pos), size})
+	} else {
+		if version := prefix.u8(); version != 1 {
+			return nil, fmt.Errorf("object header version %d", version)
+		}
+		prefix.skip(7) // Reserved, message count, and reference count
+		size := uint64(prefix.u32())
+		blocks = append(blocks, headerBlock{addr + 16, size})
+	}
+	if prefix.err != nil {
+		return nil, prefix.err
+	}
+
+	var msgs messages
+	for i := 0; i < len(blocks); i++ {
+		if i >= maxHeaderBlocks {
+			return nil, fmt.Errorf("more than %d object header blocks", maxHeaderBlocks)
+		}
+		if blocks[i].size > uint64(h.size) {
+			return nil, fmt.Errorf("object header block of %d bytes", blocks[i].size)
+		}
+		b, err := h.readAt(blocks[i].addr, int(blocks[i].size))
+		if err != nil {
+			return nil, err
+		}
+		if v2 && i > 0 {
+			if !bytes.HasPrefix(b.b, []byte("OCHK")) || len(b.b) < 8 {
+				return nil, fmt.Errorf("bad object header continuation block")
+			}
+			b.b = b.b[4 : len(b.b)-4] // Signature and checksum
+		}
+		for {
+			var msg message
+			if v2 {
+				if b.remaining() < 4 || (crtOrder && b.remaining() < 6) {
+					break // Gap before the checksum
+				}
+				msg.typ = uint16(b.u8())
+				size := int(b.u16())
+				msg.flags = b.u8()
+				if crtOrder {
+					b.skip(2)
+				}
+				msg.data = b.bytes(size)
+			} else {
+				if b.remaining() < 8 {
+					break
+				}
+				msg.typ = b.u16()
+				size := int(b.u16())
+				msg.flags = b.u8()
+				b.skip(3)
+				msg.data = b.bytes(size)
+			}
+			if b.err != nil {
+				return nil, b.err
+			}
+			if msg.typ == msgContinuation {
+				c := h.parse(msg.data)
+				blocks = append(blocks, headerBlock{c.offset(), c.length()})
+				if c.err != nil {
+					return nil, c.err
+				}
+				continue
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+// parse returns a buffer decoding the data of a message.
+func (h *File) parse(data []byte) *buffer {
+	return &buffer{b: data, sizeOffsets: h.sizeOffsets, sizeLengths: h.sizeLengths}
+}
+
+// link is a named hard link from a group to an object.
+type link struct {
+	name string
+	addr uint64
+}
+
+// groupLinks returns the hard links of a group, from its symbol table or its
+// link messages. Soft and external links are skipped.
+func (h *File) groupLinks(msgs messages) ([]link, error) {
+	if msg, ok := msgs.first(msgSymbolTable); ok {
+		b := h.parse(msg.data)
+		btree, heap := b.offset(), b.offset()
+		if b.err != nil {
+			return nil, b.err
+		}
+		names, err := h.readLocalHeap(heap)
+		if err != nil {
+			return nil, err
+		}
+		var links []link
+		return links, h.walkGroupBTree(btree, names, &links, 0)
+	}
+
+	if msg, ok := msgs.first(msgLinkInfo); ok {
+		b := h.parse(msg.data)
+		b.skip(1)
+		if flags := b.u8(); flags&1 != 0 {
+			b.skip(8) // Maximum creation index
+		}
+		if heap := b.offset(); heap != undefinedAddr && b.err == nil {
+			return nil, fmt.Errorf("dense link storage is not supported")
+		}
+	}
+	var links []link
+	for _, msg := range msgs {
+		if msg.typ != msgLink {
+			continue
+		}
+		b := h.parse(msg.data)
+		if version := b.u8(); version != 1 {
+			return nil, fmt.Errorf("link message version %d", version)
+		}
+		flags := b.u8()
+		linkType := uint8(0)
+		if flags&0x08 != 0 {
+			linkType = b.u8()
+		}
+		if flags&0x04 != 0 {
+			b.skip(8) // Creation order
+		}
+		if flags&0x10 != 0 {
+			b.skip(1) // Character set
+		}
+		name := string(b.bytes(int(b.uint(1 << (flags & 3)))))
+		if b.err != nil {
+			return nil, b.err
+		}
+		if linkType != 0 {
+			continue
+		}
+		addr := b.offset()
+		if b.err != nil {
+			return nil, b.err
+		}
+		links = append(links, link{name, addr})
+	}
+	return links, nil
+}
+
+// readLocalHeap returns the data segment of the local heap at addr, which
+// holds the link names of a symbol table group.
+func (h *File) readLocalHeap(addr uint64) ([]byte, error) {
+	b, err := h.readAt(addr, 8+2*h.sizeLengths+h.sizeOffsets)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(b.bytes(4), []byte("HEAP")) {
+		return nil, fmt.Errorf("bad local heap signature")
+	}
+	b.skip(4) // Version and reserved
+	size := b.length()
+	b.length() // Free list offset
+	data := b.offset()
+	if b.err != nil {
+		return nil, b.err
+	}
+	if size > uint64(h.size) {
+		return nil, fmt.Errorf("local heap of %d bytes", size)
+	}
+	seg, err := h.readAt(data, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return seg.b, nil
+}
+
+// heapName returns the NUL-terminated string at off in a local heap.
+func heapName(heap []byte, off uint64) (string, error) {
+	if off >= uint64(len(heap)) {
+		return "", fmt.Errorf("link name offset %d outside the local heap", off)
+	}
+	name := heap[off:]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return string(name), nil
+}
+
+// walkGroupBTree collects the links of the symbol table nodes under the group
+// B-tree node at addr.
+func (h *File) walkGroupBTree(addr uint64, names []byte, links *[]link, depth int) error {
+	if depth > 64 {
+		return fmt.Errorf("group B-tree too deep")
+	}
+	head, err := h.readAt(addr, 8+2*h.sizeOffsets)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(head.bytes(4), []byte("TREE")) {
+		return fmt.Errorf("bad B-tree signature")
+	}
+	if typ := head.u8(); typ != 0 {
+		return fmt.Errorf("group B-tree has node type %d", typ)
+	}
+	level := head.u8()
+	entries := int(head.u16())
+	b, err := h.readAt(addr+uint64(len(head.b)), entries*(h.sizeLengths+h.sizeOffsets)+h.sizeLengths)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < entries; i++ {
+		b.length() // Key
+		child := b.offset()
+		if b.err != nil {
+			return b.err
+		}
+		if level > 0 {
+			err = h.walkGroupBTree(child, names, links, depth+1)
+		} else {
+			err = h.readSymbolNode(child, names, links)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSymbolNode collects the links of the symbol table node at addr.
+func (h *File) readSymbolNode(addr uint64, names []byte, links *[]link) error {
+	head, err := h.readAt(addr, 8)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(head.bytes(4), []byte("SNOD")) {
+		return fmt.Errorf("bad symbol table node signature")
+	}
+	head.skip(2) // Version and reserved
+	count := int(head.u16())
+	entrySize := 2*h.sizeOffsets + 24
+	b, err := h.readAt(addr+8, count*entrySize)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		nameOff := b.offset()
+		obj := b.offset()
+		b.skip(24) // Cache type, reserved, and scratch pad
+		if b.err != nil {
+			return b.err
+		}
+		name, err := heapName(names, nameOff)
+		if err != nil {
+			return err
+		}
+		*links = append(*links, link{name, obj})
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package hdf5
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gocnn/gonpy"
+)
+
+// superblockSize is the size of the version 2 superblock WriteFile writes,
+// with 8-byte addresses and lengths.
+const superblockSize = 48
+
+// floatProperties are the bit offset, precision, exponent location, exponent
+// size, mantissa location, mantissa size, and exponent bias of the
+// floating-point dtypes WriteFile accepts.
+var floatProperties = map[gonpy.DType][7]uint32{
+	gonpy.DTypeF64:    {0, 64, 52, 11, 0, 52, 1023},
+	gonpy.DTypeF32:    {0, 32, 23, 8, 0, 23, 127},
+	gonpy.DTypeF16:    {0, 16, 10, 5, 0, 10, 15},
+	gonpy.DTypeBF16:   {0, 16, 7, 8, 0, 7, 127},
+	gonpy.DTypeF8E4M3: {0, 8, 3, 4, 0, 3, 7},
+	gonpy.DTypeF8E5M2: {0, 8, 2, 5, 0, 2, 15},
+}
+
+// node is a group or dataset of the file WriteFile builds.
+type node struct {
+	children map[string]*node // Nil for datasets
+	tensor   *gonpy.Tensor
+	addr     uint64 // Address of a dataset's data
+}
+
+// WriteFile writes tensors to a new HDF5 file as contiguous datasets. Names
+// containing slashes, such as "dense/kernel", place datasets in nested groups,
+// which are created as needed. Integer, floating-point, and boolean tensors
+// are accepted; booleans are stored as the enum h5py uses.
+func WriteFile(path string, tensors []gonpy.NamedTensor) error {
+	root := &node{children: make(map[string]*node)}
+	for _, nt := range tensors {
+		if err := root.add(nt.Name, nt.Tensor); err != nil {
+			return fmt.Errorf("hdf5: dataset %s: %w", nt.Name, err)
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f, root)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// add places t at the slash-separated path name below n.
+func (n *node) add(name string, t *gonpy.Tensor) error {
+	t, err := t.ToHost()
+	if err != nil {
+		return err
+	}
+	if _, err := datatypeMessage(t.DType); err != nil {
+		return err
+	}
+	if size := binary.Size(t.Data); size < 0 || size != t.Shape.ElemCount()*t.DType.Size() {
+		return fmt.Errorf("%d bytes of data for shape %v", size, t.Shape)
+	}
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	for i, part := range parts {
+		if part == "" || part == "." {
+			return fmt.Errorf("invalid name")
+		}
+		child, ok := n.children[part]
+		if i == len(parts)-1 {
+			if ok {
+				return fmt.Errorf("name already used")
+			}
+			n.children[part] = &node{tensor: t}
+			return nil
+		}
+		if !ok {
+			child = &node{children: make(map[string]*node)}
+			n.children[part] = child
+		} else if child.children == nil {
+			return fmt.Errorf("%s is a dataset", strings.Join(parts[:i+1], "/"))
+		}
+		n = child
+	}
+	return nil
+}
+
+// sortedChildren returns the names of a group's children in sorted order.
+func (n *node) sortedChildren() []string {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writer writes a file sequentially, tracking the address of the next byte.
+type writer struct {
+	w   *bufio.Writer
+	off uint64
+	err error
+}
+
+func (w *writer) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+		w.off += uint64(len(b))
+	}
+}
+
+// write writes the file: space for the superblock, the data of every dataset,
+// and the object headers, each after those of its children. The superblock,
+// which points at the root group, is filled in last.
+func write(f *os.File, root *node) error {
+	w := &writer{w: bufio.NewWriter(f)}
+	w.write(make([]byte, superblockSize))
+	w.writeData(root)
+	rootAddr := w.writeHeaders(root)
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	if w.err != nil {
+		return w.err
+	}
+
+	sb := append([]byte(nil), signature...)
+	sb = append(sb, 2, 8, 8, 0)
+	for _, addr := range []uint64{0, undefinedAddr, w.off, rootAddr} {
+		sb = binary.LittleEndian.AppendUint64(sb, addr)
+	}
+	sb = binary.LittleEndian.AppendUint32(sb, lookup3(sb))
+	_, err := f.WriteAt(sb, 0)
+	return err
+}
+
+// writeData writes the data of the datasets below n.
+func (w *writer) writeData(n *node) {
+	for _, name := range n.sortedChildren() {
+		child := n.children[name]
+		if child.children != nil {
+			w.writeData(child)
+			continue
+		}
+		child.addr = w.off
+		if w.err == nil {
+			w.err = binary.Write(w, binary.LittleEndian, child.tensor.Data)
+		}
+	}
+}
+
+// Write lets binary.Write stream data through w.
+func (w *writer) Write(b []byte) (int, error) {
+	w.write(b)
+	return len(b), w.err
+}
+
+// writeHeaders writes the object headers of n and everything below it, and
+// returns the address of n's header.
+func (w *writer) writeHeaders(n *node) uint64 {
+	if n.children == nil {
+		return w.writeHeader(datasetMessages(n))
+	}
+	var links []message
+	for _, name := range n.sortedChildren() {
+		addr := w.writeHeaders(n.children[name])
+		links = append(links, linkMessage(name, addr))
+	}
+	// Link info without a fractal heap or name index, and default group info
+	linkInfo := []byte{0, 0}
+	linkInfo = binary.LittleEndian.AppendUint64(linkInfo, undefinedAddr)
+	linkInfo = binary.LittleEndian.AppendUint64(linkInfo, undefinedAddr)
+	msgs := []message{{typ: msgLinkInfo, data: linkInfo}, {typ: msgGroupInfo, data: []byte{0, 0}}}
+	return w.writeHeader(append(msgs, links...))
+}
+
+// writeHeader writes a version 2 object header holding msgs and returns its
+// address.
+func (w *writer) writeHeader(msgs []message) uint64 {
+	var body []byte
+	for _, msg := range msgs {
+		body = append(body, byte(msg.typ))
+		body = binary.LittleEndian.AppendUint16(body, uint16(len(msg.data)))
+		body = append(body, msg.flags)
+		body = append(body, msg.data...)
+	}
+	// Flags select a 4-byte size for the first chunk of messages
+	b := append([]byte("OHDR"), 2, 0x02)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(body)))
+	b = append(b, body...)
+	b = binary.LittleEndian.AppendUint32(b, lookup3(b))
+	addr := w.off
+	w.write(b)
+	return addr
+}
+
+// linkMessage returns a message linking name to the object header at addr.
+func linkMessage(name string, addr uint64) message {
+	data := []byte{1, 0}
+	if len(name) < 256 {
+		data = append(data, byte(len(name)))
+	} else {
+		data[1] = 1 // 2-byte name length
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(name)))
+	}
+	data = append(data, name...)
+	data = binary.LittleEndian.AppendUint64(data, addr)
+	return message{typ: msgLink, data: data}
+}
+
+// datasetMessages returns the header messages of a dataset node.
+func datasetMessages(n *node) []message {
+	t := n.tensor
+	space := []byte{2, byte(len(t.Shape)), 0, 1}
+	if len(t.Shape) == 0 {
+		space[3] = 0 // Scalar
+	}
+	for _, d := range t.Shape {
+		space = binary.LittleEndian.AppendUint64(space, uint64(d))
+	}
+	dtype, _ := datatypeMessage(t.DType)
+
+	size := uint64(t.Shape.ElemCount() * t.DType.Size())
+	addr := n.addr
+	if size == 0 {
+		addr = undefinedAddr
+	}
+	layout := []byte{3, layoutContiguous}
+	layout = binary.LittleEndian.AppendUint64(layout, addr)
+	layout = binary.LittleEndian.AppendUint64(layout, size)
+
+	// Fill value: allocated late, written if set, and not defined
+	return []message{
+		{typ: msgDataspace, data: space},
+		{typ: msgDatatype, flags: 0x01, data: dtype},
+		{typ: msgFillValue, flags: 0x01, data: []byte{3, 0x0a}},
+		{typ: msgLayout, data: layout},
+	}
+}
+
+// datatypeMessage returns the little-endian datatype message for dtype.
+func datatypeMessage(dtype gonpy.DType) ([]byte, error) {
+	size := uint32(dtype.Size())
+	var b []byte
+	switch dtype {
+	case gonpy.DTypeI8, gonpy.DTypeI32, gonpy.DTypeI64:
+		b = fixedPoint(size, true)
+	case gonpy.DTypeU8, gonpy.DTypeU32, gonpy.DTypeU64:
+		b = fixedPoint(size, false)
+	case gonpy.DTypeBool:
+		b = []byte{0x10 | classEnum, 2, 0, 0}
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = append(b, fixedPoint(1, true)...)
+		b = append(b, "FALSE\x00\x00\x00TRUE\x00\x00\x00\x00"...)
+		b = append(b, 0, 1)
+	default:
+		p, ok := floatProperties[dtype]
+		if !ok {
+			return nil, fmt.Errorf("no HDF5 datatype for %s: %w", dtype, gonpy.ErrUnsupportedDType)
+		}
+		// Implied leading mantissa bit, and the sign bit above the exponent
+		b = []byte{0x10 | classFloat, 0x20, byte(p[1] - 1), 0}
+		b = binary.LittleEndian.AppendUint32(b, size)
+		b = binary.LittleEndian.AppendUint16(b, uint16(p[0]))
+		b = binary.LittleEndian.AppendUint16(b, uint16(p[1]))
+		b = append(b, byte(p[2]), byte(p[3]), byte(p[4]), byte(p[5]))
+		b = binary.LittleEndian.AppendUint32(b, p[6])
+	}
+	return b, nil
+}
+
+// fixedPoint returns the datatype message of a little-endian integer.
+func fixedPoint(size uint32, signed bool) []byte {
+	b := []byte{0x10 | classFixedPoint, 0, 0, 0}
+	if signed {
+		b[1] = 0x08
+	}
+	b = binary.LittleEndian.AppendUint32(b, size)
+	b = binary.LittleEndian.AppendUint16(b, 0)
+	return binary.LittleEndian.AppendUint16(b, uint16(8*size))
+}
@@ -0,0 +1,126 @@
+package gonpy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Index returns the position in row-major order of the element at idx, which
+// must give one in-range index per dimension.
+func (s Shape) Index(idx ...int) (int, error) {
+	if len(idx) != len(s) {
+		return 0, ErrorNpy{Msg: fmt.Sprintf("got %d indices for shape %v", len(idx), s)}
+	}
+	flat := 0
+	for d, i := range idx {
+		if i < 0 || i >= s[d] {
+			return 0, ErrorNpy{Msg: fmt.Sprintf("index %d out of range for dimension %d of size %d", i, d, s[d])}
+		}
+		flat = flat*s[d] + i
+	}
+	return flat, nil
+}
+
+// At returns the element at idx, one index per dimension, in the representation
+// used by Data: for example the uint16 bits of an F16 element. Elements of
+// packed 4-bit tensors are returned as int8 for I4 and uint8 for U4.
+func (t *Tensor) At(idx ...int) (interface{}, error) {
+	i, err := t.Shape.Index(idx...)
+	if err != nil {
+		return nil, err
+	}
+	return t.atFlat(i)
+}
+
+// atFlat returns element i of the flattened tensor.
+func (t *Tensor) atFlat(i int) (interface{}, error) {
+	if t.DType.isPacked() {
+		packed, ok := t.Data.([]byte)
+		if !ok || i/2 >= len(packed) {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not match its shape", t.DType)}
+		}
+		nibble := packed[i/2] >> (4 * (i % 2)) & 0x0f
+		if t.DType == DTypeI4 {
+			return int8(nibble<<4) >> 4, nil
+		}
+		return nibble, nil
+	}
+	data, err := t.elements()
+	if err != nil {
+		return nil, err
+	}
+	if i >= data.Len() {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not match its shape", t.DType)}
+	}
+	return data.Index(i).Interface(), nil
+}
+
+// Set stores v as the element at idx, one index per dimension. v must have the
+// type that At returns for the tensor's dtype.
+func (t *Tensor) Set(v interface{}, idx ...int) error {
+	i, err := t.Shape.Index(idx...)
+	if err != nil {
+		return err
+	}
+	return t.setFlat(v, i)
+}
+
+// setFlat stores v as element i of the flattened tensor.
+func (t *Tensor) setFlat(v interface{}, i int) error {
+	if t.DType.isPacked() {
+		return t.setPacked(v, i)
+	}
+	data, err := t.elements()
+	if err != nil {
+		return err
+	}
+	if i >= data.Len() {
+		return ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not match its shape", t.DType)}
+	}
+	val := reflect.ValueOf(v)
+	if !val.IsValid() || val.Type() != data.Type().Elem() {
+		return ErrorNpy{Msg: fmt.Sprintf("cannot set %T in %s tensor, expected %s", v, t.DType, data.Type().Elem())}
+	}
+	data.Index(i).Set(val)
+	return nil
+}
+
+// elements returns the tensor's data for element access. Structured tensors
+// are rejected, since their data holds raw records rather than one value per
+// element.
+func (t *Tensor) elements() (reflect.Value, error) {
+	if t.DType.isStructured() {
+		return reflect.Value{}, ErrorNpy{Msg: fmt.Sprintf("cannot index elements of structured dtype %s", t.DType)}
+	}
+	data := reflect.ValueOf(t.Data)
+	if data.Kind() != reflect.Slice {
+		return reflect.Value{}, ErrorNpy{Msg: fmt.Sprintf("%s tensor data is %T, not a slice", t.DType, t.Data)}
+	}
+	return data, nil
+}
+
+// setPacked stores v as element i of a packed 4-bit tensor.
+func (t *Tensor) setPacked(v interface{}, i int) error {
+	var nibble byte
+	switch x := v.(type) {
+	case int8:
+		if t.DType != DTypeI4 || x < -8 || x > 7 {
+			return ErrorNpy{Msg: fmt.Sprintf("cannot set int8 %d in %s tensor", x, t.DType)}
+		}
+		nibble = byte(x) & 0x0f
+	case uint8:
+		if t.DType != DTypeU4 || x > 15 {
+			return ErrorNpy{Msg: fmt.Sprintf("cannot set uint8 %d in %s tensor", x, t.DType)}
+		}
+		nibble = x
+	default:
+		return ErrorNpy{Msg: fmt.Sprintf("cannot set %T in %s tensor", v, t.DType)}
+	}
+	packed, ok := t.Data.([]byte)
+	if !ok || i/2 >= len(packed) {
+		return ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not match its shape", t.DType)}
+	}
+	shift := 4 * (i % 2)
+	packed[i/2] = packed[i/2]&^(0x0f<<shift) | nibble<<shift
+	return nil
+}
@@ -0,0 +1,128 @@
+package gonpy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShapeIndex(t *testing.T) {
+	tests := []struct {
+		shape Shape
+		idx   []int
+		want  int // -1 if the index is invalid
+	}{
+		{Shape{}, nil, 0},
+		{Shape{4}, []int{3}, 3},
+		{Shape{2, 3}, []int{1, 2}, 5},
+		{Shape{2, 3, 4}, []int{1, 0, 3}, 15},
+		{Shape{2, 3}, []int{1}, -1},
+		{Shape{2, 3}, []int{0, 0, 0}, -1},
+		{Shape{2, 3}, []int{2, 0}, -1},
+		{Shape{2, 3}, []int{0, 3}, -1},
+		{Shape{2, 3}, []int{-1, 0}, -1},
+		{Shape{0}, []int{0}, -1},
+	}
+	for _, tt := range tests {
+		got, err := tt.shape.Index(tt.idx...)
+		if tt.want < 0 {
+			if err == nil {
+				t.Errorf("%v.Index(%v) = %d, want an error", tt.shape, tt.idx, got)
+			}
+		} else if err != nil || got != tt.want {
+			t.Errorf("%v.Index(%v) = %d, %v, want %d", tt.shape, tt.idx, got, err, tt.want)
+		}
+	}
+}
+
+func TestAtSet(t *testing.T) {
+	tests := []struct {
+		t    *Tensor
+		idx  []int
+		v    interface{} // Value to set, of the type At returns
+		want string      // Data after the Set
+	}{
+		{&Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: Shape{2, 3}, DType: DTypeF32}, []int{1, 0}, float32(-1), "[1 2 3 -1 5 6]"},
+		{&Tensor{Data: []int64{7}, Shape: Shape{}, DType: DTypeI64}, nil, int64(8), "[8]"},
+		{&Tensor{Data: []bool{false, false}, Shape: Shape{2}, DType: DTypeBool}, []int{1}, true, "[false true]"},
+		{&Tensor{Data: []uint16{0x3c00, 0}, Shape: Shape{2}, DType: DTypeF16}, []int{0}, uint16(0x4000), "[16384 0]"},
+		{&Tensor{Data: []string{"ab", "c"}, Shape: Shape{2}, DType: UnicodeDType(2)}, []int{1}, "d", "[ab d]"},
+		{&Tensor{Data: []byte{0x21, 0x0f}, Shape: Shape{4}, DType: DTypeI4}, []int{2}, int8(-8), "[33 8]"},
+		{&Tensor{Data: []byte{0x21, 0x0f}, Shape: Shape{3}, DType: DTypeU4}, []int{1}, uint8(15), "[241 15]"},
+	}
+	for _, tt := range tests {
+		before, err := tt.t.At(tt.idx...)
+		if err != nil {
+			t.Fatalf("%s At(%v): %v", tt.t.DType, tt.idx, err)
+		}
+		if err := tt.t.Set(tt.v, tt.idx...); err != nil {
+			t.Fatalf("%s Set(%v, %v): %v", tt.t.DType, tt.v, tt.idx, err)
+		}
+		if got := fmt.Sprint(tt.t.Data); got != tt.want {
+			t.Errorf("%s: after Set(%v, %v) data is %s, want %s", tt.t.DType, tt.v, tt.idx, got, tt.want)
+		}
+		after, err := tt.t.At(tt.idx...)
+		if err != nil || after != tt.v {
+			t.Errorf("%s: At(%v) = %v (%T), %v after setting %v", tt.t.DType, tt.idx, after, after, err, tt.v)
+		}
+		if fmt.Sprintf("%T", before) != fmt.Sprintf("%T", tt.v) {
+			t.Errorf("%s: At returned %T, Set takes %T", tt.t.DType, before, tt.v)
+		}
+	}
+}
+
+func TestAtPacked(t *testing.T) {
+	i4 := &Tensor{Data: []byte{0x8f, 0x07}, Shape: Shape{3}, DType: DTypeI4}
+	u4 := &Tensor{Data: []byte{0x8f, 0x07}, Shape: Shape{3}, DType: DTypeU4}
+	var got []interface{}
+	for i := 0; i < 3; i++ {
+		a, err := i4.At(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := u4.At(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, a, b)
+	}
+	if fmt.Sprint(got) != "[-1 15 -8 8 7 7]" {
+		t.Errorf("packed elements %v", got)
+	}
+}
+
+func TestAtSetInvalid(t *testing.T) {
+	f32 := func() *Tensor { return &Tensor{Data: []float32{1, 2, 3, 4}, Shape: Shape{2, 2}, DType: DTypeF32} }
+	tests := []struct {
+		name string
+		t    *Tensor
+		v    interface{}
+		idx  []int
+		at   bool // At fails as well
+	}{
+		{"too few indices", f32(), float32(0), []int{1}, true},
+		{"too many indices", f32(), float32(0), []int{1, 1, 0}, true},
+		{"index out of range", f32(), float32(0), []int{0, 2}, true},
+		{"negative index", f32(), float32(0), []int{-1, 0}, true},
+		{"wrong value type", f32(), float64(0), []int{0, 0}, false},
+		{"nil value", f32(), nil, []int{0, 0}, false},
+		{"short data", &Tensor{Data: []float32{1}, Shape: Shape{2}, DType: DTypeF32}, float32(0), []int{1}, true},
+		{"not a slice", &Tensor{Data: 1.0, Shape: Shape{}, DType: DTypeF64}, 2.0, nil, true},
+		{"structured", &Tensor{Data: make([]byte, 8), Shape: Shape{2}, DType: "[('a', '<i4')]"}, []byte{0}, []int{0}, true},
+		{"i4 out of range", &Tensor{Data: []byte{0}, Shape: Shape{2}, DType: DTypeI4}, int8(8), []int{0}, false},
+		{"u4 out of range", &Tensor{Data: []byte{0}, Shape: Shape{2}, DType: DTypeU4}, uint8(16), []int{0}, false},
+		{"u4 value in i4", &Tensor{Data: []byte{0}, Shape: Shape{2}, DType: DTypeI4}, uint8(1), []int{0}, false},
+		{"short packed data", &Tensor{Data: []byte{0}, Shape: Shape{3}, DType: DTypeU4}, uint8(1), []int{2}, true},
+	}
+	for _, tt := range tests {
+		before := fmt.Sprint(tt.t.Data)
+		if err := tt.t.Set(tt.v, tt.idx...); err == nil {
+			t.Errorf("%s: Set succeeded", tt.name)
+		}
+		if after := fmt.Sprint(tt.t.Data); after != before {
+			t.Errorf("%s: failed Set changed data from %s to %s", tt.name, before, after)
+		}
+		if got, err := tt.t.At(tt.idx...); tt.at && err == nil {
+			t.Errorf("%s: At = %v", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// ReadNPYInto decodes an NPY file into the preallocated data of dst, avoiding
+// allocation of a new slice. The file must have the dtype and shape of dst; a
+// *MismatchError is returned otherwise. A nil dst.Shape is not checked, but
+// the element count must still match the length of dst.Data.
+func ReadNPYInto(path string, dst *Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readTensorInto(f, path, dst)
+}
+
+// GetInto decodes a named tensor from the NPZ file into the preallocated data
+// of dst, as ReadNPYInto does.
+func (n *NpzTensors) GetInto(name string, dst *Tensor) error {
+	file, err := n.file(name)
+	if err != nil {
+		return err
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r, verify := n.opts.verifyingReader(name, rc)
+	if err := n.readInto(r, name, dst); err != nil {
+		return err
+	}
+	return verify()
+}
+
+// readInto reads the entry for name from r into dst, restoring packed storage.
+func (n *NpzTensors) readInto(r io.Reader, name string, dst *Tensor) error {
+	if info, ok := n.packing[name]; ok {
+		if err := checkTensor(name, &Tensor{DType: info.DType, Shape: info.Shape}, dst.DType, dst.Shape); err != nil {
+			return err
+		}
+		if _, err := readSeekableHeader(r); err != nil {
+			return err
+		}
+		data, ok := dst.Data.([]byte)
+		if !ok || len(data) != (info.Shape.ElemCount()+1)/2 {
+			return ErrorNpy{Msg: fmt.Sprintf("%s destination data cannot hold %d packed values", name, info.Shape.ElemCount())}
+		}
+		_, err := io.ReadFull(r, data)
+		return err
+	}
+	return readTensorInto(r, name, dst)
+}
+
+// readTensorInto reads an NPY stream into dst after checking that they match.
+func readTensorInto(r io.Reader, name string, dst *Tensor) error {
+	header, err := readSeekableHeader(r)
+	if err != nil {
+		return err
+	}
+	if err := checkTensor(name, &Tensor{DType: header.Descr, Shape: header.Shape}, dst.DType, dst.Shape); err != nil {
+		return err
+	}
+
+	if err := checkBuffer(name, header.Descr, header.Shape, dst.Data); err != nil {
+		return err
+	}
+	return decodeInto(r, header.Descr, dst.Data)
+}
+
+// checkBuffer checks that data is a slice that can hold the elements of an
+// NPY tensor of dtype and shape.
+func checkBuffer(name string, dtype DType, shape Shape, data interface{}) error {
+	want, err := makeData(dtype, 0)
+	if err != nil {
+		return err
+	}
+	n := shape.ElemCount()
+	if dtype.isStructured() {
+		n *= dtype.Size() // Records are raw bytes
+	}
+	length := 0
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Slice {
+		length = v.Len()
+	}
+	if reflect.TypeOf(data) != reflect.TypeOf(want) || length != n {
+		return ErrorNpy{Msg: fmt.Sprintf("%s destination data is %T of length %d, expected %T of length %d",
+			name, data, length, want, n)}
+	}
+	return nil
+}
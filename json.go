@@ -0,0 +1,194 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// WithJSONValues makes EncodeJSON write the elements of a tensor as nested
+// JSON arrays of numbers, booleans, or strings under "values", instead of
+// base64-encoded data. Non-finite floats and complex numbers are written as
+// strings such as "NaN" and "(1+2i)".
+func WithJSONValues() Option {
+	return func(o *options) {
+		o.jsonValues = true
+	}
+}
+
+// tensorJSON is the JSON form of a tensor.
+type tensorJSON struct {
+	DType  DType           `json:"dtype"`
+	Shape  Shape           `json:"shape"`
+	Data   *string         `json:"data,omitempty"`
+	Values json.RawMessage `json:"values,omitempty"`
+}
+
+// MarshalJSON encodes the tensor as a JSON object with its dtype, shape, and
+// little-endian data in base64, as EncodeJSON does by default.
+func (t *Tensor) MarshalJSON() ([]byte, error) {
+	return t.EncodeJSON()
+}
+
+// EncodeJSON encodes the tensor as a JSON object such as
+// {"dtype":"f32","shape":[2],"data":"AACAPwAAAEA="}, where data holds the
+// elements as they are stored in NPY files. With WithJSONValues, the elements
+// are written as readable values instead: {"dtype":"f32","shape":[2],"values":[1,2]}.
+func (t *Tensor) EncodeJSON(opts ...Option) ([]byte, error) {
+	o := newOptions(opts)
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkData(); err != nil && !t.DType.isPacked() {
+		return nil, err
+	}
+	out := tensorJSON{DType: t.DType, Shape: t.Shape}
+	if out.Shape == nil {
+		out.Shape = Shape{}
+	}
+	if !o.jsonValues {
+		var buf bytes.Buffer
+		if err := writeData(&buf, t.DType, t.Data); err != nil {
+			return nil, err
+		}
+		data := base64.StdEncoding.EncodeToString(buf.Bytes())
+		out.Data = &data
+		return json.Marshal(out)
+	}
+
+	if !csvDType(t.DType) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot write %s tensor as JSON values", t.DType), Err: ErrUnsupportedDType}
+	}
+	var values []byte
+	var i int
+	var encode func(dim int)
+	encode = func(dim int) {
+		if dim == len(t.Shape) {
+			values = t.appendJSONElement(values, i)
+			i++
+			return
+		}
+		values = append(values, '[')
+		for k := 0; k < t.Shape[dim]; k++ {
+			if k > 0 {
+				values = append(values, ',')
+			}
+			encode(dim + 1)
+		}
+		values = append(values, ']')
+	}
+	encode(0)
+	out.Values = values
+	return json.Marshal(out)
+}
+
+// appendJSONElement appends element i of t as a JSON value.
+func (t *Tensor) appendJSONElement(b []byte, i int) []byte {
+	s := t.formatElement(i)
+	switch t.Data.(type) {
+	case []string, []complex64, []complex128:
+		return strconv.AppendQuote(b, s)
+	}
+	if s == "NaN" || s == "+Inf" || s == "-Inf" {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+// UnmarshalJSON decodes a tensor encoded by EncodeJSON, with either base64
+// data or values.
+func (t *Tensor) UnmarshalJSON(b []byte) error {
+	var in tensorJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	if _, err := in.Shape.CheckedElemCount(); err != nil {
+		return err
+	}
+
+	var result *Tensor
+	switch {
+	case in.Data != nil:
+		raw, err := base64.StdEncoding.DecodeString(*in.Data)
+		if err != nil {
+			return ErrorNpy{Msg: fmt.Sprintf("tensor data: %v", err)}
+		}
+		want, ok := dataSize(in.DType, in.Shape)
+		if in.DType.isPacked() {
+			want = (want + 1) / 2 // Two elements per byte
+		}
+		if !ok || int64(len(raw)) != want {
+			return ErrorNpy{Msg: fmt.Sprintf("tensor data has %d bytes, %s tensor of shape %v needs %d", len(raw), in.DType, in.Shape, want)}
+		}
+		if result, err = Zeros(in.DType, in.Shape); err != nil {
+			return err
+		}
+		if in.DType.isPacked() {
+			result.Data = raw
+		} else if result.Data, err = readData(in.Shape, in.DType, bytes.NewReader(raw)); err != nil {
+			return err
+		}
+	case in.Values != nil:
+		if !csvDType(in.DType) {
+			return ErrorNpy{Msg: fmt.Sprintf("cannot read %s tensor from JSON values", in.DType), Err: ErrUnsupportedDType}
+		}
+		d := json.NewDecoder(bytes.NewReader(in.Values))
+		d.UseNumber()
+		var values interface{}
+		if err := d.Decode(&values); err != nil {
+			return err
+		}
+		leaves, err := flattenJSON(values, in.Shape, nil)
+		if err != nil {
+			return err
+		}
+		if result, err = Zeros(in.DType, in.Shape); err != nil {
+			return err
+		}
+		for i, v := range leaves {
+			var s string
+			switch v := v.(type) {
+			case json.Number:
+				s = string(v)
+			case string:
+				s = v
+			case bool:
+				s = strconv.FormatBool(v)
+			default:
+				return ErrorNpy{Msg: fmt.Sprintf("tensor value %d is %v", i, v)}
+			}
+			if err := result.parseElement(i, s); err != nil {
+				return ErrorNpy{Msg: fmt.Sprintf("tensor value %d: %v", i, err)}
+			}
+		}
+	default:
+		return ErrorNpy{Msg: "tensor JSON has neither data nor values"}
+	}
+	*t = *result
+	return nil
+}
+
+// flattenJSON appends the leaves of nested JSON arrays to leaves, checking
+// that the nesting matches shape.
+func flattenJSON(v interface{}, shape Shape, leaves []interface{}) ([]interface{}, error) {
+	if len(shape) == 0 {
+		if _, ok := v.([]interface{}); ok {
+			return nil, ErrorNpy{Msg: "tensor values are nested deeper than the shape"}
+		}
+		return append(leaves, v), nil
+	}
+	list, ok := v.([]interface{})
+	if !ok || len(list) != shape[0] {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("tensor values do not match shape %v", shape)}
+	}
+	var err error
+	for _, item := range list {
+		if leaves, err = flattenJSON(item, shape[1:], leaves); err != nil {
+			return nil, err
+		}
+	}
+	return leaves, nil
+}
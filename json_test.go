@@ -0,0 +1,73 @@
+package gonpy
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	packed, err := PackUint4([]uint8{1, 15, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []*Tensor{
+		{Data: []float32{1.5, float32(math.NaN()), float32(math.Inf(-1)), 4}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"},
+		{Data: []complex128{1 + 2i}, Shape: Shape{}, DType: DTypeC128, Device: "cpu"},
+		{Data: []string{"a", "\"b\""}, Shape: Shape{2}, DType: UnicodeDType(3), Device: "cpu"},
+		{Data: []uint64{math.MaxUint64, 0}, Shape: Shape{2, 1}, DType: DTypeU64, Device: "cpu"},
+		{Data: []uint16{0x3c00}, Shape: Shape{1}, DType: DTypeF16, Device: "cpu"},
+		{Data: packed, Shape: Shape{3}, DType: DTypeU4, Device: "cpu"},
+	}
+	for _, in := range tests {
+		for _, values := range []bool{false, true} {
+			if values && in.DType.isPacked() {
+				continue
+			}
+			var b []byte
+			var err error
+			if values {
+				b, err = in.EncodeJSON(WithJSONValues())
+			} else {
+				b, err = json.Marshal(in)
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", in.DType, err)
+			}
+			var out Tensor
+			if err := json.Unmarshal(b, &out); err != nil {
+				t.Fatalf("%s: %s: %v", in.DType, b, err)
+			}
+			again, err := json.Marshal(&out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := json.Marshal(in)
+			if out.DType != in.DType || !out.Shape.Equal(in.Shape) || string(again) != string(want) {
+				t.Errorf("%s decoded as %s", b, again)
+			}
+		}
+	}
+}
+
+func TestHostileJSON(t *testing.T) {
+	tests := map[string]string{
+		"short data":     `{"dtype":"f32","shape":[2],"data":"AACAPw=="}`,
+		"huge shape":     `{"dtype":"f32","shape":[4611686018427387904,4],"data":""}`,
+		"negative shape": `{"dtype":"f32","shape":[-1],"values":[]}`,
+		"huge values":    `{"dtype":"u8","shape":[1099511627776],"values":[1]}`,
+		"deep values":    `{"dtype":"u8","shape":[1],"values":[[1]]}`,
+		"shallow values": `{"dtype":"u8","shape":[1,1],"values":[1]}`,
+		"value type":     `{"dtype":"u8","shape":[1],"values":[{}]}`,
+		"value range":    `{"dtype":"u8","shape":[1],"values":[256]}`,
+		"bad base64":     `{"dtype":"u8","shape":[1],"data":"!"}`,
+		"dtype":          `{"dtype":"q7","shape":[1],"data":"AA=="}`,
+		"empty":          `{"dtype":"u8","shape":[1]}`,
+	}
+	for name, s := range tests {
+		var out Tensor
+		if err := json.Unmarshal([]byte(s), &out); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
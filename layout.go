@@ -0,0 +1,228 @@
+package gonpy
+
+import "fmt"
+
+// Reshape returns a tensor with the same elements as t and the given shape,
+// sharing t's data. One dimension may be -1, in which case it is inferred
+// from the element count, which must not change.
+func (t *Tensor) Reshape(shape ...int) (*Tensor, error) {
+	n := t.Shape.ElemCount()
+	newShape := append(Shape(nil), shape...)
+	infer := -1
+	known := 1
+	for i, dim := range newShape {
+		switch {
+		case dim == -1 && infer < 0:
+			infer = i
+		case dim < 0:
+			return nil, ErrorNpy{Msg: fmt.Sprintf("invalid shape %v", shape)}
+		default:
+			known *= dim
+		}
+	}
+	if infer >= 0 {
+		if known == 0 || n%known != 0 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("cannot reshape %v into %v", t.Shape, shape)}
+		}
+		newShape[infer] = n / known
+	}
+	if newShape.ElemCount() != n {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot reshape %v into %v", t.Shape, shape)}
+	}
+	return &Tensor{Data: t.Data, Shape: newShape, DType: t.DType, Device: t.Device}, nil
+}
+
+// Squeeze returns t without the given dimensions of size 1, or without all
+// of them if no axes are given. The data is shared.
+func (t *Tensor) Squeeze(axes ...int) (*Tensor, error) {
+	drop := make([]bool, len(t.Shape))
+	for _, axis := range axes {
+		a, err := normalizeAxis(axis, len(t.Shape))
+		if err != nil {
+			return nil, err
+		}
+		if t.Shape[a] != 1 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("cannot squeeze dimension %d of size %d", axis, t.Shape[a])}
+		}
+		drop[a] = true
+	}
+	shape := Shape{}
+	for i, dim := range t.Shape {
+		if drop[i] || (len(axes) == 0 && dim == 1) {
+			continue
+		}
+		shape = append(shape, dim)
+	}
+	return &Tensor{Data: t.Data, Shape: shape, DType: t.DType, Device: t.Device}, nil
+}
+
+// Unsqueeze returns t with a dimension of size 1 inserted at axis, which may
+// be negative to count from the end of the new shape. The data is shared.
+func (t *Tensor) Unsqueeze(axis int) (*Tensor, error) {
+	a, err := normalizeAxis(axis, len(t.Shape)+1)
+	if err != nil {
+		return nil, err
+	}
+	shape := make(Shape, 0, len(t.Shape)+1)
+	shape = append(shape, t.Shape[:a]...)
+	shape = append(shape, 1)
+	shape = append(shape, t.Shape[a:]...)
+	return &Tensor{Data: t.Data, Shape: shape, DType: t.DType, Device: t.Device}, nil
+}
+
+// Transpose returns a copy of t with its dimensions permuted: dimension i of
+// the result is dimension axes[i] of t. Without axes, the order of the
+// dimensions is reversed. The elements are moved so that the result is in
+// row-major order like every other tensor.
+func (t *Tensor) Transpose(axes ...int) (*Tensor, error) {
+	perm, err := permutation(axes, len(t.Shape))
+	if err != nil {
+		return nil, err
+	}
+	shape := make(Shape, len(perm))
+	for i, a := range perm {
+		shape[i] = t.Shape[a]
+	}
+	if err := t.checkLength(); err != nil {
+		return nil, err
+	}
+	strides := rowMajorStrides(t.Shape)
+	steps := make([]int, len(perm))
+	for i, a := range perm {
+		steps[i] = strides[a]
+	}
+	data, err := t.gather(shape, steps, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{Data: data, Shape: shape, DType: t.DType, Device: t.Device}, nil
+}
+
+// checkLength verifies that the tensor's data holds as many elements as its
+// shape, counting the nibbles of packed tensors.
+func (t *Tensor) checkLength() error {
+	if !t.DType.isPacked() {
+		return t.checkData()
+	}
+	if packed, ok := t.Data.([]byte); !ok || len(packed) != (t.Shape.ElemCount()+1)/2 {
+		return ErrorNpy{Msg: fmt.Sprintf("%s tensor data does not match its shape", t.DType)}
+	}
+	return nil
+}
+
+// gather returns new data holding, in row-major order, the elements of an
+// array of shape dims laid out in t's data from element offset with the given
+// strides.
+func (t *Tensor) gather(dims Shape, strides []int, offset int) (interface{}, error) {
+	switch d := t.Data.(type) {
+	case []uint16:
+		return gather(d, dims, strides, offset, 1), nil
+	case []float32:
+		return gather(d, dims, strides, offset, 1), nil
+	case []float64:
+		return gather(d, dims, strides, offset, 1), nil
+	case []complex64:
+		return gather(d, dims, strides, offset, 1), nil
+	case []complex128:
+		return gather(d, dims, strides, offset, 1), nil
+	case []int32:
+		return gather(d, dims, strides, offset, 1), nil
+	case []int64:
+		return gather(d, dims, strides, offset, 1), nil
+	case []uint32:
+		return gather(d, dims, strides, offset, 1), nil
+	case []uint64:
+		return gather(d, dims, strides, offset, 1), nil
+	case []bool:
+		return gather(d, dims, strides, offset, 1), nil
+	case []int8:
+		return gather(d, dims, strides, offset, 1), nil
+	case []string:
+		return gather(d, dims, strides, offset, 1), nil
+	case []byte: // U8, packed, or structured records
+		n := t.Shape.ElemCount()
+		switch t.DType {
+		case DTypeI4:
+			return PackInt4(gather(UnpackInt4(d, n), dims, strides, offset, 1))
+		case DTypeU4:
+			return PackUint4(gather(UnpackUint4(d, n), dims, strides, offset, 1))
+		}
+		return gather(d, dims, strides, offset, max(1, t.DType.Size())), nil
+	}
+	return nil, ErrorNpy{Msg: fmt.Sprintf("cannot copy %s tensor data of type %T", t.DType, t.Data)}
+}
+
+// normalizeAxis resolves a possibly negative axis of a shape with ndim dimensions.
+func normalizeAxis(axis, ndim int) (int, error) {
+	if axis < -ndim || axis >= ndim {
+		return 0, ErrorNpy{Msg: fmt.Sprintf("axis %d out of range for %d dimensions", axis, ndim)}
+	}
+	if axis < 0 {
+		axis += ndim
+	}
+	return axis, nil
+}
+
+// permutation validates axes as a permutation of ndim dimensions, returning
+// the reversed order if axes is empty.
+func permutation(axes []int, ndim int) ([]int, error) {
+	if len(axes) == 0 {
+		perm := make([]int, ndim)
+		for i := range perm {
+			perm[i] = ndim - 1 - i
+		}
+		return perm, nil
+	}
+	if len(axes) != ndim {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("got %d axes for %d dimensions", len(axes), ndim)}
+	}
+	perm := make([]int, ndim)
+	seen := make([]bool, ndim)
+	for i, axis := range axes {
+		a, err := normalizeAxis(axis, ndim)
+		if err != nil {
+			return nil, err
+		}
+		if seen[a] {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("repeated axis %d in %v", axis, axes)}
+		}
+		seen[a] = true
+		perm[i] = a
+	}
+	return perm, nil
+}
+
+// rowMajorStrides returns the distance in elements between neighbours along
+// each dimension of a row-major array of the given shape.
+func rowMajorStrides(shape Shape) []int {
+	strides := make([]int, len(shape))
+	stride := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		strides[i] = stride
+		stride *= shape[i]
+	}
+	return strides
+}
+
+// gather copies, in row-major order, the elements of an array of shape dims
+// laid out in src from element offset with the given strides. Each element
+// is stored as size values of src.
+func gather[T any](src []T, dims, strides []int, offset, size int) []T {
+	n := Shape(dims).ElemCount()
+	dst := make([]T, n*size)
+	idx := make([]int, len(dims))
+	s := offset
+	for d := 0; d < n; d++ {
+		copy(dst[d*size:(d+1)*size], src[s*size:(s+1)*size])
+		for k := len(dims) - 1; k >= 0; k-- {
+			idx[k]++
+			s += strides[k]
+			if idx[k] < dims[k] {
+				break
+			}
+			s -= strides[k] * dims[k]
+			idx[k] = 0
+		}
+	}
+	return dst
+}
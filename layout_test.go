@@ -0,0 +1,134 @@
+package gonpy
+
+import (
+	"fmt"
+	"testing"
+)
+
+// arange returns a float32 tensor of the given shape holding 0, 1, 2, ...
+func arange(shape ...int) *Tensor {
+	data := make([]float32, Shape(shape).ElemCount())
+	for i := range data {
+		data[i] = float32(i)
+	}
+	return &Tensor{Data: data, Shape: shape, DType: DTypeF32, Device: "cpu"}
+}
+
+func TestReshape(t *testing.T) {
+	x := arange(2, 3, 4)
+	tests := []struct {
+		shape []int
+		want  Shape
+	}{
+		{[]int{6, 4}, Shape{6, 4}},
+		{[]int{-1, 4}, Shape{6, 4}},
+		{[]int{2, -1, 2}, Shape{2, 6, 2}},
+		{[]int{-1}, Shape{24}},
+		{[]int{24, 1, -1}, Shape{24, 1, 1}},
+	}
+	for _, tt := range tests {
+		y, err := x.Reshape(tt.shape...)
+		if err != nil {
+			t.Errorf("Reshape%v: %v", tt.shape, err)
+			continue
+		}
+		if !y.Shape.Equal(tt.want) || fmt.Sprint(y.Data) != fmt.Sprint(x.Data) {
+			t.Errorf("Reshape%v = %v", tt.shape, y.Shape)
+		}
+	}
+	if y, err := arange(0, 3).Reshape(-1, 3); err != nil || !y.Shape.Equal(Shape{0, 3}) {
+		t.Errorf("Reshape of an empty tensor = %v, %v", y, err)
+	}
+
+	invalid := [][]int{{5, 5}, {-1, 5}, {-1, -1}, {-2, 12}, {0, -1}, {25}, {}}
+	for _, shape := range invalid {
+		if y, err := x.Reshape(shape...); err == nil {
+			t.Errorf("Reshape%v = %v, want an error", shape, y.Shape)
+		}
+	}
+}
+
+func TestSqueezeUnsqueeze(t *testing.T) {
+	x := arange(1, 3, 1)
+	tests := []struct {
+		axes []int
+		want Shape
+	}{
+		{nil, Shape{3}},
+		{[]int{0}, Shape{3, 1}},
+		{[]int{-1}, Shape{1, 3}},
+		{[]int{0, 2}, Shape{3}},
+	}
+	for _, tt := range tests {
+		y, err := x.Squeeze(tt.axes...)
+		if err != nil || !y.Shape.Equal(tt.want) {
+			t.Errorf("Squeeze%v = %v, %v; want %v", tt.axes, y, err, tt.want)
+		}
+	}
+	for _, axes := range [][]int{{1}, {3}, {-4}} {
+		if _, err := x.Squeeze(axes...); err == nil {
+			t.Errorf("Squeeze%v accepted", axes)
+		}
+	}
+
+	y := arange(2, 3)
+	for axis, want := range map[int]Shape{0: {1, 2, 3}, 2: {2, 3, 1}, -1: {2, 3, 1}, -3: {1, 2, 3}} {
+		z, err := y.Unsqueeze(axis)
+		if err != nil || !z.Shape.Equal(want) {
+			t.Errorf("Unsqueeze(%d) = %v, %v; want %v", axis, z, err, want)
+		}
+	}
+	for _, axis := range []int{3, -4} {
+		if _, err := y.Unsqueeze(axis); err == nil {
+			t.Errorf("Unsqueeze(%d) accepted", axis)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	x := arange(2, 3, 4)
+	tests := []struct {
+		axes  []int
+		shape Shape
+		want  string
+	}{
+		{nil, Shape{4, 3, 2}, "[0 12 4 16 8 20 1 13 5 17 9 21 2 14 6 18 10 22 3 15 7 19 11 23]"},
+		{[]int{0, 2, 1}, Shape{2, 4, 3}, "[0 4 8 1 5 9 2 6 10 3 7 11 12 16 20 13 17 21 14 18 22 15 19 23]"},
+		{[]int{1, 0, -1}, Shape{3, 2, 4}, "[0 1 2 3 12 13 14 15 4 5 6 7 16 17 18 19 8 9 10 11 20 21 22 23]"},
+		{[]int{0, 1, 2}, Shape{2, 3, 4}, fmt.Sprint(x.Data)},
+	}
+	for _, tt := range tests {
+		y, err := x.Transpose(tt.axes...)
+		if err != nil {
+			t.Errorf("Transpose%v: %v", tt.axes, err)
+			continue
+		}
+		if !y.Shape.Equal(tt.shape) || fmt.Sprint(y.Data) != tt.want {
+			t.Errorf("Transpose%v = %v %v, want %v %s", tt.axes, y.Shape, y.Data, tt.shape, tt.want)
+		}
+	}
+
+	// Packed tensors are transposed nibble by nibble
+	packed, err := PackInt4([]int8{1, 2, 3, -4, -5, -6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := &Tensor{Data: packed, Shape: Shape{2, 3}, DType: DTypeI4, Device: "cpu"}
+	y, err := q.Transpose()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := UnpackInt4(y.Data.([]byte), 6); fmt.Sprint(got) != "[1 -4 2 -5 3 -6]" {
+		t.Errorf("transposed int4 = %v", got)
+	}
+
+	for _, axes := range [][]int{{0, 1}, {0, 1, 1}, {0, 1, 3}, {0, 1, -4}} {
+		if _, err := x.Transpose(axes...); err == nil {
+			t.Errorf("Transpose%v accepted", axes)
+		}
+	}
+	short := &Tensor{Data: []float32{1}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"}
+	if _, err := short.Transpose(); err == nil {
+		t.Error("Transpose of a tensor with short data accepted")
+	}
+}
@@ -0,0 +1,51 @@
+package gonpy
+
+import "sync/atomic"
+
+// FileLimiter bounds the number of files held open at once by read operations.
+// It is a counting semaphore; a nil *FileLimiter imposes no limit.
+type FileLimiter struct {
+	sem chan struct{}
+}
+
+// NewFileLimiter returns a limiter allowing at most n files open at once.
+// A non-positive n returns nil, meaning no limit.
+func NewFileLimiter(n int) *FileLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &FileLimiter{sem: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a file slot is available.
+func (l *FileLimiter) Acquire() {
+	if l != nil {
+		l.sem <- struct{}{}
+	}
+}
+
+// Release returns a slot obtained with Acquire.
+func (l *FileLimiter) Release() {
+	if l != nil {
+		<-l.sem
+	}
+}
+
+// defaultLimiter is the package-wide limiter used when no per-call limiter is given.
+var defaultLimiter atomic.Pointer[FileLimiter]
+
+// SetMaxOpenFiles sets the package-wide limit on files held open at once by
+// read operations, including NpzTensors and parallel loaders. A non-positive
+// n removes the limit. Operations already in flight keep the limiter they
+// started with.
+func SetMaxOpenFiles(n int) {
+	defaultLimiter.Store(NewFileLimiter(n))
+}
+
+// WithFileLimiter overrides the package-wide file limiter for a single call.
+// Passing nil disables limiting for that call.
+func WithFileLimiter(l *FileLimiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
@@ -0,0 +1,162 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLimitFiles writes a valid NPY, NPZ and safetensors file to dir and
+// returns their paths.
+func writeLimitFiles(t *testing.T, dir string) (npy, npz, st string) {
+	t.Helper()
+	x := &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"}
+	npy, npz, st = filepath.Join(dir, "a.npy"), filepath.Join(dir, "a.npz"), filepath.Join(dir, "a.safetensors")
+	if err := x.WriteNPY(npy); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteNPZ(npz, map[string]*Tensor{"x": x}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSafetensors(st, map[string]*Tensor{"x": x}); err != nil {
+		t.Fatal(err)
+	}
+	return npy, npz, st
+}
+
+// limitOpeners opens each kind of file that holds a limiter slot until Close.
+func limitOpeners(npy, npz, st string) map[string]func(opts ...Option) (interface{ Close() error }, error) {
+	return map[string]func(opts ...Option) (interface{ Close() error }, error){
+		"OpenNPY":         func(opts ...Option) (interface{ Close() error }, error) { return OpenNPY(npy, opts...) },
+		"NewNpzTensors":   func(opts ...Option) (interface{ Close() error }, error) { return NewNpzTensors(npz, opts...) },
+		"OpenSafetensors": func(opts ...Option) (interface{ Close() error }, error) { return OpenSafetensors(st, opts...) },
+	}
+}
+
+func TestFileLimiterBlocks(t *testing.T) {
+	npy, npz, st := writeLimitFiles(t, t.TempDir())
+	for name, open := range limitOpeners(npy, npz, st) {
+		l := NewFileLimiter(2)
+		var held []interface{ Close() error }
+		for i := 0; i < 2; i++ {
+			f, err := open(WithFileLimiter(l))
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			held = append(held, f)
+		}
+
+		opened := make(chan interface{ Close() error })
+		go func() {
+			f, err := open(WithFileLimiter(l))
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			opened <- f
+		}()
+		select {
+		case <-opened:
+			t.Fatalf("%s: third open did not wait for a slot", name)
+		case <-time.After(50 * time.Millisecond):
+		}
+		// Calls with their own limiter are not held up
+		if _, err := ReadNPY(npy, WithFileLimiter(nil)); err != nil {
+			t.Fatalf("%s: unlimited read: %v", name, err)
+		}
+
+		if err := held[0].Close(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case f := <-opened:
+			held[0] = f
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: open still blocked after Close", name)
+		}
+		for _, f := range held {
+			if err := f.Close(); err != nil {
+				t.Error(err)
+			}
+		}
+		if n := len(l.sem); n != 0 {
+			t.Errorf("%s: %d slots held after every file was closed", name, n)
+		}
+	}
+}
+
+func TestFileLimiterErrorPaths(t *testing.T) {
+	dir := t.TempDir()
+	npy, npz, _ := writeLimitFiles(t, dir)
+	missing := filepath.Join(dir, "missing")
+	write := func(name string, b []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	good, err := os.ReadFile(npy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncatedNPY := write("truncated.npy", good[:len(good)-1])
+	badMagic := write("magic.npy", append([]byte("NUMPY!"), good[6:]...))
+	notZip := write("not.npz", good)
+	dupNPZ := filepath.Join(dir, "dup.npz")
+	writeZipEntries(t, dupNPZ, "x.npy", string(good), "x.npy", string(good))
+	unsafeNPZ := filepath.Join(dir, "unsafe.npz")
+	writeZipEntries(t, unsafeNPZ, "../x.npy", string(good))
+	badPacking := filepath.Join(dir, "packing.npz")
+	writeZipEntries(t, badPacking, "x.npy", string(good), packingEntry, "{")
+	badSums := filepath.Join(dir, "sums.npz")
+	writeZipEntries(t, badSums, "x.npy", string(good), checksumEntry, "{")
+	var huge bytes.Buffer
+	binary.Write(&huge, binary.LittleEndian, uint64(1<<40))
+	hugeHeader := write("huge.safetensors", huge.Bytes())
+	badJSON := write("json.safetensors", append(binary.LittleEndian.AppendUint64(nil, 2), "{["...))
+
+	failures := map[string]func(opts ...Option) error{
+		"OpenNPY missing":         func(opts ...Option) error { _, err := OpenNPY(missing, opts...); return err },
+		"OpenNPY truncated":       func(opts ...Option) error { _, err := OpenNPY(truncatedNPY, opts...); return err },
+		"OpenNPY magic":           func(opts ...Option) error { _, err := OpenNPY(badMagic, opts...); return err },
+		"NewNpzTensors missing":   func(opts ...Option) error { _, err := NewNpzTensors(missing, opts...); return err },
+		"NewNpzTensors not zip":   func(opts ...Option) error { _, err := NewNpzTensors(notZip, opts...); return err },
+		"NewNpzTensors duplicate": func(opts ...Option) error { _, err := NewNpzTensors(dupNPZ, opts...); return err },
+		"NewNpzTensors unsafe":    func(opts ...Option) error { _, err := NewNpzTensors(unsafeNPZ, opts...); return err },
+		"NewNpzTensors packing":   func(opts ...Option) error { _, err := NewNpzTensors(badPacking, opts...); return err },
+		"NewNpzTensors checksums": func(opts ...Option) error {
+			_, err := NewNpzTensors(badSums, append(opts, WithVerifyChecksums())...)
+			return err
+		},
+		"OpenSafetensors missing": func(opts ...Option) error { _, err := OpenSafetensors(missing, opts...); return err },
+		"OpenSafetensors header":  func(opts ...Option) error { _, err := OpenSafetensors(hugeHeader, opts...); return err },
+		"OpenSafetensors json":    func(opts ...Option) error { _, err := OpenSafetensors(badJSON, opts...); return err },
+		"OpenSafetensors npz":     func(opts ...Option) error { _, err := OpenSafetensors(npz, opts...); return err },
+		"ReadNPY":                 func(opts ...Option) error { _, err := ReadNPY(truncatedNPY, opts...); return err },
+		"ReadNPZ":                 func(opts ...Option) error { _, err := ReadNPZ(dupNPZ, opts...); return err },
+		"ReadSafetensors":         func(opts ...Option) error { _, err := ReadSafetensors(badJSON, opts...); return err },
+	}
+	for name, fail := range failures {
+		l := NewFileLimiter(1)
+		if err := fail(WithFileLimiter(l)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+		if n := len(l.sem); n != 0 {
+			t.Errorf("%s: failure kept %d limiter slots", name, n)
+		}
+	}
+}
+
+func TestNewFileLimiterUnlimited(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		if l := NewFileLimiter(n); l != nil {
+			t.Errorf("NewFileLimiter(%d) = %v, want nil", n, l)
+		}
+	}
+	var l *FileLimiter
+	l.Acquire() // A nil limiter never blocks
+	l.Release()
+}
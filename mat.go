@@ -0,0 +1,358 @@
+package gonpy
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// A Level 5 MAT-file is a 128-byte header followed by data elements. Each
+// element has a tag holding its data type and size, then its data padded to
+// 8 bytes; a small element packs both into 8 bytes. A variable is an
+// miMATRIX element, possibly inside an miCOMPRESSED element holding zlib data.
+
+// MAT-file data types.
+const (
+	miINT8       = 1
+	miUINT8      = 2
+	miINT16      = 3
+	miUINT16     = 4
+	miINT32      = 5
+	miUINT32     = 6
+	miSINGLE     = 7
+	miDOUBLE     = 9
+	miINT64      = 12
+	miUINT64     = 13
+	miMATRIX     = 14
+	miCOMPRESSED = 15
+)
+
+// matTypes maps MAT-file numeric data types to the dtypes that hold them.
+// 16-bit integers are widened, since there is no 16-bit integer dtype.
+var matTypes = map[uint32]DType{
+	miINT8:   DTypeI8,
+	miUINT8:  DTypeU8,
+	miINT16:  DTypeI32,
+	miUINT16: DTypeU32,
+	miINT32:  DTypeI32,
+	miUINT32: DTypeU32,
+	miSINGLE: DTypeF32,
+	miDOUBLE: DTypeF64,
+	miINT64:  DTypeI64,
+	miUINT64: DTypeU64,
+}
+
+// matClasses maps the numeric MATLAB array classes, mxDOUBLE_CLASS through
+// mxUINT64_CLASS, to dtypes.
+var matClasses = map[byte]DType{
+	6:  DTypeF64,
+	7:  DTypeF32,
+	8:  DTypeI8,
+	9:  DTypeU8,
+	10: DTypeI32,
+	11: DTypeU32,
+	12: DTypeI32,
+	13: DTypeU32,
+	14: DTypeI64,
+	15: DTypeU64,
+}
+
+// Array flags of an miMATRIX element.
+const (
+	matComplex = 0x0800
+	matLogical = 0x0200
+)
+
+// ReadMAT reads the numeric and logical arrays of a Level 5 MAT-file, as
+// saved by MATLAB up to -v7 or by scipy.io.savemat, keyed by variable name.
+// Arrays are converted from MATLAB's column-major layout, so a 2x3 matrix
+// becomes a tensor of shape [2 3] with the same element at each index.
+// Complex arrays become C64 or C128 tensors, logical arrays Bool tensors, and
+// 16-bit integers are widened to 32 bits. Char, cell, struct, object, and
+// sparse arrays are skipped. MAT-files saved with -v7.3 are HDF5 files and
+// can be read with the hdf5 package.
+func ReadMAT(path string, opts ...Option) (map[string]*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(o.cancelableReader(f))
+	var header [128]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, locate(ErrorNpy{Msg: "not a MAT-file", Err: ErrBadMagic}, path, "")
+		}
+		return nil, err
+	}
+	if strings.HasPrefix(string(header[:]), "MATLAB 7.3") {
+		return nil, locate(ErrorNpy{Msg: "MAT-file version 7.3 is HDF5; read it with the hdf5 package", Err: ErrUnsupportedVersion}, path, "")
+	}
+	var order binary.ByteOrder
+	switch string(header[126:]) {
+	case "IM":
+		order = binary.LittleEndian
+	case "MI":
+		order = binary.BigEndian
+	default:
+		return nil, locate(ErrorNpy{Msg: "not a Level 5 MAT-file", Err: ErrBadMagic}, path, "")
+	}
+	if v := order.Uint16(header[124:]); v != 0x0100 {
+		return nil, locate(ErrorNpy{Msg: fmt.Sprintf("MAT-file version %#x", v), Err: ErrUnsupportedVersion}, path, "")
+	}
+
+	m := &matReader{order: order, o: o}
+	tensors := make(map[string]*Tensor)
+	for {
+		typ, data, err := m.element(r, true)
+		if errors.Is(err, io.EOF) {
+			return tensors, nil
+		}
+		if err != nil {
+			return nil, locate(err, path, "")
+		}
+		if typ == miCOMPRESSED {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, locate(ErrorNpy{Msg: fmt.Sprintf("compressed MAT-file element: %v", err)}, path, "")
+			}
+			typ, data, err = m.element(bufio.NewReader(zr), false)
+			if err != nil {
+				return nil, locate(err, path, "")
+			}
+		}
+		if typ != miMATRIX {
+			continue
+		}
+		name, t, err := m.matrix(data)
+		if err != nil {
+			return nil, locate(err, path, name)
+		}
+		if t != nil {
+			tensors[name] = t
+		}
+	}
+}
+
+// matReader decodes the data elements of a MAT-file.
+type matReader struct {
+	order binary.ByteOrder
+	o     *options
+}
+
+// element reads the next data element from r and returns its data type and
+// data. Elements other than compressed ones are followed by padding to a
+// multiple of 8 bytes, which is skipped if padded is true. It returns io.EOF
+// if r is at its end.
+func (m *matReader) element(r io.Reader, padded bool) (uint32, []byte, error) {
+	var tag [8]byte
+	if _, err := io.ReadFull(r, tag[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil, ErrorNpy{Msg: "truncated MAT-file element tag"}
+		}
+		return 0, nil, err
+	}
+	typ, size := m.order.Uint32(tag[:]), m.order.Uint32(tag[4:])
+	if typ>>16 != 0 { // Small element: type and size share the first word
+		typ, size = typ&0xffff, typ>>16
+		if size > 4 {
+			return 0, nil, ErrorNpy{Msg: fmt.Sprintf("small MAT-file element of %d bytes", size)}
+		}
+		return typ, tag[4 : 4+size], nil
+	}
+	if err := m.o.checkSize(DTypeU8, Shape{int(size)}); err != nil {
+		return 0, nil, err
+	}
+	n := int64(size)
+	if padded && typ != miCOMPRESSED {
+		n = (n + 7) &^ 7
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, n); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil, ErrorNpy{Msg: fmt.Sprintf("truncated MAT-file element of %d bytes", size)}
+		}
+		return 0, nil, err
+	}
+	return typ, buf.Bytes()[:size], nil
+}
+
+// arrayElement reads the next element of an array's data, which ends early
+// if there is none.
+func (m *matReader) arrayElement(r io.Reader) (uint32, []byte, error) {
+	typ, data, err := m.element(r, true)
+	if errors.Is(err, io.EOF) {
+		return 0, nil, ErrorNpy{Msg: "truncated MAT-file array"}
+	}
+	return typ, data, err
+}
+
+// matrix decodes the data of an miMATRIX element into its name and tensor.
+// The tensor is nil if the array is not numeric or logical.
+func (m *matReader) matrix(data []byte) (string, *Tensor, error) {
+	if len(data) == 0 {
+		return "", nil, nil // Empty placeholder, as in empty cells
+	}
+	r := bytes.NewReader(data)
+	typ, flags, err := m.arrayElement(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if typ != miUINT32 || len(flags) != 8 {
+		return "", nil, ErrorNpy{Msg: "MAT-file array has no array flags"}
+	}
+	bits := m.order.Uint32(flags)
+	dtype, numeric := matClasses[byte(bits)]
+
+	typ, dimData, err := m.arrayElement(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if typ != miINT32 || len(dimData) < 8 || len(dimData)%4 != 0 {
+		return "", nil, ErrorNpy{Msg: "MAT-file array has no dimensions"}
+	}
+	dims := make(Shape, len(dimData)/4)
+	for i := range dims {
+		dims[i] = int(int32(m.order.Uint32(dimData[4*i:])))
+	}
+	typ, nameData, err := m.arrayElement(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if typ != miINT8 {
+		return "", nil, ErrorNpy{Msg: "MAT-file array has no name"}
+	}
+	name := string(nameData)
+	if !numeric {
+		return name, nil, nil
+	}
+	n, err := dims.CheckedElemCount()
+	if err != nil {
+		return name, nil, err
+	}
+	if bits&matLogical != 0 {
+		dtype = DTypeBool
+	}
+
+	t, err := m.values(r, dtype, n)
+	if err != nil {
+		return name, nil, err
+	}
+	if bits&matComplex != 0 {
+		im, err := m.values(r, dtype, n)
+		if err != nil {
+			return name, nil, err
+		}
+		if t, err = matComplexTensor(t, im); err != nil {
+			return name, nil, err
+		}
+	}
+
+	// Element k of the column-major data is at index (i0, i1, ...) with
+	// k = i0 + d0*(i1 + d1*(...)).
+	strides := make([]int, len(dims))
+	stride := 1
+	for i, d := range dims {
+		strides[i] = stride
+		stride *= d
+	}
+	flat := &Tensor{Data: t.Data, Shape: Shape{n}, DType: t.DType}
+	if t.Data, err = flat.gather(dims, strides, 0); err != nil {
+		return name, nil, err
+	}
+	t.Shape = dims
+	return name, t, nil
+}
+
+// values reads the next element of r as n values of dtype. MAT-files may store
+// values in a smaller data type than their array class, such as a double
+// array of small integers as miUINT8, so the values are converted to dtype.
+func (m *matReader) values(r io.Reader, dtype DType, n int) (*Tensor, error) {
+	typ, data, err := m.arrayElement(r)
+	if err != nil {
+		return nil, err
+	}
+	stored, ok := matTypes[typ]
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("MAT-file array values of data type %d", typ), Err: ErrUnsupportedDType}
+	}
+	width := stored.Size()
+	if typ == miINT16 || typ == miUINT16 {
+		width = 2
+	}
+	if len(data) != n*width {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("MAT-file array of %d elements has %d bytes of data type %d", n, len(data), typ)}
+	}
+	if err := m.o.checkSize(dtype, Shape{n}); err != nil {
+		return nil, err
+	}
+
+	t, err := Zeros(stored, Shape{n})
+	if err != nil {
+		return nil, err
+	}
+	switch d := t.Data.(type) {
+	case []int32:
+		if typ == miINT16 {
+			for i := range d {
+				d[i] = int32(int16(m.order.Uint16(data[2*i:])))
+			}
+			break
+		}
+		err = binary.Read(bytes.NewReader(data), m.order, d)
+	case []uint32:
+		if typ == miUINT16 {
+			for i := range d {
+				d[i] = uint32(m.order.Uint16(data[2*i:]))
+			}
+			break
+		}
+		err = binary.Read(bytes.NewReader(data), m.order, d)
+	default:
+		err = binary.Read(bytes.NewReader(data), m.order, d)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored == dtype {
+		return t, nil
+	}
+	return t.Cast(dtype)
+}
+
+// matComplexTensor combines the real and imaginary parts of a complex array.
+func matComplexTensor(re, im *Tensor) (*Tensor, error) {
+	dtype := DTypeC128
+	if re.DType == DTypeF32 {
+		dtype = DTypeC64
+	}
+	re, err := re.Cast(dtype)
+	if err != nil {
+		return nil, err
+	}
+	im, err = im.Cast(DTypeF64)
+	if err != nil {
+		return nil, err
+	}
+	switch d := re.Data.(type) {
+	case []complex64:
+		for i, v := range im.Data.([]float64) {
+			d[i] += complex(0, float32(v))
+		}
+	case []complex128:
+		for i, v := range im.Data.([]float64) {
+			d[i] += complex(0, v)
+		}
+	}
+	return re, nil
+}
@@ -0,0 +1,148 @@
+package gonpy
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// matElement returns a little-endian MAT-file data element, padded to 8
+// bytes unless it is compressed.
+func matElement(typ uint32, data []byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, typ)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	if typ == miCOMPRESSED {
+		return b
+	}
+	return append(b, make([]byte, -len(b)&7)...)
+}
+
+// matMatrix returns an miMATRIX element of the given array class and flags,
+// followed by the value elements.
+func matMatrix(class byte, flags uint32, dims []int32, name string, values ...[]byte) []byte {
+	b := matElement(miUINT32, binary.LittleEndian.AppendUint64(nil, uint64(flags|uint32(class))))
+	var d []byte
+	for _, n := range dims {
+		d = binary.LittleEndian.AppendUint32(d, uint32(n))
+	}
+	b = append(b, matElement(miINT32, d)...)
+	b = append(b, matElement(miINT8, []byte(name))...)
+	for _, v := range values {
+		b = append(b, v...)
+	}
+	return matElement(miMATRIX, b)
+}
+
+// writeMAT writes a little-endian Level 5 MAT-file of the given elements.
+func writeMAT(t *testing.T, elements ...[]byte) string {
+	t.Helper()
+	header := make([]byte, 128)
+	copy(header, "MATLAB 5.0 MAT-file")
+	copy(header[124:], "\x00\x01IM")
+	b := header
+	for _, e := range elements {
+		b = append(b, e...)
+	}
+	path := filepath.Join(t.TempDir(), "a.mat")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// matValues returns an element of little-endian values.
+func matValues(typ uint32, values interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, values)
+	return matElement(typ, buf.Bytes())
+}
+
+func TestReadMAT(t *testing.T) {
+	// A 2x3 double matrix stored column-major as small integers, an int16
+	// vector, a complex single scalar, and a logical vector
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(matMatrix(9, matLogical, []int32{1, 2}, "mask", matValues(miUINT8, []uint8{1, 0})))
+	zw.Close()
+	path := writeMAT(t,
+		matMatrix(6, 0, []int32{2, 3}, "x", matValues(miUINT8, []uint8{1, 4, 2, 5, 3, 6})),
+		matMatrix(10, 0, []int32{1, 2}, "counts", matValues(miINT16, []int16{-2, 300})),
+		matMatrix(7, matComplex, []int32{1, 1}, "z", matValues(miSINGLE, []float32{1}), matValues(miSINGLE, []float32{-2})),
+		matMatrix(4, 0, []int32{1, 2}, "text", matValues(miUINT16, []uint16{'h', 'i'})),
+		matElement(miCOMPRESSED, compressed.Bytes()),
+	)
+	tensors, err := ReadMAT(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"x":      "f64 [2 3] [1 2 3 4 5 6]",
+		"counts": "i32 [1 2] [-2 300]",
+		"z":      "c64 [1 1] [(1-2i)]",
+		"mask":   "bool [1 2] [true false]",
+	}
+	if len(tensors) != len(want) {
+		t.Errorf("read %d arrays, want %d", len(tensors), len(want))
+	}
+	for name, w := range want {
+		x, ok := tensors[name]
+		if !ok {
+			t.Errorf("%s not read", name)
+			continue
+		}
+		if got := fmt.Sprint(x.DType, " ", []int(x.Shape), " ", x.Data); got != w {
+			t.Errorf("%s = %s, want %s", name, got, w)
+		}
+	}
+}
+
+func TestHostileMAT(t *testing.T) {
+	x := func(values ...[]byte) []byte {
+		return matMatrix(6, 0, []int32{1, 2}, "x", values...)
+	}
+	f64 := matValues(miDOUBLE, []float64{1, 2})
+	tests := map[string][][]byte{
+		"truncated tag":     {x(f64)[:4]},
+		"truncated element": {x(f64)[:20]},
+		"small element":     {{1, 0, 8, 0, 0, 0, 0, 0}},
+		"no flags":          {matElement(miMATRIX, matElement(miINT32, make([]byte, 8)))},
+		"no dimensions":     {matMatrix(6, 0, []int32{1}, "x", f64)},
+		"negative dims":     {matMatrix(6, 0, []int32{-1, 2}, "x", f64)},
+		"huge dims":         {matMatrix(6, 0, []int32{1 << 30, 1 << 30, 1 << 30}, "x", f64)},
+		"value count":       {x(matValues(miDOUBLE, []float64{1}))},
+		"value type":        {x(matElement(miMATRIX, make([]byte, 16)))},
+		"no imaginary part": {matMatrix(6, matComplex, []int32{1, 2}, "x", f64)},
+		"compressed":        {matElement(miCOMPRESSED, []byte("not zlib"))},
+	}
+	for name, elements := range tests {
+		if _, err := ReadMAT(writeMAT(t, elements...)); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+
+	path := writeMAT(t, x(f64))
+	if _, err := ReadMAT(path, WithMaxBytes(8)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v73 := append([]byte("MATLAB 7.3 MAT-file"), b[19:128]...)
+	order := append(b[:126:126], "XX"...)
+	version := append(b[:124:124], "\x00\x02IM"...)
+	for name, header := range map[string][]byte{"v7.3": v73, "short": b[:100], "order": order, "version": version} {
+		if err := os.WriteFile(path, header, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadMAT(path); err == nil {
+			t.Errorf("%s header: read without error", name)
+		}
+	}
+}
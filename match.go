@@ -0,0 +1,74 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+	"regexp"
+)
+
+// ReadNPZMatch reads the tensors whose names match a glob pattern, in archive
+// order. The pattern syntax is that of path.Match, so "encoder.layer.*.weight"
+// matches every layer's weight; '*' does not match '/'.
+func ReadNPZMatch(npzPath, pattern string, opts ...Option) ([]NamedTensor, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid pattern %q: %v", pattern, err)}
+	}
+	return readNPZFiltered(npzPath, func(name string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, newOptions(opts))
+}
+
+// ReadNPZRegexp reads the tensors whose names match re, in archive order.
+// Unanchored expressions match anywhere in the name.
+func ReadNPZRegexp(npzPath string, re *regexp.Regexp, opts ...Option) ([]NamedTensor, error) {
+	return readNPZFiltered(npzPath, re.MatchString, newOptions(opts))
+}
+
+// readNPZFiltered reads the tensors whose names satisfy match, skipping the
+// data of all other entries.
+func readNPZFiltered(npzPath string, match func(string) bool, o *options) ([]NamedTensor, error) {
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(npzPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.loadChecksums(r.File); err != nil {
+		return nil, err
+	}
+
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, err
+	}
+	var files []*zip.File
+	for _, file := range entries {
+		if match(o.nameCodec.Decode(file.Name)) {
+			files = append(files, file)
+		}
+	}
+	o.track(entriesSize(files))
+
+	var result []NamedTensor
+	var skipped []error
+	for _, file := range files {
+		nt, err := readEntry(file, packing, o)
+		if err != nil {
+			if !o.skipFailure() {
+				return nil, locate(err, npzPath, "")
+			}
+			skipped = append(skipped, locate(err, npzPath, ""))
+			continue
+		}
+		result = append(result, nt)
+	}
+	return result, partialFailure(npzPath, skipped)
+}
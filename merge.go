@@ -0,0 +1,126 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// CollisionPolicy selects how MergeNPZWith handles an entry name that an earlier
+// source archive already provided.
+type CollisionPolicy int
+
+const (
+	// CollisionError fails the merge.
+	CollisionError CollisionPolicy = iota
+	// CollisionSkip keeps the entry from the earlier archive.
+	CollisionSkip
+	// CollisionPrefix keeps both entries, renaming the later one to
+	// "<source>/<name>", where source is the base name of its archive
+	// without the .npz extension.
+	CollisionPrefix
+)
+
+// MergeNPZ combines the entries of several NPZ archives into dstPath, failing on
+// duplicate names. See MergeNPZWith.
+func MergeNPZ(dstPath string, srcPaths ...string) error {
+	return MergeNPZWith(dstPath, srcPaths, CollisionError)
+}
+
+// MergeNPZWith combines the entries of the source archives, in order, into a new
+// archive at dstPath, resolving duplicate names according to policy. Entry data
+// is copied without being decoded. dstPath may be one of the sources; it is
+// only replaced once the merged archive is complete.
+func MergeNPZWith(dstPath string, srcPaths []string, policy CollisionPolicy, opts ...Option) error {
+	o := newOptions(opts)
+
+	tmp, err := createTemp(dstPath, o)
+	if err != nil {
+		return err
+	}
+	if err := mergeInto(tmp, srcPaths, policy, o); err != nil {
+		tmp.discard()
+		return err
+	}
+	return tmp.commit()
+}
+
+// mergeInto writes the merged archive of the source archives to f.
+func mergeInto(f *outputFile, srcPaths []string, policy CollisionPolicy, o *options) error {
+	zw := zip.NewWriter(f)
+	names := make(map[string]bool)
+	packing := make(map[string]packingInfo)
+	sums := make(map[string]uint32)
+	for _, src := range srcPaths {
+		if err := mergeSource(zw, src, policy, o, names, packing, sums); err != nil {
+			return err
+		}
+	}
+	if err := writePacking(zw, packing); err != nil {
+		return err
+	}
+	if err := writeChecksums(zw, sums); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// mergeSource copies the entries of the archive at path into zw, recording the
+// names written, the packing metadata of packed tensors, and checksums.
+func mergeSource(zw *zip.Writer, path string, policy CollisionPolicy, o *options, names map[string]bool, packing map[string]packingInfo, sums map[string]uint32) error {
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	srcPacking, err := readPacking(r.File)
+	if err != nil {
+		return err
+	}
+	srcSums, err := readChecksums(r.File)
+	if err != nil {
+		return err
+	}
+
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		return err
+	}
+	for _, file := range entries {
+		srcName := o.nameCodec.Decode(file.Name)
+		name := srcName
+		if names[name] {
+			switch policy {
+			case CollisionSkip:
+				continue
+			case CollisionPrefix:
+				name = strings.TrimSuffix(filepath.Base(path), ".npz") + "/" + name
+				if !names[name] {
+					break
+				}
+				fallthrough
+			default:
+				return ErrorNpy{Msg: fmt.Sprintf("duplicate array name %s in %s", name, path)}
+			}
+		}
+		entryName := o.nameCodec.Encode(name)
+		if _, err := sanitizeEntryName(entryName); err != nil {
+			return err
+		}
+		if err := copyRawEntry(zw, file, entryName); err != nil {
+			return err
+		}
+		names[name] = true
+		if info, ok := srcPacking[srcName]; ok {
+			packing[name] = info
+		}
+		if sum, ok := srcSums[srcName]; ok {
+			sums[name] = sum
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package gonpy
+
+import "os"
+
+// mmapFile reports that memory mapping is not available on this platform.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return nil, ErrorNpy{Msg: "memory-mapped files are not supported on this platform"}
+}
+
+// munmapFile does nothing, since mmapFile never succeeds.
+func munmapFile(mem []byte) error {
+	return nil
+}
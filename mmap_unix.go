@@ -0,0 +1,19 @@
+//go:build unix
+
+package gonpy
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmapFile maps the first size bytes of f into memory for reading and writing.
+// Changes to the memory are written back to the file.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+// munmapFile releases memory returned by mmapFile.
+func munmapFile(mem []byte) error {
+	return syscall.Munmap(mem)
+}
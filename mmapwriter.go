@@ -0,0 +1,93 @@
+package gonpy
+
+import (
+	"fmt"
+	"os"
+)
+
+// NpyMmapWriter is an NPY file whose data region is mapped into memory, so that
+// very large arrays can be filled in place instead of being built in memory and
+// then copied out. The header is written when the file is created; producers
+// write the data through Bytes or Data, from several goroutines if they write
+// disjoint ranges.
+type NpyMmapWriter struct {
+	f      *os.File
+	mem    []byte // The whole file
+	offset int
+	dtype  DType
+	shape  Shape
+}
+
+// WriteNPYMmap creates the NPY file at path sized for an array of the given
+// dtype and shape, writes its header, and maps it into memory. The data region
+// starts zeroed. Close must be called to release the mapping.
+func WriteNPYMmap(path string, dtype DType, shape Shape, opts ...Option) (*NpyMmapWriter, error) {
+	if dtype.isPacked() || dtype == DTypeObject {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot map arrays of dtype %s", dtype)}
+	}
+	o := newOptions(opts)
+	prefix, err := encodeHeader(&Header{Descr: dtype, Shape: shape}, o.headerAlign, 0)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(prefix)) + tensorBytes(&Tensor{DType: dtype, Shape: shape})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	mem, err := mmapFile(f, int(size))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	copy(mem, prefix)
+	return &NpyMmapWriter{f: f, mem: mem, offset: len(prefix), dtype: dtype, shape: append(Shape(nil), shape...)}, nil
+}
+
+// Bytes returns the data region of the file. Elements are stored little-endian
+// in C order.
+func (w *NpyMmapWriter) Bytes() []byte {
+	return w.mem[w.offset:]
+}
+
+// Data returns the data region of the file as a slice of the dtype's element
+// type, such as []float32 for DTypeF32, which writes through to the file. It
+// is available for numeric dtypes on little-endian platforms when the data
+// offset is aligned for the element type; otherwise use Bytes.
+func (w *NpyMmapWriter) Data() (interface{}, error) {
+	if size := w.dtype.Size(); size > 0 && w.offset%size == 0 {
+		if data, ok := viewData(w.dtype, w.Bytes()); ok {
+			return data, nil
+		}
+	}
+	return nil, ErrorNpy{Msg: fmt.Sprintf("cannot view mapped %s data as a typed slice", w.dtype)}
+}
+
+// Shape returns the shape of the array.
+func (w *NpyMmapWriter) Shape() Shape {
+	return w.shape
+}
+
+// DType returns the dtype of the array.
+func (w *NpyMmapWriter) DType() DType {
+	return w.dtype
+}
+
+// Close releases the mapping and closes the file. Slices returned by Bytes and
+// Data must not be used afterwards.
+func (w *NpyMmapWriter) Close() error {
+	if w.f == nil {
+		return nil
+	}
+	err := munmapFile(w.mem)
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f, w.mem = nil, nil
+	return err
+}
@@ -0,0 +1,61 @@
+package gonpy
+
+import (
+	"bufio"
+	"io"
+)
+
+// MultiWrite encodes the tensor in NPY format once and writes the bytes to every
+// destination, in the manner of io.MultiWriter. Writing stops at the first
+// destination that returns an error.
+func MultiWrite(t *Tensor, dests ...io.Writer) error {
+	if len(dests) == 0 {
+		return ErrorNpy{Msg: "no destinations to write to"}
+	}
+	return t.Write(io.MultiWriter(dests...))
+}
+
+// WriteNPYMulti writes the tensor to several NPY files, encoding it only once.
+// Options apply as in WriteNPY; with WithAtomicWrite no destination is replaced
+// unless every file was written.
+func (t *Tensor) WriteNPYMulti(paths []string, opts ...Option) error {
+	if len(paths) == 0 {
+		return MultiWrite(t)
+	}
+	o := newOptions(opts)
+	files := make([]*outputFile, 0, len(paths))
+	dests := make([]io.Writer, 0, len(paths))
+	for _, path := range paths {
+		f, err := createOutput(path, o)
+		if err != nil {
+			discardAll(files)
+			return err
+		}
+		files = append(files, f)
+		dests = append(dests, f)
+	}
+
+	bw := bufio.NewWriterSize(io.MultiWriter(dests...), writeBufferSize)
+	if err := t.Write(bw, opts...); err != nil {
+		discardAll(files)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		discardAll(files)
+		return err
+	}
+	for i, f := range files {
+		if err := f.commit(); err != nil {
+			discardAll(files[i+1:])
+			return err
+		}
+	}
+	return nil
+}
+
+// discardAll discards files after a failed write.
+func discardAll(files []*outputFile) {
+	for _, f := range files {
+		f.discard()
+	}
+}
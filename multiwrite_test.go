@@ -0,0 +1,41 @@
+package gonpy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteNPYMulti(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.npy"), filepath.Join(dir, "b.npy")}
+	in := &Tensor{Data: []int32{1, 2, 3}, Shape: Shape{3}, DType: DTypeI32, Device: "cpu"}
+	if err := in.WriteNPYMulti(paths, WithAtomicWrite()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		out, err := ReadNPY(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := out.Data.([]int32); len(got) != 3 || got[2] != 3 {
+			t.Errorf("%s holds %v", path, got)
+		}
+	}
+}
+
+func TestWriteNPYMultiFailure(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.npy"), filepath.Join(dir, "missing", "b.npy")}
+	in := &Tensor{Data: []int32{1}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"}
+	if err := in.WriteNPYMulti(paths, WithAtomicWrite()); err == nil {
+		t.Fatal("write to a missing directory succeeded")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("failed write left %s behind", e.Name())
+	}
+}
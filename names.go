@@ -0,0 +1,84 @@
+package gonpy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode/utf8"
+)
+
+// NameCodec controls how tensor names map to entry names inside an NPZ archive.
+// Encode is used when writing or looking up a tensor; Decode is used when
+// listing the tensors of an existing archive.
+type NameCodec interface {
+	Encode(name string) string
+	Decode(entry string) string
+}
+
+// NumpyNameCodec is the default codec. It follows numpy.savez, appending the
+// ".npy" suffix to tensor names and stripping it from entry names.
+type NumpyNameCodec struct{}
+
+// Encode implements NameCodec.
+func (NumpyNameCodec) Encode(name string) string {
+	return name + npySuffix
+}
+
+// Decode implements NameCodec.
+func (NumpyNameCodec) Decode(entry string) string {
+	return strings.TrimSuffix(entry, npySuffix)
+}
+
+// ConfigurableNameCodec is a NameCodec assembled from common conventions.
+// Transformations are applied in field order when encoding and reversed when
+// decoding, except that case normalization and hashing are lossy.
+type ConfigurableNameCodec struct {
+	Suffix      string // Suffix appended to entry names, e.g. ".npy"; may be empty
+	EscapeSlash bool   // Escape '/' (and '%') so names never create archive directories
+	Lowercase   bool   // Normalize names to lower case
+	MaxLen      int    // Names longer than this are replaced by a hash; 0 disables
+}
+
+// nameEscaper escapes characters that would otherwise be interpreted as paths.
+var (
+	nameEscaper   = strings.NewReplacer("%", "%25", "/", "%2F")
+	nameUnescaper = strings.NewReplacer("%2F", "/", "%2f", "/", "%25", "%")
+)
+
+// Encode implements NameCodec.
+func (c ConfigurableNameCodec) Encode(name string) string {
+	if c.EscapeSlash {
+		name = nameEscaper.Replace(name)
+	}
+	if c.Lowercase {
+		name = strings.ToLower(name)
+	}
+	if c.MaxLen > 0 && len(name) > c.MaxLen {
+		name = hashName(name, c.MaxLen)
+	}
+	return name + c.Suffix
+}
+
+// Decode implements NameCodec.
+func (c ConfigurableNameCodec) Decode(entry string) string {
+	entry = strings.TrimSuffix(entry, c.Suffix)
+	if c.EscapeSlash {
+		entry = nameUnescaper.Replace(entry)
+	}
+	return entry
+}
+
+// hashName shortens name to at most maxLen bytes by keeping a prefix and
+// appending a hex digest of the full name.
+func hashName(name string, maxLen int) string {
+	sum := sha256.Sum256([]byte(name))
+	digest := hex.EncodeToString(sum[:])
+	if maxLen <= len(digest) {
+		return digest[:maxLen]
+	}
+	keep := maxLen - len(digest) - 1
+	for keep > 0 && !utf8.RuneStart(name[keep]) {
+		keep--
+	}
+	return name[:keep] + "-" + digest
+}
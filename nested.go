@@ -0,0 +1,169 @@
+package gonpy
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// nestedDTypes maps the element types accepted by FromNested to their dtypes.
+// Go's int and uint become 64-bit integers.
+var nestedDTypes = map[reflect.Kind]DType{
+	reflect.Float32:    DTypeF32,
+	reflect.Float64:    DTypeF64,
+	reflect.Complex64:  DTypeC64,
+	reflect.Complex128: DTypeC128,
+	reflect.Int8:       DTypeI8,
+	reflect.Int32:      DTypeI32,
+	reflect.Int64:      DTypeI64,
+	reflect.Int:        DTypeI64,
+	reflect.Uint8:      DTypeU8,
+	reflect.Uint32:     DTypeU32,
+	reflect.Uint64:     DTypeU64,
+	reflect.Uint:       DTypeU64,
+	reflect.Bool:       DTypeBool,
+	reflect.String:     nestedString,
+}
+
+// nestedString stands for a unicode string dtype in nestedDTypes; its width is
+// only known once the strings have been seen.
+const nestedString DType = "U"
+
+// FromNested builds a tensor from nested Go slices or arrays such as
+// [][]float32 or [][][]int64, or from a single value for a 0-dimensional
+// tensor. The shape follows the nesting, which must not be ragged, and the
+// dtype follows the element type. Strings become a unicode dtype wide enough
+// for the longest one. The data is copied.
+func FromNested(v interface{}) (*Tensor, error) {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		return nil, ErrorNpy{Msg: "cannot build a tensor from nil"}
+	}
+	elem := val.Type()
+	ndim := 0
+	for elem.Kind() == reflect.Slice || elem.Kind() == reflect.Array {
+		elem = elem.Elem()
+		ndim++
+	}
+	dtype, ok := nestedDTypes[elem.Kind()]
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported element type %s", elem), Err: ErrUnsupportedDType}
+	}
+
+	shape := make(Shape, ndim)
+	for d, level := 0, val; d < ndim && level.Len() > 0; d, level = d+1, level.Index(0) {
+		shape[d] = level.Len()
+	}
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+
+	flat := reflect.MakeSlice(reflect.SliceOf(elem), 0, n)
+	if flat, err = flatten(val, shape, flat); err != nil {
+		return nil, err
+	}
+	data, err := nestedData(dtype, flat)
+	if err != nil {
+		return nil, err
+	}
+	if dtype == nestedString {
+		width := 1
+		for _, s := range data.([]string) {
+			width = max(width, utf8.RuneCountInString(s))
+		}
+		dtype = UnicodeDType(width)
+	}
+	return &Tensor{Data: data, Shape: shape, DType: dtype, Device: "cpu"}, nil
+}
+
+// flatten appends the elements of the nested value v of the given shape to
+// flat in row-major order, reporting ragged nesting.
+func flatten(v reflect.Value, shape Shape, flat reflect.Value) (reflect.Value, error) {
+	if len(shape) == 0 {
+		return reflect.Append(flat, v.Convert(flat.Type().Elem())), nil
+	}
+	if v.Len() != shape[0] {
+		return flat, ErrorNpy{Msg: fmt.Sprintf("ragged nesting: length %d, expected %d", v.Len(), shape[0])}
+	}
+	if len(shape) == 1 && v.Kind() == reflect.Slice {
+		return reflect.AppendSlice(flat, v), nil
+	}
+	var err error
+	for i := 0; i < v.Len(); i++ {
+		if flat, err = flatten(v.Index(i), shape[1:], flat); err != nil {
+			return flat, err
+		}
+	}
+	return flat, nil
+}
+
+// nestedData converts flattened elements to the data slice of dtype,
+// widening Go's int and uint and unwrapping named element types.
+func nestedData(dtype DType, flat reflect.Value) (interface{}, error) {
+	want, err := makeData(dtype, 0)
+	if dtype == nestedString {
+		want, err = []string(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wantType := reflect.TypeOf(want)
+	if flat.Type() == wantType {
+		return flat.Interface(), nil
+	}
+	data := reflect.MakeSlice(wantType, flat.Len(), flat.Len())
+	for i := 0; i < flat.Len(); i++ {
+		data.Index(i).Set(flat.Index(i).Convert(wantType.Elem()))
+	}
+	return data.Interface(), nil
+}
+
+// ToNested returns the tensor's data as nested slices following its shape,
+// such as [][]float32 for a 2-D F32 tensor, or a single value for a
+// 0-dimensional tensor. Elements have the type they have in Data, except that
+// packed 4-bit values are unpacked to int8 or uint8. The data is copied.
+func (t *Tensor) ToNested() (interface{}, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	if t.DType.isStructured() {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot nest structured dtype %s", t.DType)}
+	}
+	if err := t.checkLength(); err != nil {
+		return nil, err
+	}
+	var flat reflect.Value
+	switch t.DType {
+	case DTypeI4:
+		flat = reflect.ValueOf(UnpackInt4(t.Data.([]byte), t.Shape.ElemCount()))
+	case DTypeU4:
+		flat = reflect.ValueOf(UnpackUint4(t.Data.([]byte), t.Shape.ElemCount()))
+	default:
+		flat = reflect.ValueOf(t.Data)
+	}
+	if len(t.Shape) == 0 {
+		return flat.Index(0).Interface(), nil
+	}
+	return nest(flat, t.Shape).Interface(), nil
+}
+
+// nest copies the row-major elements of flat into nested slices of the given shape.
+func nest(flat reflect.Value, shape Shape) reflect.Value {
+	typ := flat.Type()
+	for range shape[1:] {
+		typ = reflect.SliceOf(typ)
+	}
+	if len(shape) == 1 {
+		out := reflect.MakeSlice(typ, flat.Len(), flat.Len())
+		reflect.Copy(out, flat)
+		return out
+	}
+	out := reflect.MakeSlice(typ, shape[0], shape[0])
+	stride := shape[1:].ElemCount()
+	for i := 0; i < shape[0]; i++ {
+		out.Index(i).Set(nest(flat.Slice(i*stride, (i+1)*stride), shape[1:]))
+	}
+	return out
+}
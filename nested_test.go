@@ -0,0 +1,146 @@
+package gonpy
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type celsius float32
+
+func TestFromNested(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    interface{}
+		dtype DType
+		shape Shape
+		want  string
+	}{
+		{"matrix", [][]float32{{1, 2, 3}, {4, 5, 6}}, DTypeF32, Shape{2, 3}, "[1 2 3 4 5 6]"},
+		{"3-D", [][][]int64{{{1}, {2}}, {{3}, {4}}}, DTypeI64, Shape{2, 2, 1}, "[1 2 3 4]"},
+		{"scalar", 2.5, DTypeF64, Shape{}, "[2.5]"},
+		{"go int", []int{-1, 1 << 30}, DTypeI64, Shape{2}, "[-1 1073741824]"},
+		{"go uint", [][]uint{{7}}, DTypeU64, Shape{1, 1}, "[7]"},
+		{"bool", []bool{true, false}, DTypeBool, Shape{2}, "[true false]"},
+		{"complex", []complex64{1 + 2i}, DTypeC64, Shape{1}, "[(1+2i)]"},
+		{"array", [2][3]uint8{{1, 2, 3}, {4, 5, 6}}, DTypeU8, Shape{2, 3}, "[1 2 3 4 5 6]"},
+		{"slice of arrays", [][2]int32{{1, 2}, {3, 4}}, DTypeI32, Shape{2, 2}, "[1 2 3 4]"},
+		{"named type", []celsius{-40, 100}, DTypeF32, Shape{2}, "[-40 100]"},
+		{"strings", [][]string{{"a", "héllo"}, {"", "xy"}}, UnicodeDType(5), Shape{2, 2}, "[a héllo  xy]"},
+		{"empty strings", []string{""}, UnicodeDType(1), Shape{1}, "[]"},
+		{"empty", [][]float64{}, DTypeF64, Shape{0, 0}, "[]"},
+		{"empty rows", [][]float64{{}, {}}, DTypeF64, Shape{2, 0}, "[]"},
+	}
+	for _, tt := range tests {
+		got, err := FromNested(tt.in)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got.DType != tt.dtype || !got.Shape.Equal(tt.shape) || fmt.Sprint(got.Data) != tt.want {
+			t.Errorf("%s: got %s %v %v, want %s %v %s", tt.name, got.DType, got.Shape, got.Data, tt.dtype, tt.shape, tt.want)
+		}
+		if err := got.checkLength(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+
+	// The data is copied
+	in := []float64{1, 2}
+	x, err := FromNested(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in[0] = 9
+	if fmt.Sprint(x.Data) != "[1 2]" {
+		t.Errorf("tensor data %v shared with the input", x.Data)
+	}
+}
+
+func TestFromNestedInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          interface{}
+		unsupported bool // Fails with ErrUnsupportedDType
+	}{
+		{"nil", nil, false},
+		{"ragged", [][]float32{{1, 2}, {3}}, false},
+		{"ragged after empty", [][]float32{{}, {3}}, false},
+		{"ragged deep", [][][]int32{{{1}, {2}}, {{3}, {4, 5}}}, false},
+		{"int16", []int16{1}, true},
+		{"pointers", []*float32{nil}, true},
+		{"interfaces", []interface{}{1.0}, true},
+		{"struct", struct{}{}, true},
+	}
+	for _, tt := range tests {
+		got, err := FromNested(tt.in)
+		if err == nil {
+			t.Errorf("%s: got %s %v %v", tt.name, got.DType, got.Shape, got.Data)
+			continue
+		}
+		if tt.unsupported != errors.Is(err, ErrUnsupportedDType) {
+			t.Errorf("%s: got %v", tt.name, err)
+		}
+	}
+}
+
+func TestToNested(t *testing.T) {
+	tests := []struct {
+		name string
+		t    *Tensor
+		want string // The result printed with %T %v
+	}{
+		{"matrix", &Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: Shape{2, 3}, DType: DTypeF32}, "[][]float32 [[1 2 3] [4 5 6]]"},
+		{"3-D", &Tensor{Data: []int64{1, 2, 3, 4}, Shape: Shape{2, 1, 2}, DType: DTypeI64}, "[][][]int64 [[[1 2]] [[3 4]]]"},
+		{"vector", &Tensor{Data: []bool{true}, Shape: Shape{1}, DType: DTypeBool}, "[]bool [true]"},
+		{"scalar", &Tensor{Data: []complex128{1i}, Shape: Shape{}, DType: DTypeC128}, "complex128 (0+1i)"},
+		{"f16 bits", &Tensor{Data: []uint16{0x3c00}, Shape: Shape{1, 1}, DType: DTypeF16}, "[][]uint16 [[15360]]"},
+		{"strings", &Tensor{Data: []string{"a", "b"}, Shape: Shape{2, 1}, DType: UnicodeDType(1)}, "[][]string [[a] [b]]"},
+		{"i4", &Tensor{Data: []byte{0x8f, 0x07}, Shape: Shape{3}, DType: DTypeI4}, "[]int8 [-1 -8 7]"},
+		{"u4", &Tensor{Data: []byte{0x8f}, Shape: Shape{1, 2}, DType: DTypeU4}, "[][]uint8 [[15 8]]"},
+		{"empty", &Tensor{Data: []float64{}, Shape: Shape{2, 0}, DType: DTypeF64}, "[][]float64 [[] []]"},
+	}
+	for _, tt := range tests {
+		got, err := tt.t.ToNested()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if s := fmt.Sprintf("%T %v", got, got); s != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, s, tt.want)
+		}
+	}
+
+	// The result does not share the tensor's data, and converts back
+	x := &Tensor{Data: []int32{1, 2, 3, 4}, Shape: Shape{2, 2}, DType: DTypeI32}
+	n, err := x.ToNested()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.([][]int32)[0][0] = 9
+	if fmt.Sprint(x.Data) != "[1 2 3 4]" {
+		t.Errorf("tensor data %v shared with the nested slices", x.Data)
+	}
+	y, err := FromNested(n)
+	if err != nil || !y.Shape.Equal(x.Shape) || fmt.Sprint(y.Data) != "[9 2 3 4]" {
+		t.Errorf("FromNested(ToNested) = %v, %v", y, err)
+	}
+}
+
+func TestToNestedInvalid(t *testing.T) {
+	sd, err := NewStructuredDType(Field{Name: "a", DType: DTypeI32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]*Tensor{
+		"short data":    {Data: []float32{1}, Shape: Shape{2}, DType: DTypeF32},
+		"mistyped data": {Data: []float32{1}, Shape: Shape{1}, DType: DTypeF64},
+		"short packed":  {Data: []byte{0}, Shape: Shape{3}, DType: DTypeI4},
+		"structured":    {Data: make([]byte, 4), Shape: Shape{1}, DType: sd.DType()},
+	}
+	for name, x := range tests {
+		if got, err := x.ToNested(); err == nil {
+			t.Errorf("%s: got %v", name, got)
+		}
+	}
+}
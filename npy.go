@@ -7,26 +7,37 @@
 // These are placeholders and should be replaced with actual types from your ML framework.
 // For demonstration, minimal definitions are provided.
 //
-// Supported DTypes: BF16, F16, F32, F64, I64, U32, U8.
+// Supported DTypes: BF16, F16, F32, F64, C64, C128, I8, I32, I64, U64, U32, U8, Bool, F8E4M3,
+// F8E5M2, fixed-width byte (S) and unicode (U) strings, and structured (record) dtypes.
+// BF16 has no native numpy descr; it is written as '<V2' following the ml_dtypes
+// convention, and '<V2' descrs are read back as BF16. numpy users can recover the
+// values with arr.view(ml_dtypes.bfloat16). The FP8 dtypes are likewise written as
+// '|V1', which is what numpy saves for ml_dtypes float8 arrays. The descr does not
+// say which format the bytes hold, so '|V1' is read back as F8E4M3; set the DType
+// of an F8E5M2 tensor after reading it. The ml_dtypes type names 'float8_e4m3fn'
+// and 'float8_e5m2' are also accepted when reading.
 // Fortran order is not supported for reading/writing.
 
 package gonpy
 
 import (
 	"archive/zip"
+	"bufio"
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
-	"regexp"
-	"strconv"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 )
 
 const (
 	npyMagicString = "\x93NUMPY"
 	npySuffix      = ".npy"
+	maxHeaderLen   = 1 << 20 // Longest header read, bounding the allocation a hostile length can cause
 )
 
 // DType represents the data type of the tensor.
@@ -38,16 +49,60 @@ const (
 	DTypeF16    DType = "f16"
 	DTypeF32    DType = "f32"
 	DTypeF64    DType = "f64"
+	DTypeC64    DType = "c64"
+	DTypeC128   DType = "c128"
+	DTypeI8     DType = "i8"
+	DTypeI32    DType = "i32"
 	DTypeI64    DType = "i64"
+	DTypeU64    DType = "u64"
 	DTypeU32    DType = "u32"
 	DTypeU8     DType = "u8"
+	DTypeBool   DType = "bool"
 	DTypeF8E4M3 DType = "f8e4m3"
+	DTypeF8E5M2 DType = "f8e5m2"
+	DTypeI4     DType = "int4"   // Packed two per byte; see PackInt4
+	DTypeU4     DType = "uint4"  // Packed two per byte; see PackUint4
+	DTypeObject DType = "object" // Pickled Python objects; see WithAllowPickle
 )
 
+// Size returns the number of bytes occupied by a single element of the dtype,
+// or 0 if the dtype is unknown or packs several elements per byte.
+func (d DType) Size() int {
+	switch d {
+	case DTypeU8, DTypeI8, DTypeBool, DTypeF8E4M3, DTypeF8E5M2:
+		return 1
+	case DTypeBF16, DTypeF16:
+		return 2
+	case DTypeF32, DTypeI32, DTypeU32:
+		return 4
+	case DTypeF64, DTypeI64, DTypeU64, DTypeC64:
+		return 8
+	case DTypeC128:
+		return 16
+	default:
+		if d.isStructured() {
+			sd, err := ParseStructuredDType(d)
+			if err != nil {
+				return 0
+			}
+			return sd.ItemSize
+		}
+		if kind, width, ok := d.stringKind(); ok {
+			if kind == 'U' {
+				return 4 * width
+			}
+			return width
+		}
+		return 0
+	}
+}
+
 // Shape represents the shape of the tensor.
 // This is a placeholder; typically a struct with methods like ElemCount().
 type Shape []int
 
+// ElemCount returns the number of elements. It does not guard against
+// overflow; use CheckedElemCount for shapes from untrusted sources.
 func (s Shape) ElemCount() int {
 	count := 1
 	for _, dim := range s {
@@ -56,13 +111,53 @@ func (s Shape) ElemCount() int {
 	return count
 }
 
+// CheckedElemCount returns the number of elements, or an error if a dimension
+// is negative or the count does not fit in an int.
+func (s Shape) CheckedElemCount() (int, error) {
+	for _, dim := range s {
+		if dim < 0 {
+			return 0, ErrorNpy{Msg: fmt.Sprintf("negative dimension in shape %v", s)}
+		}
+		if dim == 0 {
+			return 0, nil
+		}
+	}
+	count := 1
+	for _, dim := range s {
+		if count > math.MaxInt/dim {
+			return 0, ErrorNpy{Msg: fmt.Sprintf("element count of shape %v overflows", s)}
+		}
+		count *= dim
+	}
+	return count, nil
+}
+
+// Equal reports whether two shapes have the same dimensions.
+func (s Shape) Equal(other Shape) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for i := range s {
+		if s[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Tensor represents a multi-dimensional array.
-// This is a placeholder; in a real framework, this would have more methods.
+//
+// Tensors returned by reads own their data, except as documented for
+// WithAllocator, ReadNPZArena, and WithCacheBytes. Data may be shared with
+// another tensor or with other memory by Reshape, Squeeze, Unsqueeze,
+// View.Contiguous of a contiguous view, DataAs and Typed when no conversion
+// is needed, and NpyMmapWriter.Data. Use Clone before modifying data that may
+// be shared.
 type Tensor struct {
 	Data   interface{} // e.g., []float32, []uint16 for f16, etc.
 	Shape  Shape
 	DType  DType
-	Device string // e.g., "cpu"
+	Device string // "cpu", or a device registered with RegisterDevice
 }
 
 // String returns a string representation of the tensor.
@@ -73,14 +168,26 @@ func (t *Tensor) String() string {
 		dataStr = fmt.Sprintf("%v", d)
 	case []float64:
 		dataStr = fmt.Sprintf("%v", d)
+	case []complex64:
+		dataStr = fmt.Sprintf("%v", d)
+	case []complex128:
+		dataStr = fmt.Sprintf("%v", d)
+	case []int32:
+		dataStr = fmt.Sprintf("%v", d)
 	case []int64:
 		dataStr = fmt.Sprintf("%v", d)
+	case []uint64:
+		dataStr = fmt.Sprintf("%v", d)
 	case []uint32:
 		dataStr = fmt.Sprintf("%v", d)
 	case []uint16:
 		dataStr = fmt.Sprintf("%v", d)
 	case []byte:
 		dataStr = fmt.Sprintf("%v", d)
+	case []bool:
+		dataStr = fmt.Sprintf("%v", d)
+	case []string:
+		dataStr = fmt.Sprintf("%q", d)
 	case []int8:
 		dataStr = fmt.Sprintf("%v", d)
 	default:
@@ -89,15 +196,79 @@ func (t *Tensor) String() string {
 	return fmt.Sprintf("&{%s %v %s %s}", dataStr, t.Shape, t.DType, t.Device)
 }
 
+// ErrObjectArray is returned when reading an object array ('|O' descr), which numpy
+// stores as a pickle, unless pickle decoding was enabled with WithAllowPickle.
+var ErrObjectArray = ErrorNpy{Msg: "object arrays are stored as pickles; enable WithAllowPickle to decode them"}
+
+// Sentinel errors classifying common failures. Errors returned by this package
+// match them with errors.Is, including when they carry more detail.
+var (
+	ErrBadMagic           = ErrorNpy{Msg: "magic string mismatch"}
+	ErrUnsupportedVersion = ErrorNpy{Msg: "unsupported format version"}
+	ErrUnsupportedDType   = ErrorNpy{Msg: "unsupported dtype"}
+	ErrFortranOrder       = ErrorNpy{Msg: "fortran order not supported"}
+	ErrEntryNotFound      = ErrorNpy{Msg: "no such array"}
+)
+
 // ErrorNpy is a custom error type for NPY-related errors.
 type ErrorNpy struct {
 	Msg string
+	Err error // Sentinel error classifying the failure, if any
 }
 
 func (e ErrorNpy) Error() string {
 	return fmt.Sprintf("npy error: %s", e.Msg)
 }
 
+// Unwrap returns the sentinel error classifying e, if any.
+func (e ErrorNpy) Unwrap() error {
+	return e.Err
+}
+
+// MismatchError reports a tensor whose dtype or shape differs from what the
+// caller expected. An empty expected DType or nil expected Shape means that
+// property was not constrained.
+type MismatchError struct {
+	Name          string // Entry or field name, if known
+	ExpectedDType DType
+	ActualDType   DType
+	ExpectedShape Shape
+	ActualShape   Shape
+}
+
+func (e *MismatchError) Error() string {
+	var parts []string
+	if e.ExpectedDType != "" && e.ExpectedDType != e.ActualDType {
+		parts = append(parts, fmt.Sprintf("expected dtype %s, got %s", e.ExpectedDType, e.ActualDType))
+	}
+	if e.ExpectedShape != nil && !e.ExpectedShape.Equal(e.ActualShape) {
+		parts = append(parts, fmt.Sprintf("expected shape %v, got %v", e.ExpectedShape, e.ActualShape))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "tensor mismatch")
+	}
+	msg := strings.Join(parts, "; ")
+	if e.Name != "" {
+		msg = e.Name + ": " + msg
+	}
+	return fmt.Sprintf("npy error: %s", msg)
+}
+
+// checkTensor returns a *MismatchError if t does not have the expected dtype and shape.
+// An empty dtype or nil shape is not checked.
+func checkTensor(name string, t *Tensor, dtype DType, shape Shape) error {
+	if (dtype != "" && t.DType != dtype) || (shape != nil && !shape.Equal(t.Shape)) {
+		return &MismatchError{
+			Name:          name,
+			ExpectedDType: dtype,
+			ActualDType:   t.DType,
+			ExpectedShape: shape,
+			ActualShape:   t.Shape,
+		}
+	}
+	return nil
+}
+
 // readHeader reads the NPY header from the reader.
 func readHeader(r io.Reader) (string, error) {
 	magic := make([]byte, len(npyMagicString))
@@ -105,7 +276,7 @@ func readHeader(r io.Reader) (string, error) {
 		return "", err
 	}
 	if string(magic) != npyMagicString {
-		return "", ErrorNpy{Msg: "magic string mismatch"}
+		return "", ErrBadMagic
 	}
 
 	var version [2]byte
@@ -120,7 +291,7 @@ func readHeader(r io.Reader) (string, error) {
 	case 2:
 		headerLenLen = 4
 	default:
-		return "", ErrorNpy{Msg: fmt.Sprintf("unsupported version %d", version[0])}
+		return "", ErrorNpy{Msg: fmt.Sprintf("unsupported version %d", version[0]), Err: ErrUnsupportedVersion}
 	}
 
 	headerLenBytes := make([]byte, headerLenLen)
@@ -129,8 +300,18 @@ func readHeader(r io.Reader) (string, error) {
 	}
 
 	headerLen := int(binary.LittleEndian.Uint32(append(headerLenBytes, 0, 0)[:4])) // Pad to 4 bytes if needed
+	if headerLen > maxHeaderLen {
+		return "", ErrorNpy{Msg: fmt.Sprintf("header length %d exceeds the limit of %d", headerLen, maxHeaderLen)}
+	}
 
-	header := make([]byte, headerLen)
+	var header []byte
+	if headerLen <= headerPool.size {
+		buf := headerPool.get()
+		defer headerPool.put(buf)
+		header = (*buf)[:headerLen]
+	} else {
+		header = make([]byte, headerLen)
+	}
 	if _, err := io.ReadFull(r, header); err != nil {
 		return "", err
 	}
@@ -152,128 +333,209 @@ func (h *Header) String() (string, error) {
 		fortranOrder = "True"
 	}
 
-	var shapeStr string
-	if len(h.Shape) == 0 {
-		shapeStr = "()"
-	} else {
-		parts := make([]string, len(h.Shape))
-		for i, dim := range h.Shape {
-			parts[i] = strconv.Itoa(dim)
+	shapeStr := shapeLiteral(h.Shape)
+
+	descr, err := descrLiteral(h.Descr)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("{'descr': %s, 'fortran_order': %s, 'shape': %s, }", descr, fortranOrder, shapeStr), nil
+}
+
+// parseHeader parses the header string into a Header struct.
+func parseHeader(headerStr string) (*Header, error) {
+	v, err := parsePyLiteral(headerStr)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrorNpy{Msg: "header is not a dict"}
+	}
+
+	var descr DType
+	switch d := m["descr"].(type) {
+	case string:
+		if descr, err = parseDescr(d); err != nil {
+			return nil, err
+		}
+	case []interface{}:
+		sd, err := structuredFromLiteral(d)
+		if err != nil {
+			return nil, err
+		}
+		descr = sd.DType()
+	case nil:
+		return nil, ErrorNpy{Msg: "no descr in header"}
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid descr %v", d)}
+	}
+
+	fortranOrder := false
+	if fo, ok := m["fortran_order"]; ok {
+		if fortranOrder, ok = fo.(bool); !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unknown fortran_order %v", fo)}
 		}
-		shapeStr = "(" + strings.Join(parts, ",") + ",)"
 	}
 
-	var descr string
-	switch h.Descr {
+	rawShape, ok := m["shape"]
+	if !ok {
+		return nil, ErrorNpy{Msg: "no shape in header"}
+	}
+	shape, err := shapeFromLiteral(rawShape)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := shape.CheckedElemCount(); err != nil {
+		return nil, err
+	}
+	if size, ok := dataSize(descr, shape); !ok || size > math.MaxInt {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("data size of shape %v overflows", shape)}
+	}
+
+	return &Header{
+		Descr:        descr,
+		FortranOrder: fortranOrder,
+		Shape:        shape,
+	}, nil
+}
+
+// descrString returns the numpy descr string for a non-structured dtype.
+func descrString(d DType) (string, error) {
+	switch d {
 	case DTypeBF16:
-		return "", ErrorNpy{Msg: "bf16 is not supported for writing"}
+		// numpy has no bfloat16; ml_dtypes arrays are saved as two-byte voids
+		return "<V2", nil
 	case DTypeF16:
-		descr = "f2"
+		return "<f2", nil
 	case DTypeF32:
-		descr = "f4"
+		return "<f4", nil
 	case DTypeF64:
-		descr = "f8"
+		return "<f8", nil
+	case DTypeC64:
+		return "<c8", nil
+	case DTypeC128:
+		return "<c16", nil
+	case DTypeI8:
+		return "|i1", nil
+	case DTypeI32:
+		return "<i4", nil
 	case DTypeI64:
-		descr = "i8"
+		return "<i8", nil
+	case DTypeU64:
+		return "<u8", nil
 	case DTypeU32:
-		descr = "u4"
+		return "<u4", nil
 	case DTypeU8:
-		descr = "u1"
-	case DTypeF8E4M3:
-		return "", ErrorNpy{Msg: "f8e4m3 is not supported for writing"}
+		return "|u1", nil
+	case DTypeBool:
+		return "|b1", nil
+	case DTypeF8E4M3, DTypeF8E5M2:
+		// As for BF16, numpy saves ml_dtypes float8 arrays as voids
+		return "|V1", nil
 	default:
-		return "", ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", h.Descr)}
+		kind, width, ok := d.stringKind()
+		if !ok {
+			return "", ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", d), Err: ErrUnsupportedDType}
+		}
+		if kind == 'U' {
+			return fmt.Sprintf("<U%d", width), nil
+		}
+		return fmt.Sprintf("|S%d", width), nil
 	}
-
-	return fmt.Sprintf("{'descr': '<%s', 'fortran_order': %s, 'shape': %s, }", descr, fortranOrder, shapeStr), nil
 }
 
-// parseHeader parses the header string into a Header struct.
-func parseHeader(headerStr string) (*Header, error) {
-	// Trim outer braces and whitespace
-	headerStr = strings.Trim(headerStr, "{} \t\n\r,")
-
-	// Simple parser: split by top-level commas
-	re := regexp.MustCompile(`(?s)'([^']*)':\s*([^,]*?)(?:,\s*|$)`)
-
-	matches := re.FindAllStringSubmatch(headerStr, -1)
-	if len(matches) == 0 {
-		return nil, ErrorNpy{Msg: "unable to parse header"}
-	}
-
-	partMap := make(map[string]string)
-	for _, match := range matches {
-		if len(match) != 3 {
-			continue
+// descrLiteral returns the descr of a dtype as it appears in an NPY header,
+// as a quoted string or, for structured dtypes, a list of fields.
+func descrLiteral(d DType) (string, error) {
+	if d.isStructured() {
+		sd, err := ParseStructuredDType(d)
+		if err != nil {
+			return "", err
 		}
-		key := strings.Trim(match[1], "' ")
-		value := strings.Trim(match[2], "' ")
-		partMap[key] = value
+		return string(sd.DType()), nil
 	}
-
-	fortranOrder := false
-	if fo, ok := partMap["fortran_order"]; ok {
-		switch fo {
-		case "False":
-			fortranOrder = false
-		case "True":
-			fortranOrder = true
-		default:
-			return nil, ErrorNpy{Msg: fmt.Sprintf("unknown fortran_order %s", fo)}
-		}
+	descr, err := descrString(d)
+	if err != nil {
+		return "", err
 	}
+	return "'" + descr + "'", nil
+}
 
-	descrStr, ok := partMap["descr"]
-	if !ok || descrStr == "" {
-		return nil, ErrorNpy{Msg: "no descr in header"}
-	}
+// parseDescr maps a numpy descr string to a non-structured dtype.
+func parseDescr(descrStr string) (DType, error) {
 	if strings.HasPrefix(descrStr, ">") {
-		return nil, ErrorNpy{Msg: fmt.Sprintf("big-endian descr %s not supported", descrStr)}
+		return "", ErrorNpy{Msg: fmt.Sprintf("big-endian descr %s not supported", descrStr)}
 	}
 	descrStr = strings.Trim(descrStr, "=<>|")
-	var descr DType
 	switch descrStr {
+	case "V2", "bfloat16":
+		return DTypeBF16, nil
+	case "V1", "float8_e4m3fn", "f8e4m3":
+		return DTypeF8E4M3, nil
+	case "float8_e5m2", "f8e5m2":
+		return DTypeF8E5M2, nil
 	case "e", "f2":
-		descr = DTypeF16
+		return DTypeF16, nil
 	case "f", "f4":
-		descr = DTypeF32
+		return DTypeF32, nil
 	case "d", "f8":
-		descr = DTypeF64
+		return DTypeF64, nil
+	case "F", "c8":
+		return DTypeC64, nil
+	case "D", "c16":
+		return DTypeC128, nil
+	case "b", "i1":
+		return DTypeI8, nil
+	case "i", "i4":
+		return DTypeI32, nil
 	case "q", "i8":
-		descr = DTypeI64
+		return DTypeI64, nil
 	case "B", "u1":
-		descr = DTypeU8
+		return DTypeU8, nil
+	case "Q", "u8":
+		return DTypeU64, nil
 	case "I", "u4":
-		descr = DTypeU32
+		return DTypeU32, nil
 	case "?", "b1":
-		descr = DTypeU8 // Bool as U8
+		return DTypeBool, nil
+	case "O":
+		return DTypeObject, nil
 	default:
-		return nil, ErrorNpy{Msg: fmt.Sprintf("unrecognized descr %s", descrStr)}
+		if _, _, ok := DType(descrStr).stringKind(); !ok {
+			return "", ErrorNpy{Msg: fmt.Sprintf("unrecognized descr %s", descrStr), Err: ErrUnsupportedDType}
+		}
+		return DType(descrStr), nil
 	}
+}
 
-	shapeStr, ok := partMap["shape"]
-	if !ok {
-		return nil, ErrorNpy{Msg: "no shape in header"}
+// checkPayload verifies that n bytes of data are exactly what the header
+// describes. Object arrays, whose size is not fixed, are not checked.
+func checkPayload(h *Header, n int64) error {
+	if h.Descr == DTypeObject {
+		return nil
 	}
-	shapeStr = strings.Trim(shapeStr, "() ,")
-	var shape Shape
-	if shapeStr != "" {
-		parts := strings.Split(shapeStr, ",")
-		shape = make(Shape, len(parts))
-		for i, p := range parts {
-			dim, err := strconv.Atoi(strings.TrimSpace(p))
-			if err != nil {
-				return nil, err
-			}
-			shape[i] = dim
-		}
+	want, _ := dataSize(h.Descr, h.Shape)
+	if n != want {
+		return ErrorNpy{Msg: fmt.Sprintf("file holds %d bytes of data, %s shape %v needs %d", n, h.Descr, h.Shape, want)}
 	}
+	return nil
+}
 
-	return &Header{
-		Descr:        descr,
-		FortranOrder: fortranOrder,
-		Shape:        shape,
-	}, nil
+// readPayload reads array data of the given shape and dtype into memory from
+// the device or allocator, or with zero-copy reads, if requested.
+func readPayload(shape Shape, dtype DType, r io.Reader, o *options) (interface{}, error) {
+	switch {
+	case o.device != "":
+		return readDataDevice(shape, dtype, r, o.device)
+	case o.allocator != nil:
+		return readDataAlloc(shape, dtype, r, o.allocator, o.zeroCopy)
+	case o.zeroCopy:
+		return readDataZeroCopy(shape, dtype, r)
+	default:
+		return readData(shape, dtype, r)
+	}
 }
 
 // readData reads the tensor data from the reader based on shape and dtype.
@@ -281,69 +543,79 @@ func parseHeader(headerStr string) (*Header, error) {
 func readData(shape Shape, dtype DType, r io.Reader) (interface{}, error) {
 	elemCount := shape.ElemCount()
 
+	if dtype == DTypeObject {
+		return nil, ErrObjectArray
+	}
+	if _, _, ok := dtype.stringKind(); ok {
+		return readStrings(r, dtype, elemCount)
+	}
+	if dtype.isStructured() && dtype.Size() == 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid structured dtype %s", dtype)}
+	}
+
+	data, err := makeData(dtype, elemCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeInto(r, dtype, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// makeData allocates a zeroed data slice of n elements for a numeric dtype.
+func makeData(dtype DType, n int) (interface{}, error) {
 	switch dtype {
-	case DTypeBF16:
-		data := make([]uint16, elemCount) // Assume bf16 as uint16 bits
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
-	case DTypeF16:
-		data := make([]uint16, elemCount) // Assume f16 as uint16 bits
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
+	case DTypeBF16, DTypeF16:
+		return make([]uint16, n), nil
 	case DTypeF32:
-		data := make([]float32, elemCount)
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
+		return make([]float32, n), nil
 	case DTypeF64:
-		data := make([]float64, elemCount)
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
+		return make([]float64, n), nil
+	case DTypeC64:
+		return make([]complex64, n), nil
+	case DTypeC128:
+		return make([]complex128, n), nil
+	case DTypeI32:
+		return make([]int32, n), nil
 	case DTypeI64:
-		data := make([]int64, elemCount)
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
+		return make([]int64, n), nil
+	case DTypeU64:
+		return make([]uint64, n), nil
 	case DTypeU32:
-		data := make([]uint32, elemCount)
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
+		return make([]uint32, n), nil
 	case DTypeU8:
-		data := make([]byte, elemCount)
-		if _, err := io.ReadFull(r, data); err != nil {
-			return nil, err
-		}
-		return data, nil
-	case DTypeF8E4M3:
-		data := make([]int8, elemCount) // Assume f8e4m3 as int8 bits
-		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
-			return nil, err
-		}
-		return data, nil
-	default:
-		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", dtype)}
+		return make([]byte, n), nil
+	case DTypeBool:
+		return make([]bool, n), nil
+	case DTypeI8, DTypeF8E4M3, DTypeF8E5M2:
+		return make([]int8, n), nil
 	}
+	if _, _, ok := dtype.stringKind(); ok {
+		return make([]string, n), nil
+	}
+	if dtype.isStructured() {
+		return make([]byte, n*dtype.Size()), nil
+	}
+	return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", dtype), Err: ErrUnsupportedDType}
 }
 
-// ReadNPY reads a single tensor from an NPY file.
-func ReadNPY(path string) (*Tensor, error) {
-	f, err := os.Open(path)
+// readTensor reads an NPY header and the tensor data that follows it. If size
+// is not negative, it is the length of the NPY content in r, which must match
+// what the header describes.
+func readTensor(r io.Reader, size int64, o *options) (*Tensor, error) {
+	cr := &countingReader{r: o.tracker.reader(o.cancelableReader(r))}
+	t, err := decodeTensor(cr, size, o)
 	if err != nil {
-		return nil, err
+		return nil, readFailure(err, cr.n)
 	}
-	defer f.Close()
+	return t, nil
+}
 
-	headerStr, err := readHeader(f)
+// decodeTensor does the work of readTensor, reading through r so that the
+// position of a failure is known.
+func decodeTensor(r *countingReader, size int64, o *options) (*Tensor, error) {
+	headerStr, err := readHeader(r)
 	if err != nil {
 		return nil, err
 	}
@@ -352,11 +624,25 @@ func ReadNPY(path string) (*Tensor, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := o.checkSize(header.Descr, header.Shape); err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		if err := checkPayload(header, size-r.n); err != nil {
+			return nil, err
+		}
+	}
+	if header.Descr == DTypeObject {
+		if !o.allowPickle {
+			return nil, ErrObjectArray
+		}
+		return readObjectArray(r, header)
+	}
 	if header.FortranOrder {
-		return nil, ErrorNpy{Msg: "fortran order not supported"}
+		return nil, ErrFortranOrder
 	}
 
-	data, err := readData(header.Shape, header.Descr, f)
+	data, err := readPayload(header.Shape, header.Descr, r, o)
 	if err != nil {
 		return nil, err
 	}
@@ -365,259 +651,427 @@ func ReadNPY(path string) (*Tensor, error) {
 		Data:   data,
 		Shape:  header.Shape,
 		DType:  header.Descr,
-		Device: "cpu", // Assume CPU
+		Device: o.deviceName(),
 	}, nil
 }
 
-// ReadNPZ reads all named tensors from an NPZ file.
-func ReadNPZ(path string) ([]struct {
+// ReadNPY reads a single tensor from an NPY file.
+func ReadNPY(path string, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	o.track(info.Size())
+	t, err := readTensor(f, info.Size(), o)
+	if err != nil {
+		return nil, locate(err, path, "")
+	}
+	return t, nil
+}
+
+// ReadNPYFrom reads a single tensor in NPY format from a reader.
+func ReadNPYFrom(r io.Reader, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.track(-1)
+	return readTensor(r, -1, o)
+}
+
+// NamedTensor pairs a tensor with its name in an NPZ archive.
+type NamedTensor = struct {
 	Name   string
 	Tensor *Tensor
-}, error) {
+}
+
+// ReadNPZ reads all named tensors from an NPZ file in archive order.
+func ReadNPZ(path string, opts ...Option) ([]NamedTensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
 	r, err := zip.OpenReader(path)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
 
-	var result []struct {
-		Name   string
-		Tensor *Tensor
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.loadChecksums(r.File); err != nil {
+		return nil, err
 	}
-	for _, file := range r.File {
-		rc, err := file.Open()
-		if err != nil {
-			return nil, err
-		}
-		defer rc.Close()
-
-		name := strings.TrimSuffix(file.Name, npySuffix)
-
-		headerStr, err := readHeader(rc)
-		if err != nil {
-			return nil, err
-		}
 
-		header, err := parseHeader(headerStr)
-		if err != nil {
-			return nil, err
-		}
-		if header.FortranOrder {
-			return nil, ErrorNpy{Msg: "fortran order not supported"}
-		}
+	files, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, err
+	}
+	o.track(entriesSize(files))
 
-		data, err := readData(header.Shape, header.Descr, rc)
+	var result []NamedTensor
+	var skipped []error
+	for _, file := range files {
+		nt, err := readEntry(file, packing, o)
 		if err != nil {
-			return nil, err
+			if !o.skipFailure() {
+				return nil, locate(err, path, "")
+			}
+			skipped = append(skipped, locate(err, path, ""))
+			continue
 		}
+		result = append(result, nt)
+	}
+	return result, partialFailure(path, skipped)
+}
 
-		result = append(result, struct {
-			Name   string
-			Tensor *Tensor
-		}{
-			Name: name,
-			Tensor: &Tensor{
-				Data:   data,
-				Shape:  header.Shape,
-				DType:  header.Descr,
-				Device: "cpu",
-			},
-		})
+// readEntry decodes the tensor stored in a zip entry, restoring packed tensors.
+func readEntry(file *zip.File, packing map[string]packingInfo, o *options) (NamedTensor, error) {
+	name := o.nameCodec.Decode(file.Name)
+	tensor, err := readEntryTensor(file, name, packing, o)
+	if err != nil {
+		return NamedTensor{}, locate(err, "", name)
 	}
-	return result, nil
+	return NamedTensor{Name: name, Tensor: tensor}, nil
 }
 
 // ReadNPZByName reads specific named tensors from an NPZ file.
-func ReadNPZByName(path string, names []string) ([]*Tensor, error) {
+func ReadNPZByName(path string, names []string, opts ...Option) ([]*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
 	r, err := zip.OpenReader(path)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
 
-	var result []*Tensor
-	for _, name := range names {
-		fileName := name + npySuffix
-		file, err := r.Open(fileName)
-		if err != nil {
-			if errors.Is(err, zip.ErrFormat) || strings.Contains(err.Error(), "not found") {
-				return nil, ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, path)}
-			}
-			return nil, err
-		}
-		defer file.Close()
-
-		headerStr, err := readHeader(file)
-		if err != nil {
-			return nil, err
-		}
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.loadChecksums(r.File); err != nil {
+		return nil, err
+	}
 
-		header, err := parseHeader(headerStr)
-		if err != nil {
-			return nil, err
-		}
-		if header.FortranOrder {
-			return nil, ErrorNpy{Msg: "fortran order not supported"}
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, err
+	}
+	byEntry := make(map[string]*zip.File, len(entries))
+	for _, file := range entries {
+		byEntry[file.Name] = file
+	}
+	files := make([]*zip.File, len(names))
+	for i, name := range names {
+		file, ok := byEntry[o.nameCodec.Encode(name)]
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, path), Err: ErrEntryNotFound}
 		}
+		files[i] = file
+	}
+	o.track(entriesSize(files))
 
-		data, err := readData(header.Shape, header.Descr, file)
+	var result []*Tensor
+	for i, name := range names {
+		tensor, err := readEntryTensor(files[i], name, packing, o)
 		if err != nil {
-			return nil, err
+			return nil, locate(err, path, name)
 		}
-
-		result = append(result, &Tensor{
-			Data:   data,
-			Shape:  header.Shape,
-			DType:  header.Descr,
-			Device: "cpu",
-		})
+		result = append(result, tensor)
 	}
 	return result, nil
 }
 
-// writeData writes the tensor data to the writer.
-func writeData(w io.Writer, data interface{}) error {
+// writeData writes the tensor data of the given dtype to the writer.
+func writeData(w io.Writer, dtype DType, data interface{}) error {
 	switch d := data.(type) {
-	case []uint16: // BF16 or F16
-		return binary.Write(w, binary.LittleEndian, d)
-	case []float32:
-		return binary.Write(w, binary.LittleEndian, d)
-	case []float64:
-		return binary.Write(w, binary.LittleEndian, d)
-	case []int64:
-		return binary.Write(w, binary.LittleEndian, d)
-	case []uint32:
-		return binary.Write(w, binary.LittleEndian, d)
 	case []byte:
 		_, err := w.Write(d)
 		return err
-	case []int8: // F8E4M3
-		return binary.Write(w, binary.LittleEndian, d)
+	case []string:
+		return writeStrings(w, dtype, d)
 	default:
-		return ErrorNpy{Msg: "unsupported data type for writing"}
+		return encodeFrom(w, data)
 	}
 }
 
 // Write writes the tensor to the writer in NPY format.
-func (t *Tensor) Write(w io.Writer) error {
-	if _, err := w.Write([]byte(npyMagicString)); err != nil {
-		return err
-	}
-	if _, err := w.Write([]byte{1, 0}); err != nil { // Version 1.0
-		return err
+func (t *Tensor) Write(w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+	if o.progress != nil {
+		size, err := t.encodedSize(o)
+		if err != nil {
+			return err
+		}
+		o.track(size)
 	}
+	return t.write(w, o)
+}
 
-	header := &Header{
-		Descr:        t.DType,
-		FortranOrder: false,
-		Shape:        t.Shape,
+// encodeHeader returns the magic string, version, header length and padded
+// header of an NPY file. The header is padded so that the data starts at a
+// multiple of align bytes, or to a total length of size bytes if size is positive.
+func encodeHeader(h *Header, align, size int) ([]byte, error) {
+	if align <= 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid header alignment %d", align)}
 	}
-	headerStr, err := header.String()
+	headerStr, err := h.String()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Pad to 16-byte alignment
+	// Pad so that the prefix, including the terminating newline, ends on an alignment boundary
 	totalPrefixLen := len(npyMagicString) + 2 + 2 + len(headerStr) // Magic + version + len + header
-	pad := (16 - (totalPrefixLen % 16)) % 16
+	pad := (align - (totalPrefixLen+1)%align) % align
+	if size > 0 {
+		pad = size - totalPrefixLen - 1
+		if pad < 0 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("header does not fit in %d bytes", size)}
+		}
+	}
 	headerStr += strings.Repeat(" ", pad) + "\n"
 
-	headerLen := uint16(len(headerStr))
-	lenBytes := make([]byte, 2)
-	binary.LittleEndian.PutUint16(lenBytes, headerLen)
-	if _, err := w.Write(lenBytes); err != nil {
+	prefix := make([]byte, 0, totalPrefixLen+pad+1)
+	prefix = append(prefix, npyMagicString...)
+	prefix = append(prefix, 1, 0) // Version 1.0
+	prefix = binary.LittleEndian.AppendUint16(prefix, uint16(len(headerStr)))
+	return append(prefix, headerStr...), nil
+}
+
+// checkData verifies that the tensor's data is a slice of the type its dtype
+// calls for, with as many elements as its shape.
+func (t *Tensor) checkData() error {
+	want, err := makeData(t.DType, 0)
+	if err != nil {
+		return err
+	}
+	if reflect.TypeOf(t.Data) != reflect.TypeOf(want) {
+		return ErrorNpy{Msg: fmt.Sprintf("%s tensor data is %T, expected %T", t.DType, t.Data, want)}
+	}
+	n, count := reflect.ValueOf(t.Data).Len(), t.Shape.ElemCount()
+	if t.DType.isStructured() {
+		count *= t.DType.Size() // Records are raw bytes
+	}
+	if n != count {
+		return ErrorNpy{Msg: fmt.Sprintf("%s tensor has %d data elements, shape %v needs %d", t.DType, n, t.Shape, count)}
+	}
+	return nil
+}
+
+// write writes the tensor in NPY format using already collected options.
+func (t *Tensor) write(w io.Writer, o *options) error {
+	t, err := t.storedAs(o)
+	if err != nil {
+		return err
+	}
+	if t.DType.isPacked() {
+		if t, err = t.packedStorage(); err != nil {
+			return err
+		}
+	}
+	if err := t.checkData(); err != nil {
 		return err
 	}
 
-	if _, err := w.Write([]byte(headerStr)); err != nil {
+	prefix, err := encodeHeader(&Header{
+		Descr:        t.DType,
+		FortranOrder: false,
+		Shape:        t.Shape,
+	}, o.headerAlign, 0)
+	if err != nil {
+		return err
+	}
+	w = o.tracker.writer(o.cancelableWriter(w))
+	if _, err := w.Write(prefix); err != nil {
 		return err
 	}
 
-	return writeData(w, t.Data)
+	if o.zeroCopy {
+		return writeDataZeroCopy(w, t.DType, t.Data)
+	}
+	return writeData(w, t.DType, t.Data)
 }
 
 // WriteNPY writes the tensor to an NPY file.
-func (t *Tensor) WriteNPY(path string) error {
-	f, err := os.Create(path)
+func (t *Tensor) WriteNPY(path string, opts ...Option) error {
+	f, err := createOutput(path, newOptions(opts))
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	return t.Write(f)
+
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	if err := t.Write(bw, opts...); err != nil {
+		f.discard()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
 }
 
-// WriteNPZ writes multiple named tensors to an NPZ file.
-func WriteNPZ(path string, tensors map[string]*Tensor) error {
-	f, err := os.Create(path)
+// WriteNPZ writes multiple named tensors to an NPZ file. Entries are written in
+// sorted name order, so the same tensors always produce the same bytes.
+func WriteNPZ(path string, tensors map[string]*Tensor, opts ...Option) error {
+	return WriteNPZOrdered(path, sortedTensors(tensors), opts...)
+}
+
+// WriteNPZOrdered writes named tensors to an NPZ file in the given order.
+func WriteNPZOrdered(path string, tensors []NamedTensor, opts ...Option) error {
+	nw, err := NewNpzWriter(path, opts...)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-
-	zw := zip.NewWriter(f)
-	defer zw.Close()
+	if err := nw.addAll(tensors); err != nil {
+		nw.f.discard()
+		return err
+	}
+	return nw.Close()
+}
 
-	for name, tensor := range tensors {
-		w, err := zw.Create(name + npySuffix)
-		if err != nil {
-			return err
+// addAll adds tensors to the archive in order, setting the total of any
+// progress tracking first.
+func (nw *NpzWriter) addAll(tensors []NamedTensor) error {
+	if nw.o.tracker != nil {
+		var total int64
+		for _, nt := range tensors {
+			size, err := nt.Tensor.encodedSize(nw.o)
+			if err != nil {
+				return err
+			}
+			total += size
 		}
-		if err := tensor.Write(w); err != nil {
+		nw.o.tracker.total = total
+	}
+	for _, nt := range tensors {
+		if err := nw.Add(nt.Name, nt.Tensor); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// NpzTensors provides lazy loading of tensors from an NPZ file.
+// sortedTensors returns the entries of tensors ordered by name.
+func sortedTensors(tensors map[string]*Tensor) []NamedTensor {
+	sorted := make([]NamedTensor, 0, len(tensors))
+	for name, t := range tensors {
+		sorted = append(sorted, NamedTensor{Name: name, Tensor: t})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// NpzTensors provides lazy loading of tensors from an NPZ file. The archive is
+// kept open, occupying one slot of the file limiter, until Close is called.
+// Tensors may be loaded concurrently.
 type NpzTensors struct {
-	indexPerName map[string]int
-	path         string
+	mu       sync.Mutex // Guards r
+	r        *zip.ReadCloser
+	files    map[string]*zip.File
+	packing  map[string]packingInfo
+	path     string
+	opts     *options
+	cache    *tensorCache
+	preloads sync.WaitGroup
 }
 
-// NewNpzTensors creates a new lazy loader for an NPZ file.
-func NewNpzTensors(path string) (*NpzTensors, error) {
+// NewNpzTensors opens an NPZ file for lazy loading.
+func NewNpzTensors(path string, opts ...Option) (*NpzTensors, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
 	r, err := zip.OpenReader(path)
 	if err != nil {
+		o.limiter.Release()
+		return nil, err
+	}
+
+	packing, err := readPacking(r.File)
+	if err == nil {
+		err = o.loadChecksums(r.File)
+	}
+	if err != nil {
+		r.Close()
+		o.limiter.Release()
 		return nil, err
 	}
-	defer r.Close()
 
-	indexPerName := make(map[string]int)
-	for i, file := range r.File {
-		name := strings.TrimSuffix(file.Name, npySuffix)
-		indexPerName[name] = i
+	entries, err := tensorEntries(r.File, o)
+	if err != nil {
+		r.Close()
+		o.limiter.Release()
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(entries))
+	for _, file := range entries {
+		files[o.nameCodec.Decode(file.Name)] = file
 	}
 
 	return &NpzTensors{
-		indexPerName: indexPerName,
-		path:         path,
+		r:       r,
+		files:   files,
+		packing: packing,
+		path:    path,
+		opts:    o,
+		cache:   newTensorCache(o.cacheBytes),
 	}, nil
 }
 
+// Close waits for pending preloads and closes the underlying archive. Tensors
+// already loaded remain valid.
+func (n *NpzTensors) Close() error {
+	n.preloads.Wait()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.r == nil {
+		return nil
+	}
+	err := n.r.Close()
+	n.r = nil
+	n.opts.limiter.Release()
+	return err
+}
+
 // Names returns the list of tensor names in the NPZ file.
 func (n *NpzTensors) Names() []string {
-	names := make([]string, 0, len(n.indexPerName))
-	for name := range n.indexPerName {
+	names := make([]string, 0, len(n.files))
+	for name := range n.files {
 		names = append(names, name)
 	}
 	return names
 }
 
-// GetShapeAndDType returns the shape and dtype for a named tensor without loading data.
-func (n *NpzTensors) GetShapeAndDType(name string) (Shape, DType, error) {
-	index, ok := n.indexPerName[name]
+// file returns the archive entry holding the named tensor.
+func (n *NpzTensors) file(name string) (*zip.File, error) {
+	n.mu.Lock()
+	closed := n.r == nil
+	n.mu.Unlock()
+	if closed {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s is closed", n.path)}
+	}
+	file, ok := n.files[name]
 	if !ok {
-		return nil, "", fmt.Errorf("cannot find tensor %s", name)
+		return nil, ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", name, n.path), Err: ErrEntryNotFound}
 	}
+	return file, nil
+}
 
-	r, err := zip.OpenReader(n.path)
+// GetShapeAndDType returns the shape and dtype for a named tensor without loading data.
+func (n *NpzTensors) GetShapeAndDType(name string) (Shape, DType, error) {
+	file, err := n.file(name)
 	if err != nil {
 		return nil, "", err
 	}
-	defer r.Close()
 
-	rc, err := r.File[index].Open()
+	rc, err := file.Open()
 	if err != nil {
 		return nil, "", err
 	}
@@ -632,51 +1086,37 @@ func (n *NpzTensors) GetShapeAndDType(name string) (Shape, DType, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	if info, ok := n.packing[name]; ok {
+		return info.Shape, info.DType, nil
+	}
 
 	return header.Shape, header.Descr, nil
 }
 
-// Get loads a named tensor from the NPZ file.
+// Get loads a named tensor from the NPZ file, or returns it from the cache.
 func (n *NpzTensors) Get(name string) (*Tensor, error) {
-	index, ok := n.indexPerName[name]
-	if !ok {
-		return nil, fmt.Errorf("cannot find tensor %s", name)
-	}
-
-	r, err := zip.OpenReader(n.path)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
+	return n.get(name, n.opts)
+}
 
-	rc, err := r.File[index].Open()
-	if err != nil {
-		return nil, err
+// get loads a named tensor using options o.
+func (n *NpzTensors) get(name string, o *options) (*Tensor, error) {
+	if t, ok := n.cache.get(name); ok {
+		return t, nil
 	}
-	defer rc.Close()
-
-	headerStr, err := readHeader(rc)
+	file, err := n.file(name)
 	if err != nil {
 		return nil, err
 	}
 
-	header, err := parseHeader(headerStr)
-	if err != nil {
-		return nil, err
-	}
-	if header.FortranOrder {
-		return nil, ErrorNpy{Msg: "fortran order not supported"}
+	if o.progress != nil {
+		tracked := *o
+		tracked.track(int64(file.UncompressedSize64))
+		o = &tracked
 	}
-
-	data, err := readData(header.Shape, header.Descr, rc)
+	tensor, err := readEntryTensor(file, name, n.packing, o)
 	if err != nil {
-		return nil, err
+		return nil, locate(err, n.path, name)
 	}
-
-	return &Tensor{
-		Data:   data,
-		Shape:  header.Shape,
-		DType:  header.Descr,
-		Device: "cpu",
-	}, nil
+	n.cache.put(name, tensor)
+	return tensor, nil
 }
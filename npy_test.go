@@ -0,0 +1,114 @@
+package gonpy
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestNpzTensorsConcurrentClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	err := WriteNPZ(path, map[string]*Tensor{
+		"x": {Data: []float32{1, 2, 3}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewFileLimiter(2)
+	l.Acquire() // A slot that a second release would steal
+	n, err := NewNpzTensors(path, WithFileLimiter(l))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.Close()
+			n.Get("x")
+		}()
+	}
+	wg.Wait()
+	if got := len(l.sem); got != 1 {
+		t.Errorf("%d limiter slots held after Close, want 1", got)
+	}
+	if _, err := n.Get("x"); err == nil {
+		t.Error("Get after Close succeeded")
+	}
+}
+
+func TestDTypeRoundTrip(t *testing.T) {
+	tests := []struct {
+		in    *Tensor
+		descr string
+	}{
+		{&Tensor{Data: []int32{-1 << 31, 7}, Shape: Shape{2}, DType: DTypeI32}, "<i4"},
+		{&Tensor{Data: []uint64{1<<64 - 1, 0}, Shape: Shape{2}, DType: DTypeU64}, "<u8"},
+		{&Tensor{Data: []complex64{1 - 2i}, Shape: Shape{1}, DType: DTypeC64}, "<c8"},
+		{&Tensor{Data: []complex128{-3.5 + 0.25i, 0}, Shape: Shape{1, 2}, DType: DTypeC128}, "<c16"},
+		{&Tensor{Data: []bool{true, false, true}, Shape: Shape{3}, DType: DTypeBool}, "|b1"},
+		{&Tensor{Data: []string{"ab", ""}, Shape: Shape{2}, DType: BytesDType(2)}, "|S2"},
+		{&Tensor{Data: []string{"é", "xyz"}, Shape: Shape{2}, DType: UnicodeDType(3)}, "<U3"},
+		{&Tensor{Data: []int8{0x38, -0x40}, Shape: Shape{2}, DType: DTypeF8E4M3}, "|V1"},
+	}
+	for _, tt := range tests {
+		tt.in.Device = "cpu"
+		var b bytes.Buffer
+		if err := tt.in.Write(&b); err != nil {
+			t.Fatalf("%s: %v", tt.in.DType, err)
+		}
+		if want := fmt.Sprintf("{'descr': '%s', ", tt.descr); !bytes.Contains(b.Bytes(), []byte(want)) {
+			t.Errorf("%s: header %q does not contain %q", tt.in.DType, b.Bytes()[10:], want)
+		}
+		out, err := ReadNPYFrom(&b)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in.DType, err)
+		}
+		if out.DType != tt.in.DType || !out.Shape.Equal(tt.in.Shape) || fmt.Sprint(out.Data) != fmt.Sprint(tt.in.Data) {
+			t.Errorf("%s %v read back as %s %v %v", tt.in.DType, tt.in.Data, out.DType, out.Shape, out.Data)
+		}
+	}
+}
+
+func TestF8E5M2ReadsAsE4M3(t *testing.T) {
+	in := &Tensor{Data: []int8{0x3c, -0x40}, Shape: Shape{2}, DType: DTypeF8E5M2, Device: "cpu"}
+	var b bytes.Buffer
+	if err := in.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadNPYFrom(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bytes survive; only the format is lost
+	if out.DType != DTypeF8E4M3 || fmt.Sprint(out.Data) != fmt.Sprint(in.Data) {
+		t.Errorf("read back as %s %v", out.DType, out.Data)
+	}
+}
+
+// mlDtypesFP8 is what np.save writes for
+// np.array([1, -2, 0.5, 448], dtype=ml_dtypes.float8_e4m3fn).
+var mlDtypesFP8 = []byte("\x93NUMPY\x01\x00v\x00" +
+	"{'descr': '|V1', 'fortran_order': False, 'shape': (4,), }" +
+	"                                                            \n" +
+	"\x38\xc0\x30\x7e")
+
+func TestMLDtypesFP8Fixture(t *testing.T) {
+	out, err := ReadNPYFrom(bytes.NewReader(mlDtypesFP8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.DType != DTypeF8E4M3 || !out.Shape.Equal(Shape{4}) || fmt.Sprint(out.Data) != "[56 -64 48 126]" {
+		t.Fatalf("read as %s %v %v", out.DType, out.Shape, out.Data)
+	}
+	var b bytes.Buffer
+	if err := out.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), mlDtypesFP8) {
+		t.Errorf("written as %q, want the bytes numpy writes", b.Bytes())
+	}
+}
@@ -0,0 +1,124 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// NpyFile is an open NPY file whose header has been read but whose data is
+// loaded only on request, in the manner of NpzTensors for single files. The
+// file occupies one slot of the file limiter until Close is called.
+type NpyFile struct {
+	f      *os.File
+	header *Header
+	offset int64
+	o      *options
+}
+
+// OpenNPY opens an NPY file and reads its header.
+func OpenNPY(path string, opts ...Option) (*NpyFile, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	f, err := os.Open(path)
+	if err != nil {
+		o.limiter.Release()
+		return nil, err
+	}
+	nf, err := openNPY(f, o)
+	if err != nil {
+		f.Close()
+		o.limiter.Release()
+		return nil, err
+	}
+	return nf, nil
+}
+
+// openNPY reads the header of f and checks that the file holds all the data it describes.
+func openNPY(f *os.File, o *options) (*NpyFile, error) {
+	header, err := readSeekableHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPayload(header, info.Size()-offset); err != nil {
+		return nil, err
+	}
+	return &NpyFile{f: f, header: header, offset: offset, o: o}, nil
+}
+
+// Close closes the file.
+func (nf *NpyFile) Close() error {
+	if nf.f == nil {
+		return nil
+	}
+	err := nf.f.Close()
+	nf.f = nil
+	nf.o.limiter.Release()
+	return err
+}
+
+// Shape returns the shape of the array.
+func (nf *NpyFile) Shape() Shape {
+	return nf.header.Shape
+}
+
+// DType returns the dtype of the array.
+func (nf *NpyFile) DType() DType {
+	return nf.header.Descr
+}
+
+// ByteOffset returns the offset of the array data from the start of the file.
+func (nf *NpyFile) ByteOffset() int64 {
+	return nf.offset
+}
+
+// ReadAll reads the whole array.
+func (nf *NpyFile) ReadAll() (*Tensor, error) {
+	if len(nf.header.Shape) == 0 {
+		return nf.read(nf.header.Shape, nf.offset)
+	}
+	return nf.ReadRange(0, nf.header.Shape[0])
+}
+
+// ReadRange reads rows [start, end) of the leading dimension.
+func (nf *NpyFile) ReadRange(start, end int) (*Tensor, error) {
+	if len(nf.header.Shape) == 0 {
+		return nil, ErrorNpy{Msg: "cannot read rows of a scalar"}
+	}
+	if start < 0 || end < start || end > nf.header.Shape[0] {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("rows [%d, %d) out of range for shape %v", start, end, nf.header.Shape)}
+	}
+	rowBytes := int64(nf.header.Descr.Size() * nf.header.Shape[1:].ElemCount())
+	shape := append(Shape{end - start}, nf.header.Shape[1:]...)
+	return nf.read(shape, nf.offset+int64(start)*rowBytes)
+}
+
+// read decodes an array of the given shape starting at byte offset off. It
+// uses positioned reads, so concurrent calls are safe.
+func (nf *NpyFile) read(shape Shape, off int64) (*Tensor, error) {
+	if nf.f == nil {
+		return nil, ErrorNpy{Msg: "file is closed"}
+	}
+	if err := nf.o.checkSize(nf.header.Descr, shape); err != nil {
+		return nil, err
+	}
+	n := int64(nf.header.Descr.Size() * shape.ElemCount())
+	data, err := readData(shape, nf.header.Descr, io.NewSectionReader(nf.f, off, n))
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  nf.header.Descr,
+		Device: "cpu",
+	}, nil
+}
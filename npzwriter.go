@@ -0,0 +1,212 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/flate"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// NpzWriter creates an NPZ archive one tensor at a time, so that tensors can be
+// produced and released incrementally instead of being held in memory together.
+type NpzWriter struct {
+	f       *outputFile
+	bw      *bufio.Writer
+	zw      *zip.Writer
+	o       *options
+	entries map[string]bool // Names of the entries written, as encoded
+	packing map[string]packingInfo
+	sums    map[string]uint32
+	level   int   // Deflate level of the entry being created
+	err     error // First failed entry write; the archive is abandoned
+}
+
+// EntryOptions controls how NpzWriter stores a single entry.
+type EntryOptions struct {
+	Method uint16 // zip.Store or zip.Deflate
+	Level  int    // Deflate level from flate.HuffmanOnly to flate.BestCompression; 0 means flate.DefaultCompression
+}
+
+// NewNpzWriter creates the NPZ file at path and returns a writer for its entries.
+// The archive is incomplete until Close is called; with WithAtomicWrite, it only
+// appears at path then.
+func NewNpzWriter(path string, opts ...Option) (*NpzWriter, error) {
+	o := newOptions(opts)
+	f, err := createOutput(path, o)
+	if err != nil {
+		return nil, err
+	}
+	return newNpzWriter(f, o), nil
+}
+
+// newNpzWriter returns a writer that builds an archive in f.
+func newNpzWriter(f *outputFile, o *options) *NpzWriter {
+	if o.tracker == nil {
+		o.track(-1)
+	}
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	nw := &NpzWriter{
+		f:       f,
+		bw:      bw,
+		zw:      zip.NewWriter(bw),
+		o:       o,
+		entries: make(map[string]bool),
+		packing: make(map[string]packingInfo),
+		sums:    make(map[string]uint32),
+	}
+	nw.zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, nw.level)
+	})
+	return nw
+}
+
+// Add writes a tensor to the archive under name, compressed with Deflate at
+// the default level. The tensor is encoded immediately and is not retained by
+// the writer.
+func (nw *NpzWriter) Add(name string, t *Tensor) error {
+	return nw.AddWith(name, t, EntryOptions{Method: zip.Deflate})
+}
+
+// AddWith writes a tensor to the archive under name, stored as described by eo.
+// Names whose entry would not be a local relative path, such as "../x", and
+// names encoding to the entry of an earlier tensor are rejected. If writing
+// the entry fails, the archive is left incomplete: later calls to Add and
+// Close return the same error, and Close discards the output.
+func (nw *NpzWriter) AddWith(name string, t *Tensor, eo EntryOptions) error {
+	if nw.err != nil {
+		return nw.err
+	}
+	entry := nw.o.nameCodec.Encode(name)
+	if _, err := sanitizeEntryName(entry); err != nil {
+		return err
+	}
+	if nw.entries[entry] || isMetadataEntry(entry) {
+		return ErrorNpy{Msg: fmt.Sprintf("duplicate entry %s for array %s", entry, name)}
+	}
+	if eo.Method != zip.Store && eo.Method != zip.Deflate {
+		return ErrorNpy{Msg: fmt.Sprintf("unsupported compression method %d", eo.Method)}
+	}
+	if eo.Level < flate.HuffmanOnly || eo.Level > flate.BestCompression {
+		return ErrorNpy{Msg: fmt.Sprintf("invalid compression level %d", eo.Level)}
+	}
+	nw.level = eo.Level
+	if nw.level == 0 {
+		nw.level = flate.DefaultCompression
+	}
+	w, err := nw.zw.CreateHeader(&zip.FileHeader{Name: entry, Method: eo.Method})
+	if err != nil {
+		nw.err = err
+		return err
+	}
+	h := crc32.New(castagnoli)
+	if nw.o.writeChecksums {
+		w = io.MultiWriter(w, h)
+	}
+	if err := t.write(w, nw.o); err != nil {
+		nw.err = err
+		return err
+	}
+	nw.entries[entry] = true
+	if nw.o.writeChecksums {
+		nw.sums[name] = h.Sum32()
+	}
+	if t.DType.isPacked() {
+		nw.packing[name] = packingInfo{DType: t.DType, Shape: t.Shape, Order: packingOrder}
+	}
+	return nil
+}
+
+// Close writes the archive's metadata and central directory and closes the file.
+func (nw *NpzWriter) Close() error {
+	if nw.err != nil {
+		nw.f.discard()
+		return nw.err
+	}
+	err := writePacking(nw.zw, nw.packing)
+	if err == nil {
+		err = writeChecksums(nw.zw, nw.sums)
+	}
+	if cerr := nw.zw.Close(); err == nil {
+		err = cerr
+	}
+	if ferr := nw.bw.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		nw.f.discard()
+		return err
+	}
+	return nw.f.commit()
+}
+
+// AppendNPZ adds tensors to an existing NPZ archive. The whole archive is
+// rewritten, not just its central directory: existing entries are copied
+// without being decompressed into a new archive alongside the original, which
+// then replaces it atomically. Appending therefore takes time proportional to
+// the size of the archive and needs disk space for a second copy of it. If the
+// archive records checksums, they are also recorded for the new tensors, with
+// or without WithChecksums. It is an error for a new tensor to reuse the name
+// of an existing one.
+func AppendNPZ(path string, tensors map[string]*Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return err
+	}
+	sums, err := readChecksums(r.File)
+	if err != nil {
+		return err
+	}
+	if sums != nil {
+		// Keep the manifest complete, so verifying the new tensors does not fail
+		o.writeChecksums = true
+	}
+
+	tmp, err := createTemp(path, o)
+	if err != nil {
+		return err
+	}
+
+	nw := newNpzWriter(tmp, o)
+	for name, info := range packing {
+		nw.packing[name] = info
+	}
+	for name, sum := range sums {
+		nw.sums[name] = sum
+	}
+	if err := nw.append(r.File, sortedTensors(tensors)); err != nil {
+		tmp.discard()
+		return err
+	}
+	r.Close()
+	return nw.Close()
+}
+
+// append copies the tensor entries of files into the archive, then adds tensors.
+func (nw *NpzWriter) append(files []*zip.File, tensors []NamedTensor) error {
+	for _, file := range files {
+		if isMetadataEntry(file.Name) {
+			continue
+		}
+		if err := nw.zw.Copy(file); err != nil {
+			return err
+		}
+		nw.entries[file.Name] = true
+	}
+	for _, nt := range tensors {
+		if err := nw.Add(nt.Name, nt.Tensor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
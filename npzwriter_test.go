@@ -0,0 +1,161 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNpzWriterFailedEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	nw, err := NewNpzWriter(path, WithAtomicWrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := &Tensor{Data: []float32{1, 2, 3}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"}
+	bad := &Tensor{Data: []float32{1}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"}
+	if err := nw.Add("x", good); err != nil {
+		t.Fatal(err)
+	}
+	if err := nw.Add("y", bad); err == nil {
+		t.Fatal("Add of a short tensor succeeded")
+	}
+	if err := nw.Add("z", good); err == nil {
+		t.Error("Add after a failed entry succeeded")
+	}
+	if err := nw.Close(); err == nil {
+		t.Error("Close after a failed entry succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("abandoned archive was written: %v", err)
+	}
+}
+
+func TestNpzWriterEntryNames(t *testing.T) {
+	x := &Tensor{Data: []float32{1}, Shape: Shape{1}, DType: DTypeF32, Device: "cpu"}
+	lower := WithNameCodec(ConfigurableNameCodec{Suffix: ".npy", Lowercase: true})
+	tests := map[string]struct {
+		tensors []NamedTensor
+		opts    []Option
+	}{
+		"parent":    {[]NamedTensor{{"../evil", x}}, nil},
+		"absolute":  {[]NamedTensor{{"/etc/evil", x}}, nil},
+		"backslash": {[]NamedTensor{{`..\evil`, x}}, nil},
+		"empty":     {[]NamedTensor{{"", x}}, []Option{WithNameCodec(ConfigurableNameCodec{})}},
+		"duplicate": {[]NamedTensor{{"W", x}, {"w", x}}, []Option{lower}},
+		"metadata":  {[]NamedTensor{{"__checksums__", x}}, []Option{WithNameCodec(ConfigurableNameCodec{Suffix: ".json"})}},
+	}
+	for name, tt := range tests {
+		path := filepath.Join(t.TempDir(), "a.npz")
+		if err := WriteNPZOrdered(path, tt.tensors, append(tt.opts, WithAtomicWrite())...); err == nil {
+			t.Errorf("%s: written without error", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: failed write left a file", name)
+		}
+	}
+
+	// Appending checks names against the entries already in the archive
+	path := filepath.Join(t.TempDir(), "a.npz")
+	if err := WriteNPZ(path, map[string]*Tensor{"W": x}, lower); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendNPZ(path, map[string]*Tensor{"w": x}, lower); err == nil {
+		t.Error("appended a tensor encoding to an existing entry")
+	}
+	if err := AppendNPZ(path, map[string]*Tensor{"../evil": x}); err == nil {
+		t.Error("appended a tensor with an unsafe name")
+	}
+	out, err := ReadNPZ(path, lower)
+	if err != nil || len(out) != 1 {
+		t.Errorf("archive after rejected appends: %d tensors, %v", len(out), err)
+	}
+}
+
+func TestAppendNPZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	packed, err := PackInt4([]int8{-8, 7, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = WriteNPZ(path, map[string]*Tensor{
+		"a": {Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"},
+		"q": {Data: packed, Shape: Shape{3}, DType: DTypeI4, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AppendNPZ(path, map[string]*Tensor{
+		"b": {Data: []int64{3}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, nt := range out {
+		got[nt.Name] = fmt.Sprintf("%s %v", nt.Tensor.DType, nt.Tensor.Data)
+	}
+	want := map[string]string{"a": "f32 [1 2]", "b": "i64 [3]", "q": fmt.Sprintf("%s %v", DTypeI4, packed)}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("archive holds %v, want %v", got, want)
+	}
+
+	// A failed append leaves the archive as it was
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = AppendNPZ(path, map[string]*Tensor{
+		"c": {Data: []int64{4}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"},
+		"a": {Data: []int64{5}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"},
+	})
+	if err == nil {
+		t.Fatal("appended a tensor with an existing name")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(before, after) {
+		t.Errorf("failed append changed the archive: %v", err)
+	}
+}
+
+func TestAppendNPZChecksums(t *testing.T) {
+	x := &Tensor{Data: []float32{1}, Shape: Shape{1}, DType: DTypeF32, Device: "cpu"}
+
+	// New tensors get checksums when the archive has a manifest
+	path := filepath.Join(t.TempDir(), "a.npz")
+	if err := WriteNPZ(path, map[string]*Tensor{"a": x}, WithChecksums()); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendNPZ(path, map[string]*Tensor{"b": x}); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := ReadNPZ(path, WithVerifyChecksums()); err != nil || len(out) != 2 {
+		t.Errorf("verified read = %v, %v", out, err)
+	}
+
+	// and no manifest is added to an archive without one
+	path = filepath.Join(t.TempDir(), "b.npz")
+	if err := WriteNPZ(path, map[string]*Tensor{"a": x}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendNPZ(path, map[string]*Tensor{"b": x}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, file := range r.File {
+		if file.Name == checksumEntry {
+			t.Error("manifest added to an archive without checksums")
+		}
+	}
+}
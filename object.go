@@ -0,0 +1,118 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// readObjectArray decodes the pickled payload of an object array. numpy pickles
+// the whole ndarray, whose reconstruction state is the tuple
+// (version, shape, dtype, is_fortran, items); the items must be a flat list of
+// Python scalars of a single kind.
+func readObjectArray(r io.Reader, header *Header) (*Tensor, error) {
+	v, err := unpickle(r)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := v.(*pyObject)
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("object array pickle holds %T, not an ndarray", v)}
+	}
+	if g, ok := obj.Callable.(pyGlobal); !ok || g.Name != "_reconstruct" {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("object array pickle constructs %v, not an ndarray", obj.Callable)}
+	}
+	state, ok := obj.State.(pyTuple)
+	if !ok || len(state) != 5 {
+		return nil, ErrorNpy{Msg: "unexpected ndarray pickle state"}
+	}
+
+	shape, err := shapeFromLiteral(state[1])
+	if err != nil {
+		return nil, err
+	}
+	if !shape.Equal(header.Shape) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("pickled shape %v does not match header shape %v", shape, header.Shape)}
+	}
+	if fortran, _ := state[3].(bool); fortran && len(shape) > 1 {
+		return nil, ErrFortranOrder
+	}
+	items, ok := state[4].(*pyList)
+	if !ok {
+		return nil, ErrorNpy{Msg: "object array items are not a list"}
+	}
+	if len(items.Items) != shape.ElemCount() {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("object array has %d items, shape %v needs %d", len(items.Items), shape, shape.ElemCount())}
+	}
+
+	return objectsToTensor(items.Items, shape)
+}
+
+// objectsToTensor converts a flat list of Python scalars into a tensor. Strings
+// become a unicode dtype wide enough for the longest entry, bytes a byte-string
+// dtype, booleans DTypeBool, integers DTypeI64, and any mix of integers and
+// floats DTypeF64.
+func objectsToTensor(items []interface{}, shape Shape) (*Tensor, error) {
+	var nStr, nBytes, nBool, nInt, nFloat, width int
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			nStr++
+			width = max(width, utf8.RuneCountInString(v))
+		case []byte:
+			nBytes++
+			width = max(width, len(v))
+		case bool:
+			nBool++
+		case int64:
+			nInt++
+		case float64:
+			nFloat++
+		default:
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported object of type %T in object array", item)}
+		}
+	}
+	width = max(width, 1)
+
+	n := len(items)
+	t := &Tensor{Shape: shape, Device: "cpu"}
+	switch {
+	case nStr == n:
+		data := make([]string, n)
+		for i, item := range items {
+			data[i] = item.(string)
+		}
+		t.Data, t.DType = data, UnicodeDType(width)
+	case nBytes == n:
+		data := make([]string, n)
+		for i, item := range items {
+			data[i] = string(item.([]byte))
+		}
+		t.Data, t.DType = data, BytesDType(width)
+	case nBool == n:
+		data := make([]bool, n)
+		for i, item := range items {
+			data[i] = item.(bool)
+		}
+		t.Data, t.DType = data, DTypeBool
+	case nInt == n:
+		data := make([]int64, n)
+		for i, item := range items {
+			data[i] = item.(int64)
+		}
+		t.Data, t.DType = data, DTypeI64
+	case nInt+nFloat == n:
+		data := make([]float64, n)
+		for i, item := range items {
+			if v, ok := item.(int64); ok {
+				data[i] = float64(v)
+			} else {
+				data[i] = item.(float64)
+			}
+		}
+		t.Data, t.DType = data, DTypeF64
+	default:
+		return nil, ErrorNpy{Msg: "object array mixes incompatible element types"}
+	}
+	return t, nil
+}
@@ -0,0 +1,376 @@
+// Package onnx converts between gonpy tensors and ONNX TensorProto messages,
+// and reads the initializer tensors of ONNX models, including those stored in
+// external data files.
+//
+// The package encodes and decodes the protobuf wire format of the messages it
+// needs directly, so it has no dependency on generated ONNX bindings. Fields
+// it does not know are skipped when decoding.
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// DataType is the element type of a TensorProto.
+type DataType int32
+
+const (
+	DataTypeUndefined  DataType = 0
+	DataTypeFloat      DataType = 1
+	DataTypeUint8      DataType = 2
+	DataTypeInt8       DataType = 3
+	DataTypeUint16     DataType = 4
+	DataTypeInt16      DataType = 5
+	DataTypeInt32      DataType = 6
+	DataTypeInt64      DataType = 7
+	DataTypeString     DataType = 8
+	DataTypeBool       DataType = 9
+	DataTypeFloat16    DataType = 10
+	DataTypeDouble     DataType = 11
+	DataTypeUint32     DataType = 12
+	DataTypeUint64     DataType = 13
+	DataTypeComplex64  DataType = 14
+	DataTypeComplex128 DataType = 15
+	DataTypeBFloat16   DataType = 16
+	DataTypeFloat8E4M3 DataType = 17 // FLOAT8E4M3FN
+	DataTypeFloat8E5M2 DataType = 19
+	DataTypeUint4      DataType = 21
+	DataTypeInt4       DataType = 22
+)
+
+// DataLocation says where the data of a TensorProto is stored.
+type DataLocation int32
+
+const (
+	DataLocationDefault  DataLocation = 0 // In the message's data fields
+	DataLocationExternal DataLocation = 1 // In a file described by ExternalData
+)
+
+// StringStringEntry is a key/value pair, as in onnx.StringStringEntryProto.
+type StringStringEntry struct {
+	Key   string
+	Value string
+}
+
+// TensorProto holds the fields of onnx.TensorProto that describe a tensor's
+// data. Segments are not supported.
+type TensorProto struct {
+	Dims         []int64
+	DataType     DataType
+	Name         string
+	DocString    string
+	RawData      []byte
+	FloatData    []float32
+	Int32Data    []int32
+	StringData   [][]byte
+	Int64Data    []int64
+	DoubleData   []float64
+	Uint64Data   []uint64
+	ExternalData []StringStringEntry
+	DataLocation DataLocation
+}
+
+// TensorProto field numbers.
+const (
+	fieldDims         = 1
+	fieldDataType     = 2
+	fieldSegment      = 3
+	fieldFloatData    = 4
+	fieldInt32Data    = 5
+	fieldStringData   = 6
+	fieldInt64Data    = 7
+	fieldName         = 8
+	fieldRawData      = 9
+	fieldDoubleData   = 10
+	fieldUint64Data   = 11
+	fieldDocString    = 12
+	fieldExternalData = 13
+	fieldDataLocation = 14
+)
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Marshal encodes the message in the protobuf wire format, with repeated
+// numeric fields packed.
+func (p *TensorProto) Marshal() []byte {
+	var e encoder
+	e.packedVarints(fieldDims, len(p.Dims), func(i int) uint64 { return uint64(p.Dims[i]) })
+	if p.DataType != 0 {
+		e.varintField(fieldDataType, uint64(p.DataType))
+	}
+	if len(p.FloatData) > 0 {
+		e.tag(fieldFloatData, wireBytes)
+		e.varint(uint64(4 * len(p.FloatData)))
+		for _, f := range p.FloatData {
+			e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(f))
+		}
+	}
+	e.packedVarints(fieldInt32Data, len(p.Int32Data), func(i int) uint64 { return uint64(int64(p.Int32Data[i])) })
+	for _, s := range p.StringData {
+		e.bytesField(fieldStringData, s)
+	}
+	e.packedVarints(fieldInt64Data, len(p.Int64Data), func(i int) uint64 { return uint64(p.Int64Data[i]) })
+	if p.Name != "" {
+		e.bytesField(fieldName, []byte(p.Name))
+	}
+	if len(p.RawData) > 0 {
+		e.bytesField(fieldRawData, p.RawData)
+	}
+	if len(p.DoubleData) > 0 {
+		e.tag(fieldDoubleData, wireBytes)
+		e.varint(uint64(8 * len(p.DoubleData)))
+		for _, f := range p.DoubleData {
+			e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(f))
+		}
+	}
+	e.packedVarints(fieldUint64Data, len(p.Uint64Data), func(i int) uint64 { return p.Uint64Data[i] })
+	if p.DocString != "" {
+		e.bytesField(fieldDocString, []byte(p.DocString))
+	}
+	for _, kv := range p.ExternalData {
+		var entry encoder
+		entry.bytesField(1, []byte(kv.Key))
+		entry.bytesField(2, []byte(kv.Value))
+		e.bytesField(fieldExternalData, entry.buf)
+	}
+	if p.DataLocation != 0 {
+		e.varintField(fieldDataLocation, uint64(p.DataLocation))
+	}
+	return e.buf
+}
+
+// UnmarshalTensorProto decodes a TensorProto from the protobuf wire format.
+// Repeated numeric fields are accepted both packed and unpacked.
+func UnmarshalTensorProto(b []byte) (*TensorProto, error) {
+	p := &TensorProto{}
+	d := decoder{buf: b}
+	for !d.done() {
+		field, wire := d.tag()
+		switch {
+		case field == fieldDims:
+			d.repeatedVarint(wire, func(v uint64) { p.Dims = append(p.Dims, int64(v)) })
+		case field == fieldDataType && wire == wireVarint:
+			p.DataType = DataType(d.varint())
+		case field == fieldFloatData:
+			d.repeatedFixed32(wire, func(v uint32) { p.FloatData = append(p.FloatData, math.Float32frombits(v)) })
+		case field == fieldInt32Data:
+			d.repeatedVarint(wire, func(v uint64) { p.Int32Data = append(p.Int32Data, int32(v)) })
+		case field == fieldStringData && wire == wireBytes:
+			p.StringData = append(p.StringData, append([]byte(nil), d.bytes()...))
+		case field == fieldInt64Data:
+			d.repeatedVarint(wire, func(v uint64) { p.Int64Data = append(p.Int64Data, int64(v)) })
+		case field == fieldName && wire == wireBytes:
+			p.Name = string(d.bytes())
+		case field == fieldRawData && wire == wireBytes:
+			p.RawData = append([]byte(nil), d.bytes()...)
+		case field == fieldDoubleData:
+			d.repeatedFixed64(wire, func(v uint64) { p.DoubleData = append(p.DoubleData, math.Float64frombits(v)) })
+		case field == fieldUint64Data:
+			d.repeatedVarint(wire, func(v uint64) { p.Uint64Data = append(p.Uint64Data, v) })
+		case field == fieldDocString && wire == wireBytes:
+			p.DocString = string(d.bytes())
+		case field == fieldExternalData && wire == wireBytes:
+			kv, err := unmarshalEntry(d.bytes())
+			if err != nil {
+				return nil, err
+			}
+			p.ExternalData = append(p.ExternalData, kv)
+		case field == fieldDataLocation && wire == wireVarint:
+			p.DataLocation = DataLocation(d.varint())
+		case field == fieldSegment:
+			return nil, fmt.Errorf("onnx: segmented tensors are not supported")
+		default:
+			d.skip(wire)
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("onnx: decoding TensorProto: %w", d.err)
+	}
+	return p, nil
+}
+
+// unmarshalEntry decodes a StringStringEntryProto.
+func unmarshalEntry(b []byte) (StringStringEntry, error) {
+	var kv StringStringEntry
+	d := decoder{buf: b}
+	for !d.done() {
+		field, wire := d.tag()
+		switch {
+		case field == 1 && wire == wireBytes:
+			kv.Key = string(d.bytes())
+		case field == 2 && wire == wireBytes:
+			kv.Value = string(d.bytes())
+		default:
+			d.skip(wire)
+		}
+	}
+	return kv, d.err
+}
+
+// encoder appends protobuf wire data to a buffer.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) varint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.varint(uint64(field<<3 | wire))
+}
+
+func (e *encoder) varintField(field int, v uint64) {
+	e.tag(field, wireVarint)
+	e.varint(v)
+}
+
+func (e *encoder) bytesField(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// packedVarints writes n varints as one packed field, if n is not zero.
+func (e *encoder) packedVarints(field, n int, value func(i int) uint64) {
+	if n == 0 {
+		return
+	}
+	var packed encoder
+	for i := 0; i < n; i++ {
+		packed.varint(value(i))
+	}
+	e.bytesField(field, packed.buf)
+}
+
+// decoder reads protobuf wire data, remembering the first error so that
+// callers can check once after a run of reads.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) done() bool {
+	return d.err != nil || len(d.buf) == 0
+}
+
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+	d.buf = nil
+}
+
+func (d *decoder) varint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail("invalid varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) tag() (int, int) {
+	t := d.varint()
+	return int(t >> 3), int(t & 7)
+}
+
+func (d *decoder) fixed(n int) []byte {
+	if len(d.buf) < n {
+		d.fail("truncated field")
+		return make([]byte, n)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.varint()
+	if n > uint64(len(d.buf)) {
+		d.fail("length %d overruns message", n)
+		return nil
+	}
+	return d.fixed(int(n))
+}
+
+// skip discards a field of an unknown number.
+func (d *decoder) skip(wire int) {
+	switch wire {
+	case wireVarint:
+		d.varint()
+	case wireFixed64:
+		d.fixed(8)
+	case wireBytes:
+		d.bytes()
+	case wireFixed32:
+		d.fixed(4)
+	default:
+		d.fail("unsupported wire type %d", wire)
+	}
+}
+
+// repeatedVarint reads one unpacked value or a packed run of varints.
+func (d *decoder) repeatedVarint(wire int, add func(uint64)) {
+	switch wire {
+	case wireVarint:
+		add(d.varint())
+	case wireBytes:
+		packed := decoder{buf: d.bytes()}
+		for !packed.done() {
+			add(packed.varint())
+		}
+		if packed.err != nil {
+			d.fail("%v", packed.err)
+		}
+	default:
+		d.skip(wire)
+	}
+}
+
+// repeatedFixed32 reads one unpacked value or a packed run of 32-bit values.
+func (d *decoder) repeatedFixed32(wire int, add func(uint32)) {
+	switch wire {
+	case wireFixed32:
+		add(binary.LittleEndian.Uint32(d.fixed(4)))
+	case wireBytes:
+		b := d.bytes()
+		if len(b)%4 != 0 {
+			d.fail("packed fixed32 field of %d bytes", len(b))
+			return
+		}
+		for i := 0; i < len(b); i += 4 {
+			add(binary.LittleEndian.Uint32(b[i:]))
+		}
+	default:
+		d.skip(wire)
+	}
+}
+
+// repeatedFixed64 reads one unpacked value or a packed run of 64-bit values.
+func (d *decoder) repeatedFixed64(wire int, add func(uint64)) {
+	switch wire {
+	case wireFixed64:
+		add(binary.LittleEndian.Uint64(d.fixed(8)))
+	case wireBytes:
+		b := d.bytes()
+		if len(b)%8 != 0 {
+			d.fail("packed fixed64 field of %d bytes", len(b))
+			return
+		}
+		for i := 0; i < len(b); i += 8 {
+			add(binary.LittleEndian.Uint64(b[i:]))
+		}
+	default:
+		d.skip(wire)
+	}
+}
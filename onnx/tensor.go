@@ -0,0 +1,412 @@
+package onnx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/gocnn/gonpy"
+)
+
+// dataTypes maps dtypes to ONNX data types. String dtypes map to
+// DataTypeString and are handled separately.
+var dataTypes = map[gonpy.DType]DataType{
+	gonpy.DTypeF32:    DataTypeFloat,
+	gonpy.DTypeU8:     DataTypeUint8,
+	gonpy.DTypeI8:     DataTypeInt8,
+	gonpy.DTypeI32:    DataTypeInt32,
+	gonpy.DTypeI64:    DataTypeInt64,
+	gonpy.DTypeBool:   DataTypeBool,
+	gonpy.DTypeF16:    DataTypeFloat16,
+	gonpy.DTypeF64:    DataTypeDouble,
+	gonpy.DTypeU32:    DataTypeUint32,
+	gonpy.DTypeU64:    DataTypeUint64,
+	gonpy.DTypeC64:    DataTypeComplex64,
+	gonpy.DTypeC128:   DataTypeComplex128,
+	gonpy.DTypeBF16:   DataTypeBFloat16,
+	gonpy.DTypeF8E4M3: DataTypeFloat8E4M3,
+	gonpy.DTypeF8E5M2: DataTypeFloat8E5M2,
+	gonpy.DTypeU4:     DataTypeUint4,
+	gonpy.DTypeI4:     DataTypeInt4,
+}
+
+// dtypes maps ONNX data types to the dtypes they are read as. 16-bit integers
+// are widened to 32 bits, since gonpy has no dtype for them.
+var dtypes = map[DataType]gonpy.DType{
+	DataTypeUint16: gonpy.DTypeU32,
+	DataTypeInt16:  gonpy.DTypeI32,
+}
+
+func init() {
+	for dtype, dt := range dataTypes {
+		dtypes[dt] = dtype
+	}
+}
+
+// FromTensor converts a tensor to a TensorProto with the given name. Numeric
+// data is stored in RawData, and string data in StringData.
+func FromTensor(name string, t *gonpy.Tensor) (*TensorProto, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	p := &TensorProto{Name: name, Dims: make([]int64, len(t.Shape))}
+	for i, dim := range t.Shape {
+		p.Dims[i] = int64(dim)
+	}
+	n := t.Shape.ElemCount()
+
+	if strs, ok := t.Data.([]string); ok {
+		if len(strs) != n {
+			return nil, fmt.Errorf("onnx: tensor %s has %d strings, shape %v needs %d", name, len(strs), t.Shape, n)
+		}
+		p.DataType = DataTypeString
+		for _, s := range strs {
+			p.StringData = append(p.StringData, []byte(s))
+		}
+		return p, nil
+	}
+
+	dt, ok := dataTypes[t.DType]
+	if !ok {
+		return nil, fmt.Errorf("onnx: no data type for %s tensor %s: %w", t.DType, name, gonpy.ErrUnsupportedDType)
+	}
+	p.DataType = dt
+	size := rawSize(dt, n)
+	if dt == DataTypeInt4 || dt == DataTypeUint4 {
+		// Packed low nibble first, as ONNX stores them
+		packed, ok := t.Data.([]byte)
+		if !ok || len(packed) != size {
+			return nil, fmt.Errorf("onnx: %s tensor %s data does not match its shape", t.DType, name)
+		}
+		p.RawData = append([]byte(nil), packed...)
+		return p, nil
+	}
+	if binary.Size(t.Data) != size {
+		return nil, fmt.Errorf("onnx: tensor %s has %d bytes of data, shape %v needs %d", name, binary.Size(t.Data), t.Shape, size)
+	}
+	var buf bytes.Buffer
+	buf.Grow(size)
+	if err := binary.Write(&buf, binary.LittleEndian, t.Data); err != nil {
+		return nil, err
+	}
+	p.RawData = buf.Bytes()
+	return p, nil
+}
+
+// rawSize returns the number of bytes of n elements of dt in RawData, or -1
+// if dt has no raw form or the size overflows an int.
+func rawSize(dt DataType, n int) int {
+	elem := 0
+	switch dt {
+	case DataTypeUint8, DataTypeInt8, DataTypeBool, DataTypeFloat8E4M3, DataTypeFloat8E5M2:
+		elem = 1
+	case DataTypeUint16, DataTypeInt16, DataTypeFloat16, DataTypeBFloat16:
+		elem = 2
+	case DataTypeFloat, DataTypeInt32, DataTypeUint32:
+		elem = 4
+	case DataTypeDouble, DataTypeInt64, DataTypeUint64, DataTypeComplex64:
+		elem = 8
+	case DataTypeComplex128:
+		elem = 16
+	case DataTypeUint4, DataTypeInt4:
+		return n/2 + n%2
+	}
+	if elem == 0 || n > math.MaxInt/elem {
+		return -1
+	}
+	return elem * n
+}
+
+// ToTensor converts a TensorProto to a tensor, reading its data from RawData,
+// the typed data field for its data type, or an external data file. External
+// file locations are resolved against baseDir, normally the directory of the
+// model, and must not lead outside it.
+func ToTensor(p *TensorProto, baseDir string) (*gonpy.Tensor, error) {
+	shape := make(gonpy.Shape, len(p.Dims))
+	for i, dim := range p.Dims {
+		if dim < 0 || dim > math.MaxInt32 {
+			return nil, fmt.Errorf("onnx: tensor %s has invalid dimension %d", p.Name, dim)
+		}
+		shape[i] = int(dim)
+	}
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	if p.DataType == DataTypeString {
+		return stringTensor(p, shape, n)
+	}
+	dtype, ok := dtypes[p.DataType]
+	if !ok {
+		return nil, fmt.Errorf("onnx: tensor %s has unsupported data type %d: %w", p.Name, p.DataType, gonpy.ErrUnsupportedDType)
+	}
+
+	size := rawSize(p.DataType, n)
+	if size < 0 {
+		return nil, fmt.Errorf("onnx: tensor %s of shape %v is too large: %w", p.Name, shape, gonpy.ErrTooLarge)
+	}
+
+	// Check the data against the shape before allocating the tensor
+	raw := p.RawData
+	isRaw := raw != nil || p.DataLocation == DataLocationExternal
+	if p.DataLocation == DataLocationExternal {
+		if raw, err = readExternal(p, baseDir, size); err != nil {
+			return nil, err
+		}
+	}
+	if isRaw && len(raw) != size {
+		return nil, fmt.Errorf("onnx: tensor %s: raw data has %d bytes, shape %v needs %d", p.Name, len(raw), shape, size)
+	}
+	if !isRaw {
+		if err := checkTyped(p, shape, n); err != nil {
+			return nil, fmt.Errorf("onnx: tensor %s: %w", p.Name, err)
+		}
+	}
+
+	t, err := gonpy.Zeros(dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	if isRaw {
+		err = decodeRaw(t, p.DataType, raw)
+	} else {
+		err = decodeTyped(t, p)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("onnx: tensor %s: %w", p.Name, err)
+	}
+	return t, nil
+}
+
+// decodeRaw fills t from little-endian raw data of type dt, whose length has
+// been checked against the shape of t.
+func decodeRaw(t *gonpy.Tensor, dt DataType, raw []byte) error {
+	switch dt {
+	case DataTypeInt16:
+		data := t.Data.([]int32)
+		for i := range data {
+			data[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+		}
+		return nil
+	case DataTypeUint16:
+		data := t.Data.([]uint32)
+		for i := range data {
+			data[i] = uint32(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+		return nil
+	case DataTypeInt4, DataTypeUint4:
+		copy(t.Data.([]byte), raw)
+		return nil
+	}
+	return binary.Read(bytes.NewReader(raw), binary.LittleEndian, t.Data)
+}
+
+// checkTyped checks that the typed data field that ONNX uses for the data type
+// of p holds the values of n elements.
+func checkTyped(p *TensorProto, shape gonpy.Shape, n int) error {
+	var count int
+	switch p.DataType {
+	case DataTypeFloat, DataTypeComplex64:
+		count = len(p.FloatData)
+	case DataTypeDouble, DataTypeComplex128:
+		count = len(p.DoubleData)
+	case DataTypeInt64:
+		count = len(p.Int64Data)
+	case DataTypeUint32, DataTypeUint64:
+		count = len(p.Uint64Data)
+	default:
+		count = len(p.Int32Data)
+	}
+	want := n
+	switch p.DataType {
+	case DataTypeComplex64, DataTypeComplex128:
+		want = 2 * n
+	case DataTypeInt4, DataTypeUint4:
+		want = (n + 1) / 2 // One packed byte per value
+	}
+	if count != want {
+		return fmt.Errorf("typed data has %d values, shape %v needs %d", count, shape, want)
+	}
+	return nil
+}
+
+// decodeTyped fills t from the typed data field that ONNX uses for dt, whose
+// length has been checked with checkTyped.
+func decodeTyped(t *gonpy.Tensor, p *TensorProto) error {
+	switch data := t.Data.(type) {
+	case []float32:
+		copy(data, p.FloatData)
+	case []complex64:
+		for i := range data {
+			data[i] = complex(p.FloatData[2*i], p.FloatData[2*i+1])
+		}
+	case []float64:
+		copy(data, p.DoubleData)
+	case []complex128:
+		for i := range data {
+			data[i] = complex(p.DoubleData[2*i], p.DoubleData[2*i+1])
+		}
+	case []int64:
+		copy(data, p.Int64Data)
+	case []uint64:
+		copy(data, p.Uint64Data)
+	case []uint32:
+		for i := range data {
+			if p.DataType == DataTypeUint32 {
+				data[i] = uint32(p.Uint64Data[i])
+			} else {
+				data[i] = uint32(p.Int32Data[i])
+			}
+		}
+	case []int32:
+		copy(data, p.Int32Data)
+	case []int8:
+		for i := range data {
+			data[i] = int8(p.Int32Data[i])
+		}
+	case []uint8: // U8 or packed 4-bit values
+		for i := range data {
+			data[i] = uint8(p.Int32Data[i])
+		}
+	case []bool:
+		for i := range data {
+			data[i] = p.Int32Data[i] != 0
+		}
+	case []uint16: // F16 and BF16 bit patterns
+		for i := range data {
+			data[i] = uint16(p.Int32Data[i])
+		}
+	default:
+		return fmt.Errorf("unexpected data of type %T", t.Data)
+	}
+	return nil
+}
+
+// stringTensor converts a STRING TensorProto to a unicode tensor, or to a
+// bytes tensor if any string is not valid UTF-8.
+func stringTensor(p *TensorProto, shape gonpy.Shape, n int) (*gonpy.Tensor, error) {
+	if len(p.StringData) != n {
+		return nil, fmt.Errorf("onnx: tensor %s has %d strings, shape %v needs %d", p.Name, len(p.StringData), shape, n)
+	}
+	data := make([]string, n)
+	runes, width, valid := 1, 1, true
+	for i, s := range p.StringData {
+		data[i] = string(s)
+		width = max(width, len(s))
+		runes = max(runes, utf8.RuneCount(s))
+		valid = valid && utf8.Valid(s)
+	}
+	dtype := gonpy.BytesDType(width)
+	if valid {
+		dtype = gonpy.UnicodeDType(runes)
+	}
+	return &gonpy.Tensor{Data: data, Shape: shape, DType: dtype, Device: "cpu"}, nil
+}
+
+// readExternal reads the raw data of a tensor stored in an external file,
+// described by the location, offset, and length entries of ExternalData. The
+// data must be size bytes long.
+func readExternal(p *TensorProto, baseDir string, size int) ([]byte, error) {
+	var location string
+	offset, length := int64(0), int64(-1)
+	for _, kv := range p.ExternalData {
+		var err error
+		switch kv.Key {
+		case "location":
+			location = kv.Value
+		case "offset":
+			offset, err = strconv.ParseInt(kv.Value, 10, 64)
+		case "length":
+			length, err = strconv.ParseInt(kv.Value, 10, 64)
+		}
+		if err != nil || offset < 0 || length < -1 {
+			return nil, fmt.Errorf("onnx: tensor %s has invalid external data %s %q", p.Name, kv.Key, kv.Value)
+		}
+	}
+	if location == "" || !filepath.IsLocal(location) {
+		return nil, fmt.Errorf("onnx: tensor %s has invalid external data location %q", p.Name, location)
+	}
+
+	f, err := os.Open(filepath.Join(baseDir, location))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset > info.Size() {
+		return nil, fmt.Errorf("onnx: tensor %s external data overruns %s", p.Name, location)
+	}
+	if length == -1 {
+		length = info.Size() - offset
+	}
+	if length > info.Size()-offset {
+		return nil, fmt.Errorf("onnx: tensor %s external data overruns %s", p.Name, location)
+	}
+	if length != int64(size) {
+		return nil, fmt.Errorf("onnx: tensor %s has %d bytes of external data, needs %d", p.Name, length, size)
+	}
+	raw := make([]byte, length)
+	if _, err := f.ReadAt(raw, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// ModelProto and GraphProto field numbers of the initializer path.
+const (
+	modelGraph       = 7
+	graphInitializer = 5
+)
+
+// ReadInitializers reads the initializer tensors of an ONNX model file in
+// graph order. Initializers stored as external data are read from files next
+// to the model.
+func ReadInitializers(path string) ([]gonpy.NamedTensor, error) {
+	model, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tensors []gonpy.NamedTensor
+	md := decoder{buf: model}
+	for !md.done() {
+		field, wire := md.tag()
+		if field != modelGraph || wire != wireBytes {
+			md.skip(wire)
+			continue
+		}
+		gd := decoder{buf: md.bytes()}
+		for !gd.done() {
+			field, wire := gd.tag()
+			if field != graphInitializer || wire != wireBytes {
+				gd.skip(wire)
+				continue
+			}
+			p, err := UnmarshalTensorProto(gd.bytes())
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			t, err := ToTensor(p, filepath.Dir(path))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			tensors = append(tensors, gonpy.NamedTensor{Name: p.Name, Tensor: t})
+		}
+		if gd.err != nil {
+			md.fail("%v", gd.err)
+		}
+	}
+	if md.err != nil {
+		return nil, fmt.Errorf("onnx: decoding %s: %w", path, md.err)
+	}
+	return tensors, nil
+}
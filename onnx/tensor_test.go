@@ -0,0 +1,132 @@
+package onnx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+func TestRoundTrip(t *testing.T) {
+	in := &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"}
+	p, err := FromTensor("w", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := UnmarshalTensorProto(p.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToTensor(q, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != "w" || !out.Shape.Equal(in.Shape) || out.Data.([]float32)[5] != 6 {
+		t.Errorf("got %s %v %v", q.Name, out.Shape, out.Data)
+	}
+}
+
+func TestTypedData(t *testing.T) {
+	p := &TensorProto{Dims: []int64{3}, DataType: DataTypeInt64, Int64Data: []int64{7, 8, 9}}
+	out, err := ToTensor(p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data.([]int64); got[2] != 9 {
+		t.Errorf("got %v", got)
+	}
+	p.Int64Data = p.Int64Data[:2]
+	if _, err := ToTensor(p, ""); err == nil {
+		t.Error("short typed data accepted")
+	}
+}
+
+func TestHostileTensors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "data.bin"), make([]byte, 64), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	external := func(kv ...string) []StringStringEntry {
+		entries := []StringStringEntry{{Key: "location", Value: "data.bin"}}
+		for i := 0; i < len(kv); i += 2 {
+			entries = append(entries, StringStringEntry{Key: kv[i], Value: kv[i+1]})
+		}
+		return entries
+	}
+	cases := map[string]*TensorProto{
+		"huge raw":   {Dims: []int64{0x7fffffff, 0x7fffffff}, DataType: DataTypeFloat, RawData: make([]byte, 4)},
+		"huge typed": {Dims: []int64{0x7fffffff, 0x7fffffff}, DataType: DataTypeFloat, FloatData: []float32{1}},
+		"overflowing offset": {Dims: []int64{2}, DataType: DataTypeFloat, DataLocation: DataLocationExternal,
+			ExternalData: external("offset", "9223372036854775800", "length", "8")},
+		"overflowing length": {Dims: []int64{2}, DataType: DataTypeFloat, DataLocation: DataLocationExternal,
+			ExternalData: external("offset", "8", "length", "9223372036854775800")},
+		"wrong length": {Dims: []int64{2}, DataType: DataTypeFloat, DataLocation: DataLocationExternal,
+			ExternalData: external("length", "12")},
+		"escaping location": {Dims: []int64{2}, DataType: DataTypeFloat, DataLocation: DataLocationExternal,
+			ExternalData: []StringStringEntry{{Key: "location", Value: "../data.bin"}}},
+	}
+	for name, p := range cases {
+		if _, err := ToTensor(p, dir); err == nil {
+			t.Errorf("%s: ToTensor succeeded", name)
+		}
+	}
+
+	p := &TensorProto{Dims: []int64{2}, DataType: DataTypeFloat, DataLocation: DataLocationExternal,
+		ExternalData: external("offset", "56")}
+	if _, err := ToTensor(p, dir); err != nil {
+		t.Errorf("valid external data: %v", err)
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestFromDeviceTensor(t *testing.T) {
+	x := &gonpy.Tensor{Data: []int32{1, -2, 3}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeI32, Device: "cpu"}
+	p, err := FromTensor("x", onDevice(t, x))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ToTensor(p, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out.Data) != "[1 -2 3]" {
+		t.Errorf("read back %v", out.Data)
+	}
+}
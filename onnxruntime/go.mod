@@ -0,0 +1,10 @@
+module github.com/gocnn/gonpy/onnxruntime
+
+go 1.25
+
+require (
+	github.com/gocnn/gonpy v0.0.0-00010101000000-000000000000
+	github.com/yalue/onnxruntime_go v1.13.0
+)
+
+replace github.com/gocnn/gonpy => ../
@@ -0,0 +1,193 @@
+// Package onnxruntime builds input values for the onnxruntime Go bindings,
+// github.com/yalue/onnxruntime_go, from gonpy tensors, and converts output
+// values back, so that inference services can feed NPY and NPZ data to ONNX
+// models and save their results.
+//
+// gonpy itself does not depend on onnxruntime, so this package is a module
+// of its own, github.com/gocnn/gonpy/onnxruntime, which requires the
+// bindings.
+package onnxruntime
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/gocnn/gonpy"
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// NewValue returns an onnxruntime tensor holding the data of t, for use as a
+// session input. Numeric tensors share their data with the value, which must
+// be destroyed before the data is released. F16 and BF16 tensors become
+// custom data tensors sharing their data as well, bool tensors are copied to
+// one, float8 tensors are converted to float32, and packed int4 and uint4
+// tensors to int8 and uint8. Complex, string, structured, and object tensors
+// are not supported.
+func NewValue(t *gonpy.Tensor) (ort.Value, error) {
+	v, err := newValue(t)
+	if err != nil {
+		return nil, fmt.Errorf("onnxruntime: %w", err)
+	}
+	return v, nil
+}
+
+func newValue(t *gonpy.Tensor) (ort.Value, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	switch t.DType {
+	case gonpy.DTypeF8E4M3, gonpy.DTypeF8E5M2:
+		t, err = t.Cast(gonpy.DTypeF32)
+	case gonpy.DTypeI4:
+		t, err = t.Cast(gonpy.DTypeI8)
+	case gonpy.DTypeU4:
+		t, err = t.Cast(gonpy.DTypeU8)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if data := reflect.ValueOf(t.Data); data.Kind() != reflect.Slice || data.Len() != t.Shape.ElemCount() {
+		return nil, fmt.Errorf("%s tensor of shape %v has data %T of the wrong length", t.DType, t.Shape, t.Data)
+	}
+	shape := make(ort.Shape, len(t.Shape))
+	for i, dim := range t.Shape {
+		shape[i] = int64(dim)
+	}
+
+	switch d := t.Data.(type) {
+	case []float32:
+		return ort.NewTensor(shape, d)
+	case []float64:
+		return ort.NewTensor(shape, d)
+	case []int8:
+		return ort.NewTensor(shape, d)
+	case []int32:
+		return ort.NewTensor(shape, d)
+	case []int64:
+		return ort.NewTensor(shape, d)
+	case []uint8:
+		return ort.NewTensor(shape, d)
+	case []uint32:
+		return ort.NewTensor(shape, d)
+	case []uint64:
+		return ort.NewTensor(shape, d)
+	case []uint16:
+		var elem ort.TensorElementDataType = ort.TensorElementDataTypeFloat16
+		if t.DType == gonpy.DTypeBF16 {
+			elem = ort.TensorElementDataTypeBFloat16
+		}
+		return ort.NewCustomDataTensor(shape, flatBytes(d), elem)
+	case []bool:
+		b := make([]byte, len(d))
+		for i, v := range d {
+			if v {
+				b[i] = 1
+			}
+		}
+		return ort.NewCustomDataTensor(shape, b, ort.TensorElementDataTypeBool)
+	}
+	return nil, fmt.Errorf("%s tensor: %w", t.DType, gonpy.ErrUnsupportedDType)
+}
+
+// FromValue returns a copy of the data of an onnxruntime tensor, such as a
+// session output, as a gonpy tensor; the value may be destroyed afterwards.
+// Int16 and uint16 tensors are widened to I32 and U32. Custom data tensors
+// carry no Go element type and must be converted with FromCustomDataTensor.
+func FromValue(v ort.Value) (*gonpy.Tensor, error) {
+	var t *gonpy.Tensor
+	var err error
+	switch v := v.(type) {
+	case *ort.Tensor[float32]:
+		t, err = fromData(gonpy.DTypeF32, v.GetShape(), v.GetData())
+	case *ort.Tensor[float64]:
+		t, err = fromData(gonpy.DTypeF64, v.GetShape(), v.GetData())
+	case *ort.Tensor[int8]:
+		t, err = fromData(gonpy.DTypeI8, v.GetShape(), v.GetData())
+	case *ort.Tensor[int16]:
+		t, err = fromData(gonpy.DTypeI32, v.GetShape(), widen[int16, int32](v.GetData()))
+	case *ort.Tensor[int32]:
+		t, err = fromData(gonpy.DTypeI32, v.GetShape(), v.GetData())
+	case *ort.Tensor[int64]:
+		t, err = fromData(gonpy.DTypeI64, v.GetShape(), v.GetData())
+	case *ort.Tensor[uint8]:
+		t, err = fromData(gonpy.DTypeU8, v.GetShape(), v.GetData())
+	case *ort.Tensor[uint16]:
+		t, err = fromData(gonpy.DTypeU32, v.GetShape(), widen[uint16, uint32](v.GetData()))
+	case *ort.Tensor[uint32]:
+		t, err = fromData(gonpy.DTypeU32, v.GetShape(), v.GetData())
+	case *ort.Tensor[uint64]:
+		t, err = fromData(gonpy.DTypeU64, v.GetShape(), v.GetData())
+	case *ort.CustomDataTensor:
+		return nil, fmt.Errorf("onnxruntime: custom data tensors need FromCustomDataTensor")
+	default:
+		return nil, fmt.Errorf("onnxruntime: %T values are not supported", v)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("onnxruntime: %w", err)
+	}
+	return t, nil
+}
+
+// FromCustomDataTensor returns a copy of the data of a custom data tensor,
+// such as a float16, bfloat16, or bool output, as a gonpy tensor of dtype,
+// which must have the element size of the tensor's data.
+func FromCustomDataTensor(v *ort.CustomDataTensor, dtype gonpy.DType) (*gonpy.Tensor, error) {
+	shape, err := toShape(v.GetShape())
+	if err != nil {
+		return nil, fmt.Errorf("onnxruntime: %w", err)
+	}
+	t, err := gonpy.Zeros(dtype, shape)
+	if err != nil {
+		return nil, fmt.Errorf("onnxruntime: %w", err)
+	}
+	dst, src := flatBytes(t.Data), v.GetData()
+	if reflect.ValueOf(t.Data).Kind() != reflect.Slice || len(dst) != len(src) {
+		return nil, fmt.Errorf("onnxruntime: %d bytes of data cannot hold %s tensor of shape %v", len(src), dtype, shape)
+	}
+	copy(dst, src)
+	return t, nil
+}
+
+// fromData returns a tensor holding a copy of data.
+func fromData[T any](dtype gonpy.DType, s ort.Shape, data []T) (*gonpy.Tensor, error) {
+	shape, err := toShape(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != shape.ElemCount() {
+		return nil, fmt.Errorf("tensor of shape %v has %d elements", shape, len(data))
+	}
+	return &gonpy.Tensor{Data: append([]T{}, data...), Shape: shape, DType: dtype, Device: "cpu"}, nil
+}
+
+// toShape converts an onnxruntime shape to a gonpy shape.
+func toShape(s ort.Shape) (gonpy.Shape, error) {
+	shape := make(gonpy.Shape, len(s))
+	for i, dim := range s {
+		if dim < 0 || int64(int(dim)) != dim {
+			return nil, fmt.Errorf("tensor dimension %d", dim)
+		}
+		shape[i] = int(dim)
+	}
+	return shape, nil
+}
+
+// widen converts integers to a wider type.
+func widen[S int16 | uint16, D int32 | uint32](src []S) []D {
+	dst := make([]D, len(src))
+	for i, v := range src {
+		dst[i] = D(v)
+	}
+	return dst
+}
+
+// flatBytes views the memory of a slice of fixed-size values as bytes.
+func flatBytes(data interface{}) []byte {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Slice || v.Len() == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(v.UnsafePointer()), v.Len()*int(v.Type().Elem().Size()))
+}
@@ -0,0 +1,85 @@
+package onnxruntime
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// initRuntime loads the onnxruntime library named by
+// ONNXRUNTIME_SHARED_LIBRARY_PATH, skipping the test if it is not set.
+func initRuntime(t *testing.T) {
+	t.Helper()
+	path := os.Getenv("ONNXRUNTIME_SHARED_LIBRARY_PATH")
+	if path == "" {
+		t.Skip("ONNXRUNTIME_SHARED_LIBRARY_PATH is not set")
+	}
+	if ort.IsInitialized() {
+		return
+	}
+	ort.SetSharedLibraryPath(path)
+	if err := ort.InitializeEnvironment(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	initRuntime(t)
+	tests := []*gonpy.Tensor{
+		{Data: []float32{1, 2, 3, 4, 5, 6}, Shape: gonpy.Shape{2, 3}, DType: gonpy.DTypeF32, Device: "cpu"},
+		{Data: []int64{-1, 7}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeI64, Device: "cpu"},
+		{Data: []uint16{0x3f80, 0xc000}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeBF16, Device: "cpu"},
+		{Data: []bool{true, false, true}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeBool, Device: "cpu"},
+	}
+	for _, in := range tests {
+		v, err := NewValue(in)
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		var out *gonpy.Tensor
+		if c, ok := v.(*ort.CustomDataTensor); ok {
+			out, err = FromCustomDataTensor(c, in.DType)
+		} else {
+			out, err = FromValue(v)
+		}
+		v.Destroy()
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		if out.DType != in.DType || !out.Shape.Equal(in.Shape) || fmt.Sprint(out.Data) != fmt.Sprint(in.Data) {
+			t.Errorf("%s %v read back as %s %v %v", in.DType, in.Shape, out.DType, out.Shape, out.Data)
+		}
+	}
+}
+
+func TestNewValueInvalid(t *testing.T) {
+	// Both are rejected before the runtime is used
+	c := &gonpy.Tensor{Data: []complex64{1}, Shape: gonpy.Shape{1}, DType: gonpy.DTypeC64, Device: "cpu"}
+	if _, err := NewValue(c); !errors.Is(err, gonpy.ErrUnsupportedDType) {
+		t.Errorf("complex tensor: got %v, want ErrUnsupportedDType", err)
+	}
+	short := &gonpy.Tensor{Data: []float32{1}, Shape: gonpy.Shape{2}, DType: gonpy.DTypeF32, Device: "cpu"}
+	if _, err := NewValue(short); err == nil {
+		t.Error("tensor with short data converted")
+	}
+}
+
+func TestFromData(t *testing.T) {
+	x, err := fromData(gonpy.DTypeI32, ort.Shape{1, 2}, widen[int16, int32]([]int16{-1, 300}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(x.Data); !x.Shape.Equal(gonpy.Shape{1, 2}) || got != "[-1 300]" {
+		t.Errorf("x = %v %s", x.Shape, got)
+	}
+	if _, err := fromData(gonpy.DTypeF32, ort.Shape{3}, []float32{1}); err == nil {
+		t.Error("data shorter than its shape accepted")
+	}
+	if _, err := toShape(ort.Shape{2, -1}); err == nil {
+		t.Error("negative dimension accepted")
+	}
+}
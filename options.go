@@ -0,0 +1,103 @@
+package gonpy
+
+import "context"
+
+// Option configures the behavior of read and write operations.
+// Options that do not apply to a particular operation are ignored.
+type Option func(*options)
+
+// options holds the settings collected from a list of Option values.
+type options struct {
+	nameCodec NameCodec
+	limiter   *FileLimiter
+
+	allowPickle bool
+	overflow    OverflowPolicy
+	storeAs     DType
+	zeroCopy    bool
+	headerAlign int
+	maxBytes    int64
+	allocator   Allocator
+	device      string
+	duplicates  DuplicatePolicy
+	atomic      bool
+	sync        bool
+	skipFailed  bool
+	equalNaN    bool
+
+	writeChecksums  bool
+	verifyChecksums bool
+	checksums       map[string]uint32 // Loaded from the archive being read
+
+	cacheBytes int64
+
+	safetensorsMetadata map[string]string
+
+	csvDelimiter rune
+	csvHeader    []string
+	csvHasHeader bool
+
+	jsonValues bool
+
+	tfrecordRaw bool
+
+	wavBits int
+
+	progress ProgressFunc
+	tracker  *progressTracker // Progress of the operation in flight
+
+	ctx context.Context
+}
+
+// defaultHeaderAlign is the alignment of array data in written NPY files,
+// matching numpy since version 1.14.
+const defaultHeaderAlign = 64
+
+// newOptions applies opts over the package defaults.
+func newOptions(opts []Option) *options {
+	o := &options{
+		nameCodec:   NumpyNameCodec{},
+		limiter:     defaultLimiter.Load(),
+		headerAlign: defaultHeaderAlign,
+		maxBytes:    defaultMaxBytes,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithNameCodec sets the codec used to map tensor names to NPZ entry names.
+func WithNameCodec(c NameCodec) Option {
+	return func(o *options) {
+		o.nameCodec = c
+	}
+}
+
+// WithAllowPickle enables decoding of object arrays, which numpy stores as
+// pickles. Only a restricted unpickler is used: it never executes code and
+// understands just enough of the format to recover flat lists of Python
+// strings, bytes, booleans, integers, and floats.
+func WithAllowPickle() Option {
+	return func(o *options) {
+		o.allowPickle = true
+	}
+}
+
+// WithStoreAs converts floating-point tensors to the given floating-point dtype
+// (typically DTypeF16 or DTypeBF16) as they are written, trading precision for
+// size. Tensors of other dtypes are written unchanged.
+func WithStoreAs(dtype DType) Option {
+	return func(o *options) {
+		o.storeAs = dtype
+	}
+}
+
+// WithHeaderAlignment pads the headers of written NPY files so that the array
+// data starts at a multiple of n bytes. The default is 64, as in numpy, which
+// suits memory-mapped and SIMD consumers; older numpy versions used 16.
+func WithHeaderAlignment(n int) Option {
+	return func(o *options) {
+		o.headerAlign = n
+	}
+}
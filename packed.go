@@ -0,0 +1,152 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Packed 4-bit integer tensors store two values per byte. Values are packed in
+// row-major order over the flattened tensor, with element 2k in the low nibble
+// and element 2k+1 in the high nibble of byte k; when the element count is odd
+// the final high nibble is zero. Signed values use 4-bit two's complement.
+//
+// numpy has no 4-bit dtype, so packed tensors are stored as flat '|u1' arrays
+// of the packed bytes. In NPZ archives the logical dtype and shape of each
+// packed tensor are recorded in a JSON entry named packingEntry, which readers
+// in this package use to restore the tensor. Single NPY files have no room for
+// this metadata and read back as U8.
+
+// packingEntry is the NPZ entry recording the logical dtype and shape of packed tensors.
+const packingEntry = "__packing__.json"
+
+// packingOrder names the nibble order recorded in the packing metadata.
+const packingOrder = "low-nibble-first"
+
+// packingInfo describes one packed tensor in the packing metadata.
+type packingInfo struct {
+	DType DType  `json:"dtype"`
+	Shape Shape  `json:"shape"`
+	Order string `json:"order"`
+}
+
+// isPacked reports whether the dtype stores multiple elements per byte.
+func (d DType) isPacked() bool {
+	return d == DTypeI4 || d == DTypeU4
+}
+
+// PackInt4 packs signed values in [-8, 7] two per byte.
+func PackInt4(values []int8) ([]byte, error) {
+	packed := make([]byte, (len(values)+1)/2)
+	for i, v := range values {
+		if v < -8 || v > 7 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("value %d at index %d out of int4 range", v, i)}
+		}
+		packed[i/2] |= byte(v&0x0f) << (4 * (i % 2))
+	}
+	return packed, nil
+}
+
+// UnpackInt4 unpacks n signed 4-bit values.
+func UnpackInt4(packed []byte, n int) []int8 {
+	values := make([]int8, n)
+	for i := range values {
+		nibble := packed[i/2] >> (4 * (i % 2)) & 0x0f
+		values[i] = int8(nibble<<4) >> 4 // Sign-extend
+	}
+	return values
+}
+
+// PackUint4 packs unsigned values in [0, 15] two per byte.
+func PackUint4(values []uint8) ([]byte, error) {
+	packed := make([]byte, (len(values)+1)/2)
+	for i, v := range values {
+		if v > 15 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("value %d at index %d out of uint4 range", v, i)}
+		}
+		packed[i/2] |= v << (4 * (i % 2))
+	}
+	return packed, nil
+}
+
+// UnpackUint4 unpacks n unsigned 4-bit values.
+func UnpackUint4(packed []byte, n int) []uint8 {
+	values := make([]uint8, n)
+	for i := range values {
+		values[i] = packed[i/2] >> (4 * (i % 2)) & 0x0f
+	}
+	return values
+}
+
+// packedStorage returns the U8 tensor of packed bytes used to store a packed tensor.
+func (t *Tensor) packedStorage() (*Tensor, error) {
+	data, ok := t.Data.([]byte)
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor data is %T, expected packed []byte", t.DType, t.Data)}
+	}
+	if want := (t.Shape.ElemCount() + 1) / 2; len(data) != want {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s tensor has %d packed bytes, shape %v needs %d", t.DType, len(data), t.Shape, want)}
+	}
+	return &Tensor{Data: data, Shape: Shape{len(data)}, DType: DTypeU8, Device: t.Device}, nil
+}
+
+// readPacking loads the packing metadata of an archive, if present.
+func readPacking(files []*zip.File) (map[string]packingInfo, error) {
+	for _, file := range files {
+		if file.Name != packingEntry {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return decodePacking(rc)
+	}
+	return nil, nil
+}
+
+// decodePacking parses packing metadata.
+func decodePacking(r io.Reader) (map[string]packingInfo, error) {
+	var packing map[string]packingInfo
+	if err := json.NewDecoder(r).Decode(&packing); err != nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid packing metadata: %v", err)}
+	}
+	for name, info := range packing {
+		if !info.DType.isPacked() || info.Order != packingOrder {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported packing %s/%s for %s", info.DType, info.Order, name)}
+		}
+		if _, err := info.Shape.CheckedElemCount(); err != nil {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("invalid packing shape for %s: %v", name, err)}
+		}
+	}
+	return packing, nil
+}
+
+// writePacking writes the packing metadata entry, if there are any packed tensors.
+func writePacking(zw *zip.Writer, packing map[string]packingInfo) error {
+	if len(packing) == 0 {
+		return nil
+	}
+	w, err := zw.Create(packingEntry)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(packing)
+}
+
+// unpackStorage restores a packed tensor from its stored U8 form.
+func (p packingInfo) unpackStorage(name string, t *Tensor) (*Tensor, error) {
+	if t.OnDevice() {
+		if t.DType != DTypeU8 || t.Shape.ElemCount() != (p.Shape.ElemCount()+1)/2 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("stored data of packed tensor %s does not match its metadata", name)}
+		}
+		return &Tensor{Data: t.Data, Shape: p.Shape, DType: p.DType, Device: t.Device}, nil
+	}
+	data, ok := t.Data.([]byte)
+	if t.DType != DTypeU8 || !ok || len(data) != (p.Shape.ElemCount()+1)/2 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("stored data of packed tensor %s does not match its metadata", name)}
+	}
+	return &Tensor{Data: data, Shape: p.Shape, DType: p.DType, Device: t.Device}, nil
+}
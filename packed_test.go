@@ -0,0 +1,38 @@
+package gonpy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackedRoundTrip(t *testing.T) {
+	packed, err := PackInt4([]int8{-8, 7, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "p.npz")
+	err = WriteNPZ(path, map[string]*Tensor{
+		"q": {Data: packed, Shape: Shape{3}, DType: DTypeI4, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := out[0].Tensor
+	if q.DType != DTypeI4 || !q.Shape.Equal(Shape{3}) || string(q.Data.([]byte)) != string(packed) {
+		t.Errorf("q = %s %v %v", q.DType, q.Shape, q.Data)
+	}
+}
+
+func TestDecodePackingHostileShapes(t *testing.T) {
+	for _, shape := range []string{"[-2]", "[4611686018427387904, 4]"} {
+		meta := `{"q": {"dtype": "int4", "shape": ` + shape + `, "order": "low-nibble-first"}}`
+		if _, err := decodePacking(strings.NewReader(meta)); err == nil {
+			t.Errorf("packing shape %s accepted", shape)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ReadNPZParallel reads all named tensors from an NPZ file as ReadNPZ does, but
+// decodes up to workers entries concurrently. A non-positive workers uses
+// runtime.GOMAXPROCS(0). Results are returned in archive order. The first error
+// stops further entries from being started and is returned once the entries in
+// progress have finished.
+func ReadNPZParallel(path string, workers int, opts ...Option) ([]NamedTensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	packing, err := readPacking(r.File)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.loadChecksums(r.File); err != nil {
+		return nil, err
+	}
+
+	files, err := tensorEntries(r.File, o)
+	if err != nil {
+		return nil, err
+	}
+	o.track(entriesSize(files))
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(files) {
+		workers = len(files)
+	}
+
+	result := make([]NamedTensor, len(files))
+	errs := make([]error, len(files)) // Entries skipped with WithSkipFailedEntries
+	var next atomic.Int64
+	var failed atomic.Bool
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(files) {
+					return
+				}
+				nt, err := readEntry(files[i], packing, o)
+				if err != nil && o.skipFailure() {
+					errs[i] = locate(err, path, "")
+					continue
+				}
+				if err != nil {
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+				result[i] = nt
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, locate(firstErr, path, "")
+	}
+
+	var skipped []error
+	read := result[:0]
+	for i, nt := range result {
+		if errs[i] != nil {
+			skipped = append(skipped, errs[i])
+			continue
+		}
+		read = append(read, nt)
+	}
+	return read, partialFailure(path, skipped)
+}
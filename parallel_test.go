@@ -0,0 +1,91 @@
+package gonpy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// writeManyNPZ writes an archive of n tensors of assorted dtypes and sizes.
+func writeManyNPZ(t *testing.T, path string, n int) {
+	t.Helper()
+	tensors := make(map[string]*Tensor, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("t%02d", i)
+		switch i % 3 {
+		case 0:
+			data := make([]float32, 100*i+1)
+			for j := range data {
+				data[j] = float32(i*j) / 7
+			}
+			tensors[name] = &Tensor{Data: data, Shape: Shape{len(data)}, DType: DTypeF32, Device: "cpu"}
+		case 1:
+			data := make([]int64, 6*i)
+			for j := range data {
+				data[j] = int64(j - i)
+			}
+			tensors[name] = &Tensor{Data: data, Shape: Shape{i, 6}, DType: DTypeI64, Device: "cpu"}
+		default:
+			tensors[name] = &Tensor{Data: []string{name, "x"}, Shape: Shape{2}, DType: UnicodeDType(3), Device: "cpu"}
+		}
+	}
+	if err := WriteNPZ(path, tensors); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadNPZParallelMatchesSerial(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	writeManyNPZ(t, path, 40)
+	want, err := ReadNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{0, 1, 3, 100} {
+		got, err := ReadNPZParallel(path, workers)
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%d workers: %d tensors, want %d", workers, len(got), len(want))
+		}
+		for i := range want {
+			g, w := got[i], want[i]
+			if g.Name != w.Name || g.Tensor.DType != w.Tensor.DType || !g.Tensor.Shape.Equal(w.Tensor.Shape) || fmt.Sprint(g.Tensor.Data) != fmt.Sprint(w.Tensor.Data) {
+				t.Errorf("%d workers: tensor %d is %s, want %s", workers, i, g.Name, w.Name)
+			}
+		}
+	}
+}
+
+func TestReadNPZParallelErrors(t *testing.T) {
+	dir := t.TempDir()
+	good := string(npyBytes(f32Header, make([]byte, 12)))
+	bad := string(npyBytes(f32Header, make([]byte, 8)))
+	path := filepath.Join(dir, "a.npz")
+	writeZipEntries(t, path, "a.npy", good, "b.npy", bad, "c.npy", good, "d.npy", good)
+
+	for _, workers := range []int{1, 4} {
+		if out, err := ReadNPZParallel(path, workers); err == nil || out != nil {
+			t.Errorf("%d workers: got %d tensors, %v; want an error", workers, len(out), err)
+		}
+
+		out, err := ReadNPZParallel(path, workers, WithSkipFailedEntries())
+		var partial *PartialReadError
+		if !errors.As(err, &partial) {
+			t.Fatalf("%d workers: got %v, want a PartialReadError", workers, err)
+		}
+		var names []string
+		for _, nt := range out {
+			names = append(names, nt.Name)
+		}
+		if fmt.Sprint(names) != "[a c d]" {
+			t.Errorf("%d workers: read %v", workers, names)
+		}
+	}
+
+	if _, err := ReadNPZParallel(filepath.Join(dir, "missing.npz"), 2); err == nil {
+		t.Error("missing archive read")
+	}
+}
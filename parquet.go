@@ -0,0 +1,363 @@
+package gonpy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// parquetPageRows is the number of rows written to each data page.
+const parquetPageRows = 1 << 16
+
+// Parquet physical types, converted types, and encodings.
+const (
+	parquetBoolean   = 0
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetFloat     = 4
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8   = 0
+	parquetUint8  = 11
+	parquetUint32 = 13
+	parquetUint64 = 14
+	parquetInt8   = 15
+
+	parquetPlain = 0
+)
+
+// parquetColumn is one column of a Parquet file: column col of a 2-D tensor
+// with ncols columns, or a whole 1-D tensor.
+type parquetColumn struct {
+	name       string
+	t          *Tensor
+	col, ncols int
+	physical   int32
+	converted  int32 // -1 if none
+	offset     int64 // File offset of the first page
+	size       int64 // Bytes of all pages, with headers
+}
+
+// WriteParquet writes tensors to a Parquet file as the columns of a single
+// row group, so that tools such as DuckDB and Spark can query them. A 1-D
+// tensor becomes one column named after it. A 2-D tensor becomes one column
+// per tensor column, named name_0, name_1, and so on. All tensors must have
+// the same number of rows. Columns are required (no nulls), plain-encoded,
+// and uncompressed. F16 and BF16 values are stored as floats, and unsigned
+// and 8-bit integers carry their converted types.
+func WriteParquet(path string, tensors map[string]*Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	var columns []*parquetColumn
+	rows := -1
+	for _, nt := range sortedTensors(tensors) {
+		t, err := nt.Tensor.storedAs(o)
+		if err != nil {
+			return err
+		}
+		if err := t.checkData(); err != nil {
+			return err
+		}
+		if len(t.Shape) != 1 && len(t.Shape) != 2 {
+			return ErrorNpy{Msg: fmt.Sprintf("parquet column %s must be 1-D or 2-D, not shape %v", nt.Name, t.Shape)}
+		}
+		if rows >= 0 && t.Shape[0] != rows {
+			return ErrorNpy{Msg: fmt.Sprintf("parquet column %s has %d rows, others have %d", nt.Name, t.Shape[0], rows)}
+		}
+		rows = t.Shape[0]
+		physical, converted, ok := parquetType(t.DType)
+		if !ok {
+			return ErrorNpy{Msg: fmt.Sprintf("parquet has no type for %s tensor %s", t.DType, nt.Name), Err: ErrUnsupportedDType}
+		}
+		if len(t.Shape) == 1 {
+			columns = append(columns, &parquetColumn{name: nt.Name, t: t, ncols: 1, physical: physical, converted: converted})
+			continue
+		}
+		for j := 0; j < t.Shape[1]; j++ {
+			name := fmt.Sprintf("%s_%d", nt.Name, j)
+			columns = append(columns, &parquetColumn{name: name, t: t, col: j, ncols: t.Shape[1], physical: physical, converted: converted})
+		}
+	}
+	rows = max(rows, 0)
+
+	f, err := createOutput(path, o)
+	if err != nil {
+		return err
+	}
+	if err := writeParquet(f, columns, rows, o); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
+}
+
+// parquetType returns the physical and converted types that store dtype.
+func parquetType(dtype DType) (int32, int32, bool) {
+	switch dtype {
+	case DTypeBool:
+		return parquetBoolean, -1, true
+	case DTypeI8:
+		return parquetInt32, parquetInt8, true
+	case DTypeU8:
+		return parquetInt32, parquetUint8, true
+	case DTypeI32:
+		return parquetInt32, -1, true
+	case DTypeU32:
+		return parquetInt32, parquetUint32, true
+	case DTypeI64:
+		return parquetInt64, -1, true
+	case DTypeU64:
+		return parquetInt64, parquetUint64, true
+	case DTypeF16, DTypeBF16, DTypeF32:
+		return parquetFloat, -1, true
+	case DTypeF64:
+		return parquetDouble, -1, true
+	}
+	if kind, _, ok := dtype.stringKind(); ok {
+		if kind == 'U' {
+			return parquetByteArray, parquetUTF8, true
+		}
+		return parquetByteArray, -1, true
+	}
+	return 0, 0, false
+}
+
+// writeParquet writes the pages of every column and the file metadata to f.
+func writeParquet(f *outputFile, columns []*parquetColumn, rows int, o *options) error {
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	w := o.cancelableWriter(bw)
+	offset := int64(len(parquetMagic))
+	if _, err := w.Write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	var page []byte
+	for _, c := range columns {
+		c.offset = offset
+		for start := 0; start < rows || (start == 0 && rows == 0); start += parquetPageRows {
+			end := min(start+parquetPageRows, rows)
+			page = c.appendValues(page[:0], start, end)
+			if len(page) > math.MaxInt32 {
+				return ErrorNpy{Msg: fmt.Sprintf("parquet page of column %s is too large", c.name)}
+			}
+			header := parquetPageHeader(end-start, len(page))
+			if _, err := w.Write(header); err != nil {
+				return err
+			}
+			if _, err := w.Write(page); err != nil {
+				return err
+			}
+			offset += int64(len(header) + len(page))
+		}
+		c.size = offset - c.offset
+	}
+
+	meta := parquetFileMetaData(columns, rows)
+	if _, err := w.Write(meta); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(meta))); err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(parquetMagic)); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// appendValues appends the plain encoding of rows start to end of the column.
+func (c *parquetColumn) appendValues(b []byte, start, end int) []byte {
+	le := binary.LittleEndian
+	for i := start*c.ncols + c.col; i < end*c.ncols; i += c.ncols {
+		switch data := c.t.Data.(type) {
+		case []bool:
+			k := i / c.ncols
+			if (k-start)%8 == 0 {
+				b = append(b, 0)
+			}
+			if data[i] {
+				b[len(b)-1] |= 1 << ((k - start) % 8)
+			}
+		case []int8:
+			b = le.AppendUint32(b, uint32(int32(data[i])))
+		case []uint8:
+			b = le.AppendUint32(b, uint32(data[i]))
+		case []int32:
+			b = le.AppendUint32(b, uint32(data[i]))
+		case []uint32:
+			b = le.AppendUint32(b, data[i])
+		case []int64:
+			b = le.AppendUint64(b, uint64(data[i]))
+		case []uint64:
+			b = le.AppendUint64(b, data[i])
+		case []uint16:
+			v := f16BitsToF32(data[i])
+			if c.t.DType == DTypeBF16 {
+				v = bf16BitsToF32(data[i])
+			}
+			b = le.AppendUint32(b, math.Float32bits(v))
+		case []float32:
+			b = le.AppendUint32(b, math.Float32bits(data[i]))
+		case []float64:
+			b = le.AppendUint64(b, math.Float64bits(data[i]))
+		case []string:
+			b = le.AppendUint32(b, uint32(len(data[i])))
+			b = append(b, data[i]...)
+		}
+	}
+	return b
+}
+
+// parquetPageHeader returns the PageHeader of a plain-encoded data page.
+func parquetPageHeader(values, size int) []byte {
+	var w thriftWriter
+	w.i32(1, 0) // DATA_PAGE
+	w.i32(2, int32(size))
+	w.i32(3, int32(size))
+	w.beginStruct(5)
+	w.i32(1, int32(values))
+	w.i32(2, parquetPlain)
+	w.i32(3, 3) // RLE levels, though required columns have none
+	w.i32(4, 3)
+	w.endStruct()
+	w.endStruct()
+	return w.buf
+}
+
+// parquetFileMetaData returns the FileMetaData of a file with one row group.
+func parquetFileMetaData(columns []*parquetColumn, rows int) []byte {
+	var w thriftWriter
+	w.i32(1, 1) // Version
+	w.beginList(2, thriftStruct, len(columns)+1)
+	w.beginElement()
+	w.binary(4, "schema") // Root schema element
+	w.i32(5, int32(len(columns)))
+	w.endStruct()
+	for _, c := range columns {
+		w.beginElement()
+		w.i32(1, c.physical)
+		w.i32(3, 0) // REQUIRED
+		w.binary(4, c.name)
+		if c.converted >= 0 {
+			w.i32(6, c.converted)
+		}
+		w.endStruct()
+	}
+	w.i64(3, int64(rows))
+
+	var total int64
+	for _, c := range columns {
+		total += c.size
+	}
+	w.beginList(4, thriftStruct, 1)
+	w.beginElement()
+	w.beginList(1, thriftStruct, len(columns))
+	for _, c := range columns {
+		w.beginElement()
+		w.i64(2, c.offset)
+		w.beginStruct(3)
+		w.i32(1, c.physical)
+		w.beginList(2, thriftI32, 1)
+		w.buf = binary.AppendVarint(w.buf, parquetPlain)
+		w.beginList(3, thriftBinary, 1)
+		w.varint(uint64(len(c.name)))
+		w.buf = append(w.buf, c.name...)
+		w.i32(4, 0) // UNCOMPRESSED
+		w.i64(5, int64(rows))
+		w.i64(6, c.size)
+		w.i64(7, c.size)
+		w.i64(9, c.offset)
+		w.endStruct()
+		w.endStruct()
+	}
+	w.i64(2, total)
+	w.i64(3, int64(rows))
+	w.endStruct()
+	w.binary(6, "gonpy")
+	w.endStruct()
+	return w.buf
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes structs in the Thrift compact protocol. Fields are
+// written in increasing id order, and endStruct closes the innermost struct.
+type thriftWriter struct {
+	buf   []byte
+	last  int16   // Id of the previous field in the current struct
+	outer []int16 // Saved ids of enclosing structs
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	w.buf = binary.AppendUvarint(w.buf, v)
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if delta := id - w.last; delta > 0 && delta <= 15 {
+		w.buf = append(w.buf, byte(delta)<<4|typ)
+	} else {
+		w.buf = append(w.buf, typ)
+		w.buf = binary.AppendVarint(w.buf, int64(id))
+	}
+	w.last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.buf = binary.AppendVarint(w.buf, int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.buf = binary.AppendVarint(w.buf, v)
+}
+
+func (w *thriftWriter) binary(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// beginStruct starts a struct-valued field.
+func (w *thriftWriter) beginStruct(id int16) {
+	w.field(id, thriftStruct)
+	w.outer = append(w.outer, w.last)
+	w.last = 0
+}
+
+// beginList starts a list field of n elements. Struct elements are written
+// between beginElement and endStruct; others with the raw encoders.
+func (w *thriftWriter) beginList(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf = append(w.buf, byte(n)<<4|elem)
+	} else {
+		w.buf = append(w.buf, 0xf0|elem)
+		w.varint(uint64(n))
+	}
+}
+
+// beginElement starts a struct element of a list.
+func (w *thriftWriter) beginElement() {
+	w.outer = append(w.outer, w.last)
+	w.last = 0
+}
+
+// endStruct writes the stop byte of the innermost struct.
+func (w *thriftWriter) endStruct() {
+	w.buf = append(w.buf, 0)
+	if n := len(w.outer); n > 0 {
+		w.last = w.outer[n-1]
+		w.outer = w.outer[:n-1]
+	}
+}
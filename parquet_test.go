@@ -0,0 +1,81 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteParquet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.parquet")
+	err := WriteParquet(path, map[string]*Tensor{
+		"a": {Data: []int32{1, -2, 3}, Shape: Shape{3}, DType: DTypeI32, Device: "cpu"},
+		"b": {Data: []float64{1, 2, 3, 4, 5, 6}, Shape: Shape{3, 2}, DType: DTypeF64, Device: "cpu"},
+		"c": {Data: []bool{true, false, true}, Shape: Shape{3}, DType: DTypeBool, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, []byte(parquetMagic)) || !bytes.HasSuffix(b, []byte(parquetMagic)) {
+		t.Fatal("missing magic")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	if metaLen <= 0 || metaLen > len(b)-12 {
+		t.Fatalf("footer length %d in a file of %d bytes", metaLen, len(b))
+	}
+	meta := b[len(b)-8-metaLen : len(b)-8]
+	for _, name := range []string{"a", "b_0", "b_1", "c"} {
+		if !bytes.Contains(meta, []byte(name)) {
+			t.Errorf("footer lacks column %s", name)
+		}
+	}
+
+	// The first column is a, whose single page follows the magic
+	values := []byte{1, 0, 0, 0, 0xfe, 0xff, 0xff, 0xff, 3, 0, 0, 0}
+	page := append(parquetPageHeader(3, len(values)), values...)
+	if !bytes.HasPrefix(b[len(parquetMagic):], page) {
+		t.Errorf("first page is %x, want %x", b[len(parquetMagic):len(parquetMagic)+len(page)], page)
+	}
+}
+
+func TestParquetColumnValues(t *testing.T) {
+	m := &Tensor{Data: []uint16{0x3c00, 0x4000, 0x4200, 0x4400}, Shape: Shape{2, 2}, DType: DTypeF16, Device: "cpu"}
+	c := &parquetColumn{t: m, col: 1, ncols: 2}
+	want := []byte{0, 0, 0, 0x40, 0, 0, 0x80, 0x40} // 2.0 and 4.0 as float32
+	if got := c.appendValues(nil, 0, 2); !bytes.Equal(got, want) {
+		t.Errorf("column 1 of f16 matrix = %x, want %x", got, want)
+	}
+	bits := make([]bool, 10)
+	bits[0], bits[9] = true, true
+	c = &parquetColumn{t: &Tensor{Data: bits, Shape: Shape{10}, DType: DTypeBool}, ncols: 1}
+	if got := c.appendValues(nil, 0, 10); !bytes.Equal(got, []byte{0x01, 0x02}) {
+		t.Errorf("bit-packed booleans = %x", got)
+	}
+}
+
+func TestWriteParquetInvalid(t *testing.T) {
+	tests := map[string]map[string]*Tensor{
+		"rows": {
+			"a": {Data: []int32{1, 2}, Shape: Shape{2}, DType: DTypeI32, Device: "cpu"},
+			"b": {Data: []int32{1}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"},
+		},
+		"rank":  {"a": {Data: []int32{1}, Shape: Shape{1, 1, 1}, DType: DTypeI32, Device: "cpu"}},
+		"dtype": {"a": {Data: []complex64{1}, Shape: Shape{1}, DType: DTypeC64, Device: "cpu"}},
+		"short": {"a": {Data: []int32{1}, Shape: Shape{2}, DType: DTypeI32, Device: "cpu"}},
+	}
+	for name, tensors := range tests {
+		path := filepath.Join(t.TempDir(), "t.parquet")
+		if err := WriteParquet(path, tensors); err == nil {
+			t.Errorf("%s: written without error", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: failed write left a file", name)
+		}
+	}
+}
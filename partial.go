@@ -0,0 +1,48 @@
+package gonpy
+
+import "fmt"
+
+// WithSkipFailedEntries makes ReadNPZ, ReadNPZParallel, ReadNPZMatch, and
+// ReadNPZRegexp skip entries that cannot be read, for example because of an
+// unsupported dtype, instead of failing the whole read. The tensors that were
+// read are returned together with a *PartialReadError listing the entries
+// skipped. Cancellation still stops the read.
+func WithSkipFailedEntries() Option {
+	return func(o *options) {
+		o.skipFailed = true
+	}
+}
+
+// PartialReadError is returned alongside the tensors that were read when
+// WithSkipFailedEntries caused entries to be skipped. Use errors.As to obtain
+// it; errors.Is matches the errors of the individual entries.
+type PartialReadError struct {
+	Path   string
+	Errors []error // A *ReadError for each skipped entry, in archive order
+}
+
+func (e *PartialReadError) Error() string {
+	if len(e.Errors) == 1 {
+		return fmt.Sprintf("skipped 1 array: %v", e.Errors[0])
+	}
+	return fmt.Sprintf("skipped %d arrays, first: %v", len(e.Errors), e.Errors[0])
+}
+
+func (e *PartialReadError) Unwrap() []error {
+	return e.Errors
+}
+
+// skipFailure reports whether an entry that failed to read should be skipped
+// rather than failing the read.
+func (o *options) skipFailure() bool {
+	return o.skipFailed && (o.ctx == nil || o.ctx.Err() == nil)
+}
+
+// partialFailure returns a *PartialReadError for the entries of the archive at
+// path that were skipped, or nil if there were none.
+func partialFailure(path string, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PartialReadError{Path: path, Errors: errs}
+}
@@ -0,0 +1,596 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"unicode/utf8"
+)
+
+// pyGlobal is a reference to a Python callable or class by module and name.
+// The unpickler never resolves or calls it.
+type pyGlobal struct {
+	Module string
+	Name   string
+}
+
+// pyObject records the construction of a Python object: the callable applied to
+// its arguments (REDUCE/NEWOBJ) and the state later applied with BUILD.
+type pyObject struct {
+	Callable interface{}
+	Args     pyTuple
+	State    interface{}
+}
+
+// pyList is a mutable Python list.
+type pyList struct {
+	Items []interface{}
+}
+
+// pyDict is a Python dict preserving insertion order.
+type pyDict struct {
+	Keys   []interface{}
+	Values []interface{}
+}
+
+// Get returns the value stored under a string key.
+func (d *pyDict) Get(key string) (interface{}, bool) {
+	for i, k := range d.Keys {
+		if s, ok := k.(string); ok && s == key {
+			return d.Values[i], true
+		}
+	}
+	return nil, false
+}
+
+// pyMark is the stack sentinel pushed by the MARK opcode.
+type pyMark struct{}
+
+// Pickle opcodes understood by the restricted unpickler.
+const (
+	opMark           = '('
+	opStop           = '.'
+	opPop            = '0'
+	opPopMark        = '1'
+	opDup            = '2'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opBinFloat       = 'G'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opBinUnicode     = 'X'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	opAppend         = 'a'
+	opAppends        = 'e'
+	opBuild          = 'b'
+	opGlobal         = 'c'
+	opDict           = 'd'
+	opEmptyDict      = '}'
+	opGet            = 'g'
+	opBinGet         = 'h'
+	opLongBinGet     = 'j'
+	opList           = 'l'
+	opEmptyList      = ']'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opReduce         = 'R'
+	opSetItem        = 's'
+	opSetItems       = 'u'
+	opTuple          = 't'
+	opEmptyTuple     = ')'
+	opPersID         = 'P'
+	opBinPersID      = 'Q'
+	opProto          = 0x80
+	opNewObj         = 0x81
+	opTuple1         = 0x85
+	opTuple2         = 0x86
+	opTuple3         = 0x87
+	opNewTrue        = 0x88
+	opNewFalse       = 0x89
+	opLong1          = 0x8a
+	opLong4          = 0x8b
+	opShortUnicode   = 0x8c
+	opBinUnicode8    = 0x8d
+	opBinBytes8      = 0x8e
+	opEmptySet       = 0x8f
+	opStackGlobal    = 0x93
+	opMemoize        = 0x94
+	opFrame          = 0x95
+)
+
+// maxPickleAlloc bounds the size of any single string or bytes value so that a
+// corrupt length prefix cannot trigger an enormous allocation.
+const maxPickleAlloc = 1 << 30
+
+// pickleReadChunk is the largest value read into a buffer allocated up front.
+const pickleReadChunk = 1 << 16
+
+// unpickler is a restricted pickle virtual machine. It builds an inert object
+// graph and never imports modules or invokes callables.
+type unpickler struct {
+	r     io.Reader
+	stack []interface{}
+	memo  map[int]interface{}
+
+	// persistentLoad resolves persistent ids to objects stored outside the
+	// pickle. Persistent ids are rejected when it is nil.
+	persistentLoad func(pid interface{}) (interface{}, error)
+}
+
+// unpickle decodes a single pickled value from r.
+func unpickle(r io.Reader) (interface{}, error) {
+	u := &unpickler{r: r, memo: make(map[int]interface{})}
+	return u.run()
+}
+
+func (u *unpickler) errorf(format string, args ...interface{}) error {
+	return ErrorNpy{Msg: "pickle: " + fmt.Sprintf(format, args...)}
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, u.errorf("stack underflow")
+	}
+	v := u.stack[len(u.stack)-1]
+	u.stack = u.stack[:len(u.stack)-1]
+	return v, nil
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, u.errorf("stack underflow")
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+// popMark pops all items above the most recent mark, and the mark itself.
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(pyMark); ok {
+			items := append([]interface{}{}, u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, u.errorf("mark not found")
+}
+
+func (u *unpickler) readN(n int) ([]byte, error) {
+	if n < 0 || n > maxPickleAlloc {
+		return nil, u.errorf("invalid length %d", n)
+	}
+	if n > pickleReadChunk {
+		// Grow with the data read, so that a length prefix beyond the end of
+		// the input fails without allocating its full size
+		buf, err := io.ReadAll(io.LimitReader(u.r, int64(n)))
+		if err != nil {
+			return nil, err
+		}
+		if len(buf) < n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return buf, nil
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(u.r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (u *unpickler) readUint(n int) (uint64, error) {
+	buf, err := u.readN(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for i := n - 1; i >= 0; i-- {
+		v = v<<8 | uint64(buf[i])
+	}
+	return v, nil
+}
+
+// readLine reads a newline-terminated argument of a text opcode.
+func (u *unpickler) readLine() (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(u.r, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return string(line), nil
+		}
+		if len(line) >= 1<<16 {
+			return "", u.errorf("line too long")
+		}
+		line = append(line, b[0])
+	}
+}
+
+func (u *unpickler) run() (interface{}, error) {
+	op := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(u.r, op); err != nil {
+			return nil, err
+		}
+		if err := u.step(op[0]); err != nil {
+			if err == io.EOF {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if op[0] == opStop {
+			return u.pop()
+		}
+	}
+}
+
+func (u *unpickler) step(op byte) error {
+	switch op {
+	case opStop:
+		return nil
+	case opProto:
+		_, err := u.readUint(1)
+		return err
+	case opFrame:
+		_, err := u.readUint(8)
+		return err
+	case opMark:
+		u.push(pyMark{})
+	case opPop:
+		_, err := u.pop()
+		return err
+	case opPopMark:
+		_, err := u.popMark()
+		return err
+	case opDup:
+		v, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.push(v)
+
+	case opNone:
+		u.push(nil)
+	case opNewTrue:
+		u.push(true)
+	case opNewFalse:
+		u.push(false)
+	case opBinInt:
+		v, err := u.readUint(4)
+		if err != nil {
+			return err
+		}
+		u.push(int64(int32(uint32(v))))
+	case opBinInt1:
+		v, err := u.readUint(1)
+		if err != nil {
+			return err
+		}
+		u.push(int64(v))
+	case opBinInt2:
+		v, err := u.readUint(2)
+		if err != nil {
+			return err
+		}
+		u.push(int64(v))
+	case opLong1, opLong4:
+		width := 1
+		if op == opLong4 {
+			width = 4
+		}
+		n, err := u.readUint(width)
+		if err != nil {
+			return err
+		}
+		buf, err := u.readN(int(n))
+		if err != nil {
+			return err
+		}
+		v, err := u.decodeLong(buf)
+		if err != nil {
+			return err
+		}
+		u.push(v)
+	case opBinFloat:
+		buf, err := u.readN(8)
+		if err != nil {
+			return err
+		}
+		u.push(math.Float64frombits(binary.BigEndian.Uint64(buf)))
+
+	case opShortUnicode, opBinUnicode, opBinUnicode8:
+		s, err := u.readCounted(op)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(s) {
+			return u.errorf("invalid utf-8 string")
+		}
+		u.push(string(s))
+	case opShortBinBytes, opBinBytes, opBinBytes8, opShortBinString, opBinString:
+		b, err := u.readCounted(op)
+		if err != nil {
+			return err
+		}
+		u.push(b)
+
+	case opEmptyTuple:
+		u.push(pyTuple{})
+	case opTuple:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(pyTuple(items))
+	case opTuple1, opTuple2, opTuple3:
+		n := int(op-opTuple1) + 1
+		if len(u.stack) < n {
+			return u.errorf("stack underflow")
+		}
+		items := append(pyTuple{}, u.stack[len(u.stack)-n:]...)
+		u.stack = u.stack[:len(u.stack)-n]
+		u.push(items)
+	case opEmptyList:
+		u.push(&pyList{})
+	case opList:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(&pyList{Items: items})
+	case opAppend, opAppends:
+		var items []interface{}
+		if op == opAppend {
+			v, err := u.pop()
+			if err != nil {
+				return err
+			}
+			items = []interface{}{v}
+		} else {
+			var err error
+			if items, err = u.popMark(); err != nil {
+				return err
+			}
+		}
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		list, ok := top.(*pyList)
+		if !ok {
+			return u.errorf("append to non-list %T", top)
+		}
+		list.Items = append(list.Items, items...)
+	case opEmptyDict:
+		u.push(&pyDict{})
+	case opEmptySet:
+		u.push(&pyList{})
+	case opDict:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		d := &pyDict{}
+		if err := u.setItems(d, items); err != nil {
+			return err
+		}
+		u.push(d)
+	case opSetItem, opSetItems:
+		var items []interface{}
+		if op == opSetItem {
+			if len(u.stack) < 2 {
+				return u.errorf("stack underflow")
+			}
+			items = append([]interface{}{}, u.stack[len(u.stack)-2:]...)
+			u.stack = u.stack[:len(u.stack)-2]
+		} else {
+			var err error
+			if items, err = u.popMark(); err != nil {
+				return err
+			}
+		}
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		d, ok := top.(*pyDict)
+		if !ok {
+			return u.errorf("setitem on non-dict %T", top)
+		}
+		return u.setItems(d, items)
+
+	case opGlobal:
+		module, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		name, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		u.push(pyGlobal{Module: module, Name: name})
+	case opStackGlobal:
+		name, err := u.pop()
+		if err != nil {
+			return err
+		}
+		module, err := u.pop()
+		if err != nil {
+			return err
+		}
+		m, ok1 := module.(string)
+		n, ok2 := name.(string)
+		if !ok1 || !ok2 {
+			return u.errorf("invalid stack global")
+		}
+		u.push(pyGlobal{Module: m, Name: n})
+	case opReduce, opNewObj:
+		args, err := u.pop()
+		if err != nil {
+			return err
+		}
+		callable, err := u.pop()
+		if err != nil {
+			return err
+		}
+		tuple, ok := args.(pyTuple)
+		if !ok {
+			return u.errorf("call arguments are %T, not a tuple", args)
+		}
+		if callable == (pyGlobal{Module: "collections", Name: "OrderedDict"}) && len(tuple) == 0 {
+			u.push(&pyDict{}) // Filled by later SETITEMS
+			break
+		}
+		u.push(&pyObject{Callable: callable, Args: tuple})
+	case opBuild:
+		state, err := u.pop()
+		if err != nil {
+			return err
+		}
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		if _, ok := top.(*pyDict); ok {
+			break // Attributes of dict subclasses are dropped
+		}
+		obj, ok := top.(*pyObject)
+		if !ok {
+			return u.errorf("build on non-object %T", top)
+		}
+		obj.State = state
+	case opPersID, opBinPersID:
+		var pid interface{}
+		var err error
+		if op == opPersID {
+			pid, err = u.readLine()
+		} else {
+			pid, err = u.pop()
+		}
+		if err != nil {
+			return err
+		}
+		if u.persistentLoad == nil {
+			return u.errorf("unsupported persistent id %v", pid)
+		}
+		v, err := u.persistentLoad(pid)
+		if err != nil {
+			return err
+		}
+		u.push(v)
+
+	case opBinPut, opLongBinPut:
+		width := 1
+		if op == opLongBinPut {
+			width = 4
+		}
+		idx, err := u.readUint(width)
+		if err != nil {
+			return err
+		}
+		v, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.memo[int(idx)] = v
+	case opMemoize:
+		v, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.memo[len(u.memo)] = v
+	case opBinGet, opLongBinGet, opGet:
+		var idx int
+		switch op {
+		case opGet:
+			line, err := u.readLine()
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Sscanf(line, "%d", &idx); err != nil {
+				return u.errorf("invalid memo index %q", line)
+			}
+		default:
+			width := 1
+			if op == opLongBinGet {
+				width = 4
+			}
+			v, err := u.readUint(width)
+			if err != nil {
+				return err
+			}
+			idx = int(v)
+		}
+		v, ok := u.memo[idx]
+		if !ok {
+			return u.errorf("memo index %d not found", idx)
+		}
+		u.push(v)
+
+	default:
+		return u.errorf("unsupported opcode 0x%02x", op)
+	}
+	return nil
+}
+
+// readCounted reads the length-prefixed string or bytes argument of op.
+func (u *unpickler) readCounted(op byte) ([]byte, error) {
+	var width int
+	switch op {
+	case opShortUnicode, opShortBinBytes, opShortBinString:
+		width = 1
+	case opBinUnicode, opBinBytes, opBinString:
+		width = 4
+	default:
+		width = 8
+	}
+	n, err := u.readUint(width)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxPickleAlloc {
+		return nil, u.errorf("opcode 0x%02x length %d too large", op, n)
+	}
+	return u.readN(int(n))
+}
+
+// decodeLong decodes a little-endian two's complement integer that fits in an int64.
+func (u *unpickler) decodeLong(buf []byte) (int64, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+	be := make([]byte, len(buf))
+	for i, b := range buf {
+		be[len(buf)-1-i] = b
+	}
+	v := new(big.Int).SetBytes(be)
+	if buf[len(buf)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(buf))))
+	}
+	if !v.IsInt64() {
+		return 0, u.errorf("integer %s overflows int64", v)
+	}
+	return v.Int64(), nil
+}
+
+func (u *unpickler) setItems(d *pyDict, items []interface{}) error {
+	if len(items)%2 != 0 {
+		return u.errorf("odd number of dict items")
+	}
+	for i := 0; i < len(items); i += 2 {
+		d.Keys = append(d.Keys, items[i])
+		d.Values = append(d.Values, items[i+1])
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package gonpy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// objectArrayPickle returns the pickle numpy writes for a one-dimensional
+// object array holding items, which must already be pickled values.
+func objectArrayPickle(n byte, items string) string {
+	return "\x80\x02cnumpy.core.multiarray\n_reconstruct\n)R" +
+		"(K\x01K" + string(n) + "\x85N\x89]q\x00(" + items + "etb."
+}
+
+const objectHeader = "{'descr': '|O', 'fortran_order': False, 'shape': (2,), }"
+
+func TestObjectArray(t *testing.T) {
+	tests := []struct {
+		items string
+		dtype DType
+		want  string
+	}{
+		{"\x8c\x02ab\x8c\x01c", UnicodeDType(2), "[ab c]"},
+		{"C\x02abC\x01c", BytesDType(2), "[ab c]"},
+		{"K\x01M\x00\x01", DTypeI64, "[1 256]"},
+		{"K\x01G\x3f\xf8\x00\x00\x00\x00\x00\x00", DTypeF64, "[1 1.5]"},
+		{"\x88\x89", DTypeBool, "[true false]"},
+	}
+	for _, tt := range tests {
+		in := npyBytes(objectHeader, []byte(objectArrayPickle(2, tt.items)))
+		out, err := ReadNPYFrom(bytes.NewReader(in), WithAllowPickle())
+		if err != nil {
+			t.Errorf("%s: %v", tt.dtype, err)
+			continue
+		}
+		if out.DType != tt.dtype || fmt.Sprint(out.Data) != tt.want {
+			t.Errorf("got %s %s, want %s %s", out.DType, fmt.Sprint(out.Data), tt.dtype, tt.want)
+		}
+	}
+}
+
+func TestObjectArrayNeedsOptIn(t *testing.T) {
+	in := npyBytes(objectHeader, []byte(objectArrayPickle(2, "K\x01K\x02")))
+	if _, err := ReadNPYFrom(bytes.NewReader(in)); !errors.Is(err, ErrObjectArray) {
+		t.Errorf("got %v, want ErrObjectArray", err)
+	}
+}
+
+func TestHostileObjectArrays(t *testing.T) {
+	tests := map[string]string{
+		"shape mismatch": objectArrayPickle(3, "K\x01K\x02K\x03"),
+		"item count":     objectArrayPickle(2, "K\x01"),
+		"mixed kinds":    objectArrayPickle(2, "K\x01\x8c\x01a"),
+		"nested":         objectArrayPickle(2, "K\x01]"),
+		"truncated":      objectArrayPickle(2, "K\x01K\x02")[:30],
+		"huge string":    objectArrayPickle(2, "\x8d\xff\xff\xff\xff\xff\xff\xff\x7f"),
+		"huge long":      objectArrayPickle(2, "\x8b\xff\xff\xff\x7f"),
+		"bad utf-8":      objectArrayPickle(2, "\x8c\x01\xff\x8c\x01a"),
+		"underflow":      "\x80\x02e.",
+		"no mark":        "\x80\x02K\x01t.",
+		"memo miss":      "\x80\x02h\x05.",
+		"import":         "\x80\x02cos\nsystem\n\x8c\x02ls\x85R.",
+		"opcode":         "\x80\x02i.",
+	}
+	for name, p := range tests {
+		in := npyBytes(objectHeader, []byte(p))
+		if _, err := ReadNPYFrom(bytes.NewReader(in), WithAllowPickle()); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+}
+
+func TestPickleLengthBeyondInput(t *testing.T) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := unpickle(strings.NewReader("\x80\x02\x8d\xff\xff\xff\x3f\x00\x00\x00\x00abc"))
+	runtime.ReadMemStats(&after)
+	if err == nil {
+		t.Fatal("truncated string decoded without error")
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("allocated %d bytes for 3 bytes of input", n)
+	}
+}
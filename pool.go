@@ -0,0 +1,35 @@
+package gonpy
+
+import "sync"
+
+// bufferPool recycles scratch buffers of a fixed size.
+type bufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// newBufferPool returns a pool of size-byte buffers.
+func newBufferPool(size int) *bufferPool {
+	return &bufferPool{size: size}
+}
+
+// get returns a buffer of the pool's size with unspecified contents.
+func (p *bufferPool) get() *[]byte {
+	if b, ok := p.pool.Get().(*[]byte); ok {
+		return b
+	}
+	b := make([]byte, p.size)
+	return &b
+}
+
+// put returns a buffer obtained from get to the pool.
+func (p *bufferPool) put(b *[]byte) {
+	p.pool.Put(b)
+}
+
+// Scratch buffers shared by all decode and encode operations.
+var (
+	chunkPool         = newBufferPool(codecChunkBytes)
+	parallelChunkPool = newBufferPool(parallelChunkBytes)
+	headerPool        = newBufferPool(4096)
+)
@@ -0,0 +1,128 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"io"
+	"sync"
+)
+
+// ProgressFunc receives the number of bytes of NPY content processed so far and
+// the total expected, or -1 if the total is not known in advance. For NPZ
+// archives the counts are of uncompressed bytes.
+type ProgressFunc func(done, total int64)
+
+// ioChunk is the largest read or write performed as a single step when
+// progress or cancellation is tracked.
+const ioChunk = 1 << 20
+
+// WithProgress reports the progress of reads and writes to fn. Calls are
+// serialized, including when entries are decoded concurrently.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// progressTracker accumulates the bytes processed by one operation.
+type progressTracker struct {
+	mu    sync.Mutex
+	fn    ProgressFunc
+	done  int64
+	total int64
+}
+
+// track starts tracking an operation of total bytes, if progress was requested.
+func (o *options) track(total int64) {
+	if o.progress != nil {
+		o.tracker = &progressTracker{fn: o.progress, total: total}
+	}
+}
+
+// add records n more bytes processed.
+func (p *progressTracker) add(n int) {
+	if n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += int64(n)
+	p.fn(p.done, p.total)
+}
+
+// reader wraps r so that reads through it are reported. A nil tracker returns r.
+func (p *progressTracker) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+// writer wraps w so that writes through it are reported. A nil tracker returns w.
+func (p *progressTracker) writer(w io.Writer) io.Writer {
+	if p == nil {
+		return w
+	}
+	return &progressWriter{w: w, p: p}
+}
+
+// progressReader reports reads in steps of at most ioChunk bytes.
+type progressReader struct {
+	r io.Reader
+	p *progressTracker
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	if len(b) > ioChunk {
+		b = b[:ioChunk]
+	}
+	n, err := pr.r.Read(b)
+	pr.p.add(n)
+	return n, err
+}
+
+// progressWriter reports writes in steps of at most ioChunk bytes.
+type progressWriter struct {
+	w io.Writer
+	p *progressTracker
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		chunk := b[:min(len(b), ioChunk)]
+		n, err := pw.w.Write(chunk)
+		written += n
+		pw.p.add(n)
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// encodedSize returns the number of bytes t occupies when written as NPY with o.
+func (t *Tensor) encodedSize(o *options) (int64, error) {
+	dtype, shape := t.DType, t.Shape
+	if o.storeAs != "" && t.DType.isFloat() && o.storeAs.isFloat() {
+		dtype = o.storeAs
+	}
+	size := tensorBytes(&Tensor{DType: dtype, Shape: shape})
+	if dtype.isPacked() {
+		dtype, shape = DTypeU8, Shape{int(size)}
+	}
+	prefix, err := encodeHeader(&Header{Descr: dtype, Shape: shape}, o.headerAlign, 0)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(prefix)) + size, nil
+}
+
+// entriesSize returns the total uncompressed size of zip entries.
+func entriesSize(files []*zip.File) int64 {
+	var total int64
+	for _, file := range files {
+		total += int64(file.UncompressedSize64)
+	}
+	return total
+}
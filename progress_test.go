@@ -0,0 +1,153 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// progressLog records the calls of a ProgressFunc.
+type progressLog struct {
+	mu    sync.Mutex
+	calls [][2]int64
+}
+
+func (p *progressLog) fn(done, total int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls = append(p.calls, [2]int64{done, total})
+}
+
+// check fails unless progress rose steadily to a final count equal to total,
+// which every call reported.
+func (p *progressLog) check(t *testing.T, name string, total int64) {
+	t.Helper()
+	if len(p.calls) == 0 {
+		t.Errorf("%s: no progress reported", name)
+		return
+	}
+	var prev int64
+	for _, c := range p.calls {
+		if c[1] != total || c[0] <= prev {
+			t.Errorf("%s: progress %d of %d after %d, want a rising count of %d", name, c[0], c[1], prev, total)
+			return
+		}
+		prev = c[0]
+	}
+	if prev != total {
+		t.Errorf("%s: progress ended at %d of %d", name, prev, total)
+	}
+}
+
+// zipContentSize returns the total uncompressed size of the tensor entries of an archive.
+func zipContentSize(t *testing.T, path string) int64 {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var total int64
+	for _, file := range r.File {
+		if !isMetadataEntry(file.Name) {
+			total += int64(file.UncompressedSize64)
+		}
+	}
+	return total
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+func TestProgressTotals(t *testing.T) {
+	dir := t.TempDir()
+	// Large enough to be reported in several steps
+	big := &Tensor{Data: make([]float64, 300_000), Shape: Shape{1000, 300}, DType: DTypeF64, Device: "cpu"}
+	small := &Tensor{Data: []int32{1, 2, 3}, Shape: Shape{3}, DType: DTypeI32, Device: "cpu"}
+	tensors := map[string]*Tensor{"big": big, "small": small}
+
+	npy := filepath.Join(dir, "a.npy")
+	var p progressLog
+	if err := big.WriteNPY(npy, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "WriteNPY", fileSize(t, npy))
+	p = progressLog{}
+	if _, err := ReadNPY(npy, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "ReadNPY", fileSize(t, npy))
+
+	half := filepath.Join(dir, "half.npy")
+	p = progressLog{}
+	if err := big.WriteNPY(half, WithStoreAs(DTypeF16), WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "WriteNPY as f16", fileSize(t, half))
+
+	npz := filepath.Join(dir, "a.npz")
+	p = progressLog{}
+	if err := WriteNPZ(npz, tensors, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	content := zipContentSize(t, npz)
+	p.check(t, "WriteNPZ", content)
+	p = progressLog{}
+	if _, err := ReadNPZ(npz, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "ReadNPZ", content)
+	p = progressLog{}
+	if _, err := ReadNPZParallel(npz, 2, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "ReadNPZParallel", content)
+
+	p = progressLog{}
+	n, err := NewNpzTensors(npz, WithProgress(p.fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+	if _, err := n.Get("big"); err != nil {
+		t.Fatal(err)
+	}
+	var one bytes.Buffer
+	if err := big.Write(&one); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "NpzTensors.Get", int64(one.Len()))
+
+	var stream bytes.Buffer
+	p = progressLog{}
+	if err := WriteNPYStream(&stream, []*Tensor{big, small}, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "WriteNPYStream", int64(stream.Len()))
+
+	st := filepath.Join(dir, "a.safetensors")
+	p = progressLog{}
+	if err := WriteSafetensors(st, tensors, WithProgress(p.fn)); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "WriteSafetensors", 8*300_000+4*3)
+	p = progressLog{}
+	s, err := OpenSafetensors(st, WithProgress(p.fn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.Get("big"); err != nil {
+		t.Fatal(err)
+	}
+	p.check(t, "SafetensorsFile.Get", 8*300_000)
+}
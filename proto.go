@@ -0,0 +1,192 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// MarshalProto encodes the tensor in the protobuf wire format as the
+// gonpy.Tensor message of tensor.proto: its dtype, its shape, and its
+// elements as they are stored in NPY files, little-endian. Services can carry
+// the message in gRPC requests without a separate tensor format.
+func (t *Tensor) MarshalProto() ([]byte, error) {
+	t, err := t.ToHost()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkData(); err != nil && !t.DType.isPacked() {
+		return nil, err
+	}
+	var data bytes.Buffer
+	if err := writeData(&data, t.DType, t.Data); err != nil {
+		return nil, err
+	}
+	var p, shape protoBuffer
+	if t.DType != "" {
+		p.bytesField(1, []byte(t.DType))
+	}
+	for _, d := range t.Shape {
+		shape.varint(uint64(d))
+	}
+	if len(shape.buf) > 0 {
+		p.bytesField(2, shape.buf)
+	}
+	if data.Len() > 0 {
+		p.bytesField(3, data.Bytes())
+	}
+	return p.buf, nil
+}
+
+// UnmarshalProto decodes a gonpy.Tensor message encoded by MarshalProto or
+// by code generated from tensor.proto. Unknown fields are skipped.
+func (t *Tensor) UnmarshalProto(b []byte) error {
+	var dtype DType
+	shape := Shape{}
+	var raw []byte
+	d := protoReader{buf: b}
+	for !d.done() {
+		field, wire := d.tag()
+		switch {
+		case field == 1 && wire == 2:
+			dtype = DType(d.bytes())
+		case field == 2 && wire == 0: // Unpacked
+			shape = appendProtoDim(shape, d.varint(), &d)
+		case field == 2 && wire == 2:
+			packed := protoReader{buf: d.bytes()}
+			for !packed.done() {
+				shape = appendProtoDim(shape, packed.varint(), &d)
+			}
+			if packed.err != nil {
+				return packed.err
+			}
+		case field == 3 && wire == 2:
+			raw = d.bytes()
+		default:
+			d.skip(wire)
+		}
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if _, err := shape.CheckedElemCount(); err != nil {
+		return err
+	}
+	want, ok := dataSize(dtype, shape)
+	if dtype.isPacked() {
+		want = (want + 1) / 2 // Two elements per byte
+	}
+	if !ok || int64(len(raw)) != want {
+		return ErrorNpy{Msg: fmt.Sprintf("tensor data has %d bytes, %s tensor of shape %v needs %d", len(raw), dtype, shape, want)}
+	}
+	result, err := Zeros(dtype, shape)
+	if err != nil {
+		return err
+	}
+	if dtype.isPacked() {
+		result.Data = bytes.Clone(raw)
+	} else if result.Data, err = readData(shape, dtype, bytes.NewReader(raw)); err != nil {
+		return err
+	}
+	*t = *result
+	return nil
+}
+
+// appendProtoDim appends a dimension decoded from a varint to shape,
+// failing d if it does not fit an int.
+func appendProtoDim(shape Shape, v uint64, d *protoReader) Shape {
+	if v > math.MaxInt32 {
+		d.fail(fmt.Sprintf("tensor dimension %d", int64(v)))
+		return shape
+	}
+	return append(shape, int(v))
+}
+
+// protoBuffer appends protobuf wire data to a buffer.
+type protoBuffer struct {
+	buf []byte
+}
+
+func (p *protoBuffer) reset() {
+	p.buf = p.buf[:0]
+}
+
+func (p *protoBuffer) varint(v uint64) {
+	p.buf = binary.AppendUvarint(p.buf, v)
+}
+
+// bytesField appends a length-delimited field.
+func (p *protoBuffer) bytesField(field int, b []byte) {
+	p.varint(uint64(field<<3 | 2))
+	p.varint(uint64(len(b)))
+	p.buf = append(p.buf, b...)
+}
+
+// protoReader reads protobuf wire data, remembering the first error so that
+// callers can check once after a run of reads.
+type protoReader struct {
+	buf []byte
+	err error
+}
+
+func (d *protoReader) done() bool {
+	return d.err != nil || len(d.buf) == 0
+}
+
+func (d *protoReader) fail(msg string) {
+	if d.err == nil {
+		d.err = ErrorNpy{Msg: msg}
+	}
+	d.buf = nil
+}
+
+func (d *protoReader) varint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail("invalid protobuf varint")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *protoReader) tag() (int, int) {
+	t := d.varint()
+	return int(t >> 3), int(t & 7)
+}
+
+func (d *protoReader) fixed(n int) []byte {
+	if len(d.buf) < n {
+		d.fail("truncated protobuf field")
+		return make([]byte, n)
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *protoReader) bytes() []byte {
+	n := d.varint()
+	if n > uint64(len(d.buf)) {
+		d.fail("protobuf field overruns message")
+		return nil
+	}
+	return d.fixed(int(n))
+}
+
+// skip discards a field of an unknown number.
+func (d *protoReader) skip(wire int) {
+	switch wire {
+	case 0:
+		d.varint()
+	case 1:
+		d.fixed(8)
+	case 2:
+		d.bytes()
+	case 5:
+		d.fixed(4)
+	default:
+		d.fail(fmt.Sprintf("unsupported protobuf wire type %d", wire))
+	}
+}
@@ -0,0 +1,54 @@
+package gonpy
+
+import "testing"
+
+func TestProtoRoundTrip(t *testing.T) {
+	packed, err := PackInt4([]int8{-8, 7, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []*Tensor{
+		{Data: []float64{1, -2.5, 3, 4, 5, 6}, Shape: Shape{3, 2}, DType: DTypeF64, Device: "cpu"},
+		{Data: []int8{-1}, Shape: Shape{}, DType: DTypeI8, Device: "cpu"},
+		{Data: []string{"ab", "c"}, Shape: Shape{2}, DType: BytesDType(2), Device: "cpu"},
+		{Data: packed, Shape: Shape{3}, DType: DTypeI4, Device: "cpu"},
+		{Data: []float32{}, Shape: Shape{0, 4}, DType: DTypeF32, Device: "cpu"},
+	}
+	for _, in := range tests {
+		b, err := in.MarshalProto()
+		if err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		var out Tensor
+		if err := out.UnmarshalProto(b); err != nil {
+			t.Fatalf("%s: %v", in.DType, err)
+		}
+		again, err := out.MarshalProto()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.DType != in.DType || !out.Shape.Equal(in.Shape) || string(again) != string(b) {
+			t.Errorf("%s %v decoded as %s %v", in.DType, in.Shape, out.DType, out.Shape)
+		}
+	}
+}
+
+func TestHostileProto(t *testing.T) {
+	tests := map[string]string{
+		"short data":      "\x0a\x03f32\x12\x01\x02\x1a\x04\x00\x00\x80\x3f",
+		"huge dims":       "\x0a\x02u8\x12\x0a\xff\xff\xff\xff\x07\xff\xff\xff\xff\x07",
+		"dim overflow":    "\x0a\x02u8\x12\x0a\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01",
+		"field overrun":   "\x0a\x7ff32",
+		"bad varint":      "\x0a\x02u8\x10\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff\xff",
+		"truncated fixed": "\x0d\x00\x00",
+		"wire type":       "\x0f",
+		"dtype":           "\x0a\x02q7\x1a\x01\x00",
+		"packed overrun":  "\x0a\x02u8\x12\x05\x01",
+	}
+	for name, b := range tests {
+		var out Tensor
+		if err := out.UnmarshalProto([]byte(b)); err == nil {
+			t.Errorf("%s: decoded without error", name)
+		}
+	}
+}
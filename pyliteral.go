@@ -0,0 +1,211 @@
+package gonpy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pyTuple is a parsed Python tuple. Lists are represented as []interface{}.
+type pyTuple []interface{}
+
+// pyParser is a recursive-descent parser for the subset of Python literal
+// syntax that appears in NPY headers: strings, integers, booleans, None,
+// tuples, lists, and dicts with string keys.
+type pyParser struct {
+	s   string
+	pos int
+}
+
+// parsePyLiteral parses a single Python literal value from s.
+func parsePyLiteral(s string) (interface{}, error) {
+	p := &pyParser{s: s}
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos != len(p.s) {
+		return nil, p.errorf("unexpected trailing input")
+	}
+	return v, nil
+}
+
+func (p *pyParser) errorf(format string, args ...interface{}) error {
+	return ErrorNpy{Msg: fmt.Sprintf("header parse error at offset %d: %s", p.pos, fmt.Sprintf(format, args...))}
+}
+
+func (p *pyParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// peek returns the next non-space byte without consuming it, or 0 at end of input.
+func (p *pyParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.s) {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *pyParser) value() (interface{}, error) {
+	switch c := p.peek(); {
+	case c == 0:
+		return nil, p.errorf("unexpected end of input")
+	case c == '\'' || c == '"':
+		return p.str()
+	case c == '(':
+		items, err := p.sequence('(', ')')
+		if err != nil {
+			return nil, err
+		}
+		return pyTuple(items), nil
+	case c == '[':
+		return p.sequence('[', ']')
+	case c == '{':
+		return p.dict()
+	case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+		return p.integer()
+	default:
+		return p.name()
+	}
+}
+
+func (p *pyParser) str() (string, error) {
+	quote := p.s[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.s) {
+				return "", p.errorf("unterminated escape")
+			}
+			e := p.s[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pyParser) integer() (int64, error) {
+	start := p.pos
+	if c := p.s[p.pos]; c == '-' || c == '+' {
+		p.pos++
+	}
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	text := p.s[start:p.pos]
+	// Python 2 long literals carry an L suffix
+	if p.pos < len(p.s) && (p.s[p.pos] == 'L' || p.s[p.pos] == 'l') {
+		p.pos++
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return 0, p.errorf("invalid integer %q", text)
+	}
+	return n, nil
+}
+
+func (p *pyParser) name() (interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')) {
+			break
+		}
+		p.pos++
+	}
+	switch word := p.s[start:p.pos]; word {
+	case "True":
+		return true, nil
+	case "False":
+		return false, nil
+	case "None":
+		return nil, nil
+	case "":
+		return nil, p.errorf("unexpected character %q", p.s[p.pos])
+	default:
+		return nil, p.errorf("unexpected name %q", word)
+	}
+}
+
+// sequence parses comma-separated values between open and close, allowing a trailing comma.
+func (p *pyParser) sequence(open, close byte) ([]interface{}, error) {
+	p.pos++ // open
+	items := []interface{}{}
+	for {
+		if p.peek() == close {
+			p.pos++
+			return items, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case close:
+		default:
+			return nil, p.errorf("expected ',' or %q", close)
+		}
+	}
+}
+
+func (p *pyParser) dict() (map[string]interface{}, error) {
+	p.pos++ // {
+	m := make(map[string]interface{})
+	for {
+		c := p.peek()
+		if c == '}' {
+			p.pos++
+			return m, nil
+		}
+		if c != '\'' && c != '"' {
+			return nil, p.errorf("expected string key")
+		}
+		key, err := p.str()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ':' {
+			return nil, p.errorf("expected ':' after key %q", key)
+		}
+		p.pos++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return nil, p.errorf("expected ',' or '}'")
+		}
+	}
+}
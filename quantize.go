@@ -0,0 +1,210 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+)
+
+// Quantized tensors use asymmetric affine int8 quantization:
+//
+//	real = scale * (q - zeroPoint)
+//
+// with one scale and zero point for the whole tensor or one per slice along a
+// channel axis. In NPZ archives the int8 values are stored under the tensor's
+// own name and the parameters as companion entries: name+"__scale" (f32),
+// name+"__zero_point" (i8), each of shape () for per-tensor or (C,) for
+// per-channel parameters, and name+"__axis" (i64 scalar, -1 for per-tensor).
+const (
+	quantScaleSuffix     = "__scale"
+	quantZeroPointSuffix = "__zero_point"
+	quantAxisSuffix      = "__axis"
+)
+
+// QuantParams holds the scale and zero point of an int8 quantized tensor.
+type QuantParams struct {
+	Scale     []float32
+	ZeroPoint []int8
+	Axis      int // Channel axis, or -1 for per-tensor parameters
+}
+
+// channels returns the number of parameter sets and the element stride of one
+// channel for a tensor of the given shape.
+func (p *QuantParams) channels(shape Shape) (int, int, error) {
+	if len(p.Scale) != len(p.ZeroPoint) {
+		return 0, 0, ErrorNpy{Msg: fmt.Sprintf("%d scales but %d zero points", len(p.Scale), len(p.ZeroPoint))}
+	}
+	if p.Axis == -1 {
+		if len(p.Scale) != 1 {
+			return 0, 0, ErrorNpy{Msg: fmt.Sprintf("per-tensor quantization needs 1 scale, got %d", len(p.Scale))}
+		}
+		return 1, 1, nil
+	}
+	if p.Axis < 0 || p.Axis >= len(shape) {
+		return 0, 0, ErrorNpy{Msg: fmt.Sprintf("quantization axis %d out of range for shape %v", p.Axis, shape)}
+	}
+	if len(p.Scale) != shape[p.Axis] {
+		return 0, 0, ErrorNpy{Msg: fmt.Sprintf("axis %d has %d channels, got %d scales", p.Axis, shape[p.Axis], len(p.Scale))}
+	}
+	return shape[p.Axis], shape[p.Axis+1:].ElemCount(), nil
+}
+
+// Quantize converts a floating-point tensor to int8 with per-tensor parameters
+// when axis is -1, or per-channel parameters along axis otherwise. Each range
+// is widened to include zero so that zero is represented exactly.
+func Quantize(t *Tensor, axis int) (*Tensor, *QuantParams, error) {
+	f, err := t.ToFloat32()
+	if err != nil {
+		return nil, nil, err
+	}
+	src := f.Data.([]float32)
+
+	p := &QuantParams{Axis: axis}
+	channels := 1
+	if axis != -1 {
+		if axis < 0 || axis >= len(t.Shape) {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("quantization axis %d out of range for shape %v", axis, t.Shape)}
+		}
+		channels = t.Shape[axis]
+	}
+	p.Scale = make([]float32, channels)
+	p.ZeroPoint = make([]int8, channels)
+	_, inner, err := p.channels(t.Shape)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lo := make([]float64, channels)
+	hi := make([]float64, channels)
+	for i, v := range src {
+		x := float64(v)
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("cannot quantize non-finite value %v at index %d", v, i)}
+		}
+		c := i / inner % channels
+		lo[c] = math.Min(lo[c], x)
+		hi[c] = math.Max(hi[c], x)
+	}
+	for c := range p.Scale {
+		scale := (hi[c] - lo[c]) / (math.MaxInt8 - math.MinInt8)
+		if scale == 0 {
+			p.Scale[c] = 1
+			continue
+		}
+		p.Scale[c] = float32(scale)
+		p.ZeroPoint[c] = int8(clampInt8(math.Round(math.MinInt8 - lo[c]/float64(p.Scale[c]))))
+	}
+
+	data := make([]int8, len(src))
+	for i, v := range src {
+		c := i / inner % channels
+		q := math.Round(float64(v)/float64(p.Scale[c])) + float64(p.ZeroPoint[c])
+		data[i] = int8(clampInt8(q))
+	}
+
+	shape := append(Shape(nil), t.Shape...)
+	return &Tensor{Data: data, Shape: shape, DType: DTypeI8, Device: t.Device}, p, nil
+}
+
+// clampInt8 limits x to the int8 range.
+func clampInt8(x float64) float64 {
+	return math.Max(math.MinInt8, math.Min(math.MaxInt8, x))
+}
+
+// Dequantize converts an int8 tensor back to f32 using its quantization parameters.
+func Dequantize(q *Tensor, p *QuantParams) (*Tensor, error) {
+	src, ok := q.Data.([]int8)
+	if q.DType != DTypeI8 || !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot dequantize %s tensor with %T data", q.DType, q.Data)}
+	}
+	channels, inner, err := p.channels(q.Shape)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]float32, len(src))
+	for i, v := range src {
+		c := i / inner % channels
+		data[i] = p.Scale[c] * float32(int(v)-int(p.ZeroPoint[c]))
+	}
+
+	shape := append(Shape(nil), q.Shape...)
+	return &Tensor{Data: data, Shape: shape, DType: DTypeF32, Device: q.Device}, nil
+}
+
+// QuantizedEntries returns the NPZ entries storing a quantized tensor under name:
+// the int8 tensor itself and its companion parameter entries. The result can be
+// merged into the map passed to WriteNPZ.
+func QuantizedEntries(name string, q *Tensor, p *QuantParams) (map[string]*Tensor, error) {
+	if _, _, err := p.channels(q.Shape); err != nil {
+		return nil, err
+	}
+	paramShape := Shape{len(p.Scale)}
+	if p.Axis == -1 {
+		paramShape = Shape{}
+	}
+	return map[string]*Tensor{
+		name:                        q,
+		name + quantScaleSuffix:     {Data: p.Scale, Shape: paramShape, DType: DTypeF32, Device: "cpu"},
+		name + quantZeroPointSuffix: {Data: p.ZeroPoint, Shape: paramShape, DType: DTypeI8, Device: "cpu"},
+		name + quantAxisSuffix:      {Data: []int64{int64(p.Axis)}, Shape: Shape{}, DType: DTypeI64, Device: "cpu"},
+	}, nil
+}
+
+// QuantParamsFrom extracts the quantization parameters of the tensor stored under
+// name from a set of tensors read from an NPZ archive.
+func QuantParamsFrom(tensors map[string]*Tensor, name string) (*QuantParams, error) {
+	get := func(suffix string, dtype DType) (*Tensor, error) {
+		t, ok := tensors[name+suffix]
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("no quantization entry %s%s", name, suffix)}
+		}
+		if t.DType != dtype {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("quantization entry %s%s is %s, expected %s", name, suffix, t.DType, dtype)}
+		}
+		return t, nil
+	}
+
+	scale, err := get(quantScaleSuffix, DTypeF32)
+	if err != nil {
+		return nil, err
+	}
+	zeroPoint, err := get(quantZeroPointSuffix, DTypeI8)
+	if err != nil {
+		return nil, err
+	}
+	axis, err := get(quantAxisSuffix, DTypeI64)
+	if err != nil {
+		return nil, err
+	}
+	scales, ok1 := scale.Data.([]float32)
+	zeroPoints, ok2 := zeroPoint.Data.([]int8)
+	axes, ok3 := axis.Data.([]int64)
+	if !ok1 || !ok2 || !ok3 || len(axes) != 1 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid quantization entries for %s", name)}
+	}
+	return &QuantParams{Scale: scales, ZeroPoint: zeroPoints, Axis: int(axes[0])}, nil
+}
+
+// GetQuantized reads an int8 tensor and its quantization parameters from the archive.
+func (n *NpzTensors) GetQuantized(name string) (*Tensor, *QuantParams, error) {
+	tensors := make(map[string]*Tensor, 4)
+	for _, entry := range []string{name, name + quantScaleSuffix, name + quantZeroPointSuffix, name + quantAxisSuffix} {
+		if _, ok := n.files[entry]; !ok {
+			return nil, nil, ErrorNpy{Msg: fmt.Sprintf("no array for %s in %s", entry, n.path), Err: ErrEntryNotFound}
+		}
+		t, err := n.Get(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		tensors[entry] = t
+	}
+	p, err := QuantParamsFrom(tensors, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	q := tensors[name]
+	if _, _, err := p.channels(q.Shape); err != nil {
+		return nil, nil, err
+	}
+	return q, p, nil
+}
@@ -0,0 +1,141 @@
+package gonpy
+
+import (
+	"io"
+	"os"
+	"reflect"
+)
+
+// castChunkElems is the number of elements decoded and converted at a time by
+// the ReadNPYAs family, bounding the size of the intermediate buffer.
+const castChunkElems = 1 << 16
+
+// ReadNPYAs reads a tensor from an NPY file, converting it to dtype as it is
+// streamed so that no full-size copy in the stored dtype is materialized.
+// Conversion follows the rules of Tensor.Cast, including the WithOverflow option.
+func ReadNPYAs(path string, dtype DType, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	o.track(info.Size())
+	t, err := readTensorAs(f, info.Size(), dtype, o)
+	if err != nil {
+		return nil, locate(err, path, "")
+	}
+	return t, nil
+}
+
+// GetAs loads a named tensor from the NPZ file, converting it to dtype as it is
+// decoded, in the manner of ReadNPYAs.
+func (n *NpzTensors) GetAs(name string, dtype DType, opts ...Option) (*Tensor, error) {
+	file, err := n.file(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := n.packing[name]; ok || n.opts.verifyChecksums {
+		// Packed storage bytes cannot be converted piecewise, and checksums
+		// are verified by Get
+		t, err := n.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		return t.Cast(dtype, opts...)
+	}
+
+	o := *n.opts
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.track(int64(file.UncompressedSize64))
+
+	rc, err := file.Open()
+	if err != nil {
+		return nil, locate(err, n.path, name)
+	}
+	defer rc.Close()
+
+	t, err := readTensorAs(rc, int64(file.UncompressedSize64), dtype, &o)
+	if err != nil {
+		return nil, locate(err, n.path, name)
+	}
+	return t, nil
+}
+
+// readTensorAs reads an NPY header and decodes the data in chunks, casting each
+// chunk to dtype. A non-negative size is checked as in readTensor.
+func readTensorAs(r io.Reader, size int64, dtype DType, o *options) (*Tensor, error) {
+	cr := &countingReader{r: o.tracker.reader(o.cancelableReader(r))}
+	t, err := decodeTensorAs(cr, size, dtype, o)
+	if err != nil {
+		return nil, readFailure(err, cr.n)
+	}
+	return t, nil
+}
+
+// decodeTensorAs does the work of readTensorAs, reading through r so that the
+// position of a failure is known.
+func decodeTensorAs(r *countingReader, size int64, dtype DType, o *options) (*Tensor, error) {
+	headerStr, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		return nil, err
+	}
+	if size >= 0 {
+		if err := checkPayload(header, size-r.n); err != nil {
+			return nil, err
+		}
+	}
+	if header.FortranOrder {
+		return nil, ErrFortranOrder
+	}
+	if err := o.checkSize(dtype, header.Shape); err != nil {
+		return nil, err
+	}
+	if header.Descr == dtype {
+		data, err := readData(header.Shape, header.Descr, r)
+		if err != nil {
+			return nil, err
+		}
+		return &Tensor{Data: data, Shape: header.Shape, DType: dtype, Device: "cpu"}, nil
+	}
+
+	total := header.Shape.ElemCount()
+	dst, err := makeData(dtype, total)
+	if err != nil {
+		return nil, err
+	}
+	out := reflect.ValueOf(dst)
+	for off := 0; off < total; off += castChunkElems {
+		n := min(castChunkElems, total-off)
+		src, err := readData(Shape{n}, header.Descr, r)
+		if err != nil {
+			return nil, err
+		}
+		chunk := &Tensor{Data: src, Shape: Shape{n}, DType: header.Descr}
+		converted, err := chunk.Cast(dtype, WithOverflow(o.overflow))
+		if err != nil {
+			return nil, err
+		}
+		reflect.Copy(out.Slice(off, off+n), reflect.ValueOf(converted.Data))
+	}
+
+	return &Tensor{
+		Data:   dst,
+		Shape:  header.Shape,
+		DType:  dtype,
+		Device: "cpu",
+	}, nil
+}
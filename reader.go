@@ -0,0 +1,73 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+)
+
+// NpyReader reads the data of an NPY stream in blocks of rows of the leading
+// dimension, so that arrays larger than memory can be processed piecewise.
+type NpyReader struct {
+	r      io.Reader
+	header *Header
+	rows   int // rows not yet read
+	opts   *options
+}
+
+// NewNpyReader reads the NPY header from r and returns a reader for its data.
+// The limit set with WithMaxBytes applies to each block returned by Next
+// rather than to the whole array, which may be larger than memory.
+func NewNpyReader(r io.Reader, opts ...Option) (*NpyReader, error) {
+	o := newOptions(opts)
+	r = o.cancelableReader(r)
+	header, err := readSeekableHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	rows := 1
+	if len(header.Shape) > 0 {
+		rows = header.Shape[0]
+	}
+	return &NpyReader{r: r, header: header, rows: rows, opts: o}, nil
+}
+
+// Shape returns the shape of the whole array.
+func (nr *NpyReader) Shape() Shape {
+	return nr.header.Shape
+}
+
+// DType returns the dtype of the array.
+func (nr *NpyReader) DType() DType {
+	return nr.header.Descr
+}
+
+// Next returns the next block of up to chunkRows rows, or io.EOF once all rows
+// have been read. A scalar array is returned whole as a single block.
+func (nr *NpyReader) Next(chunkRows int) (*Tensor, error) {
+	if chunkRows <= 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid chunk size %d", chunkRows)}
+	}
+	if nr.rows == 0 {
+		return nil, io.EOF
+	}
+	n := min(chunkRows, nr.rows)
+
+	shape := Shape{}
+	if len(nr.header.Shape) > 0 {
+		shape = append(Shape{n}, nr.header.Shape[1:]...)
+	}
+	if err := nr.opts.checkSize(nr.header.Descr, shape); err != nil {
+		return nil, err
+	}
+	data, err := readData(shape, nr.header.Descr, nr.r)
+	if err != nil {
+		return nil, err
+	}
+	nr.rows -= n
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  nr.header.Descr,
+		Device: "cpu",
+	}, nil
+}
@@ -0,0 +1,45 @@
+package gonpy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestNpyReaderBlocks(t *testing.T) {
+	in := &Tensor{Data: []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, Shape: Shape{5, 2}, DType: DTypeI32, Device: "cpu"}
+	var b bytes.Buffer
+	if err := in.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	nr, err := NewNpyReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int32
+	for {
+		block, err := nr.Next(2)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, block.Data.([]int32)...)
+	}
+	if len(got) != 10 || got[9] != 10 {
+		t.Errorf("read %v", got)
+	}
+}
+
+func TestNpyReaderBudget(t *testing.T) {
+	header := "{'descr': '<f8', 'fortran_order': False, 'shape': (1099511627776,), }"
+	nr, err := NewNpyReader(bytes.NewReader(npyBytes(header, nil)), WithMaxBytes(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nr.Next(1 << 30); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+}
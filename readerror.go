@@ -0,0 +1,63 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadError locates a failure to read an array: the file, the entry within an
+// NPZ archive, and how far into the array's NPY content reading had got. Use
+// errors.As to obtain it; errors.Is still matches the underlying error.
+type ReadError struct {
+	Path   string // File or archive path, if known
+	Entry  string // Array name within an NPZ archive, if any
+	Offset int64  // Bytes of NPY content consumed when the failure occurred
+	Err    error
+}
+
+func (e *ReadError) Error() string {
+	var b strings.Builder
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	if e.Entry != "" {
+		b.WriteString("entry " + e.Entry + ": ")
+	}
+	fmt.Fprintf(&b, "offset %d: %v", e.Offset, e.Err)
+	return b.String()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// readFailure wraps an error from reading NPY content in a *ReadError recording
+// the n bytes consumed. An io.EOF before anything was read is returned as is, so
+// that the end of a stream of arrays can be detected; one after that means the
+// content was cut short.
+func readFailure(err error, n int64) error {
+	if err == io.EOF {
+		if n == 0 {
+			return err
+		}
+		err = io.ErrUnexpectedEOF
+	}
+	return &ReadError{Offset: n, Err: err}
+}
+
+// locate records the path and entry of a failed read in err, wrapping it in a
+// *ReadError if it is not one already. Fields that are already set are kept.
+func locate(err error, path, entry string) error {
+	re, ok := err.(*ReadError)
+	if !ok {
+		return &ReadError{Path: path, Entry: entry, Err: err}
+	}
+	if re.Path == "" {
+		re.Path = path
+	}
+	if re.Entry == "" {
+		re.Entry = entry
+	}
+	return re
+}
@@ -0,0 +1,112 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// RecoverNPY reads the complete leading-dimension rows of a possibly truncated
+// NPY file, such as one left behind by a writer that was killed mid-way. The
+// returned tensor's shape reflects the number of rows actually present; an
+// intact file is returned in full. Trailing bytes of a partial row are ignored.
+func RecoverNPY(path string, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	headerStr, err := readHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		return nil, err
+	}
+	if header.FortranOrder {
+		return nil, ErrFortranOrder
+	}
+
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	shape := salvageableShape(header, info.Size()-offset)
+	if shape == nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("no complete data in %s", path)}
+	}
+	if err := o.checkSize(header.Descr, shape); err != nil {
+		return nil, err
+	}
+
+	data, err := readData(shape, header.Descr, f)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  header.Descr,
+		Device: "cpu",
+	}, nil
+}
+
+// RepairNPY recovers the salvageable prefix of a truncated NPY file as in
+// RecoverNPY and rewrites the file in place with a header matching the
+// recovered shape. The file is replaced atomically via a temporary file in
+// the same directory.
+func RepairNPY(path string, opts ...Option) (*Tensor, error) {
+	t, err := RecoverNPY(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := createTemp(path, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Write(tmp); err != nil {
+		tmp.discard()
+		return nil, err
+	}
+	if err := tmp.commit(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// salvageableShape returns the shape of the largest prefix of complete rows that
+// fits in avail payload bytes, or nil if not even the smallest unit is present.
+func salvageableShape(header *Header, avail int64) Shape {
+	size := int64(header.Descr.Size())
+	if size == 0 {
+		return nil
+	}
+	if len(header.Shape) == 0 {
+		if avail < size {
+			return nil
+		}
+		return header.Shape
+	}
+
+	rowBytes := size * int64(header.Shape[1:].ElemCount())
+	rows := int64(header.Shape[0])
+	if rowBytes > 0 && avail/rowBytes < rows {
+		rows = avail / rowBytes
+	}
+
+	shape := make(Shape, len(header.Shape))
+	copy(shape, header.Shape)
+	shape[0] = int(rows)
+	return shape
+}
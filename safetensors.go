@@ -0,0 +1,335 @@
+package gonpy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// A safetensors file is an 8-byte little-endian header length, a JSON header
+// mapping tensor names to their dtype, shape, and byte range, and the tensor
+// data. Byte ranges are relative to the end of the header and must tile the
+// data without gaps. An optional "__metadata__" key holds string pairs.
+
+// safetensorsMetadataKey is the header key holding free-form metadata.
+const safetensorsMetadataKey = "__metadata__"
+
+// maxSafetensorsHeader bounds the JSON header, as in the reference implementation.
+const maxSafetensorsHeader = 100 << 20
+
+// safetensorsDTypes maps dtypes to their safetensors names.
+var safetensorsDTypes = map[DType]string{
+	DTypeBool:   "BOOL",
+	DTypeU8:     "U8",
+	DTypeI8:     "I8",
+	DTypeF16:    "F16",
+	DTypeBF16:   "BF16",
+	DTypeI32:    "I32",
+	DTypeU32:    "U32",
+	DTypeF32:    "F32",
+	DTypeF64:    "F64",
+	DTypeI64:    "I64",
+	DTypeU64:    "U64",
+	DTypeC64:    "C64",
+	DTypeF8E4M3: "F8_E4M3",
+	DTypeF8E5M2: "F8_E5M2",
+}
+
+// safetensorsEntry describes one tensor in a safetensors header.
+type safetensorsEntry struct {
+	DType       string   `json:"dtype"`
+	Shape       Shape    `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// WithSafetensorsMetadata sets the string metadata written to the header of
+// safetensors files.
+func WithSafetensorsMetadata(m map[string]string) Option {
+	return func(o *options) {
+		o.safetensorsMetadata = m
+	}
+}
+
+// SafetensorsFile provides lazy access to the tensors of a safetensors file.
+// The file is kept open, occupying one slot of the file limiter, until Close
+// is called. Tensors may be loaded concurrently.
+type SafetensorsFile struct {
+	mu        sync.RWMutex // Held for reading by loads and for writing by Close
+	f         *os.File
+	path      string
+	dataStart int64
+	names     []string // In data order
+	entries   map[string]safetensorsEntry
+	dtypes    map[string]DType
+	metadata  map[string]string
+	opts      *options
+}
+
+// OpenSafetensors opens a safetensors file and reads its header.
+func OpenSafetensors(path string, opts ...Option) (*SafetensorsFile, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	f, err := os.Open(path)
+	if err != nil {
+		o.limiter.Release()
+		return nil, err
+	}
+	s := &SafetensorsFile{f: f, path: path, opts: o}
+	if err := s.readHeader(); err != nil {
+		f.Close()
+		o.limiter.Release()
+		return nil, locate(err, path, "")
+	}
+	return s, nil
+}
+
+// readHeader parses and validates the header.
+func (s *SafetensorsFile) readHeader() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	var lenBuf [8]byte
+	if _, err := io.ReadFull(s.f, lenBuf[:]); err != nil {
+		return err
+	}
+	headerLen := binary.LittleEndian.Uint64(lenBuf[:])
+	if headerLen > maxSafetensorsHeader || int64(headerLen) > info.Size()-8 {
+		return ErrorNpy{Msg: fmt.Sprintf("invalid safetensors header length %d", headerLen)}
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(s.f, header); err != nil {
+		return err
+	}
+	s.dataStart = 8 + int64(headerLen)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(header, &raw); err != nil {
+		return ErrorNpy{Msg: fmt.Sprintf("invalid safetensors header: %v", err)}
+	}
+	s.entries = make(map[string]safetensorsEntry, len(raw))
+	s.dtypes = make(map[string]DType, len(raw))
+	for name, msg := range raw {
+		if name == safetensorsMetadataKey {
+			if err := json.Unmarshal(msg, &s.metadata); err != nil {
+				return ErrorNpy{Msg: fmt.Sprintf("invalid safetensors metadata: %v", err)}
+			}
+			continue
+		}
+		var e safetensorsEntry
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return ErrorNpy{Msg: fmt.Sprintf("invalid safetensors entry %s: %v", name, err)}
+		}
+		dtype, err := safetensorsDType(e.DType)
+		if err != nil {
+			return err
+		}
+		if _, err := e.Shape.CheckedElemCount(); err != nil {
+			return err
+		}
+		size, ok := dataSize(dtype, e.Shape)
+		if !ok || e.DataOffsets[1]-e.DataOffsets[0] != size {
+			return ErrorNpy{Msg: fmt.Sprintf("tensor %s has %d bytes, %s shape %v needs %d", name, e.DataOffsets[1]-e.DataOffsets[0], dtype, e.Shape, size)}
+		}
+		s.entries[name] = e
+		s.dtypes[name] = dtype
+		s.names = append(s.names, name)
+	}
+
+	// The byte ranges must tile the data exactly. Ties, which only empty
+	// tensors can produce, are broken by end offset and then by name.
+	sort.Slice(s.names, func(i, j int) bool {
+		a, b := s.entries[s.names[i]].DataOffsets, s.entries[s.names[j]].DataOffsets
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		if a[1] != b[1] {
+			return a[1] < b[1]
+		}
+		return s.names[i] < s.names[j]
+	})
+	var end int64
+	for _, name := range s.names {
+		e := s.entries[name]
+		if e.DataOffsets[0] != end {
+			return ErrorNpy{Msg: fmt.Sprintf("tensor %s starts at byte %d, expected %d", name, e.DataOffsets[0], end)}
+		}
+		end = e.DataOffsets[1]
+	}
+	if end != info.Size()-s.dataStart {
+		return ErrorNpy{Msg: fmt.Sprintf("safetensors data is %d bytes, header describes %d", info.Size()-s.dataStart, end)}
+	}
+	return nil
+}
+
+// safetensorsDType returns the dtype of a safetensors dtype name.
+func safetensorsDType(name string) (DType, error) {
+	for dtype, n := range safetensorsDTypes {
+		if n == name {
+			return dtype, nil
+		}
+	}
+	return "", ErrorNpy{Msg: fmt.Sprintf("unsupported safetensors dtype %s", name), Err: ErrUnsupportedDType}
+}
+
+// Close closes the file, waiting for loads in progress. Tensors already
+// loaded remain valid.
+func (s *SafetensorsFile) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	s.opts.limiter.Release()
+	return err
+}
+
+// Names returns the names of the tensors in the order of their data.
+func (s *SafetensorsFile) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// Metadata returns the string metadata of the file's header, if any.
+func (s *SafetensorsFile) Metadata() map[string]string {
+	return s.metadata
+}
+
+// GetShapeAndDType returns the shape and dtype of a named tensor without loading data.
+func (s *SafetensorsFile) GetShapeAndDType(name string) (Shape, DType, error) {
+	e, ok := s.entries[name]
+	if !ok {
+		return nil, "", ErrorNpy{Msg: fmt.Sprintf("no tensor %s in %s", name, s.path), Err: ErrEntryNotFound}
+	}
+	return append(Shape{}, e.Shape...), s.dtypes[name], nil
+}
+
+// Get loads a named tensor.
+func (s *SafetensorsFile) Get(name string) (*Tensor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.f == nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%s is closed", s.path)}
+	}
+	shape, dtype, err := s.GetShapeAndDType(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.opts.checkSize(dtype, shape); err != nil {
+		return nil, locate(err, s.path, name)
+	}
+	e := s.entries[name]
+	o := s.opts
+	if o.progress != nil {
+		tracked := *o
+		tracked.track(e.DataOffsets[1] - e.DataOffsets[0])
+		o = &tracked
+	}
+	section := io.NewSectionReader(s.f, s.dataStart+e.DataOffsets[0], e.DataOffsets[1]-e.DataOffsets[0])
+	cr := &countingReader{r: o.tracker.reader(o.cancelableReader(section))}
+	data, err := readPayload(shape, dtype, cr, o)
+	if err != nil {
+		return nil, locate(readFailure(err, cr.n), s.path, name)
+	}
+	return &Tensor{Data: data, Shape: shape, DType: dtype, Device: o.deviceName()}, nil
+}
+
+// ReadSafetensors reads all tensors of a safetensors file, in the order of their data.
+func ReadSafetensors(path string, opts ...Option) ([]NamedTensor, error) {
+	s, err := OpenSafetensors(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	result := make([]NamedTensor, 0, len(s.names))
+	for _, name := range s.names {
+		t, err := s.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, NamedTensor{Name: name, Tensor: t})
+	}
+	return result, nil
+}
+
+// WriteSafetensors writes named tensors to a safetensors file, with their data
+// in sorted name order. Tensors of dtypes safetensors cannot represent, such
+// as strings and C128, are rejected.
+func WriteSafetensors(path string, tensors map[string]*Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	sorted := sortedTensors(tensors)
+	header := make(map[string]interface{}, len(sorted)+1)
+	if len(o.safetensorsMetadata) > 0 {
+		header[safetensorsMetadataKey] = o.safetensorsMetadata
+	}
+	var offset int64
+	for i, nt := range sorted {
+		if nt.Name == safetensorsMetadataKey {
+			return ErrorNpy{Msg: fmt.Sprintf("tensor name %s is reserved", nt.Name)}
+		}
+		t, err := nt.Tensor.storedAs(o)
+		if err != nil {
+			return err
+		}
+		name, ok := safetensorsDTypes[t.DType]
+		if !ok {
+			return ErrorNpy{Msg: fmt.Sprintf("safetensors has no dtype for %s tensor %s", t.DType, nt.Name), Err: ErrUnsupportedDType}
+		}
+		if err := t.checkData(); err != nil {
+			return err
+		}
+		size := tensorBytes(t)
+		header[nt.Name] = safetensorsEntry{DType: name, Shape: t.Shape, DataOffsets: [2]int64{offset, offset + size}}
+		offset += size
+		sorted[i].Tensor = t
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	for len(headerJSON)%8 != 0 {
+		headerJSON = append(headerJSON, ' ') // Keep the data 8-byte aligned
+	}
+
+	f, err := createOutput(path, o)
+	if err != nil {
+		return err
+	}
+	if err := writeSafetensors(f, headerJSON, sorted, offset, o); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
+}
+
+// writeSafetensors writes the header and data of a safetensors file to f.
+func writeSafetensors(f *outputFile, header []byte, tensors []NamedTensor, size int64, o *options) error {
+	o.track(size)
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	if err := binary.Write(bw, binary.LittleEndian, uint64(len(header))); err != nil {
+		return err
+	}
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	w := o.tracker.writer(o.cancelableWriter(bw))
+	for _, nt := range tensors {
+		var err error
+		if o.zeroCopy {
+			err = writeDataZeroCopy(w, nt.Tensor.DType, nt.Tensor.Data)
+		} else {
+			err = writeData(w, nt.Tensor.DType, nt.Tensor.Data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
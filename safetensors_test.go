@@ -0,0 +1,112 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeSafetensorsRaw writes a safetensors file with the given JSON header and data.
+func writeSafetensorsRaw(t *testing.T, path, header string, data []byte) {
+	t.Helper()
+	b := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	b = append(append(b, header...), data...)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSafetensorsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.safetensors")
+	err := WriteSafetensors(path, map[string]*Tensor{
+		"w": {Data: []float32{1, 2, 3, 4}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"},
+		"b": {Data: []int64{5}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"},
+	}, WithSafetensorsMetadata(map[string]string{"format": "pt"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenSafetensors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := s.Metadata()["format"]; got != "pt" {
+		t.Errorf("metadata format = %q", got)
+	}
+	w, err := s.Get("w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Data.([]float32); !w.Shape.Equal(Shape{2, 2}) || got[3] != 4 {
+		t.Errorf("w = %v %v", w.Shape, got)
+	}
+}
+
+func TestSafetensorsEmptyTensorOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.safetensors")
+	header := `{"c":{"dtype":"U8","shape":[0],"data_offsets":[0,0]},` +
+		`"x":{"dtype":"U8","shape":[2],"data_offsets":[0,2]},` +
+		`"a":{"dtype":"U8","shape":[0],"data_offsets":[0,0]},` +
+		`"b":{"dtype":"U8","shape":[0],"data_offsets":[2,2]}}`
+	writeSafetensorsRaw(t, path, header, []byte{1, 2})
+	for i := 0; i < 20; i++ {
+		s, err := OpenSafetensors(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		if got := s.Names(); len(got) != 4 || got[0] != "a" || got[1] != "c" || got[2] != "x" || got[3] != "b" {
+			t.Fatalf("names = %v, want [a c x b]", got)
+		}
+	}
+}
+
+func TestSafetensorsGetDuringClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.safetensors")
+	err := WriteSafetensors(path, map[string]*Tensor{
+		"x": {Data: make([]float64, 1024), Shape: Shape{1024}, DType: DTypeF64, Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenSafetensors(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Loads either succeed or report the file closed
+			if x, err := s.Get("x"); err == nil && len(x.Data.([]float64)) != 1024 {
+				t.Errorf("short load of %d elements", len(x.Data.([]float64)))
+			}
+		}()
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+	wg.Wait()
+}
+
+func TestSafetensorsHostileHeaders(t *testing.T) {
+	tests := map[string]string{
+		"gap":      `{"x":{"dtype":"U8","shape":[1],"data_offsets":[1,2]}}`,
+		"overlap":  `{"x":{"dtype":"U8","shape":[2],"data_offsets":[0,2]},"y":{"dtype":"U8","shape":[1],"data_offsets":[1,2]}}`,
+		"size":     `{"x":{"dtype":"F32","shape":[1],"data_offsets":[0,2]}}`,
+		"negative": `{"x":{"dtype":"U8","shape":[-2],"data_offsets":[2,0]}}`,
+		"past end": `{"x":{"dtype":"U8","shape":[4],"data_offsets":[0,4]}}`,
+		"dtype":    `{"x":{"dtype":"Q4","shape":[2],"data_offsets":[0,2]}}`,
+	}
+	for name, header := range tests {
+		path := filepath.Join(t.TempDir(), "bad.safetensors")
+		writeSafetensorsRaw(t, path, header, []byte{1, 2})
+		if s, err := OpenSafetensors(path); err == nil {
+			s.Close()
+			t.Errorf("%s: opened without error", name)
+		}
+	}
+}
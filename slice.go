@@ -0,0 +1,133 @@
+package gonpy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadNPYSlice reads rows [start, end) of the leading dimension of an NPY file,
+// seeking past the rows before start so that only the requested rows are read.
+func ReadNPYSlice(path string, start, end int, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	header, err := readSeekableHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Shape) == 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot slice rows of a scalar in %s", path)}
+	}
+	if start < 0 || end < start || end > header.Shape[0] {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("rows [%d, %d) out of range for shape %v", start, end, header.Shape)}
+	}
+
+	rowBytes := int64(header.Descr.Size() * header.Shape[1:].ElemCount())
+	if _, err := f.Seek(int64(start)*rowBytes, io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	shape := append(Shape{end - start}, header.Shape[1:]...)
+	if err := o.checkSize(header.Descr, shape); err != nil {
+		return nil, err
+	}
+	data, err := readData(shape, header.Descr, f)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  header.Descr,
+		Device: "cpu",
+	}, nil
+}
+
+// readSeekableHeader reads and parses an NPY header, rejecting layouts whose
+// elements cannot be located by offset: fortran order and dtypes without a
+// fixed item size.
+func readSeekableHeader(r io.Reader) (*Header, error) {
+	headerStr, err := readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		return nil, err
+	}
+	if header.FortranOrder {
+		return nil, ErrFortranOrder
+	}
+	if header.Descr.Size() == 0 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported dtype %s", header.Descr), Err: ErrUnsupportedDType}
+	}
+	return header, nil
+}
+
+// ReadNPYColumns reads the given columns of a 2D NPY file in a single buffered
+// pass, holding only one row of the full matrix in memory at a time. The result
+// has shape (rows, len(cols)), with columns in the order given.
+func ReadNPYColumns(path string, cols []int, opts ...Option) (*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 1<<20)
+	header, err := readSeekableHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if len(header.Shape) != 2 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot extract columns from shape %v, expected 2 dimensions", header.Shape)}
+	}
+	rows, width := header.Shape[0], header.Shape[1]
+	for _, c := range cols {
+		if c < 0 || c >= width {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("column %d out of range for shape %v", c, header.Shape)}
+		}
+	}
+	if err := o.checkSize(header.Descr, Shape{rows, len(cols)}); err != nil {
+		return nil, err
+	}
+	if err := o.checkSize(header.Descr, header.Shape[1:]); err != nil {
+		return nil, err
+	}
+
+	size := header.Descr.Size()
+	row := make([]byte, width*size)
+	out := make([]byte, 0, rows*len(cols)*size)
+	for i := 0; i < rows; i++ {
+		if _, err := io.ReadFull(br, row); err != nil {
+			return nil, err
+		}
+		for _, c := range cols {
+			out = append(out, row[c*size:(c+1)*size]...)
+		}
+	}
+
+	shape := Shape{rows, len(cols)}
+	data, err := readData(shape, header.Descr, bytes.NewReader(out))
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  header.Descr,
+		Device: "cpu",
+	}, nil
+}
@@ -0,0 +1,196 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// A LevelDB table, as TensorFlow writes for checkpoint indexes, is a sequence
+// of blocks followed by a 48-byte footer holding the handles (offset and size
+// varints) of the metaindex and index blocks and a magic number. The index
+// block maps keys to the handles of the data blocks holding them. Each block
+// is followed by a compression type byte and the masked CRC-32C of the block
+// and type.
+
+// tableMagic ends the footer of every table.
+const tableMagic = 0xdb4775248b80fb57
+
+// tableFooterSize is the size of a table footer.
+const tableFooterSize = 48
+
+// tableEntry is one key and value of a table.
+type tableEntry struct {
+	key   string
+	value []byte
+}
+
+// readTable returns the entries of the table held in b, in key order.
+func readTable(b []byte) ([]tableEntry, error) {
+	if len(b) < tableFooterSize || binary.LittleEndian.Uint64(b[len(b)-8:]) != tableMagic {
+		return nil, ErrorNpy{Msg: "not a table", Err: ErrBadMagic}
+	}
+	footer := b[len(b)-tableFooterSize:]
+	_, n := binary.Uvarint(footer) // Metaindex offset
+	if n <= 0 {
+		return nil, ErrorNpy{Msg: "corrupt table footer"}
+	}
+	_, m := binary.Uvarint(footer[n:]) // Metaindex size
+	if m <= 0 {
+		return nil, ErrorNpy{Msg: "corrupt table footer"}
+	}
+	index, err := readTableBlock(b, footer[n+m:])
+	if err != nil {
+		return nil, err
+	}
+	handles, err := blockEntries(index)
+	if err != nil {
+		return nil, err
+	}
+	var entries []tableEntry
+	for _, h := range handles {
+		block, err := readTableBlock(b, h.value)
+		if err != nil {
+			return nil, err
+		}
+		more, err := blockEntries(block)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, more...)
+	}
+	return entries, nil
+}
+
+// readTableBlock returns the contents of the block whose handle starts handle,
+// checking its checksum and decompressing it.
+func readTableBlock(b []byte, handle []byte) ([]byte, error) {
+	off, n := binary.Uvarint(handle)
+	if n <= 0 {
+		return nil, ErrorNpy{Msg: "corrupt table block handle"}
+	}
+	size, m := binary.Uvarint(handle[n:])
+	if m <= 0 || off > uint64(len(b)) || uint64(len(b))-off < 5 || size > uint64(len(b))-off-5 {
+		return nil, ErrorNpy{Msg: "corrupt table block handle"}
+	}
+	block := b[off : off+size]
+	trailer := b[off+size : off+size+5]
+	crc := crc32.Checksum(block, castagnoli)
+	crc = crc32.Update(crc, castagnoli, trailer[:1])
+	if maskCRC(crc) != binary.LittleEndian.Uint32(trailer[1:]) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("table block at %d", off), Err: ErrChecksumMismatch}
+	}
+	switch trailer[0] {
+	case 0:
+		return block, nil
+	case 1:
+		return snappyDecode(block)
+	}
+	return nil, ErrorNpy{Msg: fmt.Sprintf("table block compression %d is not supported", trailer[0])}
+}
+
+// blockEntries decodes the entries of a block. Each entry holds the number of
+// bytes its key shares with the previous key, the lengths of the rest of the
+// key and of the value as varints, then the rest of the key and the value.
+// The block ends with the offsets of restart points, which are not needed to
+// read it in order.
+func blockEntries(block []byte) ([]tableEntry, error) {
+	if len(block) < 4 {
+		return nil, ErrorNpy{Msg: "corrupt table block"}
+	}
+	restarts := int(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	if restarts > (len(block)-4)/4 {
+		return nil, ErrorNpy{Msg: "corrupt table block"}
+	}
+	data := block[:len(block)-4-4*restarts]
+	var entries []tableEntry
+	var key []byte
+	for len(data) > 0 {
+		var lens [3]uint64
+		for i := range lens {
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, ErrorNpy{Msg: "corrupt table block entry"}
+			}
+			lens[i] = v
+			data = data[n:]
+		}
+		shared, unshared, size := lens[0], lens[1], lens[2]
+		if shared > uint64(len(key)) || unshared > uint64(len(data)) || size > uint64(len(data))-unshared {
+			return nil, ErrorNpy{Msg: "corrupt table block entry"}
+		}
+		key = append(key[:shared], data[:unshared]...)
+		entries = append(entries, tableEntry{key: string(key), value: data[unshared : unshared+size]})
+		data = data[unshared+size:]
+	}
+	return entries, nil
+}
+
+// snappyDecode decompresses a block in the Snappy format: the uncompressed
+// length as a varint, then literals and back-references.
+func snappyDecode(src []byte) ([]byte, error) {
+	n, k := binary.Uvarint(src)
+	if k <= 0 || n > uint64(len(src))*255 {
+		return nil, ErrorNpy{Msg: "corrupt snappy block"}
+	}
+	src = src[k:]
+	dst := make([]byte, 0, n)
+	for len(src) > 0 {
+		tag := src[0]
+		src = src[1:]
+		var length, offset int
+		switch tag & 3 {
+		case 0: // Literal
+			length = int(tag >> 2)
+			if length >= 60 {
+				extra := length - 59
+				if len(src) < extra {
+					return nil, ErrorNpy{Msg: "corrupt snappy block"}
+				}
+				length = 0
+				for i := 0; i < extra; i++ {
+					length |= int(src[i]) << (8 * i)
+				}
+				src = src[extra:]
+			}
+			length++
+			if length > len(src) || uint64(len(dst)+length) > n {
+				return nil, ErrorNpy{Msg: "corrupt snappy block"}
+			}
+			dst = append(dst, src[:length]...)
+			src = src[length:]
+			continue
+		case 1:
+			if len(src) < 1 {
+				return nil, ErrorNpy{Msg: "corrupt snappy block"}
+			}
+			length = 4 + int(tag>>2&7)
+			offset = int(tag>>5)<<8 | int(src[0])
+			src = src[1:]
+		case 2:
+			if len(src) < 2 {
+				return nil, ErrorNpy{Msg: "corrupt snappy block"}
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src))
+			src = src[2:]
+		case 3:
+			if len(src) < 4 {
+				return nil, ErrorNpy{Msg: "corrupt snappy block"}
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src))
+			src = src[4:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, ErrorNpy{Msg: "corrupt snappy block"}
+		}
+		for i := 0; i < length; i++ { // Copies may overlap their source
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)) != n {
+		return nil, ErrorNpy{Msg: "corrupt snappy block"}
+	}
+	return dst, nil
+}
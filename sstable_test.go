@@ -0,0 +1,62 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+)
+
+// appendBlock appends a block holding entries, without key sharing, and its
+// trailer to b, returning the new table bytes and the block's handle.
+func appendBlock(b []byte, entries []tableEntry) ([]byte, []byte) {
+	var block []byte
+	for _, e := range entries {
+		block = binary.AppendUvarint(block, 0)
+		block = binary.AppendUvarint(block, uint64(len(e.key)))
+		block = binary.AppendUvarint(block, uint64(len(e.value)))
+		block = append(block, e.key...)
+		block = append(block, e.value...)
+	}
+	block = binary.LittleEndian.AppendUint32(block, 0) // No restart points
+	handle := binary.AppendUvarint(nil, uint64(len(b)))
+	handle = binary.AppendUvarint(handle, uint64(len(block)))
+	crc := crc32.Update(crc32.Checksum(block, castagnoli), castagnoli, []byte{0})
+	b = append(append(b, block...), 0)
+	return binary.LittleEndian.AppendUint32(b, maskCRC(crc)), handle
+}
+
+// testTable returns a table holding entries in one data block.
+func testTable(entries []tableEntry) []byte {
+	b, data := appendBlock(nil, entries)
+	b, meta := appendBlock(b, nil)
+	b, index := appendBlock(b, []tableEntry{{key: entries[len(entries)-1].key, value: data}})
+	footer := append(append([]byte(nil), meta...), index...)
+	footer = append(footer, make([]byte, tableFooterSize-8-len(footer))...)
+	footer = binary.LittleEndian.AppendUint64(footer, tableMagic)
+	return append(b, footer...)
+}
+
+func TestReadTable(t *testing.T) {
+	b := testTable([]tableEntry{{key: "a", value: []byte("1")}, {key: "b", value: []byte("22")}})
+	entries, err := readTable(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].key != "b" || string(entries[1].value) != "22" {
+		t.Errorf("entries = %v", entries)
+	}
+}
+
+func TestHostileTableHandles(t *testing.T) {
+	b := testTable([]tableEntry{{key: "a", value: []byte("1")}})
+	handles := map[string][]byte{
+		"wrapping size":   binary.AppendUvarint(binary.AppendUvarint(nil, 0), 1<<64-3),
+		"offset at end":   binary.AppendUvarint(binary.AppendUvarint(nil, uint64(len(b)-2)), 0),
+		"offset past end": binary.AppendUvarint(binary.AppendUvarint(nil, uint64(len(b)+1)), 0),
+	}
+	for name, h := range handles {
+		if _, err := readTableBlock(b, h); err == nil {
+			t.Errorf("%s: handle accepted", name)
+		}
+	}
+}
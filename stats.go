@@ -0,0 +1,120 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats summarizes the values of a tensor, or of one lane of it along an axis.
+// NaN values are counted but otherwise ignored. When no other values remain,
+// Min, Max, Mean, and Std are NaN.
+type Stats struct {
+	Count    int // Values summarized, not counting NaN
+	NaNCount int
+	Min      float64
+	Max      float64
+	Sum      float64
+	Mean     float64
+	Std      float64 // Population standard deviation, as numpy.std computes by default
+}
+
+// Stats summarizes all values of a real numeric tensor, including F16, BF16,
+// and packed tensors.
+func (t *Tensor) Stats() (*Stats, error) {
+	values, err := t.realValues()
+	if err != nil {
+		return nil, err
+	}
+	var acc statsAccumulator
+	for _, x := range values {
+		acc.add(x)
+	}
+	s := acc.stats()
+	return &s, nil
+}
+
+// StatsAlong summarizes a real numeric tensor along axis, which may be
+// negative to count from the last dimension. The result has one Stats for
+// each element of the tensor's shape with axis removed, in row-major order.
+func (t *Tensor) StatsAlong(axis int) ([]Stats, error) {
+	a, err := normalizeAxis(axis, len(t.Shape))
+	if err != nil {
+		return nil, err
+	}
+	values, err := t.realValues()
+	if err != nil {
+		return nil, err
+	}
+	outer := t.Shape[:a].ElemCount()
+	n := t.Shape[a]
+	inner := t.Shape[a+1:].ElemCount()
+
+	result := make([]Stats, outer*inner)
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			var acc statsAccumulator
+			for k := 0; k < n; k++ {
+				acc.add(values[(o*n+k)*inner+i])
+			}
+			result[o*inner+i] = acc.stats()
+		}
+	}
+	return result, nil
+}
+
+// realValues returns the values of a real numeric tensor as float64.
+func (t *Tensor) realValues() ([]float64, error) {
+	v, err := t.widen()
+	if err != nil {
+		return nil, err
+	}
+	if v.c != nil {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("cannot summarize complex dtype %s", t.DType)}
+	}
+	return toFloat64(v), nil
+}
+
+// statsAccumulator computes Stats in one pass, using Welford's method for the
+// mean and variance.
+type statsAccumulator struct {
+	count, nans int
+	min, max    float64
+	sum         float64
+	mean, m2    float64
+}
+
+// add includes x in the summary.
+func (a *statsAccumulator) add(x float64) {
+	if math.IsNaN(x) {
+		a.nans++
+		return
+	}
+	a.count++
+	if a.count == 1 || x < a.min {
+		a.min = x
+	}
+	if a.count == 1 || x > a.max {
+		a.max = x
+	}
+	a.sum += x
+	delta := x - a.mean
+	a.mean += delta / float64(a.count)
+	a.m2 += delta * (x - a.mean)
+}
+
+// stats returns the summary of the values added.
+func (a *statsAccumulator) stats() Stats {
+	if a.count == 0 {
+		nan := math.NaN()
+		return Stats{NaNCount: a.nans, Min: nan, Max: nan, Mean: nan, Std: nan}
+	}
+	return Stats{
+		Count:    a.count,
+		NaNCount: a.nans,
+		Min:      a.min,
+		Max:      a.max,
+		Sum:      a.sum,
+		Mean:     a.mean,
+		Std:      math.Sqrt(a.m2 / float64(a.count)),
+	}
+}
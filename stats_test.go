@@ -0,0 +1,122 @@
+package gonpy
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// statsClose reports whether the counts of a and b are equal and their
+// values equal up to rounding.
+func statsClose(a, b Stats) bool {
+	x := []float64{a.Min, a.Max, a.Sum, a.Mean, a.Std}
+	y := []float64{b.Min, b.Max, b.Sum, b.Mean, b.Std}
+	for i := range x {
+		if !isClose(x[i], y[i], 1e-12, 1e-12, true) {
+			return false
+		}
+	}
+	return a.Count == b.Count && a.NaNCount == b.NaNCount
+}
+
+func TestStats(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		t    *Tensor
+		want Stats
+	}{
+		{"f64", &Tensor{Data: []float64{2, 4, 4, 4, 5, 5, 7, 9}, Shape: Shape{2, 4}, DType: DTypeF64}, Stats{8, 0, 2, 9, 40, 5, 2}},
+		{"i32", &Tensor{Data: []int32{-3, 3}, Shape: Shape{2}, DType: DTypeI32}, Stats{2, 0, -3, 3, 0, 0, 3}},
+		{"u64", &Tensor{Data: []uint64{1 << 40}, Shape: Shape{}, DType: DTypeU64}, Stats{1, 0, 1 << 40, 1 << 40, 1 << 40, 1 << 40, 0}},
+		{"bool", &Tensor{Data: []bool{true, false, true, true}, Shape: Shape{4}, DType: DTypeBool}, Stats{4, 0, 0, 1, 3, 0.75, math.Sqrt(0.1875)}},
+		{"f16", &Tensor{Data: []uint16{0x3c00, 0xc000}, Shape: Shape{2}, DType: DTypeF16}, Stats{2, 0, -2, 1, -1, -0.5, 1.5}},
+		{"bf16", &Tensor{Data: []uint16{0x4000, 0x4080}, Shape: Shape{2}, DType: DTypeBF16}, Stats{2, 0, 2, 4, 6, 3, 1}},
+		{"i4", &Tensor{Data: []byte{0x8f, 0x07}, Shape: Shape{3}, DType: DTypeI4}, Stats{3, 0, -8, 7, -2, -2.0 / 3, math.Sqrt(338.0 / 9)}},
+		{"NaNs ignored", &Tensor{Data: []float32{float32(nan), 1, float32(nan), 3}, Shape: Shape{4}, DType: DTypeF32}, Stats{2, 2, 1, 3, 4, 2, 1}},
+		{"all NaN", &Tensor{Data: []float64{nan}, Shape: Shape{1}, DType: DTypeF64}, Stats{0, 1, nan, nan, 0, nan, nan}},
+		{"empty", &Tensor{Data: []float64{}, Shape: Shape{0, 3}, DType: DTypeF64}, Stats{0, 0, nan, nan, 0, nan, nan}},
+	}
+	for _, tt := range tests {
+		s, err := tt.t.Stats()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !statsClose(*s, tt.want) {
+			t.Errorf("%s: Stats = %v, want %v", tt.name, *s, tt.want)
+		}
+	}
+}
+
+func TestStatsAlong(t *testing.T) {
+	x := &Tensor{Data: []float64{1, 2, 3, 4, 5, 6}, Shape: Shape{2, 3}, DType: DTypeF64}
+	columns := []Stats{{2, 0, 1, 4, 5, 2.5, 1.5}, {2, 0, 2, 5, 7, 3.5, 1.5}, {2, 0, 3, 6, 9, 4.5, 1.5}}
+	rows := []Stats{{3, 0, 1, 3, 6, 2, math.Sqrt(2.0 / 3)}, {3, 0, 4, 6, 15, 5, math.Sqrt(2.0 / 3)}}
+	tests := []struct {
+		axis int
+		want []Stats
+	}{
+		{0, columns},
+		{1, rows},
+		{-1, rows},
+		{-2, columns},
+	}
+	for _, tt := range tests {
+		s, err := x.StatsAlong(tt.axis)
+		if err != nil {
+			t.Errorf("axis %d: %v", tt.axis, err)
+			continue
+		}
+		if len(s) != len(tt.want) {
+			t.Errorf("axis %d: got %d lanes, want %d", tt.axis, len(s), len(tt.want))
+			continue
+		}
+		for i := range s {
+			if !statsClose(s[i], tt.want[i]) {
+				t.Errorf("axis %d: lane %d is %v, want %v", tt.axis, i, s[i], tt.want[i])
+			}
+		}
+	}
+
+	// A middle axis of a 3-dimensional tensor
+	y := &Tensor{Data: []int32{0, 1, 2, 3, 4, 5, 6, 7}, Shape: Shape{2, 2, 2}, DType: DTypeI32}
+	s, err := y.StatsAlong(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sums []float64
+	for _, l := range s {
+		sums = append(sums, l.Sum)
+	}
+	if fmt.Sprint(sums) != "[2 4 10 12]" {
+		t.Errorf("sums along axis 1 are %v", sums)
+	}
+}
+
+func TestStatsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		t    *Tensor
+		axis int
+	}{
+		{"complex", &Tensor{Data: []complex64{1}, Shape: Shape{1}, DType: DTypeC64}, 0},
+		{"strings", &Tensor{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1)}, 0},
+		{"short data", &Tensor{Data: []float32{1}, Shape: Shape{2}, DType: DTypeF32}, 0},
+		{"mistyped data", &Tensor{Data: []float64{1}, Shape: Shape{1}, DType: DTypeF32}, 0},
+	}
+	for _, tt := range tests {
+		if s, err := tt.t.Stats(); err == nil {
+			t.Errorf("%s: Stats = %v", tt.name, *s)
+		}
+		if s, err := tt.t.StatsAlong(tt.axis); err == nil {
+			t.Errorf("%s: StatsAlong = %v", tt.name, s)
+		}
+	}
+	x := &Tensor{Data: []float64{1, 2}, Shape: Shape{1, 2}, DType: DTypeF64}
+	for _, axis := range []int{2, -3} {
+		if _, err := x.StatsAlong(axis); err == nil {
+			t.Errorf("axis %d of a 2-dimensional tensor accepted", axis)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package gonpy
+
+import "io"
+
+// ReadAllNPYStream reads consecutive NPY records from r until it is exhausted,
+// as produced by calling numpy.save repeatedly on one open file. Each record is
+// consumed exactly, so ReadNPYFrom may also be called in a loop to process a
+// stream one array at a time.
+func ReadAllNPYStream(r io.Reader, opts ...Option) ([]*Tensor, error) {
+	o := newOptions(opts)
+	o.track(-1)
+	cr := &countingReader{r: r}
+	var tensors []*Tensor
+	for {
+		start := cr.n
+		t, err := readTensor(cr, -1, o)
+		if err == io.EOF {
+			if cr.n == start {
+				return tensors, nil
+			}
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		tensors = append(tensors, t)
+	}
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteNPYStream writes tensors to w as consecutive NPY records, which numpy
+// reads back with repeated calls to numpy.load on the same open file.
+func WriteNPYStream(w io.Writer, tensors []*Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	if o.progress != nil {
+		var total int64
+		for _, t := range tensors {
+			size, err := t.encodedSize(o)
+			if err != nil {
+				return err
+			}
+			total += size
+		}
+		o.track(total)
+	}
+	for _, t := range tensors {
+		if err := t.write(w, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
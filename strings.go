@@ -0,0 +1,107 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// BytesDType returns the dtype of fixed-width byte strings holding up to width
+// bytes each, corresponding to numpy's 'S<width>' descr. Tensors of this dtype
+// carry their data as []string.
+func BytesDType(width int) DType {
+	return DType("S" + strconv.Itoa(width))
+}
+
+// UnicodeDType returns the dtype of fixed-width unicode strings holding up to
+// width code points each, corresponding to numpy's 'U<width>' descr. Entries are
+// stored as UTF-32 and carried as []string.
+func UnicodeDType(width int) DType {
+	return DType("U" + strconv.Itoa(width))
+}
+
+// maxItemSize bounds the bytes per element of string and structured dtypes.
+// numpy stores itemsize as a C int, so larger dtypes cannot come from numpy.
+const maxItemSize = math.MaxInt32
+
+// stringKind reports whether the dtype is a fixed-width string dtype, returning
+// its kind ('S' or 'U') and width. Widths whose item size would exceed
+// maxItemSize are rejected.
+func (d DType) stringKind() (byte, int, bool) {
+	if len(d) < 2 || (d[0] != 'S' && d[0] != 'U') {
+		return 0, 0, false
+	}
+	width, err := strconv.Atoi(string(d[1:]))
+	if err != nil || width <= 0 || strconv.Itoa(width) != string(d[1:]) {
+		return 0, 0, false
+	}
+	if width > maxItemSize || d[0] == 'U' && width > maxItemSize/4 {
+		return 0, 0, false
+	}
+	return d[0], width, true
+}
+
+// readStrings decodes n fixed-width string entries, trimming trailing NULs.
+func readStrings(r io.Reader, dtype DType, n int) ([]string, error) {
+	kind, width, _ := dtype.stringKind()
+	buf := make([]byte, dtype.Size())
+	data := make([]string, n)
+	for i := range data {
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if kind == 'S' {
+			end := len(buf)
+			for end > 0 && buf[end-1] == 0 {
+				end--
+			}
+			data[i] = string(buf[:end])
+			continue
+		}
+
+		runes := make([]rune, 0, width)
+		for j := 0; j < width; j++ {
+			runes = append(runes, rune(binary.LittleEndian.Uint32(buf[4*j:])))
+		}
+		end := len(runes)
+		for end > 0 && runes[end-1] == 0 {
+			end--
+		}
+		data[i] = string(runes[:end])
+	}
+	return data, nil
+}
+
+// writeStrings encodes string entries at the fixed width of dtype, padding with NULs.
+func writeStrings(w io.Writer, dtype DType, data []string) error {
+	kind, width, ok := dtype.stringKind()
+	if !ok {
+		return ErrorNpy{Msg: fmt.Sprintf("dtype %s cannot hold string data", dtype)}
+	}
+	buf := make([]byte, dtype.Size())
+	for i, s := range data {
+		clear(buf)
+		if kind == 'S' {
+			if len(s) > width {
+				return ErrorNpy{Msg: fmt.Sprintf("entry %d has %d bytes, exceeds width %d", i, len(s), width)}
+			}
+			copy(buf, s)
+		} else {
+			if utf8.RuneCountInString(s) > width {
+				return ErrorNpy{Msg: fmt.Sprintf("entry %d has %d code points, exceeds width %d", i, utf8.RuneCountInString(s), width)}
+			}
+			j := 0
+			for _, r := range s {
+				binary.LittleEndian.PutUint32(buf[4*j:], uint32(r))
+				j++
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// npyBytes returns an NPY version 1.0 stream with the given header dictionary
+// followed by data.
+func npyBytes(header string, data []byte) []byte {
+	pad := 64 - (10+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+	var b bytes.Buffer
+	b.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&b, binary.LittleEndian, uint16(len(header)))
+	b.WriteString(header)
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestStringsRoundTrip(t *testing.T) {
+	for _, dtype := range []DType{BytesDType(3), UnicodeDType(3)} {
+		in := &Tensor{Data: []string{"a", "bc", "def"}, Shape: Shape{3}, DType: dtype, Device: "cpu"}
+		var b bytes.Buffer
+		if err := in.Write(&b); err != nil {
+			t.Fatalf("%s: %v", dtype, err)
+		}
+		out, err := ReadNPYFrom(&b)
+		if err != nil {
+			t.Fatalf("%s: %v", dtype, err)
+		}
+		if got := out.Data.([]string); strings.Join(got, ",") != "a,bc,def" {
+			t.Errorf("%s: got %q", dtype, got)
+		}
+	}
+}
+
+func TestHostileItemSizes(t *testing.T) {
+	descrs := []string{
+		"'<U6917529027641081856'",
+		"'<U4611686018427387905'",
+		"'|S9000000000000000000'",
+		"'<U536870912'",
+		"[('a', '<U6917529027641081856')]",
+		"[('a', '<f8', (1073741824, 1073741824))]",
+		"[('a', '<U1000'), ('', '|V9223372036854775807')]",
+	}
+	for _, descr := range descrs {
+		header := "{'descr': " + descr + ", 'fortran_order': False, 'shape': (), }"
+		b := npyBytes(header, make([]byte, 16))
+		if _, err := ReadNPYFrom(bytes.NewReader(b)); err == nil {
+			t.Errorf("ReadNPYFrom accepted descr %s", descr)
+		}
+		if _, err := NewNpyReader(bytes.NewReader(b)); err == nil {
+			t.Errorf("NewNpyReader accepted descr %s", descr)
+		}
+	}
+}
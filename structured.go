@@ -0,0 +1,349 @@
+package gonpy
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field describes one named member of a structured dtype.
+type Field struct {
+	Name   string
+	DType  DType
+	Shape  Shape // Subarray shape of the field; nil for scalar fields
+	Offset int   // Byte offset of the field within a record
+}
+
+// Size returns the number of bytes the field occupies in a record, or 0 if
+// the dtype is unknown or the size would exceed the largest item size numpy
+// supports.
+func (f Field) Size() int {
+	size, ok := dataSize(f.DType, f.Shape)
+	if !ok || size > maxItemSize {
+		return 0
+	}
+	return int(size)
+}
+
+// StructuredDType describes the layout of a numpy record array, whose descr is
+// a list of (name, format[, shape]) tuples. Tensors with a structured dtype
+// carry their records as packed bytes in a []byte, and use the list literal
+// returned by StructuredDType.DType as their DType.
+type StructuredDType struct {
+	Fields   []Field
+	ItemSize int // Bytes per record, including any padding
+}
+
+// NewStructuredDType builds a packed structured dtype from the given fields.
+// Field offsets are assigned in order and any Offset values passed are ignored.
+func NewStructuredDType(fields ...Field) (*StructuredDType, error) {
+	sd := &StructuredDType{Fields: make([]Field, len(fields))}
+	for i, f := range fields {
+		f.Offset = sd.ItemSize
+		sd.Fields[i] = f
+		if err := sd.grow(f.Name, f.DType, f.Shape); err != nil {
+			return nil, err
+		}
+	}
+	if err := sd.validate(); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// ParseStructuredDType parses the list-form descr held by a structured DType.
+func ParseStructuredDType(d DType) (*StructuredDType, error) {
+	if !d.isStructured() {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("dtype %s is not structured", d)}
+	}
+	v, err := parsePyLiteral(string(d))
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("structured dtype %s is not a list", d)}
+	}
+	return structuredFromLiteral(list)
+}
+
+// DType returns the structured dtype as a DType holding its numpy descr literal.
+func (s *StructuredDType) DType() DType {
+	var parts []string
+	pos := 0
+	for _, f := range s.Fields {
+		if f.Offset > pos {
+			parts = append(parts, fmt.Sprintf("('', '|V%d')", f.Offset-pos))
+		}
+		descr, _ := descrString(f.DType) // validated on construction
+		part := fmt.Sprintf("('%s', '%s'", f.Name, descr)
+		if len(f.Shape) > 0 {
+			part += ", " + shapeLiteral(f.Shape)
+		}
+		parts = append(parts, part+")")
+		pos = f.Offset + f.Size()
+	}
+	if s.ItemSize > pos {
+		parts = append(parts, fmt.Sprintf("('', '|V%d')", s.ItemSize-pos))
+	}
+	return DType("[" + strings.Join(parts, ", ") + "]")
+}
+
+// Field returns the field with the given name.
+func (s *StructuredDType) Field(name string) (Field, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Names returns the field names in record order.
+func (s *StructuredDType) Names() []string {
+	names := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// validate checks field names, dtypes, and offsets for consistency.
+func (s *StructuredDType) validate() error {
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		if f.Name == "" || strings.ContainsAny(f.Name, `'\`) {
+			return ErrorNpy{Msg: fmt.Sprintf("invalid field name %q", f.Name)}
+		}
+		if seen[f.Name] {
+			return ErrorNpy{Msg: fmt.Sprintf("duplicate field name %s", f.Name)}
+		}
+		seen[f.Name] = true
+		if f.DType.isStructured() {
+			return ErrorNpy{Msg: fmt.Sprintf("nested structured field %s not supported", f.Name)}
+		}
+		if _, err := descrString(f.DType); err != nil {
+			return err
+		}
+		for _, dim := range f.Shape {
+			if dim < 0 {
+				return ErrorNpy{Msg: fmt.Sprintf("negative dimension in field %s", f.Name)}
+			}
+		}
+		if f.Offset < 0 || f.Offset+f.Size() > s.ItemSize {
+			return ErrorNpy{Msg: fmt.Sprintf("field %s exceeds record size", f.Name)}
+		}
+	}
+	return nil
+}
+
+// grow extends the record by a member of the given dtype and shape, failing
+// if the record would exceed maxItemSize.
+func (s *StructuredDType) grow(name string, dtype DType, shape Shape) error {
+	for _, dim := range shape {
+		if dim < 0 {
+			return ErrorNpy{Msg: fmt.Sprintf("negative dimension in field %s", name)}
+		}
+	}
+	size, ok := dataSize(dtype, shape)
+	if !ok || size > int64(maxItemSize-s.ItemSize) {
+		return ErrorNpy{Msg: fmt.Sprintf("field %s overflows the record size", name), Err: ErrTooLarge}
+	}
+	s.ItemSize += int(size)
+	return nil
+}
+
+// isStructured reports whether the dtype holds a list-form structured descr.
+func (d DType) isStructured() bool {
+	return strings.HasPrefix(string(d), "[")
+}
+
+// structuredFromLiteral builds a structured dtype from a parsed descr list.
+// Unnamed void fields, which numpy emits for padding, advance the offset only.
+func structuredFromLiteral(list []interface{}) (*StructuredDType, error) {
+	sd := &StructuredDType{}
+	for _, item := range list {
+		tuple, ok := item.(pyTuple)
+		if !ok || len(tuple) < 2 || len(tuple) > 3 {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("invalid structured descr field %v", item)}
+		}
+		name, ok := tuple[0].(string)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported field name %v", tuple[0])}
+		}
+		format, ok := tuple[1].(string)
+		if !ok {
+			return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported format for field %s", name)}
+		}
+
+		if name == "" && strings.HasPrefix(strings.TrimLeft(format, "|"), "V") {
+			n, err := strconv.Atoi(strings.TrimLeft(format, "|V"))
+			if err != nil || n < 0 {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("invalid padding descr %s", format)}
+			}
+			if err := sd.grow("padding", DTypeU8, Shape{n}); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		dtype, err := parseDescr(format)
+		if err != nil {
+			return nil, err
+		}
+		var shape Shape
+		if len(tuple) == 3 {
+			if shape, err = shapeFromLiteral(tuple[2]); err != nil {
+				return nil, err
+			}
+		}
+		f := Field{Name: name, DType: dtype, Shape: shape, Offset: sd.ItemSize}
+		sd.Fields = append(sd.Fields, f)
+		if err := sd.grow(name, dtype, shape); err != nil {
+			return nil, err
+		}
+	}
+	if err := sd.validate(); err != nil {
+		return nil, err
+	}
+	return sd, nil
+}
+
+// shapeFromLiteral converts a parsed tuple (or bare integer) of dimensions to a Shape.
+func shapeFromLiteral(v interface{}) (Shape, error) {
+	switch v := v.(type) {
+	case int64:
+		return shapeFromLiteral(pyTuple{v})
+	case pyTuple:
+		shape := make(Shape, len(v))
+		for i, d := range v {
+			dim, ok := d.(int64)
+			if !ok || dim < 0 || int64(int(dim)) != dim {
+				return nil, ErrorNpy{Msg: fmt.Sprintf("invalid dimension %v", d)}
+			}
+			shape[i] = int(dim)
+		}
+		return shape, nil
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid shape %v", v)}
+	}
+}
+
+// shapeLiteral formats a shape as a Python tuple.
+func shapeLiteral(shape Shape) string {
+	if len(shape) == 0 {
+		return "()"
+	}
+	parts := make([]string, len(shape))
+	for i, dim := range shape {
+		parts[i] = strconv.Itoa(dim)
+	}
+	return "(" + strings.Join(parts, ",") + ",)"
+}
+
+// Records provides per-field access to the data of a structured tensor.
+type Records struct {
+	DType *StructuredDType
+	Shape Shape
+	Data  []byte // Packed records of DType.ItemSize bytes each
+}
+
+// NewRecords allocates zeroed records of the given structured dtype and shape.
+func NewRecords(dt *StructuredDType, shape Shape) *Records {
+	return &Records{
+		DType: dt,
+		Shape: shape,
+		Data:  make([]byte, shape.ElemCount()*dt.ItemSize),
+	}
+}
+
+// Records returns a view of a structured tensor's data. The returned Records
+// shares its Data with the tensor.
+func (t *Tensor) Records() (*Records, error) {
+	sd, err := ParseStructuredDType(t.DType)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := t.Data.([]byte)
+	if !ok {
+		return nil, ErrorNpy{Msg: "structured tensor data must be []byte"}
+	}
+	if len(data) != t.Shape.ElemCount()*sd.ItemSize {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("structured data has %d bytes, expected %d", len(data), t.Shape.ElemCount()*sd.ItemSize)}
+	}
+	return &Records{DType: sd, Shape: t.Shape, Data: data}, nil
+}
+
+// Tensor returns a tensor sharing the records' data, suitable for writing.
+func (r *Records) Tensor() *Tensor {
+	return &Tensor{
+		Data:   r.Data,
+		Shape:  r.Shape,
+		DType:  r.DType.DType(),
+		Device: "cpu",
+	}
+}
+
+// Len returns the number of records.
+func (r *Records) Len() int {
+	return r.Shape.ElemCount()
+}
+
+// Column copies out the named field of every record as a tensor whose shape is
+// the records' shape followed by the field's subarray shape.
+func (r *Records) Column(name string) (*Tensor, error) {
+	f, ok := r.DType.Field(name)
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("no field %s", name)}
+	}
+
+	size := f.Size()
+	buf := make([]byte, 0, r.Len()*size)
+	for i := 0; i < r.Len(); i++ {
+		start := i*r.DType.ItemSize + f.Offset
+		buf = append(buf, r.Data[start:start+size]...)
+	}
+
+	shape := append(append(Shape{}, r.Shape...), f.Shape...)
+	data, err := readData(shape, f.DType, bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{
+		Data:   data,
+		Shape:  shape,
+		DType:  f.DType,
+		Device: "cpu",
+	}, nil
+}
+
+// SetColumn stores the values of col into the named field of every record.
+// The column must have the field's dtype and the shape returned by Column;
+// otherwise a *MismatchError is returned.
+func (r *Records) SetColumn(name string, col *Tensor) error {
+	f, ok := r.DType.Field(name)
+	if !ok {
+		return ErrorNpy{Msg: fmt.Sprintf("no field %s", name)}
+	}
+	shape := append(append(Shape{}, r.Shape...), f.Shape...)
+	if err := checkTensor(name, col, f.DType, shape); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeData(&buf, col.DType, col.Data); err != nil {
+		return err
+	}
+	size := f.Size()
+	if buf.Len() != r.Len()*size {
+		return ErrorNpy{Msg: fmt.Sprintf("column has %d bytes, field %s needs %d", buf.Len(), name, r.Len()*size)}
+	}
+
+	src := buf.Bytes()
+	for i := 0; i < r.Len(); i++ {
+		start := i*r.DType.ItemSize + f.Offset
+		copy(r.Data[start:start+size], src[i*size:])
+	}
+	return nil
+}
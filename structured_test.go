@@ -0,0 +1,161 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseStructuredDType(t *testing.T) {
+	tests := []struct {
+		name     string
+		descr    DType
+		fields   string // Name, dtype, shape and offset of each field
+		itemSize int
+	}{
+		{"packed", "[('x', '<f4'), ('y', '<i8')]", "[{x f32 [] 0} {y i64 [] 4}]", 12},
+		{"subarray", "[('pos', '<f4', (3,)), ('id', '<u4')]", "[{pos f32 [3] 0} {id u32 [] 12}]", 16},
+		{"bare dimension", "[('v', '<f8', 2)]", "[{v f64 [2] 0}]", 16},
+		{"matrix field", "[('m', '<f4', (2, 3)), ('k', '|u1')]", "[{m f32 [2 3] 0} {k u8 [] 24}]", 25},
+		{"empty subarray", "[('e', '<f8', (0,)), ('k', '|b1')]", "[{e f64 [0] 0} {k bool [] 0}]", 1},
+		{"padding", "[('a', '|u1'), ('', '|V3'), ('b', '<i4'), ('', '|V4')]", "[{a u8 [] 0} {b i32 [] 4}]", 12},
+		{"leading padding", "[('', 'V8'), ('c', '<c8')]", "[{c c64 [] 8}]", 16},
+		{"strings", "[('name', '<U4'), ('tag', '|S2')]", "[{name U4 [] 0} {tag S2 [] 16}]", 18},
+		{"double quotes", `[("a", "<f8")]`, "[{a f64 [] 0}]", 8},
+	}
+	for _, tt := range tests {
+		sd, err := ParseStructuredDType(tt.descr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var fields []string
+		for _, f := range sd.Fields {
+			fields = append(fields, fmt.Sprintf("{%s %s %v %d}", f.Name, f.DType, []int(f.Shape), f.Offset))
+		}
+		if got := fmt.Sprint(fields); got != tt.fields || sd.ItemSize != tt.itemSize {
+			t.Errorf("%s: fields %s of %d bytes, want %s of %d", tt.name, got, sd.ItemSize, tt.fields, tt.itemSize)
+		}
+		if got := tt.descr.Size(); got != tt.itemSize {
+			t.Errorf("%s: Size = %d, want %d", tt.name, got, tt.itemSize)
+		}
+
+		// The descr written back keeps the layout, padding included
+		again, err := ParseStructuredDType(sd.DType())
+		if err != nil {
+			t.Errorf("%s: reparsing %s: %v", tt.name, sd.DType(), err)
+		} else if fmt.Sprint(again) != fmt.Sprint(sd) {
+			t.Errorf("%s: %s parsed as %v, want %v", tt.name, sd.DType(), again, sd)
+		}
+	}
+}
+
+func TestParseStructuredDTypeInvalid(t *testing.T) {
+	tests := []struct {
+		descr    DType
+		tooLarge bool // Fails with ErrTooLarge
+	}{
+		{"<f4", false},
+		{"[", false},
+		{"[('a', '<f4')", false},
+		{"[('a', '<f4'))]", false},
+		{"['a']", false},
+		{"[1, 2]", false},
+		{"[('a',)]", false},
+		{"[('a', '<f4', (2,), 1)]", false},
+		{"[(1, '<f4')]", false},
+		{"[('a', 5)]", false},
+		{"[('a', '<q9')]", false},
+		{"[('', '<f4')]", false},
+		{"[('a', '<f4'), ('a', '<i4')]", false},
+		{"[('a', '<f4', (-1,))]", false},
+		{"[('a', '<f4', (2.5,))]", false},
+		{"[('a', '<f4', 'x')]", false},
+		{"[('', '|Vx')]", false},
+		{"[('', '|V-2')]", false},
+		{"[('outer', [('a', '<i4')])]", false}, // Nested records are not supported
+		{"[('a', '<f8', (1099511627776, 1099511627776))]", true},
+		{"[('a', '<f8', (4611686018427387904,))]", true},
+		{"[('', '|V2147483647'), ('', '|V2147483647')]", true},
+	}
+	for _, tt := range tests {
+		sd, err := ParseStructuredDType(tt.descr)
+		if err == nil {
+			t.Errorf("%s: parsed as %v", tt.descr, sd)
+			continue
+		}
+		if errors.Is(err, ErrTooLarge) != tt.tooLarge {
+			t.Errorf("%s: got %v", tt.descr, err)
+		}
+		if size := tt.descr.Size(); size != 0 {
+			t.Errorf("%s: Size = %d", tt.descr, size)
+		}
+	}
+}
+
+func TestNewStructuredDType(t *testing.T) {
+	sd, err := NewStructuredDType(
+		Field{Name: "a", DType: DTypeU8, Offset: 9},
+		Field{Name: "b", DType: DTypeF64, Shape: Shape{2}},
+		Field{Name: "c", DType: DTypeBool},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DType("[('a', '|u1'), ('b', '<f8', (2,)), ('c', '|b1')]"); sd.DType() != want || sd.ItemSize != 18 {
+		t.Errorf("DType = %s of %d bytes, want %s", sd.DType(), sd.ItemSize, want)
+	}
+	if f, ok := sd.Field("c"); !ok || f.Offset != 17 {
+		t.Errorf("field c = %v, %v", f, ok)
+	}
+	if fmt.Sprint(sd.Names()) != "[a b c]" {
+		t.Errorf("Names = %v", sd.Names())
+	}
+
+	invalid := map[string][]Field{
+		"duplicate name": {{Name: "a", DType: DTypeU8}, {Name: "a", DType: DTypeI8}},
+		"empty name":     {{Name: "", DType: DTypeU8}},
+		"quoted name":    {{Name: "a'b", DType: DTypeU8}},
+		"nested":         {{Name: "a", DType: sd.DType()}},
+		"unknown dtype":  {{Name: "a", DType: "<q9"}},
+		"negative shape": {{Name: "a", DType: DTypeU8, Shape: Shape{-1}}},
+	}
+	for name, fields := range invalid {
+		if sd, err := NewStructuredDType(fields...); err == nil {
+			t.Errorf("%s: built %s", name, sd.DType())
+		}
+	}
+}
+
+func TestRecordsPadding(t *testing.T) {
+	// Records of numpy's aligned layout for a uint8 and an int32
+	sd, err := ParseStructuredDType("[('a', '|u1'), ('', '|V3'), ('b', '<i4')]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 2*sd.ItemSize)
+	for i, v := range []int32{-5, 70000} {
+		data[i*8] = byte(i + 1)
+		data[i*8+1] = 0xee // Padding, never read
+		binary.LittleEndian.PutUint32(data[i*8+4:], uint32(v))
+	}
+	r, err := (&Tensor{Data: data, Shape: Shape{2}, DType: sd.DType()}).Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := r.Column("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := r.Column("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(a.Data) != "[1 2]" || fmt.Sprint(b.Data) != "[-5 70000]" {
+		t.Errorf("columns a %v and b %v", a.Data, b.Data)
+	}
+	if _, err := r.Column("padding"); err == nil {
+		t.Error("padding read as a field")
+	}
+}
@@ -0,0 +1,98 @@
+package gonpy
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// TensorLike is a destination for decoded data, implemented by the tensor
+// types of other frameworks so that readers can fill their memory directly
+// rather than through an intermediate Tensor. AcquireBuffer is called once
+// the dtype and shape of the stored tensor are known. It returns the slice to
+// decode into, which must have the Go type and length of the Data of a Tensor
+// of that dtype and shape: []float32 for F32, []uint16 for F16 and BF16, and
+// []byte holding packed values or raw records for packed and structured
+// dtypes. An error, such as for a dtype the framework does not support, ends
+// the read.
+type TensorLike interface {
+	AcquireBuffer(dtype DType, shape Shape) (interface{}, error)
+}
+
+// ReadNPYTo decodes an NPY file into the buffer that dst acquires for it.
+// Files in fortran order and dtypes without a fixed item size are rejected.
+func ReadNPYTo(path string, dst TensorLike, opts ...Option) error {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return readTensorTo(o.cancelableReader(f), path, dst, o)
+}
+
+// GetTo decodes a named tensor from the NPZ file into the buffer that dst
+// acquires for it, as ReadNPYTo does.
+func (n *NpzTensors) GetTo(name string, dst TensorLike) error {
+	file, err := n.file(name)
+	if err != nil {
+		return err
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	r, verify := n.opts.verifyingReader(name, rc)
+	if err := n.readTo(r, name, dst); err != nil {
+		return err
+	}
+	return verify()
+}
+
+// readTo reads the entry for name from r into dst, restoring packed storage.
+func (n *NpzTensors) readTo(r io.Reader, name string, dst TensorLike) error {
+	info, ok := n.packing[name]
+	if !ok {
+		return readTensorTo(r, name, dst, n.opts)
+	}
+	if err := n.opts.checkSize(info.DType, info.Shape); err != nil {
+		return err
+	}
+	buf, err := dst.AcquireBuffer(info.DType, info.Shape)
+	if err != nil {
+		return err
+	}
+	if _, err := readSeekableHeader(r); err != nil {
+		return err
+	}
+	data, ok := buf.([]byte)
+	if !ok || len(data) != (info.Shape.ElemCount()+1)/2 {
+		return ErrorNpy{Msg: fmt.Sprintf("%s destination data cannot hold %d packed values", name, info.Shape.ElemCount())}
+	}
+	_, err = io.ReadFull(r, data)
+	return err
+}
+
+// readTensorTo reads an NPY stream into a buffer acquired from dst.
+func readTensorTo(r io.Reader, name string, dst TensorLike, o *options) error {
+	header, err := readSeekableHeader(r)
+	if err != nil {
+		return err
+	}
+	if err := o.checkSize(header.Descr, header.Shape); err != nil {
+		return err
+	}
+	buf, err := dst.AcquireBuffer(header.Descr, header.Shape)
+	if err != nil {
+		return err
+	}
+	if err := checkBuffer(name, header.Descr, header.Shape, buf); err != nil {
+		return err
+	}
+	return decodeInto(r, header.Descr, buf)
+}
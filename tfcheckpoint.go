@@ -0,0 +1,259 @@
+package gonpy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// A TensorFlow checkpoint in the TensorBundle format is an index file,
+// <prefix>.index, and data files, <prefix>.data-00000-of-00001 and so on. The
+// index is a LevelDB table mapping "" to a BundleHeaderProto and each tensor
+// name to a BundleEntryProto, which locates the tensor's little-endian
+// elements in a data file.
+
+// tfDTypes maps TensorFlow DataType values to dtypes. DT_INT16 and DT_UINT16
+// are widened to 32 bits, since there is no 16-bit integer dtype.
+var tfDTypes = map[uint64]DType{
+	1:  DTypeF32,
+	2:  DTypeF64,
+	3:  DTypeI32,
+	4:  DTypeU8,
+	5:  DTypeI32, // DT_INT16
+	6:  DTypeI8,
+	8:  DTypeC64,
+	9:  DTypeI64,
+	10: DTypeBool,
+	14: DTypeBF16,
+	17: DTypeU32, // DT_UINT16
+	18: DTypeC128,
+	19: DTypeF16,
+	22: DTypeU32,
+	23: DTypeU64,
+}
+
+// tfBundleEntry is a BundleEntryProto.
+type tfBundleEntry struct {
+	dtype  uint64
+	shape  Shape
+	shard  int
+	offset int64
+	size   int64
+	crc    uint32
+	sliced bool
+}
+
+// ReadTFCheckpoint reads the variables of a TensorFlow checkpoint written by
+// tf.train.Checkpoint or a SavedModel, given its prefix such as
+// "ckpt/model-1000" or "saved_model/variables/variables", or the path of its
+// .index file. Variables are keyed by their checkpoint names, such as
+// "layer/kernel/.ATTRIBUTES/VARIABLE_VALUE". Numeric and boolean variables are
+// read; strings, such as the object graph, and partitioned variables are
+// skipped.
+func ReadTFCheckpoint(prefix string, opts ...Option) (map[string]*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	prefix = strings.TrimSuffix(prefix, ".index")
+	index, err := os.ReadFile(prefix + ".index")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readTable(index)
+	if err != nil {
+		return nil, locate(err, prefix+".index", "")
+	}
+	if len(entries) == 0 || entries[0].key != "" {
+		return nil, locate(ErrorNpy{Msg: "checkpoint index has no header", Err: ErrBadMagic}, prefix+".index", "")
+	}
+	shards, err := parseTFBundleHeader(entries[0].value)
+	if err != nil {
+		return nil, locate(err, prefix+".index", "")
+	}
+
+	files := make([]*os.File, shards)
+	defer func() {
+		for _, f := range files {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
+	tensors := make(map[string]*Tensor)
+	for _, entry := range entries[1:] {
+		e, err := parseTFBundleEntry(entry.value)
+		if err != nil {
+			return nil, locate(err, prefix+".index", entry.key)
+		}
+		dtype, ok := tfDTypes[e.dtype]
+		if !ok || e.sliced {
+			continue
+		}
+		if e.shard < 0 || e.shard >= shards {
+			return nil, locate(ErrorNpy{Msg: fmt.Sprintf("shard %d of %d", e.shard, shards)}, prefix+".index", entry.key)
+		}
+		if files[e.shard] == nil {
+			path := fmt.Sprintf("%s.data-%05d-of-%05d", prefix, e.shard, shards)
+			if files[e.shard], err = os.Open(path); err != nil {
+				return nil, err
+			}
+		}
+		t, err := readTFTensor(files[e.shard], e, dtype, o)
+		if err != nil {
+			return nil, locate(err, files[e.shard].Name(), entry.key)
+		}
+		tensors[entry.key] = t
+	}
+	return tensors, nil
+}
+
+// parseTFBundleHeader returns the number of shards of a BundleHeaderProto,
+// checking that the data is little-endian.
+func parseTFBundleHeader(b []byte) (int, error) {
+	d := protoReader{buf: b}
+	shards := 1
+	for !d.done() {
+		field, wire := d.tag()
+		switch {
+		case field == 1 && wire == 0:
+			shards = int(d.varint())
+		case field == 2 && wire == 0:
+			if d.varint() != 0 {
+				return 0, ErrorNpy{Msg: "big-endian checkpoint", Err: ErrUnsupportedVersion}
+			}
+		default:
+			d.skip(wire)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	if shards <= 0 || shards > 1<<20 {
+		return 0, ErrorNpy{Msg: fmt.Sprintf("checkpoint has %d shards", shards)}
+	}
+	return shards, nil
+}
+
+// parseTFBundleEntry decodes a BundleEntryProto.
+func parseTFBundleEntry(b []byte) (tfBundleEntry, error) {
+	var e tfBundleEntry
+	e.shape = Shape{}
+	d := protoReader{buf: b}
+	for !d.done() {
+		field, wire := d.tag()
+		switch {
+		case field == 1 && wire == 0:
+			e.dtype = d.varint()
+		case field == 2 && wire == 2:
+			shape := protoReader{buf: d.bytes()}
+			for !shape.done() {
+				field, wire := shape.tag()
+				if field != 2 || wire != 2 {
+					shape.skip(wire)
+					continue
+				}
+				dim := protoReader{buf: shape.bytes()}
+				size := int64(0)
+				for !dim.done() {
+					field, wire := dim.tag()
+					if field == 1 && wire == 0 {
+						size = int64(dim.varint())
+					} else {
+						dim.skip(wire)
+					}
+				}
+				if dim.err != nil {
+					return e, dim.err
+				}
+				if size < 0 || size > math.MaxInt32 {
+					return e, ErrorNpy{Msg: fmt.Sprintf("tensor dimension %d", size)}
+				}
+				e.shape = append(e.shape, int(size))
+			}
+			if shape.err != nil {
+				return e, shape.err
+			}
+		case field == 3 && wire == 0:
+			e.shard = int(d.varint())
+		case field == 4 && wire == 0:
+			e.offset = int64(d.varint())
+		case field == 5 && wire == 0:
+			e.size = int64(d.varint())
+		case field == 6 && wire == 5:
+			e.crc = binary.LittleEndian.Uint32(d.fixed(4))
+		case field == 7:
+			e.sliced = true
+			d.skip(wire)
+		default:
+			d.skip(wire)
+		}
+	}
+	return e, d.err
+}
+
+// readTFTensor reads the tensor of e from a data file, checking its size and
+// checksum.
+func readTFTensor(f *os.File, e tfBundleEntry, dtype DType, o *options) (*Tensor, error) {
+	if _, err := e.shape.CheckedElemCount(); err != nil {
+		return nil, err
+	}
+	if err := o.checkSize(dtype, e.shape); err != nil {
+		return nil, err
+	}
+	n := e.shape.ElemCount()
+	width := dtype.Size()
+	if e.dtype == 5 || e.dtype == 17 {
+		width = 2
+	}
+	if e.offset < 0 || e.size != int64(n)*int64(width) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("%d bytes at offset %d for %s tensor of shape %v", e.size, e.offset, dtype, e.shape)}
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if e.offset > info.Size() || e.size > info.Size()-e.offset {
+		return nil, ErrorNpy{Msg: "truncated checkpoint data"}
+	}
+	raw := make([]byte, e.size)
+	if _, err := io.ReadFull(o.cancelableReader(io.NewSectionReader(f, e.offset, e.size)), raw); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrorNpy{Msg: "truncated checkpoint data"}
+		}
+		return nil, err
+	}
+	if maskCRC(crc32.Checksum(raw, castagnoli)) != e.crc {
+		return nil, ErrChecksumMismatch
+	}
+
+	t, err := Zeros(dtype, e.shape)
+	if err != nil {
+		return nil, err
+	}
+	switch d := t.Data.(type) {
+	case []int32:
+		if e.dtype == 5 {
+			for i := range d {
+				d[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+			}
+			return t, nil
+		}
+	case []uint32:
+		if e.dtype == 17 {
+			for i := range d {
+				d[i] = uint32(binary.LittleEndian.Uint16(raw[2*i:]))
+			}
+			return t, nil
+		}
+	}
+	if t.Data, err = readData(e.shape, dtype, bytes.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
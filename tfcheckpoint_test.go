@@ -0,0 +1,105 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// bundleEntry returns a BundleEntryProto for a tensor of TensorFlow dtype
+// and dims stored at offset in shard 0.
+func bundleEntry(dtype uint64, dims []uint64, offset, size uint64, crc uint32) []byte {
+	var shape protoBuffer
+	for _, d := range dims {
+		var dim protoBuffer
+		dim.varint(1<<3 | 0)
+		dim.varint(d)
+		shape.bytesField(2, dim.buf)
+	}
+	var e protoBuffer
+	e.varint(1<<3 | 0)
+	e.varint(dtype)
+	e.bytesField(2, shape.buf)
+	e.varint(4<<3 | 0)
+	e.varint(offset)
+	e.varint(5<<3 | 0)
+	e.varint(size)
+	e.varint(6<<3 | 5)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, crc)
+	return e.buf
+}
+
+// writeCheckpoint writes a single-shard checkpoint with one tensor "w" and
+// returns its prefix.
+func writeCheckpoint(t *testing.T, header, entry, data []byte) string {
+	t.Helper()
+	prefix := filepath.Join(t.TempDir(), "model")
+	index := testTable([]tableEntry{{key: "", value: header}, {key: "w", value: entry}})
+	if err := os.WriteFile(prefix+".index", index, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(prefix+".data-00000-of-00001", data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return prefix
+}
+
+func TestReadTFCheckpoint(t *testing.T) {
+	data := append([]byte("pad!"), f32Bytes(1, 2, 3, 4, 5, 6)...)
+	crc := maskCRC(crc32.Checksum(data[4:], castagnoli))
+	prefix := writeCheckpoint(t, nil, bundleEntry(1, []uint64{2, 3}, 4, 24, crc), data)
+	tensors, err := ReadTFCheckpoint(prefix + ".index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := tensors["w"]
+	if got := w.Data.([]float32); !w.Shape.Equal(Shape{2, 3}) || got[5] != 6 {
+		t.Errorf("w = %v %v", w.Shape, got)
+	}
+
+	// DT_INT16 is widened to I32
+	data = []byte{0xff, 0xff, 2, 0}
+	crc = maskCRC(crc32.Checksum(data, castagnoli))
+	prefix = writeCheckpoint(t, nil, bundleEntry(5, []uint64{2}, 0, 4, crc), data)
+	if tensors, err = ReadTFCheckpoint(prefix); err != nil {
+		t.Fatal(err)
+	}
+	if got := tensors["w"].Data.([]int32); got[0] != -1 || got[1] != 2 {
+		t.Errorf("int16 w = %v", got)
+	}
+}
+
+func TestHostileTFCheckpoint(t *testing.T) {
+	data := f32Bytes(1, 2)
+	crc := maskCRC(crc32.Checksum(data, castagnoli))
+	bigEndian := []byte{2<<3 | 0, 1}
+	tests := map[string]struct {
+		header, entry []byte
+	}{
+		"checksum":     {nil, bundleEntry(1, []uint64{2}, 0, 8, crc+1)},
+		"size":         {nil, bundleEntry(1, []uint64{2}, 0, 4, crc)},
+		"offset":       {nil, bundleEntry(1, []uint64{2}, 4, 8, crc)},
+		"huge tensor":  {nil, bundleEntry(1, []uint64{1 << 30, 4}, 0, 1<<34, crc)},
+		"huge offset":  {nil, bundleEntry(1, []uint64{2}, 1<<63, 8, crc)},
+		"dimension":    {nil, bundleEntry(1, []uint64{1 << 40}, 0, 8, crc)},
+		"shard":        {nil, append(bundleEntry(1, []uint64{2}, 0, 8, crc), 3<<3|0, 5)},
+		"byte order":   {bigEndian, bundleEntry(1, []uint64{2}, 0, 8, crc)},
+		"shard count":  {[]byte{1<<3 | 0, 0}, bundleEntry(1, []uint64{2}, 0, 8, crc)},
+		"entry proto":  {nil, []byte{1<<3 | 2, 9}},
+		"header proto": {[]byte{0xff}, bundleEntry(1, []uint64{2}, 0, 8, crc)},
+	}
+	for name, tt := range tests {
+		prefix := writeCheckpoint(t, tt.header, tt.entry, data)
+		if _, err := ReadTFCheckpoint(prefix, WithMaxBytes(0)); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+
+	prefix := writeCheckpoint(t, nil, bundleEntry(1, []uint64{2}, 0, 8, crc), data)
+	if _, err := ReadTFCheckpoint(prefix, WithMaxBytes(4)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,237 @@
+package gonpy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+// A TFRecord file is a sequence of records, each framed by its little-endian
+// uint64 length, the masked CRC-32C of the length, the data, and the masked
+// CRC-32C of the data. WriteTFRecord writes one serialized tf.train.Example
+// per record, whose features map names to lists of bytes, floats, or int64s.
+
+// WithTFRecordRaw makes WriteTFRecord write every numeric feature as a
+// bytes_list holding the row's elements as one little-endian byte string, as
+// tf.io.decode_raw reads them, instead of as a float_list or int64_list.
+func WithTFRecordRaw() Option {
+	return func(o *options) {
+		o.tfrecordRaw = true
+	}
+}
+
+// WriteTFRecord writes tensors to a TFRecord file of tf.train.Example
+// records, one per row along the first dimension, so that TensorFlow input
+// pipelines can read them with tf.data.TFRecordDataset. All tensors must have
+// the same number of rows. Each example has one feature per tensor holding
+// its row flattened: floats as a float_list of float32 values, integers and
+// booleans as an int64_list, and strings as a bytes_list of one value per
+// element. Complex tensors need WithTFRecordRaw.
+func WriteTFRecord(path string, tensors map[string]*Tensor, opts ...Option) error {
+	o := newOptions(opts)
+	var features []NamedTensor
+	rows := -1
+	for _, nt := range sortedTensors(tensors) {
+		t, err := nt.Tensor.storedAs(o)
+		if err != nil {
+			return err
+		}
+		if err := t.checkData(); err != nil {
+			return err
+		}
+		if len(t.Shape) == 0 {
+			return ErrorNpy{Msg: fmt.Sprintf("TFRecord feature %s must have at least one dimension", nt.Name)}
+		}
+		if rows >= 0 && t.Shape[0] != rows {
+			return ErrorNpy{Msg: fmt.Sprintf("TFRecord feature %s has %d rows, others have %d", nt.Name, t.Shape[0], rows)}
+		}
+		rows = t.Shape[0]
+		if !tfrecordDType(t.DType, o.tfrecordRaw) {
+			return ErrorNpy{Msg: fmt.Sprintf("TFRecord has no feature type for %s tensor %s", t.DType, nt.Name), Err: ErrUnsupportedDType}
+		}
+		features = append(features, NamedTensor{Name: nt.Name, Tensor: t})
+	}
+
+	f, err := createOutput(path, o)
+	if err != nil {
+		return err
+	}
+	if err := writeTFRecord(f, features, max(rows, 0), o); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
+}
+
+// tfrecordDType reports whether WriteTFRecord can write tensors of dtype.
+func tfrecordDType(dtype DType, raw bool) bool {
+	if _, _, ok := dtype.stringKind(); ok {
+		return true
+	}
+	if raw {
+		return !dtype.isPacked() && !dtype.isStructured() && dtype != DTypeObject && dtype.Size() > 0
+	}
+	switch dtype {
+	case DTypeC64, DTypeC128, DTypeF8E4M3, DTypeF8E5M2:
+		return false
+	}
+	return csvDType(dtype)
+}
+
+// writeTFRecord writes one record per row of the features to f.
+func writeTFRecord(f *outputFile, features []NamedTensor, rows int, o *options) error {
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	w := o.cancelableWriter(bw)
+	var featureMap, feature, list protoBuffer
+	var raw bytes.Buffer
+	for r := 0; r < rows; r++ {
+		featureMap.reset()
+		for _, nt := range features {
+			t := nt.Tensor
+			n := t.Shape[1:].ElemCount()
+			feature.reset()
+			list.reset()
+			_, isString := t.Data.([]string)
+			switch {
+			case o.tfrecordRaw && !isString:
+				raw.Reset()
+				if err := writeData(&raw, t.DType, t.sliceRows(r, n)); err != nil {
+					return err
+				}
+				list.bytesField(1, raw.Bytes())
+				feature.bytesField(1, list.buf)
+			default:
+				t.appendTFRecordList(&list, &feature, r*n, n)
+			}
+			var entry protoBuffer
+			entry.bytesField(1, []byte(nt.Name))
+			entry.bytesField(2, feature.buf)
+			featureMap.bytesField(1, entry.buf)
+		}
+		var example protoBuffer
+		example.bytesField(1, featureMap.buf)
+		if err := writeTFRecordFrame(w, example.buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// sliceRows returns the data of the n elements of row r of t.
+func (t *Tensor) sliceRows(r, n int) interface{} {
+	switch d := t.Data.(type) {
+	case []bool:
+		return d[r*n : (r+1)*n]
+	case []int8:
+		return d[r*n : (r+1)*n]
+	case []uint8:
+		return d[r*n : (r+1)*n]
+	case []int32:
+		return d[r*n : (r+1)*n]
+	case []uint32:
+		return d[r*n : (r+1)*n]
+	case []int64:
+		return d[r*n : (r+1)*n]
+	case []uint64:
+		return d[r*n : (r+1)*n]
+	case []uint16:
+		return d[r*n : (r+1)*n]
+	case []float32:
+		return d[r*n : (r+1)*n]
+	case []float64:
+		return d[r*n : (r+1)*n]
+	case []complex64:
+		return d[r*n : (r+1)*n]
+	case []complex128:
+		return d[r*n : (r+1)*n]
+	}
+	return nil
+}
+
+// appendTFRecordList encodes elements start to start+n of t as the list of a
+// Feature: field 1 bytes_list, 2 float_list, or 3 int64_list.
+func (t *Tensor) appendTFRecordList(list, feature *protoBuffer, start, n int) {
+	switch data := t.Data.(type) {
+	case []string:
+		for _, s := range data[start : start+n] {
+			list.bytesField(1, []byte(s))
+		}
+		feature.bytesField(1, list.buf)
+		return
+	case []uint16, []float32, []float64:
+		var packed protoBuffer
+		for i := start; i < start+n; i++ {
+			var v float32
+			switch data := data.(type) {
+			case []uint16:
+				v = f16BitsToF32(data[i])
+				if t.DType == DTypeBF16 {
+					v = bf16BitsToF32(data[i])
+				}
+			case []float32:
+				v = data[i]
+			case []float64:
+				v = float32(data[i])
+			}
+			packed.buf = binary.LittleEndian.AppendUint32(packed.buf, math.Float32bits(v))
+		}
+		list.bytesField(1, packed.buf)
+		feature.bytesField(2, list.buf)
+		return
+	}
+	var packed protoBuffer
+	for i := start; i < start+n; i++ {
+		var v int64
+		switch data := t.Data.(type) {
+		case []bool:
+			if data[i] {
+				v = 1
+			}
+		case []int8:
+			v = int64(data[i])
+		case []uint8:
+			v = int64(data[i])
+		case []int32:
+			v = int64(data[i])
+		case []uint32:
+			v = int64(data[i])
+		case []int64:
+			v = data[i]
+		case []uint64:
+			v = int64(data[i])
+		}
+		packed.varint(uint64(v))
+	}
+	list.bytesField(1, packed.buf)
+	feature.bytesField(3, list.buf)
+}
+
+// writeTFRecordFrame writes data as one framed record.
+func writeTFRecordFrame(w io.Writer, data []byte) error {
+	var header [12]byte
+	binary.LittleEndian.PutUint64(header[:], uint64(len(data)))
+	binary.LittleEndian.PutUint32(header[8:], maskedCRC(header[:8]))
+	var footer [4]byte
+	binary.LittleEndian.PutUint32(footer[:], maskedCRC(data))
+	for _, b := range [][]byte{header[:], data, footer[:]} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maskedCRC returns the CRC-32C of b masked as TFRecord and LevelDB do.
+func maskedCRC(b []byte) uint32 {
+	return maskCRC(crc32.Checksum(b, castagnoli))
+}
+
+// maskCRC masks a CRC-32C, so that data holding its own checksum does not
+// checksum to a fixed value.
+func maskCRC(crc uint32) uint32 {
+	return (crc>>15 | crc<<17) + 0xa282ead8
+}
@@ -0,0 +1,137 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readTFRecords returns the records of a TFRecord file, checking their framing.
+func readTFRecords(t *testing.T, path string) [][]byte {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records [][]byte
+	for len(b) > 0 {
+		if len(b) < 12 || binary.LittleEndian.Uint32(b[8:]) != maskedCRC(b[:8]) {
+			t.Fatal("bad record length")
+		}
+		n := int(binary.LittleEndian.Uint64(b))
+		if len(b) < 16+n || binary.LittleEndian.Uint32(b[12+n:]) != maskedCRC(b[12:12+n]) {
+			t.Fatal("bad record data")
+		}
+		records = append(records, b[12:12+n])
+		b = b[16+n:]
+	}
+	return records
+}
+
+// exampleFeatures decodes a tf.train.Example into its features, formatting
+// each list as its kind followed by its values.
+func exampleFeatures(t *testing.T, example []byte) map[string]string {
+	t.Helper()
+	features := make(map[string]string)
+	d := protoReader{buf: example}
+	for !d.done() {
+		if field, _ := d.tag(); field != 1 {
+			t.Fatalf("unexpected Example field %d", field)
+		}
+		m := protoReader{buf: d.bytes()}
+		for !m.done() {
+			m.tag()
+			entry := protoReader{buf: m.bytes()}
+			entry.tag()
+			name := string(entry.bytes())
+			entry.tag()
+			feature := protoReader{buf: entry.bytes()}
+			kind, _ := feature.tag()
+			list := protoReader{buf: feature.bytes()}
+			var values []interface{}
+			for !list.done() {
+				list.tag()
+				switch kind {
+				case 1:
+					values = append(values, string(list.bytes()))
+				case 2:
+					packed := protoReader{buf: list.bytes()}
+					for !packed.done() {
+						values = append(values, math.Float32frombits(binary.LittleEndian.Uint32(packed.fixed(4))))
+					}
+				case 3:
+					packed := protoReader{buf: list.bytes()}
+					for !packed.done() {
+						values = append(values, int64(packed.varint()))
+					}
+				}
+			}
+			features[name] = fmt.Sprint(kind, values)
+		}
+	}
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+	return features
+}
+
+func TestWriteTFRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.tfrecord")
+	err := WriteTFRecord(path, map[string]*Tensor{
+		"x": {Data: []float32{1, 2, 3.5, 4}, Shape: Shape{2, 2}, DType: DTypeF32, Device: "cpu"},
+		"y": {Data: []int64{-1, 7}, Shape: Shape{2}, DType: DTypeI64, Device: "cpu"},
+		"s": {Data: []string{"a", "bc"}, Shape: Shape{2}, DType: UnicodeDType(2), Device: "cpu"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := readTFRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	got := exampleFeatures(t, records[1])
+	want := map[string]string{"x": "2 [3.5 4]", "y": "3 [7]", "s": "1 [bc]"}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("feature %s = %s, want %s", name, got[name], w)
+		}
+	}
+}
+
+func TestWriteTFRecordRaw(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t.tfrecord")
+	err := WriteTFRecord(path, map[string]*Tensor{
+		"z": {Data: []complex64{1 + 2i}, Shape: Shape{1}, DType: DTypeC64, Device: "cpu"},
+	}, WithTFRecordRaw())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprint(1, []interface{}{"\x00\x00\x80\x3f\x00\x00\x00\x40"})
+	if got := exampleFeatures(t, readTFRecords(t, path)[0])["z"]; got != want {
+		t.Errorf("raw feature = %q, want %q", got, want)
+	}
+}
+
+func TestWriteTFRecordInvalid(t *testing.T) {
+	tests := map[string]map[string]*Tensor{
+		"rows": {
+			"a": {Data: []int32{1, 2}, Shape: Shape{2}, DType: DTypeI32, Device: "cpu"},
+			"b": {Data: []int32{1}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"},
+		},
+		"scalar":  {"a": {Data: []int32{1}, Shape: Shape{}, DType: DTypeI32, Device: "cpu"}},
+		"complex": {"a": {Data: []complex64{1}, Shape: Shape{1}, DType: DTypeC64, Device: "cpu"}},
+		"short":   {"a": {Data: []int32{1}, Shape: Shape{2}, DType: DTypeI32, Device: "cpu"}},
+	}
+	for name, tensors := range tests {
+		path := filepath.Join(t.TempDir(), "t.tfrecord")
+		if err := WriteTFRecord(path, tensors); err == nil {
+			t.Errorf("%s: written without error", name)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s: failed write left a file", name)
+		}
+	}
+}
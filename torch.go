@@ -0,0 +1,285 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// torch.save writes a zip archive holding data.pkl, a pickle of the saved
+// object in which each tensor is rebuilt by torch._utils._rebuild_tensor_v2
+// from a storage, and one data/<key> entry per storage holding its raw
+// little-endian elements. Storages are referenced from the pickle by
+// persistent ids of the form ('storage', storage type, key, location, numel).
+
+// torchStorageTypes maps PyTorch storage classes to dtypes. ShortStorage is
+// widened to I32, since there is no 16-bit integer dtype.
+var torchStorageTypes = map[string]DType{
+	"FloatStorage":         DTypeF32,
+	"DoubleStorage":        DTypeF64,
+	"HalfStorage":          DTypeF16,
+	"BFloat16Storage":      DTypeBF16,
+	"ComplexFloatStorage":  DTypeC64,
+	"ComplexDoubleStorage": DTypeC128,
+	"LongStorage":          DTypeI64,
+	"IntStorage":           DTypeI32,
+	"ShortStorage":         DTypeI32,
+	"CharStorage":          DTypeI8,
+	"ByteStorage":          DTypeU8,
+	"BoolStorage":          DTypeBool,
+}
+
+// maxTorchDepth bounds the nesting of dicts searched for tensors, which also
+// stops the search on self-referencing dicts.
+const maxTorchDepth = 32
+
+// torchStorage is a storage of a PyTorch checkpoint, loaded on first use.
+type torchStorage struct {
+	class string
+	key   string
+	numel int
+	data  *Tensor // 1-D tensor of the storage's elements once loaded
+}
+
+// torchArchive resolves the storages of a checkpoint's zip archive.
+type torchArchive struct {
+	files    map[string]*zip.File
+	prefix   string // Directory holding data.pkl
+	storages map[string]*torchStorage
+	o        *options
+}
+
+// ReadTorchStateDict reads the tensors of a PyTorch checkpoint written by
+// torch.save in its default zip format, such as a model's state_dict. Tensors
+// inside nested dicts are named by their dot-separated key path, and values
+// other than tensors and dicts are ignored. Non-contiguous tensors are copied
+// into row-major order. Checkpoints in the legacy pre-1.6 format are not
+// supported.
+func ReadTorchStateDict(path string, opts ...Option) (map[string]*Tensor, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if errors.Is(err, zip.ErrFormat) {
+		return nil, locate(ErrorNpy{Msg: "not a zip-format torch checkpoint", Err: ErrBadMagic}, path, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	a := &torchArchive{files: make(map[string]*zip.File), storages: make(map[string]*torchStorage), o: o}
+	var pkl *zip.File
+	for _, f := range r.File {
+		a.files[f.Name] = f
+		if pkl == nil && (f.Name == "data.pkl" || strings.HasSuffix(f.Name, "/data.pkl")) {
+			pkl = f
+			a.prefix = strings.TrimSuffix(f.Name, "data.pkl")
+		}
+	}
+	if pkl == nil {
+		return nil, locate(ErrorNpy{Msg: "no data.pkl in torch checkpoint", Err: ErrEntryNotFound}, path, "")
+	}
+	if err := a.checkByteOrder(); err != nil {
+		return nil, locate(err, path, "")
+	}
+
+	rc, err := pkl.Open()
+	if err != nil {
+		return nil, err
+	}
+	u := &unpickler{r: rc, memo: make(map[int]interface{}), persistentLoad: a.persistentLoad}
+	v, err := u.run()
+	rc.Close()
+	if err != nil {
+		return nil, locate(err, path, pkl.Name)
+	}
+
+	tensors := make(map[string]*Tensor)
+	if err := a.collect(v, "", 0, tensors); err != nil {
+		return nil, locate(err, path, "")
+	}
+	return tensors, nil
+}
+
+// checkByteOrder rejects checkpoints saved on big-endian machines.
+func (a *torchArchive) checkByteOrder() error {
+	f, ok := a.files[a.prefix+"byteorder"]
+	if !ok {
+		return nil // Older versions only wrote little-endian checkpoints
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	order, err := io.ReadAll(io.LimitReader(rc, 16))
+	if err != nil {
+		return err
+	}
+	if string(order) != "little" {
+		return ErrorNpy{Msg: fmt.Sprintf("unsupported torch byte order %q", order)}
+	}
+	return nil
+}
+
+// persistentLoad resolves a storage reference in data.pkl.
+func (a *torchArchive) persistentLoad(pid interface{}) (interface{}, error) {
+	t, ok := pid.(pyTuple)
+	if !ok || len(t) != 5 || t[0] != "storage" {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported torch persistent id %v", pid)}
+	}
+	class, ok1 := t[1].(pyGlobal)
+	key, ok2 := t[2].(string)
+	numel, ok3 := t[4].(int64)
+	if !ok1 || !ok2 || !ok3 || numel < 0 || numel > math.MaxInt {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid torch storage reference %v", pid)}
+	}
+	if _, ok := torchStorageTypes[class.Name]; !ok || class.Module != "torch" {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("unsupported torch storage %s.%s", class.Module, class.Name), Err: ErrUnsupportedDType}
+	}
+	if s, ok := a.storages[key]; ok {
+		return s, nil
+	}
+	s := &torchStorage{class: class.Name, key: key, numel: int(numel)}
+	a.storages[key] = s
+	return s, nil
+}
+
+// collect adds the tensors in v to tensors, naming them by their key path.
+func (a *torchArchive) collect(v interface{}, name string, depth int, tensors map[string]*Tensor) error {
+	if depth > maxTorchDepth {
+		return ErrorNpy{Msg: fmt.Sprintf("torch checkpoint nested more than %d levels deep", maxTorchDepth)}
+	}
+	switch v := v.(type) {
+	case *pyDict:
+		for i, k := range v.Keys {
+			key := fmt.Sprint(k)
+			if name != "" {
+				key = name + "." + key
+			}
+			if err := a.collect(v.Values[i], key, depth+1, tensors); err != nil {
+				return err
+			}
+		}
+	case *pyObject:
+		g, _ := v.Callable.(pyGlobal)
+		switch {
+		case g.Module == "torch._utils" && (g.Name == "_rebuild_tensor_v2" || g.Name == "_rebuild_tensor"):
+			t, err := a.rebuildTensor(v.Args)
+			if err != nil {
+				return locate(err, "", name)
+			}
+			tensors[name] = t
+		case g.Module == "torch._utils" && strings.HasPrefix(g.Name, "_rebuild_parameter") && len(v.Args) > 0:
+			return a.collect(v.Args[0], name, depth+1, tensors)
+		}
+	}
+	return nil
+}
+
+// rebuildTensor applies the arguments of _rebuild_tensor_v2: the storage, the
+// element offset into it, the size, and the strides.
+func (a *torchArchive) rebuildTensor(args pyTuple) (*Tensor, error) {
+	if len(args) < 4 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("torch tensor rebuilt from %d arguments", len(args))}
+	}
+	s, ok := args[0].(*torchStorage)
+	offset, ok2 := args[1].(int64)
+	if !ok || !ok2 || offset < 0 || offset > int64(s.numel) {
+		return nil, ErrorNpy{Msg: "invalid torch tensor storage"}
+	}
+	shape, err := shapeFromLiteral(args[2])
+	if err != nil {
+		return nil, err
+	}
+	strides, err := shapeFromLiteral(args[3])
+	if err != nil || len(strides) != len(shape) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("invalid torch tensor strides %v", args[3])}
+	}
+	if err := a.o.checkSize(torchStorageTypes[s.class], shape); err != nil {
+		return nil, err
+	}
+
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	last := int(offset) // Furthest element the tensor uses, kept within the storage
+	overrun := false
+	if n > 0 {
+		for d, dim := range shape {
+			if dim > 1 && strides[d] > (s.numel-last)/(dim-1) {
+				overrun = true
+				break
+			}
+			last += (dim - 1) * strides[d]
+		}
+		overrun = overrun || last >= s.numel
+	}
+	if overrun {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("torch tensor of shape %v overruns storage %s of %d elements", shape, s.key, s.numel)}
+	}
+
+	storage, err := a.load(s)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 && n == s.numel && strides.Equal(rowMajorStrides(shape)) {
+		return &Tensor{Data: storage.Data, Shape: shape, DType: storage.DType, Device: "cpu"}, nil
+	}
+	data, err := storage.gather(shape, strides, int(offset))
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{Data: data, Shape: shape, DType: storage.DType, Device: "cpu"}, nil
+}
+
+// load reads the elements of a storage, once.
+func (a *torchArchive) load(s *torchStorage) (*Tensor, error) {
+	if s.data != nil {
+		return s.data, nil
+	}
+	f, ok := a.files[a.prefix+"data/"+s.key]
+	if !ok {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("torch storage %s not found", s.key), Err: ErrEntryNotFound}
+	}
+	dtype := torchStorageTypes[s.class]
+	shape := Shape{s.numel}
+	if err := a.o.checkSize(dtype, shape); err != nil {
+		return nil, err
+	}
+	size, ok := dataSize(dtype, shape)
+	if s.class == "ShortStorage" {
+		size /= 2
+	}
+	if !ok || uint64(size) > f.UncompressedSize64 {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("torch storage %s is shorter than %d elements", s.key, s.numel)}
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var data interface{}
+	if s.class == "ShortStorage" {
+		raw := make([]byte, size)
+		if _, err := io.ReadFull(rc, raw); err != nil {
+			return nil, err
+		}
+		widened := make([]int32, s.numel)
+		for i := range widened {
+			widened[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+		}
+		data = widened
+	} else if data, err = readData(shape, dtype, rc); err != nil {
+		return nil, err
+	}
+	s.data = &Tensor{Data: data, Shape: shape, DType: dtype, Device: "cpu"}
+	return s.data, nil
+}
@@ -0,0 +1,93 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// torchPickle returns a data.pkl holding {"w": tensor} for a tensor of the
+// given storage class rebuilt with offset, shape, and strides, which must
+// already be pickled.
+func torchPickle(class string, numel byte, offset, shape, strides string) string {
+	storage := "(\x8c\x07storagectorch\n" + class + "\n\x8c\x010\x8c\x03cpuK" + string(numel) + "tQ"
+	return "\x80\x02}(\x8c\x01wctorch._utils\n_rebuild_tensor_v2\n(" +
+		storage + offset + shape + strides + "\x89}tRu."
+}
+
+// writeTorch writes a torch checkpoint with the given data.pkl and storage 0.
+func writeTorch(t *testing.T, pkl string, storage []byte, byteorder string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.pt")
+	writeZip(t, path, map[string][]byte{
+		"archive/data.pkl":  []byte(pkl),
+		"archive/data/0":    storage,
+		"archive/byteorder": []byte(byteorder),
+	})
+	return path
+}
+
+// f32Bytes returns the little-endian encoding of values.
+func f32Bytes(values ...float32) []byte {
+	b := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, math.Float32bits(v))
+	}
+	return b
+}
+
+func TestReadTorchStateDict(t *testing.T) {
+	storage := f32Bytes(0, 1, 2, 3, 4, 5)
+	tests := []struct {
+		offset, shape, strides string
+		want                   []float32
+	}{
+		{"K\x00", "K\x02K\x03\x86", "K\x03K\x01\x86", []float32{0, 1, 2, 3, 4, 5}},
+		{"K\x00", "K\x03K\x02\x86", "K\x01K\x03\x86", []float32{0, 3, 1, 4, 2, 5}}, // Transposed
+		{"K\x01", "K\x02\x85", "K\x02\x85", []float32{1, 3}},
+	}
+	for _, tt := range tests {
+		path := writeTorch(t, torchPickle("FloatStorage", 6, tt.offset, tt.shape, tt.strides), storage, "little")
+		tensors, err := ReadTorchStateDict(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := tensors["w"].Data.([]float32)
+		for i := range tt.want {
+			if got[i] != tt.want[i] {
+				t.Errorf("got %v, want %v", got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestHostileTorchStateDict(t *testing.T) {
+	storage := f32Bytes(0, 1, 2, 3, 4, 5)
+	tests := map[string]struct {
+		pkl       string
+		storage   []byte
+		byteorder string
+	}{
+		"stride overrun": {torchPickle("FloatStorage", 6, "K\x00", "K\x02K\x03\x86", "K\x04K\x01\x86"), storage, "little"},
+		"offset overrun": {torchPickle("FloatStorage", 6, "K\x07", "K\x00\x85", "K\x01\x85"), storage, "little"},
+		"huge stride":    {torchPickle("FloatStorage", 6, "K\x00", "K\x05\x85", "\x8a\x08\x00\x00\x00\x00\x00\x00\x00\x40\x85"), storage, "little"},
+		"short storage":  {torchPickle("FloatStorage", 6, "K\x00", "K\x06\x85", "K\x01\x85"), storage[:20], "little"},
+		"storage class":  {torchPickle("Evil", 6, "K\x00", "K\x06\x85", "K\x01\x85"), storage, "little"},
+		"byte order":     {torchPickle("FloatStorage", 6, "K\x00", "K\x06\x85", "K\x01\x85"), storage, "big"},
+		"stride rank":    {torchPickle("FloatStorage", 6, "K\x00", "K\x06\x85", "K\x01K\x01\x86"), storage, "little"},
+	}
+	for name, tt := range tests {
+		path := writeTorch(t, tt.pkl, tt.storage, tt.byteorder)
+		if _, err := ReadTorchStateDict(path); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+
+	path := writeTorch(t, torchPickle("FloatStorage", 6, "K\x00", "K\x06\x85", "K\x01\x85"), storage, "little")
+	if _, err := ReadTorchStateDict(path, WithMaxBytes(8)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
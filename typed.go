@@ -0,0 +1,50 @@
+package gonpy
+
+// TypedTensor is a tensor whose element type is fixed at compile time. Its
+// dtype is the one whose element type is T.
+type TypedTensor[T Numeric] struct {
+	Data   []T
+	Shape  Shape
+	Device string
+}
+
+// NewTypedTensor returns a zeroed tensor of the given shape.
+func NewTypedTensor[T Numeric](shape Shape) *TypedTensor[T] {
+	return &TypedTensor[T]{Data: make([]T, shape.ElemCount()), Shape: shape, Device: "cpu"}
+}
+
+// DType returns the dtype of the tensor's elements.
+func (t *TypedTensor[T]) DType() DType {
+	return dtypeOf[T]()
+}
+
+// Tensor returns the tensor as a *Tensor sharing its data.
+func (t *TypedTensor[T]) Tensor() *Tensor {
+	return &Tensor{Data: t.Data, Shape: t.Shape, DType: dtypeOf[T](), Device: t.Device}
+}
+
+// Typed returns t as a TypedTensor[T], converting its data as DataAs does: the
+// data is shared if T is the element type of t's dtype and converted if every
+// value is exactly representable as T.
+func Typed[T Numeric](t *Tensor) (*TypedTensor[T], error) {
+	data, err := DataAs[T](t)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedTensor[T]{Data: data, Shape: t.Shape, Device: t.Device}, nil
+}
+
+// ReadNPYTyped reads an NPY file as a TypedTensor[T]. Arrays whose dtype
+// converts to T without loss are converted; any other dtype is an error.
+func ReadNPYTyped[T Numeric](path string, opts ...Option) (*TypedTensor[T], error) {
+	t, err := ReadNPY(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return Typed[T](t)
+}
+
+// WriteNPYTyped writes a TypedTensor[T] to an NPY file.
+func WriteNPYTyped[T Numeric](path string, t *TypedTensor[T], opts ...Option) error {
+	return t.Tensor().WriteNPY(path, opts...)
+}
@@ -0,0 +1,78 @@
+package gonpy
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestTyped(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *Tensor
+		want string // Data as float64, or "" if the conversion must fail
+	}{
+		{"same dtype", &Tensor{Data: []float64{1.5, -2}, Shape: Shape{2}, DType: DTypeF64, Device: "cpu"}, "[1.5 -2]"},
+		{"widened", &Tensor{Data: []float32{0.25, 3}, Shape: Shape{1, 2}, DType: DTypeF32, Device: "cpu"}, "[0.25 3]"},
+		{"from int32", &Tensor{Data: []int32{-7, 1 << 30}, Shape: Shape{2}, DType: DTypeI32, Device: "cpu"}, "[-7 1.073741824e+09]"},
+		{"from bool", &Tensor{Data: []bool{true, false}, Shape: Shape{2}, DType: DTypeBool, Device: "cpu"}, "[1 0]"},
+		{"from int64", &Tensor{Data: []int64{1 << 60}, Shape: Shape{1}, DType: DTypeI64, Device: "cpu"}, ""},
+		{"from complex", &Tensor{Data: []complex128{1i}, Shape: Shape{1}, DType: DTypeC128, Device: "cpu"}, ""},
+		{"from string", &Tensor{Data: []string{"a"}, Shape: Shape{1}, DType: UnicodeDType(1), Device: "cpu"}, ""},
+		{"mistyped data", &Tensor{Data: []float32{1}, Shape: Shape{1}, DType: DTypeF64, Device: "cpu"}, ""},
+	}
+	for _, tt := range tests {
+		got, err := Typed[float64](tt.in)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: converted to %v", tt.name, got.Data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if fmt.Sprint(got.Data) != tt.want || !got.Shape.Equal(tt.in.Shape) || got.DType() != DTypeF64 {
+			t.Errorf("%s: got %s %v %v, want %s", tt.name, got.DType(), got.Shape, got.Data, tt.want)
+		}
+	}
+}
+
+func TestTypedSharesData(t *testing.T) {
+	x := NewTypedTensor[int32](Shape{2, 2})
+	if x.DType() != DTypeI32 || len(x.Data) != 4 || x.Device != "cpu" {
+		t.Fatalf("NewTypedTensor = %s %v on %s", x.DType(), x.Data, x.Device)
+	}
+	x.Tensor().Data.([]int32)[3] = 9
+	y, err := Typed[int32](x.Tensor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	y.Data[0] = 5
+	if fmt.Sprint(x.Data) != "[5 0 0 9]" {
+		t.Errorf("data not shared: %v", x.Data)
+	}
+}
+
+func TestNPYTypedRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npy")
+	x := &TypedTensor[uint8]{Data: []uint8{1, 2, 255}, Shape: Shape{3}, Device: "cpu"}
+	if err := WriteNPYTyped(path, x); err != nil {
+		t.Fatal(err)
+	}
+	same, err := ReadNPYTyped[uint8](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wide, err := ReadNPYTyped[int64](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(same.Data) != "[1 2 255]" || fmt.Sprint(wide.Data) != "[1 2 255]" || !wide.Shape.Equal(x.Shape) {
+		t.Errorf("read back %v and %v %v", same.Data, wide.Data, wide.Shape)
+	}
+	if _, err := ReadNPYTyped[int8](path); err == nil {
+		t.Error("uint8 array read as int8")
+	}
+}
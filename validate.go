@@ -0,0 +1,195 @@
+package gonpy
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// ValidationReport describes the integrity of an NPY file or NPZ archive, as
+// checked by ValidateNPY or ValidateNPZ.
+type ValidationReport struct {
+	Path     string
+	Arrays   []ArrayReport // One per array, in file order
+	Problems []error       // Problems with the archive as a whole, such as duplicate names
+}
+
+// ArrayReport describes one array of a validated file.
+type ArrayReport struct {
+	Name      string  // Tensor name within an NPZ archive; empty for an NPY file
+	Version   int     // Major version of the NPY format, or 0 if it could not be read
+	Header    *Header // Nil if the header could not be parsed
+	DataBytes int64   // Bytes following the header
+	Problems  []error
+}
+
+// Valid reports whether no problems were found.
+func (r *ValidationReport) Valid() bool {
+	return r.Err() == nil
+}
+
+// Err returns the problems found joined into one error, or nil if there are none.
+// Each problem is prefixed with the path and, for an array in an archive, its name.
+func (r *ValidationReport) Err() error {
+	var errs []error
+	for _, p := range r.Problems {
+		errs = append(errs, &locatedError{where: r.Path, err: p})
+	}
+	for _, a := range r.Arrays {
+		where := r.Path
+		if a.Name != "" {
+			where += ": entry " + a.Name
+		}
+		for _, p := range a.Problems {
+			errs = append(errs, &locatedError{where: where, err: p})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// locatedError prefixes an error with where it was found.
+type locatedError struct {
+	where string
+	err   error
+}
+
+func (e *locatedError) Error() string {
+	return e.where + ": " + e.err.Error()
+}
+
+func (e *locatedError) Unwrap() error {
+	return e.err
+}
+
+// ValidateNPY checks an NPY file without decoding its data: the magic string,
+// format version, header, and that the data length matches the dtype and shape.
+// Problems found are listed in the report; the error is only for failures to
+// open or read the file.
+func ValidateNPY(path string, opts ...Option) (*ValidationReport, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	a, err := validateArray(f)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidationReport{Path: path, Arrays: []ArrayReport{*a}}, nil
+}
+
+// ValidateNPZ checks an NPZ archive without decoding its data. Besides the
+// checks of ValidateNPY for every entry, it verifies the CRC-32 of every entry
+// and any checksums recorded with WithChecksums, and reports entry names that
+// are unsafe or that decode to the same tensor name. Problems found are listed
+// in the report; the error is only for failures to open the archive.
+func ValidateNPZ(path string, opts ...Option) (*ValidationReport, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	report := &ValidationReport{Path: path}
+	if _, err := readPacking(r.File); err != nil {
+		report.Problems = append(report.Problems, err)
+	}
+	sums, err := readChecksums(r.File)
+	if err != nil {
+		report.Problems = append(report.Problems, err)
+	}
+
+	seen := make(map[string]bool, len(r.File))
+	for _, file := range r.File {
+		if isMetadataEntry(file.Name) {
+			continue
+		}
+		if _, err := sanitizeEntryName(file.Name); err != nil {
+			report.Problems = append(report.Problems, err)
+		}
+		name := o.nameCodec.Decode(file.Name)
+		if seen[name] {
+			report.Problems = append(report.Problems, ErrorNpy{Msg: fmt.Sprintf("duplicate array name %s", name)})
+		}
+		seen[name] = true
+
+		a := validateEntry(file, name, sums)
+		a.Name = name
+		report.Arrays = append(report.Arrays, *a)
+	}
+	return report, nil
+}
+
+// validateEntry checks the array in a zip entry for tensor name, reading it to
+// the end so that its CRC-32 and any recorded CRC-32C checksum are verified.
+func validateEntry(file *zip.File, name string, sums map[string]uint32) *ArrayReport {
+	rc, err := file.Open()
+	if err != nil {
+		return &ArrayReport{Problems: []error{err}}
+	}
+	defer rc.Close()
+
+	h := crc32.New(castagnoli)
+	a, err := validateArray(io.TeeReader(rc, h))
+	if err != nil {
+		return &ArrayReport{Problems: []error{err}}
+	}
+	if want, ok := sums[name]; ok && len(a.Problems) == 0 {
+		if got := h.Sum32(); got != want {
+			a.Problems = append(a.Problems, ErrorNpy{Msg: fmt.Sprintf("checksum mismatch: got %08x, want %08x", got, want), Err: ErrChecksumMismatch})
+		}
+	}
+	return a
+}
+
+// validateArray checks the NPY content read from r, which is consumed to the
+// end. Problems with the content are recorded in the report; an error is
+// returned only if r fails for another reason.
+func validateArray(r io.Reader) (*ArrayReport, error) {
+	a := &ArrayReport{}
+	var prefix bytes.Buffer
+	headerStr, err := readHeader(io.TeeReader(r, &prefix))
+	if p := prefix.Bytes(); len(p) > len(npyMagicString) && string(p[:len(npyMagicString)]) == npyMagicString {
+		a.Version = int(p[len(npyMagicString)])
+	}
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrorNpy{Msg: "file ends within the header"}
+		}
+		if _, ok := err.(ErrorNpy); !ok {
+			return nil, err
+		}
+		a.Problems = append(a.Problems, err)
+		return a, nil
+	}
+
+	header, err := parseHeader(headerStr)
+	if err != nil {
+		a.Problems = append(a.Problems, err)
+	}
+	a.Header = header
+
+	// Count the data without keeping it
+	a.DataBytes, err = io.Copy(io.Discard, r)
+	if err != nil {
+		a.Problems = append(a.Problems, err)
+		return a, nil
+	}
+	if header != nil {
+		if err := checkPayload(header, a.DataBytes); err != nil {
+			a.Problems = append(a.Problems, err)
+		}
+	}
+	return a, nil
+}
@@ -0,0 +1,107 @@
+package gonpy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const f32Header = "{'descr': '<f4', 'fortran_order': False, 'shape': (3,), }"
+
+func TestValidateNPY(t *testing.T) {
+	good := npyBytes(f32Header, make([]byte, 12))
+	tests := map[string]struct {
+		content []byte
+		problem string // Empty if the file is valid
+	}{
+		"good":         {good, ""},
+		"truncated":    {npyBytes(f32Header, make([]byte, 8)), "holds 8 bytes of data"},
+		"trailing":     {npyBytes(f32Header, make([]byte, 16)), "holds 16 bytes of data"},
+		"bad magic":    {append([]byte("\x93NUMPZ"), good[6:]...), ""},
+		"short header": {good[:40], "ends within the header"},
+		"empty":        {nil, "ends within the header"},
+		"bad dict":     {npyBytes("{'descr': '<f4', 'shape': (3,", make([]byte, 12)), ""},
+		"bad descr":    {npyBytes("{'descr': '<x9', 'fortran_order': False, 'shape': (3,), }", make([]byte, 12)), ""},
+	}
+	for name, tt := range tests {
+		path := filepath.Join(t.TempDir(), "a.npy")
+		if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		report, err := ValidateNPY(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(report.Arrays) != 1 {
+			t.Fatalf("%s: %d array reports", name, len(report.Arrays))
+		}
+		if name == "good" {
+			a := report.Arrays[0]
+			if !report.Valid() || a.Version != 1 || a.Header == nil || !a.Header.Shape.Equal(Shape{3}) || a.DataBytes != 12 {
+				t.Errorf("good file: %+v, %v", a, report.Err())
+			}
+			continue
+		}
+		err = report.Err()
+		if report.Valid() || err == nil {
+			t.Errorf("%s: reported valid", name)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), path+": ") || !strings.Contains(err.Error(), tt.problem) {
+			t.Errorf("%s: got %v, want a problem with %q", name, err, tt.problem)
+		}
+	}
+
+	if _, err := ValidateNPY(filepath.Join(t.TempDir(), "missing.npy")); err == nil {
+		t.Error("missing file validated")
+	}
+}
+
+func TestValidateNPZ(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.npz")
+	writeChecksummedNPZ(t, path, WithChecksums())
+	report, err := ValidateNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid() || len(report.Arrays) != 2 || report.Arrays[0].Name != "x" || report.Arrays[1].Name != "y" {
+		t.Errorf("good archive: %+v, %v", report, report.Err())
+	}
+
+	flipLastByte(t, path, "x.npy")
+	report, err = ValidateNPZ(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := report.Err(); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), "entry x:") {
+		t.Errorf("corrupted archive: got %v, want a checksum mismatch for x", err)
+	}
+
+	good := string(npyBytes(f32Header, make([]byte, 12)))
+	tests := map[string]struct {
+		entries []string
+		problem string
+	}{
+		"parent":    {[]string{"../x.npy", good}, "escapes the destination"},
+		"absolute":  {[]string{"/x.npy", good}, "escapes the destination"},
+		"backslash": {[]string{`a\x.npy`, good}, "backslash"},
+		"drive":     {[]string{"C:/x.npy", good}, "volume name"},
+		"duplicate": {[]string{"x.npy", good, "x.npy", good}, "duplicate array name x"},
+		"truncated": {[]string{"x.npy", good[:len(good)-4]}, "entry x: npy error: file holds 8 bytes"},
+		"header":    {[]string{"x.npy", good[:20]}, "entry x: npy error: file ends within the header"},
+		"manifest":  {[]string{"x.npy", good, checksumEntry, "{"}, "invalid checksum metadata"},
+	}
+	for name, tt := range tests {
+		path := filepath.Join(t.TempDir(), "a.npz")
+		writeZipEntries(t, path, tt.entries...)
+		report, err := ValidateNPZ(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if err := report.Err(); report.Valid() || err == nil || !strings.Contains(err.Error(), tt.problem) {
+			t.Errorf("%s: got %v, want a problem with %q", name, err, tt.problem)
+		}
+	}
+}
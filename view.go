@@ -0,0 +1,173 @@
+package gonpy
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// View is a strided window onto the elements of a tensor. It shares the
+// tensor's data, so taking a view copies nothing and changes made through Set
+// are seen by the tensor. Element i along dimension d of the view is Strides[d]
+// elements away from element i-1 in the tensor's flattened data. Views may be
+// built directly, for instance with permuted strides for a transpose or with
+// negative strides to reverse a dimension; every element must lie within Base.
+type View struct {
+	Base    *Tensor
+	Shape   Shape
+	Strides []int // In elements, per dimension
+	Offset  int   // Flat index in Base of the view's first element
+}
+
+// View returns a view of the whole tensor.
+func (t *Tensor) View() *View {
+	return &View{
+		Base:    t,
+		Shape:   append(Shape(nil), t.Shape...),
+		Strides: rowMajorStrides(t.Shape),
+	}
+}
+
+// Slice returns a view of elements start to end-1 along dimension dim.
+func (t *Tensor) Slice(dim, start, end int) (*View, error) {
+	return t.View().Slice(dim, start, end)
+}
+
+// Narrow returns a view of length elements from start along dimension dim.
+func (t *Tensor) Narrow(dim, start, length int) (*View, error) {
+	return t.View().Narrow(dim, start, length)
+}
+
+// DType returns the dtype of the view's elements.
+func (v *View) DType() DType {
+	return v.Base.DType
+}
+
+// Slice returns a view of elements start to end-1 along dimension dim, which
+// may be negative to count from the last dimension.
+func (v *View) Slice(dim, start, end int) (*View, error) {
+	if err := v.check(); err != nil {
+		return nil, err
+	}
+	d, err := normalizeAxis(dim, len(v.Shape))
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || end < start || end > v.Shape[d] {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("slice [%d:%d] out of range for dimension %d of size %d", start, end, dim, v.Shape[d])}
+	}
+	s := &View{
+		Base:    v.Base,
+		Shape:   append(Shape(nil), v.Shape...),
+		Strides: append([]int(nil), v.Strides...),
+		Offset:  v.Offset + start*v.Strides[d],
+	}
+	s.Shape[d] = end - start
+	return s, nil
+}
+
+// Narrow returns a view of length elements from start along dimension dim.
+func (v *View) Narrow(dim, start, length int) (*View, error) {
+	return v.Slice(dim, start, start+length)
+}
+
+// At returns the element at idx, one index per dimension, as Tensor.At does.
+func (v *View) At(idx ...int) (interface{}, error) {
+	i, err := v.index(idx)
+	if err != nil {
+		return nil, err
+	}
+	return v.Base.atFlat(i)
+}
+
+// Set stores x as the element at idx of the view, and so of its base tensor.
+func (v *View) Set(x interface{}, idx ...int) error {
+	i, err := v.index(idx)
+	if err != nil {
+		return err
+	}
+	return v.Base.setFlat(x, i)
+}
+
+// check verifies that the view has a stride per dimension and that all of its
+// elements lie within the base tensor.
+func (v *View) check() error {
+	if len(v.Strides) != len(v.Shape) {
+		return ErrorNpy{Msg: fmt.Sprintf("view has %d strides for %d dimensions", len(v.Strides), len(v.Shape))}
+	}
+	lo, hi := v.Offset, v.Offset
+	for d, dim := range v.Shape {
+		switch {
+		case dim < 0:
+			return ErrorNpy{Msg: fmt.Sprintf("invalid view shape %v", v.Shape)}
+		case dim == 0:
+			return nil
+		case v.Strides[d] < 0:
+			lo += (dim - 1) * v.Strides[d]
+		default:
+			hi += (dim - 1) * v.Strides[d]
+		}
+	}
+	if lo < 0 || hi >= v.Base.Shape.ElemCount() {
+		return ErrorNpy{Msg: fmt.Sprintf("view of shape %v with strides %v at offset %d exceeds tensor of shape %v", v.Shape, v.Strides, v.Offset, v.Base.Shape)}
+	}
+	return nil
+}
+
+// index returns the flat index in Base of the element at idx.
+func (v *View) index(idx []int) (int, error) {
+	if err := v.check(); err != nil {
+		return 0, err
+	}
+	if _, err := v.Shape.Index(idx...); err != nil {
+		return 0, err
+	}
+	flat := v.Offset
+	for d, i := range idx {
+		flat += i * v.Strides[d]
+	}
+	return flat, nil
+}
+
+// IsContiguous reports whether the view's elements are adjacent in row-major
+// order in the base tensor's data.
+func (v *View) IsContiguous() bool {
+	if len(v.Strides) != len(v.Shape) {
+		return false
+	}
+	stride := 1
+	for d := len(v.Shape) - 1; d >= 0; d-- {
+		if v.Shape[d] != 1 && v.Strides[d] != stride {
+			return false
+		}
+		stride *= v.Shape[d]
+	}
+	return true
+}
+
+// Contiguous returns the view's elements as a tensor. A contiguous view of an
+// unpacked tensor shares the base tensor's data; otherwise the elements are
+// copied into new data in row-major order.
+func (v *View) Contiguous() (*Tensor, error) {
+	t := v.Base
+	if err := t.checkLength(); err != nil {
+		return nil, err
+	}
+	if err := v.check(); err != nil {
+		return nil, err
+	}
+	shape := append(Shape(nil), v.Shape...)
+	n := shape.ElemCount()
+	if n > 0 && v.IsContiguous() && !t.DType.isPacked() {
+		size := 1
+		if t.DType.isStructured() {
+			size = t.DType.Size() // Records are raw bytes
+		}
+		data := reflect.ValueOf(t.Data).Slice(v.Offset*size, (v.Offset+n)*size)
+		return &Tensor{Data: data.Interface(), Shape: shape, DType: t.DType, Device: t.Device}, nil
+	}
+	data, err := t.gather(shape, v.Strides, v.Offset)
+	if err != nil {
+		return nil, err
+	}
+	return &Tensor{Data: data, Shape: shape, DType: t.DType, Device: t.Device}, nil
+}
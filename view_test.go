@@ -0,0 +1,131 @@
+package gonpy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestViewContiguous(t *testing.T) {
+	x := arange(2, 3, 4) // Strides 12, 4, 1
+	sliced, err := x.Slice(2, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	narrowed, err := sliced.Narrow(-3, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := x.Slice(1, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		view  *View
+		shape Shape
+		want  string
+	}{
+		{"whole", x.View(), Shape{2, 3, 4}, fmt.Sprint(x.Data)},
+		{"sliced", sliced, Shape{2, 3, 2}, "[1 2 5 6 9 10 13 14 17 18 21 22]"},
+		{"narrowed", narrowed, Shape{1, 3, 2}, "[13 14 17 18 21 22]"},
+		{"rows", rows, Shape{2, 2, 4}, "[4 5 6 7 8 9 10 11 16 17 18 19 20 21 22 23]"},
+		{"transposed", &View{Base: x, Shape: Shape{4, 3, 2}, Strides: []int{1, 4, 12}}, Shape{4, 3, 2},
+			"[0 12 4 16 8 20 1 13 5 17 9 21 2 14 6 18 10 22 3 15 7 19 11 23]"},
+		{"reversed", &View{Base: x, Shape: Shape{2, 3, 4}, Strides: []int{12, 4, -1}, Offset: 3}, Shape{2, 3, 4},
+			"[3 2 1 0 7 6 5 4 11 10 9 8 15 14 13 12 19 18 17 16 23 22 21 20]"},
+		{"reversed rows", &View{Base: x, Shape: Shape{2, 2}, Strides: []int{-12, 4}, Offset: 16}, Shape{2, 2}, "[16 20 4 8]"},
+		{"every other", &View{Base: x, Shape: Shape{2, 2}, Strides: []int{12, -2}, Offset: 3}, Shape{2, 2}, "[3 1 15 13]"},
+		{"empty", &View{Base: x, Shape: Shape{0, 4}, Strides: []int{4, 1}, Offset: 24}, Shape{0, 4}, "[]"},
+	}
+	for _, tt := range tests {
+		c, err := tt.view.Contiguous()
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !c.Shape.Equal(tt.shape) || fmt.Sprint(c.Data) != tt.want {
+			t.Errorf("%s: Contiguous = %v %v, want %v %s", tt.name, c.Shape, c.Data, tt.shape, tt.want)
+		}
+		// At agrees with the materialized order
+		if n := c.Shape.ElemCount(); n > 0 {
+			last := make([]int, len(tt.shape))
+			for d := range last {
+				last[d] = tt.shape[d] - 1
+			}
+			got, err := tt.view.At(last...)
+			if want := c.Data.([]float32)[n-1]; err != nil || got != want {
+				t.Errorf("%s: At(%v) = %v, %v, want %v", tt.name, last, got, err, want)
+			}
+		}
+	}
+}
+
+func TestViewSharesData(t *testing.T) {
+	x := arange(2, 3)
+	v, err := x.Slice(1, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set(float32(-1), 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(x.Data); got != "[0 1 2 3 -1 5]" {
+		t.Errorf("Set through view: tensor = %s", got)
+	}
+	row, err := x.Slice(0, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := row.Contiguous()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Data.([]float32)[0] = 30
+	if x.Data.([]float32)[3] != 30 {
+		t.Error("contiguous view does not share data")
+	}
+}
+
+func TestViewInvalid(t *testing.T) {
+	x := arange(2, 3)
+	if _, err := x.Slice(2, 0, 1); err == nil {
+		t.Error("slice of dimension 2 of a matrix accepted")
+	}
+	if _, err := x.Slice(-3, 0, 1); err == nil {
+		t.Error("slice of dimension -3 of a matrix accepted")
+	}
+	for _, r := range [][2]int{{-1, 1}, {2, 1}, {0, 4}} {
+		if _, err := x.Slice(1, r[0], r[1]); err == nil {
+			t.Errorf("slice %v accepted", r)
+		}
+	}
+	if _, err := x.Narrow(0, 1, 2); err == nil {
+		t.Error("narrow past the end accepted")
+	}
+	v, _ := x.Slice(1, 1, 2)
+	if _, err := v.At(0, 1); err == nil {
+		t.Error("At outside the view accepted")
+	}
+	if err := v.Set(float32(1), 0); err == nil {
+		t.Error("Set with too few indices accepted")
+	}
+
+	views := map[string]*View{
+		"too few strides": {Base: x, Shape: Shape{2, 3}, Strides: []int{3}},
+		"past the end":    {Base: x, Shape: Shape{2, 3}, Strides: []int{3, 1}, Offset: 1},
+		"before start":    {Base: x, Shape: Shape{2, 3}, Strides: []int{3, -1}},
+		"stride too big":  {Base: x, Shape: Shape{2, 2}, Strides: []int{5, 1}},
+		"negative dim":    {Base: x, Shape: Shape{-1, 3}, Strides: []int{3, 1}},
+	}
+	for name, v := range views {
+		if _, err := v.Contiguous(); err == nil {
+			t.Errorf("%s: Contiguous succeeded", name)
+		}
+		if _, err := v.At(0, 0); err == nil {
+			t.Errorf("%s: At succeeded", name)
+		}
+		if _, err := v.Slice(0, 0, 1); err == nil {
+			t.Errorf("%s: Slice succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package gonpy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// A WAV file is a RIFF container: "RIFF", the size of the rest of the file,
+// "WAVE", and a sequence of chunks, each an ID, a little-endian uint32 size,
+// and data padded to an even length. The "fmt " chunk describes the samples
+// and the "data" chunk holds them, one frame of interleaved channels at a
+// time.
+
+// WAV format tags.
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xfffe
+)
+
+// WithWAVBits sets the width in bits of the samples WriteWAV writes for
+// integer tensors: 16, the default, 24, or 32.
+func WithWAVBits(bits int) Option {
+	return func(o *options) {
+		o.wavBits = bits
+	}
+}
+
+// wavFormat is the content of a "fmt " chunk.
+type wavFormat struct {
+	tag        uint16
+	channels   int
+	sampleRate int
+	blockAlign int
+	bits       int
+}
+
+// ReadWAV reads the samples of a PCM or IEEE float WAV file and returns them
+// with the sample rate in Hz. A mono file gives a tensor of shape [frames],
+// and any other a tensor of shape [frames, channels]. Samples keep their
+// stored values: 8-bit samples, which are unsigned, as a U8 tensor, 16, 24,
+// and 32-bit samples as an I32 tensor, and float samples as an F32 or F64
+// tensor. Cast to F32 and scale by 1/32768 for the usual [-1, 1) range of
+// 16-bit audio.
+func ReadWAV(path string, opts ...Option) (*Tensor, int, error) {
+	o := newOptions(opts)
+	o.limiter.Acquire()
+	defer o.limiter.Release()
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r := bufio.NewReader(o.cancelableReader(f))
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil || string(header[:4]) != "RIFF" || string(header[8:]) != "WAVE" {
+		return nil, 0, locate(ErrorNpy{Msg: "not a WAV file", Err: ErrBadMagic}, path, "")
+	}
+	var format *wavFormat
+	offset := int64(len(header))
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, 0, locate(ErrorNpy{Msg: "WAV file has no data chunk"}, path, "")
+			}
+			return nil, 0, err
+		}
+		offset += int64(len(chunk))
+		size := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		if string(chunk[:4]) == "data" {
+			size = min(size, info.Size()-offset) // Streaming writers may leave the size unset
+		}
+		if size > info.Size()-offset {
+			return nil, 0, locate(ErrorNpy{Msg: fmt.Sprintf("truncated WAV %q chunk", chunk[:4])}, path, "")
+		}
+		switch string(chunk[:4]) {
+		case "fmt ":
+			b := make([]byte, size)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, 0, err
+			}
+			if format, err = parseWAVFormat(b); err != nil {
+				return nil, 0, locate(err, path, "")
+			}
+		case "data":
+			if format == nil {
+				return nil, 0, locate(ErrorNpy{Msg: "WAV data chunk precedes fmt chunk"}, path, "")
+			}
+			t, err := readWAVData(r, format, size, o)
+			if err != nil {
+				return nil, 0, locate(err, path, "")
+			}
+			return t, format.sampleRate, nil
+		default:
+			if _, err := r.Discard(int(size)); err != nil {
+				return nil, 0, err
+			}
+		}
+		if size%2 == 1 {
+			size++
+			r.Discard(1)
+		}
+		offset += size
+	}
+}
+
+// parseWAVFormat decodes a "fmt " chunk, resolving the format of
+// WAVE_FORMAT_EXTENSIBLE files from their subformat GUID.
+func parseWAVFormat(b []byte) (*wavFormat, error) {
+	if len(b) < 16 {
+		return nil, ErrorNpy{Msg: "WAV fmt chunk too short"}
+	}
+	format := &wavFormat{
+		tag:        binary.LittleEndian.Uint16(b),
+		channels:   int(binary.LittleEndian.Uint16(b[2:])),
+		sampleRate: int(binary.LittleEndian.Uint32(b[4:])),
+		blockAlign: int(binary.LittleEndian.Uint16(b[12:])),
+		bits:       int(binary.LittleEndian.Uint16(b[14:])),
+	}
+	if format.tag == wavExtensible {
+		if len(b) < 26 {
+			return nil, ErrorNpy{Msg: "WAV fmt chunk too short"}
+		}
+		format.tag = binary.LittleEndian.Uint16(b[24:])
+	}
+	if format.channels == 0 || format.blockAlign%format.channels != 0 || format.bits > 8*(format.blockAlign/format.channels) {
+		return nil, ErrorNpy{Msg: fmt.Sprintf("WAV file with %d channels of %d bits in %d-byte frames", format.channels, format.bits, format.blockAlign)}
+	}
+	width := format.blockAlign / format.channels
+	switch {
+	case format.tag == wavPCM && width >= 1 && width <= 4:
+	case format.tag == wavFloat && (width == 4 || width == 8):
+	default:
+		return nil, ErrorNpy{Msg: fmt.Sprintf("WAV format %d with %d-byte samples", format.tag, width), Err: ErrUnsupportedDType}
+	}
+	return format, nil
+}
+
+// readWAVData reads size bytes of samples from r. Any partial frame at the
+// end is ignored.
+func readWAVData(r io.Reader, format *wavFormat, size int64, o *options) (*Tensor, error) {
+	width := format.blockAlign / format.channels
+	dtype := DTypeI32
+	switch {
+	case width == 1:
+		dtype = DTypeU8
+	case format.tag == wavFloat && width == 4:
+		dtype = DTypeF32
+	case format.tag == wavFloat:
+		dtype = DTypeF64
+	}
+	frames := int(size / int64(format.blockAlign))
+	shape := Shape{frames, format.channels}
+	if format.channels == 1 {
+		shape = Shape{frames}
+	}
+	if err := o.checkSize(dtype, shape); err != nil {
+		return nil, err
+	}
+	raw := make([]byte, frames*format.blockAlign)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, err
+	}
+
+	t, err := Zeros(dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	switch d := t.Data.(type) {
+	case []uint8:
+		copy(d, raw)
+	case []int32:
+		for i := range d {
+			b := raw[i*width:]
+			switch width {
+			case 2:
+				d[i] = int32(int16(binary.LittleEndian.Uint16(b)))
+			case 3:
+				d[i] = int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			case 4:
+				d[i] = int32(binary.LittleEndian.Uint32(b))
+			}
+		}
+	case []float32:
+		for i := range d {
+			d[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	case []float64:
+		for i := range d {
+			d[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[8*i:]))
+		}
+	}
+	return t, nil
+}
+
+// WriteWAV writes a tensor of samples, of shape [frames] for mono audio or
+// [frames, channels], to a WAV file with the given sample rate in Hz. U8
+// tensors are written as 8-bit PCM, F32 and F64 tensors as IEEE float, and
+// other integer tensors as PCM of the width set by WithWAVBits, which their
+// values must fit. F16 and BF16 tensors are written as F32.
+func WriteWAV(path string, t *Tensor, sampleRate int, opts ...Option) error {
+	o := newOptions(opts)
+	t, err := t.storedAs(o)
+	if err != nil {
+		return err
+	}
+	if err := t.checkData(); err != nil {
+		return err
+	}
+	if len(t.Shape) != 1 && len(t.Shape) != 2 {
+		return ErrorNpy{Msg: fmt.Sprintf("WAV samples must have shape [frames] or [frames, channels], not %v", t.Shape)}
+	}
+	channels := 1
+	if len(t.Shape) == 2 {
+		channels = t.Shape[1]
+	}
+	if sampleRate <= 0 || int64(sampleRate) > math.MaxUint32 {
+		return ErrorNpy{Msg: fmt.Sprintf("WAV sample rate %d", sampleRate)}
+	}
+
+	tag, width := uint16(wavPCM), 0
+	switch t.DType {
+	case DTypeU8:
+		width = 1
+	case DTypeF16, DTypeBF16:
+		if t, err = t.Cast(DTypeF32); err != nil {
+			return err
+		}
+		tag, width = wavFloat, 4
+	case DTypeF32:
+		tag, width = wavFloat, 4
+	case DTypeF64:
+		tag, width = wavFloat, 8
+	case DTypeI8, DTypeI32, DTypeI64, DTypeU32, DTypeU64:
+		bits := o.wavBits
+		if bits == 0 {
+			bits = 16
+		}
+		if bits != 16 && bits != 24 && bits != 32 {
+			return ErrorNpy{Msg: fmt.Sprintf("WAV samples of %d bits", bits)}
+		}
+		if t, err = t.Cast(DTypeI64); err != nil {
+			return err
+		}
+		width = bits / 8
+		limit := int64(1) << (bits - 1)
+		for _, v := range t.Data.([]int64) {
+			if v < -limit || v >= limit {
+				return ErrorNpy{Msg: fmt.Sprintf("sample %d out of range for %d-bit WAV", v, bits)}
+			}
+		}
+	default:
+		return ErrorNpy{Msg: fmt.Sprintf("WAV file of %s samples", t.DType), Err: ErrUnsupportedDType}
+	}
+	if channels == 0 || channels*width > math.MaxUint16 {
+		return ErrorNpy{Msg: fmt.Sprintf("WAV file with %d channels of %d-byte samples", channels, width)}
+	}
+	size := int64(t.Shape.ElemCount()) * int64(width)
+	if size+size%2 > math.MaxUint32-58 {
+		return ErrTooLarge
+	}
+
+	f, err := createOutput(path, o)
+	if err != nil {
+		return err
+	}
+	format := &wavFormat{tag: tag, channels: channels, sampleRate: sampleRate, blockAlign: channels * width, bits: 8 * width}
+	if err := writeWAV(f, t, format, size, o); err != nil {
+		f.discard()
+		return err
+	}
+	return f.commit()
+}
+
+// writeWAV writes the header and samples of a WAV file to f. Float files
+// also get the cbSize field and fact chunk required of non-PCM formats.
+func writeWAV(f *outputFile, t *Tensor, format *wavFormat, size int64, o *options) error {
+	bw := bufio.NewWriterSize(f, writeBufferSize)
+	w := o.cancelableWriter(bw)
+	var fmtChunk []byte
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, format.tag)
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(format.channels))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(format.sampleRate))
+	fmtChunk = binary.LittleEndian.AppendUint32(fmtChunk, uint32(format.sampleRate*format.blockAlign))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(format.blockAlign))
+	fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, uint16(format.bits))
+	var chunks []byte
+	if format.tag == wavFloat {
+		fmtChunk = binary.LittleEndian.AppendUint16(fmtChunk, 0)
+		chunks = appendWAVChunk(chunks, "fmt ", fmtChunk)
+		frames := uint32(size / int64(format.blockAlign))
+		chunks = appendWAVChunk(chunks, "fact", binary.LittleEndian.AppendUint32(nil, frames))
+	} else {
+		chunks = appendWAVChunk(chunks, "fmt ", fmtChunk)
+	}
+
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+int64(len(chunks))+8+size+size%2))
+	header = append(header, "WAVE"...)
+	header = append(header, chunks...)
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(size))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	switch d := t.Data.(type) {
+	case []uint8:
+		if _, err := w.Write(d); err != nil {
+			return err
+		}
+	case []float32, []float64:
+		if err := writeData(w, t.DType, d); err != nil {
+			return err
+		}
+	case []int64:
+		width := format.bits / 8
+		for _, v := range d {
+			binary.LittleEndian.PutUint64(buf[:], uint64(v))
+			if _, err := w.Write(buf[:width]); err != nil {
+				return err
+			}
+		}
+	}
+	if size%2 == 1 {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendWAVChunk appends a chunk holding data, which must have an even
+// length.
+func appendWAVChunk(b []byte, id string, data []byte) []byte {
+	b = append(b, id...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(data)))
+	return append(b, data...)
+}
@@ -0,0 +1,140 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWAVRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   *Tensor
+		bits int
+		want DType
+	}{
+		{&Tensor{Data: []uint8{0, 128, 255}, Shape: Shape{3}, DType: DTypeU8, Device: "cpu"}, 0, DTypeU8},
+		{&Tensor{Data: []int32{-32768, 32767, 0, 1}, Shape: Shape{2, 2}, DType: DTypeI32, Device: "cpu"}, 16, DTypeI32},
+		{&Tensor{Data: []int64{-8388608, 8388607, 5}, Shape: Shape{3}, DType: DTypeI64, Device: "cpu"}, 24, DTypeI32},
+		{&Tensor{Data: []int32{-1 << 31, 1<<31 - 1}, Shape: Shape{1, 2}, DType: DTypeI32, Device: "cpu"}, 32, DTypeI32},
+		{&Tensor{Data: []float32{-1, 0.5, 0}, Shape: Shape{3}, DType: DTypeF32, Device: "cpu"}, 0, DTypeF32},
+		{&Tensor{Data: []float64{0.25, -0.75}, Shape: Shape{1, 2}, DType: DTypeF64, Device: "cpu"}, 0, DTypeF64},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "a.wav")
+		if err := WriteWAV(path, tt.in, 44100, WithWAVBits(tt.bits)); err != nil {
+			t.Fatalf("%s: %v", tt.in.DType, err)
+		}
+		out, rate, err := ReadWAV(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.in.DType, err)
+		}
+		if rate != 44100 || out.DType != tt.want || !out.Shape.Equal(tt.in.Shape) {
+			t.Errorf("%s: read %s %v at %d Hz", tt.in.DType, out.DType, out.Shape, rate)
+		}
+		if got, want := fmt.Sprint(out.Data), fmt.Sprint(tt.in.Data); got != want {
+			t.Errorf("%s: samples %s, want %s", tt.in.DType, got, want)
+		}
+	}
+}
+
+// wavBytes returns a WAV file of the given chunks, each an ID followed by its
+// contents.
+func wavBytes(chunks ...string) []byte {
+	var body []byte
+	for i := 0; i < len(chunks); i += 2 {
+		body = append(body, chunks[i]...)
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(chunks[i+1])))
+		body = append(body, chunks[i+1]...)
+		if len(chunks[i+1])%2 == 1 {
+			body = append(body, 0)
+		}
+	}
+	b := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(4+len(body)))
+	return append(append(b, "WAVE"...), body...)
+}
+
+// wavFmt returns a "fmt " chunk.
+func wavFmt(tag, channels, blockAlign, bits uint16) string {
+	b := binary.LittleEndian.AppendUint16(nil, tag)
+	b = binary.LittleEndian.AppendUint16(b, channels)
+	b = binary.LittleEndian.AppendUint32(b, 8000)
+	b = binary.LittleEndian.AppendUint32(b, 8000*uint32(blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, blockAlign)
+	b = binary.LittleEndian.AppendUint16(b, bits)
+	return string(b)
+}
+
+func TestReadWAVStreamed(t *testing.T) {
+	// A data size left at its maximum by a streaming writer, an odd-sized
+	// chunk before it, and a partial frame at the end
+	b := wavBytes("LIST", "abc", "fmt ", wavFmt(wavPCM, 1, 2, 16))
+	b = append(b, "data\xff\xff\xff\xff\x01\x00\x02\x00\x03"...)
+	path := filepath.Join(t.TempDir(), "a.wav")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := ReadWAV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(out.Data); got != "[1 2]" {
+		t.Errorf("samples %s, want [1 2]", got)
+	}
+}
+
+func TestHostileWAV(t *testing.T) {
+	pcm := wavFmt(wavPCM, 1, 2, 16)
+	tests := map[string][]byte{
+		"magic":         []byte("RIFX\x00\x00\x00\x00WAVE"),
+		"no data":       wavBytes("fmt ", pcm),
+		"data first":    wavBytes("data", "\x00\x00", "fmt ", pcm),
+		"short fmt":     wavBytes("fmt ", pcm[:12], "data", "\x00\x00"),
+		"no channels":   wavBytes("fmt ", wavFmt(wavPCM, 0, 2, 16), "data", "\x00\x00"),
+		"bits":          wavBytes("fmt ", wavFmt(wavPCM, 1, 2, 24), "data", "\x00\x00"),
+		"uneven frames": wavBytes("fmt ", wavFmt(wavPCM, 2, 3, 8), "data", "\x00\x00\x00"),
+		"wide samples":  wavBytes("fmt ", wavFmt(wavPCM, 1, 8, 64), "data", "\x00\x00"),
+		"format tag":    wavBytes("fmt ", wavFmt(2, 1, 2, 16), "data", "\x00\x00"),
+		"extensible":    wavBytes("fmt ", wavFmt(wavExtensible, 1, 2, 16), "data", "\x00\x00"),
+		"chunk overrun": append(wavBytes("fmt ", pcm), "LIST\xff\xff\xff\x7f"...),
+	}
+	for name, b := range tests {
+		path := filepath.Join(t.TempDir(), "a.wav")
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ReadWAV(path); err == nil {
+			t.Errorf("%s: read without error", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "a.wav")
+	if err := os.WriteFile(path, wavBytes("fmt ", pcm, "data", string(make([]byte, 64))), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadWAV(path, WithMaxBytes(16)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestWriteWAVInvalid(t *testing.T) {
+	tests := map[string]struct {
+		t    *Tensor
+		rate int
+		bits int
+	}{
+		"range": {&Tensor{Data: []int32{40000}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"}, 8000, 16},
+		"bits":  {&Tensor{Data: []int32{1}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"}, 8000, 12},
+		"rate":  {&Tensor{Data: []int32{1}, Shape: Shape{1}, DType: DTypeI32, Device: "cpu"}, 0, 16},
+		"rank":  {&Tensor{Data: []int32{1}, Shape: Shape{1, 1, 1}, DType: DTypeI32, Device: "cpu"}, 8000, 16},
+		"dtype": {&Tensor{Data: []complex64{1}, Shape: Shape{1}, DType: DTypeC64, Device: "cpu"}, 8000, 16},
+	}
+	for name, tt := range tests {
+		path := filepath.Join(t.TempDir(), "a.wav")
+		if err := WriteWAV(path, tt.t, tt.rate, WithWAVBits(tt.bits)); err == nil {
+			t.Errorf("%s: written without error", name)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package gonpy
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Sample is one WebDataset sample: the group of consecutive tar members that
+// share a key. A member named "shard/0001.img.npy" belongs to the sample with
+// key "shard/0001" and is stored in Tensors under "img"; members that are not
+// NPY files, such as "0001.meta.json", are kept as raw bytes in Files under
+// their extension ("meta.json").
+type Sample struct {
+	Key     string
+	Tensors map[string]*Tensor
+	Files   map[string][]byte
+}
+
+// WebDatasetReader reads samples lazily from WebDataset-style tar shards.
+// The WithMaxBytes limit applies to raw members as well as to arrays.
+type WebDatasetReader struct {
+	o       *options
+	shards  []string // Remaining shard paths, when reading from files
+	closer  io.Closer
+	tr      *tar.Reader
+	pending *Sample // First member of the next sample, read ahead of time
+	release func()
+}
+
+// NewWebDatasetReader returns a reader over a single tar stream.
+func NewWebDatasetReader(r io.Reader, opts ...Option) *WebDatasetReader {
+	return &WebDatasetReader{o: newOptions(opts), tr: tar.NewReader(r)}
+}
+
+// OpenWebDataset returns a reader over the given shard files, which are opened
+// one at a time in order. Shards ending in ".gz" or ".tgz" are decompressed.
+func OpenWebDataset(paths []string, opts ...Option) *WebDatasetReader {
+	return &WebDatasetReader{o: newOptions(opts), shards: append([]string(nil), paths...)}
+}
+
+// Next returns the next sample, or io.EOF when all shards are exhausted.
+func (w *WebDatasetReader) Next() (*Sample, error) {
+	sample := w.pending
+	w.pending = nil
+	for {
+		key, ext, member, err := w.nextMember()
+		if err == io.EOF {
+			if sample != nil {
+				return sample, nil
+			}
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if sample != nil && key != sample.Key {
+			w.pending = &Sample{Key: key, Tensors: map[string]*Tensor{}, Files: map[string][]byte{}}
+			w.pending.add(ext, member)
+			return sample, nil
+		}
+		if sample == nil {
+			sample = &Sample{Key: key, Tensors: map[string]*Tensor{}, Files: map[string][]byte{}}
+		}
+		sample.add(ext, member)
+	}
+}
+
+// Close releases the currently open shard, if any.
+func (w *WebDatasetReader) Close() error {
+	w.shards = nil
+	return w.closeShard()
+}
+
+// add stores a decoded member in the sample.
+func (s *Sample) add(ext string, member interface{}) {
+	switch m := member.(type) {
+	case *Tensor:
+		s.Tensors[strings.TrimSuffix(ext, npySuffix)] = m
+	case []byte:
+		s.Files[ext] = m
+	}
+}
+
+// nextMember reads the next regular tar member across shards, returning its
+// sample key, extension, and decoded contents (a *Tensor or []byte).
+func (w *WebDatasetReader) nextMember() (string, string, interface{}, error) {
+	for {
+		if w.tr == nil {
+			if err := w.openShard(); err != nil {
+				return "", "", nil, err
+			}
+		}
+		hdr, err := w.tr.Next()
+		if err == io.EOF && w.closer != nil {
+			if err := w.closeShard(); err != nil {
+				return "", "", nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return "", "", nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		key, ext := splitSampleName(hdr.Name)
+		if strings.HasSuffix(ext, npySuffix) {
+			t, err := readTensor(w.tr, hdr.Size, w.o)
+			if err != nil {
+				return "", "", nil, locate(err, "", hdr.Name)
+			}
+			return key, ext, t, nil
+		}
+		if w.o.maxBytes > 0 && hdr.Size > w.o.maxBytes {
+			return "", "", nil, locate(ErrTooLarge, "", hdr.Name)
+		}
+		data, err := io.ReadAll(w.tr)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return key, ext, data, nil
+	}
+}
+
+// openShard opens the next shard file, returning io.EOF when none remain.
+func (w *WebDatasetReader) openShard() error {
+	if len(w.shards) == 0 {
+		return io.EOF
+	}
+	p := w.shards[0]
+	w.shards = w.shards[1:]
+
+	w.o.limiter.Acquire()
+	f, err := os.Open(p)
+	if err != nil {
+		w.o.limiter.Release()
+		return err
+	}
+	w.release = w.o.limiter.Release
+	w.closer = f
+
+	var r io.Reader = f
+	if strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".tgz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return errors.Join(err, w.closeShard())
+		}
+		r = zr
+	}
+	w.tr = tar.NewReader(r)
+	return nil
+}
+
+// closeShard closes the currently open shard file.
+func (w *WebDatasetReader) closeShard() error {
+	if w.closer == nil {
+		return nil
+	}
+	err := w.closer.Close()
+	w.closer, w.tr = nil, nil
+	if w.release != nil {
+		w.release()
+		w.release = nil
+	}
+	return err
+}
+
+// splitSampleName splits a member name into its sample key and extension at
+// the first dot of the base name, following the WebDataset convention.
+func splitSampleName(name string) (string, string) {
+	dir, base := path.Split(name)
+	if i := strings.IndexByte(base, '.'); i >= 0 {
+		return dir + base[:i], base[i+1:]
+	}
+	return name, ""
+}
@@ -0,0 +1,102 @@
+package gonpy
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarBytes returns a tar stream holding the members in order, as name and
+// contents pairs.
+func tarBytes(t *testing.T, members ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for i := 0; i < len(members); i += 2 {
+		hdr := &tar.Header{Name: members[i], Mode: 0o644, Size: int64(len(members[i+1]))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(members[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// npyString returns the NPY encoding of a tensor.
+func npyString(t *testing.T, in *Tensor) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := in.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWebDataset(t *testing.T) {
+	img := npyString(t, &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"})
+	shard := tarBytes(t,
+		"s/0001.img.npy", img,
+		"s/0001.meta.json", `{"label": 1}`,
+		"s/0002.img.npy", img,
+	)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(shard)
+	zw.Close()
+	path := filepath.Join(t.TempDir(), "shard.tar.gz")
+	if err := os.WriteFile(path, gz.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	w := OpenWebDataset([]string{path})
+	defer w.Close()
+	var keys []string
+	for {
+		s, err := w.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, s.Key)
+		if got := s.Tensors["img"].Data.([]float32); got[1] != 2 {
+			t.Errorf("%s img = %v", s.Key, got)
+		}
+	}
+	if len(keys) != 2 || keys[0] != "s/0001" || keys[1] != "s/0002" {
+		t.Errorf("keys = %v", keys)
+	}
+}
+
+func TestHostileWebDataset(t *testing.T) {
+	img := npyString(t, &Tensor{Data: []float32{1, 2}, Shape: Shape{2}, DType: DTypeF32, Device: "cpu"})
+	tests := map[string][]byte{
+		"short npy":   tarBytes(t, "0001.img.npy", img[:len(img)-1]),
+		"long npy":    tarBytes(t, "0001.img.npy", img+"x"),
+		"bad npy":     tarBytes(t, "0001.img.npy", "not npy"),
+		"large file":  tarBytes(t, "0001.bin", string(make([]byte, 100))),
+		"large array": tarBytes(t, "0001.img.npy", npyString(t, &Tensor{Data: make([]float64, 16), Shape: Shape{16}, DType: DTypeF64, Device: "cpu"})),
+		"truncated":   tarBytes(t, "0001.bin", string(make([]byte, 50)))[:540],
+	}
+	for name, shard := range tests {
+		w := NewWebDatasetReader(bytes.NewReader(shard), WithMaxBytes(64))
+		if _, err := w.Next(); err == nil || err == io.EOF {
+			t.Errorf("%s: got %v", name, err)
+		}
+	}
+	w := NewWebDatasetReader(bytes.NewReader(tests["large file"]), WithMaxBytes(64))
+	if _, err := w.Next(); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("large file: got %v, want ErrTooLarge", err)
+	}
+}
@@ -0,0 +1,119 @@
+package zarr
+
+import "github.com/gocnn/gonpy"
+
+// chunker copies chunks in and out of the raw bytes of a whole array. Chunks
+// on the upper edges of the array are full-size, with the part outside the
+// array unused.
+type chunker struct {
+	shape []int
+	chunk []int
+	size  int    // Bytes per element
+	raw   []byte // Elements of the array in row-major order
+}
+
+// chunkBytes returns the size of one uncompressed chunk.
+func (c *chunker) chunkBytes() int {
+	return gonpy.Shape(c.chunk).ElemCount() * c.size
+}
+
+// each calls fn with the element offsets and grid index of every chunk, in
+// row-major grid order.
+func (c *chunker) each(fn func(offsets, idx []int) error) error {
+	rank := len(c.shape)
+	grid := make([]int, rank)
+	for k := range grid {
+		grid[k] = (c.shape[k] + c.chunk[k] - 1) / c.chunk[k]
+		if grid[k] == 0 {
+			return nil
+		}
+	}
+	idx := make([]int, rank)
+	offsets := make([]int, rank)
+	for {
+		for k := range idx {
+			offsets[k] = idx[k] * c.chunk[k]
+		}
+		if err := fn(offsets, idx); err != nil {
+			return err
+		}
+		k := rank - 1
+		for ; k >= 0; k-- {
+			idx[k]++
+			if idx[k] < grid[k] {
+				break
+			}
+			idx[k] = 0
+		}
+		if k < 0 {
+			return nil
+		}
+	}
+}
+
+// copy copies the part of the chunk at offsets that lies inside the array
+// from data into the array, or from the array into data if toChunk is true.
+func (c *chunker) copy(data []byte, offsets []int, toChunk bool) {
+	rank := len(c.shape)
+	if rank == 0 {
+		if toChunk {
+			copy(data, c.raw)
+		} else {
+			copy(c.raw, data)
+		}
+		return
+	}
+	last := rank - 1
+	run := min(c.chunk[last], c.shape[last]-offsets[last]) * c.size
+	rows := gonpy.Shape(c.chunk[:last]).ElemCount()
+	idx := make([]int, last)
+	for r := 0; r < rows; r++ {
+		inside := true
+		dst := 0
+		for k := 0; k < last; k++ {
+			g := offsets[k] + idx[k]
+			inside = inside && g < c.shape[k]
+			dst = dst*c.shape[k] + g
+		}
+		if inside {
+			dst = (dst*c.shape[last] + offsets[last]) * c.size
+			src := r * c.chunk[last] * c.size
+			if toChunk {
+				copy(data[src:src+run], c.raw[dst:])
+			} else {
+				copy(c.raw[dst:dst+run], data[src:])
+			}
+		}
+		for k := last - 1; k >= 0; k-- {
+			idx[k]++
+			if idx[k] < c.chunk[k] {
+				break
+			}
+			idx[k] = 0
+		}
+	}
+}
+
+// fortranToC reorders the elements of a chunk from column-major to row-major
+// order.
+func (c *chunker) fortranToC(data []byte) []byte {
+	out := make([]byte, len(data))
+	rank := len(c.chunk)
+	idx := make([]int, rank)
+	for i := 0; i < len(data)/c.size; i++ {
+		f, stride := 0, 1
+		for k := 0; k < rank; k++ {
+			f += idx[k] * stride
+			stride *= c.chunk[k]
+		}
+		copy(out[i*c.size:(i+1)*c.size], data[f*c.size:])
+		for k := rank - 1; k >= 0; k-- {
+			idx[k]++
+			if idx[k] < c.chunk[k] {
+				break
+			}
+			idx[k] = 0
+		}
+	}
+	return out
+}
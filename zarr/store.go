@@ -0,0 +1,134 @@
+package zarr
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Store is a key-value store holding a Zarr hierarchy. Keys are
+// slash-separated paths such as "weights/.zarray" and "weights/0.0".
+type Store interface {
+	// Get returns the value of key, or an error wrapping fs.ErrNotExist if
+	// the key is not set.
+	Get(key string) ([]byte, error)
+	// Set sets the value of key.
+	Set(key string, value []byte) error
+}
+
+// DirStore is a store kept in a directory, with one file per key, as written
+// by zarr.DirectoryStore.
+type DirStore string
+
+// Get reads the file of key.
+func (d DirStore) Get(key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), filepath.FromSlash(key)))
+}
+
+// Set writes the file of key, creating directories as needed.
+func (d DirStore) Set(key string, value []byte) error {
+	path := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, value, 0o644)
+}
+
+// ZipStore is a store kept in a zip archive, as written by zarr.ZipStore,
+// with one uncompressed entry per key. A ZipStore is opened either for
+// reading with OpenZip or for writing with CreateZip, and must be closed.
+// A store open for writing can get only the metadata keys written to it.
+type ZipStore struct {
+	r     *zip.ReadCloser
+	files map[string]*zip.File
+	f     *os.File
+	w     *zip.Writer
+	keys  map[string][]byte // Keys written so far, with values of metadata keys
+}
+
+// OpenZip opens a zip archive as a read-only store.
+func OpenZip(path string) (*ZipStore, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &ZipStore{r: r, files: make(map[string]*zip.File)}
+	for _, f := range r.File {
+		s.files[f.Name] = f
+	}
+	return s, nil
+}
+
+// CreateZip creates a zip archive as a write-only store. Each key can be set
+// only once.
+func CreateZip(path string) (*ZipStore, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ZipStore{f: f, w: zip.NewWriter(f), keys: make(map[string][]byte)}, nil
+}
+
+// Get reads the entry of key.
+func (s *ZipStore) Get(key string) ([]byte, error) {
+	if s.r == nil {
+		value, ok := s.keys[key]
+		if !ok {
+			return nil, &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+		}
+		if value == nil {
+			return nil, fmt.Errorf("zarr: key %s of zip store open for writing cannot be read", key)
+		}
+		return value, nil
+	}
+	f, ok := s.files[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Set adds the entry of key.
+func (s *ZipStore) Set(key string, value []byte) error {
+	if s.w == nil {
+		return fmt.Errorf("zarr: zip store is open for reading")
+	}
+	if _, ok := s.keys[key]; ok {
+		return fmt.Errorf("zarr: key %s is already set in zip store", key)
+	}
+	w, err := s.w.CreateHeader(&zip.FileHeader{Name: key, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(value); err != nil {
+		return err
+	}
+	s.keys[key] = nil
+	if strings.HasPrefix(key[strings.LastIndex(key, "/")+1:], ".z") {
+		s.keys[key] = bytes.Clone(value)
+	}
+	return nil
+}
+
+// Close closes the archive. For a store created with CreateZip, it writes the
+// central directory, and the archive is incomplete until Close returns nil.
+func (s *ZipStore) Close() error {
+	if s.r != nil {
+		return s.r.Close()
+	}
+	err := s.w.Close()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+package zarr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/gocnn/gonpy"
+)
+
+// Options configures how WriteArray stores an array.
+type Options struct {
+	Chunks     gonpy.Shape // Chunk shape; nil for a single chunk holding the whole array
+	Compressor string      // "zlib", "gzip", or "" for uncompressed chunks
+	Level      int         // Compression level from 1 to 9; 0 means 1, the numcodecs default
+}
+
+// WriteArray writes t as the array at path in s, such as "" for an array at
+// the root of the store or "model/weights", with a .zgroup marking each
+// enclosing group. Every chunk is written, in C order and little-endian. A nil
+// o writes a single zlib-compressed chunk. Integer, floating-point other than
+// BF16 and F8, complex, and boolean tensors are accepted.
+func WriteArray(s Store, path string, t *gonpy.Tensor, o *Options) error {
+	if o == nil {
+		o = &Options{Compressor: "zlib"}
+	}
+	if err := writeArray(s, path, t, o); err != nil {
+		return fmt.Errorf("zarr: array %q: %w", path, err)
+	}
+	return nil
+}
+
+// writeArray writes t as the array at path.
+func writeArray(s Store, path string, t *gonpy.Tensor, o *Options) error {
+	t, err := t.ToHost()
+	if err != nil {
+		return err
+	}
+	typestr, ok := typestrs[t.DType]
+	if !ok {
+		return fmt.Errorf("no NumPy type for %s: %w", t.DType, gonpy.ErrUnsupportedDType)
+	}
+	n, err := t.Shape.CheckedElemCount()
+	if err != nil {
+		return err
+	}
+	if size := binary.Size(t.Data); size < 0 || size != n*t.DType.Size() {
+		return fmt.Errorf("%d bytes of data for shape %v", size, t.Shape)
+	}
+	chunks := o.Chunks
+	if chunks == nil {
+		chunks = make(gonpy.Shape, len(t.Shape))
+		for k, d := range t.Shape {
+			chunks[k] = max(d, 1)
+		}
+	}
+	if len(chunks) != len(t.Shape) {
+		return fmt.Errorf("chunks %v do not match shape %v", chunks, t.Shape)
+	}
+	for _, c := range chunks {
+		if c <= 0 {
+			return fmt.Errorf("invalid chunks %v", chunks)
+		}
+	}
+	if _, err := chunks.CheckedElemCount(); err != nil {
+		return err
+	}
+	var comp *codec
+	if o.Compressor != "" {
+		if o.Compressor != "zlib" && o.Compressor != "gzip" {
+			return fmt.Errorf("compressor %s is not supported", o.Compressor)
+		}
+		level := o.Level
+		if level == 0 {
+			level = 1
+		}
+		if level < 1 || level > 9 {
+			return fmt.Errorf("invalid compression level %d", o.Level)
+		}
+		comp = &codec{ID: o.Compressor, Level: &level}
+	}
+
+	fill := json.RawMessage("0")
+	switch t.DType {
+	case gonpy.DTypeBool:
+		fill = json.RawMessage("false")
+	case gonpy.DTypeC64, gonpy.DTypeC128:
+		fill = json.RawMessage("[0,0]")
+	}
+	var meta bytes.Buffer
+	enc := json.NewEncoder(&meta)
+	enc.SetEscapeHTML(false) // Keep "<f4" readable
+	enc.SetIndent("", "    ")
+	err = enc.Encode(arrayMeta{
+		ZarrFormat: 2,
+		Shape:      append([]int{}, t.Shape...),
+		Chunks:     chunks,
+		DType:      json.RawMessage(strconv.Quote(typestr)),
+		Compressor: comp,
+		FillValue:  fill,
+		Order:      "C",
+		Filters:    json.RawMessage("null"),
+	})
+	if err != nil {
+		return err
+	}
+	p := prefix(path)
+	if err := requireGroups(s, p); err != nil {
+		return err
+	}
+	if err := s.Set(p+".zarray", meta.Bytes()); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, t.Data); err != nil {
+		return err
+	}
+	c := &chunker{shape: t.Shape, chunk: chunks, size: t.DType.Size(), raw: buf.Bytes()}
+	data := make([]byte, c.chunkBytes())
+	return c.each(func(offsets, idx []int) error {
+		clear(data)
+		c.copy(data, offsets, true)
+		b, err := compress(comp, data)
+		if err != nil {
+			return err
+		}
+		return s.Set(p+chunkKey(idx, "."), b)
+	})
+}
+
+// requireGroups writes a .zgroup for the root and every group enclosing the
+// node with key prefix p, unless the group exists already.
+func requireGroups(s Store, p string) error {
+	if p == "" {
+		return nil
+	}
+	parts := strings.Split(strings.TrimSuffix(p, "/"), "/")
+	for i := range parts {
+		key := strings.Join(parts[:i], "/")
+		if key != "" {
+			key += "/"
+		}
+		key += ".zgroup"
+		_, err := s.Get(key)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := s.Set(key, []byte("{\n    \"zarr_format\": 2\n}")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compress applies the compressor c to a chunk.
+func compress(c *codec, data []byte) ([]byte, error) {
+	if c == nil {
+		return bytes.Clone(data), nil
+	}
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	if c.ID == "gzip" {
+		w, err = gzip.NewWriterLevel(&buf, *c.Level)
+	} else {
+		w, err = zlib.NewWriterLevel(&buf, *c.Level)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,381 @@
+// Package zarr reads and writes tensors as arrays in Zarr version 2 stores,
+// the chunked, compressed format of zarr-python, xarray, and many cloud
+// datasets. Stores may be directories or zip archives, or any other Store.
+//
+// Chunks may be uncompressed or compressed with the zlib and gzip codecs of
+// numcodecs. Blosc, other codecs, and filters are not supported. Integer,
+// floating-point, complex, and boolean arrays of either byte order and of C
+// or Fortran chunk order are read into the matching dtypes, with 16-bit
+// integers widened to 32 bits since gonpy has no dtype for them. Missing
+// chunks read as the fill value.
+package zarr
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gocnn/gonpy"
+)
+
+// arrayMeta is the .zarray metadata of an array.
+type arrayMeta struct {
+	ZarrFormat         int             `json:"zarr_format"`
+	Shape              []int           `json:"shape"`
+	Chunks             []int           `json:"chunks"`
+	DType              json.RawMessage `json:"dtype"`
+	Compressor         *codec          `json:"compressor"`
+	FillValue          json.RawMessage `json:"fill_value"`
+	Order              string          `json:"order"`
+	Filters            json.RawMessage `json:"filters"`
+	DimensionSeparator string          `json:"dimension_separator,omitempty"`
+}
+
+// codec is the configuration of a numcodecs codec.
+type codec struct {
+	ID    string `json:"id"`
+	Level *int   `json:"level,omitempty"`
+}
+
+// elemType describes the elements of an array as stored in its chunks.
+type elemType struct {
+	dtype     gonpy.DType
+	kind      byte // NumPy kind: b, i, u, f, or c
+	size      int  // Bytes per element in chunks
+	bigEndian bool
+	widen     bool // 16-bit integers read into 32-bit dtypes
+}
+
+// typestrs maps dtypes to the NumPy type strings WriteArray stores them as.
+var typestrs = map[gonpy.DType]string{
+	gonpy.DTypeBool: "|b1",
+	gonpy.DTypeI8:   "|i1",
+	gonpy.DTypeU8:   "|u1",
+	gonpy.DTypeI32:  "<i4",
+	gonpy.DTypeU32:  "<u4",
+	gonpy.DTypeI64:  "<i8",
+	gonpy.DTypeU64:  "<u8",
+	gonpy.DTypeF16:  "<f2",
+	gonpy.DTypeF32:  "<f4",
+	gonpy.DTypeF64:  "<f8",
+	gonpy.DTypeC64:  "<c8",
+	gonpy.DTypeC128: "<c16",
+}
+
+// parseDType parses a NumPy type string such as "<f4".
+func parseDType(raw json.RawMessage) (elemType, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil || len(s) < 3 {
+		return elemType{}, fmt.Errorf("dtype %s: %w", raw, gonpy.ErrUnsupportedDType)
+	}
+	e := elemType{kind: s[1], bigEndian: s[0] == '>'}
+	var err error
+	if e.size, err = strconv.Atoi(s[2:]); err != nil {
+		return elemType{}, fmt.Errorf("dtype %s: %w", s, gonpy.ErrUnsupportedDType)
+	}
+	if e.kind == 'i' && e.size == 2 {
+		e.dtype, e.widen = gonpy.DTypeI32, true
+		return e, nil
+	}
+	if e.kind == 'u' && e.size == 2 {
+		e.dtype, e.widen = gonpy.DTypeU32, true
+		return e, nil
+	}
+	for dtype, ts := range typestrs {
+		if ts[1:] == s[1:] {
+			e.dtype = dtype
+			return e, nil
+		}
+	}
+	return elemType{}, fmt.Errorf("dtype %s: %w", s, gonpy.ErrUnsupportedDType)
+}
+
+// ReadArray reads the array at path in s, such as "" for an array at the
+// root of the store or "model/weights". The size limit set with
+// gonpy.WithMaxBytes applies to the array and to each of its chunks.
+func ReadArray(s Store, path string, opts ...gonpy.Option) (*gonpy.Tensor, error) {
+	t, err := readArray(s, prefix(path), gonpy.Limits(opts...))
+	if err != nil {
+		return nil, fmt.Errorf("zarr: array %q: %w", path, err)
+	}
+	return t, nil
+}
+
+// prefix returns the key prefix of the node at path.
+func prefix(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return path + "/"
+}
+
+// readArray reads the array whose keys start with prefix.
+func readArray(s Store, prefix string, limits gonpy.ReadLimits) (*gonpy.Tensor, error) {
+	b, err := s.Get(prefix + ".zarray")
+	if err != nil {
+		return nil, err
+	}
+	var m arrayMeta
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	if m.ZarrFormat != 2 {
+		return nil, fmt.Errorf("zarr format %d: %w", m.ZarrFormat, gonpy.ErrUnsupportedVersion)
+	}
+	if len(m.Chunks) != len(m.Shape) {
+		return nil, fmt.Errorf("chunks %v do not match shape %v", m.Chunks, m.Shape)
+	}
+	for k, c := range m.Chunks {
+		if c <= 0 || m.Shape[k] < 0 {
+			return nil, fmt.Errorf("invalid shape %v or chunks %v", m.Shape, m.Chunks)
+		}
+	}
+	if m.Order != "C" && m.Order != "F" {
+		return nil, fmt.Errorf("order %q is not supported", m.Order)
+	}
+	if len(m.Filters) > 0 && string(m.Filters) != "null" {
+		return nil, fmt.Errorf("filters are not supported")
+	}
+	e, err := parseDType(m.DType)
+	if err != nil {
+		return nil, err
+	}
+	shape := gonpy.Shape(m.Shape)
+	n, err := shape.CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	chunkElems, err := gonpy.Shape(m.Chunks).CheckedElemCount()
+	if err != nil {
+		return nil, err
+	}
+	if n > math.MaxInt/e.size || chunkElems > math.MaxInt/e.size {
+		return nil, gonpy.ErrTooLarge
+	}
+	if err := limits.CheckSize(e.dtype, shape); err != nil {
+		return nil, err
+	}
+	if err := limits.CheckBytes(int64(chunkElems * e.size)); err != nil {
+		return nil, err
+	}
+	fill, err := fillBytes(m.FillValue, e)
+	if err != nil {
+		return nil, err
+	}
+	swapBytes(fill, e)
+
+	c := &chunker{shape: m.Shape, chunk: m.Chunks, size: e.size, raw: make([]byte, n*e.size)}
+	for i := 0; i < len(c.raw); i += e.size {
+		copy(c.raw[i:], fill)
+	}
+	sep := m.DimensionSeparator
+	if sep == "" {
+		sep = "."
+	}
+	err = c.each(func(offsets, idx []int) error {
+		key := prefix + chunkKey(idx, sep)
+		data, err := s.Get(key)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if data, err = decompress(m.Compressor, data, c.chunkBytes()); err != nil {
+			return fmt.Errorf("chunk %s: %w", key, err)
+		}
+		if len(data) != c.chunkBytes() {
+			return fmt.Errorf("chunk %s has %d bytes, %d expected", key, len(data), c.chunkBytes())
+		}
+		if m.Order == "F" {
+			data = c.fortranToC(data)
+		}
+		c.copy(data, offsets, false)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodeElements(c.raw, shape, e)
+}
+
+// chunkKey returns the key of the chunk at grid index idx, relative to its
+// array.
+func chunkKey(idx []int, sep string) string {
+	if len(idx) == 0 {
+		return "0"
+	}
+	parts := make([]string, len(idx))
+	for k, i := range idx {
+		parts[k] = strconv.Itoa(i)
+	}
+	return strings.Join(parts, sep)
+}
+
+// decompress reverses the compressor of a chunk, reading at most limit+1
+// bytes so that oversized chunks are detected without being inflated whole.
+func decompress(c *codec, data []byte, limit int) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	var r io.ReadCloser
+	var err error
+	switch c.ID {
+	case "zlib":
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("compressor %s is not supported", c.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, int64(limit)+1))
+}
+
+// fillBytes returns the little-endian bytes of one element holding the fill
+// value, or zeros if the fill value is null.
+func fillBytes(raw json.RawMessage, e elemType) ([]byte, error) {
+	b := make([]byte, e.size)
+	if len(raw) == 0 || string(raw) == "null" {
+		return b, nil
+	}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	if e.kind == 'c' {
+		parts, ok := v.([]interface{})
+		if !ok || len(parts) != 2 {
+			parts = []interface{}{v, json.Number("0")}
+		}
+		half := elemType{kind: 'f', size: e.size / 2}
+		for k, part := range parts {
+			p, err := fillBytes(mustJSON(part), half)
+			if err != nil {
+				return nil, err
+			}
+			copy(b[k*half.size:], p)
+		}
+		return b, nil
+	}
+
+	var s string
+	switch v := v.(type) {
+	case bool:
+		if v {
+			s = "1"
+		} else {
+			s = "0"
+		}
+	case json.Number:
+		s = string(v)
+	case string:
+		s = strings.TrimPrefix(v, "+")
+		if strings.HasSuffix(s, "Infinity") {
+			s = strings.TrimSuffix(s, "inity")
+		}
+	default:
+		return nil, fmt.Errorf("fill value %s", raw)
+	}
+
+	var err error
+	switch e.kind {
+	case 'b':
+		b[0] = 0
+		if s != "0" && s != "false" {
+			b[0] = 1
+		}
+	case 'i':
+		var x int64
+		x, err = strconv.ParseInt(s, 10, 8*e.size)
+		putUint(b, uint64(x))
+	case 'u':
+		var x uint64
+		x, err = strconv.ParseUint(s, 10, 8*e.size)
+		putUint(b, x)
+	case 'f':
+		var x float64
+		x, err = strconv.ParseFloat(s, 64)
+		switch e.size {
+		case 2:
+			binary.LittleEndian.PutUint16(b, gonpy.F32ToF16([]float32{float32(x)})[0])
+		case 4:
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(x)))
+		case 8:
+			binary.LittleEndian.PutUint64(b, math.Float64bits(x))
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fill value %s: %w", raw, err)
+	}
+	return b, nil
+}
+
+// mustJSON encodes a value decoded from JSON back to JSON.
+func mustJSON(v interface{}) json.RawMessage {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+// putUint stores the low len(b) bytes of x in b, little-endian.
+func putUint(b []byte, x uint64) {
+	for i := range b {
+		b[i] = byte(x >> (8 * i))
+	}
+}
+
+// swapBytes converts elements between big- and little-endian order in place
+// if e is big-endian.
+func swapBytes(raw []byte, e elemType) {
+	if !e.bigEndian {
+		return
+	}
+	unit := e.size
+	if e.kind == 'c' {
+		unit /= 2
+	}
+	for i := 0; i+unit <= len(raw); i += unit {
+		for l, r := i, i+unit-1; l < r; l, r = l+1, r-1 {
+			raw[l], raw[r] = raw[r], raw[l]
+		}
+	}
+}
+
+// decodeElements converts raw element bytes in e's byte order to a tensor.
+func decodeElements(raw []byte, shape gonpy.Shape, e elemType) (*gonpy.Tensor, error) {
+	swapBytes(raw, e)
+	t, err := gonpy.Zeros(e.dtype, shape)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case e.widen && e.kind == 'i':
+		data := t.Data.([]int32)
+		for i := range data {
+			data[i] = int32(int16(binary.LittleEndian.Uint16(raw[2*i:])))
+		}
+	case e.widen:
+		data := t.Data.([]uint32)
+		for i := range data {
+			data[i] = uint32(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+	default:
+		err = binary.Read(bytes.NewReader(raw), binary.LittleEndian, t.Data)
+	}
+	return t, err
+}
@@ -0,0 +1,129 @@
+package zarr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/gocnn/gonpy"
+)
+
+// mapStore is an in-memory Store.
+type mapStore map[string][]byte
+
+func (m mapStore) Get(key string) ([]byte, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	}
+	return v, nil
+}
+
+func (m mapStore) Set(key string, value []byte) error {
+	m[key] = value
+	return nil
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := mapStore{}
+	in := &gonpy.Tensor{Data: []float32{1, 2, 3, 4, 5, 6, 7, 8, 9}, Shape: gonpy.Shape{3, 3}, DType: gonpy.DTypeF32, Device: "cpu"}
+	if err := WriteArray(s, "model/w", in, &Options{Chunks: gonpy.Shape{2, 2}, Compressor: "gzip"}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadArray(s, "model/w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.Data.([]float32)
+	if !out.Shape.Equal(in.Shape) || got[0] != 1 || got[8] != 9 {
+		t.Errorf("read %v %v", out.Shape, got)
+	}
+}
+
+func TestMissingChunksReadAsFill(t *testing.T) {
+	s := mapStore{".zarray": []byte(`{"zarr_format": 2, "shape": [4], "chunks": [2], "dtype": "<i8",
+		"compressor": null, "fill_value": 7, "order": "C", "filters": null}`)}
+	out, err := ReadArray(s, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data.([]int64); got[0] != 7 || got[3] != 7 {
+		t.Errorf("read %v", got)
+	}
+}
+
+func TestHostileMetadata(t *testing.T) {
+	cases := map[string]string{
+		"huge shape":  `{"zarr_format": 2, "shape": [2147483647, 268435456], "chunks": [1, 1], "dtype": "<f8", "compressor": null, "fill_value": 0, "order": "C", "filters": null}`,
+		"huge chunks": `{"zarr_format": 2, "shape": [1, 1], "chunks": [2147483647, 268435456], "dtype": "<f8", "compressor": null, "fill_value": 0, "order": "C", "filters": null}`,
+	}
+	for name, meta := range cases {
+		_, err := ReadArray(mapStore{".zarray": []byte(meta)}, "")
+		if !errors.Is(err, gonpy.ErrTooLarge) {
+			t.Errorf("%s: err = %v, want ErrTooLarge", name, err)
+		}
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	s := mapStore{}
+	in := &gonpy.Tensor{Data: make([]float64, 1024), Shape: gonpy.Shape{1024}, DType: gonpy.DTypeF64, Device: "cpu"}
+	if err := WriteArray(s, "x", in, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadArray(s, "x", gonpy.WithMaxBytes(1024)); !errors.Is(err, gonpy.ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+// memDevice is a gonpy.Device whose buffers are host byte slices behind
+// opaque handles, like those of real devices.
+type memDevice struct{}
+
+type memBuffer struct{ b []byte }
+
+func (memDevice) Allocate(n int) (interface{}, error) {
+	return &memBuffer{make([]byte, n)}, nil
+}
+
+func (memDevice) Free(buf interface{}) {}
+
+func (memDevice) CopyFrom(buf interface{}, offset int, src []byte) error {
+	copy(buf.(*memBuffer).b[offset:], src)
+	return nil
+}
+
+func (memDevice) CopyTo(buf interface{}, offset int, dst []byte) error {
+	copy(dst, buf.(*memBuffer).b[offset:])
+	return nil
+}
+
+func init() {
+	gonpy.RegisterDevice("mem:0", memDevice{})
+}
+
+// onDevice returns a copy of x on the memDevice.
+func onDevice(t *testing.T, x *gonpy.Tensor) *gonpy.Tensor {
+	t.Helper()
+	d, err := x.ToDevice("mem:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestWriteDeviceTensor(t *testing.T) {
+	x := &gonpy.Tensor{Data: []float64{1, -2, 3}, Shape: gonpy.Shape{3}, DType: gonpy.DTypeF64, Device: "cpu"}
+	s := mapStore{}
+	if err := WriteArray(s, "x", onDevice(t, x), nil); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadArray(s, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(out.Data) != "[1 -2 3]" {
+		t.Errorf("read back %v", out.Data)
+	}
+}
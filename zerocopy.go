@@ -0,0 +1,148 @@
+package gonpy
+
+import (
+	"encoding/binary"
+	"io"
+	"unsafe"
+)
+
+// nativeLittleEndian reports whether the platform stores integers little-endian,
+// matching the byte order of the data written by this package.
+var nativeLittleEndian = binary.NativeEndian.Uint16([]byte{1, 0}) == 1
+
+// WithZeroCopy reads and writes numeric data by viewing the tensor's slice as
+// raw bytes, skipping the element-by-element conversion. The result slice is
+// allocated with its own element type, so it is correctly aligned and owns its
+// memory; nothing aliases the read buffer. The option has no effect on
+// big-endian platforms, where the bytes must be swapped, and for bool tensors,
+// whose bytes are not guaranteed to be 0 or 1 in files from other writers.
+func WithZeroCopy() Option {
+	return func(o *options) {
+		o.zeroCopy = true
+	}
+}
+
+// rawBytes returns the memory of a fixed-size numeric slice as bytes, or false
+// if data cannot be viewed that way.
+func rawBytes(data interface{}) ([]byte, bool) {
+	if !nativeLittleEndian {
+		return nil, false
+	}
+	switch d := data.(type) {
+	case []byte:
+		return d, true
+	case []int8:
+		return asBytes(d), true
+	case []uint16:
+		return asBytes(d), true
+	case []float32:
+		return asBytes(d), true
+	case []float64:
+		return asBytes(d), true
+	case []complex64:
+		return asBytes(d), true
+	case []complex128:
+		return asBytes(d), true
+	case []int32:
+		return asBytes(d), true
+	case []int64:
+		return asBytes(d), true
+	case []uint32:
+		return asBytes(d), true
+	case []uint64:
+		return asBytes(d), true
+	default:
+		return nil, false
+	}
+}
+
+// asBytes views the memory of s as a byte slice.
+func asBytes[T int8 | uint16 | float32 | float64 | complex64 | complex128 | int32 | int64 | uint32 | uint64](s []T) []byte {
+	if len(s) == 0 {
+		return nil
+	}
+	var zero T
+	return unsafe.Slice((*byte)(unsafe.Pointer(&s[0])), len(s)*int(unsafe.Sizeof(zero)))
+}
+
+// fromBytes views b as a slice of T. The start of b must be aligned for T.
+func fromBytes[T int8 | uint16 | float32 | float64 | complex64 | complex128 | int32 | int64 | uint32 | uint64](b []byte) []T {
+	var zero T
+	size := int(unsafe.Sizeof(zero))
+	if len(b) < size {
+		return []T{}
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&b[0])), len(b)/size)
+}
+
+// viewData views b as the data slice of a numeric dtype, such as []float32 for
+// DTypeF32, or returns false if the dtype's elements cannot be viewed in place.
+// The start of b must be aligned for the element type.
+func viewData(dtype DType, b []byte) (interface{}, bool) {
+	if !nativeLittleEndian {
+		return nil, false
+	}
+	switch dtype {
+	case DTypeU8:
+		return b, true
+	case DTypeI8, DTypeF8E4M3, DTypeF8E5M2:
+		return fromBytes[int8](b), true
+	case DTypeF16, DTypeBF16:
+		return fromBytes[uint16](b), true
+	case DTypeF32:
+		return fromBytes[float32](b), true
+	case DTypeF64:
+		return fromBytes[float64](b), true
+	case DTypeC64:
+		return fromBytes[complex64](b), true
+	case DTypeC128:
+		return fromBytes[complex128](b), true
+	case DTypeI32:
+		return fromBytes[int32](b), true
+	case DTypeI64:
+		return fromBytes[int64](b), true
+	case DTypeU32:
+		return fromBytes[uint32](b), true
+	case DTypeU64:
+		return fromBytes[uint64](b), true
+	}
+	if dtype.isStructured() {
+		return b, true
+	}
+	return nil, false
+}
+
+// readDataZeroCopy reads data as readData does, filling numeric slices directly
+// from r when possible.
+func readDataZeroCopy(shape Shape, dtype DType, r io.Reader) (interface{}, error) {
+	switch dtype {
+	case DTypeBool, DTypeObject:
+		return readData(shape, dtype, r)
+	}
+	if _, _, ok := dtype.stringKind(); ok || dtype.isStructured() {
+		return readData(shape, dtype, r)
+	}
+	data, err := makeData(dtype, shape.ElemCount())
+	if err != nil {
+		return nil, err
+	}
+	b, ok := rawBytes(data)
+	if !ok {
+		return readData(shape, dtype, r)
+	}
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeDataZeroCopy writes data as writeData does, writing numeric slices
+// directly from their memory when possible.
+func writeDataZeroCopy(w io.Writer, dtype DType, data interface{}) error {
+	b, ok := rawBytes(data)
+	if !ok {
+		return writeData(w, dtype, data)
+	}
+	_, err := w.Write(b)
+	return err
+}